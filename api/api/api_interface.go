@@ -85,6 +85,7 @@ type TenantEnvInterface interface {
 	GetManyDeployVersion(w http.ResponseWriter, r *http.Request)
 	LimitTenantEnvMemory(w http.ResponseWriter, r *http.Request)
 	TenantEnvResourcesStatus(w http.ResponseWriter, r *http.Request)
+	TenantEnvQuota(w http.ResponseWriter, r *http.Request)
 	CheckResourceName(w http.ResponseWriter, r *http.Request)
 	Log(w http.ResponseWriter, r *http.Request)
 	GetKubeConfig(w http.ResponseWriter, r *http.Request)
@@ -163,6 +164,7 @@ type ServiceInterface interface {
 	Share(w http.ResponseWriter, r *http.Request)
 	ShareResult(w http.ResponseWriter, r *http.Request)
 	BuildVersionInfo(w http.ResponseWriter, r *http.Request)
+	BuildVersionSBOM(w http.ResponseWriter, r *http.Request)
 	GetDeployVersion(w http.ResponseWriter, r *http.Request)
 	AutoscalerRules(w http.ResponseWriter, r *http.Request)
 	ScalingRecords(w http.ResponseWriter, r *http.Request)
@@ -203,6 +205,7 @@ type TenantEnvInterfaceWithV1 interface {
 	VerticalService(w http.ResponseWriter, r *http.Request)
 	HorizontalService(w http.ResponseWriter, r *http.Request)
 	BuildService(w http.ResponseWriter, r *http.Request)
+	CancelBuild(w http.ResponseWriter, r *http.Request)
 	DeployService(w http.ResponseWriter, r *http.Request)
 	UpgradeService(w http.ResponseWriter, r *http.Request)
 	StatusService(w http.ResponseWriter, r *http.Request)
@@ -213,6 +216,7 @@ type TenantEnvInterfaceWithV1 interface {
 // LogInterface log interface
 type LogInterface interface {
 	HistoryLogs(w http.ResponseWriter, r *http.Request)
+	AccessLogs(w http.ResponseWriter, r *http.Request)
 	LogList(w http.ResponseWriter, r *http.Request)
 	LogFile(w http.ResponseWriter, r *http.Request)
 	LogSocket(w http.ResponseWriter, r *http.Request)
@@ -341,9 +345,48 @@ type RegistryAuthSecretInterface interface {
 	RegistryAuthSecret(w http.ResponseWriter, r *http.Request)
 }
 
+// ImageSignaturePolicyInterface image signature policy interface
+type ImageSignaturePolicyInterface interface {
+	ClusterImageSignaturePolicy(w http.ResponseWriter, r *http.Request)
+	TenantEnvImageSignaturePolicy(w http.ResponseWriter, r *http.Request)
+}
+
+// ServiceReleaseInterface service release interface
+type ServiceReleaseInterface interface {
+	ServiceReleases(w http.ResponseWriter, r *http.Request)
+	ServiceRelease(w http.ResponseWriter, r *http.Request)
+}
+
 // AppStoreVersionInterface app store version interface
 type AppStoreVersionInterface interface {
 	ExportAppStoreVersionStatus(w http.ResponseWriter, r *http.Request)
 	ExportAppStoreVersion(w http.ResponseWriter, r *http.Request)
 	DownloadAppStoreVersion(w http.ResponseWriter, r *http.Request)
 }
+
+// AlertInterface notification channels, alert silences, alert rules, slos and log alert rules interface
+type AlertInterface interface {
+	NotificationChannels(w http.ResponseWriter, r *http.Request)
+	NotificationChannel(w http.ResponseWriter, r *http.Request)
+	TestNotificationChannel(w http.ResponseWriter, r *http.Request)
+	AlertSilences(w http.ResponseWriter, r *http.Request)
+	AlertSilence(w http.ResponseWriter, r *http.Request)
+	ServiceAlertRules(w http.ResponseWriter, r *http.Request)
+	ServiceAlertRule(w http.ResponseWriter, r *http.Request)
+	AppAlertRules(w http.ResponseWriter, r *http.Request)
+	AppAlertRule(w http.ResponseWriter, r *http.Request)
+	ServiceSLOs(w http.ResponseWriter, r *http.Request)
+	ServiceSLO(w http.ResponseWriter, r *http.Request)
+	ServiceSLOStatus(w http.ResponseWriter, r *http.Request)
+	ServiceLogAlertRules(w http.ResponseWriter, r *http.Request)
+	ServiceLogAlertRule(w http.ResponseWriter, r *http.Request)
+}
+
+// ScheduledScalingInterface scheduled scaling and scale-to-zero interface
+type ScheduledScalingInterface interface {
+	ServiceScheduledScalingRules(w http.ResponseWriter, r *http.Request)
+	ServiceScheduledScalingRule(w http.ResponseWriter, r *http.Request)
+	TenantEnvScheduledScalingRules(w http.ResponseWriter, r *http.Request)
+	TenantEnvScheduledScalingRule(w http.ResponseWriter, r *http.Request)
+	ScaleToZero(w http.ResponseWriter, r *http.Request)
+}
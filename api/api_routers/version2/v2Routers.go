@@ -115,6 +115,9 @@ func (v2 *V2) clusterRouter() chi.Router {
 	r.Get("/builder/mavensetting/{name}", controller.GetManager().MavenSettingDetail)
 	r.Put("/builder/mavensetting/{name}", controller.GetManager().MavenSettingUpdate)
 	r.Delete("/builder/mavensetting/{name}", controller.GetManager().MavenSettingDelete)
+	r.Get("/image-signature-policy", controller.GetManager().ClusterImageSignaturePolicy)
+	r.Put("/image-signature-policy", controller.GetManager().ClusterImageSignaturePolicy)
+	r.Delete("/image-signature-policy", controller.GetManager().ClusterImageSignaturePolicy)
 
 	// features
 	r.Get("/features", controller.GetManager().Features)
@@ -222,6 +225,8 @@ func (v2 *V2) tenantEnvNameRouter() chi.Router {
 	//团队资源限制
 	r.Post("/limit_memory", controller.GetManager().LimitTenantEnvMemory)
 	r.Get("/limit_memory", controller.GetManager().TenantEnvResourcesStatus)
+	r.Get("/quota", controller.GetManager().TenantEnvQuota)
+	r.Put("/quota", controller.GetManager().TenantEnvQuota)
 
 	// Gateway
 	r.Post("/http-rule", controller.GetManager().HTTPRule)
@@ -240,6 +245,26 @@ func (v2 *V2) tenantEnvNameRouter() chi.Router {
 	r.Put("/registry/auth", controller.GetManager().RegistryAuthSecret)
 	r.Delete("/registry/auth", controller.GetManager().RegistryAuthSecret)
 
+	// image signature policy
+	r.Get("/image-signature-policy", controller.GetManager().TenantEnvImageSignaturePolicy)
+	r.Put("/image-signature-policy", controller.GetManager().TenantEnvImageSignaturePolicy)
+	r.Delete("/image-signature-policy", controller.GetManager().TenantEnvImageSignaturePolicy)
+
+	// scheduled scaling rules of all components
+	r.Get("/scheduled-scaling-rules", controller.GetManager().TenantEnvScheduledScalingRules)
+	r.Post("/scheduled-scaling-rules", controller.GetManager().TenantEnvScheduledScalingRules)
+	r.Put("/scheduled-scaling-rules/{rule_id}", controller.GetManager().TenantEnvScheduledScalingRule)
+	r.Delete("/scheduled-scaling-rules/{rule_id}", controller.GetManager().TenantEnvScheduledScalingRule)
+	// alert notification channels and silences
+	r.Get("/notification-channels", controller.GetManager().NotificationChannels)
+	r.Post("/notification-channels", controller.GetManager().NotificationChannels)
+	r.Put("/notification-channels/{channel_id}", controller.GetManager().NotificationChannel)
+	r.Delete("/notification-channels/{channel_id}", controller.GetManager().NotificationChannel)
+	r.Post("/notification-channels/{channel_id}/test", controller.GetManager().TestNotificationChannel)
+	r.Get("/alert-silences", controller.GetManager().AlertSilences)
+	r.Post("/alert-silences", controller.GetManager().AlertSilences)
+	r.Delete("/alert-silences/{silence_id}", controller.GetManager().AlertSilence)
+
 	// kubeconfig
 	r.Get("/kubeconfig", controller.GetManager().GetKubeConfig)
 
@@ -265,6 +290,7 @@ func (v2 *V2) serviceRouter() chi.Router {
 	r.Put("/", middleware.WrapEL(controller.GetManager().UpdateService, dbmodel.TargetTypeService, "update-service", dbmodel.SyncEventType))
 	// component build
 	r.Post("/build", middleware.WrapEL(controller.GetManager().BuildService, dbmodel.TargetTypeService, "build-service", dbmodel.AsyncEventType))
+	r.Post("/build/{event_id}/cancel", middleware.WrapEL(controller.GetManager().CancelBuild, dbmodel.TargetTypeService, "cancel-build", dbmodel.SyncEventType))
 	// component start
 	r.Post("/start", middleware.WrapEL(controller.GetManager().StartService, dbmodel.TargetTypeService, "start-service", dbmodel.AsyncEventType))
 	// component stop event set to synchronous event, not wait.
@@ -281,6 +307,11 @@ func (v2 *V2) serviceRouter() chi.Router {
 	r.Delete("/", middleware.WrapEL(controller.GetManager().SingleServiceInfo, dbmodel.TargetTypeService, "delete-service", dbmodel.SyncEventType))
 	//应用升级(act)
 	r.Post("/upgrade", middleware.WrapEL(controller.GetManager().UpgradeService, dbmodel.TargetTypeService, "upgrade-service", dbmodel.AsyncEventType))
+	// canary and blue-green release
+	r.Get("/releases", controller.GetManager().ServiceReleases)
+	r.Post("/releases", middleware.WrapEL(controller.GetManager().ServiceReleases, dbmodel.TargetTypeService, "release-service", dbmodel.AsyncEventType))
+	r.Get("/releases/{release_id}", controller.GetManager().ServiceRelease)
+	r.Put("/releases/{release_id}", middleware.WrapEL(controller.GetManager().ServiceRelease, dbmodel.TargetTypeService, "update-service-release", dbmodel.SyncEventType))
 	//应用状态获取(act)
 	r.Get("/status", controller.GetManager().StatusService)
 	//构建版本列表
@@ -288,12 +319,14 @@ func (v2 *V2) serviceRouter() chi.Router {
 	//构建版本操作
 	r.Get("/build-version/{build_version}", controller.GetManager().BuildVersionInfo)
 	r.Put("/build-version/{build_version}", controller.GetManager().BuildVersionInfo)
+	r.Get("/build-version/{build_version}/sbom", controller.GetManager().BuildVersionSBOM)
 	r.Get("/deployversion", controller.GetManager().GetDeployVersion)
 	r.Delete("/build-version/{build_version}", middleware.WrapEL(controller.GetManager().BuildVersionInfo, dbmodel.TargetTypeService, "delete-buildversion", dbmodel.SyncEventType))
 	//应用分享
 	r.Post("/share", middleware.WrapEL(controller.GetManager().Share, dbmodel.TargetTypeService, "share-service", dbmodel.SyncEventType))
 	r.Get("/share/{share_id}", controller.GetManager().ShareResult)
 	r.Get("/logs", controller.GetManager().HistoryLogs)
+	r.Get("/access-logs", controller.GetManager().AccessLogs)
 	r.Get("/log-file", controller.GetManager().LogList)
 	r.Get("/log-instance", controller.GetManager().LogSocket)
 	r.Post("/event-log", controller.GetManager().LogByAction)
@@ -389,6 +422,29 @@ func (v2 *V2) serviceRouter() chi.Router {
 	r.Put("/xparules", middleware.WrapEL(controller.GetManager().AutoscalerRules, dbmodel.TargetTypeService, "update-app-autoscaler-rule", dbmodel.SyncEventType))
 	r.Delete("/xparules/{rule_id}", middleware.WrapEL(controller.GetManager().AutoscalerRules, dbmodel.TargetTypeService, "delete-app-autoscaler-rule", dbmodel.SyncEventType))
 	r.Get("/xparecords", controller.GetManager().ScalingRecords)
+	// scheduled scaling and scale-to-zero
+	r.Get("/scheduled-scaling-rules", controller.GetManager().ServiceScheduledScalingRules)
+	r.Post("/scheduled-scaling-rules", middleware.WrapEL(controller.GetManager().ServiceScheduledScalingRules, dbmodel.TargetTypeService, "add-scheduled-scaling-rule", dbmodel.SyncEventType))
+	r.Put("/scheduled-scaling-rules/{rule_id}", middleware.WrapEL(controller.GetManager().ServiceScheduledScalingRule, dbmodel.TargetTypeService, "update-scheduled-scaling-rule", dbmodel.SyncEventType))
+	r.Delete("/scheduled-scaling-rules/{rule_id}", middleware.WrapEL(controller.GetManager().ServiceScheduledScalingRule, dbmodel.TargetTypeService, "delete-scheduled-scaling-rule", dbmodel.SyncEventType))
+	// alert rules
+	r.Get("/alert-rules", controller.GetManager().ServiceAlertRules)
+	r.Post("/alert-rules", middleware.WrapEL(controller.GetManager().ServiceAlertRules, dbmodel.TargetTypeService, "add-alert-rule", dbmodel.SyncEventType))
+	r.Put("/alert-rules/{rule_id}", middleware.WrapEL(controller.GetManager().ServiceAlertRule, dbmodel.TargetTypeService, "update-alert-rule", dbmodel.SyncEventType))
+	r.Delete("/alert-rules/{rule_id}", middleware.WrapEL(controller.GetManager().ServiceAlertRule, dbmodel.TargetTypeService, "delete-alert-rule", dbmodel.SyncEventType))
+	// slos of the requests through the gateway
+	r.Get("/slos", controller.GetManager().ServiceSLOs)
+	r.Post("/slos", middleware.WrapEL(controller.GetManager().ServiceSLOs, dbmodel.TargetTypeService, "add-slo", dbmodel.SyncEventType))
+	r.Put("/slos/{slo_id}", middleware.WrapEL(controller.GetManager().ServiceSLO, dbmodel.TargetTypeService, "update-slo", dbmodel.SyncEventType))
+	r.Delete("/slos/{slo_id}", middleware.WrapEL(controller.GetManager().ServiceSLO, dbmodel.TargetTypeService, "delete-slo", dbmodel.SyncEventType))
+	r.Get("/slos/{slo_id}/status", controller.GetManager().ServiceSLOStatus)
+	// log alert rules of the container logs
+	r.Get("/log-alert-rules", controller.GetManager().ServiceLogAlertRules)
+	r.Post("/log-alert-rules", middleware.WrapEL(controller.GetManager().ServiceLogAlertRules, dbmodel.TargetTypeService, "add-log-alert-rule", dbmodel.SyncEventType))
+	r.Put("/log-alert-rules/{rule_id}", middleware.WrapEL(controller.GetManager().ServiceLogAlertRule, dbmodel.TargetTypeService, "update-log-alert-rule", dbmodel.SyncEventType))
+	r.Delete("/log-alert-rules/{rule_id}", middleware.WrapEL(controller.GetManager().ServiceLogAlertRule, dbmodel.TargetTypeService, "delete-log-alert-rule", dbmodel.SyncEventType))
+	r.Get("/scale-to-zero", controller.GetManager().ScaleToZero)
+	r.Put("/scale-to-zero", middleware.WrapEL(controller.GetManager().ScaleToZero, dbmodel.TargetTypeService, "update-scale-to-zero", dbmodel.SyncEventType))
 
 	//service monitor
 	r.Post("/service-monitors", middleware.WrapEL(controller.GetManager().AddServiceMonitors, dbmodel.TargetTypeService, "add-app-service-monitor", dbmodel.SyncEventType))
@@ -483,6 +539,11 @@ func (v2 *V2) applicationRouter() chi.Router {
 	r.Post("/app-config-groups", controller.GetManager().SyncAppConfigGroups)
 
 	r.Get("/kube-resources", controller.GetManager().GetApplicationKubeResources)
+	// alert rules
+	r.Get("/alert-rules", controller.GetManager().AppAlertRules)
+	r.Post("/alert-rules", controller.GetManager().AppAlertRules)
+	r.Put("/alert-rules/{rule_id}", controller.GetManager().AppAlertRule)
+	r.Delete("/alert-rules/{rule_id}", controller.GetManager().AppAlertRule)
 	return r
 }
 
@@ -0,0 +1,322 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/wutong-paas/wutong/api/handler"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	httputil "github.com/wutong-paas/wutong/util/http"
+)
+
+// AlertStruct -
+type AlertStruct struct {
+}
+
+// NotificationChannels lists or creates the notification channels of the tenant env
+func (a *AlertStruct) NotificationChannels(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	h := handler.GetAlertHandler()
+	switch r.Method {
+	case "GET":
+		channels, err := h.ListNotificationChannels(tenantEnvID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, channels)
+	case "POST":
+		var req api_model.NotificationChannelReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		channel, err := h.CreateNotificationChannel(tenantEnvID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, channel)
+	}
+}
+
+// NotificationChannel updates or deletes the notification channel
+func (a *AlertStruct) NotificationChannel(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	channelID := chi.URLParam(r, "channel_id")
+	h := handler.GetAlertHandler()
+	switch r.Method {
+	case "PUT":
+		var req api_model.NotificationChannelReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		channel, err := h.UpdateNotificationChannel(tenantEnvID, channelID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, channel)
+	case "DELETE":
+		if err := h.DeleteNotificationChannel(tenantEnvID, channelID); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
+
+// TestNotificationChannel sends a test message to the notification channel
+func (a *AlertStruct) TestNotificationChannel(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	channelID := chi.URLParam(r, "channel_id")
+	if err := handler.GetAlertHandler().TestNotificationChannel(tenantEnvID, channelID); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// AlertSilences lists or creates the alert silences of the tenant env
+func (a *AlertStruct) AlertSilences(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	h := handler.GetAlertHandler()
+	switch r.Method {
+	case "GET":
+		silences, err := h.ListAlertSilences(tenantEnvID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, silences)
+	case "POST":
+		var req api_model.AlertSilenceReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		silence, err := h.CreateAlertSilence(tenantEnvID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, silence)
+	}
+}
+
+// AlertSilence deletes the alert silence
+func (a *AlertStruct) AlertSilence(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	silenceID := chi.URLParam(r, "silence_id")
+	if err := handler.GetAlertHandler().DeleteAlertSilence(tenantEnvID, silenceID); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// ServiceAlertRules lists or creates the alert rules of the component
+func (a *AlertStruct) ServiceAlertRules(w http.ResponseWriter, r *http.Request) {
+	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
+	a.alertRules(w, r, service.TenantEnvID, "", service.ServiceID)
+}
+
+// ServiceAlertRule updates or deletes the alert rule of the component
+func (a *AlertStruct) ServiceAlertRule(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	a.alertRule(w, r, "", serviceID)
+}
+
+// AppAlertRules lists or creates the alert rules of the application
+func (a *AlertStruct) AppAlertRules(w http.ResponseWriter, r *http.Request) {
+	app := r.Context().Value(ctxutil.ContextKey("application")).(*dbmodel.Application)
+	a.alertRules(w, r, app.TenantEnvID, app.AppID, "")
+}
+
+// AppAlertRule updates or deletes the alert rule of the application
+func (a *AlertStruct) AppAlertRule(w http.ResponseWriter, r *http.Request) {
+	app := r.Context().Value(ctxutil.ContextKey("application")).(*dbmodel.Application)
+	a.alertRule(w, r, app.AppID, "")
+}
+
+func (a *AlertStruct) alertRules(w http.ResponseWriter, r *http.Request, tenantEnvID, appID, serviceID string) {
+	h := handler.GetAlertRuleHandler()
+	switch r.Method {
+	case "GET":
+		rules, err := h.ListAlertRules(appID, serviceID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rules)
+	case "POST":
+		var req api_model.AlertRuleReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		rule, err := h.CreateAlertRule(tenantEnvID, appID, serviceID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rule)
+	}
+}
+
+func (a *AlertStruct) alertRule(w http.ResponseWriter, r *http.Request, appID, serviceID string) {
+	ruleID := chi.URLParam(r, "rule_id")
+	h := handler.GetAlertRuleHandler()
+	switch r.Method {
+	case "PUT":
+		var req api_model.AlertRuleReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		rule, err := h.UpdateAlertRule(appID, serviceID, ruleID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rule)
+	case "DELETE":
+		if err := h.DeleteAlertRule(appID, serviceID, ruleID); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
+
+// ServiceSLOs lists or creates the slos of the component
+func (a *AlertStruct) ServiceSLOs(w http.ResponseWriter, r *http.Request) {
+	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
+	h := handler.GetSLOHandler()
+	switch r.Method {
+	case "GET":
+		slos, err := h.ListSLOs(service.ServiceID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, slos)
+	case "POST":
+		var req api_model.ServiceSLOReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		slo, err := h.CreateSLO(service.TenantEnvID, service.ServiceID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, slo)
+	}
+}
+
+// ServiceSLO updates or deletes the slo of the component
+func (a *AlertStruct) ServiceSLO(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	sloID := chi.URLParam(r, "slo_id")
+	h := handler.GetSLOHandler()
+	switch r.Method {
+	case "PUT":
+		var req api_model.ServiceSLOReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		slo, err := h.UpdateSLO(serviceID, sloID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, slo)
+	case "DELETE":
+		if err := h.DeleteSLO(serviceID, sloID); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
+
+// ServiceSLOStatus returns the current sli, the remaining error budget and the burn rates of the slo,
+// the releases of the component can be gated on the remaining error budget.
+func (a *AlertStruct) ServiceSLOStatus(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	status, err := handler.GetSLOHandler().GetSLOStatus(serviceID, chi.URLParam(r, "slo_id"))
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, status)
+}
+
+// ServiceLogAlertRules lists or creates the log alert rules of the component
+func (a *AlertStruct) ServiceLogAlertRules(w http.ResponseWriter, r *http.Request) {
+	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
+	h := handler.GetLogAlertRuleHandler()
+	switch r.Method {
+	case "GET":
+		rules, err := h.ListLogAlertRules(service.ServiceID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rules)
+	case "POST":
+		var req api_model.LogAlertRuleReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		rule, err := h.CreateLogAlertRule(service.TenantEnvID, service.ServiceID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rule)
+	}
+}
+
+// ServiceLogAlertRule updates or deletes the log alert rule of the component
+func (a *AlertStruct) ServiceLogAlertRule(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	ruleID := chi.URLParam(r, "rule_id")
+	h := handler.GetLogAlertRuleHandler()
+	switch r.Method {
+	case "PUT":
+		var req api_model.LogAlertRuleReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		rule, err := h.UpdateLogAlertRule(serviceID, ruleID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rule)
+	case "DELETE":
+		if err := h.DeleteLogAlertRule(serviceID, ruleID); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/wutong-paas/wutong/api/handler"
 	"github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
 	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
 	"github.com/wutong-paas/wutong/db/errors"
 	httputil "github.com/wutong-paas/wutong/util/http"
@@ -55,6 +56,10 @@ func (t *TenantEnvStruct) addAutoscalerRule(w http.ResponseWriter, r *http.Reque
 	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
 	req.ServiceID = serviceID
 	if err := handler.GetServiceManager().AddAutoscalerRule(&req); err != nil {
+		if bcode.ErrInvalidAutoscalerRule.Equal(err) {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
 		if err == errors.ErrRecordAlreadyExist {
 			httputil.ReturnError(r, w, 400, err.Error())
 			return
@@ -75,6 +80,10 @@ func (t *TenantEnvStruct) updAutoscalerRule(w http.ResponseWriter, r *http.Reque
 	}
 
 	if err := handler.GetServiceManager().UpdAutoscalerRule(&req); err != nil {
+		if bcode.ErrInvalidAutoscalerRule.Equal(err) {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
 		if err == errors.ErrRecordAlreadyExist {
 			httputil.ReturnError(r, w, 400, err.Error())
 			return
@@ -111,6 +111,10 @@ func GetNotificationEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	for _, v := range res {
+		// the names of the tenant env, node and cluster events are saved with the events
+		if v.Kind != "service" {
+			continue
+		}
 		service, err := db.GetManager().TenantEnvServiceDao().GetServiceByID(v.KindID)
 		if err != nil {
 			if err == gorm.ErrRecordNotFound {
@@ -58,6 +58,18 @@ func (e *EventLogStruct) HistoryLogs(w http.ResponseWriter, r *http.Request) {
 	e.EventlogServerProxy.Proxy(w, r)
 }
 
+// AccessLogs get service gateway access logs, the query parameters rows, rule_id, status and follow
+// are passed to the eventlog, the access logs are streamed as json lines if follow is true.
+// proxy
+func (e *EventLogStruct) AccessLogs(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	serviceAlias := r.Context().Value(ctxutil.ContextKey("service_alias")).(string)
+	//Replace service alias to service id in path
+	r.URL.Path = strings.Replace(r.URL.Path, serviceAlias, serviceID, 1)
+	r.URL.Path = strings.Replace(r.URL.Path, "/v2/", "/", 1)
+	e.EventlogServerProxy.Proxy(w, r)
+}
+
 // LogList GetLogList
 func (e *EventLogStruct) LogList(w http.ResponseWriter, r *http.Request) {
 	// swagger:operation GET  /v2/tenants/{tenant_name}/envs/{tenant_env_name}/services/{service_alias}/log-file v2 logList
@@ -26,11 +26,14 @@ import (
 	"strings"
 
 	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/api/handler"
 	api_model "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
 	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
 	"github.com/wutong-paas/wutong/cmd/api/option"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
 	"github.com/wutong-paas/wutong/mq/client"
 	httputil "github.com/wutong-paas/wutong/util/http"
 	k8svalidation "k8s.io/apimachinery/pkg/util/validation"
@@ -227,6 +230,11 @@ func (g *GatewayStruct) AddTCPRule(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnValidationError(r, w, values)
 		return
 	}
+	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
+	if err := handler.GetTenantEnvManager().CheckTenantEnvQuota(r.Context(), tenantEnv, handler.QuotaRequest{TCPPorts: 1}); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
 	err := h.AddTCPRule(&req)
 	if err != nil {
 		httputil.ReturnError(r, w, 500, fmt.Sprintf("Unexpected error occorred while "+
@@ -319,6 +327,10 @@ func (g *GatewayStruct) RuleConfig(w http.ResponseWriter, r *http.Request) {
 	req.ServiceID = sid
 	req.EventID = eventID
 	if err := handler.GetGatewayHandler().RuleConfig(&req); err != nil {
+		if errors.Cause(err) == bcode.ErrInvalidRuleConfig {
+			httputil.ReturnError(r, w, 400, err.Error())
+			return
+		}
 		httputil.ReturnError(r, w, 500, fmt.Sprintf("Rule id: %s; error update rule config: %v", req.RuleID, err))
 		return
 	}
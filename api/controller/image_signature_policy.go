@@ -0,0 +1,73 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/wutong-paas/wutong/api/handler"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
+	httputil "github.com/wutong-paas/wutong/util/http"
+)
+
+// ImageSignaturePolicyStruct -
+type ImageSignaturePolicyStruct struct {
+}
+
+// ClusterImageSignaturePolicy gets, updates or deletes the image signature policy of the cluster
+func (i *ImageSignaturePolicyStruct) ClusterImageSignaturePolicy(w http.ResponseWriter, r *http.Request) {
+	i.imageSignaturePolicy(w, r, "")
+}
+
+// TenantEnvImageSignaturePolicy gets, updates or deletes the image signature policy of the tenant env,
+// it is applied on top of the cluster policy
+func (i *ImageSignaturePolicyStruct) TenantEnvImageSignaturePolicy(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	i.imageSignaturePolicy(w, r, tenantEnvID)
+}
+
+func (i *ImageSignaturePolicyStruct) imageSignaturePolicy(w http.ResponseWriter, r *http.Request, tenantEnvID string) {
+	h := handler.GetImageSignaturePolicyHandler()
+	switch r.Method {
+	case "GET":
+		policy, err := h.GetImageSignaturePolicy(tenantEnvID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, policy)
+	case "PUT":
+		var req api_model.ImageSignaturePolicy
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		if err := h.UpdateImageSignaturePolicy(tenantEnvID, &req); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, req)
+	case "DELETE":
+		if err := h.DeleteImageSignaturePolicy(tenantEnvID); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
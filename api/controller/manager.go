@@ -51,6 +51,10 @@ type V2Manager interface {
 	api.ApplicationInterface
 	api.HelmAppsInterface
 	api.RegistryAuthSecretInterface
+	api.ImageSignaturePolicyInterface
+	api.ServiceReleaseInterface
+	api.ScheduledScalingInterface
+	api.AlertInterface
 	api.AppStoreVersionInterface
 }
 
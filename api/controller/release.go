@@ -0,0 +1,87 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/wutong-paas/wutong/api/handler"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	httputil "github.com/wutong-paas/wutong/util/http"
+)
+
+// ServiceReleaseStruct -
+type ServiceReleaseStruct struct {
+}
+
+// ServiceReleases lists the releases of the component, or creates a canary or blue-green release
+func (s *ServiceReleaseStruct) ServiceReleases(w http.ResponseWriter, r *http.Request) {
+	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
+	h := handler.GetServiceReleaseHandler()
+	switch r.Method {
+	case "GET":
+		releases, err := h.ListServiceReleases(service.ServiceID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, releases)
+	case "POST":
+		var req api_model.CreateServiceReleaseReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		eventID := r.Context().Value(ctxutil.ContextKey("event_id")).(string)
+		release, err := h.CreateServiceRelease(service, eventID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, release)
+	}
+}
+
+// ServiceRelease gets the release, or promotes or aborts the running release
+func (s *ServiceReleaseStruct) ServiceRelease(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	releaseID := chi.URLParam(r, "release_id")
+	h := handler.GetServiceReleaseHandler()
+	switch r.Method {
+	case "GET":
+		release, err := h.GetServiceRelease(serviceID, releaseID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, release)
+	case "PUT":
+		var req api_model.ServiceReleaseCommandReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		if err := h.CommandServiceRelease(serviceID, releaseID, req.Command); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
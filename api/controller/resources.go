@@ -69,6 +69,10 @@ type V2Routes struct {
 	ApplicationController
 	HelmAppsController
 	RegistryAuthSecretStruct
+	ImageSignaturePolicyStruct
+	ServiceReleaseStruct
+	ScheduledScalingStruct
+	AlertStruct
 	AppStoreVersionStruct
 }
 
@@ -97,11 +101,17 @@ func (v2 *V2Routes) Health(w http.ResponseWriter, r *http.Request) {
 	httputil.ReturnSuccess(r, w, map[string]string{"status": "health", "info": "api service health"})
 }
 
-// AlertManagerWebHook -
+// AlertManagerWebHook receives the alerts of the alertmanager, the alerts are persisted as
+// the notification events and delivered to the notification channels of the tenant envs
 func (v2 *V2Routes) AlertManagerWebHook(w http.ResponseWriter, r *http.Request) {
-	_, err := io.ReadAll(r.Body)
-	if err != nil {
-		httputil.ReturnError(r, w, 400, "")
+	var payload api_model.AlertManagerPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		httputil.ReturnBcodeError(r, w, bcode.ErrInvalidAlertPayload)
+		return
+	}
+	if err := handler.GetAlertHandler().ReceiveAlerts(&payload); err != nil {
+		logrus.Errorf("receive alerts of the alertmanager: %v", err)
+		httputil.ReturnBcodeError(r, w, err)
 		return
 	}
 	httputil.ReturnSuccess(r, w, "")
@@ -472,6 +482,9 @@ func (t *TenantEnvStruct) AddTenantEnv(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnError(r, w, 500, fmt.Sprintf("create tenant env error, %v", err))
 		return
 	}
+	if err := handler.GetTenantEnvManager().SyncTenantEnvQuota(r.Context(), &dbts); err != nil {
+		logrus.Warningf("sync quota of tenant env %s: %v", dbts.Name, err)
+	}
 	rc := make(map[string]string)
 	rc["tenant_env_id"] = id
 	rc["tenant_env_name"] = name
@@ -2274,6 +2287,14 @@ func (t *TenantEnvStruct) SingleTenantEnvResources(w http.ResponseWriter, r *htt
 	statsInfo, _ := handler.GetTenantEnvManager().StatsMemCPU(services)
 	//900ms
 	statsInfo.UUID = tenantEnvID
+	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
+	statsInfo.Quota = handler.GetTenantEnvManager().GetTenantEnvQuota(tenantEnv)
+	usage, err := handler.GetTenantEnvManager().GetTenantEnvQuotaUsage(r.Context(), tenantEnv, "")
+	if err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	statsInfo.Usage = usage
 	httputil.ReturnSuccess(r, w, statsInfo)
 }
 
@@ -0,0 +1,135 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/wutong-paas/wutong/api/handler"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	httputil "github.com/wutong-paas/wutong/util/http"
+)
+
+// ScheduledScalingStruct -
+type ScheduledScalingStruct struct {
+}
+
+// ServiceScheduledScalingRules lists or creates the scheduled scaling rules of the component
+func (s *ScheduledScalingStruct) ServiceScheduledScalingRules(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	s.scheduledScalingRules(w, r, tenantEnvID, serviceID)
+}
+
+// ServiceScheduledScalingRule updates or deletes the scheduled scaling rule of the component
+func (s *ScheduledScalingStruct) ServiceScheduledScalingRule(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	s.scheduledScalingRule(w, r, tenantEnvID, serviceID)
+}
+
+// TenantEnvScheduledScalingRules lists or creates the scheduled scaling rules of all components in the tenant env
+func (s *ScheduledScalingStruct) TenantEnvScheduledScalingRules(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	s.scheduledScalingRules(w, r, tenantEnvID, "")
+}
+
+// TenantEnvScheduledScalingRule updates or deletes the scheduled scaling rule of all components in the tenant env
+func (s *ScheduledScalingStruct) TenantEnvScheduledScalingRule(w http.ResponseWriter, r *http.Request) {
+	tenantEnvID := r.Context().Value(ctxutil.ContextKey("tenant_env_id")).(string)
+	s.scheduledScalingRule(w, r, tenantEnvID, "")
+}
+
+func (s *ScheduledScalingStruct) scheduledScalingRules(w http.ResponseWriter, r *http.Request, tenantEnvID, serviceID string) {
+	h := handler.GetScheduledScalingHandler()
+	switch r.Method {
+	case "GET":
+		rules, err := h.ListScheduledScalingRules(tenantEnvID, serviceID)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rules)
+	case "POST":
+		var req api_model.ScheduledScalingRuleReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		rule, err := h.CreateScheduledScalingRule(tenantEnvID, serviceID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rule)
+	}
+}
+
+func (s *ScheduledScalingStruct) scheduledScalingRule(w http.ResponseWriter, r *http.Request, tenantEnvID, serviceID string) {
+	ruleID := chi.URLParam(r, "rule_id")
+	h := handler.GetScheduledScalingHandler()
+	switch r.Method {
+	case "PUT":
+		var req api_model.ScheduledScalingRuleReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		rule, err := h.UpdateScheduledScalingRule(tenantEnvID, serviceID, ruleID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, rule)
+	case "DELETE":
+		if err := h.DeleteScheduledScalingRule(tenantEnvID, serviceID, ruleID); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, nil)
+	}
+}
+
+// ScaleToZero gets or updates the scale-to-zero setting of the component
+func (s *ScheduledScalingStruct) ScaleToZero(w http.ResponseWriter, r *http.Request) {
+	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
+	h := handler.GetScheduledScalingHandler()
+	switch r.Method {
+	case "GET":
+		setting, err := h.GetScaleToZero(service)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, setting)
+	case "PUT":
+		var req api_model.ScaleToZeroReq
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		eventID := r.Context().Value(ctxutil.ContextKey("event_id")).(string)
+		setting, err := h.UpdateScaleToZero(service, eventID, &req)
+		if err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, setting)
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/api/handler"
 	api_model "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
 	ctxutil "github.com/wutong-paas/wutong/api/util/ctx"
 	"github.com/wutong-paas/wutong/db"
 	dbmodel "github.com/wutong-paas/wutong/db/model"
@@ -73,8 +74,8 @@ func (t *TenantEnvStruct) StartService(w http.ResponseWriter, r *http.Request) {
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
 	sEvent := r.Context().Value(ctxutil.ContextKey("event")).(*dbmodel.ServiceEvent)
 	if service.Kind != "third_party" {
-		if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.Replicas*service.ContainerMemory); err != nil {
-			httputil.ReturnResNotEnough(r, w, sEvent.EventID, err.Error())
+		if err := handler.CheckComponentResource(r.Context(), tenantEnv, handler.ComponentQuotaRequest(service, service.Replicas, service.ContainerCPU, service.ContainerMemory)); err != nil {
+			httputil.ReturnResNotEnoughError(r, w, sEvent.EventID, err)
 			return
 		}
 	}
@@ -176,8 +177,8 @@ func (t *TenantEnvStruct) RestartService(w http.ResponseWriter, r *http.Request)
 
 	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
-	if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.Replicas*service.ContainerMemory); err != nil {
-		httputil.ReturnResNotEnough(r, w, sEvent.EventID, err.Error())
+	if err := handler.CheckComponentResource(r.Context(), tenantEnv, handler.ComponentRolloutQuotaRequest(service, service.Replicas, service.ContainerCPU, service.ContainerMemory)); err != nil {
+		httputil.ReturnResNotEnoughError(r, w, sEvent.EventID, err)
 		return
 	}
 
@@ -255,11 +256,9 @@ func (t *TenantEnvStruct) VerticalService(w http.ResponseWriter, r *http.Request
 	}
 	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
-	if limitMemory != nil {
-		if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.Replicas*(*limitMemory)); err != nil {
-			httputil.ReturnResNotEnough(r, w, sEvent.EventID, err.Error())
-			return
-		}
+	if err := handler.CheckComponentResource(r.Context(), tenantEnv, handler.ComponentRolloutQuotaRequest(service, service.Replicas, *limitCPU, *limitMemory)); err != nil {
+		httputil.ReturnResNotEnoughError(r, w, sEvent.EventID, err)
+		return
 	}
 	verticalTask := &model.VerticalScalingTaskBody{
 		TenantEnvID:            tenantEnvID,
@@ -316,8 +315,8 @@ func (t *TenantEnvStruct) HorizontalService(w http.ResponseWriter, r *http.Reque
 
 	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
-	if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.ContainerMemory*int(replicas)); err != nil {
-		httputil.ReturnResNotEnough(r, w, sEvent.EventID, err.Error())
+	if err := handler.CheckComponentResource(r.Context(), tenantEnv, handler.ComponentQuotaRequest(service, int(replicas), service.ContainerCPU, service.ContainerMemory)); err != nil {
+		httputil.ReturnResNotEnoughError(r, w, sEvent.EventID, err)
 		return
 	}
 
@@ -375,8 +374,10 @@ func (t *TenantEnvStruct) BuildService(w http.ResponseWriter, r *http.Request) {
 
 	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
-	if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.Replicas*service.ContainerMemory); err != nil {
-		httputil.ReturnResNotEnough(r, w, build.EventID, err.Error())
+	quotaReq := handler.ComponentRolloutQuotaRequest(service, service.Replicas, service.ContainerCPU, service.ContainerMemory)
+	quotaReq.Builds = 1
+	if err := handler.CheckComponentResource(r.Context(), tenantEnv, quotaReq); err != nil {
+		httputil.ReturnResNotEnoughError(r, w, build.EventID, err)
 		return
 	}
 
@@ -388,6 +389,42 @@ func (t *TenantEnvStruct) BuildService(w http.ResponseWriter, r *http.Request) {
 	httputil.ReturnSuccess(r, w, res)
 }
 
+// CancelBuild CancelBuild
+// swagger:operation POST /v2/tenants/{tenant_name}/envs/{tenant_env_name}/services/{service_alias}/build/{event_id}/cancel v2 cancelServiceBuild
+//
+// 取消应用构建
+//
+// cancel service build
+//
+// ---
+// consumes:
+// - application/json
+//
+// produces:
+// - application/json
+//
+// responses:
+//
+//	default:
+//	  schema:
+//	    "$ref": "#/responses/commandResponse"
+//	  description: 统一返回格式
+func (t *TenantEnvStruct) CancelBuild(w http.ResponseWriter, r *http.Request) {
+	var req api_model.CancelBuildReq
+	if r.ContentLength > 0 {
+		if ok := httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil); !ok {
+			return
+		}
+	}
+	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
+	eventID := chi.URLParam(r, "event_id")
+	if err := handler.GetOperationHandler().CancelBuild(service, eventID, req.Operator); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
 // BuildList BuildList
 func (t *TenantEnvStruct) BuildList(w http.ResponseWriter, r *http.Request) {
 	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
@@ -457,6 +494,13 @@ func (t *TenantEnvStruct) DeleteBuildVersion(w http.ResponseWriter, r *http.Requ
 				return
 			}
 		}
+		if val.DeliveredType == "image" || val.FinalStatus == "failure" || (val.DeliveredType == "slug" && val.FinalStatus == "success") {
+			for _, file := range val.ArtifactFiles() {
+				if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+					logrus.Warningf("remove artifact %s: %v", file, err)
+				}
+			}
+		}
 	}
 	httputil.ReturnSuccess(r, w, nil)
 
@@ -485,6 +529,32 @@ func (t *TenantEnvStruct) UpdateBuildVersion(w http.ResponseWriter, r *http.Requ
 	httputil.ReturnSuccess(r, w, nil)
 }
 
+// BuildVersionSBOM returns the cyclonedx sbom of the build version
+func (t *TenantEnvStruct) BuildVersionSBOM(w http.ResponseWriter, r *http.Request) {
+	serviceID := r.Context().Value(ctxutil.ContextKey("service_id")).(string)
+	buildVersion := chi.URLParam(r, "build_version")
+	versionInfo, err := db.GetManager().VersionInfoDao().GetVersionByDeployVersion(buildVersion, serviceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			httputil.ReturnBcodeError(r, w, bcode.ErrBuildVersionNotFound)
+			return
+		}
+		httputil.ReturnError(r, w, 500, fmt.Sprintf("get build version error, %v", err))
+		return
+	}
+	if versionInfo.SBOMPath == "" {
+		httputil.ReturnBcodeError(r, w, bcode.ErrSBOMNotFound)
+		return
+	}
+	if _, err := os.Stat(versionInfo.SBOMPath); err != nil {
+		logrus.Warningf("stat sbom %s: %v", versionInfo.SBOMPath, err)
+		httputil.ReturnBcodeError(r, w, bcode.ErrSBOMNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/vnd.cyclonedx+json")
+	http.ServeFile(w, r, versionInfo.SBOMPath)
+}
+
 // BuildVersionInfo -
 func (t *TenantEnvStruct) BuildVersionInfo(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -593,8 +663,8 @@ func (t *TenantEnvStruct) UpgradeService(w http.ResponseWriter, r *http.Request)
 	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
 	if service.Kind != "third_party" {
-		if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.Replicas*service.ContainerMemory); err != nil {
-			httputil.ReturnResNotEnough(r, w, upgradeRequest.EventID, err.Error())
+		if err := handler.CheckComponentResource(r.Context(), tenantEnv, handler.ComponentRolloutQuotaRequest(service, service.Replicas, service.ContainerCPU, service.ContainerMemory)); err != nil {
+			httputil.ReturnResNotEnoughError(r, w, upgradeRequest.EventID, err)
 			return
 		}
 	}
@@ -686,8 +756,8 @@ func (t *TenantEnvStruct) RollBack(w http.ResponseWriter, r *http.Request) {
 
 	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
 	service := r.Context().Value(ctxutil.ContextKey("service")).(*dbmodel.TenantEnvServices)
-	if err := handler.CheckTenantEnvResource(r.Context(), tenantEnv, service.Replicas*service.ContainerMemory); err != nil {
-		httputil.ReturnResNotEnough(r, w, rollbackRequest.EventID, err.Error())
+	if err := handler.CheckComponentResource(r.Context(), tenantEnv, handler.ComponentRolloutQuotaRequest(service, service.Replicas, service.ContainerCPU, service.ContainerMemory)); err != nil {
+		httputil.ReturnResNotEnoughError(r, w, rollbackRequest.EventID, err)
 		return
 	}
 
@@ -721,13 +791,33 @@ func (t *TenantEnvStruct) LimitTenantEnvMemory(w http.ResponseWriter, r *http.Re
 		return
 	}
 	tenantEnv.LimitMemory = lm.LimitMemory
-	if err := db.GetManager().TenantEnvDao().UpdateModel(tenantEnv); err != nil {
+	if err := handler.GetTenantEnvManager().UpdateTenantEnv(tenantEnv); err != nil {
 		httputil.ReturnError(r, w, 500, err.Error())
+		return
 	}
 	httputil.ReturnSuccess(r, w, "success!")
 
 }
 
+// TenantEnvQuota gets or updates the resource quota of the tenant env
+func (t *TenantEnvStruct) TenantEnvQuota(w http.ResponseWriter, r *http.Request) {
+	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
+	switch r.Method {
+	case "GET":
+		httputil.ReturnSuccess(r, w, handler.GetTenantEnvManager().GetTenantEnvQuota(tenantEnv))
+	case "PUT":
+		var req api_model.TenantEnvQuota
+		if !httputil.ValidatorRequestStructAndErrorResponse(r, w, &req, nil) {
+			return
+		}
+		if err := handler.GetTenantEnvManager().UpdateTenantEnvQuota(r.Context(), tenantEnv, &req); err != nil {
+			httputil.ReturnBcodeError(r, w, err)
+			return
+		}
+		httputil.ReturnSuccess(r, w, handler.GetTenantEnvManager().GetTenantEnvQuota(tenantEnv))
+	}
+}
+
 // SourcesInfo -
 type SourcesInfo struct {
 	TenantEnvID     string `json:"tenant_env_id"`
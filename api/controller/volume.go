@@ -177,6 +177,11 @@ func (t *TenantEnvStruct) AddVolume(w http.ResponseWriter, r *http.Request) {
 		httputil.ReturnError(r, w, 400, "volume path is invalid,must begin with /")
 		return
 	}
+	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
+	if err := handler.GetTenantEnvManager().CheckTenantEnvQuota(r.Context(), tenantEnv, handler.QuotaRequest{Storage: handler.VolumeStorage(tsv)}); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
 	if err := handler.GetServiceManager().VolumnVar(tsv, tenantEnvID, "", "add"); err != nil {
 		err.Handle(r, w)
 		return
@@ -416,6 +421,11 @@ func AddVolume(w http.ResponseWriter, r *http.Request) {
 		Mode:               avs.Body.Mode,
 	}
 
+	tenantEnv := r.Context().Value(ctxutil.ContextKey("tenant_env")).(*dbmodel.TenantEnvs)
+	if err := handler.GetTenantEnvManager().CheckTenantEnvQuota(r.Context(), tenantEnv, handler.QuotaRequest{Storage: handler.VolumeStorage(tsv)}); err != nil {
+		httputil.ReturnBcodeError(r, w, err)
+		return
+	}
 	if err := handler.GetServiceManager().VolumnVar(tsv, tenantEnvID, avs.Body.FileContent, "add"); err != nil {
 		err.Handle(r, w)
 		return
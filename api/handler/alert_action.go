@@ -0,0 +1,630 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/notifier"
+	"github.com/wutong-paas/wutong/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// the kinds of the notification events
+	alertKindService   = "service"
+	alertKindTenantEnv = "tenant_env"
+	alertKindNode      = "node"
+	alertKindCluster   = "cluster"
+
+	// the types of the notification events
+	alertEventFiring   = "UnNormal"
+	alertEventResolved = "Normal"
+
+	// secretMask takes the place of the secrets of the notification channels in the responses
+	secretMask = "******"
+
+	alertNotifyTimeout = 30 * time.Second
+	// alertEventMessageMax the max characters of the message and the reason of the notification event
+	alertEventMessageMax = 200
+)
+
+// AlertAction -
+type AlertAction struct {
+	dbmanager      db.Manager
+	kubeClient     kubernetes.Interface
+	repeatInterval time.Duration
+	newNotifier    func(typ string, cfg *notifier.Config) (notifier.Notifier, error)
+	now            func() time.Time
+}
+
+// CreateAlertManager creates alert manager, the firing alerts are sent again after the repeat interval
+func CreateAlertManager(dbmanager db.Manager, kubeClient kubernetes.Interface, repeatInterval time.Duration) *AlertAction {
+	return &AlertAction{
+		dbmanager:      dbmanager,
+		kubeClient:     kubeClient,
+		repeatInterval: repeatInterval,
+		newNotifier:    notifier.New,
+		now:            time.Now,
+	}
+}
+
+// alertTarget the object the alert belongs to
+type alertTarget struct {
+	kind      string
+	kindID    string
+	tenantEnv *dbmodel.TenantEnvs
+	service   *dbmodel.TenantEnvServices
+}
+
+// alertReceivers the enabled channels and the silences of a tenant env
+type alertReceivers struct {
+	channels []*dbmodel.NotificationChannel
+	silences []*dbmodel.AlertSilence
+}
+
+// ReceiveAlerts persists the alerts as the notification events and delivers them
+// to the notification channels of the tenant envs they belong to
+func (a *AlertAction) ReceiveAlerts(payload *apimodel.AlertManagerPayload) error {
+	receivers := make(map[string]*alertReceivers)
+	for _, alert := range payload.Alerts {
+		if alert == nil {
+			continue
+		}
+		if alert.Status == "" {
+			alert.Status = payload.Status
+		}
+		if alert.Status != notifier.StatusFiring && alert.Status != notifier.StatusResolved {
+			return pkgerr.Wrapf(bcode.ErrInvalidAlertPayload, "unknown alert status %q", alert.Status)
+		}
+		if alert.Annotations == nil {
+			alert.Annotations = make(map[string]string)
+		}
+		for key, value := range payload.CommonAnnotations {
+			if _, ok := alert.Annotations[key]; !ok {
+				alert.Annotations[key] = value
+			}
+		}
+		if err := a.receiveAlert(alert, receivers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AlertAction) receiveAlert(alert *apimodel.Alert, receivers map[string]*alertReceivers) error {
+	target := a.resolveTarget(alert.Labels)
+	now := a.now()
+	firing := alert.Status == notifier.StatusFiring
+
+	event, err := a.dbmanager.NotificationEventDao().GetNotificationEventByHash(alertHash(alert))
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == gorm.ErrRecordNotFound {
+		event = nil
+	}
+	notify := shouldNotifyAlert(event, firing, now, a.repeatInterval)
+	isNew := event == nil
+	if isNew {
+		event = &dbmodel.NotificationEvent{Hash: alertHash(alert)}
+	}
+	if firing && (isNew || event.Type != alertEventFiring) {
+		event.Count++
+		// the alert fires again, it needs to be handled again
+		event.IsHandle = false
+	}
+	event.Kind = target.kind
+	event.KindID = target.kindID
+	event.Type = alertEventResolved
+	if firing {
+		event.Type = alertEventFiring
+	}
+	event.Reason = truncateRunes(alert.Labels["alertname"], alertEventMessageMax)
+	event.Message = truncateRunes(alertMessage(alert), alertEventMessageMax)
+	event.LastTime = now
+	if target.tenantEnv != nil {
+		event.TenantEnvName = target.tenantEnv.Name
+	}
+	if target.service != nil {
+		event.ServiceName = target.service.ServiceAlias
+	}
+
+	if notify && target.tenantEnv != nil {
+		rs, err := a.getReceivers(target.tenantEnv.UUID, receivers)
+		if err != nil {
+			return err
+		}
+		if a.deliver(rs, alert, target, now) {
+			event.NotifiedAt = &now
+		}
+	}
+
+	if isNew {
+		return a.dbmanager.NotificationEventDao().AddModel(event)
+	}
+	return a.dbmanager.NotificationEventDao().UpdateModel(event)
+}
+
+// resolveTarget maps the labels of the alert to the component, the tenant env, the node or the cluster
+func (a *AlertAction) resolveTarget(labels map[string]string) *alertTarget {
+	if serviceID := labels["service_id"]; serviceID != "" {
+		if target := a.serviceTarget(serviceID); target != nil {
+			if target.inTenantEnv(labels) {
+				return target
+			}
+			logrus.Warningf("the component %s of the alert is not in the tenant env %s or the namespace %s of the alert, ignore it",
+				serviceID, labels["tenant_env_id"], labels["namespace"])
+		}
+	}
+	if tenantEnvID := labels["tenant_env_id"]; tenantEnvID != "" {
+		tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByUUID(tenantEnvID)
+		if err == nil {
+			return &alertTarget{kind: alertKindTenantEnv, kindID: tenantEnv.UUID, tenantEnv: tenantEnv}
+		}
+		if err != gorm.ErrRecordNotFound {
+			logrus.Warningf("get tenant env %s of the alert: %v", tenantEnvID, err)
+		}
+	}
+	if namespace := labels["namespace"]; namespace != "" {
+		tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByNamespace(namespace)
+		if err == nil {
+			if serviceID := a.podServiceID(namespace, labels["pod"]); serviceID != "" {
+				if target := a.serviceTarget(serviceID); target != nil {
+					return target
+				}
+			}
+			return &alertTarget{kind: alertKindTenantEnv, kindID: tenantEnv.UUID, tenantEnv: tenantEnv}
+		}
+		if err != gorm.ErrRecordNotFound {
+			logrus.Warningf("get tenant env of the namespace %s of the alert: %v", namespace, err)
+		}
+	}
+	if node := labels["node"]; node != "" {
+		return &alertTarget{kind: alertKindNode, kindID: node}
+	}
+	return &alertTarget{kind: alertKindCluster}
+}
+
+// inTenantEnv checks if the component of the target belongs to the tenant env and the namespace
+// of the alert, the service_id of the alert can not route it to the component of another tenant env.
+func (t *alertTarget) inTenantEnv(labels map[string]string) bool {
+	if tenantEnvID := labels["tenant_env_id"]; tenantEnvID != "" && tenantEnvID != t.service.TenantEnvID {
+		return false
+	}
+	if namespace := labels["namespace"]; namespace != "" && (t.tenantEnv == nil || t.tenantEnv.Namespace != namespace) {
+		return false
+	}
+	return true
+}
+
+func (a *AlertAction) serviceTarget(serviceID string) *alertTarget {
+	service, err := a.dbmanager.TenantEnvServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			logrus.Warningf("get component %s of the alert: %v", serviceID, err)
+		}
+		return nil
+	}
+	target := &alertTarget{kind: alertKindService, kindID: service.ServiceID, service: service}
+	tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByUUID(service.TenantEnvID)
+	if err != nil {
+		logrus.Warningf("get tenant env %s of the alert: %v", service.TenantEnvID, err)
+		return target
+	}
+	target.tenantEnv = tenantEnv
+	return target
+}
+
+// podServiceID returns the component id of the pod, or empty if the pod is not a component
+func (a *AlertAction) podServiceID(namespace, podName string) string {
+	if podName == "" || a.kubeClient == nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pod, err := a.kubeClient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		logrus.Debugf("get pod %s/%s of the alert: %v", namespace, podName, err)
+		return ""
+	}
+	return pod.Labels["service_id"]
+}
+
+func (a *AlertAction) getReceivers(tenantEnvID string, receivers map[string]*alertReceivers) (*alertReceivers, error) {
+	if rs, ok := receivers[tenantEnvID]; ok {
+		return rs, nil
+	}
+	channels, err := a.dbmanager.NotificationChannelDao().ListByTenantEnvID(tenantEnvID)
+	if err != nil {
+		return nil, err
+	}
+	silences, err := a.dbmanager.AlertSilenceDao().ListByTenantEnvID(tenantEnvID)
+	if err != nil {
+		return nil, err
+	}
+	rs := &alertReceivers{silences: silences}
+	for _, channel := range channels {
+		if channel.Enabled {
+			rs.channels = append(rs.channels, channel)
+		}
+	}
+	receivers[tenantEnvID] = rs
+	return rs, nil
+}
+
+// deliver sends the alert to the channels in the background, returns false if the
+// alert is silenced or no channel accepts it
+func (a *AlertAction) deliver(rs *alertReceivers, alert *apimodel.Alert, target *alertTarget, now time.Time) bool {
+	for _, silence := range rs.silences {
+		if silence.Mutes(alert.Labels, now) {
+			logrus.Debugf("the alert %s is muted by the silence %s", alert.Labels["alertname"], silence.SilenceID)
+			return false
+		}
+	}
+	msg := alertNotifyMessage(alert, target)
+	delivered := false
+	for _, channel := range rs.channels {
+		if !channel.AcceptSeverity(msg.Severity) {
+			continue
+		}
+		if msg.Status == notifier.StatusResolved && !channel.SendResolved {
+			continue
+		}
+		n, err := a.channelNotifier(channel)
+		if err != nil {
+			logrus.Warningf("notification channel %s: %v", channel.ChannelID, err)
+			continue
+		}
+		delivered = true
+		go func(channelID string, n notifier.Notifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), alertNotifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, msg); err != nil {
+				logrus.Errorf("send the alert %s to the notification channel %s: %v", msg.AlertName, channelID, err)
+			}
+		}(channel.ChannelID, n)
+	}
+	return delivered
+}
+
+func (a *AlertAction) channelNotifier(channel *dbmodel.NotificationChannel) (notifier.Notifier, error) {
+	var cfg notifier.Config
+	if err := json.Unmarshal([]byte(channel.Config), &cfg); err != nil {
+		return nil, err
+	}
+	return a.newNotifier(channel.Type, &cfg)
+}
+
+// shouldNotifyAlert returns true if the alert is new, the status is changed, or the
+// alert keeps firing after the repeat interval and it is not handled
+func shouldNotifyAlert(event *dbmodel.NotificationEvent, firing bool, now time.Time, repeatInterval time.Duration) bool {
+	if event == nil {
+		return firing
+	}
+	wasFiring := event.Type == alertEventFiring
+	if firing != wasFiring {
+		// the resolved alert is sent only if the firing one was sent
+		return firing || event.NotifiedAt != nil
+	}
+	if !firing || event.IsHandle {
+		return false
+	}
+	return event.NotifiedAt == nil || now.Sub(*event.NotifiedAt) >= repeatInterval
+}
+
+// alertHash returns the fingerprint of the alert, or the hash of the labels if the fingerprint is empty
+func alertHash(alert *apimodel.Alert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+	keys := make([]string, 0, len(alert.Labels))
+	for key := range alert.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key + "\xff" + alert.Labels[key] + "\xff"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func alertMessage(alert *apimodel.Alert) string {
+	for _, key := range []string{"summary", "description", "message"} {
+		if value := strings.TrimSpace(alert.Annotations[key]); value != "" {
+			return value
+		}
+	}
+	return alert.Labels["alertname"]
+}
+
+func alertNotifyMessage(alert *apimodel.Alert, target *alertTarget) *notifier.Message {
+	msg := &notifier.Message{
+		Status:       alert.Status,
+		AlertName:    alert.Labels["alertname"],
+		Severity:     alert.Labels["severity"],
+		Summary:      alert.Annotations["summary"],
+		Description:  alert.Annotations["description"],
+		Labels:       alert.Labels,
+		StartsAt:     alert.StartsAt,
+		GeneratorURL: alert.GeneratorURL,
+	}
+	if alert.Status == notifier.StatusResolved {
+		msg.EndsAt = alert.EndsAt
+	}
+	if msg.Summary == "" && msg.Description == "" {
+		msg.Summary = alertMessage(alert)
+	}
+	if target.tenantEnv != nil {
+		msg.TenantEnvName = target.tenantEnv.Name
+	}
+	if target.service != nil {
+		msg.ServiceAlias = target.service.ServiceAlias
+	}
+	return msg
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+// ListNotificationChannels lists the notification channels of the tenant env
+func (a *AlertAction) ListNotificationChannels(tenantEnvID string) ([]*apimodel.NotificationChannel, error) {
+	channels, err := a.dbmanager.NotificationChannelDao().ListByTenantEnvID(tenantEnvID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apimodel.NotificationChannel, 0, len(channels))
+	for _, channel := range channels {
+		result = append(result, notificationChannelResponse(channel))
+	}
+	return result, nil
+}
+
+// CreateNotificationChannel creates the notification channel, the config is validated
+func (a *AlertAction) CreateNotificationChannel(tenantEnvID string, req *apimodel.NotificationChannelReq) (*apimodel.NotificationChannel, error) {
+	channel := &dbmodel.NotificationChannel{
+		ChannelID:   util.NewUUID(),
+		TenantEnvID: tenantEnvID,
+	}
+	if err := a.setNotificationChannel(channel, req); err != nil {
+		return nil, err
+	}
+	if err := a.dbmanager.NotificationChannelDao().AddModel(channel); err != nil {
+		return nil, err
+	}
+	return notificationChannelResponse(channel), nil
+}
+
+// UpdateNotificationChannel updates the notification channel, the empty or masked
+// secret and password keep the old ones
+func (a *AlertAction) UpdateNotificationChannel(tenantEnvID, channelID string, req *apimodel.NotificationChannelReq) (*apimodel.NotificationChannel, error) {
+	channel, err := a.getNotificationChannel(tenantEnvID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	var old notifier.Config
+	_ = json.Unmarshal([]byte(channel.Config), &old)
+	if req.Config.Secret == "" || req.Config.Secret == secretMask {
+		req.Config.Secret = old.Secret
+	}
+	if req.Config.Password == "" || req.Config.Password == secretMask {
+		req.Config.Password = old.Password
+	}
+	if err := a.setNotificationChannel(channel, req); err != nil {
+		return nil, err
+	}
+	if err := a.dbmanager.NotificationChannelDao().UpdateModel(channel); err != nil {
+		return nil, err
+	}
+	return notificationChannelResponse(channel), nil
+}
+
+// DeleteNotificationChannel deletes the notification channel
+func (a *AlertAction) DeleteNotificationChannel(tenantEnvID, channelID string) error {
+	if _, err := a.getNotificationChannel(tenantEnvID, channelID); err != nil {
+		return err
+	}
+	return a.dbmanager.NotificationChannelDao().DeleteByChannelID(channelID)
+}
+
+// TestNotificationChannel sends a test message to the notification channel and waits for the result
+func (a *AlertAction) TestNotificationChannel(tenantEnvID, channelID string) error {
+	channel, err := a.getNotificationChannel(tenantEnvID, channelID)
+	if err != nil {
+		return err
+	}
+	n, err := a.channelNotifier(channel)
+	if err != nil {
+		return pkgerr.Wrapf(bcode.ErrInvalidNotificationChannel, "%v", err)
+	}
+	msg := &notifier.Message{
+		Status:    notifier.StatusFiring,
+		AlertName: "TestNotification",
+		Severity:  "info",
+		Summary:   "This is a test message of the notification channel " + channel.Name,
+		StartsAt:  a.now(),
+	}
+	if tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByUUID(tenantEnvID); err == nil {
+		msg.TenantEnvName = tenantEnv.Name
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), alertNotifyTimeout)
+	defer cancel()
+	if err := n.Notify(ctx, msg); err != nil {
+		return pkgerr.Wrapf(bcode.ErrNotificationFailed, "%v", err)
+	}
+	return nil
+}
+
+func (a *AlertAction) getNotificationChannel(tenantEnvID, channelID string) (*dbmodel.NotificationChannel, error) {
+	channel, err := a.dbmanager.NotificationChannelDao().GetByChannelID(channelID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrNotificationChannelNotFound
+		}
+		return nil, err
+	}
+	if channel.TenantEnvID != tenantEnvID {
+		return nil, bcode.ErrNotificationChannelNotFound
+	}
+	return channel, nil
+}
+
+func (a *AlertAction) setNotificationChannel(channel *dbmodel.NotificationChannel, req *apimodel.NotificationChannelReq) error {
+	if _, err := a.newNotifier(req.Type, &req.Config); err != nil {
+		return pkgerr.Wrapf(bcode.ErrInvalidNotificationChannel, "%v", err)
+	}
+	cfg, err := json.Marshal(req.Config)
+	if err != nil {
+		return err
+	}
+	var severities []string
+	for _, s := range req.Severities {
+		if s = strings.TrimSpace(s); s != "" {
+			severities = append(severities, s)
+		}
+	}
+	channel.Name = req.Name
+	channel.Type = req.Type
+	channel.Config = string(cfg)
+	channel.Severities = strings.Join(severities, ",")
+	channel.SendResolved = req.SendResolved
+	channel.Enabled = req.Enabled
+	return nil
+}
+
+func notificationChannelResponse(channel *dbmodel.NotificationChannel) *apimodel.NotificationChannel {
+	var cfg notifier.Config
+	_ = json.Unmarshal([]byte(channel.Config), &cfg)
+	if cfg.Secret != "" {
+		cfg.Secret = secretMask
+	}
+	if cfg.Password != "" {
+		cfg.Password = secretMask
+	}
+	res := &apimodel.NotificationChannel{
+		ChannelID:    channel.ChannelID,
+		TenantEnvID:  channel.TenantEnvID,
+		Name:         channel.Name,
+		Type:         channel.Type,
+		Config:       cfg,
+		SendResolved: channel.SendResolved,
+		Enabled:      channel.Enabled,
+		CreateTime:   channel.CreatedAt,
+	}
+	if channel.Severities != "" {
+		res.Severities = strings.Split(channel.Severities, ",")
+	}
+	return res
+}
+
+// ListAlertSilences lists the alert silences of the tenant env
+func (a *AlertAction) ListAlertSilences(tenantEnvID string) ([]*apimodel.AlertSilence, error) {
+	silences, err := a.dbmanager.AlertSilenceDao().ListByTenantEnvID(tenantEnvID)
+	if err != nil {
+		return nil, err
+	}
+	now := a.now()
+	result := make([]*apimodel.AlertSilence, 0, len(silences))
+	for _, silence := range silences {
+		result = append(result, alertSilenceResponse(silence, now))
+	}
+	return result, nil
+}
+
+// CreateAlertSilence creates the alert silence, the regex matchers are validated
+func (a *AlertAction) CreateAlertSilence(tenantEnvID string, req *apimodel.AlertSilenceReq) (*apimodel.AlertSilence, error) {
+	for i := range req.Matchers {
+		if err := req.Matchers[i].Validate(); err != nil {
+			return nil, pkgerr.Wrapf(bcode.ErrInvalidAlertSilence, "%v", err)
+		}
+	}
+	now := a.now()
+	if req.StartsAt.IsZero() {
+		req.StartsAt = now
+	}
+	if !req.EndsAt.After(req.StartsAt) || !req.EndsAt.After(now) {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidAlertSilence, "the end time should be after the start time and now")
+	}
+	matchers, err := json.Marshal(req.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	silence := &dbmodel.AlertSilence{
+		SilenceID:   util.NewUUID(),
+		TenantEnvID: tenantEnvID,
+		Matchers:    string(matchers),
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		Comment:     req.Comment,
+		CreatedBy:   req.CreatedBy,
+	}
+	if err := a.dbmanager.AlertSilenceDao().AddModel(silence); err != nil {
+		return nil, err
+	}
+	return alertSilenceResponse(silence, now), nil
+}
+
+// DeleteAlertSilence deletes the alert silence
+func (a *AlertAction) DeleteAlertSilence(tenantEnvID, silenceID string) error {
+	silence, err := a.dbmanager.AlertSilenceDao().GetBySilenceID(silenceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bcode.ErrAlertSilenceNotFound
+		}
+		return err
+	}
+	if silence.TenantEnvID != tenantEnvID {
+		return bcode.ErrAlertSilenceNotFound
+	}
+	return a.dbmanager.AlertSilenceDao().DeleteBySilenceID(silenceID)
+}
+
+func alertSilenceResponse(silence *dbmodel.AlertSilence, now time.Time) *apimodel.AlertSilence {
+	matchers, _ := silence.MatcherList()
+	return &apimodel.AlertSilence{
+		SilenceID:   silence.SilenceID,
+		TenantEnvID: silence.TenantEnvID,
+		Matchers:    matchers,
+		StartsAt:    silence.StartsAt,
+		EndsAt:      silence.EndsAt,
+		Comment:     silence.Comment,
+		CreatedBy:   silence.CreatedBy,
+		Active:      silence.IsActive(now),
+	}
+}
@@ -0,0 +1,162 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/notifier"
+)
+
+func TestShouldNotifyAlert(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Hour)
+	old := now.Add(-5 * time.Hour)
+	tests := []struct {
+		name   string
+		event  *dbmodel.NotificationEvent
+		firing bool
+		want   bool
+	}{
+		{name: "new firing", firing: true, want: true},
+		{name: "new resolved", firing: false, want: false},
+		{name: "repeat within interval", event: &dbmodel.NotificationEvent{Type: alertEventFiring, NotifiedAt: &recent}, firing: true, want: false},
+		{name: "repeat after interval", event: &dbmodel.NotificationEvent{Type: alertEventFiring, NotifiedAt: &old}, firing: true, want: true},
+		{name: "handled", event: &dbmodel.NotificationEvent{Type: alertEventFiring, NotifiedAt: &old, IsHandle: true}, firing: true, want: false},
+		{name: "never sent", event: &dbmodel.NotificationEvent{Type: alertEventFiring}, firing: true, want: true},
+		{name: "resolved after sent", event: &dbmodel.NotificationEvent{Type: alertEventFiring, NotifiedAt: &recent}, firing: false, want: true},
+		{name: "resolved never sent", event: &dbmodel.NotificationEvent{Type: alertEventFiring}, firing: false, want: false},
+		{name: "resolved again", event: &dbmodel.NotificationEvent{Type: alertEventResolved, NotifiedAt: &recent}, firing: false, want: false},
+		{name: "fires again", event: &dbmodel.NotificationEvent{Type: alertEventResolved, NotifiedAt: &recent, IsHandle: true}, firing: true, want: true},
+	}
+	for _, tc := range tests {
+		if got := shouldNotifyAlert(tc.event, tc.firing, now, 4*time.Hour); got != tc.want {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestAlertHash(t *testing.T) {
+	a := &apimodel.Alert{Labels: map[string]string{"alertname": "foo", "service_id": "bar"}}
+	b := &apimodel.Alert{Labels: map[string]string{"service_id": "bar", "alertname": "foo"}}
+	if alertHash(a) != alertHash(b) {
+		t.Error("the hash should not depend on the order of the labels")
+	}
+	b.Labels["pod"] = "baz"
+	if alertHash(a) == alertHash(b) {
+		t.Error("the hash should depend on the labels")
+	}
+	a.Fingerprint = "0123456789abcdef"
+	if alertHash(a) != a.Fingerprint {
+		t.Error("the fingerprint should be used if it is set")
+	}
+}
+
+type fakeNotifier struct {
+	sent chan string
+	name string
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, msg *notifier.Message) error {
+	f.sent <- f.name
+	return nil
+}
+
+func TestAlertTargetInTenantEnv(t *testing.T) {
+	target := &alertTarget{
+		kind:      alertKindService,
+		service:   &dbmodel.TenantEnvServices{ServiceID: "sid", TenantEnvID: "tid"},
+		tenantEnv: &dbmodel.TenantEnvs{UUID: "tid", Namespace: "ns"},
+	}
+	tests := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{labels: map[string]string{"service_id": "sid"}, want: true},
+		{labels: map[string]string{"service_id": "sid", "tenant_env_id": "tid", "namespace": "ns"}, want: true},
+		{labels: map[string]string{"service_id": "sid", "tenant_env_id": "other"}, want: false},
+		{labels: map[string]string{"service_id": "sid", "namespace": "other"}, want: false},
+	}
+	for _, tc := range tests {
+		if got := target.inTenantEnv(tc.labels); got != tc.want {
+			t.Errorf("inTenantEnv(%v) = %v, want %v", tc.labels, got, tc.want)
+		}
+	}
+}
+
+func TestDeliverAlert(t *testing.T) {
+	sent := make(chan string, 10)
+	a := &AlertAction{
+		newNotifier: func(typ string, cfg *notifier.Config) (notifier.Notifier, error) {
+			return &fakeNotifier{sent: sent, name: cfg.URL}, nil
+		},
+	}
+	now := time.Now()
+	rs := &alertReceivers{
+		channels: []*dbmodel.NotificationChannel{
+			{ChannelID: "all", Config: `{"url":"all"}`, SendResolved: true},
+			{ChannelID: "critical", Config: `{"url":"critical"}`, Severities: "critical"},
+		},
+		silences: []*dbmodel.AlertSilence{
+			{Matchers: `[{"name":"alertname","value":"Muted.*","is_regex":true}]`, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		},
+	}
+	target := &alertTarget{kind: alertKindService}
+	recv := func(n int) map[string]bool {
+		got := make(map[string]bool)
+		for i := 0; i < n; i++ {
+			select {
+			case name := <-sent:
+				got[name] = true
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for the notifications")
+			}
+		}
+		return got
+	}
+
+	firing := &apimodel.Alert{Status: notifier.StatusFiring, Labels: map[string]string{"alertname": "HighErrorRate", "severity": "critical"}}
+	if !a.deliver(rs, firing, target, now) {
+		t.Fatal("the critical alert should be delivered")
+	}
+	if got := recv(2); !got["all"] || !got["critical"] {
+		t.Errorf("want all channels, got %v", got)
+	}
+
+	resolved := &apimodel.Alert{Status: notifier.StatusResolved, Labels: map[string]string{"alertname": "HighErrorRate", "severity": "warning"}}
+	if !a.deliver(rs, resolved, target, now) {
+		t.Fatal("the resolved alert should be delivered")
+	}
+	if got := recv(1); !got["all"] {
+		t.Errorf("want the channel sending resolved alerts, got %v", got)
+	}
+
+	muted := &apimodel.Alert{Status: notifier.StatusFiring, Labels: map[string]string{"alertname": "MutedAlert", "severity": "critical"}}
+	if a.deliver(rs, muted, target, now) {
+		t.Error("the silenced alert should not be delivered")
+	}
+	if !a.deliver(rs, muted, target, now.Add(2*time.Hour)) {
+		t.Error("the alert should be delivered after the silence ends")
+	}
+	recv(2)
+}
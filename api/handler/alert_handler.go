@@ -0,0 +1,39 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	apimodel "github.com/wutong-paas/wutong/api/model"
+)
+
+// AlertHandler receives the alerts of the alertmanager and delivers them to the
+// notification channels of the tenant envs
+type AlertHandler interface {
+	ReceiveAlerts(payload *apimodel.AlertManagerPayload) error
+
+	ListNotificationChannels(tenantEnvID string) ([]*apimodel.NotificationChannel, error)
+	CreateNotificationChannel(tenantEnvID string, req *apimodel.NotificationChannelReq) (*apimodel.NotificationChannel, error)
+	UpdateNotificationChannel(tenantEnvID, channelID string, req *apimodel.NotificationChannelReq) (*apimodel.NotificationChannel, error)
+	DeleteNotificationChannel(tenantEnvID, channelID string) error
+	TestNotificationChannel(tenantEnvID, channelID string) error
+
+	ListAlertSilences(tenantEnvID string) ([]*apimodel.AlertSilence, error)
+	CreateAlertSilence(tenantEnvID string, req *apimodel.AlertSilenceReq) (*apimodel.AlertSilence, error)
+	DeleteAlertSilence(tenantEnvID, silenceID string) error
+}
@@ -0,0 +1,343 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	monitorv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitorclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/alerting"
+	"github.com/wutong-paas/wutong/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// alertRuleReservedLabels the labels of the alerts set by the platform, the alerts
+// are routed to the components and the tenant envs by them
+var alertRuleReservedLabels = map[string]bool{
+	"alertname":             true,
+	"severity":              true,
+	"tenant_env_id":         true,
+	"app_id":                true,
+	"service_id":            true,
+	alerting.NamespaceLabel: true,
+}
+
+// AlertRuleAction -
+type AlertRuleAction struct {
+	dbmanager     db.Manager
+	monitorClient monitorclient.Interface
+}
+
+// CreateAlertRuleManager creates alert rule manager
+func CreateAlertRuleManager(dbmanager db.Manager, monitorClient monitorclient.Interface) *AlertRuleAction {
+	return &AlertRuleAction{
+		dbmanager:     dbmanager,
+		monitorClient: monitorClient,
+	}
+}
+
+// ListAlertRules lists the alert rules of the application or the component
+func (a *AlertRuleAction) ListAlertRules(appID, serviceID string) ([]*apimodel.AlertRule, error) {
+	rules, err := a.dbmanager.AlertRuleDao().ListByScope(appID, serviceID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apimodel.AlertRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, alertRuleResponse(rule))
+	}
+	return result, nil
+}
+
+// CreateAlertRule validates and creates the alert rule, then applies the rules of the scope
+func (a *AlertRuleAction) CreateAlertRule(tenantEnvID, appID, serviceID string, req *apimodel.AlertRuleReq) (*apimodel.AlertRule, error) {
+	if err := a.checkAlertRule(appID, serviceID, "", req); err != nil {
+		return nil, err
+	}
+	rule := &dbmodel.AlertRule{
+		RuleID:      util.NewUUID(),
+		TenantEnvID: tenantEnvID,
+		AppID:       appID,
+		ServiceID:   serviceID,
+	}
+	setAlertRule(rule, req)
+	if err := a.dbmanager.AlertRuleDao().AddModel(rule); err != nil {
+		return nil, err
+	}
+	if err := a.SyncAlertRules(tenantEnvID, appID, serviceID); err != nil {
+		return nil, err
+	}
+	return alertRuleResponse(rule), nil
+}
+
+// UpdateAlertRule validates and updates the alert rule, then applies the rules of the scope
+func (a *AlertRuleAction) UpdateAlertRule(appID, serviceID, ruleID string, req *apimodel.AlertRuleReq) (*apimodel.AlertRule, error) {
+	rule, err := a.getAlertRule(appID, serviceID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkAlertRule(appID, serviceID, ruleID, req); err != nil {
+		return nil, err
+	}
+	setAlertRule(rule, req)
+	if err := a.dbmanager.AlertRuleDao().UpdateModel(rule); err != nil {
+		return nil, err
+	}
+	if err := a.SyncAlertRules(rule.TenantEnvID, appID, serviceID); err != nil {
+		return nil, err
+	}
+	return alertRuleResponse(rule), nil
+}
+
+// DeleteAlertRule deletes the alert rule, then applies the rules of the scope
+func (a *AlertRuleAction) DeleteAlertRule(appID, serviceID, ruleID string) error {
+	rule, err := a.getAlertRule(appID, serviceID, ruleID)
+	if err != nil {
+		return err
+	}
+	if err := a.dbmanager.AlertRuleDao().DeleteByRuleID(ruleID); err != nil {
+		return err
+	}
+	return a.SyncAlertRules(rule.TenantEnvID, appID, serviceID)
+}
+
+// SyncAlertRules renders the enabled alert rules of the application or the component
+// into a PrometheusRule, the PrometheusRule is deleted if there is no enabled rule.
+func (a *AlertRuleAction) SyncAlertRules(tenantEnvID, appID, serviceID string) error {
+	tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByUUID(tenantEnvID)
+	if err != nil {
+		return pkgerr.Wrapf(err, "get tenant env %s", tenantEnvID)
+	}
+	rules, err := a.dbmanager.AlertRuleDao().ListByScope(appID, serviceID)
+	if err != nil {
+		return err
+	}
+	promRule, err := renderAlertRules(tenantEnv.Namespace, tenantEnvID, appID, serviceID, rules)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if promRule == nil {
+		return deletePrometheusRule(ctx, a.monitorClient, tenantEnv.Namespace, alertRuleResourceName(appID, serviceID))
+	}
+	return ensurePrometheusRule(ctx, a.monitorClient, promRule)
+}
+
+func (a *AlertRuleAction) getAlertRule(appID, serviceID, ruleID string) (*dbmodel.AlertRule, error) {
+	rule, err := a.dbmanager.AlertRuleDao().GetByRuleID(ruleID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrAlertRuleNotFound
+		}
+		return nil, err
+	}
+	if rule.AppID != appID || rule.ServiceID != serviceID {
+		return nil, bcode.ErrAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+// checkAlertRule validates the rule with the prometheus parser, the name should be unique in the scope
+func (a *AlertRuleAction) checkAlertRule(appID, serviceID, ruleID string, req *apimodel.AlertRuleReq) error {
+	if err := validateAlertRule(req); err != nil {
+		return pkgerr.Wrapf(bcode.ErrInvalidAlertRule, "%v", err)
+	}
+	rules, err := a.dbmanager.AlertRuleDao().ListByScope(appID, serviceID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Name == req.Name && rule.RuleID != ruleID {
+			return bcode.ErrAlertRuleNameExists
+		}
+	}
+	return nil
+}
+
+func validateAlertRule(req *apimodel.AlertRuleReq) error {
+	if err := alerting.ValidateExpr(req.Expr); err != nil {
+		return fmt.Errorf("invalid expr: %v", err)
+	}
+	if err := alerting.ValidateDuration(req.For); err != nil {
+		return fmt.Errorf("invalid for: %v", err)
+	}
+	for name := range req.Labels {
+		if err := alerting.ValidateLabelName(name); err != nil {
+			return err
+		}
+		if alertRuleReservedLabels[name] {
+			return fmt.Errorf("the label %s is set by the platform", name)
+		}
+	}
+	return nil
+}
+
+func setAlertRule(rule *dbmodel.AlertRule, req *apimodel.AlertRuleReq) {
+	rule.Name = req.Name
+	rule.Expr = req.Expr
+	rule.For = req.For
+	rule.Severity = req.Severity
+	rule.Summary = req.Summary
+	rule.Description = req.Description
+	rule.Labels = ""
+	if len(req.Labels) > 0 {
+		labels, _ := json.Marshal(req.Labels)
+		rule.Labels = string(labels)
+	}
+	rule.Enabled = req.Enabled
+}
+
+func alertRuleResponse(rule *dbmodel.AlertRule) *apimodel.AlertRule {
+	return &apimodel.AlertRule{
+		RuleID:      rule.RuleID,
+		TenantEnvID: rule.TenantEnvID,
+		AppID:       rule.AppID,
+		ServiceID:   rule.ServiceID,
+		Name:        rule.Name,
+		Expr:        rule.Expr,
+		For:         rule.For,
+		Severity:    rule.Severity,
+		Summary:     rule.Summary,
+		Description: rule.Description,
+		Labels:      rule.LabelMap(),
+		Enabled:     rule.Enabled,
+		CreateTime:  rule.CreatedAt,
+	}
+}
+
+// alertRuleResourceName returns the name of the PrometheusRule of the application or the component
+func alertRuleResourceName(appID, serviceID string) string {
+	if serviceID != "" {
+		return "alert-rules-" + serviceID
+	}
+	return "alert-rules-app-" + appID
+}
+
+// renderAlertRules renders the enabled rules into a PrometheusRule, returns nil if there
+// is no enabled rule. The expressions only select the series of the namespace, and the
+// alerts are labeled with the tenant env and the component or the application. The
+// service_id of the application rules is pinned to the empty value, which removes the
+// service_id of the series, so that the alerts are not routed to a component.
+func renderAlertRules(namespace, tenantEnvID, appID, serviceID string, rules []*dbmodel.AlertRule) (*monitorv1.PrometheusRule, error) {
+	scopeLabels := map[string]string{"tenant_env_id": tenantEnvID}
+	if serviceID != "" {
+		scopeLabels["service_id"] = serviceID
+	} else {
+		scopeLabels["app_id"] = appID
+		scopeLabels["service_id"] = ""
+	}
+	var promRules []monitorv1.Rule
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		expr, err := alerting.EnforceNamespace(rule.Expr, namespace)
+		if err != nil {
+			return nil, pkgerr.Wrapf(bcode.ErrInvalidAlertRule, "rule %s: %v", rule.Name, err)
+		}
+		labels := rule.LabelMap()
+		for key, value := range scopeLabels {
+			labels[key] = value
+		}
+		labels["severity"] = rule.Severity
+		promRule := monitorv1.Rule{
+			Alert:       rule.Name,
+			Expr:        intstr.FromString(expr),
+			Labels:      labels,
+			Annotations: make(map[string]string),
+		}
+		if rule.For != "" {
+			d := monitorv1.Duration(rule.For)
+			promRule.For = &d
+		}
+		if rule.Summary != "" {
+			promRule.Annotations["summary"] = rule.Summary
+		}
+		if rule.Description != "" {
+			promRule.Annotations["description"] = rule.Description
+		}
+		promRules = append(promRules, promRule)
+	}
+	if len(promRules) == 0 {
+		return nil, nil
+	}
+	name := alertRuleResourceName(appID, serviceID)
+	resourceLabels := map[string]string{"creator": "Wutong"}
+	for key, value := range scopeLabels {
+		if value != "" {
+			resourceLabels[key] = value
+		}
+	}
+	return &monitorv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    resourceLabels,
+		},
+		Spec: monitorv1.PrometheusRuleSpec{
+			Groups: []monitorv1.RuleGroup{
+				{
+					Name:  name,
+					Rules: promRules,
+				},
+			},
+		},
+	}, nil
+}
+
+// ensurePrometheusRule creates or updates the PrometheusRule
+func ensurePrometheusRule(ctx context.Context, clientset monitorclient.Interface, promRule *monitorv1.PrometheusRule) error {
+	old, err := clientset.MonitoringV1().PrometheusRules(promRule.Namespace).Get(ctx, promRule.Name, metav1.GetOptions{})
+	if err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return pkgerr.Wrapf(err, "get prometheus rule %s/%s", promRule.Namespace, promRule.Name)
+		}
+		if _, err := clientset.MonitoringV1().PrometheusRules(promRule.Namespace).Create(ctx, promRule, metav1.CreateOptions{}); err != nil {
+			return pkgerr.Wrapf(err, "create prometheus rule %s/%s", promRule.Namespace, promRule.Name)
+		}
+		return nil
+	}
+	promRule.ResourceVersion = old.ResourceVersion
+	if _, err := clientset.MonitoringV1().PrometheusRules(promRule.Namespace).Update(ctx, promRule, metav1.UpdateOptions{}); err != nil {
+		return pkgerr.Wrapf(err, "update prometheus rule %s/%s", promRule.Namespace, promRule.Name)
+	}
+	return nil
+}
+
+// deletePrometheusRule deletes the PrometheusRule, it is ok if the PrometheusRule does not exist
+func deletePrometheusRule(ctx context.Context, clientset monitorclient.Interface, namespace, name string) error {
+	err := clientset.MonitoringV1().PrometheusRules(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return pkgerr.Wrapf(err, "delete prometheus rule %s/%s", namespace, name)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateAlertRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   apimodel.AlertRuleReq
+		valid bool
+	}{
+		{name: "valid", req: apimodel.AlertRuleReq{Expr: "up == 0", For: "5m", Labels: map[string]string{"team": "foo"}}, valid: true},
+		{name: "invalid expr", req: apimodel.AlertRuleReq{Expr: "up ==", For: "5m"}},
+		{name: "range vector", req: apimodel.AlertRuleReq{Expr: "up[5m]"}},
+		{name: "invalid for", req: apimodel.AlertRuleReq{Expr: "up == 0", For: "5 minutes"}},
+		{name: "reserved label", req: apimodel.AlertRuleReq{Expr: "up == 0", Labels: map[string]string{"service_id": "foo"}}},
+		{name: "invalid label", req: apimodel.AlertRuleReq{Expr: "up == 0", Labels: map[string]string{"team-name": "foo"}}},
+	}
+	for _, tc := range tests {
+		if err := validateAlertRule(&tc.req); (err == nil) != tc.valid {
+			t.Errorf("%s: want valid %v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestRenderAlertRules(t *testing.T) {
+	rules := []*dbmodel.AlertRule{
+		{
+			Name:     "HighErrorRate",
+			Expr:     `rate(http_requests_total{namespace="other",status=~"5.."}[5m]) > 1`,
+			For:      "5m",
+			Severity: "critical",
+			Summary:  "the error rate is {{ $value }}",
+			Labels:   `{"team":"foo"}`,
+			Enabled:  true,
+		},
+		{Name: "Disabled", Expr: "up == 0", Severity: "info"},
+	}
+	promRule, err := renderAlertRules("ns", "tid", "", "sid", rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if promRule.Name != "alert-rules-sid" || promRule.Namespace != "ns" || promRule.Labels["service_id"] != "sid" {
+		t.Errorf("unexpected metadata %+v", promRule.ObjectMeta)
+	}
+	if len(promRule.Spec.Groups) != 1 || len(promRule.Spec.Groups[0].Rules) != 1 {
+		t.Fatalf("want only the enabled rule, got %+v", promRule.Spec.Groups)
+	}
+	rule := promRule.Spec.Groups[0].Rules[0]
+	if want := `rate(http_requests_total{namespace="ns",status=~"5.."}[5m]) > 1`; rule.Expr.String() != want {
+		t.Errorf("want expr %s, got %s", want, rule.Expr.String())
+	}
+	if rule.Labels["team"] != "foo" || rule.Labels["service_id"] != "sid" || rule.Labels["tenant_env_id"] != "tid" || rule.Labels["severity"] != "critical" {
+		t.Errorf("unexpected labels %v", rule.Labels)
+	}
+	if rule.For == nil || *rule.For != "5m" || rule.Annotations["summary"] == "" {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	promRule, err = renderAlertRules("ns", "tid", "aid", "", rules[1:])
+	if err != nil || promRule != nil {
+		t.Errorf("want nil for no enabled rule, got %v, %v", promRule, err)
+	}
+}
+
+func TestEnsurePrometheusRule(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	ctx := context.Background()
+	rules := []*dbmodel.AlertRule{{Name: "Down", Expr: "up == 0", Severity: "critical", Enabled: true}}
+	for i := 0; i < 2; i++ {
+		promRule, _ := renderAlertRules("ns", "tid", "aid", "", rules)
+		if err := ensurePrometheusRule(ctx, clientset, promRule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := clientset.MonitoringV1().PrometheusRules("ns").Get(ctx, "alert-rules-app-aid", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the empty service_id removes the service_id of the series
+	if labels := got.Spec.Groups[0].Rules[0].Labels; labels["app_id"] != "aid" || labels["service_id"] != "" {
+		t.Errorf("unexpected labels %v", labels)
+	} else if _, exists := labels["service_id"]; !exists {
+		t.Errorf("want the service_id pinned to the empty value, got %v", labels)
+	}
+	if _, exists := got.Labels["service_id"]; exists {
+		t.Errorf("unexpected resource labels %v", got.Labels)
+	}
+	if err := deletePrometheusRule(ctx, clientset, "ns", "alert-rules-app-aid"); err != nil {
+		t.Fatal(err)
+	}
+	if err := deletePrometheusRule(ctx, clientset, "ns", "alert-rules-app-aid"); err != nil {
+		t.Errorf("deleting the missing prometheus rule should be ok, got %v", err)
+	}
+}
@@ -0,0 +1,34 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	apimodel "github.com/wutong-paas/wutong/api/model"
+)
+
+// AlertRuleHandler the PromQL alerting rules of the components and the applications.
+// The rules of a component have the service id, the rules of an application have the
+// app id and an empty service id.
+type AlertRuleHandler interface {
+	ListAlertRules(appID, serviceID string) ([]*apimodel.AlertRule, error)
+	CreateAlertRule(tenantEnvID, appID, serviceID string, req *apimodel.AlertRuleReq) (*apimodel.AlertRule, error)
+	UpdateAlertRule(appID, serviceID, ruleID string, req *apimodel.AlertRuleReq) (*apimodel.AlertRule, error)
+	DeleteAlertRule(appID, serviceID, ruleID string) error
+	SyncAlertRules(tenantEnvID, appID, serviceID string) error
+}
@@ -282,11 +282,20 @@ func (a *ApplicationAction) GetAppByID(appID string) (*dbmodel.Application, erro
 
 // DeleteApp -
 func (a *ApplicationAction) DeleteApp(ctx context.Context, app *dbmodel.Application) error {
+	var err error
 	if app.AppType == dbmodel.AppTypeHelm {
-		return a.deleteHelmApp(ctx, app)
+		err = a.deleteHelmApp(ctx, app)
+	} else {
+		err = a.deleteWutongApp(app)
 	}
-
-	return a.deleteWutongApp(app)
+	if err != nil {
+		return err
+	}
+	// the alert rules are deleted with the app, remove the prometheus rule of them
+	if err := GetAlertRuleHandler().SyncAlertRules(app.TenantEnvID, app.AppID, ""); err != nil {
+		logrus.Warningf("remove the alert rules of the app %s: %v", app.AppID, err)
+	}
+	return nil
 }
 
 func (a *ApplicationAction) deleteWutongApp(app *dbmodel.Application) error {
@@ -349,6 +358,11 @@ func (a *ApplicationAction) deleteApp(tx *gorm.DB, app *dbmodel.Application) err
 		return err
 	}
 
+	// delete alert rules
+	if err := db.GetManager().AlertRuleDaoTransactions(tx).DeleteByAppID(app.AppID); err != nil {
+		return err
+	}
+
 	// delete application
 	return db.GetManager().ApplicationDaoTransactions(tx).DeleteApp(app.AppID)
 }
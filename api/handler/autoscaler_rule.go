@@ -0,0 +1,164 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	pkgerr "github.com/pkg/errors"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/alerting"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// validateAutoscalerRule checks the rule can be converted to an autoscaling/v2 HPA
+func validateAutoscalerRule(req *api_model.AutoscalerRuleReq) error {
+	if err := checkAutoscalerRule(req); err != nil {
+		return pkgerr.Wrap(bcode.ErrInvalidAutoscalerRule, err.Error())
+	}
+	return nil
+}
+
+func checkAutoscalerRule(req *api_model.AutoscalerRuleReq) error {
+	if req.MinReplicas < 1 {
+		return fmt.Errorf("min_replicas must be greater than 0")
+	}
+	if req.MaxReplicas < req.MinReplicas {
+		return fmt.Errorf("max_replicas must be greater than or equal to min_replicas")
+	}
+	names := make(map[string]struct{}, len(req.Metrics))
+	for i := range req.Metrics {
+		metric := &req.Metrics[i]
+		key := metric.MetricsType + "/" + metric.MetricsName
+		if _, ok := names[key]; ok {
+			return fmt.Errorf("duplicate metric %s", key)
+		}
+		names[key] = struct{}{}
+		if err := checkRuleMetric(metric); err != nil {
+			return fmt.Errorf("metric %s: %v", metric.MetricsName, err)
+		}
+	}
+	if req.Behavior != nil {
+		if err := checkScalingRules(req.Behavior.ScaleUp); err != nil {
+			return fmt.Errorf("scale up behavior: %v", err)
+		}
+		if err := checkScalingRules(req.Behavior.ScaleDown); err != nil {
+			return fmt.Errorf("scale down behavior: %v", err)
+		}
+	}
+	return nil
+}
+
+func checkRuleMetric(metric *api_model.RuleMetric) error {
+	if metric.MetricsName == "" {
+		return fmt.Errorf("metric_name is required")
+	}
+	if metric.MetricsType == dbmodel.XPAMetricTypeResource {
+		if metric.MetricsName != "cpu" && metric.MetricsName != "memory" {
+			return fmt.Errorf("only cpu and memory are supported by resource metrics")
+		}
+		if metric.MetricTargetType != dbmodel.XPATargetTypeUtilization && metric.MetricTargetType != dbmodel.XPATargetTypeAverageValue {
+			return fmt.Errorf("the target type of resource metrics must be utilization or average_value")
+		}
+		if metric.MetricTargetValue <= 0 {
+			return fmt.Errorf("metric_target_value must be greater than 0")
+		}
+		return nil
+	}
+
+	switch metric.MetricsType {
+	case dbmodel.XPAMetricTypePods:
+		if metric.MetricTargetType != dbmodel.XPATargetTypeAverageValue {
+			return fmt.Errorf("the target type of pods metrics must be average_value")
+		}
+	case dbmodel.XPAMetricTypeObject:
+		if metric.ObjectKind == "" || metric.ObjectName == "" {
+			return fmt.Errorf("object_kind and object_name are required by object metrics")
+		}
+	case dbmodel.XPAMetricTypeExternal:
+	case dbmodel.XPAMetricTypePrometheus:
+		if strings.TrimSpace(metric.PromQL) == "" {
+			return fmt.Errorf("promql is required by prometheus metrics")
+		}
+		if metric.MetricSelector != "" {
+			return fmt.Errorf("metric_selector is not supported by prometheus metrics, filter the series in promql")
+		}
+		// the selectors are pinned to the namespace variable, the worker enforces the namespace of the component again
+		promql, err := alerting.EnforceNamespace(metric.PromQL, "{{namespace}}")
+		if err != nil {
+			return fmt.Errorf("invalid promql: %v", err)
+		}
+		if err := alerting.ValidateExpr(promql); err != nil {
+			return fmt.Errorf("invalid promql: %v", err)
+		}
+		metric.PromQL = promql
+	default:
+		return fmt.Errorf("unsupported metric type %s", metric.MetricsType)
+	}
+	if metric.MetricTargetType != dbmodel.XPATargetTypeAverageValue && metric.MetricTargetType != dbmodel.XPATargetTypeValue {
+		return fmt.Errorf("the target type of %s must be average_value or value", metric.MetricsType)
+	}
+	if metric.MetricTargetQuantity != "" {
+		q, err := resource.ParseQuantity(metric.MetricTargetQuantity)
+		if err != nil {
+			return fmt.Errorf("invalid metric_target_quantity %s: %v", metric.MetricTargetQuantity, err)
+		}
+		if q.Sign() <= 0 {
+			return fmt.Errorf("metric_target_quantity must be greater than 0")
+		}
+	} else if metric.MetricTargetValue <= 0 {
+		return fmt.Errorf("metric_target_value must be greater than 0")
+	}
+	if metric.MetricSelector != "" {
+		if _, err := metav1.ParseToLabelSelector(metric.MetricSelector); err != nil {
+			return fmt.Errorf("invalid metric_selector %s: %v", metric.MetricSelector, err)
+		}
+	}
+	return nil
+}
+
+func checkScalingRules(rules *dbmodel.AutoscalerScalingRules) error {
+	if rules == nil {
+		return nil
+	}
+	if w := rules.StabilizationWindowSeconds; w != nil && (*w < 0 || *w > 3600) {
+		return fmt.Errorf("stabilization_window_seconds must be between 0 and 3600")
+	}
+	switch rules.SelectPolicy {
+	case "", "Max", "Min", "Disabled":
+	default:
+		return fmt.Errorf("select_policy must be Max, Min or Disabled")
+	}
+	for _, p := range rules.Policies {
+		if p.Type != "Pods" && p.Type != "Percent" {
+			return fmt.Errorf("the type of policy must be Pods or Percent")
+		}
+		if p.Value <= 0 {
+			return fmt.Errorf("the value of policy must be greater than 0")
+		}
+		if p.PeriodSeconds <= 0 || p.PeriodSeconds > 1800 {
+			return fmt.Errorf("the period_seconds of policy must be between 1 and 1800")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"testing"
+
+	api_model "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+func TestValidateAutoscalerRule(t *testing.T) {
+	window := int32(7200)
+	tests := []struct {
+		name     string
+		metrics  []api_model.RuleMetric
+		behavior *dbmodel.AutoscalerBehavior
+		// wantPromQL the saved query of the last metric
+		wantPromQL string
+		wantErr    bool
+	}{
+		{
+			name: "resource and prometheus metrics",
+			metrics: []api_model.RuleMetric{
+				{MetricsType: "resource_metrics", MetricsName: "cpu", MetricTargetType: "utilization", MetricTargetValue: 70},
+				{MetricsType: "prometheus_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetQuantity: "100",
+					PromQL: `sum(rate(gateway_requests{service_id="{{service_id}}"}[1m]))`},
+			},
+			behavior: &dbmodel.AutoscalerBehavior{ScaleDown: &dbmodel.AutoscalerScalingRules{
+				SelectPolicy: "Min",
+				Policies:     []dbmodel.AutoscalerScalingPolicy{{Type: "Percent", Value: 10, PeriodSeconds: 60}},
+			}},
+			wantPromQL: `sum(rate(gateway_requests{namespace="{{namespace}}",service_id="{{service_id}}"}[1m]))`,
+		},
+		{
+			name: "prometheus metric selecting other namespace",
+			metrics: []api_model.RuleMetric{{MetricsType: "prometheus_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetValue: 10,
+				PromQL: `sum(rate(gateway_requests{namespace="other"}[1m])) + sum(rate(http_requests_total[1m]))`}},
+			wantPromQL: `sum(rate(gateway_requests{namespace="{{namespace}}"}[1m])) + sum(rate(http_requests_total{namespace="{{namespace}}"}[1m]))`,
+		},
+		{
+			name: "invalid promql",
+			metrics: []api_model.RuleMetric{{MetricsType: "prometheus_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetValue: 10,
+				PromQL: `sum(rate(gateway_requests[1m])`}},
+			wantErr: true,
+		},
+		{
+			name: "range vector promql",
+			metrics: []api_model.RuleMetric{{MetricsType: "prometheus_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetValue: 10,
+				PromQL: `gateway_requests[1m]`}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported resource",
+			metrics: []api_model.RuleMetric{{MetricsType: "resource_metrics", MetricsName: "gpu", MetricTargetType: "utilization", MetricTargetValue: 70}},
+			wantErr: true,
+		},
+		{
+			name:    "object metric without object",
+			metrics: []api_model.RuleMetric{{MetricsType: "object_metrics", MetricsName: "rps", MetricTargetType: "value", MetricTargetValue: 10}},
+			wantErr: true,
+		},
+		{
+			name:    "prometheus metric without promql",
+			metrics: []api_model.RuleMetric{{MetricsType: "prometheus_metrics", MetricsName: "qps", MetricTargetType: "value", MetricTargetValue: 10}},
+			wantErr: true,
+		},
+		{
+			name: "duplicate metrics",
+			metrics: []api_model.RuleMetric{
+				{MetricsType: "external_metrics", MetricsName: "queue", MetricTargetType: "value", MetricTargetValue: 10},
+				{MetricsType: "external_metrics", MetricsName: "queue", MetricTargetType: "average_value", MetricTargetValue: 10},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "invalid stabilization window",
+			behavior: &dbmodel.AutoscalerBehavior{ScaleUp: &dbmodel.AutoscalerScalingRules{StabilizationWindowSeconds: &window}},
+			wantErr:  true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &api_model.AutoscalerRuleReq{RuleID: "rule", MinReplicas: 1, MaxReplicas: 3, Metrics: tc.metrics, Behavior: tc.behavior}
+			err := validateAutoscalerRule(req)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("validateAutoscalerRule() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err != nil && !bcode.ErrInvalidAutoscalerRule.Equal(err) {
+				t.Errorf("want ErrInvalidAutoscalerRule, got %v", err)
+			}
+			if tc.wantPromQL != "" {
+				if promql := req.Metrics[len(req.Metrics)-1].PromQL; promql != tc.wantPromQL {
+					t.Errorf("want promql %s, got %s", tc.wantPromQL, promql)
+				}
+			}
+		})
+	}
+}
@@ -21,7 +21,9 @@ package handler
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,8 +39,16 @@ import (
 	"github.com/wutong-paas/wutong/mq/client"
 	"github.com/wutong-paas/wutong/util"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"golang.org/x/crypto/bcrypt"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+// basicAuthUsernameRegexp the username can not contain the colon which separates the username and password
+var basicAuthUsernameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._@-]{1,64}$`)
+
+// defaultClientAuthVerifyDepth the gateway verifies the client certificate chain of depth 1 by default
+const defaultClientAuthVerifyDepth = 1
+
 // GatewayAction -
 type GatewayAction struct {
 	dbmanager db.Manager
@@ -95,6 +105,7 @@ func (g *GatewayAction) CreateHTTPRule(tx *gorm.DB, req *apimodel.AddHTTPRuleStr
 		IP:            req.IP,
 		CertificateID: req.CertificateID,
 		PathRewrite:   req.PathRewrite,
+		AutoTLS:       req.AutoTLS,
 	}
 	if err := db.GetManager().HTTPRuleDaoTransactions(tx).AddModel(httpRule); err != nil {
 		return fmt.Errorf("create http rule: %v", err)
@@ -183,20 +194,23 @@ func (g *GatewayAction) UpdateHTTPRule(req *apimodel.UpdateHTTPRuleStruct) error
 		}
 	}
 
-	if strings.Replace(req.CertificateID, " ", "", -1) != "" {
-		// add new certificate
-		cert := &model.Certificate{
-			UUID:        req.CertificateID,
-			Certificate: req.Certificate,
-			PrivateKey:  req.PrivateKey,
-		}
-		if err := g.dbmanager.CertificateDaoTransactions(tx).AddOrUpdate(cert); err != nil {
-			tx.Rollback()
-			return err
+	// the certificate issued by the ACME controller is kept
+	if !req.AutoTLS || !rule.AutoTLS {
+		if strings.Replace(req.CertificateID, " ", "", -1) != "" {
+			// add new certificate
+			cert := &model.Certificate{
+				UUID:        req.CertificateID,
+				Certificate: req.Certificate,
+				PrivateKey:  req.PrivateKey,
+			}
+			if err := g.dbmanager.CertificateDaoTransactions(tx).AddOrUpdate(cert); err != nil {
+				tx.Rollback()
+				return err
+			}
+			rule.CertificateID = req.CertificateID
+		} else {
+			rule.CertificateID = ""
 		}
-		rule.CertificateID = req.CertificateID
-	} else {
-		rule.CertificateID = ""
 	}
 	if len(req.RuleExtensions) > 0 {
 		// delete old RuleExtensions
@@ -238,6 +252,7 @@ func (g *GatewayAction) UpdateHTTPRule(req *apimodel.UpdateHTTPRuleStruct) error
 	rule.Cookie = req.Cookie
 	rule.Weight = req.Weight
 	rule.PathRewrite = req.PathRewrite
+	rule.AutoTLS = req.AutoTLS
 	if req.IP != "" {
 		rule.IP = req.IP
 	}
@@ -294,10 +309,32 @@ func (g *GatewayAction) DeleteHTTPRule(req *apimodel.DeleteHTTPRuleStruct) error
 		tx.Rollback()
 		return err
 	}
+
+	// delete the slos of the http rule
+	slos, err := g.dbmanager.ServiceSLODaoTransactions(tx).ListByServiceID(svcID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var sloTenantEnvID string
+	for _, slo := range slos {
+		if slo.HTTPRuleID == httpRule.UUID {
+			sloTenantEnvID = slo.TenantEnvID
+		}
+	}
+	if err := g.dbmanager.ServiceSLODaoTransactions(tx).DeleteByHTTPRuleID(httpRule.UUID); err != nil {
+		tx.Rollback()
+		return err
+	}
 	// end transaction
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
+	if sloTenantEnvID != "" {
+		if err := GetSLOHandler().SyncSLOs(sloTenantEnvID, svcID); err != nil {
+			logrus.Warningf("remove the slos of the http rule %s: %v", httpRule.UUID, err)
+		}
+	}
 
 	if err := g.SendTaskDeprecated(map[string]interface{}{
 		"service_id": svcID,
@@ -794,6 +831,16 @@ func (g *GatewayAction) RuleConfig(req *apimodel.RuleConfigReq) error {
 		Key:    "access-log",
 		Value:  accessLog,
 	})
+	accessConfigs, err := g.accessControlConfigs(req)
+	if err != nil {
+		return err
+	}
+	configs = append(configs, accessConfigs...)
+	tlsConfigs, err := tlsConfigs(req)
+	if err != nil {
+		return err
+	}
+	configs = append(configs, tlsConfigs...)
 	setheaders := make(map[string]string)
 	for _, item := range req.Body.SetHeaders {
 		if strings.TrimSpace(item.Key) == "" {
@@ -817,6 +864,9 @@ func (g *GatewayAction) RuleConfig(req *apimodel.RuleConfigReq) error {
 	if err != nil {
 		return err
 	}
+	if err := g.checkClientAuth(req, rule); err != nil {
+		return err
+	}
 
 	tx := db.GetManager().Begin()
 	defer func() {
@@ -851,6 +901,231 @@ func (g *GatewayAction) RuleConfig(req *apimodel.RuleConfigReq) error {
 	return nil
 }
 
+// accessControlConfigs returns the rate limit, ip access and basic authentication configs of the rule
+func (g *GatewayAction) accessControlConfigs(req *apimodel.RuleConfigReq) ([]*model.GwRuleConfig, error) {
+	var configs []*model.GwRuleConfig
+	add := func(key, value string) {
+		configs = append(configs, &model.GwRuleConfig{
+			RuleID: req.RuleID,
+			Key:    key,
+			Value:  value,
+		})
+	}
+	body := req.Body
+	if body.LimitRPS < 0 || body.LimitBurst < 0 || body.LimitConnections < 0 {
+		return nil, errors.Wrap(bcode.ErrInvalidRuleConfig, "the limits can not be negative")
+	}
+	if body.LimitRPS > 0 {
+		add("limit-rps", strconv.Itoa(body.LimitRPS))
+		if body.LimitBurst > 0 {
+			add("limit-burst", strconv.Itoa(body.LimitBurst))
+		}
+	}
+	if body.LimitConnections > 0 {
+		add("limit-connections", strconv.Itoa(body.LimitConnections))
+	}
+
+	for key, cidrs := range map[string][]string{"allow-cidrs": body.AllowCIDRs, "deny-cidrs": body.DenyCIDRs} {
+		if len(cidrs) == 0 {
+			continue
+		}
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid cidr %s", cidr)
+			}
+		}
+		add(key, strings.Join(cidrs, ","))
+	}
+
+	if body.BasicAuth == nil || len(body.BasicAuth.Users) == 0 {
+		return configs, nil
+	}
+	oldConfigs, err := g.dbmanager.GwRuleConfigDao().ListByRuleID(req.RuleID)
+	if err != nil {
+		return nil, err
+	}
+	oldPasswords := make(map[string]string)
+	for _, cfg := range oldConfigs {
+		if strings.HasPrefix(cfg.Key, model.BasicAuthUserConfigPrefix) {
+			oldPasswords[strings.TrimPrefix(cfg.Key, model.BasicAuthUserConfigPrefix)] = cfg.Value
+		}
+	}
+	users := make(map[string]string)
+	for _, user := range body.BasicAuth.Users {
+		if !basicAuthUsernameRegexp.MatchString(user.Username) {
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid username %s", user.Username)
+		}
+		password := oldPasswords[user.Username]
+		if user.Password != "" {
+			if password, err = hashBasicAuthPassword(user.Password); err != nil {
+				return nil, err
+			}
+		}
+		if password == "" {
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "the password of user %s is required", user.Username)
+		}
+		users[user.Username] = password
+	}
+	for username, password := range users {
+		add(model.BasicAuthUserConfigPrefix+username, password)
+	}
+	if body.BasicAuth.Realm != "" {
+		add("auth-realm", body.BasicAuth.Realm)
+	}
+	return configs, nil
+}
+
+// checkClientAuth rejects the client certificate authentication conflicting with the other rules of the
+// domain, the gateway requests the client certificates of a host by one CA bundle and verify depth.
+func (g *GatewayAction) checkClientAuth(req *apimodel.RuleConfigReq, rule *model.HTTPRule) error {
+	clientAuth := req.Body.ClientAuth
+	if clientAuth == nil {
+		return nil
+	}
+	depth := clientAuth.VerifyDepth
+	if depth == 0 {
+		depth = defaultClientAuthVerifyDepth
+	}
+	rules, err := g.dbmanager.HTTPRuleDao().ListByDomain(rule.Domain)
+	if err != nil {
+		return err
+	}
+	for _, other := range rules {
+		if other.UUID == rule.UUID {
+			continue
+		}
+		configs, err := g.dbmanager.GwRuleConfigDao().ListByRuleID(other.UUID)
+		if err != nil {
+			return err
+		}
+		secret, otherDepth := "", defaultClientAuthVerifyDepth
+		for _, cfg := range configs {
+			switch cfg.Key {
+			case "client-auth-secret":
+				secret = cfg.Value
+			case "client-auth-verify-depth":
+				otherDepth, _ = strconv.Atoi(cfg.Value)
+			}
+		}
+		if secret == "" {
+			continue
+		}
+		if secret != clientAuth.Secret || otherDepth != depth {
+			return errors.Wrapf(bcode.ErrInvalidRuleConfig, "the client auth conflicts with the rule %s of the domain %s, secret %s, verify depth %d",
+				other.UUID, rule.Domain, secret, otherDepth)
+		}
+		if other.ServiceID != rule.ServiceID {
+			// the secrets of the components in different tenant envs are different
+			same, err := g.sameTenantEnv(other.ServiceID, rule.ServiceID)
+			if err != nil {
+				return err
+			}
+			if !same {
+				return errors.Wrapf(bcode.ErrInvalidRuleConfig, "the client auth conflicts with the rule %s of the domain %s in another tenant env",
+					other.UUID, rule.Domain)
+			}
+		}
+	}
+	return nil
+}
+
+// sameTenantEnv checks if the components belong to the same tenant env
+func (g *GatewayAction) sameTenantEnv(serviceID, otherServiceID string) (bool, error) {
+	service, err := g.dbmanager.TenantEnvServiceDao().GetServiceByID(serviceID)
+	if err != nil {
+		return false, err
+	}
+	other, err := g.dbmanager.TenantEnvServiceDao().GetServiceByID(otherServiceID)
+	if err != nil {
+		return false, err
+	}
+	return service.TenantEnvID == other.TenantEnvID, nil
+}
+
+// tlsConfigs returns the client certificate authentication, the backend protocol and upstream tls configs of the rule
+func tlsConfigs(req *apimodel.RuleConfigReq) ([]*model.GwRuleConfig, error) {
+	var configs []*model.GwRuleConfig
+	add := func(key, value string) {
+		configs = append(configs, &model.GwRuleConfig{
+			RuleID: req.RuleID,
+			Key:    key,
+			Value:  value,
+		})
+	}
+	if clientAuth := req.Body.ClientAuth; clientAuth != nil {
+		if errs := validation.IsDNS1123Subdomain(clientAuth.Secret); len(errs) > 0 {
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid client auth secret %s: %s", clientAuth.Secret, strings.Join(errs, ","))
+		}
+		add("client-auth-secret", clientAuth.Secret)
+		switch clientAuth.Verify {
+		case "":
+		case "on", "optional", "optional_no_ca":
+			add("client-auth-verify", clientAuth.Verify)
+		default:
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid client auth verify %s", clientAuth.Verify)
+		}
+		if clientAuth.VerifyDepth < 0 {
+			return nil, errors.Wrap(bcode.ErrInvalidRuleConfig, "the verify depth can not be negative")
+		}
+		if clientAuth.VerifyDepth > 0 {
+			add("client-auth-verify-depth", strconv.Itoa(clientAuth.VerifyDepth))
+		}
+		if clientAuth.PassCertificate {
+			add("client-auth-pass-certificate", "true")
+		}
+	}
+
+	upstreamTLS := req.Body.UpstreamTLS
+	switch protocol := strings.ToUpper(req.Body.BackendProtocol); protocol {
+	case "", "HTTP":
+	case "HTTP2", "GRPC":
+		if upstreamTLS != nil {
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "the upstream tls does not support the backend protocol %s", protocol)
+		}
+		add("backend-protocol", protocol)
+	default:
+		return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid backend protocol %s", req.Body.BackendProtocol)
+	}
+	if upstreamTLS == nil {
+		return configs, nil
+	}
+	add("backend-protocol", "HTTPS")
+	if upstreamTLS.Secret != "" {
+		if errs := validation.IsDNS1123Subdomain(upstreamTLS.Secret); len(errs) > 0 {
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid upstream tls secret %s: %s", upstreamTLS.Secret, strings.Join(errs, ","))
+		}
+		add("proxy-ssl-secret", upstreamTLS.Secret)
+	} else if upstreamTLS.Verify {
+		return nil, errors.Wrap(bcode.ErrInvalidRuleConfig, "the secret is required to verify the upstream certificate")
+	}
+	add("proxy-ssl-verify", strconv.FormatBool(upstreamTLS.Verify))
+	if upstreamTLS.VerifyDepth < 0 {
+		return nil, errors.Wrap(bcode.ErrInvalidRuleConfig, "the verify depth can not be negative")
+	}
+	if upstreamTLS.VerifyDepth > 0 {
+		add("proxy-ssl-verify-depth", strconv.Itoa(upstreamTLS.VerifyDepth))
+	}
+	if upstreamTLS.ServerName != "" {
+		if errs := validation.IsDNS1123Subdomain(upstreamTLS.ServerName); len(errs) > 0 {
+			return nil, errors.Wrapf(bcode.ErrInvalidRuleConfig, "invalid upstream server name %s: %s", upstreamTLS.ServerName, strings.Join(errs, ","))
+		}
+		add("proxy-ssl-server-name", upstreamTLS.ServerName)
+	}
+	return configs, nil
+}
+
+// hashBasicAuthPassword hashes the password by bcrypt which is verified by nginx with the crypt(3) of musl
+func hashBasicAuthPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		if err == bcrypt.ErrPasswordTooLong {
+			return "", errors.Wrapf(bcode.ErrInvalidRuleConfig, "the password should not be longer than 72 bytes")
+		}
+		return "", err
+	}
+	return string(hashed), nil
+}
+
 // TCPRuleConfig -
 func (g *GatewayAction) TCPRuleConfig(req *apimodel.TCPRuleConfigReq) error {
 	var configs []*model.GwRuleConfig
@@ -29,6 +29,7 @@ import (
 	"github.com/sirupsen/logrus"
 	apimodel "github.com/wutong-paas/wutong/api/model"
 	"github.com/wutong-paas/wutong/util"
+	"golang.org/x/crypto/bcrypt"
 )
 
 var gm *GatewayAction
@@ -118,3 +119,97 @@ func TestAddTCPRule(t *testing.T) {
 func TestDeleteHTTPRule(t *testing.T) {
 	gm.DeleteHTTPRule(&apimodel.DeleteHTTPRuleStruct{HTTPRuleID: ""})
 }
+
+func TestHashBasicAuthPassword(t *testing.T) {
+	hashed, err := hashBasicAuthPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hashed, "$2a$") {
+		t.Fatalf("hashBasicAuthPassword() = %s, want the bcrypt scheme", hashed)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte("secret")); err != nil {
+		t.Errorf("the hash does not match the password: %v", err)
+	}
+	if again, _ := hashBasicAuthPassword("secret"); again == hashed {
+		t.Errorf("the same password is hashed to the same value without a random salt")
+	}
+}
+
+func TestTLSConfigs(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    apimodel.Body
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "client auth",
+			body: apimodel.Body{ClientAuth: &apimodel.ClientAuth{Secret: "ca", Verify: "optional", PassCertificate: true}},
+			want: map[string]string{
+				"client-auth-secret":           "ca",
+				"client-auth-verify":           "optional",
+				"client-auth-pass-certificate": "true",
+			},
+		},
+		{
+			name:    "invalid verify mode",
+			body:    apimodel.Body{ClientAuth: &apimodel.ClientAuth{Secret: "ca", Verify: "off"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid secret",
+			body:    apimodel.Body{ClientAuth: &apimodel.ClientAuth{Secret: "../ca"}},
+			wantErr: true,
+		},
+		{
+			name: "upstream tls",
+			body: apimodel.Body{UpstreamTLS: &apimodel.UpstreamTLS{Secret: "ca", Verify: true, VerifyDepth: 2, ServerName: "backend.local"}},
+			want: map[string]string{
+				"backend-protocol":       "HTTPS",
+				"proxy-ssl-secret":       "ca",
+				"proxy-ssl-verify":       "true",
+				"proxy-ssl-verify-depth": "2",
+				"proxy-ssl-server-name":  "backend.local",
+			},
+		},
+		{
+			name:    "verify upstream without ca",
+			body:    apimodel.Body{UpstreamTLS: &apimodel.UpstreamTLS{Verify: true}},
+			wantErr: true,
+		},
+		{
+			name: "grpc backend",
+			body: apimodel.Body{BackendProtocol: "grpc"},
+			want: map[string]string{"backend-protocol": "GRPC"},
+		},
+		{
+			name:    "http2 backend with upstream tls",
+			body:    apimodel.Body{BackendProtocol: "HTTP2", UpstreamTLS: &apimodel.UpstreamTLS{}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid backend protocol",
+			body:    apimodel.Body{BackendProtocol: "FTP"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			configs, err := tlsConfigs(&apimodel.RuleConfigReq{RuleID: "rule", Body: tc.body})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("tlsConfigs() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			got := make(map[string]string)
+			for _, cfg := range configs {
+				got[cfg.Key] = cfg.Value
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want) {
+				t.Errorf("tlsConfigs() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@
 package handler
 
 import (
+	monitorclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
 	"github.com/wutong-paas/wutong/api/handler/group"
 	"github.com/wutong-paas/wutong/api/handler/share"
 	"github.com/wutong-paas/wutong/cmd/api/option"
@@ -125,6 +126,17 @@ func InitHandle(conf option.Config) error {
 	defServiceEventHandler = NewServiceEventHandler()
 	defApplicationHandler = NewApplicationHandler(statusCli, prometheusCli, wutongClient, kubeClient)
 	defRegistryAuthSecretHandler = CreateRegistryAuthSecretManager(dbmanager, mqClient)
+	defImageSignaturePolicyHandler = CreateImageSignaturePolicyManager(dbmanager)
+	defServiceReleaseHandler = CreateServiceReleaseManager(dbmanager, mqClient)
+	defScheduledScalingHandler = CreateScheduledScalingManager(dbmanager, mqClient)
+	defAlertHandler = CreateAlertManager(dbmanager, kubeClient, conf.AlertRepeatInterval)
+	monitorClient, err := monitorclient.NewForConfig(restconfig)
+	if err != nil {
+		return err
+	}
+	defAlertRuleHandler = CreateAlertRuleManager(dbmanager, monitorClient)
+	defSLOHandler = CreateSLOManager(dbmanager, monitorClient, prometheusCli)
+	defLogAlertRuleHandler = CreateLogAlertRuleManager(dbmanager)
 	defAppStoreVersionHandler = CreateAppStoreVersionManager(&conf)
 	return nil
 }
@@ -286,9 +298,58 @@ func GetRegistryAuthSecretHandler() RegistryAuthSecretHandler {
 	return defRegistryAuthSecretHandler
 }
 
+var defImageSignaturePolicyHandler ImageSignaturePolicyHandler
+
+// GetImageSignaturePolicyHandler -
+func GetImageSignaturePolicyHandler() ImageSignaturePolicyHandler {
+	return defImageSignaturePolicyHandler
+}
+
+var defServiceReleaseHandler ServiceReleaseHandler
+
+// GetServiceReleaseHandler -
+func GetServiceReleaseHandler() ServiceReleaseHandler {
+	return defServiceReleaseHandler
+}
+
+var defAlertHandler AlertHandler
+
+// GetAlertHandler -
+func GetAlertHandler() AlertHandler {
+	return defAlertHandler
+}
+
+var defAlertRuleHandler AlertRuleHandler
+
+// GetAlertRuleHandler -
+func GetAlertRuleHandler() AlertRuleHandler {
+	return defAlertRuleHandler
+}
+
+var defSLOHandler SLOHandler
+
+// GetSLOHandler -
+func GetSLOHandler() SLOHandler {
+	return defSLOHandler
+}
+
+var defLogAlertRuleHandler LogAlertRuleHandler
+
+// GetLogAlertRuleHandler -
+func GetLogAlertRuleHandler() LogAlertRuleHandler {
+	return defLogAlertRuleHandler
+}
+
 var defAppStoreVersionHandler AppStoreVersionHandler
 
 // GetAppStoreVersionHandler -
 func GetAppStoreVersionHandler() AppStoreVersionHandler {
 	return defAppStoreVersionHandler
 }
+
+var defScheduledScalingHandler ScheduledScalingHandler
+
+// GetScheduledScalingHandler -
+func GetScheduledScalingHandler() ScheduledScalingHandler {
+	return defScheduledScalingHandler
+}
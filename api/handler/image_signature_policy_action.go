@@ -0,0 +1,121 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"path"
+
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+)
+
+// ImageSignaturePolicyAction -
+type ImageSignaturePolicyAction struct {
+	dbmanager db.Manager
+}
+
+// CreateImageSignaturePolicyManager creates image signature policy manager
+func CreateImageSignaturePolicyManager(dbmanager db.Manager) *ImageSignaturePolicyAction {
+	return &ImageSignaturePolicyAction{
+		dbmanager: dbmanager,
+	}
+}
+
+// GetImageSignaturePolicy gets the image signature policy
+func (i *ImageSignaturePolicyAction) GetImageSignaturePolicy(tenantEnvID string) (*apimodel.ImageSignaturePolicy, error) {
+	policy, err := i.dbmanager.ImageSignaturePolicyDao().GetByTenantEnvID(tenantEnvID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrImageSignaturePolicyNotFound
+		}
+		return nil, err
+	}
+	return apimodel.NewImageSignaturePolicy(policy), nil
+}
+
+// UpdateImageSignaturePolicy creates or updates the image signature policy
+func (i *ImageSignaturePolicyAction) UpdateImageSignaturePolicy(tenantEnvID string, req *apimodel.ImageSignaturePolicy) error {
+	if err := validateImageSignaturePolicy(req); err != nil {
+		return err
+	}
+	policy := req.DbModel(tenantEnvID)
+	old, err := i.dbmanager.ImageSignaturePolicyDao().GetByTenantEnvID(tenantEnvID)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		return i.dbmanager.ImageSignaturePolicyDao().AddModel(policy)
+	}
+	policy.ID = old.ID
+	policy.CreatedAt = old.CreatedAt
+	return i.dbmanager.ImageSignaturePolicyDao().UpdateModel(policy)
+}
+
+// DeleteImageSignaturePolicy deletes the image signature policy
+func (i *ImageSignaturePolicyAction) DeleteImageSignaturePolicy(tenantEnvID string) error {
+	return i.dbmanager.ImageSignaturePolicyDao().DeleteByTenantEnvID(tenantEnvID)
+}
+
+func validateImageSignaturePolicy(req *apimodel.ImageSignaturePolicy) error {
+	if len(req.PublicKeys) == 0 && len(req.Identities) == 0 {
+		return pkgerr.Wrap(bcode.ErrInvalidImageSignaturePolicy, "public keys or keyless identities are required")
+	}
+	for _, image := range req.Images {
+		if _, err := path.Match(image, ""); err != nil {
+			return pkgerr.Wrapf(bcode.ErrInvalidImageSignaturePolicy, "image pattern %s: %v", image, err)
+		}
+	}
+	for _, key := range req.PublicKeys {
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			return pkgerr.Wrap(bcode.ErrInvalidImageSignaturePolicy, "the public key is not PEM encoded")
+		}
+		if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return pkgerr.Wrapf(bcode.ErrInvalidImageSignaturePolicy, "parse public key: %v", err)
+		}
+	}
+	for _, identity := range req.Identities {
+		if identity.Issuer == "" || identity.Subject == "" {
+			return pkgerr.Wrap(bcode.ErrInvalidImageSignaturePolicy, "the issuer and the subject are required by the keyless identity")
+		}
+		for _, pattern := range []string{identity.Issuer, identity.Subject} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return pkgerr.Wrapf(bcode.ErrInvalidImageSignaturePolicy, "identity pattern %s: %v", pattern, err)
+			}
+		}
+	}
+	if len(req.Identities) > 0 && !x509.NewCertPool().AppendCertsFromPEM([]byte(req.RootCerts)) {
+		return pkgerr.Wrap(bcode.ErrInvalidImageSignaturePolicy, "root certificates are required by the keyless identities")
+	}
+	if len(req.Identities) > 0 {
+		block, _ := pem.Decode([]byte(req.RekorPublicKeys))
+		if block == nil {
+			return pkgerr.Wrap(bcode.ErrInvalidImageSignaturePolicy, "rekor public keys are required by the keyless identities")
+		}
+		if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return pkgerr.Wrapf(bcode.ErrInvalidImageSignaturePolicy, "parse rekor public key: %v", err)
+		}
+	}
+	return nil
+}
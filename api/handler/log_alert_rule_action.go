@@ -0,0 +1,171 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/util"
+)
+
+// the window of the log alert rules should be in the range
+const (
+	minLogAlertRuleWindow = 60
+	maxLogAlertRuleWindow = 86400
+)
+
+// LogAlertRuleAction -
+type LogAlertRuleAction struct {
+	dbmanager db.Manager
+}
+
+// CreateLogAlertRuleManager creates log alert rule manager
+func CreateLogAlertRuleManager(dbmanager db.Manager) *LogAlertRuleAction {
+	return &LogAlertRuleAction{
+		dbmanager: dbmanager,
+	}
+}
+
+// ListLogAlertRules lists the log alert rules of the component
+func (a *LogAlertRuleAction) ListLogAlertRules(serviceID string) ([]*apimodel.LogAlertRule, error) {
+	rules, err := a.dbmanager.LogAlertRuleDao().ListByServiceID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apimodel.LogAlertRule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, logAlertRuleResponse(rule))
+	}
+	return result, nil
+}
+
+// CreateLogAlertRule validates and creates the log alert rule, the eventlog loads it in 30 seconds
+func (a *LogAlertRuleAction) CreateLogAlertRule(tenantEnvID, serviceID string, req *apimodel.LogAlertRuleReq) (*apimodel.LogAlertRule, error) {
+	if err := a.checkLogAlertRule(serviceID, "", req); err != nil {
+		return nil, err
+	}
+	rule := &dbmodel.LogAlertRule{
+		RuleID:      util.NewUUID(),
+		TenantEnvID: tenantEnvID,
+		ServiceID:   serviceID,
+	}
+	setLogAlertRule(rule, req)
+	if err := a.dbmanager.LogAlertRuleDao().AddModel(rule); err != nil {
+		return nil, err
+	}
+	return logAlertRuleResponse(rule), nil
+}
+
+// UpdateLogAlertRule validates and updates the log alert rule, the matches of the rule are
+// counted again if the rule is changed
+func (a *LogAlertRuleAction) UpdateLogAlertRule(serviceID, ruleID string, req *apimodel.LogAlertRuleReq) (*apimodel.LogAlertRule, error) {
+	rule, err := a.getLogAlertRule(serviceID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkLogAlertRule(serviceID, ruleID, req); err != nil {
+		return nil, err
+	}
+	setLogAlertRule(rule, req)
+	if err := a.dbmanager.LogAlertRuleDao().UpdateModel(rule); err != nil {
+		return nil, err
+	}
+	return logAlertRuleResponse(rule), nil
+}
+
+// DeleteLogAlertRule deletes the log alert rule
+func (a *LogAlertRuleAction) DeleteLogAlertRule(serviceID, ruleID string) error {
+	if _, err := a.getLogAlertRule(serviceID, ruleID); err != nil {
+		return err
+	}
+	return a.dbmanager.LogAlertRuleDao().DeleteByRuleID(ruleID)
+}
+
+func (a *LogAlertRuleAction) getLogAlertRule(serviceID, ruleID string) (*dbmodel.LogAlertRule, error) {
+	rule, err := a.dbmanager.LogAlertRuleDao().GetByRuleID(ruleID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrLogAlertRuleNotFound
+		}
+		return nil, err
+	}
+	if rule.ServiceID != serviceID {
+		return nil, bcode.ErrLogAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+// checkLogAlertRule validates the pattern and the window, the name should be unique in the component
+func (a *LogAlertRuleAction) checkLogAlertRule(serviceID, ruleID string, req *apimodel.LogAlertRuleReq) error {
+	if err := validateLogAlertRule(req); err != nil {
+		return err
+	}
+	rules, err := a.dbmanager.LogAlertRuleDao().ListByServiceID(serviceID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Name == req.Name && rule.RuleID != ruleID {
+			return bcode.ErrLogAlertRuleNameExists
+		}
+	}
+	return nil
+}
+
+func validateLogAlertRule(req *apimodel.LogAlertRuleReq) error {
+	if req.WindowSeconds < minLogAlertRuleWindow || req.WindowSeconds > maxLogAlertRuleWindow {
+		return pkgerr.Wrapf(bcode.ErrInvalidLogAlertRule, "the window should be between %d and %d seconds", minLogAlertRuleWindow, maxLogAlertRuleWindow)
+	}
+	if req.Threshold < 1 {
+		return pkgerr.Wrapf(bcode.ErrInvalidLogAlertRule, "the threshold should be at least 1")
+	}
+	rule := &dbmodel.LogAlertRule{MatchType: req.MatchType, Pattern: req.Pattern}
+	if _, err := rule.Matcher(); err != nil {
+		return pkgerr.Wrapf(bcode.ErrInvalidLogAlertRule, "invalid pattern: %v", err)
+	}
+	return nil
+}
+
+func setLogAlertRule(rule *dbmodel.LogAlertRule, req *apimodel.LogAlertRuleReq) {
+	rule.Name = req.Name
+	rule.MatchType = req.MatchType
+	rule.Pattern = req.Pattern
+	rule.Threshold = req.Threshold
+	rule.WindowSeconds = req.WindowSeconds
+	rule.Enabled = req.Enabled
+}
+
+func logAlertRuleResponse(rule *dbmodel.LogAlertRule) *apimodel.LogAlertRule {
+	return &apimodel.LogAlertRule{
+		RuleID:        rule.RuleID,
+		TenantEnvID:   rule.TenantEnvID,
+		ServiceID:     rule.ServiceID,
+		Name:          rule.Name,
+		MatchType:     rule.MatchType,
+		Pattern:       rule.Pattern,
+		Threshold:     rule.Threshold,
+		WindowSeconds: rule.WindowSeconds,
+		Enabled:       rule.Enabled,
+		CreateTime:    rule.CreatedAt,
+	}
+}
@@ -0,0 +1,45 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"testing"
+
+	apimodel "github.com/wutong-paas/wutong/api/model"
+)
+
+func TestValidateLogAlertRule(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   apimodel.LogAlertRuleReq
+		valid bool
+	}{
+		{name: "keyword", req: apimodel.LogAlertRuleReq{MatchType: "keyword", Pattern: "OutOfMemoryError", Threshold: 1, WindowSeconds: 60}, valid: true},
+		{name: "regex", req: apimodel.LogAlertRuleReq{MatchType: "regex", Pattern: `level=(error|fatal)`, Threshold: 10, WindowSeconds: 300}, valid: true},
+		{name: "invalid regex", req: apimodel.LogAlertRuleReq{MatchType: "regex", Pattern: `level=(error`, Threshold: 10, WindowSeconds: 300}},
+		{name: "short window", req: apimodel.LogAlertRuleReq{MatchType: "keyword", Pattern: "error", Threshold: 1, WindowSeconds: 10}},
+		{name: "long window", req: apimodel.LogAlertRuleReq{MatchType: "keyword", Pattern: "error", Threshold: 1, WindowSeconds: 86401}},
+		{name: "threshold", req: apimodel.LogAlertRuleReq{MatchType: "keyword", Pattern: "error", WindowSeconds: 60}},
+	}
+	for _, tc := range tests {
+		if err := validateLogAlertRule(&tc.req); (err == nil) != tc.valid {
+			t.Errorf("%s: want valid %v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/mq/client"
+	"github.com/wutong-paas/wutong/util"
+)
+
+var (
+	defaultReleaseSteps        = []int{10, 30, 50, 100}
+	defaultReleaseStepInterval = 60
+	releaseMax5xxRate          = 0.05
+	releaseMaxP99Latency       = 1.0
+	// defaultReleaseAnalyses analyzes the 5xx rate and the p99 latency of the requests served by
+	// the canary services on the gateway
+	defaultReleaseAnalyses = []dbmodel.ReleaseAnalysis{
+		{
+			Name:  "5xx-rate",
+			Query: `sum(rate(gateway_requests{service_id="{{service_id}}",upstream_service=~".*-canary",status=~"5.."}[1m])) / sum(rate(gateway_requests{service_id="{{service_id}}",upstream_service=~".*-canary"}[1m]))`,
+			Max:   &releaseMax5xxRate,
+		},
+		{
+			Name:  "p99-latency",
+			Query: `histogram_quantile(0.99, sum(rate(gateway_request_duration_seconds_bucket{service_id="{{service_id}}",upstream_service=~".*-canary"}[1m])) by (le))`,
+			Max:   &releaseMaxP99Latency,
+		},
+	}
+)
+
+// ServiceReleaseAction -
+type ServiceReleaseAction struct {
+	dbmanager db.Manager
+	mqclient  client.MQClient
+}
+
+// CreateServiceReleaseManager creates service release manager
+func CreateServiceReleaseManager(dbmanager db.Manager, mqclient client.MQClient) *ServiceReleaseAction {
+	return &ServiceReleaseAction{
+		dbmanager: dbmanager,
+		mqclient:  mqclient,
+	}
+}
+
+// CreateServiceRelease creates the release and sends it to the worker
+func (s *ServiceReleaseAction) CreateServiceRelease(service *dbmodel.TenantEnvServices, eventID string, req *apimodel.CreateServiceReleaseReq) (*apimodel.ServiceRelease, error) {
+	if dbmodel.ServiceKind(service.Kind) == dbmodel.ServiceKindThirdParty || service.IsState() {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidServiceRelease, "only the stateless components can be released")
+	}
+	if _, err := s.dbmanager.ServiceReleaseDao().GetRunningByServiceID(service.ServiceID); err == nil {
+		return nil, bcode.ErrServiceReleaseRunning
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if req.DeployVersion == service.DeployVersion {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidServiceRelease, "the deploy version is running")
+	}
+	version, err := s.dbmanager.VersionInfoDao().GetVersionByDeployVersion(req.DeployVersion, service.ServiceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, pkgerr.Wrapf(bcode.ErrInvalidServiceRelease, "deploy version %s not found", req.DeployVersion)
+		}
+		return nil, err
+	}
+	if version.FinalStatus != "success" {
+		return nil, pkgerr.Wrapf(bcode.ErrInvalidServiceRelease, "deploy version %s is not built successfully", req.DeployVersion)
+	}
+
+	release, err := newServiceRelease(service, eventID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.dbmanager.ServiceReleaseDao().AddModel(release); err != nil {
+		return nil, err
+	}
+	err = s.mqclient.SendBuilderTopic(client.TaskStruct{
+		Topic:    client.WorkerTopic,
+		TaskType: "release_service",
+		TaskBody: map[string]interface{}{
+			"tenant_env_id": service.TenantEnvID,
+			"service_id":    service.ServiceID,
+			"release_id":    release.ReleaseID,
+			"event_id":      eventID,
+		},
+	})
+	if err != nil {
+		now := time.Now()
+		release.Status = dbmodel.ReleaseStatusFailed
+		release.Message = "send release task failure"
+		release.FinishTime = &now
+		if err := s.dbmanager.ServiceReleaseDao().UpdateModel(release); err != nil {
+			logrus.Errorf("update release %s: %v", release.ReleaseID, err)
+		}
+		return nil, pkgerr.Wrap(err, "send release task")
+	}
+	return apimodel.NewServiceRelease(release), nil
+}
+
+// ListServiceReleases lists the releases of the component, the latest first
+func (s *ServiceReleaseAction) ListServiceReleases(serviceID string) ([]*apimodel.ServiceRelease, error) {
+	releases, err := s.dbmanager.ServiceReleaseDao().ListByServiceID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*apimodel.ServiceRelease, 0, len(releases))
+	for _, release := range releases {
+		res = append(res, apimodel.NewServiceRelease(release))
+	}
+	return res, nil
+}
+
+// GetServiceRelease gets the release of the component
+func (s *ServiceReleaseAction) GetServiceRelease(serviceID, releaseID string) (*apimodel.ServiceRelease, error) {
+	release, err := s.getServiceRelease(serviceID, releaseID)
+	if err != nil {
+		return nil, err
+	}
+	return apimodel.NewServiceRelease(release), nil
+}
+
+// CommandServiceRelease promotes or aborts the running release, the worker
+// running the release checks the command periodically.
+func (s *ServiceReleaseAction) CommandServiceRelease(serviceID, releaseID, command string) error {
+	release, err := s.getServiceRelease(serviceID, releaseID)
+	if err != nil {
+		return err
+	}
+	switch release.Status {
+	case dbmodel.ReleaseStatusPending, dbmodel.ReleaseStatusProgressing, dbmodel.ReleaseStatusPaused:
+	default:
+		return pkgerr.Wrapf(bcode.ErrServiceReleaseFinished, "the release is %s", release.Status)
+	}
+	return s.dbmanager.ServiceReleaseDao().UpdateCommand(releaseID, command)
+}
+
+func (s *ServiceReleaseAction) getServiceRelease(serviceID, releaseID string) (*dbmodel.ServiceRelease, error) {
+	release, err := s.dbmanager.ServiceReleaseDao().GetByReleaseID(releaseID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrServiceReleaseNotFound
+		}
+		return nil, err
+	}
+	if release.ServiceID != serviceID {
+		return nil, bcode.ErrServiceReleaseNotFound
+	}
+	return release, nil
+}
+
+func newServiceRelease(service *dbmodel.TenantEnvServices, eventID string, req *apimodel.CreateServiceReleaseReq) (*dbmodel.ServiceRelease, error) {
+	if req.StepInterval < 0 || req.NoDataTimeout < 0 || req.CanaryReplicas < 0 {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidServiceRelease, "the step interval, no data timeout and canary replicas can not be negative")
+	}
+	release := &dbmodel.ServiceRelease{
+		ReleaseID:      util.NewUUID(),
+		TenantEnvID:    service.TenantEnvID,
+		ServiceID:      service.ServiceID,
+		EventID:        eventID,
+		Strategy:       req.Strategy,
+		StableVersion:  service.DeployVersion,
+		CanaryVersion:  req.DeployVersion,
+		CanaryReplicas: req.CanaryReplicas,
+		StepInterval:   req.StepInterval,
+		NoDataTimeout:  req.NoDataTimeout,
+		Header:         strings.TrimSpace(req.Header),
+		Cookie:         strings.TrimSpace(req.Cookie),
+		ManualPromote:  req.ManualPromote,
+		Status:         dbmodel.ReleaseStatusPending,
+	}
+	if release.CanaryReplicas == 0 {
+		release.CanaryReplicas = 1
+		if release.Strategy == dbmodel.ReleaseStrategyBlueGreen && service.Replicas > 1 {
+			release.CanaryReplicas = service.Replicas
+		}
+	}
+	if release.StepInterval == 0 {
+		release.StepInterval = defaultReleaseStepInterval
+	}
+	steps := req.Steps
+	if len(steps) == 0 {
+		steps = defaultReleaseSteps
+	}
+	var weights []string
+	for _, step := range steps {
+		weights = append(weights, strconv.Itoa(step))
+	}
+	release.Steps = strings.Join(weights, ",")
+	if _, err := release.StepWeights(); err != nil {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidServiceRelease, err.Error())
+	}
+	for _, match := range []string{release.Header, release.Cookie} {
+		if match != "" && !strings.Contains(match, "=") {
+			return nil, pkgerr.Wrapf(bcode.ErrInvalidServiceRelease, "invalid match %s, it should be like key=value", match)
+		}
+	}
+	analyses := req.Analyses
+	if len(analyses) == 0 {
+		analyses = defaultReleaseAnalyses
+	}
+	for _, analysis := range analyses {
+		if strings.TrimSpace(analysis.Query) == "" || (analysis.Max == nil && analysis.Min == nil) {
+			return nil, pkgerr.Wrapf(bcode.ErrInvalidServiceRelease, "the analysis %s requires the query and the min or max", analysis.Name)
+		}
+	}
+	data, err := json.Marshal(analyses)
+	if err != nil {
+		return nil, err
+	}
+	release.Analyses = string(data)
+	return release, nil
+}
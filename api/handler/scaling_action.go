@@ -0,0 +1,217 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/mq/client"
+	"github.com/wutong-paas/wutong/util"
+)
+
+var (
+	defaultIdleTimeout = 1800
+	minIdleTimeout     = 60
+	defaultHoldTimeout = 60
+)
+
+// ScheduledScalingAction -
+type ScheduledScalingAction struct {
+	dbmanager db.Manager
+	mqclient  client.MQClient
+}
+
+// CreateScheduledScalingManager creates scheduled scaling manager
+func CreateScheduledScalingManager(dbmanager db.Manager, mqclient client.MQClient) *ScheduledScalingAction {
+	return &ScheduledScalingAction{
+		dbmanager: dbmanager,
+		mqclient:  mqclient,
+	}
+}
+
+// ListScheduledScalingRules lists the rules of the component, or the rules of the tenant env if the service id is empty
+func (s *ScheduledScalingAction) ListScheduledScalingRules(tenantEnvID, serviceID string) ([]*dbmodel.TenantEnvServiceScheduledScalingRule, error) {
+	if serviceID != "" {
+		return s.dbmanager.TenantEnvServiceScheduledScalingRuleDao().ListByServiceID(serviceID)
+	}
+	return s.dbmanager.TenantEnvServiceScheduledScalingRuleDao().ListByTenantEnvID(tenantEnvID)
+}
+
+// CreateScheduledScalingRule creates a scheduled scaling rule
+func (s *ScheduledScalingAction) CreateScheduledScalingRule(tenantEnvID, serviceID string, req *apimodel.ScheduledScalingRuleReq) (*dbmodel.TenantEnvServiceScheduledScalingRule, error) {
+	rule := &dbmodel.TenantEnvServiceScheduledScalingRule{
+		RuleID:      util.NewUUID(),
+		TenantEnvID: tenantEnvID,
+		ServiceID:   serviceID,
+	}
+	if err := setScheduledScalingRule(rule, req); err != nil {
+		return nil, err
+	}
+	// the rule is executed from now on, the past activations are not executed
+	now := time.Now()
+	rule.LastScheduleTime = &now
+	if err := s.dbmanager.TenantEnvServiceScheduledScalingRuleDao().AddModel(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateScheduledScalingRule updates the scheduled scaling rule
+func (s *ScheduledScalingAction) UpdateScheduledScalingRule(tenantEnvID, serviceID, ruleID string, req *apimodel.ScheduledScalingRuleReq) (*dbmodel.TenantEnvServiceScheduledScalingRule, error) {
+	rule, err := s.getScheduledScalingRule(tenantEnvID, serviceID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := setScheduledScalingRule(rule, req); err != nil {
+		return nil, err
+	}
+	if req.Enable {
+		// do not execute the activations missed while the rule is disabled
+		now := time.Now()
+		rule.LastScheduleTime = &now
+	}
+	if err := s.dbmanager.TenantEnvServiceScheduledScalingRuleDao().UpdateModel(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteScheduledScalingRule deletes the scheduled scaling rule
+func (s *ScheduledScalingAction) DeleteScheduledScalingRule(tenantEnvID, serviceID, ruleID string) error {
+	if _, err := s.getScheduledScalingRule(tenantEnvID, serviceID, ruleID); err != nil {
+		return err
+	}
+	return s.dbmanager.TenantEnvServiceScheduledScalingRuleDao().DeleteByRuleID(ruleID)
+}
+
+func (s *ScheduledScalingAction) getScheduledScalingRule(tenantEnvID, serviceID, ruleID string) (*dbmodel.TenantEnvServiceScheduledScalingRule, error) {
+	rule, err := s.dbmanager.TenantEnvServiceScheduledScalingRuleDao().GetByRuleID(ruleID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrScheduledScalingRuleNotFound
+		}
+		return nil, err
+	}
+	if rule.TenantEnvID != tenantEnvID || rule.ServiceID != serviceID {
+		return nil, bcode.ErrScheduledScalingRuleNotFound
+	}
+	return rule, nil
+}
+
+func setScheduledScalingRule(rule *dbmodel.TenantEnvServiceScheduledScalingRule, req *apimodel.ScheduledScalingRuleReq) error {
+	rule.Name = req.Name
+	rule.Schedule = req.Schedule
+	rule.TimeZone = req.TimeZone
+	rule.Action = req.Action
+	rule.Replicas = req.Replicas
+	rule.Enable = req.Enable
+	if _, err := rule.ParseSchedule(); err != nil {
+		return pkgerr.Wrap(bcode.ErrInvalidScheduledScalingRule, err.Error())
+	}
+	if rule.Action == dbmodel.ScheduledScalingActionScale && rule.Replicas < 0 {
+		return pkgerr.Wrap(bcode.ErrInvalidScheduledScalingRule, "the replicas can not be negative")
+	}
+	if rule.Action != dbmodel.ScheduledScalingActionScale {
+		rule.Replicas = 0
+	}
+	return nil
+}
+
+// GetScaleToZero gets the scale-to-zero setting of the component, the default setting is returned if not set
+func (s *ScheduledScalingAction) GetScaleToZero(service *dbmodel.TenantEnvServices) (*dbmodel.TenantEnvServiceScaleToZero, error) {
+	setting, err := s.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(service.ServiceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return &dbmodel.TenantEnvServiceScaleToZero{
+				TenantEnvID: service.TenantEnvID,
+				ServiceID:   service.ServiceID,
+				IdleTimeout: defaultIdleTimeout,
+				HoldTimeout: defaultHoldTimeout,
+			}, nil
+		}
+		return nil, err
+	}
+	return setting, nil
+}
+
+// UpdateScaleToZero updates the scale-to-zero setting of the component, and refreshes the gateway rules
+// so that the gateway holds the requests to the sleeping component.
+func (s *ScheduledScalingAction) UpdateScaleToZero(service *dbmodel.TenantEnvServices, eventID string, req *apimodel.ScaleToZeroReq) (*dbmodel.TenantEnvServiceScaleToZero, error) {
+	if req.IdleEnable && dbmodel.ServiceKind(service.Kind) == dbmodel.ServiceKindThirdParty {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidScaleToZero, "the third-party components can not be scaled to zero")
+	}
+	if req.IdleTimeout == 0 {
+		req.IdleTimeout = defaultIdleTimeout
+	}
+	if req.IdleTimeout < minIdleTimeout {
+		return nil, pkgerr.Wrapf(bcode.ErrInvalidScaleToZero, "the idle timeout must be at least %d seconds", minIdleTimeout)
+	}
+	if req.HoldTimeout == 0 {
+		req.HoldTimeout = defaultHoldTimeout
+	}
+	if req.HoldTimeout < 0 {
+		return nil, pkgerr.Wrap(bcode.ErrInvalidScaleToZero, "the hold timeout can not be negative")
+	}
+
+	setting, err := s.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(service.ServiceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	create := err == gorm.ErrRecordNotFound
+	if create {
+		setting = &dbmodel.TenantEnvServiceScaleToZero{
+			TenantEnvID: service.TenantEnvID,
+			ServiceID:   service.ServiceID,
+		}
+	}
+	ingressChanged := create || setting.IdleEnable != req.IdleEnable || setting.HoldTimeout != req.HoldTimeout
+	setting.IdleEnable = req.IdleEnable
+	setting.IdleTimeout = req.IdleTimeout
+	setting.HoldTimeout = req.HoldTimeout
+	if create {
+		err = s.dbmanager.TenantEnvServiceScaleToZeroDao().AddModel(setting)
+	} else {
+		err = s.dbmanager.TenantEnvServiceScaleToZeroDao().UpdateModel(setting)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ingressChanged {
+		err = s.mqclient.SendBuilderTopic(client.TaskStruct{
+			Topic:    client.WorkerTopic,
+			TaskType: "apply_rule",
+			TaskBody: map[string]interface{}{
+				"service_id":     service.ServiceID,
+				"deploy_version": service.DeployVersion,
+				"event_id":       eventID,
+				"action":         "update-scale-to-zero",
+			},
+		})
+		if err != nil {
+			return nil, pkgerr.WithMessage(err, "send gateway task")
+		}
+	}
+	return setting, nil
+}
@@ -0,0 +1,35 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+// ScheduledScalingHandler the scheduled scaling rules and the scale-to-zero settings of the components,
+// the rules with empty service id apply to all components of the tenant env.
+type ScheduledScalingHandler interface {
+	ListScheduledScalingRules(tenantEnvID, serviceID string) ([]*dbmodel.TenantEnvServiceScheduledScalingRule, error)
+	CreateScheduledScalingRule(tenantEnvID, serviceID string, req *apimodel.ScheduledScalingRuleReq) (*dbmodel.TenantEnvServiceScheduledScalingRule, error)
+	UpdateScheduledScalingRule(tenantEnvID, serviceID, ruleID string, req *apimodel.ScheduledScalingRuleReq) (*dbmodel.TenantEnvServiceScheduledScalingRule, error)
+	DeleteScheduledScalingRule(tenantEnvID, serviceID, ruleID string) error
+	GetScaleToZero(service *dbmodel.TenantEnvServices) (*dbmodel.TenantEnvServiceScaleToZero, error)
+	UpdateScaleToZero(service *dbmodel.TenantEnvServices, eventID string, req *apimodel.ScaleToZeroReq) (*dbmodel.TenantEnvServiceScaleToZero, error)
+}
@@ -205,6 +205,7 @@ func (s *ServiceAction) buildFromImage(r *api_model.BuildServiceStruct, service
 	}
 	body["image"] = r.Body.ImageURL
 	body["service_id"] = service.ServiceID
+	body["tenant_env_id"] = service.TenantEnvID
 	body["deploy_version"] = r.Body.DeployVersion
 	body["namespace"] = service.Namespace
 	body["operator"] = r.Body.Operator
@@ -798,6 +799,22 @@ func (s *ServiceAction) ServiceCreate(sc *api_model.ServiceStruct) error {
 				return err
 			}
 		}
+		if sc.Endpoints.Registry != nil {
+			if !dbmodel.DiscorveryType(sc.Endpoints.Registry.Type).IsRegistry() {
+				tx.Rollback()
+				return fmt.Errorf("unsupported registry type %s for third-party service", sc.Endpoints.Registry.Type)
+			}
+			if sc.Endpoints.Registry.Servers == "" || sc.Endpoints.Registry.ServiceName == "" {
+				tx.Rollback()
+				return fmt.Errorf("registry servers and service name can not be empty for third-party service")
+			}
+			if err := db.GetManager().ThirdPartySvcDiscoveryCfgDaoTransactions(tx).
+				AddModel(sc.Endpoints.Registry.DbModel(sc.ServiceID)); err != nil {
+				logrus.Errorf("error saving discover center configuration: %v", err)
+				tx.Rollback()
+				return err
+			}
+		}
 		if sc.Endpoints.Static != nil {
 			for _, o := range sc.Endpoints.Static {
 				ep := &dbmodel.Endpoint{
@@ -2849,6 +2866,9 @@ func (s *ServiceAction) deleteComponent(tx *gorm.DB, service *dbmodel.TenantEnvS
 		db.GetManager().ServiceEventDaoTransactions(tx).DelEventByServiceID,
 		db.GetManager().TenantEnvServiceMonitorDaoTransactions(tx).DeleteServiceMonitorByServiceID,
 		db.GetManager().AppConfigGroupServiceDaoTransactions(tx).DeleteEffectiveServiceByServiceID,
+		db.GetManager().AlertRuleDaoTransactions(tx).DeleteByServiceID,
+		db.GetManager().ServiceSLODaoTransactions(tx).DeleteByServiceID,
+		db.GetManager().LogAlertRuleDaoTransactions(tx).DeleteByServiceID,
 	}
 	if err := GetGatewayHandler().DeleteTCPRuleByServiceIDWithTransaction(service.ServiceID, tx); err != nil {
 		return err
@@ -2863,7 +2883,10 @@ func (s *ServiceAction) deleteComponent(tx *gorm.DB, service *dbmodel.TenantEnvS
 			}
 		}
 	}
-	return nil
+	if err := db.GetManager().TenantEnvServiceScheduledScalingRuleDaoTransactions(tx).DeleteByComponentIDs([]string{service.ServiceID}); err != nil {
+		return err
+	}
+	return db.GetManager().TenantEnvServiceScaleToZeroDaoTransactions(tx).DeleteByComponentIDs([]string{service.ServiceID})
 }
 
 // delServiceMetadata deletes service-related metadata in the database.
@@ -2873,12 +2896,23 @@ func (s *ServiceAction) delServiceMetadata(ctx context.Context, serviceID string
 		return err
 	}
 	logrus.Infof("delete service %s %s", serviceID, service.ServiceAlias)
-	return db.GetManager().DB().Transaction(func(tx *gorm.DB) error {
+	err = db.GetManager().DB().Transaction(func(tx *gorm.DB) error {
 		if err := s.deleteThirdComponent(ctx, service); err != nil {
 			return err
 		}
 		return s.deleteComponent(tx, service)
 	})
+	if err != nil {
+		return err
+	}
+	// the alert rules and the slos are deleted with the component, remove the prometheus rules of them
+	if err := GetAlertRuleHandler().SyncAlertRules(service.TenantEnvID, "", serviceID); err != nil {
+		logrus.Warningf("remove the alert rules of the component %s: %v", serviceID, err)
+	}
+	if err := GetSLOHandler().SyncSLOs(service.TenantEnvID, serviceID); err != nil {
+		logrus.Warningf("remove the slos of the component %s: %v", serviceID, err)
+	}
+	return nil
 }
 
 func (s *ServiceAction) deleteThirdComponent(ctx context.Context, component *dbmodel.TenantEnvServices) error {
@@ -2896,7 +2930,8 @@ func (s *ServiceAction) deleteThirdComponent(ctx context.Context, component *dbm
 	if thirdPartySvcDiscoveryCfg == nil {
 		return nil
 	}
-	if thirdPartySvcDiscoveryCfg.Type != string(dbmodel.DiscorveryTypeKubernetes) {
+	discoveryType := dbmodel.DiscorveryType(thirdPartySvcDiscoveryCfg.Type)
+	if discoveryType != dbmodel.DiscorveryTypeKubernetes && !discoveryType.IsRegistry() {
 		return nil
 	}
 
@@ -2974,6 +3009,10 @@ func (s *ServiceAction) ListVersionInfo(serviceID string) (*api_model.BuildListR
 
 // AddAutoscalerRule -
 func (s *ServiceAction) AddAutoscalerRule(req *api_model.AutoscalerRuleReq) error {
+	if err := validateAutoscalerRule(req); err != nil {
+		return err
+	}
+
 	tx := db.GetManager().Begin()
 	defer db.GetManager().EnsureEndTransactionFunc()
 
@@ -2984,6 +3023,7 @@ func (s *ServiceAction) AddAutoscalerRule(req *api_model.AutoscalerRuleReq) erro
 		XPAType:     req.XPAType,
 		MinReplicas: req.MinReplicas,
 		MaxReplicas: req.MaxReplicas,
+		Behavior:    api_model.AutoscalerBehaviorString(req.Behavior),
 	}
 	if err := db.GetManager().TenantEnvServceAutoscalerRulesDaoTransactions(tx).AddModel(r); err != nil {
 		tx.Rollback()
@@ -2991,13 +3031,7 @@ func (s *ServiceAction) AddAutoscalerRule(req *api_model.AutoscalerRuleReq) erro
 	}
 
 	for _, metric := range req.Metrics {
-		m := &dbmodel.TenantEnvServiceAutoscalerRuleMetrics{
-			RuleID:            req.RuleID,
-			MetricsType:       metric.MetricsType,
-			MetricsName:       metric.MetricsName,
-			MetricTargetType:  metric.MetricTargetType,
-			MetricTargetValue: metric.MetricTargetValue,
-		}
+		m := metric.DbModel(req.RuleID)
 		if err := db.GetManager().TenantEnvServceAutoscalerRuleMetricsDaoTransactions(tx).AddModel(m); err != nil {
 			tx.Rollback()
 			return err
@@ -3023,6 +3057,9 @@ func (s *ServiceAction) AddAutoscalerRule(req *api_model.AutoscalerRuleReq) erro
 
 // UpdAutoscalerRule -
 func (s *ServiceAction) UpdAutoscalerRule(req *api_model.AutoscalerRuleReq) error {
+	if err := validateAutoscalerRule(req); err != nil {
+		return err
+	}
 	rule, err := db.GetManager().TenantEnvServceAutoscalerRulesDao().GetByRuleID(req.RuleID)
 	if err != nil {
 		return err
@@ -3032,6 +3069,7 @@ func (s *ServiceAction) UpdAutoscalerRule(req *api_model.AutoscalerRuleReq) erro
 	rule.XPAType = req.XPAType
 	rule.MinReplicas = req.MinReplicas
 	rule.MaxReplicas = req.MaxReplicas
+	rule.Behavior = api_model.AutoscalerBehaviorString(req.Behavior)
 
 	tx := db.GetManager().Begin()
 	defer db.GetManager().EnsureEndTransactionFunc()
@@ -3048,13 +3086,7 @@ func (s *ServiceAction) UpdAutoscalerRule(req *api_model.AutoscalerRuleReq) erro
 	}
 
 	for _, metric := range req.Metrics {
-		m := &dbmodel.TenantEnvServiceAutoscalerRuleMetrics{
-			RuleID:            req.RuleID,
-			MetricsType:       metric.MetricsType,
-			MetricsName:       metric.MetricsName,
-			MetricTargetType:  metric.MetricTargetType,
-			MetricTargetValue: metric.MetricTargetValue,
-		}
+		m := metric.DbModel(req.RuleID)
 		if err := db.GetManager().TenantEnvServceAutoscalerRuleMetricsDaoTransactions(tx).AddModel(m); err != nil {
 			tx.Rollback()
 			return err
@@ -3524,7 +3556,7 @@ func (s *ServiceAction) SyncComponentEndpoints(tx *gorm.DB, components []*api_mo
 			continue
 		}
 		componentIDs = append(componentIDs, component.ComponentBase.ComponentID)
-		if component.Endpoint.Kubernetes != nil {
+		if component.Endpoint.Kubernetes != nil || component.Endpoint.Registry != nil {
 			thirdPartySvcDiscoveryCfgs = append(thirdPartySvcDiscoveryCfgs, component.Endpoint.DbModel(component.ComponentBase.ComponentID))
 		}
 	}
@@ -85,13 +85,24 @@ func (b *BatchOperationHandler) Build(ctx context.Context, tenantEnv *dbmodel.Te
 		return nil, errors.WithMessage(err, "new alloc memory")
 	}
 	batchOpResult := allocm.BatchOpResult()
-	validBuilds := allocm.BatchOpRequests()
+	bads := allocm.BadReasons()
 
-	batchOpReqs, batchOpResult2 := b.checkEvents(batchOpReqs)
+	// check the quota of the tenant env, the build adds a running build and rolls out the component
+	validBuilds, batchOpResult2, err := b.checkQuota(ctx, tenantEnv, allocm.components, allocm.BatchOpRequests(), bads, func(component *dbmodel.TenantEnvServices) QuotaRequest {
+		req := ComponentRolloutQuotaRequest(component, component.Replicas, component.ContainerCPU, component.ContainerMemory)
+		req.Builds = 1
+		return req
+	})
+	if err != nil {
+		return nil, err
+	}
+	batchOpResult = append(batchOpResult, batchOpResult2...)
+
+	batchOpReqs, batchOpResult2 = b.checkEvents(batchOpReqs)
 	batchOpResult = append(batchOpResult, batchOpResult2...)
 
 	// create events
-	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, allocm.badOpRequest, allocm.memoryType); err != nil {
+	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, bads); err != nil {
 		return nil, err
 	}
 
@@ -131,13 +142,22 @@ func (b *BatchOperationHandler) Start(ctx context.Context, tenantEnv *dbmodel.Te
 		return nil, errors.WithMessage(err, "new alloc memory")
 	}
 	batchOpResult := allocm.BatchOpResult()
-	validRequestes := allocm.BatchOpRequests()
+	bads := allocm.BadReasons()
 
-	batchOpReqs, batchOpResult2 := b.checkEvents(batchOpReqs)
+	// check the quota of the tenant env
+	validRequestes, batchOpResult2, err := b.checkQuota(ctx, tenantEnv, allocm.components, allocm.BatchOpRequests(), bads, func(component *dbmodel.TenantEnvServices) QuotaRequest {
+		return ComponentQuotaRequest(component, component.Replicas, component.ContainerCPU, component.ContainerMemory)
+	})
+	if err != nil {
+		return nil, err
+	}
+	batchOpResult = append(batchOpResult, batchOpResult2...)
+
+	batchOpReqs, batchOpResult2 = b.checkEvents(batchOpReqs)
 	batchOpResult = append(batchOpResult, batchOpResult2...)
 
 	// create events
-	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, allocm.BadOpRequests(), allocm.memoryType); err != nil {
+	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, bads); err != nil {
 		return nil, err
 	}
 
@@ -171,7 +191,7 @@ func (b *BatchOperationHandler) Stop(ctx context.Context, tenantEnv *dbmodel.Ten
 	batchOpReqs, batchOpResult := b.checkEvents(batchOpReqs)
 
 	// create events
-	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, nil, ""); err != nil {
+	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, nil); err != nil {
 		return nil, err
 	}
 
@@ -210,13 +230,22 @@ func (b *BatchOperationHandler) Upgrade(ctx context.Context, tenantEnv *dbmodel.
 		return nil, errors.WithMessage(err, "new alloc memory")
 	}
 	batchOpResult := allocm.BatchOpResult()
-	validUpgrades := allocm.BatchOpRequests()
+	bads := allocm.BadReasons()
+
+	// check the quota of the tenant env
+	validUpgrades, batchOpResult2, err := b.checkQuota(ctx, tenantEnv, allocm.components, allocm.BatchOpRequests(), bads, func(component *dbmodel.TenantEnvServices) QuotaRequest {
+		return ComponentRolloutQuotaRequest(component, component.Replicas, component.ContainerCPU, component.ContainerMemory)
+	})
+	if err != nil {
+		return nil, err
+	}
+	batchOpResult = append(batchOpResult, batchOpResult2...)
 
-	validUpgrades, batchOpResult2 := b.checkEvents(validUpgrades)
+	validUpgrades, batchOpResult2 = b.checkEvents(validUpgrades)
 	batchOpResult = append(batchOpResult, batchOpResult2...)
 
 	// create events
-	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, allocm.BadOpRequests(), allocm.memoryType); err != nil {
+	if err := b.createEvents(tenantEnv.UUID, operator, batchOpReqs, bads); err != nil {
 		return nil, err
 	}
 
@@ -259,14 +288,48 @@ func (b *BatchOperationHandler) checkEvents(batchOpReqs model.BatchOpRequesters)
 	return validReqs, batchOpResult
 }
 
-func (b *BatchOperationHandler) createEvents(tenantEnvID, operator string, batchOpReqs, badOpReqs model.BatchOpRequesters, memoryType string) error {
+// checkQuota checks the quota of the tenant env for the requests as a whole, with the same quota requests as
+// the single operations. The rejected requests are recorded in bads by the event id, with the reason.
+func (b *BatchOperationHandler) checkQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, components map[string]*dbmodel.TenantEnvServices,
+	batchOpReqs model.BatchOpRequesters, bads map[string]string, quotaRequest func(*dbmodel.TenantEnvServices) QuotaRequest) (model.BatchOpRequesters, model.BatchOpResult, error) {
 	if logrus.IsLevelEnabled(logrus.DebugLevel) {
-		defer util.Elapsed("[BatchOperationHandler] create events")()
+		defer util.Elapsed("[BatchOperationHandler] check quota")()
 	}
 
-	bads := make(map[string]struct{})
-	for _, req := range badOpReqs {
-		bads[req.GetEventID()] = struct{}{}
+	var checked model.BatchOpRequesters
+	var quotaReqs []QuotaRequest
+	var validReqs model.BatchOpRequesters
+	for _, req := range batchOpReqs {
+		component, ok := components[req.GetComponentID()]
+		if !ok || component.Kind == dbmodel.ServiceKindThirdParty.String() {
+			validReqs = append(validReqs, req)
+			continue
+		}
+		checked = append(checked, req)
+		quotaReqs = append(quotaReqs, quotaRequest(component))
+	}
+	errs, err := GetTenantEnvManager().CheckTenantEnvQuotas(ctx, tenantEnv, quotaReqs)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "check tenant env quota")
+	}
+	var batchOpResult model.BatchOpResult
+	for i, req := range checked {
+		if errs[i] == nil {
+			validReqs = append(validReqs, req)
+			continue
+		}
+		item := req.BatchOpFailureItem()
+		item.ErrMsg = errs[i].Error()
+		batchOpResult = append(batchOpResult, item)
+		bads[req.GetEventID()] = errs[i].Error()
+	}
+	return validReqs, batchOpResult, nil
+}
+
+// createEvents creates the events of the requests, the events in bads are failed with the reason
+func (b *BatchOperationHandler) createEvents(tenantEnvID, operator string, batchOpReqs model.BatchOpRequesters, bads map[string]string) error {
+	if logrus.IsLevelEnabled(logrus.DebugLevel) {
+		defer util.Elapsed("[BatchOperationHandler] create events")()
 	}
 
 	var events []*dbmodel.ServiceEvent
@@ -281,9 +344,9 @@ func (b *BatchOperationHandler) createEvents(tenantEnvID, operator string, batch
 			SynType:     dbmodel.AsyncEventType,
 			OptType:     req.OpType(),
 		}
-		_, ok := bads[req.GetEventID()]
+		reason, ok := bads[req.GetEventID()]
 		if ok {
-			event.Reason = memoryType
+			event.Reason = reason
 			event.EndTime = event.StartTime
 			event.FinalStatus = "complete"
 			event.Status = "failure"
@@ -541,6 +604,15 @@ func (a *AllocMemory) BadOpRequests() model.BatchOpRequesters {
 	return a.badOpRequest
 }
 
+// BadReasons returns the reasons of the badOpRequests by the event id.
+func (a *AllocMemory) BadReasons() map[string]string {
+	bads := make(map[string]string, len(a.badOpRequest))
+	for _, req := range a.badOpRequest {
+		bads[req.GetEventID()] = a.memoryType
+	}
+	return bads
+}
+
 func (a *AllocMemory) listComponents(componentIDs []string) (map[string]*dbmodel.TenantEnvServices, error) {
 	components, err := db.GetManager().TenantEnvServiceDao().GetServiceByIDs(componentIDs)
 	if err != nil {
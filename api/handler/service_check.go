@@ -59,6 +59,7 @@ func (s *ServiceAction) ServiceCheck(scs *api_model.ServiceCheckStruct) (string,
 		TaskType: "service_check",
 		TaskBody: scs.Body,
 		Topic:    topic,
+		Priority: client.TaskPriorityHigh,
 	})
 	if err != nil {
 		logrus.Errorf("enqueue service check message to mq error, %v", err)
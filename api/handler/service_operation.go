@@ -26,6 +26,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
 	"github.com/wutong-paas/wutong/db"
 	dbmodel "github.com/wutong-paas/wutong/db/model"
 	gclient "github.com/wutong-paas/wutong/mq/client"
@@ -124,6 +125,34 @@ func (o *OperationHandler) build(batchOpReq model.ComponentOpReq) error {
 	return nil
 }
 
+// CancelBuild cancel the build of the event. The cancel task is sent to the
+// cancel topic of the builders, the node running or holding the build stops it.
+func (o *OperationHandler) CancelBuild(service *dbmodel.TenantEnvServices, eventID, operator string) error {
+	version, err := db.GetManager().VersionInfoDao().GetVersionByEventID(eventID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return bcode.ErrBuildVersionNotFound
+		}
+		return err
+	}
+	if version.ServiceID != service.ServiceID {
+		return bcode.ErrBuildVersionNotFound
+	}
+	if version.FinalStatus != "" {
+		return bcode.ErrBuildFinished
+	}
+	body := make(map[string]interface{})
+	body["tenant_env_id"] = service.TenantEnvID
+	body["service_id"] = service.ServiceID
+	body["event_id"] = eventID
+	return o.mqCli.SendBuilderTopic(gclient.TaskStruct{
+		Topic:    gclient.BuilderCancelTopic,
+		TaskType: "cancel_build",
+		TaskBody: body,
+		Operator: operator,
+	})
+}
+
 // Stop service stop
 func (o *OperationHandler) Stop(batchOpReq model.ComponentOpReq) error {
 	service, err := db.GetManager().TenantEnvServiceDao().GetServiceByID(batchOpReq.GetComponentID())
@@ -305,6 +334,7 @@ func (o *OperationHandler) buildFromImage(r *model.ComponentBuildReq, service *d
 	body["operator"] = r.Operator
 	body["image"] = r.ImageInfo.ImageURL
 	body["service_id"] = service.ServiceID
+	body["tenant_env_id"] = service.TenantEnvID
 	body["deploy_version"] = r.DeployVersion
 	body["namespace"] = service.Namespace
 	body["event_id"] = r.GetEventID()
@@ -0,0 +1,371 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	pkgerr "github.com/pkg/errors"
+	monitorv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	monitorclient "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
+	"github.com/sirupsen/logrus"
+	apimodel "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/alerting"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+	"github.com/wutong-paas/wutong/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sloBurnRateWindows the windows of the burn rates in the status of a SLO
+var sloBurnRateWindows = []string{"1h", "6h", "1d", "3d"}
+
+// SLOAction -
+type SLOAction struct {
+	dbmanager     db.Manager
+	monitorClient monitorclient.Interface
+	promClient    prometheus.Interface
+}
+
+// CreateSLOManager creates slo manager
+func CreateSLOManager(dbmanager db.Manager, monitorClient monitorclient.Interface, promClient prometheus.Interface) *SLOAction {
+	return &SLOAction{
+		dbmanager:     dbmanager,
+		monitorClient: monitorClient,
+		promClient:    promClient,
+	}
+}
+
+// ListSLOs lists the slos of the component
+func (a *SLOAction) ListSLOs(serviceID string) ([]*apimodel.ServiceSLO, error) {
+	slos, err := a.dbmanager.ServiceSLODao().ListByServiceID(serviceID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*apimodel.ServiceSLO, 0, len(slos))
+	for _, slo := range slos {
+		result = append(result, sloResponse(slo))
+	}
+	return result, nil
+}
+
+// CreateSLO validates and creates the slo, then applies the slo rules of the component
+func (a *SLOAction) CreateSLO(tenantEnvID, serviceID string, req *apimodel.ServiceSLOReq) (*apimodel.ServiceSLO, error) {
+	if err := a.checkSLO(serviceID, "", req); err != nil {
+		return nil, err
+	}
+	slo := &dbmodel.ServiceSLO{
+		SLOID:       util.NewUUID(),
+		TenantEnvID: tenantEnvID,
+		ServiceID:   serviceID,
+	}
+	setSLO(slo, req)
+	if err := a.dbmanager.ServiceSLODao().AddModel(slo); err != nil {
+		return nil, err
+	}
+	if err := a.SyncSLOs(tenantEnvID, serviceID); err != nil {
+		return nil, err
+	}
+	return sloResponse(slo), nil
+}
+
+// UpdateSLO validates and updates the slo, then applies the slo rules of the component
+func (a *SLOAction) UpdateSLO(serviceID, sloID string, req *apimodel.ServiceSLOReq) (*apimodel.ServiceSLO, error) {
+	slo, err := a.getSLO(serviceID, sloID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkSLO(serviceID, sloID, req); err != nil {
+		return nil, err
+	}
+	setSLO(slo, req)
+	if err := a.dbmanager.ServiceSLODao().UpdateModel(slo); err != nil {
+		return nil, err
+	}
+	if err := a.SyncSLOs(slo.TenantEnvID, serviceID); err != nil {
+		return nil, err
+	}
+	return sloResponse(slo), nil
+}
+
+// DeleteSLO deletes the slo, then applies the slo rules of the component
+func (a *SLOAction) DeleteSLO(serviceID, sloID string) error {
+	slo, err := a.getSLO(serviceID, sloID)
+	if err != nil {
+		return err
+	}
+	if err := a.dbmanager.ServiceSLODao().DeleteBySLOID(sloID); err != nil {
+		return err
+	}
+	return a.SyncSLOs(slo.TenantEnvID, serviceID)
+}
+
+// GetSLOStatus queries the sli, the remaining error budget over the window and the recent burn rates
+func (a *SLOAction) GetSLOStatus(serviceID, sloID string) (*apimodel.ServiceSLOStatus, error) {
+	slo, err := a.getSLO(serviceID, sloID)
+	if err != nil {
+		return nil, err
+	}
+	tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByUUID(slo.TenantEnvID)
+	if err != nil {
+		return nil, pkgerr.Wrapf(err, "get tenant env %s", slo.TenantEnvID)
+	}
+	s, err := a.sloSpec(tenantEnv.Namespace, slo)
+	if err != nil {
+		return nil, err
+	}
+
+	errorRatio, ok, err := a.queryErrorRatio(s, s.WindowErrorRatioExpr())
+	if err != nil {
+		return nil, err
+	}
+	status := &apimodel.ServiceSLOStatus{
+		SLOID:                slo.SLOID,
+		Objective:            slo.Objective,
+		Window:               slo.Window,
+		NoData:               !ok,
+		SLI:                  (1 - errorRatio) * 100,
+		ErrorBudgetRemaining: s.BudgetRemaining(errorRatio) * 100,
+		BurnRates:            make(map[string]float64, len(sloBurnRateWindows)),
+	}
+	status.BudgetExhausted = status.ErrorBudgetRemaining <= 0
+	for _, window := range sloBurnRateWindows {
+		errorRatio, _, err := a.queryErrorRatio(s, s.ErrorRatioExpr(window))
+		if err != nil {
+			return nil, err
+		}
+		status.BurnRates[window] = s.BurnRate(errorRatio)
+	}
+	return status, nil
+}
+
+// queryErrorRatio queries the ratio of the bad requests by the expr, returns false if there is no request
+func (a *SLOAction) queryErrorRatio(s *alerting.SLO, expr string) (float64, bool, error) {
+	metric := a.promClient.GetMetric(expr, time.Now())
+	if metric.Error != "" {
+		return 0, false, fmt.Errorf("query the error ratio of the slo %s: %s", s.Name, metric.Error)
+	}
+	if len(metric.MetricValues) == 0 || metric.MetricValues[0].Sample == nil {
+		return 0, false, nil
+	}
+	value := metric.MetricValues[0].Sample.Value()
+	if math.IsNaN(value) {
+		return 0, false, nil
+	}
+	return value, true, nil
+}
+
+// SyncSLOs renders the enabled slos of the component into a PrometheusRule, the PrometheusRule
+// is deleted if there is no enabled slo.
+func (a *SLOAction) SyncSLOs(tenantEnvID, serviceID string) error {
+	tenantEnv, err := a.dbmanager.TenantEnvDao().GetTenantEnvByUUID(tenantEnvID)
+	if err != nil {
+		return pkgerr.Wrapf(err, "get tenant env %s", tenantEnvID)
+	}
+	slos, err := a.dbmanager.ServiceSLODao().ListByServiceID(serviceID)
+	if err != nil {
+		return err
+	}
+	var specs []*alerting.SLO
+	for _, slo := range slos {
+		if !slo.Enabled {
+			continue
+		}
+		s, err := a.sloSpec(tenantEnv.Namespace, slo)
+		if err != nil {
+			// the other slos of the component are still applied
+			logrus.Warningf("skip the slo %s of the component %s: %v", slo.SLOID, serviceID, err)
+			continue
+		}
+		specs = append(specs, s)
+	}
+	promRule, err := renderSLORules(tenantEnv.Namespace, tenantEnvID, serviceID, specs)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if promRule == nil {
+		return deletePrometheusRule(ctx, a.monitorClient, tenantEnv.Namespace, sloResourceName(serviceID))
+	}
+	return ensurePrometheusRule(ctx, a.monitorClient, promRule)
+}
+
+func (a *SLOAction) getSLO(serviceID, sloID string) (*dbmodel.ServiceSLO, error) {
+	slo, err := a.dbmanager.ServiceSLODao().GetBySLOID(sloID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, bcode.ErrSLONotFound
+		}
+		return nil, err
+	}
+	if slo.ServiceID != serviceID {
+		return nil, bcode.ErrSLONotFound
+	}
+	return slo, nil
+}
+
+// getHTTPRule returns the http rule of the component
+func (a *SLOAction) getHTTPRule(serviceID, httpRuleID string) (*dbmodel.HTTPRule, error) {
+	rule, err := a.dbmanager.HTTPRuleDao().GetHTTPRuleByID(httpRuleID)
+	if err != nil {
+		return nil, err
+	}
+	if rule.UUID == "" || rule.ServiceID != serviceID {
+		return nil, pkgerr.Wrapf(bcode.ErrInvalidSLO, "http rule %s not found", httpRuleID)
+	}
+	return rule, nil
+}
+
+// sloSpec returns the slo selecting the gateway metrics of the component, or of the
+// domain of the http rule
+func (a *SLOAction) sloSpec(namespace string, slo *dbmodel.ServiceSLO) (*alerting.SLO, error) {
+	s := &alerting.SLO{
+		ID:               slo.SLOID,
+		Name:             slo.Name,
+		Type:             slo.SLIType,
+		Objective:        slo.Objective / 100,
+		Window:           slo.Window,
+		LatencyThreshold: slo.LatencyThreshold,
+		Selector: map[string]string{
+			alerting.NamespaceLabel: namespace,
+			"service_id":            slo.ServiceID,
+		},
+	}
+	if slo.HTTPRuleID != "" {
+		rule, err := a.getHTTPRule(slo.ServiceID, slo.HTTPRuleID)
+		if err != nil {
+			return nil, err
+		}
+		s.Selector["host"] = rule.Domain
+	}
+	return s, nil
+}
+
+// checkSLO validates the slo, the http rule should belong to the component and the name
+// should be unique in the component
+func (a *SLOAction) checkSLO(serviceID, sloID string, req *apimodel.ServiceSLOReq) error {
+	s := &alerting.SLO{
+		Type:             req.SLIType,
+		Objective:        req.Objective / 100,
+		Window:           req.Window,
+		LatencyThreshold: req.LatencyThreshold,
+	}
+	if err := s.Validate(); err != nil {
+		return pkgerr.Wrapf(bcode.ErrInvalidSLO, "%v", err)
+	}
+	retention, err := a.promClient.GetRetention()
+	if err != nil {
+		logrus.Warningf("get the retention of the monitor: %v, skip checking the window of the slo", err)
+	} else if err := s.ValidateRetention(retention); err != nil {
+		return pkgerr.Wrapf(bcode.ErrInvalidSLO, "%v", err)
+	}
+	if req.HTTPRuleID != "" {
+		if _, err := a.getHTTPRule(serviceID, req.HTTPRuleID); err != nil {
+			return err
+		}
+	}
+	slos, err := a.dbmanager.ServiceSLODao().ListByServiceID(serviceID)
+	if err != nil {
+		return err
+	}
+	for _, slo := range slos {
+		if slo.Name == req.Name && slo.SLOID != sloID {
+			return bcode.ErrSLONameExists
+		}
+	}
+	return nil
+}
+
+func setSLO(slo *dbmodel.ServiceSLO, req *apimodel.ServiceSLOReq) {
+	slo.Name = req.Name
+	slo.HTTPRuleID = req.HTTPRuleID
+	slo.SLIType = req.SLIType
+	slo.Objective = req.Objective
+	slo.Window = req.Window
+	slo.LatencyThreshold = 0
+	if req.SLIType == alerting.SLITypeLatency {
+		slo.LatencyThreshold = req.LatencyThreshold
+	}
+	slo.Enabled = req.Enabled
+}
+
+func sloResponse(slo *dbmodel.ServiceSLO) *apimodel.ServiceSLO {
+	return &apimodel.ServiceSLO{
+		SLOID:            slo.SLOID,
+		TenantEnvID:      slo.TenantEnvID,
+		ServiceID:        slo.ServiceID,
+		HTTPRuleID:       slo.HTTPRuleID,
+		Name:             slo.Name,
+		SLIType:          slo.SLIType,
+		Objective:        slo.Objective,
+		Window:           slo.Window,
+		LatencyThreshold: slo.LatencyThreshold,
+		Enabled:          slo.Enabled,
+		CreateTime:       slo.CreatedAt,
+	}
+}
+
+// sloResourceName returns the name of the PrometheusRule of the slos of the component
+func sloResourceName(serviceID string) string {
+	return "slo-" + serviceID
+}
+
+// renderSLORules renders the recording rules of the error ratios and the burn rate alerts
+// of the slos into a PrometheusRule, one group for each slo. Returns nil if there is no slo.
+func renderSLORules(namespace, tenantEnvID, serviceID string, slos []*alerting.SLO) (*monitorv1.PrometheusRule, error) {
+	if len(slos) == 0 {
+		return nil, nil
+	}
+	scopeLabels := map[string]string{
+		"tenant_env_id": tenantEnvID,
+		"service_id":    serviceID,
+	}
+	var groups []monitorv1.RuleGroup
+	for _, s := range slos {
+		rules, err := s.Rules(scopeLabels)
+		if err != nil {
+			return nil, pkgerr.Wrapf(bcode.ErrInvalidSLO, "slo %s: %v", s.Name, err)
+		}
+		groups = append(groups, monitorv1.RuleGroup{
+			Name:  "slo-" + s.ID,
+			Rules: rules,
+		})
+	}
+	resourceLabels := map[string]string{"creator": "Wutong"}
+	for key, value := range scopeLabels {
+		resourceLabels[key] = value
+	}
+	return &monitorv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sloResourceName(serviceID),
+			Namespace: namespace,
+			Labels:    resourceLabels,
+		},
+		Spec: monitorv1.PrometheusRuleSpec{
+			Groups: groups,
+		},
+	}, nil
+}
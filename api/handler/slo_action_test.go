@@ -0,0 +1,89 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/wutong-paas/wutong/pkg/alerting"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+)
+
+type fakePrometheus struct {
+	prometheus.Interface
+	metric prometheus.Metric
+}
+
+func (f *fakePrometheus) GetMetric(expr string, ts time.Time) prometheus.Metric {
+	return f.metric
+}
+
+func TestRenderSLORules(t *testing.T) {
+	promRule, err := renderSLORules("ns", "tid", "sid", nil)
+	if err != nil || promRule != nil {
+		t.Fatalf("want nil for no slo, got %v, %v", promRule, err)
+	}
+	slos := []*alerting.SLO{
+		{ID: "a", Name: "availability", Type: alerting.SLITypeAvailability, Objective: 0.999, Window: "30d"},
+		{ID: "b", Name: "latency", Type: alerting.SLITypeLatency, Objective: 0.99, Window: "7d", LatencyThreshold: 0.5},
+	}
+	promRule, err = renderSLORules("ns", "tid", "sid", slos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if promRule.Name != "slo-sid" || promRule.Namespace != "ns" || promRule.Labels["service_id"] != "sid" {
+		t.Errorf("unexpected metadata %+v", promRule.ObjectMeta)
+	}
+	if len(promRule.Spec.Groups) != 2 || promRule.Spec.Groups[1].Name != "slo-b" {
+		t.Fatalf("want a group for each slo, got %+v", promRule.Spec.Groups)
+	}
+	for _, rule := range promRule.Spec.Groups[1].Rules {
+		if rule.Labels["slo_id"] != "b" || rule.Labels["tenant_env_id"] != "tid" || rule.Labels["service_id"] != "sid" {
+			t.Errorf("unexpected labels %v", rule.Labels)
+		}
+	}
+}
+
+func TestQueryErrorRatio(t *testing.T) {
+	slo := &alerting.SLO{Name: "api", Type: alerting.SLITypeAvailability, Objective: 0.99}
+	sample := func(value float64) prometheus.Metric {
+		return prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{{Sample: &prometheus.Point{0, value}}}}}
+	}
+	tests := []struct {
+		name    string
+		metric  prometheus.Metric
+		ratio   float64
+		ok      bool
+		wantErr bool
+	}{
+		{name: "error ratio", metric: sample(0.005), ratio: 0.005, ok: true},
+		{name: "no request", metric: sample(math.NaN())},
+		{name: "no series", metric: prometheus.Metric{}},
+		{name: "query error", metric: prometheus.Metric{Error: "timeout"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		a := &SLOAction{promClient: &fakePrometheus{metric: tc.metric}}
+		ratio, ok, err := a.queryErrorRatio(slo, "1h")
+		if (err != nil) != tc.wantErr || ok != tc.ok || ratio != tc.ratio {
+			t.Errorf("%s: want %v, %v, got %v, %v, %v", tc.name, tc.ratio, tc.ok, ratio, ok, err)
+		}
+	}
+}
@@ -153,7 +153,10 @@ func (t *TenantEnvAction) GetTenantEnvs(tenantName, query string) ([]*dbmodel.Te
 
 // UpdateTenantEnv update tenant env info
 func (t *TenantEnvAction) UpdateTenantEnv(tenantEnv *dbmodel.TenantEnvs) error {
-	return db.GetManager().TenantEnvDao().UpdateModel(tenantEnv)
+	if err := db.GetManager().TenantEnvDao().UpdateModel(tenantEnv); err != nil {
+		return err
+	}
+	return t.SyncTenantEnvQuota(context.Background(), tenantEnv)
 }
 
 // DeleteTenantEnv deletes tenant env based on the given tenantEnvID.
@@ -51,4 +51,10 @@ type TenantEnvHandler interface {
 	CheckResourceName(ctx context.Context, namespace string, req *api_model.CheckResourceNameReq) (*api_model.CheckResourceNameResp, error)
 	GetKubeConfig(namespace string) (string, error)
 	GetKubeResources(namespace, tenantEnvID string, customSetting api_model.KubeResourceCustomSetting) (string, error)
+	GetTenantEnvQuota(tenantEnv *dbmodel.TenantEnvs) *api_model.TenantEnvQuota
+	UpdateTenantEnvQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, quota *api_model.TenantEnvQuota) error
+	GetTenantEnvQuotaUsage(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, excludeServiceID string) (*api_model.TenantEnvQuotaUsage, error)
+	CheckTenantEnvQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, req QuotaRequest) error
+	CheckTenantEnvQuotas(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, reqs []QuotaRequest) ([]error, error)
+	SyncTenantEnvQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs) error
 }
@@ -0,0 +1,495 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/util/constants"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// tenantEnvQuotaName the name of the ResourceQuota and the LimitRange mirrored from the quota of the tenant env
+	tenantEnvQuotaName = "wutong-quota"
+	// the resources of the component if not set, the same as the worker
+	defaultContainerCPU    = 2000
+	defaultContainerMemory = 512
+	// the resources of the plugin sidecars if not set, the same as the worker
+	defaultPluginCPU    = 200
+	defaultPluginMemory = 256
+	// defaultVolumeCapacity the capacity of the volume if not set, the same as the worker, unit: GB
+	defaultVolumeCapacity = 20
+	// defaultContainerEphemeralStorage the ephemeral storage limit of the containers without one, unit: MB
+	defaultContainerEphemeralStorage = 1024
+	// buildQuotaWindow the builds created before it are not counted as running,
+	// a builder lost during the build never finishes the version.
+	buildQuotaWindow = 2 * time.Hour
+)
+
+// QuotaRequest the resources an operation adds to the tenant env. The pods, the cpu and the memory are
+// the totals of the component after the operation, they replace the ones of the running component.
+type QuotaRequest struct {
+	ServiceID string
+	Pods      int
+	CPU       int
+	Memory    int
+	Storage   int64
+	TCPPorts  int
+	Builds    int
+}
+
+// ComponentQuotaRequest returns the quota request of the component running with the replicas, the cpu and the memory.
+// The resources of the plugin sidecars are added by the check.
+func ComponentQuotaRequest(service *dbmodel.TenantEnvServices, replicas, cpu, memory int) QuotaRequest {
+	return QuotaRequest{
+		ServiceID: service.ServiceID,
+		Pods:      replicas,
+		CPU:       replicas * componentCPU(cpu),
+		Memory:    replicas * componentMemory(memory),
+	}
+}
+
+// ComponentRolloutQuotaRequest returns the quota request of the component rolled out with the replicas, the cpu
+// and the memory. The surge pods of the rolling update are counted, they are created before the old pods are deleted.
+func ComponentRolloutQuotaRequest(service *dbmodel.TenantEnvServices, replicas, cpu, memory int) QuotaRequest {
+	return ComponentQuotaRequest(service, replicas+surgePods(service, replicas), cpu, memory)
+}
+
+// surgePods returns the pods the rolling update of the deployment may create over the replicas, the default
+// max surge of kubernetes is 25% rounded up. The statefulsets and the recreated deployments do not surge.
+func surgePods(service *dbmodel.TenantEnvServices, replicas int) int {
+	if replicas <= 0 || service.IsState() || service.UpgradeMethod == "OnDelete" {
+		return 0
+	}
+	return (replicas + 3) / 4
+}
+
+func componentCPU(cpu int) int {
+	if cpu == 0 {
+		return defaultContainerCPU
+	}
+	return cpu
+}
+
+func componentMemory(memory int) int {
+	if memory == 0 {
+		return defaultContainerMemory
+	}
+	return memory
+}
+
+func pluginCPU(cpu int) int {
+	if cpu == 0 {
+		return defaultPluginCPU
+	}
+	return cpu
+}
+
+func pluginMemory(memory int) int {
+	if memory == 0 {
+		return defaultPluginMemory
+	}
+	return memory
+}
+
+// VolumeStorage returns the capacity the volume claims, the config files and the memory volumes claim nothing
+func VolumeStorage(volume *dbmodel.TenantEnvServiceVolume) int64 {
+	switch volume.VolumeType {
+	case dbmodel.ConfigFileVolumeType.String(), dbmodel.MemoryFSVolumeType.String():
+		return 0
+	}
+	if volume.VolumeCapacity == 0 {
+		return defaultVolumeCapacity
+	}
+	return volume.VolumeCapacity
+}
+
+// GetTenantEnvQuota returns the quota of the tenant env
+func (t *TenantEnvAction) GetTenantEnvQuota(tenantEnv *dbmodel.TenantEnvs) *api_model.TenantEnvQuota {
+	return &api_model.TenantEnvQuota{
+		LimitCPU:              tenantEnv.LimitCPU,
+		LimitMemory:           tenantEnv.LimitMemory,
+		LimitEphemeralStorage: tenantEnv.LimitEphemeralStorage,
+		LimitStorage:          tenantEnv.LimitStorage,
+		LimitPods:             tenantEnv.LimitPods,
+		LimitTCPPorts:         tenantEnv.LimitTCPPorts,
+		LimitBuilds:           tenantEnv.LimitBuilds,
+	}
+}
+
+// UpdateTenantEnvQuota updates the quota of the tenant env and mirrors it into the namespace
+func (t *TenantEnvAction) UpdateTenantEnvQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, quota *api_model.TenantEnvQuota) error {
+	limits := map[string]int{
+		"limit_cpu":               quota.LimitCPU,
+		"limit_memory":            quota.LimitMemory,
+		"limit_ephemeral_storage": quota.LimitEphemeralStorage,
+		"limit_storage":           quota.LimitStorage,
+		"limit_pods":              quota.LimitPods,
+		"limit_tcp_ports":         quota.LimitTCPPorts,
+		"limit_builds":            quota.LimitBuilds,
+	}
+	for name, limit := range limits {
+		if limit < 0 {
+			return errors.Wrapf(bcode.ErrInvalidTenantEnvQuota, "%s can not be negative", name)
+		}
+	}
+	tenantEnv.LimitCPU = quota.LimitCPU
+	tenantEnv.LimitMemory = quota.LimitMemory
+	tenantEnv.LimitEphemeralStorage = quota.LimitEphemeralStorage
+	tenantEnv.LimitStorage = quota.LimitStorage
+	tenantEnv.LimitPods = quota.LimitPods
+	tenantEnv.LimitTCPPorts = quota.LimitTCPPorts
+	tenantEnv.LimitBuilds = quota.LimitBuilds
+	if err := db.GetManager().TenantEnvDao().UpdateModel(tenantEnv); err != nil {
+		return err
+	}
+	return t.SyncTenantEnvQuota(ctx, tenantEnv)
+}
+
+// quotaState the usage of the tenant env quota, with the resources of every running component and the
+// sidecars of every component, so the requests of the components can be checked one after another.
+type quotaState struct {
+	usage *api_model.TenantEnvQuotaUsage
+	// running the pods, the cpu and the memory of the running components, sidecars included
+	running map[string]QuotaRequest
+	// sidecars the cpu and the memory of the plugin sidecars of a pod of the component
+	sidecars map[string]QuotaRequest
+}
+
+// GetTenantEnvQuotaUsage returns the usage of the tenant env quota. The running resources of the component
+// excludeServiceID are not counted, they are replaced by the operation on the component.
+func (t *TenantEnvAction) GetTenantEnvQuotaUsage(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, excludeServiceID string) (*api_model.TenantEnvQuotaUsage, error) {
+	state, err := t.getQuotaState(ctx, tenantEnv)
+	if err != nil {
+		return nil, err
+	}
+	usage := state.excluded(excludeServiceID)
+	return &usage, nil
+}
+
+func (t *TenantEnvAction) getQuotaState(ctx context.Context, tenantEnv *dbmodel.TenantEnvs) (*quotaState, error) {
+	state := &quotaState{
+		usage:    &api_model.TenantEnvQuotaUsage{},
+		running:  make(map[string]QuotaRequest),
+		sidecars: make(map[string]QuotaRequest),
+	}
+	services, err := db.GetManager().TenantEnvServiceDao().GetServicesByTenantEnvID(tenantEnv.UUID)
+	if err != nil {
+		return nil, err
+	}
+	var serviceIDs []string
+	for _, service := range services {
+		serviceIDs = append(serviceIDs, service.ServiceID)
+	}
+	if len(serviceIDs) == 0 {
+		return state, nil
+	}
+
+	relations, err := db.GetManager().TenantEnvServicePluginRelationDao().ListByComponentIDs(serviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range relations {
+		// the init plugins do not run with the component
+		if !relation.Switch || relation.PluginModel == dbmodel.InitPlugin {
+			continue
+		}
+		sidecar := state.sidecars[relation.ServiceID]
+		sidecar.CPU += pluginCPU(relation.ContainerCPU)
+		sidecar.Memory += pluginMemory(relation.ContainerMemory)
+		state.sidecars[relation.ServiceID] = sidecar
+	}
+
+	statuses := t.statusCli.GetStatuss(strings.Join(serviceIDs, ","))
+	for _, service := range services {
+		if service.Kind == dbmodel.ServiceKindThirdParty.String() {
+			continue
+		}
+		if t.statusCli.IsClosedStatus(statuses[service.ServiceID]) {
+			continue
+		}
+		running := state.withSidecars(ComponentQuotaRequest(service, service.Replicas, service.ContainerCPU, service.ContainerMemory))
+		state.running[service.ServiceID] = running
+		state.usage.Pods += running.Pods
+		state.usage.CPU += running.CPU
+		state.usage.Memory += running.Memory
+	}
+
+	volumes, err := db.GetManager().TenantEnvServiceVolumeDao().ListVolumesByComponentIDs(serviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range volumes {
+		state.usage.Storage += VolumeStorage(volume)
+	}
+	if state.usage.TCPPorts, err = db.GetManager().TCPRuleDao().CountByComponentIDs(serviceIDs); err != nil {
+		return nil, err
+	}
+	if state.usage.Builds, err = db.GetManager().VersionInfoDao().CountUnfinishedByComponentIDs(serviceIDs, time.Now().Add(-buildQuotaWindow)); err != nil {
+		return nil, err
+	}
+
+	// the ephemeral storage is only known by kubernetes, it is tracked once limited
+	if tenantEnv.LimitEphemeralStorage == 0 {
+		return state, nil
+	}
+	quota, err := t.kubeClient.CoreV1().ResourceQuotas(tenantEnv.Namespace).Get(ctx, tenantEnvQuotaName, metav1.GetOptions{})
+	if err != nil && !k8sErrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		used := quota.Status.Used[corev1.ResourceLimitsEphemeralStorage]
+		state.usage.EphemeralStorage = int(used.Value() / 1024 / 1024)
+	}
+	return state, nil
+}
+
+// withSidecars adds the plugin sidecars of every pod of the component to the request
+func (s *quotaState) withSidecars(req QuotaRequest) QuotaRequest {
+	sidecar, ok := s.sidecars[req.ServiceID]
+	if !ok || req.ServiceID == "" {
+		return req
+	}
+	req.CPU += req.Pods * sidecar.CPU
+	req.Memory += req.Pods * sidecar.Memory
+	return req
+}
+
+// excluded returns the usage without the running resources of the component
+func (s *quotaState) excluded(serviceID string) api_model.TenantEnvQuotaUsage {
+	usage := *s.usage
+	if running, ok := s.running[serviceID]; ok && serviceID != "" {
+		usage.Pods -= running.Pods
+		usage.CPU -= running.CPU
+		usage.Memory -= running.Memory
+	}
+	return usage
+}
+
+// check checks the request and adds it to the usage if it fits, the request replaces the running component
+func (s *quotaState) check(tenantEnv *dbmodel.TenantEnvs, req QuotaRequest) error {
+	req = s.withSidecars(req)
+	usage := s.excluded(req.ServiceID)
+	if err := checkTenantEnvQuota(tenantEnv, &usage, req); err != nil {
+		return err
+	}
+	usage.Pods += req.Pods
+	usage.CPU += req.CPU
+	usage.Memory += req.Memory
+	usage.Storage += req.Storage
+	usage.TCPPorts += req.TCPPorts
+	usage.Builds += req.Builds
+	*s.usage = usage
+	if req.ServiceID != "" && req.Pods > 0 {
+		s.running[req.ServiceID] = QuotaRequest{ServiceID: req.ServiceID, Pods: req.Pods, CPU: req.CPU, Memory: req.Memory}
+	}
+	return nil
+}
+
+// CheckTenantEnvQuota checks if the resources of the request exceed the quota of the tenant env
+func (t *TenantEnvAction) CheckTenantEnvQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, req QuotaRequest) error {
+	if !quotaLimited(tenantEnv, req) {
+		return nil
+	}
+	state, err := t.getQuotaState(ctx, tenantEnv)
+	if err != nil {
+		return err
+	}
+	return state.check(tenantEnv, req)
+}
+
+// CheckTenantEnvQuotas checks the requests of a batch operation one after another, the requests that fit are
+// counted in the usage of the later ones. It returns the error of every request, nil if the request fits.
+func (t *TenantEnvAction) CheckTenantEnvQuotas(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, reqs []QuotaRequest) ([]error, error) {
+	errs := make([]error, len(reqs))
+	var limited bool
+	for _, req := range reqs {
+		limited = limited || quotaLimited(tenantEnv, req)
+	}
+	if !limited {
+		return errs, nil
+	}
+	state, err := t.getQuotaState(ctx, tenantEnv)
+	if err != nil {
+		return nil, err
+	}
+	for i, req := range reqs {
+		errs[i] = state.check(tenantEnv, req)
+	}
+	return errs, nil
+}
+
+// quotaLimited returns if any resource of the request is limited by the quota
+func quotaLimited(tenantEnv *dbmodel.TenantEnvs, req QuotaRequest) bool {
+	return (tenantEnv.LimitCPU > 0 && req.CPU > 0) ||
+		(tenantEnv.LimitMemory > 0 && req.Memory > 0) ||
+		(tenantEnv.LimitPods > 0 && req.Pods > 0) ||
+		(tenantEnv.LimitStorage > 0 && req.Storage > 0) ||
+		(tenantEnv.LimitTCPPorts > 0 && req.TCPPorts > 0) ||
+		(tenantEnv.LimitBuilds > 0 && req.Builds > 0)
+}
+
+func checkTenantEnvQuota(tenantEnv *dbmodel.TenantEnvs, usage *api_model.TenantEnvQuotaUsage, req QuotaRequest) error {
+	if tenantEnv.LimitCPU > 0 && req.CPU > 0 && usage.CPU+req.CPU > tenantEnv.LimitCPU {
+		return errors.Wrapf(bcode.ErrTenantEnvCPUQuotaExceeded, "used %dm, request %dm, limit %dm", usage.CPU, req.CPU, tenantEnv.LimitCPU)
+	}
+	if tenantEnv.LimitMemory > 0 && req.Memory > 0 && usage.Memory+req.Memory > tenantEnv.LimitMemory {
+		return errors.Wrapf(bcode.ErrTenantEnvMemoryQuotaExceeded, "used %dM, request %dM, limit %dM", usage.Memory, req.Memory, tenantEnv.LimitMemory)
+	}
+	if tenantEnv.LimitPods > 0 && req.Pods > 0 && usage.Pods+req.Pods > tenantEnv.LimitPods {
+		return errors.Wrapf(bcode.ErrTenantEnvPodsQuotaExceeded, "used %d, request %d, limit %d", usage.Pods, req.Pods, tenantEnv.LimitPods)
+	}
+	if tenantEnv.LimitStorage > 0 && req.Storage > 0 && usage.Storage+req.Storage > int64(tenantEnv.LimitStorage) {
+		return errors.Wrapf(bcode.ErrTenantEnvStorageQuotaExceeded, "used %dG, request %dG, limit %dG", usage.Storage, req.Storage, tenantEnv.LimitStorage)
+	}
+	if tenantEnv.LimitTCPPorts > 0 && req.TCPPorts > 0 && usage.TCPPorts+req.TCPPorts > tenantEnv.LimitTCPPorts {
+		return errors.Wrapf(bcode.ErrTenantEnvTCPPortsQuotaExceeded, "used %d, request %d, limit %d", usage.TCPPorts, req.TCPPorts, tenantEnv.LimitTCPPorts)
+	}
+	if tenantEnv.LimitBuilds > 0 && req.Builds > 0 && usage.Builds+req.Builds > tenantEnv.LimitBuilds {
+		return errors.Wrapf(bcode.ErrTenantEnvBuildsQuotaExceeded, "running %d, limit %d", usage.Builds, tenantEnv.LimitBuilds)
+	}
+	return nil
+}
+
+// SyncTenantEnvQuota mirrors the quota of the tenant env into the ResourceQuota and the LimitRange of the namespace,
+// the LimitRange sets the defaults of the containers without limits, which are rejected by the ResourceQuota otherwise.
+func (t *TenantEnvAction) SyncTenantEnvQuota(ctx context.Context, tenantEnv *dbmodel.TenantEnvs) error {
+	quota, limitRange := buildTenantEnvQuota(tenantEnv)
+	if err := t.applyResourceQuota(ctx, tenantEnv.Namespace, quota); err != nil {
+		return fmt.Errorf("apply resource quota of namespace %s: %v", tenantEnv.Namespace, err)
+	}
+	if err := t.applyLimitRange(ctx, tenantEnv.Namespace, limitRange); err != nil {
+		return fmt.Errorf("apply limit range of namespace %s: %v", tenantEnv.Namespace, err)
+	}
+	return nil
+}
+
+// buildTenantEnvQuota returns the ResourceQuota and the LimitRange of the quota, nil if there is nothing to limit
+func buildTenantEnvQuota(tenantEnv *dbmodel.TenantEnvs) (*corev1.ResourceQuota, *corev1.LimitRange) {
+	hard := corev1.ResourceList{}
+	defaults := corev1.ResourceList{}
+	defaultRequests := corev1.ResourceList{}
+	if tenantEnv.LimitCPU > 0 {
+		hard[corev1.ResourceLimitsCPU] = *resource.NewMilliQuantity(int64(tenantEnv.LimitCPU), resource.DecimalSI)
+		defaults[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(min(defaultContainerCPU, tenantEnv.LimitCPU)), resource.DecimalSI)
+		defaultRequests[corev1.ResourceCPU] = *resource.NewMilliQuantity(0, resource.DecimalSI)
+	}
+	if tenantEnv.LimitMemory > 0 {
+		hard[corev1.ResourceLimitsMemory] = *resource.NewQuantity(int64(tenantEnv.LimitMemory)*1024*1024, resource.BinarySI)
+		defaults[corev1.ResourceMemory] = *resource.NewQuantity(int64(min(defaultContainerMemory, tenantEnv.LimitMemory))*1024*1024, resource.BinarySI)
+		defaultRequests[corev1.ResourceMemory] = *resource.NewQuantity(0, resource.BinarySI)
+	}
+	if tenantEnv.LimitEphemeralStorage > 0 {
+		hard[corev1.ResourceLimitsEphemeralStorage] = *resource.NewQuantity(int64(tenantEnv.LimitEphemeralStorage)*1024*1024, resource.BinarySI)
+		defaults[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(int64(min(defaultContainerEphemeralStorage, tenantEnv.LimitEphemeralStorage))*1024*1024, resource.BinarySI)
+		defaultRequests[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(0, resource.BinarySI)
+	}
+	if tenantEnv.LimitStorage > 0 {
+		hard[corev1.ResourceRequestsStorage] = *resource.NewQuantity(int64(tenantEnv.LimitStorage)*1024*1024*1024, resource.BinarySI)
+	}
+	if tenantEnv.LimitPods > 0 {
+		hard[corev1.ResourcePods] = *resource.NewQuantity(int64(tenantEnv.LimitPods), resource.DecimalSI)
+	}
+
+	meta := metav1.ObjectMeta{
+		Name:      tenantEnvQuotaName,
+		Namespace: tenantEnv.Namespace,
+		Labels: map[string]string{
+			constants.ResourceManagedByLabel:   constants.Wutong,
+			constants.ResourceTenantEnvIDLabel: tenantEnv.UUID,
+		},
+	}
+	var quota *corev1.ResourceQuota
+	if len(hard) > 0 {
+		quota = &corev1.ResourceQuota{
+			ObjectMeta: meta,
+			Spec:       corev1.ResourceQuotaSpec{Hard: hard},
+		}
+	}
+	var limitRange *corev1.LimitRange
+	if len(defaults) > 0 {
+		limitRange = &corev1.LimitRange{
+			ObjectMeta: meta,
+			Spec: corev1.LimitRangeSpec{
+				Limits: []corev1.LimitRangeItem{{
+					Type:           corev1.LimitTypeContainer,
+					Default:        defaults,
+					DefaultRequest: defaultRequests,
+				}},
+			},
+		}
+	}
+	return quota, limitRange
+}
+
+func (t *TenantEnvAction) applyResourceQuota(ctx context.Context, namespace string, quota *corev1.ResourceQuota) error {
+	quotas := t.kubeClient.CoreV1().ResourceQuotas(namespace)
+	if quota == nil {
+		if err := quotas.Delete(ctx, tenantEnvQuotaName, metav1.DeleteOptions{}); err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	old, err := quotas.Get(ctx, tenantEnvQuotaName, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		_, err = quotas.Create(ctx, quota, metav1.CreateOptions{})
+		return err
+	}
+	old.Labels = quota.Labels
+	old.Spec = quota.Spec
+	_, err = quotas.Update(ctx, old, metav1.UpdateOptions{})
+	return err
+}
+
+func (t *TenantEnvAction) applyLimitRange(ctx context.Context, namespace string, limitRange *corev1.LimitRange) error {
+	limitRanges := t.kubeClient.CoreV1().LimitRanges(namespace)
+	if limitRange == nil {
+		if err := limitRanges.Delete(ctx, tenantEnvQuotaName, metav1.DeleteOptions{}); err != nil && !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	old, err := limitRanges.Get(ctx, tenantEnvQuotaName, metav1.GetOptions{})
+	if err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return err
+		}
+		_, err = limitRanges.Create(ctx, limitRange, metav1.CreateOptions{})
+		return err
+	}
+	old.Labels = limitRange.Labels
+	old.Spec = limitRange.Spec
+	_, err = limitRanges.Update(ctx, old, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,150 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	api_model "github.com/wutong-paas/wutong/api/model"
+	"github.com/wutong-paas/wutong/api/util/bcode"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCheckTenantEnvQuota(t *testing.T) {
+	tenantEnv := &dbmodel.TenantEnvs{
+		LimitCPU:      4000,
+		LimitMemory:   2048,
+		LimitPods:     4,
+		LimitStorage:  50,
+		LimitTCPPorts: 2,
+		LimitBuilds:   1,
+	}
+	usage := &api_model.TenantEnvQuotaUsage{CPU: 2000, Memory: 1024, Pods: 2, Storage: 40, TCPPorts: 2, Builds: 1}
+	service := &dbmodel.TenantEnvServices{ServiceID: "foo"}
+	tests := []struct {
+		name string
+		req  QuotaRequest
+		want error
+	}{
+		{name: "fits", req: ComponentQuotaRequest(service, 2, 1000, 512)},
+		{name: "cpu", req: ComponentQuotaRequest(service, 2, 0, 512), want: bcode.ErrTenantEnvCPUQuotaExceeded},
+		{name: "memory", req: ComponentQuotaRequest(service, 1, 1000, 2048), want: bcode.ErrTenantEnvMemoryQuotaExceeded},
+		{name: "pods", req: ComponentQuotaRequest(service, 3, 100, 128), want: bcode.ErrTenantEnvPodsQuotaExceeded},
+		{name: "storage", req: QuotaRequest{Storage: VolumeStorage(&dbmodel.TenantEnvServiceVolume{})}, want: bcode.ErrTenantEnvStorageQuotaExceeded},
+		{name: "config file", req: QuotaRequest{Storage: VolumeStorage(&dbmodel.TenantEnvServiceVolume{VolumeType: "config-file"})}},
+		{name: "tcp ports", req: QuotaRequest{TCPPorts: 1}, want: bcode.ErrTenantEnvTCPPortsQuotaExceeded},
+		{name: "builds", req: QuotaRequest{Builds: 1}, want: bcode.ErrTenantEnvBuildsQuotaExceeded},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkTenantEnvQuota(tenantEnv, usage, tc.req)
+			if errors.Cause(err) != tc.want {
+				t.Errorf("want %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	if quotaLimited(&dbmodel.TenantEnvs{}, ComponentQuotaRequest(service, 1, 0, 0)) {
+		t.Errorf("the tenant env without quota should not be limited")
+	}
+}
+
+func TestComponentRolloutQuotaRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		service *dbmodel.TenantEnvServices
+		want    int
+	}{
+		{name: "rolling", service: &dbmodel.TenantEnvServices{ExtendMethod: "stateless_multiple", UpgradeMethod: "Rolling"}, want: 5},
+		{name: "on delete", service: &dbmodel.TenantEnvServices{ExtendMethod: "stateless_multiple", UpgradeMethod: "OnDelete"}, want: 4},
+		{name: "state", service: &dbmodel.TenantEnvServices{ExtendMethod: "state_multiple", UpgradeMethod: "Rolling"}, want: 4},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := ComponentRolloutQuotaRequest(tc.service, 4, 100, 128)
+			if req.Pods != tc.want || req.CPU != tc.want*100 || req.Memory != tc.want*128 {
+				t.Errorf("want %d pods, got %+v", tc.want, req)
+			}
+		})
+	}
+}
+
+func TestQuotaStateCheck(t *testing.T) {
+	tenantEnv := &dbmodel.TenantEnvs{LimitCPU: 2000, LimitMemory: 2048, LimitPods: 4, LimitBuilds: 2}
+	foo := &dbmodel.TenantEnvServices{ServiceID: "foo"}
+	bar := &dbmodel.TenantEnvServices{ServiceID: "bar"}
+	state := &quotaState{
+		usage:    &api_model.TenantEnvQuotaUsage{CPU: 600, Memory: 640, Pods: 1},
+		running:  map[string]QuotaRequest{"foo": {ServiceID: "foo", Pods: 1, CPU: 600, Memory: 640}},
+		sidecars: map[string]QuotaRequest{"foo": {CPU: 100, Memory: 128}},
+	}
+	// the running foo is replaced, its sidecar is counted for every pod
+	if err := state.check(tenantEnv, ComponentQuotaRequest(foo, 2, 500, 512)); err != nil {
+		t.Fatalf("want foo to fit, got %v", err)
+	}
+	if state.usage.CPU != 1200 || state.usage.Memory != 1280 || state.usage.Pods != 2 {
+		t.Fatalf("unexpected usage %+v", state.usage)
+	}
+	// the batch counts the accepted requests
+	if err := state.check(tenantEnv, ComponentQuotaRequest(bar, 1, 500, 512)); err != nil {
+		t.Fatalf("want bar to fit, got %v", err)
+	}
+	if err := state.check(tenantEnv, ComponentQuotaRequest(&dbmodel.TenantEnvServices{ServiceID: "baz"}, 1, 500, 512)); errors.Cause(err) != bcode.ErrTenantEnvCPUQuotaExceeded {
+		t.Fatalf("want the cpu quota exceeded, got %v", err)
+	}
+	if err := state.check(tenantEnv, QuotaRequest{Builds: 1}); err != nil {
+		t.Fatalf("want the first build to fit, got %v", err)
+	}
+	if err := state.check(tenantEnv, QuotaRequest{Builds: 1}); err != nil {
+		t.Fatalf("want the second build to fit, got %v", err)
+	}
+	if err := state.check(tenantEnv, QuotaRequest{Builds: 1}); errors.Cause(err) != bcode.ErrTenantEnvBuildsQuotaExceeded {
+		t.Fatalf("want the builds quota exceeded, got %v", err)
+	}
+}
+
+func TestBuildTenantEnvQuota(t *testing.T) {
+	quota, limitRange := buildTenantEnvQuota(&dbmodel.TenantEnvs{Namespace: "foo"})
+	if quota != nil || limitRange != nil {
+		t.Fatalf("want nothing without quota, got %v %v", quota, limitRange)
+	}
+
+	quota, limitRange = buildTenantEnvQuota(&dbmodel.TenantEnvs{Namespace: "foo", LimitCPU: 1000, LimitStorage: 10, LimitPods: 5})
+	if quota == nil || limitRange == nil {
+		t.Fatalf("want the resource quota and the limit range")
+	}
+	if cpu := quota.Spec.Hard[corev1.ResourceLimitsCPU]; cpu.MilliValue() != 1000 {
+		t.Errorf("want limits.cpu 1000m, got %s", cpu.String())
+	}
+	if storage := quota.Spec.Hard[corev1.ResourceRequestsStorage]; storage.String() != "10Gi" {
+		t.Errorf("want requests.storage 10Gi, got %s", storage.String())
+	}
+	if pods := quota.Spec.Hard[corev1.ResourcePods]; pods.Value() != 5 {
+		t.Errorf("want 5 pods, got %s", pods.String())
+	}
+	// the default cpu limit is capped by the quota
+	if cpu := limitRange.Spec.Limits[0].Default[corev1.ResourceCPU]; cpu.MilliValue() != 1000 {
+		t.Errorf("want default cpu 1000m, got %s", cpu.String())
+	}
+	if _, ok := limitRange.Spec.Limits[0].Default[corev1.ResourceMemory]; ok {
+		t.Errorf("the memory is not limited")
+	}
+}
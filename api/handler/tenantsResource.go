@@ -44,6 +44,24 @@ func CheckTenantEnvResource(ctx context.Context, tenantEnv *dbmodel.TenantEnvs,
 	return nil
 }
 
+// CheckComponentResource checks the quota of the tenant env and the allocatable memory of the cluster
+// for the resources the component requests
+func CheckComponentResource(ctx context.Context, tenantEnv *dbmodel.TenantEnvs, req QuotaRequest) error {
+	if err := GetTenantEnvManager().CheckTenantEnvQuota(ctx, tenantEnv, req); err != nil {
+		return err
+	}
+
+	allcm, err := ClusterAllocMemory(ctx)
+	if err != nil {
+		return err
+	}
+	if int64(req.Memory) > allcm {
+		logrus.Errorf("cluster available memory is %d, To apply for %d, not enough", allcm, req.Memory)
+		return ErrClusterLackOfMemory
+	}
+	return nil
+}
+
 // ClusterAllocMemory returns the allocatable memory of the cluster.
 func ClusterAllocMemory(ctx context.Context) (int64, error) {
 	if logrus.IsLevelEnabled(logrus.DebugLevel) {
@@ -0,0 +1,210 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"time"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/notifier"
+)
+
+// AlertManagerPayload the payload of the alertmanager webhook receiver
+type AlertManagerPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []*Alert          `json:"alerts"`
+}
+
+// Alert an alert of the alertmanager webhook payload
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// NotificationChannelReq the request to create or update a notification channel.
+// The empty secret and password keep the old ones when updating.
+type NotificationChannelReq struct {
+	Name string `json:"name" validate:"name|required|max:64"`
+	// webhook, email, dingtalk, feishu or wecom
+	Type   string          `json:"type" validate:"type|required|in:webhook,email,dingtalk,feishu,wecom"`
+	Config notifier.Config `json:"config"`
+	// the severities to deliver, all severities are delivered if empty
+	Severities   []string `json:"severities"`
+	SendResolved bool     `json:"send_resolved"`
+	Enabled      bool     `json:"enabled"`
+}
+
+// NotificationChannel the notification channel, the secret and password are masked
+type NotificationChannel struct {
+	ChannelID    string          `json:"channel_id"`
+	TenantEnvID  string          `json:"tenant_env_id"`
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	Config       notifier.Config `json:"config"`
+	Severities   []string        `json:"severities"`
+	SendResolved bool            `json:"send_resolved"`
+	Enabled      bool            `json:"enabled"`
+	CreateTime   time.Time       `json:"create_time"`
+}
+
+// AlertSilenceReq the request to create an alert silence
+type AlertSilenceReq struct {
+	Matchers []dbmodel.SilenceMatcher `json:"matchers" validate:"matchers|required"`
+	// the silence starts now if it is empty
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at" validate:"ends_at|required"`
+	Comment   string    `json:"comment"`
+	CreatedBy string    `json:"created_by"`
+}
+
+// AlertSilence the alert silence
+type AlertSilence struct {
+	SilenceID   string                   `json:"silence_id"`
+	TenantEnvID string                   `json:"tenant_env_id"`
+	Matchers    []dbmodel.SilenceMatcher `json:"matchers"`
+	StartsAt    time.Time                `json:"starts_at"`
+	EndsAt      time.Time                `json:"ends_at"`
+	Comment     string                   `json:"comment"`
+	CreatedBy   string                   `json:"created_by"`
+	Active      bool                     `json:"active"`
+}
+
+// AlertRuleReq the request to create or update a PromQL alerting rule. The namespace
+// matchers of the expression are enforced to the namespace of the tenant env.
+type AlertRuleReq struct {
+	// the name of the alert
+	Name string `json:"name" validate:"name|required|max:64"`
+	// the PromQL expression returning the series to alert
+	Expr string `json:"expr" validate:"expr|required"`
+	// the duration the expression should be true before firing, eg: 5m
+	For      string `json:"for"`
+	Severity string `json:"severity" validate:"severity|required|in:info,warning,critical"`
+	// the summary and the description support the prometheus templates, eg: {{ $value }}
+	Summary     string `json:"summary" validate:"summary|max:255"`
+	Description string `json:"description" validate:"description|max:1024"`
+	// the extra labels of the alert, the labels set by the platform can not be overridden
+	Labels  map[string]string `json:"labels"`
+	Enabled bool              `json:"enabled"`
+}
+
+// AlertRule the PromQL alerting rule of a component or an application
+type AlertRule struct {
+	RuleID      string            `json:"rule_id"`
+	TenantEnvID string            `json:"tenant_env_id"`
+	AppID       string            `json:"app_id,omitempty"`
+	ServiceID   string            `json:"service_id,omitempty"`
+	Name        string            `json:"name"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for"`
+	Severity    string            `json:"severity"`
+	Summary     string            `json:"summary"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+	Enabled     bool              `json:"enabled"`
+	CreateTime  time.Time         `json:"create_time"`
+}
+
+// ServiceSLOReq the request to create or update a SLO of the requests of a component through the gateway
+type ServiceSLOReq struct {
+	Name string `json:"name" validate:"name|required|max:64"`
+	// the slo only covers the requests of the domain of the http rule if it is set
+	HTTPRuleID string `json:"http_rule_id"`
+	// availability: the requests not responding 5xx are good,
+	// latency: the requests faster than the latency threshold are good
+	SLIType string `json:"sli_type" validate:"sli_type|required|in:availability,latency"`
+	// the target percentage of the good requests, eg: 99.9
+	Objective float64 `json:"objective" validate:"objective|required"`
+	// the compliance window between 7d and 90d, not longer than the retention of the monitor, eg: 30d
+	Window string `json:"window" validate:"window|required"`
+	// the seconds of the latency sli, should be a bucket of the gateway request duration histogram
+	LatencyThreshold float64 `json:"latency_threshold"`
+	Enabled          bool    `json:"enabled"`
+}
+
+// ServiceSLO the SLO of the requests of a component through the gateway
+type ServiceSLO struct {
+	SLOID            string    `json:"slo_id"`
+	TenantEnvID      string    `json:"tenant_env_id"`
+	ServiceID        string    `json:"service_id"`
+	HTTPRuleID       string    `json:"http_rule_id,omitempty"`
+	Name             string    `json:"name"`
+	SLIType          string    `json:"sli_type"`
+	Objective        float64   `json:"objective"`
+	Window           string    `json:"window"`
+	LatencyThreshold float64   `json:"latency_threshold,omitempty"`
+	Enabled          bool      `json:"enabled"`
+	CreateTime       time.Time `json:"create_time"`
+}
+
+// ServiceSLOStatus the current status of the SLO measured by the gateway metrics
+type ServiceSLOStatus struct {
+	SLOID     string  `json:"slo_id"`
+	Objective float64 `json:"objective"`
+	Window    string  `json:"window"`
+	// NoData there is no request in the window, the sli is regarded as 100 percent
+	NoData bool `json:"no_data"`
+	// SLI the percentage of the good requests in the window
+	SLI float64 `json:"sli"`
+	// ErrorBudgetRemaining the percentage of the error budget left in the window, negative if overspent
+	ErrorBudgetRemaining float64 `json:"error_budget_remaining"`
+	// BurnRates the burn rates of the error budget in the last 1h, 6h, 1d and 3d, the budget is
+	// used up at the end of the window if the burn rate keeps 1
+	BurnRates map[string]float64 `json:"burn_rates"`
+	// BudgetExhausted the error budget is used up, the releases should be gated
+	BudgetExhausted bool `json:"budget_exhausted"`
+}
+
+// LogAlertRuleReq the request to create or update a rule matching the container logs of a component
+type LogAlertRuleReq struct {
+	Name string `json:"name" validate:"name|required|max:64"`
+	// keyword: the log lines containing the pattern match, regex: the log lines matching the regular expression match
+	MatchType string `json:"match_type" validate:"match_type|required|in:keyword,regex"`
+	Pattern   string `json:"pattern" validate:"pattern|required|max:512"`
+	// the matches within the window to raise a notification event
+	Threshold int `json:"threshold" validate:"threshold|required|min:1"`
+	// the seconds of the window, between 60 and 86400
+	WindowSeconds int  `json:"window_seconds" validate:"window_seconds|required"`
+	Enabled       bool `json:"enabled"`
+}
+
+// LogAlertRule the rule matching the container logs of a component
+type LogAlertRule struct {
+	RuleID        string    `json:"rule_id"`
+	TenantEnvID   string    `json:"tenant_env_id"`
+	ServiceID     string    `json:"service_id"`
+	Name          string    `json:"name"`
+	MatchType     string    `json:"match_type"`
+	Pattern       string    `json:"pattern"`
+	Threshold     int       `json:"threshold"`
+	WindowSeconds int       `json:"window_seconds"`
+	Enabled       bool      `json:"enabled"`
+	CreateTime    time.Time `json:"create_time"`
+}
@@ -18,22 +18,23 @@
 
 package model
 
-import dbmodel "github.com/wutong-paas/wutong/db/model"
+import (
+	"encoding/json"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
 
 // AutoscalerRuleReq -
 type AutoscalerRuleReq struct {
 	RuleID      string `json:"rule_id" validate:"rule_id|required"`
 	ServiceID   string
-	Enable      bool   `json:"enable" validate:"enable|required"`
-	XPAType     string `json:"xpa_type" validate:"xpa_type|required"`
-	MinReplicas int    `json:"min_replicas" validate:"min_replicas|required"`
-	MaxReplicas int    `json:"max_replicas" validate:"min_replicas|required"`
-	Metrics     []struct {
-		MetricsType       string `json:"metric_type"`
-		MetricsName       string `json:"metric_name"`
-		MetricTargetType  string `json:"metric_target_type"`
-		MetricTargetValue int    `json:"metric_target_value"`
-	} `json:"metrics"`
+	Enable      bool         `json:"enable" validate:"enable|required"`
+	XPAType     string       `json:"xpa_type" validate:"xpa_type|required"`
+	MinReplicas int          `json:"min_replicas" validate:"min_replicas|required"`
+	MaxReplicas int          `json:"max_replicas" validate:"min_replicas|required"`
+	Metrics     []RuleMetric `json:"metrics"`
+	// Behavior the scale up and scale down policies of the HPA
+	Behavior *dbmodel.AutoscalerBehavior `json:"behavior,omitempty"`
 }
 
 // AutoscalerRuleResp -
@@ -60,6 +61,8 @@ type AutoScalerRule struct {
 	MinReplicas int          `json:"min_replicas"`
 	MaxReplicas int          `json:"max_replicas"`
 	RuleMetrics []RuleMetric `json:"metrics"`
+	// Behavior the scale up and scale down policies of the HPA
+	Behavior *dbmodel.AutoscalerBehavior `json:"behavior,omitempty"`
 }
 
 // DbModel return database model
@@ -71,15 +74,38 @@ func (a AutoScalerRule) DbModel(componentID string) *dbmodel.TenantEnvServiceAut
 		MaxReplicas: a.MaxReplicas,
 		Enable:      a.Enable,
 		XPAType:     a.XPAType,
+		Behavior:    AutoscalerBehaviorString(a.Behavior),
 	}
 }
 
+// AutoscalerBehaviorString returns the json encoded behavior stored in database
+func AutoscalerBehaviorString(behavior *dbmodel.AutoscalerBehavior) string {
+	if behavior == nil {
+		return ""
+	}
+	b, _ := json.Marshal(behavior)
+	return string(b)
+}
+
 // RuleMetric -
 type RuleMetric struct {
-	MetricsType       string `json:"metric_type"`
-	MetricsName       string `json:"metric_name"`
+	// MetricsType resource_metrics, pods_metrics, object_metrics, external_metrics or prometheus_metrics
+	MetricsType string `json:"metric_type"`
+	MetricsName string `json:"metric_name"`
+	// MetricTargetType utilization, average_value or value
 	MetricTargetType  string `json:"metric_target_type"`
 	MetricTargetValue int    `json:"metric_target_value"`
+	// MetricTargetQuantity the target in quantity format, eg: 500m, takes the place of metric_target_value if not empty
+	MetricTargetQuantity string `json:"metric_target_quantity,omitempty"`
+	// MetricSelector the label selector of the custom or external metric, eg: verb=GET,code=200
+	MetricSelector string `json:"metric_selector,omitempty"`
+	// the kubernetes object described by the object metric
+	ObjectAPIVersion string `json:"object_api_version,omitempty"`
+	ObjectKind       string `json:"object_kind,omitempty"`
+	ObjectName       string `json:"object_name,omitempty"`
+	// PromQL the query of the prometheus metric, supports the variables {{namespace}} and {{service_id}},
+	// the selectors only select the series of the namespace of the component
+	PromQL string `json:"promql,omitempty"`
 }
 
 // DbModel return database model
@@ -90,5 +116,12 @@ func (r RuleMetric) DbModel(ruleID string) *dbmodel.TenantEnvServiceAutoscalerRu
 		MetricsName:       r.MetricsName,
 		MetricTargetType:  r.MetricTargetType,
 		MetricTargetValue: r.MetricTargetValue,
+
+		MetricTargetQuantity: r.MetricTargetQuantity,
+		MetricSelector:       r.MetricSelector,
+		ObjectAPIVersion:     r.ObjectAPIVersion,
+		ObjectKind:           r.ObjectKind,
+		ObjectName:           r.ObjectName,
+		PromQL:               r.PromQL,
 	}
 }
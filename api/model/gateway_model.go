@@ -43,6 +43,8 @@ type AddHTTPRuleStruct struct {
 	RuleExtensions []*RuleExtensionStruct `json:"rule_extensions"`
 	PathRewrite    bool                   `json:"path_rewrite"`
 	Rewrites       []*Rewrite             `json:"rewrites"`
+	// AutoTLS issues and renews the certificate of the domain through ACME
+	AutoTLS bool `json:"auto_tls"`
 }
 
 // DbModel return database model
@@ -64,6 +66,7 @@ func (h *AddHTTPRuleStruct) DbModel(serviceID string) *dbmodel.HTTPRule {
 		IP:            h.IP,
 		CertificateID: h.CertificateID,
 		PathRewrite:   h.PathRewrite,
+		AutoTLS:       h.AutoTLS,
 	}
 }
 
@@ -84,6 +87,8 @@ type UpdateHTTPRuleStruct struct {
 	RuleExtensions []*RuleExtensionStruct `json:"rule_extensions"`
 	PathRewrite    bool                   `json:"path_rewrite"`
 	Rewrites       []*Rewrite             `json:"rewrites"`
+	// AutoTLS issues and renews the certificate of the domain through ACME
+	AutoTLS bool `json:"auto_tls"`
 }
 
 // DeleteHTTPRuleStruct contains the id of http rule that will be deleted
@@ -186,6 +191,59 @@ type Body struct {
 	ProxyBufferNumbers  int          `json:"proxy_buffer_numbers,omitempty" validate:"proxy_buffer_size|numeric_between:1,65535"`
 	ProxyBuffering      string       `json:"proxy_buffering,omitempty" validate:"proxy_buffering|required"`
 	AccessLog           bool         `json:"access_log,omitempty" validate:"access_log|required"`
+	// the requests per second of a client ip, 0 means no limit
+	LimitRPS int `json:"limit_rps,omitempty"`
+	// the requests exceed the rate which are not rejected, default 5 times of limit_rps
+	LimitBurst int `json:"limit_burst,omitempty"`
+	// the concurrent connections of a client ip, 0 means no limit
+	LimitConnections int `json:"limit_connections,omitempty"`
+	// the ips or cidrs allowed to access, all others are denied if not empty
+	AllowCIDRs []string `json:"allow_cidrs,omitempty"`
+	// the ips or cidrs denied to access
+	DenyCIDRs []string   `json:"deny_cidrs,omitempty"`
+	BasicAuth *BasicAuth `json:"basic_auth,omitempty"`
+	// the client certificates are required and verified by the CA bundle
+	ClientAuth *ClientAuth `json:"client_auth,omitempty"`
+	// the requests are proxied to the https upstream
+	UpstreamTLS *UpstreamTLS `json:"upstream_tls,omitempty"`
+	// HTTP, HTTP2 or GRPC, default HTTP. HTTP2 and GRPC are proxied by the envoy gateway backend
+	BackendProtocol string `json:"backend_protocol,omitempty"`
+}
+
+// BasicAuth is a embedded sturct of Body.
+type BasicAuth struct {
+	Realm string           `json:"realm"`
+	Users []*BasicAuthUser `json:"users"`
+}
+
+// BasicAuthUser the user of the basic authentication, the old password is kept if the password is empty
+type BasicAuthUser struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ClientAuth is a embedded sturct of Body. The secret contains the CA bundle in the key ca.crt,
+// and it must be in the namespace of the tenant env.
+type ClientAuth struct {
+	Secret string `json:"secret"`
+	// on, optional or optional_no_ca, default on
+	Verify string `json:"verify,omitempty"`
+	// the depth of the client certificate chain, default 1
+	VerifyDepth int `json:"verify_depth,omitempty"`
+	// pass the client certificate to the upstream by the ssl-client-cert header
+	PassCertificate bool `json:"pass_certificate,omitempty"`
+}
+
+// UpstreamTLS is a embedded sturct of Body. The upstream certificate is verified by the CA bundle
+// in the key ca.crt of the secret, which must be in the namespace of the tenant env.
+type UpstreamTLS struct {
+	Secret string `json:"secret,omitempty"`
+	// verify the upstream certificate, the secret is required if it is true
+	Verify bool `json:"verify,omitempty"`
+	// the depth of the upstream certificate chain, default 1
+	VerifyDepth int `json:"verify_depth,omitempty"`
+	// the SNI and the name to verify the upstream certificate, default the request host
+	ServerName string `json:"server_name,omitempty"`
 }
 
 // TCPBody is a embedded sturct of TCPRuleConfigReq.
@@ -0,0 +1,84 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+// ImageSignaturePolicy the cosign image signature policy of the cluster or a tenant env
+type ImageSignaturePolicy struct {
+	// the glob patterns of the images to verify, all images are verified if empty.
+	// `**` and a trailing `*` match the nested repositories
+	Images []string `json:"images"`
+	// enforce: the unverified images can not be deployed; audit: only logged
+	Mode string `json:"mode" validate:"mode|in:enforce,audit"`
+	// the PEM encoded cosign public keys
+	PublicKeys []string `json:"public_keys"`
+	// the keyless identities
+	Identities []dbmodel.KeylessIdentity `json:"identities"`
+	// the PEM encoded fulcio root certificates, required by the keyless identities
+	RootCerts string `json:"root_certs"`
+	// the PEM encoded rekor public keys, required by the keyless identities
+	RekorPublicKeys string `json:"rekor_public_keys"`
+}
+
+// DbModel returns the db model of the policy
+func (p *ImageSignaturePolicy) DbModel(tenantEnvID string) *dbmodel.ImageSignaturePolicy {
+	policy := &dbmodel.ImageSignaturePolicy{
+		TenantEnvID:     tenantEnvID,
+		Images:          strings.Join(p.Images, ","),
+		Mode:            p.Mode,
+		PublicKeys:      strings.Join(p.PublicKeys, "\n"),
+		RootCerts:       p.RootCerts,
+		RekorPublicKeys: p.RekorPublicKeys,
+	}
+	if policy.Mode == "" {
+		policy.Mode = dbmodel.ImageSignatureModeEnforce
+	}
+	if len(p.Identities) > 0 {
+		identities, _ := json.Marshal(p.Identities)
+		policy.Identities = string(identities)
+	}
+	return policy
+}
+
+// NewImageSignaturePolicy creates the policy from the db model
+func NewImageSignaturePolicy(policy *dbmodel.ImageSignaturePolicy) *ImageSignaturePolicy {
+	res := &ImageSignaturePolicy{
+		Mode:            policy.Mode,
+		RootCerts:       policy.RootCerts,
+		RekorPublicKeys: policy.RekorPublicKeys,
+	}
+	for _, image := range strings.Split(policy.Images, ",") {
+		if image = strings.TrimSpace(image); image != "" {
+			res.Images = append(res.Images, image)
+		}
+	}
+	for _, key := range strings.SplitAfter(policy.PublicKeys, "-----END PUBLIC KEY-----") {
+		if key = strings.TrimSpace(key); key != "" {
+			res.PublicKeys = append(res.PublicKeys, key)
+		}
+	}
+	res.Identities, _ = policy.KeylessIdentities()
+	return res
+}
@@ -386,10 +386,14 @@ type ServiceStruct struct {
 type Endpoints struct {
 	Static     []string            `json:"static" validate:"static"`
 	Kubernetes *EndpointKubernetes `json:"kubernetes" validate:"kubernetes"`
+	Registry   *EndpointRegistry   `json:"registry" validate:"registry"`
 }
 
 // DbModel -
 func (e *Endpoints) DbModel(componentID string) *dbmodel.ThirdPartySvcDiscoveryCfg {
+	if e.Registry != nil {
+		return e.Registry.DbModel(componentID)
+	}
 	return &dbmodel.ThirdPartySvcDiscoveryCfg{
 		ServiceID:   componentID,
 		Type:        string(dbmodel.DiscorveryTypeKubernetes),
@@ -404,6 +408,34 @@ type EndpointKubernetes struct {
 	ServiceName string `json:"serviceName"`
 }
 
+// EndpointRegistry the service registered in consul, nacos or eureka
+type EndpointRegistry struct {
+	Type        string `json:"type"`
+	Servers     string `json:"servers"`
+	ServiceName string `json:"serviceName"`
+	// Namespace the nacos namespace id or the consul datacenter
+	Namespace string `json:"namespace"`
+	// Group the nacos group or the consul tag
+	Group    string `json:"group"`
+	Username string `json:"username"`
+	// Password the password, or the acl token of consul
+	Password string `json:"password"`
+}
+
+// DbModel -
+func (e *EndpointRegistry) DbModel(componentID string) *dbmodel.ThirdPartySvcDiscoveryCfg {
+	return &dbmodel.ThirdPartySvcDiscoveryCfg{
+		ServiceID:   componentID,
+		Type:        e.Type,
+		Servers:     e.Servers,
+		ServiceName: e.ServiceName,
+		Namespace:   e.Namespace,
+		Key:         e.Group,
+		Username:    e.Username,
+		Password:    e.Password,
+	}
+}
+
 // TenantEnvServiceVolumeStruct -
 type TenantEnvServiceVolumeStruct struct {
 	ServiceID string ` json:"service_id"`
@@ -602,9 +634,40 @@ type PodsList struct {
 
 // StatsInfo stats info
 type StatsInfo struct {
-	UUID string `json:"uuid"`
-	CPU  int    `json:"cpu"`
-	MEM  int    `json:"memory"`
+	UUID  string               `json:"uuid"`
+	CPU   int                  `json:"cpu"`
+	MEM   int                  `json:"memory"`
+	Quota *TenantEnvQuota      `json:"quota,omitempty"`
+	Usage *TenantEnvQuotaUsage `json:"usage,omitempty"`
+}
+
+// TenantEnvQuota the resource quota of the tenant env, 0 means unlimited
+type TenantEnvQuota struct {
+	// the limit cpu of the running components, unit: millicores
+	LimitCPU int `json:"limit_cpu"`
+	// the limit memory of the running components, unit: MB
+	LimitMemory int `json:"limit_memory"`
+	// the limit ephemeral storage of the pods, unit: MB
+	LimitEphemeralStorage int `json:"limit_ephemeral_storage"`
+	// the limit capacity of the persistent volumes, unit: GB
+	LimitStorage int `json:"limit_storage"`
+	// the limit number of the pods
+	LimitPods int `json:"limit_pods"`
+	// the limit number of the gateway tcp ports
+	LimitTCPPorts int `json:"limit_tcp_ports"`
+	// the limit number of the concurrent builds
+	LimitBuilds int `json:"limit_builds"`
+}
+
+// TenantEnvQuotaUsage the usage of the tenant env quota
+type TenantEnvQuotaUsage struct {
+	CPU              int   `json:"cpu"`
+	Memory           int   `json:"memory"`
+	EphemeralStorage int   `json:"ephemeral_storage"`
+	Storage          int64 `json:"storage"`
+	Pods             int   `json:"pods"`
+	TCPPorts         int   `json:"tcp_ports"`
+	Builds           int   `json:"builds"`
 }
 
 // TotalStatsInfo total stats info
@@ -1714,6 +1777,11 @@ type UpdateBuildVersionReq struct {
 	PlanVersion string `json:"plan_version" validate:"required"`
 }
 
+// CancelBuildReq -
+type CancelBuildReq struct {
+	Operator string `json:"operator"`
+}
+
 // ComponentUpgradeReq -
 type ComponentUpgradeReq struct {
 	ComponentOpGeneralReq
@@ -0,0 +1,110 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+import (
+	"time"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+// CreateServiceReleaseReq the request to release a new deploy version of the component
+type CreateServiceReleaseReq struct {
+	// canary: shift the traffic by steps; bluegreen: switch all traffic at once
+	Strategy string `json:"strategy" validate:"strategy|required|in:canary,bluegreen"`
+	// the deploy version to release, it must be built successfully
+	DeployVersion string `json:"deploy_version" validate:"deploy_version|required"`
+	// the traffic percentages of the new version, eg: [10, 30, 50, 100]
+	Steps []int `json:"steps"`
+	// the seconds to wait before analyzing a step, default 60
+	StepInterval int `json:"step_interval"`
+	// the analyses have no data if the new version gets no traffic yet, the step is held
+	// and analyzed again every step interval. It is the seconds a step is held at most,
+	// counted from the start of the step, the release is rolled back after that.
+	// default 0 holds the step until there is data, or the release is promoted or aborted.
+	NoDataTimeout int `json:"no_data_timeout"`
+	// the replicas of the new version before the promotion
+	CanaryReplicas int `json:"canary_replicas"`
+	// the requests match the header are always routed to the new version, eg: x-canary=true
+	Header string `json:"header"`
+	// the requests match the cookie are always routed to the new version
+	Cookie string `json:"cookie"`
+	// the prometheus analyses of each step, the 5xx rate and p99 latency of the gateway are used if empty
+	Analyses []dbmodel.ReleaseAnalysis `json:"analyses"`
+	// wait for the promote command after all steps are passed
+	ManualPromote bool `json:"manual_promote"`
+}
+
+// ServiceReleaseCommandReq the command to the running release
+type ServiceReleaseCommandReq struct {
+	Command string `json:"command" validate:"command|required|in:promote,abort"`
+}
+
+// ServiceRelease the release of a component
+type ServiceRelease struct {
+	ReleaseID      string                    `json:"release_id"`
+	ServiceID      string                    `json:"service_id"`
+	EventID        string                    `json:"event_id"`
+	Strategy       string                    `json:"strategy"`
+	StableVersion  string                    `json:"stable_version"`
+	CanaryVersion  string                    `json:"canary_version"`
+	CanaryReplicas int                       `json:"canary_replicas"`
+	Steps          []int                     `json:"steps"`
+	StepInterval   int                       `json:"step_interval"`
+	NoDataTimeout  int                       `json:"no_data_timeout"`
+	Header         string                    `json:"header"`
+	Cookie         string                    `json:"cookie"`
+	Analyses       []dbmodel.ReleaseAnalysis `json:"analyses"`
+	ManualPromote  bool                      `json:"manual_promote"`
+	Command        string                    `json:"command"`
+	Status         string                    `json:"status"`
+	CurrentStep    int                       `json:"current_step"`
+	CurrentWeight  int                       `json:"current_weight"`
+	Message        string                    `json:"message"`
+	CreateTime     time.Time                 `json:"create_time"`
+	FinishTime     *time.Time                `json:"finish_time"`
+}
+
+// NewServiceRelease creates the release from the db model
+func NewServiceRelease(release *dbmodel.ServiceRelease) *ServiceRelease {
+	res := &ServiceRelease{
+		ReleaseID:      release.ReleaseID,
+		ServiceID:      release.ServiceID,
+		EventID:        release.EventID,
+		Strategy:       release.Strategy,
+		StableVersion:  release.StableVersion,
+		CanaryVersion:  release.CanaryVersion,
+		CanaryReplicas: release.CanaryReplicas,
+		StepInterval:   release.StepInterval,
+		NoDataTimeout:  release.NoDataTimeout,
+		Header:         release.Header,
+		Cookie:         release.Cookie,
+		ManualPromote:  release.ManualPromote,
+		Command:        release.Command,
+		Status:         release.Status,
+		CurrentStep:    release.CurrentStep,
+		CurrentWeight:  release.CurrentWeight,
+		Message:        release.Message,
+		CreateTime:     release.CreatedAt,
+		FinishTime:     release.FinishTime,
+	}
+	res.Steps, _ = release.StepWeights()
+	res.Analyses, _ = release.AnalysisList()
+	return res
+}
@@ -0,0 +1,41 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package model
+
+// ScheduledScalingRuleReq the request to create or update a scheduled scaling rule
+type ScheduledScalingRuleReq struct {
+	Name string `json:"name" validate:"name|required"`
+	// the standard cron expression with 5 fields, eg: 0 20 * * 1-5
+	Schedule string `json:"schedule" validate:"schedule|required"`
+	// the IANA time zone of the schedule, eg: Asia/Shanghai
+	TimeZone string `json:"time_zone"`
+	// scale: scale to the replicas; sleep: scale to zero; wake: restore the replicas before the sleep
+	Action   string `json:"action" validate:"action|required|in:scale,sleep,wake"`
+	Replicas int    `json:"replicas"`
+	Enable   bool   `json:"enable"`
+}
+
+// ScaleToZeroReq the request to update the scale-to-zero setting of the component
+type ScaleToZeroReq struct {
+	// scale the component to zero when there is no gateway request in idle_timeout seconds
+	IdleEnable  bool `json:"idle_enable"`
+	IdleTimeout int  `json:"idle_timeout"`
+	// the seconds the gateway holds a request while the component is waking up, default 60
+	HoldTimeout int `json:"hold_timeout"`
+}
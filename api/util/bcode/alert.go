@@ -0,0 +1,35 @@
+package bcode
+
+// alert 11700~11799
+var (
+	// ErrNotificationChannelNotFound -
+	ErrNotificationChannelNotFound = newByMessage(404, 11700, "notification channel not found")
+	// ErrInvalidNotificationChannel -
+	ErrInvalidNotificationChannel = newByMessage(400, 11701, "invalid notification channel")
+	// ErrAlertSilenceNotFound -
+	ErrAlertSilenceNotFound = newByMessage(404, 11702, "alert silence not found")
+	// ErrInvalidAlertSilence -
+	ErrInvalidAlertSilence = newByMessage(400, 11703, "invalid alert silence")
+	// ErrInvalidAlertPayload -
+	ErrInvalidAlertPayload = newByMessage(400, 11704, "invalid alertmanager payload")
+	// ErrNotificationFailed -
+	ErrNotificationFailed = newByMessage(400, 11705, "failed to send the notification")
+	// ErrAlertRuleNotFound -
+	ErrAlertRuleNotFound = newByMessage(404, 11706, "alert rule not found")
+	// ErrInvalidAlertRule -
+	ErrInvalidAlertRule = newByMessage(400, 11707, "invalid alert rule")
+	// ErrAlertRuleNameExists -
+	ErrAlertRuleNameExists = newByMessage(400, 11708, "alert rule name already exists")
+	// ErrSLONotFound -
+	ErrSLONotFound = newByMessage(404, 11709, "slo not found")
+	// ErrInvalidSLO -
+	ErrInvalidSLO = newByMessage(400, 11710, "invalid slo")
+	// ErrSLONameExists -
+	ErrSLONameExists = newByMessage(400, 11711, "slo name already exists")
+	// ErrLogAlertRuleNotFound -
+	ErrLogAlertRuleNotFound = newByMessage(404, 11712, "log alert rule not found")
+	// ErrInvalidLogAlertRule -
+	ErrInvalidLogAlertRule = newByMessage(400, 11713, "invalid log alert rule")
+	// ErrLogAlertRuleNameExists -
+	ErrLogAlertRuleNameExists = newByMessage(400, 11714, "log alert rule name already exists")
+)
@@ -0,0 +1,9 @@
+package bcode
+
+// image signature policy 11400~11499
+var (
+	// ErrImageSignaturePolicyNotFound -
+	ErrImageSignaturePolicyNotFound = newByMessage(404, 11400, "image signature policy not found")
+	// ErrInvalidImageSignaturePolicy -
+	ErrInvalidImageSignaturePolicy = newByMessage(400, 11401, "invalid image signature policy")
+)
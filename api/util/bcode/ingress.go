@@ -4,4 +4,6 @@ package bcode
 var (
 	ErrIngressHTTPRuleNotFound = newByMessage(404, 11200, "http rule not found")
 	ErrIngressTCPRuleNotFound  = newByMessage(404, 11201, "tcp rule not found")
+	// ErrInvalidRuleConfig -
+	ErrInvalidRuleConfig = newByMessage(400, 11202, "invalid rule config")
 )
@@ -0,0 +1,13 @@
+package bcode
+
+// service release 11500~11599
+var (
+	// ErrServiceReleaseNotFound -
+	ErrServiceReleaseNotFound = newByMessage(404, 11500, "service release not found")
+	// ErrInvalidServiceRelease -
+	ErrInvalidServiceRelease = newByMessage(400, 11501, "invalid service release")
+	// ErrServiceReleaseRunning -
+	ErrServiceReleaseRunning = newByMessage(400, 11502, "another release of the service is running")
+	// ErrServiceReleaseFinished -
+	ErrServiceReleaseFinished = newByMessage(400, 11503, "the service release is finished")
+)
@@ -0,0 +1,11 @@
+package bcode
+
+// scheduled scaling and scale-to-zero 11600~11699
+var (
+	// ErrScheduledScalingRuleNotFound -
+	ErrScheduledScalingRuleNotFound = newByMessage(404, 11600, "scheduled scaling rule not found")
+	// ErrInvalidScheduledScalingRule -
+	ErrInvalidScheduledScalingRule = newByMessage(400, 11601, "invalid scheduled scaling rule")
+	// ErrInvalidScaleToZero -
+	ErrInvalidScaleToZero = newByMessage(400, 11602, "invalid scale-to-zero setting")
+)
@@ -14,4 +14,12 @@ var (
 	ErrHorizontalDueToNoChange = newByMessage(400, 10104, "The number of components has not changed, no need to scale")
 	ErrPodNotFound             = newByMessage(404, 10105, "pod not found")
 	ErrK8sComponentNameExists  = newByMessage(400, 10106, "k8s component name exists")
+	// ErrBuildVersionNotFound -
+	ErrBuildVersionNotFound = newByMessage(404, 10107, "build version not found")
+	// ErrBuildFinished -
+	ErrBuildFinished = newByMessage(400, 10108, "the build is already finished")
+	// ErrSBOMNotFound -
+	ErrSBOMNotFound = newByMessage(404, 10109, "sbom of the build version not found")
+	// ErrInvalidAutoscalerRule -
+	ErrInvalidAutoscalerRule = newByMessage(400, 10110, "invalid autoscaler rule")
 )
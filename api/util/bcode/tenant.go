@@ -3,4 +3,18 @@ package bcode
 // tenant env 11300~11399
 var (
 	ErrNamespaceExists = newByMessage(400, 11300, "tenant env namespace exists")
+	// ErrInvalidTenantEnvQuota -
+	ErrInvalidTenantEnvQuota = newByMessage(400, 11301, "invalid tenant env quota")
+	// ErrTenantEnvCPUQuotaExceeded -
+	ErrTenantEnvCPUQuotaExceeded = newByMessage(412, 11302, "exceeds the cpu quota of the tenant env")
+	// ErrTenantEnvMemoryQuotaExceeded -
+	ErrTenantEnvMemoryQuotaExceeded = newByMessage(412, 11303, "exceeds the memory quota of the tenant env")
+	// ErrTenantEnvStorageQuotaExceeded -
+	ErrTenantEnvStorageQuotaExceeded = newByMessage(412, 11304, "exceeds the storage quota of the tenant env")
+	// ErrTenantEnvPodsQuotaExceeded -
+	ErrTenantEnvPodsQuotaExceeded = newByMessage(412, 11305, "exceeds the pod quota of the tenant env")
+	// ErrTenantEnvTCPPortsQuotaExceeded -
+	ErrTenantEnvTCPPortsQuotaExceeded = newByMessage(412, 11306, "exceeds the tcp port quota of the tenant env")
+	// ErrTenantEnvBuildsQuotaExceeded -
+	ErrTenantEnvBuildsQuotaExceeded = newByMessage(412, 11307, "exceeds the concurrent build quota of the tenant env")
 )
@@ -21,6 +21,7 @@ package build
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -47,6 +48,12 @@ func init() {
 	buildcreaters[code.Nodejs] = slugBuilder
 	buildcreaters[code.Golang] = slugBuilder
 	buildcreaters[code.OSS] = slugBuilder
+	// there are no slug runner images for these languages, and no buildpacks in the default
+	// builder either, the builder of the language should be configured
+	buildcreaters[code.Rust] = buildpacksBuilder
+	buildcreaters[code.Elixir] = buildpacksBuilder
+	buildcreaters[code.Deno] = buildpacksBuilder
+	buildcreaters[code.Bun] = buildpacksBuilder
 }
 
 var buildcreaters map[code.Lang]CreaterBuild
@@ -108,6 +115,17 @@ type Request struct {
 	Ctx           context.Context
 }
 
+// ErrBuildCanceled the build is canceled by the user
+var ErrBuildCanceled = errors.New("build is canceled")
+
+// canceled returns a channel that is closed when the build is canceled
+func (r *Request) canceled() <-chan struct{} {
+	if r.Ctx == nil {
+		return nil
+	}
+	return r.Ctx.Done()
+}
+
 // HostAlias holds the mapping between IP and hostnames that will be injected as an entry in the
 // pod's hosts file.
 type HostAlias struct {
@@ -124,8 +142,24 @@ type Commit struct {
 	Hash    string
 }
 
+// BuildTypeEnv the build env of the service to choose the way source code is built
+const BuildTypeEnv = "BUILD_TYPE"
+
+// BuildTypeBuildpacks builds the source code with cloud native buildpacks
+const BuildTypeBuildpacks = "buildpacks"
+
 // GetBuild GetBuild
-func GetBuild(lang code.Lang) (Build, error) {
+// the languages built by slug are built by buildpacks if the service selects it,
+// or if the service has a build command, which only the buildpacks run
+func GetBuild(lang code.Lang, buildEnvs map[string]string) (Build, error) {
+	if (buildEnvs[BuildTypeEnv] == BuildTypeBuildpacks || buildEnvs[code.BuildCmdEnv] != "") && lang != code.Dockerfile && lang != code.Docker {
+		return buildpacksBuilder()
+	}
+	// the python slug runner installs requirements.txt only, the poetry and uv
+	// projects are installed by the python buildpack
+	if lang == code.Python && (buildEnvs["PACKAGE_TOOL"] == "poetry" || buildEnvs["PACKAGE_TOOL"] == "uv") {
+		return buildpacksBuilder()
+	}
 	if fun, ok := buildcreaters[lang]; ok {
 		return fun()
 	}
@@ -0,0 +1,423 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package build
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/chaos"
+	jobc "github.com/wutong-paas/wutong/chaos/job"
+	"github.com/wutong-paas/wutong/chaos/parser/code"
+	"github.com/wutong-paas/wutong/util"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BuildpacksBuilderEnv the build env of the service to use another builder image
+const BuildpacksBuilderEnv = "BUILDPACKS_BUILDER"
+
+// the build envs only used by wutong, they are not given to the buildpacks
+var buildpacksIgnoreEnvs = map[string]struct{}{
+	BuildTypeEnv:         {},
+	BuildpacksBuilderEnv: {},
+	code.BuildCmdEnv:     {},
+	code.GradleTaskEnv:   {},
+	"MAVEN_SETTING_NAME": {},
+	"PROC_ENV":           {},
+	"REPARSE":            {},
+	"NO_CACHE":           {},
+}
+
+// the languages that the default builder has no buildpacks for, they are built by the builder
+// image of the language given by the env of chaos, or by the BUILDPACKS_BUILDER of the service
+var buildpacksLangBuilderEnvs = map[code.Lang]string{
+	code.Rust:   "BUILDPACKS_RUST_BUILDER_IMAGE",
+	code.Elixir: "BUILDPACKS_ELIXIR_BUILDER_IMAGE",
+	code.Deno:   "BUILDPACKS_DENO_BUILDER_IMAGE",
+	code.Bun:    "BUILDPACKS_BUN_BUILDER_IMAGE",
+}
+
+// builderImage returns the builder image of the service, the build of the language without
+// buildpacks in the default builder is refused if no builder is configured for it
+func builderImage(re *Request) (string, error) {
+	if image := re.BuildEnvs[BuildpacksBuilderEnv]; image != "" {
+		return image, nil
+	}
+	env, ok := buildpacksLangBuilderEnvs[re.Lang]
+	if !ok {
+		return chaos.BUILDPACKSBUILDERIMAGE, nil
+	}
+	if image := os.Getenv(env); image != "" {
+		return image, nil
+	}
+	return "", fmt.Errorf("the builder of %s is not configured by %s or the build env %s", re.Lang, env, BuildpacksBuilderEnv)
+}
+
+func buildpacksBuilder() (Build, error) {
+	return &buildpacksBuild{}, nil
+}
+
+// buildpacksBuild builds the source code with the lifecycle of cloud native buildpacks,
+// the creator runs detect, build and export in one build job
+type buildpacksBuild struct {
+}
+
+func (b *buildpacksBuild) Build(re *Request) (*Response, error) {
+	re.Logger.Info("Start building the source code with buildpacks", map[string]string{"step": "build-exector"})
+	builderImage, err := builderImage(re)
+	if err != nil {
+		re.Logger.Error(fmt.Sprintf("默认构建镜像不支持%s语言，请设置构建环境变量%s指定支持该语言的buildpacks构建镜像，或使用Dockerfile构建", re.Lang, BuildpacksBuilderEnv),
+			map[string]string{"step": "build-code", "status": "failure"})
+		return nil, err
+	}
+	buildImageName := CreateImageName(re.ServiceID, re.DeployVersion)
+	//Stops previous build tasks for the same component
+	if err := b.stopPreBuildJob(re); err != nil {
+		logrus.Errorf("stop pre build job for service %s failure %s", re.ServiceID, err.Error())
+	}
+	platformDir := path.Join(re.CacheDir, "platform-"+re.DeployVersion)
+	if err := b.writePlatformEnv(platformDir, re); err != nil {
+		logrus.Errorf("write buildpacks platform env failure %s", err.Error())
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(platformDir); err != nil {
+			logrus.Warningf("remove buildpacks platform dir %s: %v", platformDir, err)
+		}
+	}()
+	if err := b.runBuildJob(re, builderImage, buildImageName, platformDir); err != nil {
+		re.Logger.Error(util.Translation("Compiling the source code failure"), map[string]string{"step": "build-code", "status": "failure"})
+		logrus.Error("build buildpacks job error,", err.Error())
+		return nil, err
+	}
+	re.Logger.Info("code build success", map[string]string{"step": "build-exector"})
+	return &Response{
+		MediumPath: buildImageName,
+		MediumType: ImageMediumType,
+	}, nil
+}
+
+// The same component retains only one build task to perform
+func (b *buildpacksBuild) stopPreBuildJob(re *Request) error {
+	jobList, err := jobc.GetJobController().GetServiceJobs(re.ServiceID)
+	if err != nil {
+		logrus.Errorf("get pre build job for service %s failure ,%s", re.ServiceID, err.Error())
+	}
+	for _, job := range jobList {
+		jobc.GetJobController().DeleteJob(job.Name)
+	}
+	return nil
+}
+
+// writePlatformEnv gives the build envs to the buildpacks, one file for each env
+// in <platform>/env as the platform spec requires
+func (b *buildpacksBuild) writePlatformEnv(platformDir string, re *Request) error {
+	envDir := path.Join(platformDir, "env")
+	if err := util.CheckAndCreateDir(envDir); err != nil {
+		return err
+	}
+	envs := map[string]string{
+		"CODE_COMMIT_HASH":    re.Commit.Hash,
+		"CODE_COMMIT_USER":    re.Commit.User,
+		"CODE_COMMIT_MESSAGE": re.Commit.Message,
+	}
+	for k, v := range re.BuildEnvs {
+		if _, ok := buildpacksIgnoreEnvs[k]; ok {
+			continue
+		}
+		envs[k] = v
+	}
+	// the build command is run by the buildpacks of the language
+	if cmd := re.BuildEnvs[code.BuildCmdEnv]; cmd != "" {
+		cmdEnvs, err := code.BuildCmdEnvs(re.Lang, cmd)
+		if err != nil {
+			return err
+		}
+		for k, v := range cmdEnvs {
+			envs[k] = v
+		}
+	}
+	// the gradle task of the module found by the parser, the build command goes first
+	if task := re.BuildEnvs[code.GradleTaskEnv]; task != "" && envs["BP_GRADLE_BUILD_ARGUMENTS"] == "" {
+		envs["BP_GRADLE_BUILD_ARGUMENTS"] = task
+	}
+	for k, v := range envs {
+		if k == "" || strings.Contains(k, "/") {
+			continue
+		}
+		if err := os.WriteFile(path.Join(envDir, k), []byte(v), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registryAuth returns the CNB_REGISTRY_AUTH of the build, so the lifecycle can
+// pull the run image and push the app image
+func registryAuth(re *Request) (string, error) {
+	auths := make(map[string]string)
+	if re.KubeClient != nil {
+		ctx := re.Ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		for domain, auth := range GetTenantEnvRegistryAuthSecrets(re.KubeClient, ctx, re.TenantEnvID) {
+			if domain == "" {
+				continue
+			}
+			auths[registryHost(domain)] = "Basic " + auth.Auth
+		}
+	}
+	if chaos.REGISTRYUSER != "" && chaos.REGISTRYPASS != "" {
+		auths[registryHost(chaos.REGISTRYDOMAIN)] = "Basic " + base64.StdEncoding.EncodeToString([]byte(chaos.REGISTRYUSER+":"+chaos.REGISTRYPASS))
+	}
+	body, err := json.Marshal(auths)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// createJobSecret keeps the credentials of the build job in a secret, so they are
+// referenced by the job rather than written in the pod spec. The returned func
+// deletes the secret.
+func createJobSecret(re *Request, name string, data map[string]string) (func(), error) {
+	if re.KubeClient == nil {
+		return nil, fmt.Errorf("kube client is required to create the secret %s", name)
+	}
+	ctx := re.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: re.WtNamespace,
+			Labels: map[string]string{
+				"creator": "Wutong",
+			},
+		},
+		StringData: data,
+		Type:       corev1.SecretTypeOpaque,
+	}
+	secrets := re.KubeClient.CoreV1().Secrets(re.WtNamespace)
+	if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+	}
+	return func() {
+		if err := secrets.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			logrus.Warningf("delete secret %s: %v", name, err)
+		}
+	}, nil
+}
+
+// secretEnv references the key of the secret as the env of the container
+func secretEnv(env, secretName, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: env,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+			},
+		},
+	}
+}
+
+// registryHost returns the registry host of the image repository
+func registryHost(domain string) string {
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "https://"), "http://")
+	return strings.SplitN(domain, "/", 2)[0]
+}
+
+func (b *buildpacksBuild) runBuildJob(re *Request, builderImage, buildImageName, platformDir string) error {
+	name := fmt.Sprintf("%s-%s", re.ServiceID, re.DeployVersion)
+	job := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: re.WtNamespace,
+			Labels: map[string]string{
+				"service": re.ServiceID,
+				"job":     "codebuild",
+			},
+		},
+	}
+	podSpec := corev1.PodSpec{RestartPolicy: corev1.RestartPolicyOnFailure} // only support never and onfailure
+	if re.CacheMode == "hostpath" {
+		logrus.Debugf("builder cache mode using hostpath, schedule job into current node")
+		hostIP := os.Getenv("HOST_IP")
+		if hostIP != "" {
+			podSpec.NodeSelector = map[string]string{
+				"kubernetes.io/hostname": hostIP,
+			}
+			podSpec.Tolerations = []corev1.Toleration{
+				{
+					Operator: "Exists",
+				},
+			}
+		}
+	}
+	auth, err := registryAuth(re)
+	if err != nil {
+		return fmt.Errorf("create registry auth failure %s", err.Error())
+	}
+	authSecret := name + "-registry-auth"
+	deleteSecret, err := createJobSecret(re, authSecret, map[string]string{"CNB_REGISTRY_AUTH": auth})
+	if err != nil {
+		return fmt.Errorf("create registry auth secret failure %s", err.Error())
+	}
+	defer deleteSecret()
+	volumes, mounts := b.createVolumeAndMount(re)
+	podSpec.Volumes = volumes
+	// the creator runs as root and drops to the CNB_USER_ID of the builder image
+	var root int64
+	container := corev1.Container{
+		Name:    name,
+		Image:   builderImage,
+		Command: []string{"/cnb/lifecycle/creator"},
+		Args: []string{
+			"-app=" + re.SourceDir,
+			"-cache-dir=" + path.Join(re.CacheDir, "buildpacks"),
+			"-platform=" + platformDir,
+			"-log-level=info",
+			buildImageName,
+		},
+		Env: []corev1.EnvVar{
+			{Name: "CNB_PLATFORM_API", Value: "0.12"},
+			secretEnv("CNB_REGISTRY_AUTH", authSecret, "CNB_REGISTRY_AUTH"),
+			{Name: "CNB_INSECURE_REGISTRIES", Value: registryHost(chaos.REGISTRYDOMAIN)},
+		},
+		SecurityContext: &corev1.SecurityContext{RunAsUser: &root, RunAsGroup: &root},
+		VolumeMounts:    mounts,
+	}
+	podSpec.Containers = append(podSpec.Containers, container)
+	for _, ha := range re.HostAlias {
+		podSpec.HostAliases = append(podSpec.HostAliases, corev1.HostAlias{IP: ha.IP, Hostnames: ha.Hostnames})
+	}
+	job.Spec = podSpec
+	writer := re.Logger.GetWriter("builder", "info")
+	reChan := channels.NewRingChannel(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logrus.Debugf("create job[name: %s; namespace: %s]", job.Name, job.Namespace)
+	if err := jobc.GetJobController().ExecJob(ctx, &job, writer, reChan); err != nil {
+		logrus.Errorf("create new job:%s failed: %s", name, err.Error())
+		return err
+	}
+	re.Logger.Info(util.Translation("create build code job success"), map[string]string{"step": "build-exector"})
+	logrus.Infof("create buildpacks job %s for service %s build version %s", job.Name, re.ServiceID, re.DeployVersion)
+	// delete job after complete
+	defer jobc.GetJobController().DeleteJob(job.Name)
+	return b.waitingComplete(re, reChan)
+}
+
+// createVolumeAndMount mounts the cache of the build node, the source code,
+// the build cache and the platform dir are all in it
+func (b *buildpacksBuild) createVolumeAndMount(re *Request) (volumes []corev1.Volume, volumeMounts []corev1.VolumeMount) {
+	return cacheVolumeAndMount(re, "buildpacks-build")
+}
+
+// cacheVolumeAndMount mounts the build cache at /cache as chaos does
+func cacheVolumeAndMount(re *Request, name string) (volumes []corev1.Volume, volumeMounts []corev1.VolumeMount) {
+	cacheVolume := corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: re.CachePVCName,
+			},
+		},
+	}
+	if re.CacheMode == "hostpath" {
+		hostPathType := corev1.HostPathDirectoryOrCreate
+		cacheVolume.VolumeSource = corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: "/cache",
+				Type: &hostPathType,
+			},
+		}
+	}
+	volumes = []corev1.Volume{cacheVolume}
+	volumeMounts = []corev1.VolumeMount{
+		{
+			Name:      name,
+			MountPath: "/cache",
+		},
+	}
+	return volumes, volumeMounts
+}
+
+func (b *buildpacksBuild) waitingComplete(re *Request, reChan *channels.RingChannel) (err error) {
+	var logComplete = false
+	var jobComplete = false
+	timeout := time.NewTimer(time.Minute * 60)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-timeout.C:
+			return fmt.Errorf("build time out (more than 60 minute)")
+		case <-re.canceled():
+			// the deferred DeleteJob removes the build job pod
+			return ErrBuildCanceled
+		case jobStatus := <-reChan.Out():
+			status := jobStatus.(string)
+			switch status {
+			case "complete":
+				jobComplete = true
+				if logComplete {
+					return nil
+				}
+				re.Logger.Info(util.Translation("build code job exec completed"), map[string]string{"step": "build-exector"})
+			case "failed":
+				jobComplete = true
+				err = fmt.Errorf("build code job exec failure")
+				if logComplete {
+					return err
+				}
+				re.Logger.Info(util.Translation("build code job exec failed"), map[string]string{"step": "build-exector"})
+			case "cancel":
+				jobComplete = true
+				err = fmt.Errorf("build code job is canceled")
+				if logComplete {
+					return err
+				}
+			case "logcomplete":
+				logComplete = true
+				if jobComplete {
+					return err
+				}
+			}
+		}
+	}
+}
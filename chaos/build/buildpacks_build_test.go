@@ -0,0 +1,191 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package build
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/wutong-paas/wutong/chaos"
+	"github.com/wutong-paas/wutong/chaos/parser/code"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetBuildpacksBuild(t *testing.T) {
+	envs := map[string]string{BuildTypeEnv: BuildTypeBuildpacks}
+	if b, _ := GetBuild(code.JavaMaven, envs); b == nil {
+		t.Fatal("expected a build")
+	} else if _, ok := b.(*buildpacksBuild); !ok {
+		t.Fatalf("expected buildpacks build for java maven, got %T", b)
+	}
+	if b, _ := GetBuild(code.Dockerfile, envs); b == nil {
+		t.Fatal("expected a build")
+	} else if _, ok := b.(*dockerfileBuild); !ok {
+		t.Fatalf("expected dockerfile build for dockerfile, got %T", b)
+	}
+	for _, tool := range []string{"poetry", "uv"} {
+		if b, _ := GetBuild(code.Python, map[string]string{"PACKAGE_TOOL": tool}); b == nil {
+			t.Fatal("expected a build")
+		} else if _, ok := b.(*buildpacksBuild); !ok {
+			t.Fatalf("expected buildpacks build for python %s, got %T", tool, b)
+		}
+	}
+	if b, _ := GetBuild(code.Python, nil); b == nil {
+		t.Fatal("expected a build")
+	} else if _, ok := b.(*slugBuild); !ok {
+		t.Fatalf("expected slug build for python pip, got %T", b)
+	}
+	if b, _ := GetBuild(code.Nodejs, nil); b == nil {
+		t.Fatal("expected a build")
+	} else if _, ok := b.(*slugBuild); !ok {
+		t.Fatalf("expected slug build by default, got %T", b)
+	}
+}
+
+func TestBuildpacksPlatformEnv(t *testing.T) {
+	dir := t.TempDir()
+	re := &Request{
+		Commit: Commit{Hash: "abc"},
+		BuildEnvs: map[string]string{
+			BuildTypeEnv:     BuildTypeBuildpacks,
+			"BP_JVM_VERSION": "17",
+		},
+	}
+	b := &buildpacksBuild{}
+	if err := b.writePlatformEnv(dir, re); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := os.ReadFile(path.Join(dir, "env", "BP_JVM_VERSION")); err != nil || string(v) != "17" {
+		t.Fatalf("expected BP_JVM_VERSION 17, got %q %v", v, err)
+	}
+	if v, err := os.ReadFile(path.Join(dir, "env", "CODE_COMMIT_HASH")); err != nil || string(v) != "abc" {
+		t.Fatalf("expected CODE_COMMIT_HASH abc, got %q %v", v, err)
+	}
+	if _, err := os.Stat(path.Join(dir, "env", BuildTypeEnv)); !os.IsNotExist(err) {
+		t.Fatalf("expected %s not given to the buildpacks", BuildTypeEnv)
+	}
+}
+
+func TestBuildpacksBuildCmd(t *testing.T) {
+	envs := map[string]string{code.BuildCmdEnv: "npm run build"}
+	if b, err := GetBuild(code.Nodejs, envs); err != nil {
+		t.Fatal(err)
+	} else if _, ok := b.(*buildpacksBuild); !ok {
+		t.Fatalf("expected buildpacks build for the build cmd, got %T", b)
+	}
+	dir := t.TempDir()
+	re := &Request{Lang: code.Nodejs, BuildEnvs: envs}
+	if err := (&buildpacksBuild{}).writePlatformEnv(dir, re); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := os.ReadFile(path.Join(dir, "env", "BP_NODE_RUN_SCRIPTS")); err != nil || string(v) != "build" {
+		t.Fatalf("expected BP_NODE_RUN_SCRIPTS build, got %q %v", v, err)
+	}
+	if _, err := os.Stat(path.Join(dir, "env", code.BuildCmdEnv)); !os.IsNotExist(err) {
+		t.Fatalf("expected %s not given to the buildpacks", code.BuildCmdEnv)
+	}
+	re.BuildEnvs = map[string]string{code.BuildCmdEnv: "make build"}
+	if err := (&buildpacksBuild{}).writePlatformEnv(t.TempDir(), re); err == nil {
+		t.Fatal("expected error for the build cmd no buildpack runs")
+	}
+}
+
+func TestBuildpacksGradleTask(t *testing.T) {
+	dir := t.TempDir()
+	re := &Request{Lang: code.Gradle, BuildEnvs: map[string]string{
+		code.GradleTaskEnv:       ":api:bootJar -x test",
+		"BP_GRADLE_BUILT_MODULE": "api",
+	}}
+	if err := (&buildpacksBuild{}).writePlatformEnv(dir, re); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := os.ReadFile(path.Join(dir, "env", "BP_GRADLE_BUILD_ARGUMENTS")); err != nil || string(v) != ":api:bootJar -x test" {
+		t.Fatalf("expected BP_GRADLE_BUILD_ARGUMENTS of the gradle task, got %q %v", v, err)
+	}
+	if v, err := os.ReadFile(path.Join(dir, "env", "BP_GRADLE_BUILT_MODULE")); err != nil || string(v) != "api" {
+		t.Fatalf("expected BP_GRADLE_BUILT_MODULE api, got %q %v", v, err)
+	}
+	if _, err := os.Stat(path.Join(dir, "env", code.GradleTaskEnv)); !os.IsNotExist(err) {
+		t.Fatalf("expected %s not given to the buildpacks", code.GradleTaskEnv)
+	}
+
+	// the build command goes first
+	dir = t.TempDir()
+	re.BuildEnvs[code.BuildCmdEnv] = "gradle build"
+	if err := (&buildpacksBuild{}).writePlatformEnv(dir, re); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := os.ReadFile(path.Join(dir, "env", "BP_GRADLE_BUILD_ARGUMENTS")); err != nil || string(v) != "build" {
+		t.Fatalf("expected BP_GRADLE_BUILD_ARGUMENTS of the build cmd, got %q %v", v, err)
+	}
+}
+
+func TestBuildpacksBuilderImage(t *testing.T) {
+	if image, err := builderImage(&Request{Lang: code.JavaMaven}); err != nil || image != chaos.BUILDPACKSBUILDERIMAGE {
+		t.Errorf("want the default builder for java maven, got %s, %v", image, err)
+	}
+	if _, err := builderImage(&Request{Lang: code.Rust}); err == nil {
+		t.Error("the rust build without a builder should be refused")
+	}
+	t.Setenv("BUILDPACKS_RUST_BUILDER_IMAGE", "example.com/rust-builder:1")
+	if image, err := builderImage(&Request{Lang: code.Rust}); err != nil || image != "example.com/rust-builder:1" {
+		t.Errorf("want the rust builder, got %s, %v", image, err)
+	}
+	re := &Request{Lang: code.Bun, BuildEnvs: map[string]string{BuildpacksBuilderEnv: "example.com/bun-builder:1"}}
+	if image, err := builderImage(re); err != nil || image != "example.com/bun-builder:1" {
+		t.Errorf("want the builder of the service, got %s, %v", image, err)
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	for domain, want := range map[string]string{
+		"goodrain.me":                    "goodrain.me",
+		"registry.example.com:5000/team": "registry.example.com:5000",
+		"https://docker.io/library":      "docker.io",
+	} {
+		if got := registryHost(domain); got != want {
+			t.Fatalf("registry host of %s: expected %s, got %s", domain, want, got)
+		}
+	}
+}
+
+func TestCreateJobSecret(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	re := &Request{KubeClient: clientset, WtNamespace: "wt-system"}
+	for i := 0; i < 2; i++ {
+		deleteSecret, err := createJobSecret(re, "sid-v1-registry-auth", map[string]string{"CNB_REGISTRY_AUTH": "{}"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		secret, err := clientset.CoreV1().Secrets("wt-system").Get(context.Background(), "sid-v1-registry-auth", metav1.GetOptions{})
+		if err != nil || secret.StringData["CNB_REGISTRY_AUTH"] != "{}" {
+			t.Fatalf("expected the registry auth secret, got %v %v", secret, err)
+		}
+		deleteSecret()
+	}
+	if _, err := clientset.CoreV1().Secrets("wt-system").Get(context.Background(), "sid-v1-registry-auth", metav1.GetOptions{}); err == nil {
+		t.Fatal("expected the secret to be deleted")
+	}
+	if _, err := createJobSecret(&Request{}, "sid-v1-registry-auth", nil); err == nil {
+		t.Fatal("expected an error without kube client")
+	}
+}
@@ -455,6 +455,9 @@ func (s *slugBuild) waitingComplete(re *Request, reChan *channels.RingChannel) (
 		select {
 		case <-timeout.C:
 			return fmt.Errorf("build time out (more than 60 minute)")
+		case <-re.canceled():
+			// the deferred DeleteJob removes the build job pod
+			return ErrBuildCanceled
 		case jobStatus := <-reChan.Out():
 			status := jobStatus.(string)
 			switch status {
@@ -145,7 +145,7 @@ func TestBuildFromOSS(t *testing.T) {
 		WTDataPVCName: "wt-cpt-wtdata",
 		CachePVCName:  "wt-chaos-cache",
 	}
-	build, err := GetBuild(code.OSS)
+	build, err := GetBuild(code.OSS, req.BuildEnvs)
 	if err != nil {
 		t.Fatal(err)
 	}
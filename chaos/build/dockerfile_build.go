@@ -208,6 +208,9 @@ func (d *dockerfileBuild) waitingComplete(re *Request, reChan *channels.RingChan
 		select {
 		case <-timeout.C:
 			return fmt.Errorf("build time out (more than 60 minute)")
+		case <-re.canceled():
+			// the deferred DeleteJob removes the build job pod
+			return ErrBuildCanceled
 		case jobStatus := <-reChan.Out():
 			status := jobStatus.(string)
 			switch status {
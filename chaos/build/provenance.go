@@ -0,0 +1,143 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package build
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// InTotoStatementType the type of the in-toto statement v1
+	InTotoStatementType = "https://in-toto.io/Statement/v1"
+	// SLSAProvenancePredicateType the predicate type of the slsa provenance v1
+	SLSAProvenancePredicateType = "https://slsa.dev/provenance/v1"
+	// ProvenanceBuildType the build type of the source code build of chaos
+	ProvenanceBuildType = "https://github.com/wutong-paas/wutong/chaos/build/source-code@v1"
+	// ProvenanceBuilderID the id of the builder
+	ProvenanceBuilderID = "https://github.com/wutong-paas/wutong/chaos"
+)
+
+// Statement in-toto statement
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Provenance           `json:"predicate"`
+}
+
+// ResourceDescriptor in-toto resource descriptor
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Provenance slsa provenance v1 predicate
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition the inputs of the build
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]string    `json:"externalParameters"`
+	InternalParameters   map[string]string    `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// RunDetails the details of the build run
+type RunDetails struct {
+	Builder  ProvenanceBuilder  `json:"builder"`
+	Metadata ProvenanceMetadata `json:"metadata"`
+}
+
+// ProvenanceBuilder the builder of the build
+type ProvenanceBuilder struct {
+	ID string `json:"id"`
+}
+
+// ProvenanceMetadata the metadata of the build run
+type ProvenanceMetadata struct {
+	InvocationID string    `json:"invocationId"`
+	StartedOn    time.Time `json:"startedOn"`
+	FinishedOn   time.Time `json:"finishedOn"`
+}
+
+// NewProvenance creates the provenance statement of the build. digest is the
+// algorithm:hex digest of the delivered, eventID identifies the build run.
+func NewProvenance(re *Request, res *Response, digest, eventID string, startedOn, finishedOn time.Time) *Statement {
+	subject := ResourceDescriptor{Name: res.MediumPath}
+	if algorithm, value, ok := strings.Cut(digest, ":"); ok {
+		subject.Digest = map[string]string{algorithm: value}
+	}
+	repository := redactRepositoryURL(re.RepositoryURL)
+	source := ResourceDescriptor{URI: repository}
+	if re.Commit.Hash != "" {
+		source.Digest = map[string]string{"gitCommit": re.Commit.Hash}
+		if re.ServerType == "svn" {
+			source.Digest = map[string]string{"svnRevision": re.Commit.Hash}
+		}
+	}
+	return &Statement{
+		Type:          InTotoStatementType,
+		Subject:       []ResourceDescriptor{subject},
+		PredicateType: SLSAProvenancePredicateType,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: ProvenanceBuildType,
+				ExternalParameters: map[string]string{
+					"repository": repository,
+					"branch":     re.Branch,
+					"serverType": re.ServerType,
+				},
+				InternalParameters: map[string]string{
+					"lang":          re.Lang.String(),
+					"runtime":       re.Runtime,
+					"buildType":     re.BuildEnvs[BuildTypeEnv],
+					"deliveredType": string(res.MediumType),
+					"deployVersion": re.DeployVersion,
+					"tenantEnvID":   re.TenantEnvID,
+					"serviceID":     re.ServiceID,
+				},
+				ResolvedDependencies: []ResourceDescriptor{source},
+			},
+			RunDetails: RunDetails{
+				Builder: ProvenanceBuilder{ID: ProvenanceBuilderID},
+				Metadata: ProvenanceMetadata{
+					InvocationID: eventID,
+					StartedOn:    startedOn,
+					FinishedOn:   finishedOn,
+				},
+			},
+		},
+	}
+}
+
+// redactRepositoryURL removes the credentials in the repository url
+func redactRepositoryURL(repository string) string {
+	u, err := url.Parse(repository)
+	if err != nil || u.User == nil {
+		return repository
+	}
+	u.User = nil
+	return u.String()
+}
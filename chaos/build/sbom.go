@@ -0,0 +1,189 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/chaos"
+	jobc "github.com/wutong-paas/wutong/chaos/job"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NoSBOMEnv the build env of the service to skip the sbom generation
+const NoSBOMEnv = "NO_SBOM"
+
+// SBOMFormat the format of the generated sbom
+const SBOMFormat = "cyclonedx-json"
+
+// sbomTimeout the max time to scan the delivered
+const sbomTimeout = 10 * time.Minute
+
+// SBOMSource returns the syft source of the build result. The image is scanned
+// from the registry so the os packages are listed, the slug is scanned from
+// the source code.
+func SBOMSource(re *Request, res *Response) string {
+	if res.MediumType == ImageMediumType {
+		return "registry:" + res.MediumPath
+	}
+	return "dir:" + re.SourceDir
+}
+
+// GenerateSBOM runs a syft job to scan the build result, the sbom is written
+// to output which must be in the cache dir of the build.
+func GenerateSBOM(re *Request, res *Response, output string) error {
+	name := fmt.Sprintf("%s-%s-sbom", re.ServiceID, re.DeployVersion)
+	job := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: re.WtNamespace,
+			// the job controller watches the codebuild jobs, the sbom job is not labeled
+			// by the service so it is not stopped as a previous build of the service
+			Labels: map[string]string{
+				"sbom": re.ServiceID,
+				"job":  "codebuild",
+			},
+		},
+	}
+	podSpec := corev1.PodSpec{RestartPolicy: corev1.RestartPolicyOnFailure}
+	if re.CacheMode == "hostpath" {
+		if hostIP := os.Getenv("HOST_IP"); hostIP != "" {
+			podSpec.NodeSelector = map[string]string{
+				"kubernetes.io/hostname": hostIP,
+			}
+			podSpec.Tolerations = []corev1.Toleration{
+				{
+					Operator: "Exists",
+				},
+			}
+		}
+	}
+	volumes, mounts := cacheVolumeAndMount(re, "sbom")
+	podSpec.Volumes = volumes
+	container := corev1.Container{
+		Name:  name,
+		Image: chaos.SBOMGENERATORIMAGE,
+		Args: []string{
+			"scan", SBOMSource(re, res),
+			"-o", SBOMFormat + "=" + output,
+			"-q",
+		},
+		Env: []corev1.EnvVar{
+			{Name: "SYFT_CHECK_FOR_APP_UPDATE", Value: "false"},
+			{Name: "SYFT_REGISTRY_INSECURE_SKIP_TLS_VERIFY", Value: "true"},
+		},
+		VolumeMounts: mounts,
+	}
+	if chaos.REGISTRYUSER != "" && chaos.REGISTRYPASS != "" {
+		authSecret := name + "-registry-auth"
+		deleteSecret, err := createJobSecret(re, authSecret, map[string]string{"SYFT_REGISTRY_AUTH_PASSWORD": chaos.REGISTRYPASS})
+		if err != nil {
+			return fmt.Errorf("create registry auth secret failure %s", err.Error())
+		}
+		defer deleteSecret()
+		container.Env = append(container.Env,
+			corev1.EnvVar{Name: "SYFT_REGISTRY_AUTH_AUTHORITY", Value: registryHost(chaos.REGISTRYDOMAIN)},
+			corev1.EnvVar{Name: "SYFT_REGISTRY_AUTH_USERNAME", Value: chaos.REGISTRYUSER},
+			secretEnv("SYFT_REGISTRY_AUTH_PASSWORD", authSecret, "SYFT_REGISTRY_AUTH_PASSWORD"),
+		)
+	}
+	podSpec.Containers = append(podSpec.Containers, container)
+	for _, ha := range re.HostAlias {
+		podSpec.HostAliases = append(podSpec.HostAliases, corev1.HostAlias{IP: ha.IP, Hostnames: ha.Hostnames})
+	}
+	job.Spec = podSpec
+	writer := re.Logger.GetWriter("builder", "info")
+	reChan := channels.NewRingChannel(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := jobc.GetJobController().ExecJob(ctx, &job, writer, reChan); err != nil {
+		logrus.Errorf("create sbom job %s failed: %s", name, err.Error())
+		return err
+	}
+	defer jobc.GetJobController().DeleteJob(job.Name)
+	return waitingSBOMComplete(re, reChan)
+}
+
+func waitingSBOMComplete(re *Request, reChan *channels.RingChannel) (err error) {
+	var logComplete = false
+	var jobComplete = false
+	timeout := time.NewTimer(sbomTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-timeout.C:
+			return fmt.Errorf("generate sbom time out (more than 10 minute)")
+		case <-re.canceled():
+			return ErrBuildCanceled
+		case jobStatus := <-reChan.Out():
+			switch jobStatus.(string) {
+			case "complete":
+				jobComplete = true
+				if logComplete {
+					return nil
+				}
+			case "failed":
+				jobComplete = true
+				err = fmt.Errorf("sbom job exec failure")
+				if logComplete {
+					return err
+				}
+			case "cancel":
+				jobComplete = true
+				err = fmt.Errorf("sbom job is canceled")
+				if logComplete {
+					return err
+				}
+			case "logcomplete":
+				logComplete = true
+				if jobComplete {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// SBOMImageDigest returns the digest of the scanned image recorded in the sbom,
+// it is empty if the sbom is not generated from an image
+func SBOMImageDigest(sbom []byte) string {
+	var doc struct {
+		Metadata struct {
+			Component struct {
+				Type    string `json:"type"`
+				Version string `json:"version"`
+			} `json:"component"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(sbom, &doc); err != nil {
+		return ""
+	}
+	if doc.Metadata.Component.Type != "container" || !strings.HasPrefix(doc.Metadata.Component.Version, "sha256:") {
+		return ""
+	}
+	return doc.Metadata.Component.Version
+}
@@ -79,6 +79,11 @@ func (t *Manager) Start(errchan chan error) error {
 				if len(versions) <= 5 {
 					continue
 				}
+				for _, file := range v.ArtifactFiles() {
+					if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+						logrus.Warningf("remove artifact %s: %v", file, err)
+					}
+				}
 				if v.DeliveredType == "image" {
 					imagePath := v.DeliveredPath
 					//remove local image, However, it is important to note that the version image is stored in the image repository
@@ -45,7 +45,6 @@ type TaskManager struct {
 	config                 option.Config
 	client                 client.MQClient
 	exec                   exector.Manager
-	callbackChan           chan *pb.TaskMessage
 }
 
 // NewTaskManager return *TaskManager
@@ -54,7 +53,6 @@ func NewTaskManager(c option.Config, client client.MQClient, exec exector.Manage
 	discoverCtx, discoverCancel := context.WithCancel(ctx)
 	healthStatus["status"] = "health"
 	healthStatus["info"] = "builder service health"
-	callbackChan := make(chan *pb.TaskMessage, 100)
 	taskManager := &TaskManager{
 		discoverCtx:    discoverCtx,
 		discoverCancel: discoverCancel,
@@ -63,7 +61,6 @@ func NewTaskManager(c option.Config, client client.MQClient, exec exector.Manage
 		config:         c,
 		client:         client,
 		exec:           exec,
-		callbackChan:   callbackChan,
 	}
 	exec.SetReturnTaskChan(taskManager.callback)
 	return taskManager
@@ -72,6 +69,7 @@ func NewTaskManager(c option.Config, client client.MQClient, exec exector.Manage
 // Start 启动
 func (t *TaskManager) Start(errChan chan error) error {
 	go t.Do(errChan)
+	go t.doCancel()
 	logrus.Info("start discover success.")
 	return nil
 }
@@ -84,6 +82,11 @@ func (t *TaskManager) callback(task *pb.TaskMessage) {
 	})
 	if err != nil {
 		logrus.Errorf("callback task to mq failure %s", err.Error())
+		return
+	}
+	// the task is enqueued again, the lease is no longer needed
+	if err := t.client.AckTask(task); err != nil {
+		logrus.Warningf("ack callback task %s failure %s", task.TaskId, err.Error())
 	}
 	logrus.Infof("The build controller returns an indigestible task(%s) to the messaging system", task.TaskId)
 }
@@ -96,8 +99,16 @@ func (t *TaskManager) Do(errChan chan error) {
 		case <-t.discoverCtx.Done():
 			return
 		default:
+			// the task is left in mq until the executor has the capacity for it
+			if err := t.exec.WaitCapacity(t.discoverCtx); err != nil {
+				return
+			}
 			ctx, cancel := context.WithCancel(t.discoverCtx)
-			data, err := t.client.Dequeue(ctx, &pb.DequeueRequest{Topic: t.config.Topic, ClientHost: hostName + "-builder"})
+			data, err := t.client.Dequeue(ctx, &pb.DequeueRequest{
+				Topic:             t.config.Topic,
+				ClientHost:        hostName + "-builder",
+				VisibilityTimeout: int32(t.config.TaskLeaseTimeout),
+			})
 			cancel()
 			if err != nil {
 				if grpc1.ErrorDesc(err) == context.DeadlineExceeded.Error() {
@@ -124,8 +135,47 @@ func (t *TaskManager) Do(errChan chan error) {
 			}
 			err = t.exec.AddTask(data)
 			if err != nil {
-				t.callbackChan <- data
 				logrus.Error("add task error:", err.Error())
+				// the task is delivered again by the mq after nacked
+				if err := t.client.NackTask(data, err.Error()); err != nil {
+					logrus.Warningf("nack task %s failure %s", data.TaskId, err.Error())
+				}
+			}
+		}
+	}
+}
+
+// doCancel receives the cancel tasks without waiting for the capacity of the executor,
+// the builds pending in the executor or in mq are canceled at once
+func (t *TaskManager) doCancel() {
+	hostName, _ := os.Hostname()
+	for {
+		select {
+		case <-t.discoverCtx.Done():
+			return
+		default:
+		}
+		ctx, cancel := context.WithCancel(t.discoverCtx)
+		data, err := t.client.Dequeue(ctx, &pb.DequeueRequest{
+			Topic:             client.BuilderCancelTopic,
+			ClientHost:        hostName + "-builder",
+			VisibilityTimeout: int32(t.config.TaskLeaseTimeout),
+		})
+		cancel()
+		if err != nil {
+			if t.discoverCtx.Err() != nil {
+				return
+			}
+			if grpc1.ErrorDesc(err) != context.DeadlineExceeded.Error() && grpc1.ErrorDesc(err) != "context timeout" {
+				logrus.Errorf("cancel task dequeue failure %s, will retry", err.Error())
+				time.Sleep(time.Second * 2)
+			}
+			continue
+		}
+		if err := t.exec.AddTask(data); err != nil {
+			logrus.Errorf("add cancel task %s error: %s", data.TaskId, err.Error())
+			if err := t.client.NackTask(data, err.Error()); err != nil {
+				logrus.Warningf("nack task %s failure %s", data.TaskId, err.Error())
 			}
 		}
 	}
@@ -137,10 +187,6 @@ func (t *TaskManager) Stop() error {
 	if err := t.exec.Stop(); err != nil {
 		logrus.Errorf("stop task exec manager failure %s", err.Error())
 	}
-	for len(t.callbackChan) > 0 {
-		logrus.Infof("waiting callback chan empty")
-		time.Sleep(time.Second * 2)
-	}
 	logrus.Info("discover manager is stoping.")
 	t.cancel()
 	if t.client != nil {
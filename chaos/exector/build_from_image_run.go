@@ -19,6 +19,7 @@
 package exector
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -51,6 +52,9 @@ type ImageBuildItem struct {
 	Action        string
 	Configs       map[string]gjson.Result `json:"configs"`
 	Operator      string                  `json:"operator"`
+	Ctx           context.Context
+	// the image signature status verified by the policy
+	signatureStatus string
 }
 
 // NewImageBuildItem 创建实体
@@ -62,6 +66,7 @@ func NewImageBuildItem(in []byte) *ImageBuildItem {
 		TenantEnvName: gjson.GetBytes(in, "tenant_env_name").String(),
 		ServiceAlias:  gjson.GetBytes(in, "service_alias").String(),
 		ServiceID:     gjson.GetBytes(in, "service_id").String(),
+		TenantEnvID:   gjson.GetBytes(in, "tenant_env_id").String(),
 		Image:         gjson.GetBytes(in, "image").String(),
 		DeployVersion: gjson.GetBytes(in, "deploy_version").String(),
 		Action:        gjson.GetBytes(in, "action").String(),
@@ -76,16 +81,22 @@ func NewImageBuildItem(in []byte) *ImageBuildItem {
 // Run Run
 func (i *ImageBuildItem) Run(timeout time.Duration) error {
 	var syncImage = true
-	image := i.Image
 	if strings.HasPrefix(i.Image, chaos.REGISTRYDOMAIN) {
 		syncImage = false
 	}
+	user, pass := chaos.GetImageUserInfoV2(i.Image, i.HubUser, i.HubPassword)
+	// the verified image is pulled or deployed by the digest
+	signatureStatus, source := verifyImageSignature(i.TenantEnvID, i.Image, user, pass, i.Logger)
+	i.signatureStatus = signatureStatus
+	image := source
 	if len(i.HubUser) == 0 {
 		syncImage = false
 	}
 	if syncImage {
-		user, pass := chaos.GetImageUserInfoV2(i.Image, i.HubUser, i.HubPassword)
-		_, err := i.ImageClient.ImagePull(i.Image, user, pass, i.Logger, 30)
+		_, err := i.ImageClient.ImagePull(source, user, pass, i.Logger, 30)
+		if err == nil {
+			err = i.canceled()
+		}
 		if err != nil {
 			logrus.Errorf("pull image %s error: %s", i.Image, err.Error())
 			i.Logger.Error(fmt.Sprintf("获取指定镜像：%s 失败，错误信息：%s", i.Image, err.Error()), map[string]string{"step": "builder-exector", "status": "failure"})
@@ -93,12 +104,15 @@ func (i *ImageBuildItem) Run(timeout time.Duration) error {
 		}
 
 		image = build.CreateImageName(i.ServiceID, i.DeployVersion)
-		if err := i.ImageClient.ImageTag(i.Image, image, i.Logger, 1); err != nil {
+		if err := i.ImageClient.ImageTag(source, image, i.Logger, 1); err != nil {
 			logrus.Errorf("change image tag error: %s", err.Error())
 			i.Logger.Error(fmt.Sprintf("修改镜像 Tag：%s -> %s 失败，错误信息：%s", i.Image, image, err.Error()), map[string]string{"step": "builder-exector", "status": "failure"})
 			return err
 		}
 		err = i.ImageClient.ImagePush(image, chaos.REGISTRYUSER, chaos.REGISTRYPASS, i.Logger, 30)
+		if err == nil {
+			err = i.canceled()
+		}
 		if err != nil {
 			logrus.Errorf("failed to push image %s: %s", image, err.Error())
 			i.Logger.Error("推送镜像至镜像仓库失败："+err.Error(), map[string]string{"step": "builder-exector", "status": "failure"})
@@ -110,7 +124,7 @@ func (i *ImageBuildItem) Run(timeout time.Duration) error {
 		}
 
 		if os.Getenv("DISABLE_IMAGE_CACHE") == "true" {
-			if err := i.ImageClient.ImageRemove(i.Image); err != nil {
+			if err := i.ImageClient.ImageRemove(source); err != nil {
 				logrus.Errorf("failed to remove image %s: %s", i.Image, err.Error())
 			}
 		}
@@ -126,6 +140,14 @@ func (i *ImageBuildItem) Run(timeout time.Duration) error {
 	return nil
 }
 
+// canceled returns the context error once the build is canceled
+func (i *ImageBuildItem) canceled() error {
+	if i.Ctx == nil {
+		return nil
+	}
+	return i.Ctx.Err()
+}
+
 // StorageVersionInfo 存储version信息
 func (i *ImageBuildItem) StorageVersionInfo(image string) error {
 	version, err := db.GetManager().VersionInfoDao().GetVersionByDeployVersion(i.DeployVersion, i.ServiceID)
@@ -136,6 +158,7 @@ func (i *ImageBuildItem) StorageVersionInfo(image string) error {
 	version.DeliveredPath = image
 	version.ImageName = image
 	version.RepoURL = i.Image
+	version.SignatureStatus = i.signatureStatus
 	version.FinalStatus = "success"
 	version.FinishTime = time.Now()
 	if err := db.GetManager().VersionInfoDao().UpdateModel(version); err != nil {
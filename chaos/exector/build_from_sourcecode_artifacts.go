@@ -0,0 +1,108 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/chaos/build"
+)
+
+// buildArtifacts the supply chain artifacts of a build version
+type buildArtifacts struct {
+	SBOMPath       string
+	ProvenancePath string
+}
+
+// generateArtifacts generates the sbom and the provenance of the build result.
+// They are not required to deploy the version, so a failure only logs a warning.
+func (i *SourceCodeBuildItem) generateArtifacts(req *build.Request, res *build.Response, startedOn time.Time) (artifacts buildArtifacts) {
+	var digest string
+	if _, ok := i.BuildEnvs[build.NoSBOMEnv]; !ok {
+		i.Logger.Info("开始生成软件物料清单(SBOM)", map[string]string{"step": "build-exector"})
+		sbom, err := i.generateSBOM(req, res)
+		if err != nil {
+			logrus.Warningf("generate sbom of service %s version %s failure %s", i.ServiceID, i.DeployVersion, err.Error())
+			i.Logger.Info("生成软件物料清单(SBOM)失败："+err.Error(), map[string]string{"step": "build-exector"})
+		} else {
+			artifacts.SBOMPath = path.Join(i.ArtifactDir, i.DeployVersion+".sbom.cdx.json")
+			if res.MediumType == build.ImageMediumType {
+				digest = build.SBOMImageDigest(sbom)
+			}
+		}
+	}
+	if res.MediumType == build.SlugMediumType {
+		if d, err := fileDigest(res.MediumPath); err != nil {
+			logrus.Warningf("get digest of slug %s failure %s", res.MediumPath, err.Error())
+		} else {
+			digest = d
+		}
+	}
+	statement := build.NewProvenance(req, res, digest, i.EventID, startedOn, time.Now())
+	body, err := json.MarshalIndent(statement, "", "  ")
+	if err == nil {
+		provenancePath := path.Join(i.ArtifactDir, i.DeployVersion+".provenance.json")
+		if err = os.WriteFile(provenancePath, body, 0644); err == nil {
+			artifacts.ProvenancePath = provenancePath
+		}
+	}
+	if err != nil {
+		logrus.Warningf("write provenance of service %s version %s failure %s", i.ServiceID, i.DeployVersion, err.Error())
+	}
+	return artifacts
+}
+
+// generateSBOM scans the build result into the cache dir shared with the job,
+// then moves the sbom into the artifact dir
+func (i *SourceCodeBuildItem) generateSBOM(req *build.Request, res *build.Response) ([]byte, error) {
+	output := path.Join(i.CacheDir, "sbom-"+i.DeployVersion+".json")
+	defer os.Remove(output)
+	if err := build.GenerateSBOM(req, res, output); err != nil {
+		return nil, err
+	}
+	sbom, err := os.ReadFile(output)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path.Join(i.ArtifactDir, i.DeployVersion+".sbom.cdx.json"), sbom, 0644); err != nil {
+		return nil, err
+	}
+	return sbom, nil
+}
+
+// fileDigest returns the sha256 digest of the file
+func fileDigest(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
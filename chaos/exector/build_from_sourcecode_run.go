@@ -58,6 +58,7 @@ type SourceCodeBuildItem struct {
 	EventID       string       `json:"event_id"`
 	CacheDir      string       `json:"cache_dir"`
 	TGZDir        string       `json:"tgz_dir"`
+	ArtifactDir   string       `json:"artifact_dir"`
 	ImageClient   sources.ImageClient
 	KanikoImage   string
 	KubeClient    kubernetes.Interface
@@ -72,9 +73,12 @@ type SourceCodeBuildItem struct {
 	CodeSouceInfo sources.CodeSourceInfo
 	RepoInfo      *sources.RepostoryBuildInfo
 	commit        Commit
-	Configs       map[string]gjson.Result `json:"configs"`
-	Ctx           context.Context
-	Operator      string `json:"operator"`
+	artifacts     buildArtifacts
+	// the image signature status verified by the policy
+	signatureStatus string
+	Configs         map[string]gjson.Result `json:"configs"`
+	Ctx             context.Context
+	Operator        string `json:"operator"`
 }
 
 // Commit code Commit
@@ -121,6 +125,7 @@ func NewSouceCodeBuildItem(in []byte) *SourceCodeBuildItem {
 	scb.CacheDir = fmt.Sprintf("/cache/build/%s/cache/%s", scb.TenantEnvID, scb.ServiceID)
 	//scb.SourceDir = scb.CodeSouceInfo.GetCodeSourceDir()
 	scb.TGZDir = fmt.Sprintf("/wtdata/build/tenantEnv/%s/slug/%s", scb.TenantEnvID, scb.ServiceID)
+	scb.ArtifactDir = fmt.Sprintf("/wtdata/build/tenantEnv/%s/artifact/%s", scb.TenantEnvID, scb.ServiceID)
 	return scb
 }
 
@@ -130,6 +135,8 @@ func (i *SourceCodeBuildItem) Run(timeout time.Duration) error {
 	// 2.check dockerfile/ source_code
 	// 3.build
 	// 4.upload image /upload slug
+	// 5.generate sbom and provenance
+	startedOn := time.Now()
 	rbi, err := sources.CreateRepostoryBuildInfo(i.CodeSouceInfo.RepositoryURL, i.CodeSouceInfo.ServerType, i.CodeSouceInfo.Branch, i.TenantEnvID, i.ServiceID)
 	if err != nil {
 		i.Logger.Error("Git 项目仓库地址格式错误", map[string]string{"step": "parse"})
@@ -208,9 +215,15 @@ func (i *SourceCodeBuildItem) Run(timeout time.Duration) error {
 		i.Lang = string(lang)
 	}
 
+	if i.Ctx != nil && i.Ctx.Err() != nil {
+		return i.Ctx.Err()
+	}
 	i.Logger.Info("pull or clone code successfully, start code build", map[string]string{"step": "codee-version"})
-	res, err := i.codeBuild()
+	req, res, err := i.codeBuild()
 	if err != nil {
+		if err == build.ErrBuildCanceled {
+			return err
+		}
 		if err.Error() == context.DeadlineExceeded.Error() {
 			i.Logger.Error("Build app version from source code timeout, the maximum time is 60 minutes", map[string]string{"step": "builder-exector", "status": "failure"})
 		} else {
@@ -218,23 +231,27 @@ func (i *SourceCodeBuildItem) Run(timeout time.Duration) error {
 		}
 		return err
 	}
+	i.artifacts = i.generateArtifacts(req, res, startedOn)
+	if res.MediumType == build.ImageMediumType {
+		i.signatureStatus, res.MediumPath = verifyImageSignature(i.TenantEnvID, res.MediumPath, chaos.REGISTRYUSER, chaos.REGISTRYPASS, i.Logger)
+	}
 	if err := i.UpdateBuildVersionInfo(res); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (i *SourceCodeBuildItem) codeBuild() (*build.Response, error) {
-	codeBuild, err := build.GetBuild(code.Lang(i.Lang))
+func (i *SourceCodeBuildItem) codeBuild() (*build.Request, *build.Response, error) {
+	codeBuild, err := build.GetBuild(code.Lang(i.Lang), i.BuildEnvs)
 	if err != nil {
 		logrus.Errorf("get code build error: %s lang %s", err.Error(), i.Lang)
 		i.Logger.Error(util.Translation("No way of compiling to support this source type was found"), map[string]string{"step": "builder-exector", "status": "failure"})
-		return nil, err
+		return nil, nil, err
 	}
 	hostAlias, err := i.getHostAlias()
 	if err != nil {
 		i.Logger.Error(util.Translation("get wt-repo ip failure"), map[string]string{"step": "builder-exector", "status": "failure"})
-		return nil, err
+		return nil, nil, err
 	}
 	buildReq := &build.Request{
 		KanikoImage:   i.KanikoImage,
@@ -265,7 +282,7 @@ func (i *SourceCodeBuildItem) codeBuild() (*build.Response, error) {
 		CachePath:     i.CachePath,
 	}
 	res, err := codeBuild.Build(buildReq)
-	return res, err
+	return buildReq, res, err
 }
 
 func (i *SourceCodeBuildItem) getHostAlias() (hostAliasList []build.HostAlias, err error) {
@@ -298,6 +315,9 @@ func (i *SourceCodeBuildItem) prepare() error {
 	if err := util.CheckAndCreateDir(i.TGZDir); err != nil {
 		return err
 	}
+	if err := util.CheckAndCreateDir(i.ArtifactDir); err != nil {
+		return err
+	}
 	if err := util.CheckAndCreateDir(i.RepoInfo.GetCodeHome()); err != nil {
 		return err
 	}
@@ -335,6 +355,13 @@ func (i *SourceCodeBuildItem) UpdateVersionInfo(vi *dbmodel.VersionInfo) error {
 	if vi.FinalStatus != "" {
 		version.FinalStatus = vi.FinalStatus
 	}
+	if vi.SBOMPath != "" {
+		version.SBOMPath = vi.SBOMPath
+	}
+	if vi.ProvenancePath != "" {
+		version.ProvenancePath = vi.ProvenancePath
+	}
+	version.SignatureStatus = vi.SignatureStatus
 	version.CommitMsg = vi.CommitMsg
 	version.Author = vi.Author
 	version.CodeVersion = vi.CodeVersion
@@ -349,16 +376,19 @@ func (i *SourceCodeBuildItem) UpdateVersionInfo(vi *dbmodel.VersionInfo) error {
 // UpdateBuildVersionInfo update service build version info to db
 func (i *SourceCodeBuildItem) UpdateBuildVersionInfo(res *build.Response) error {
 	vi := &dbmodel.VersionInfo{
-		DeliveredType: string(res.MediumType),
-		DeliveredPath: res.MediumPath,
-		EventID:       i.EventID,
-		ImageName:     chaos.RUNNERIMAGENAME,
-		FinalStatus:   "success",
-		CodeBranch:    i.CodeSouceInfo.Branch,
-		CodeVersion:   i.commit.Hash,
-		CommitMsg:     i.commit.Message,
-		Author:        i.commit.Author,
-		FinishTime:    time.Now(),
+		DeliveredType:   string(res.MediumType),
+		DeliveredPath:   res.MediumPath,
+		EventID:         i.EventID,
+		ImageName:       chaos.RUNNERIMAGENAME,
+		FinalStatus:     "success",
+		CodeBranch:      i.CodeSouceInfo.Branch,
+		CodeVersion:     i.commit.Hash,
+		CommitMsg:       i.commit.Message,
+		Author:          i.commit.Author,
+		FinishTime:      time.Now(),
+		SBOMPath:        i.artifacts.SBOMPath,
+		ProvenancePath:  i.artifacts.ProvenancePath,
+		SignatureStatus: i.signatureStatus,
 	}
 	if err := i.UpdateVersionInfo(vi); err != nil {
 		logrus.Errorf("update version info error: %s", err.Error())
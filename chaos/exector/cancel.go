@@ -0,0 +1,165 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/event"
+	"github.com/wutong-paas/wutong/mq/api/grpc/pb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// buildCancelPrefix the etcd key prefix of the canceled build events.
+// The cancel task is received by one chaos node only, so it is broadcast
+// to all nodes through etcd and the node holding the build cancels it.
+const buildCancelPrefix = "/wutong/chaos/build-cancel/"
+
+// buildCancelTTL how long a cancel request is kept, so that a build still
+// waiting in mq is canceled when it is received
+const buildCancelTTL = 2 * 60 * 60
+
+// cancelBuild handles the cancel_build task
+func (e *exectorManager) cancelBuild(task *pb.TaskMessage) {
+	eventID := gjson.GetBytes(task.TaskBody, "event_id").String()
+	defer func() {
+		if e.mqClient == nil {
+			return
+		}
+		if err := e.mqClient.AckTask(task); err != nil {
+			logrus.Warningf("ack cancel build task %s failure %s", task.TaskId, err.Error())
+		}
+	}()
+	if eventID == "" {
+		logrus.Warningf("cancel build task %s without event id", task.TaskId)
+		return
+	}
+	if e.EtcdCli == nil {
+		logrus.Warningf("can not cancel build %s without etcd", eventID)
+		return
+	}
+	ctx, cancel := context.WithTimeout(e.ctx, time.Second*5)
+	defer cancel()
+	lease, err := e.EtcdCli.Grant(ctx, buildCancelTTL)
+	if err != nil {
+		logrus.Errorf("grant lease for cancel build %s failure %s", eventID, err.Error())
+		return
+	}
+	serviceID := gjson.GetBytes(task.TaskBody, "service_id").String()
+	if _, err := e.EtcdCli.Put(ctx, buildCancelPrefix+eventID, serviceID, clientv3.WithLease(lease.ID)); err != nil {
+		logrus.Errorf("put cancel build %s failure %s", eventID, err.Error())
+		return
+	}
+	logrus.Infof("build %s of service %s is requested to cancel", eventID, serviceID)
+}
+
+// watchBuildCancel cancels the builds of this node when they are canceled
+func (e *exectorManager) watchBuildCancel() {
+	for {
+		watchCh := e.EtcdCli.Watch(e.ctx, buildCancelPrefix, clientv3.WithPrefix())
+		for resp := range watchCh {
+			for _, ev := range resp.Events {
+				if ev.Type == clientv3.EventTypePut {
+					e.cancelLocalBuild(strings.TrimPrefix(string(ev.Kv.Key), buildCancelPrefix))
+				}
+			}
+		}
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-time.After(time.Second * 3):
+			logrus.Warning("watch build cancel channel closed, rewatch it")
+		}
+	}
+}
+
+// cancelLocalBuild cancels the build if it is pending or running on this node
+func (e *exectorManager) cancelLocalBuild(eventID string) {
+	if e.scheduler != nil {
+		pending := e.scheduler.remove(func(task *pb.TaskMessage) bool {
+			return gjson.GetBytes(task.TaskBody, "event_id").String() == eventID
+		})
+		for _, task := range pending {
+			logger := event.GetLogger(eventID)
+			logger.Error("应用组件构建已取消", event.GetCanceledLoggerOption())
+			event.CloseLogger(eventID)
+			cancelVersionInfo(eventID)
+			if e.mqClient != nil {
+				if err := e.mqClient.AckTask(task); err != nil {
+					logrus.Warningf("ack canceled task %s failure %s", task.TaskId, err.Error())
+				}
+			}
+			logrus.Infof("pending build task %s is canceled", task.TaskId)
+		}
+	}
+	if cancel, ok := e.buildCancels.Load(eventID); ok {
+		logrus.Infof("cancel running build %s", eventID)
+		cancel.(func())()
+	}
+}
+
+// isBuildCanceled checks whether the build was canceled before it started
+func (e *exectorManager) isBuildCanceled(eventID string) bool {
+	if e.EtcdCli == nil || eventID == "" {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(e.ctx, time.Second*3)
+	defer cancel()
+	res, err := e.EtcdCli.Get(ctx, buildCancelPrefix+eventID, clientv3.WithCountOnly())
+	if err != nil {
+		logrus.Warningf("get cancel build %s failure %s", eventID, err.Error())
+		return false
+	}
+	return res.Count > 0
+}
+
+// buildContext returns the context of the build, it is done when the build
+// is canceled. release must be called when the build is finished.
+func (e *exectorManager) buildContext(eventID string) (ctx context.Context, release func()) {
+	// not derived from e.ctx, the running builds are sent back to mq on stop
+	ctx, cancel := context.WithCancel(context.Background())
+	e.buildCancels.Store(eventID, func() { cancel() })
+	if e.isBuildCanceled(eventID) {
+		cancel()
+	}
+	return ctx, func() {
+		e.buildCancels.Delete(eventID)
+		cancel()
+	}
+}
+
+// cancelVersionInfo sets the final status of the build version to canceled
+func cancelVersionInfo(eventID string) {
+	version, err := db.GetManager().VersionInfoDao().GetVersionByEventID(eventID)
+	if err != nil {
+		logrus.Warningf("get version info by event %s failure %s", eventID, err.Error())
+		return
+	}
+	version.FinalStatus = string(dbmodel.EventFinalStatusCanceled)
+	version.FinishTime = time.Now()
+	if err := db.GetManager().VersionInfoDao().UpdateModel(version); err != nil {
+		logrus.Errorf("update version info of event %s failure %s", eventID, err.Error())
+	}
+}
@@ -58,6 +58,7 @@ type Manager interface {
 	GetMaxConcurrentTask() float64
 	GetCurrentConcurrentTask() float64
 	AddTask(*pb.TaskMessage) error
+	WaitCapacity(ctx context.Context) error
 	SetReturnTaskChan(func(*pb.TaskMessage))
 	Start() error
 	Stop() error
@@ -119,6 +120,7 @@ func NewManager(conf option.Config, mqc mqclient.MQClient) (Manager, error) {
 		EtcdCli:           etcdCli,
 		mqClient:          mqc,
 		tasks:             make(chan *pb.TaskMessage, maxConcurrentTask),
+		scheduler:         newTaskScheduler(maxConcurrentTask, conf.TenantEnvMaxTasks, conf.TenantEnvWeights),
 		maxConcurrentTask: maxConcurrentTask,
 		ctx:               ctx,
 		cancel:            cancel,
@@ -132,12 +134,14 @@ type exectorManager struct {
 	KubeClient        kubernetes.Interface
 	EtcdCli           *clientv3.Client
 	tasks             chan *pb.TaskMessage
+	scheduler         *taskScheduler
 	callback          func(*pb.TaskMessage)
 	maxConcurrentTask int
 	mqClient          mqclient.MQClient
 	ctx               context.Context
 	cancel            context.CancelFunc
 	runningTask       sync.Map
+	buildCancels      sync.Map
 	cfg               option.Config
 	imageClient       sources.ImageClient
 }
@@ -175,27 +179,38 @@ func (e *exectorManager) SetReturnTaskChan(re func(*pb.TaskMessage)) {
 // plugin_dockerfile_build build plugin from dockerfile
 // share-slug share app with slug
 // share-image share app with image
+// cancel_build cancel the build of the event, it is dequeued apart from the builds
 func (e *exectorManager) AddTask(task *pb.TaskMessage) error {
-	select {
-	case e.tasks <- task:
-		MetricTaskNum++
-		e.RunTask(task)
+	if task.TaskType == "cancel_build" {
+		go e.cancelBuild(task)
 		return nil
-	default:
-		logrus.Infof("The current number of parallel builds exceeds the maximum")
-		if e.callback != nil {
-			e.callback(task)
-			//Wait a while
-			//It's best to wait until the current controller can continue adding tasks
-			for len(e.tasks) >= e.maxConcurrentTask {
-				time.Sleep(time.Second * 2)
-			}
-			MetricBackTaskNum++
-			return nil
+	}
+	return e.scheduler.push(e.ctx, task)
+}
+
+// WaitCapacity blocks until a task can be added without waiting, the tasks
+// should be dequeued from mq only after it returns.
+func (e *exectorManager) WaitCapacity(ctx context.Context) error {
+	return e.scheduler.waitCapacity(ctx)
+}
+
+// schedule runs the pending tasks in the order of the scheduler
+func (e *exectorManager) schedule() {
+	for {
+		pt := e.scheduler.next(e.ctx)
+		if pt == nil {
+			return
 		}
-		return ErrCallback
+		select {
+		case e.tasks <- pt.task:
+		case <-e.ctx.Done():
+			return
+		}
+		MetricTaskNum++
+		e.RunTask(pt.task)
 	}
 }
+
 func (e *exectorManager) runTask(f func(task *pb.TaskMessage), task *pb.TaskMessage, concurrencyControl bool) {
 	logrus.Infof("Build task %s in progress", task.TaskId)
 	e.runningTask.LoadOrStore(task.TaskId, task)
@@ -205,7 +220,7 @@ func (e *exectorManager) runTask(f func(task *pb.TaskMessage), task *pb.TaskMess
 		defer func() { <-e.tasks }()
 	}
 	f(task)
-	e.runningTask.Delete(task.TaskId)
+	e.finishTask(task)
 	logrus.Infof("Build task %s is completed", task.TaskId)
 }
 func (e *exectorManager) runTaskWithErr(f func(task *pb.TaskMessage) error, task *pb.TaskMessage, concurrencyControl bool) {
@@ -220,9 +235,53 @@ func (e *exectorManager) runTaskWithErr(f func(task *pb.TaskMessage) error, task
 	if err := f(task); err != nil {
 		logrus.Errorf("run builder task failure %s", err.Error())
 	}
-	e.runningTask.Delete(task.TaskId)
+	e.finishTask(task)
 	logrus.Infof("Build task %s is completed", task.TaskId)
 }
+
+// finishTask releases the tenant env slot of the task and tells mq the task
+// is finished, so it will not be redelivered
+func (e *exectorManager) finishTask(task *pb.TaskMessage) {
+	e.runningTask.Delete(task.TaskId)
+	if e.scheduler != nil {
+		e.scheduler.done(task)
+	}
+	if e.mqClient == nil {
+		return
+	}
+	if err := e.mqClient.AckTask(task); err != nil {
+		logrus.Warningf("ack build task %s failure %s", task.TaskId, err.Error())
+	}
+}
+
+// keepLeases extends the leases of the pending and running tasks periodically,
+// so mq does not redeliver a build that is still in progress, however long it
+// takes. The leases of a crashed chaos expire within one lease timeout.
+func (e *exectorManager) keepLeases() {
+	if e.mqClient == nil || e.cfg.TaskLeaseTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(e.cfg.TaskLeaseTimeout) * time.Second / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		tasks := e.scheduler.tasks()
+		e.runningTask.Range(func(k, v interface{}) bool {
+			tasks = append(tasks, v.(*pb.TaskMessage))
+			return true
+		})
+		for _, task := range tasks {
+			if err := e.mqClient.ExtendTask(task, e.cfg.TaskLeaseTimeout); err != nil {
+				logrus.Warningf("extend the lease of build task %s failure %s", task.TaskId, err.Error())
+			}
+		}
+	}
+}
+
 func (e *exectorManager) RunTask(task *pb.TaskMessage) {
 	switch task.TaskType {
 	case "build_from_image":
@@ -261,6 +320,13 @@ func (e *exectorManager) exec(task *pb.TaskMessage) error {
 		return err
 	}
 	defer event.CloseLogger(worker.GetLogger().Event())
+	eventID := worker.GetLogger().Event()
+	e.buildCancels.Store(eventID, func() {
+		if err := worker.Stop(); err != nil {
+			logrus.Warningf("stop task %s failure %s", task.TaskId, err.Error())
+		}
+	})
+	defer e.buildCancels.Delete(eventID)
 	defer func() {
 		if r := recover(); r != nil {
 			debug.PrintStack()
@@ -280,6 +346,9 @@ func (e *exectorManager) exec(task *pb.TaskMessage) error {
 func (e *exectorManager) buildFromImage(task *pb.TaskMessage) {
 	i := NewImageBuildItem(task.TaskBody)
 	i.ImageClient = e.imageClient
+	ctx, release := e.buildContext(i.EventID)
+	defer release()
+	i.Ctx = ctx
 	i.Logger.Info("开始构建应用组件（镜像源方式）...", map[string]string{"step": "builder-exector", "status": "starting"})
 	defer event.CloseLogger(i.Logger.Event())
 	defer func() {
@@ -294,6 +363,14 @@ func (e *exectorManager) buildFromImage(task *pb.TaskMessage) {
 	}()
 	for n := 0; n < 2; n++ {
 		err := i.Run(time.Minute * 30)
+		if err != nil && ctx.Err() != nil {
+			logrus.Infof("build from image %s is canceled", i.EventID)
+			i.Logger.Error("应用组件构建已取消", event.GetCanceledLoggerOption())
+			if err := i.UpdateVersionInfo(string(dbmodel.EventFinalStatusCanceled)); err != nil {
+				logrus.Debugf("update version Info error: %s", err.Error())
+			}
+			break
+		}
 		if err != nil {
 			logrus.Errorf("build from image error: %s", err.Error())
 			if n < 1 {
@@ -334,7 +411,9 @@ func (e *exectorManager) buildFromSourceCode(task *pb.TaskMessage) {
 	i.KubeClient = e.KubeClient
 	i.WtNamespace = e.cfg.WtNamespace
 	i.WtRepoName = e.cfg.WtRepoName
-	i.Ctx = e.ctx
+	ctx, release := e.buildContext(i.EventID)
+	defer release()
+	i.Ctx = ctx
 	i.CachePVCName = e.cfg.CachePVCName
 	i.WTDataPVCName = e.cfg.WTDataPVCName
 	i.CacheMode = e.cfg.CacheMode
@@ -353,10 +432,17 @@ func (e *exectorManager) buildFromSourceCode(task *pb.TaskMessage) {
 	}()
 	err := i.Run(time.Minute * 30)
 	if err != nil {
-		logrus.Errorf("build from source code error: %s", err.Error())
-		i.Logger.Error(util.Translation("Check for log location code errors"), map[string]string{"step": "callback", "status": "failure"})
+		finalStatus := "failure"
+		if ctx.Err() != nil {
+			logrus.Infof("build from source code %s is canceled", i.EventID)
+			i.Logger.Error("应用组件构建已取消", event.GetCanceledLoggerOption())
+			finalStatus = string(dbmodel.EventFinalStatusCanceled)
+		} else {
+			logrus.Errorf("build from source code error: %s", err.Error())
+			i.Logger.Error(util.Translation("Check for log location code errors"), map[string]string{"step": "callback", "status": "failure"})
+		}
 		vi := &dbmodel.VersionInfo{
-			FinalStatus: "failure",
+			FinalStatus: finalStatus,
 			EventID:     i.EventID,
 			CodeBranch:  i.CodeSouceInfo.Branch,
 			CodeVersion: i.commit.Hash,
@@ -567,11 +653,22 @@ func (e *exectorManager) garbageCollection(task *pb.TaskMessage) {
 }
 
 func (e *exectorManager) Start() error {
+	go e.schedule()
+	go e.keepLeases()
+	if e.EtcdCli != nil {
+		go e.watchBuildCancel()
+	}
 	return nil
 }
 func (e *exectorManager) Stop() error {
 	e.cancel()
 	logrus.Info("Waiting for all threads to exit.")
+	//Return the tasks that have not started
+	if e.scheduler != nil && e.callback != nil {
+		for _, task := range e.scheduler.drain() {
+			e.callback(task)
+		}
+	}
 	//Recycle all ongoing tasks
 	e.runningTask.Range(func(k, v interface{}) bool {
 		task := v.(*pb.TaskMessage)
@@ -0,0 +1,79 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package exector
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/chaos/sources"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/event"
+)
+
+// verifyImageSignature verifies the image with the cluster policy and the image signature
+// policy of the tenant env. The returned status is recorded in the version info and checked
+// by the worker before the image is deployed, it is empty if no policy matches. The image
+// is verified by the digest, the returned image is pinned to the digest if it is verified.
+func verifyImageSignature(tenantEnvID, image, user, password string, logger event.Logger) (string, string) {
+	policies, err := db.GetManager().ImageSignaturePolicyDao().GetEffectivePolicies(tenantEnvID)
+	if err != nil {
+		// the worker refuses to deploy the image which is not verified by the enforced policy
+		logrus.Warningf("get image signature policy of tenant env %s failure %s", tenantEnvID, err.Error())
+		return "", image
+	}
+	policies = policies.Match(image)
+	if len(policies) == 0 {
+		return "", image
+	}
+	pinned, err := sources.ResolveImageDigest(image, user, password)
+	if err != nil {
+		logrus.Warningf("resolve digest of image %s failure %s", image, err.Error())
+		logger.Error(fmt.Sprintf("镜像 %s 签名验证失败：%s", image, err.Error()), map[string]string{"step": "builder-exector"})
+		return dbmodel.ImageSignatureUnverified, image
+	}
+	enforced := policies.IsEnforced()
+	status := dbmodel.ImageSignatureVerified
+	for _, policy := range policies {
+		verifier, err := sources.NewImageSignatureVerifier(policy)
+		if err == nil {
+			err = verifier.Verify(pinned, user, password)
+		}
+		if err == nil {
+			continue
+		}
+		logrus.Warningf("verify signature of image %s failure %s", image, err.Error())
+		msg := fmt.Sprintf("镜像 %s 签名验证失败：%s", image, err.Error())
+		if policy.IsEnforced() {
+			logger.Error(msg+"，该版本将无法部署", map[string]string{"step": "builder-exector"})
+		} else {
+			logger.Info(msg, map[string]string{"step": "builder-exector"})
+		}
+		// the audit policy does not fail the image verified by the enforced policy
+		if policy.IsEnforced() || !enforced {
+			status = dbmodel.ImageSignatureUnverified
+		}
+	}
+	if status != dbmodel.ImageSignatureVerified {
+		return status, image
+	}
+	logger.Info(fmt.Sprintf("镜像 %s 签名验证通过", pinned), map[string]string{"step": "builder-exector"})
+	return status, pinned
+}
@@ -0,0 +1,348 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package exector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wutong-paas/wutong/mq/api/grpc/pb"
+)
+
+// MetricTaskWaitSeconds the time tasks wait in the scheduler before running
+var MetricTaskWaitSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "builder",
+	Subsystem: "exporter",
+	Name:      "builder_task_wait_seconds",
+	Help:      "Time build tasks wait before running, by tenant env",
+	Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+}, []string{"tenant_env"})
+
+// MetricPendingTasks the number of tasks waiting in the scheduler
+var MetricPendingTasks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "builder",
+	Subsystem: "exporter",
+	Name:      "builder_pending_task",
+	Help:      "Number of build tasks waiting to run, by tenant env",
+}, []string{"tenant_env"})
+
+// taskScheduler orders the pending tasks by priority first, then by weighted
+// fair share across tenant envs, so that one tenant env can not starve the
+// others. It also caps the running tasks of each tenant env, and the pending
+// tasks each tenant env may hold by its weight, so a part of the buffer is
+// always left for the other tenant envs. The tasks over the share are held in
+// the order they are dequeued, and moved to the buffer as it gets room.
+type taskScheduler struct {
+	lock        sync.Mutex
+	notify      chan struct{}
+	tenants     map[string]*tenantQueue
+	pending     int
+	held        int
+	maxPending  int
+	tenantLimit int
+	weights     map[string]int
+	// vclock is the virtual time of the last scheduled task
+	vclock float64
+}
+
+type tenantQueue struct {
+	key     string
+	tasks   []*pendingTask
+	held    []*pb.TaskMessage
+	running int
+	weight  float64
+	vtime   float64
+}
+
+type pendingTask struct {
+	task *pb.TaskMessage
+	// enqueued the time the task is enqueued to mq
+	enqueued time.Time
+}
+
+func newTaskScheduler(maxPending, tenantLimit int, weights map[string]int) *taskScheduler {
+	return &taskScheduler{
+		notify:      make(chan struct{}),
+		tenants:     make(map[string]*tenantQueue),
+		maxPending:  maxPending,
+		tenantLimit: tenantLimit,
+		weights:     weights,
+	}
+}
+
+func (s *taskScheduler) tenant(key string) *tenantQueue {
+	t, ok := s.tenants[key]
+	if !ok {
+		weight := 1.0
+		if w, ok := s.weights[key]; ok && w > 0 {
+			weight = float64(w)
+		}
+		// a tenant env that was idle does not get credit for the idle time
+		t = &tenantQueue{key: key, weight: weight, vtime: s.vclock}
+		s.tenants[key] = t
+	}
+	return t
+}
+
+// wakeup must be called with the lock held
+func (s *taskScheduler) wakeup() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// waiting returns the tasks of the tenant env waiting to run
+func (t *tenantQueue) waiting() int {
+	return len(t.tasks) + len(t.held)
+}
+
+// waitCapacity blocks until the scheduler can accept a task, so the tasks
+// wait in mq rather than being dequeued and held.
+func (s *taskScheduler) waitCapacity(ctx context.Context) error {
+	for {
+		s.lock.Lock()
+		if s.pending < s.maxPending && s.held < s.maxPending {
+			s.lock.Unlock()
+			return nil
+		}
+		notify := s.notify
+		s.lock.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+// push adds the task to its tenant env queue, it blocks while the scheduler is full.
+// The task is held if the tenant env holds its share of the pending tasks already.
+func (s *taskScheduler) push(ctx context.Context, task *pb.TaskMessage) error {
+	for {
+		if err := s.waitCapacity(ctx); err != nil {
+			return err
+		}
+		s.lock.Lock()
+		if s.pending < s.maxPending && s.held < s.maxPending {
+			t := s.tenant(task.TenantKey)
+			t.held = append(t.held, task)
+			s.held++
+			s.promote()
+			MetricPendingTasks.WithLabelValues(t.key).Set(float64(t.waiting()))
+			s.lock.Unlock()
+			return nil
+		}
+		s.lock.Unlock()
+	}
+}
+
+// promote moves the held tasks to the buffer in FIFO order, as long as there is room
+// and the tenant env is within its share. must be called with the lock held
+func (s *taskScheduler) promote() {
+	for _, t := range s.tenants {
+		for len(t.held) > 0 && s.pending < s.maxPending && len(t.tasks) < s.share(t) {
+			task := t.held[0]
+			t.held = t.held[1:]
+			s.held--
+			s.insert(t, task)
+		}
+	}
+}
+
+// share returns the pending tasks the tenant env may hold, the buffer is split by the
+// weights of the tenant envs with pending tasks and one more of the default weight,
+// which is left for a tenant env that has none yet. must be called with the lock held
+func (s *taskScheduler) share(t *tenantQueue) int {
+	total := t.weight + 1
+	for _, other := range s.tenants {
+		if other != t && len(other.tasks) > 0 {
+			total += other.weight
+		}
+	}
+	if share := int(float64(s.maxPending) * t.weight / total); share > 1 {
+		return share
+	}
+	return 1
+}
+
+// insert keeps the tenant queue ordered by priority, FIFO for the same priority
+func (s *taskScheduler) insert(t *tenantQueue, task *pb.TaskMessage) {
+	i := len(t.tasks)
+	for i > 0 && t.tasks[i-1].task.Priority < task.Priority {
+		i--
+	}
+	t.tasks = append(t.tasks, nil)
+	copy(t.tasks[i+1:], t.tasks[i:])
+	t.tasks[i] = &pendingTask{task: task, enqueued: enqueueTime(task)}
+	s.pending++
+	MetricPendingTasks.WithLabelValues(t.key).Set(float64(t.waiting()))
+	s.wakeup()
+}
+
+// next blocks until there is a task to run
+func (s *taskScheduler) next(ctx context.Context) *pendingTask {
+	for {
+		s.lock.Lock()
+		if pt := s.pick(); pt != nil {
+			s.lock.Unlock()
+			MetricTaskWaitSeconds.WithLabelValues(pt.task.TenantKey).Observe(time.Since(pt.enqueued).Seconds())
+			return pt
+		}
+		notify := s.notify
+		s.lock.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+		}
+	}
+}
+
+// pick must be called with the lock held
+func (s *taskScheduler) pick() *pendingTask {
+	var selected *tenantQueue
+	for _, t := range s.tenants {
+		if len(t.tasks) == 0 || (s.tenantLimit > 0 && t.running >= s.tenantLimit) {
+			continue
+		}
+		if selected == nil {
+			selected = t
+			continue
+		}
+		head, selectedHead := t.tasks[0].task.Priority, selected.tasks[0].task.Priority
+		if head > selectedHead || (head == selectedHead && t.vtime < selected.vtime) {
+			selected = t
+		}
+	}
+	if selected == nil {
+		return nil
+	}
+	pt := selected.tasks[0]
+	selected.tasks = selected.tasks[1:]
+	selected.running++
+	s.pending--
+	if selected.vtime > s.vclock {
+		s.vclock = selected.vtime
+	}
+	selected.vtime += 1 / selected.weight
+	s.promote()
+	MetricPendingTasks.WithLabelValues(selected.key).Set(float64(selected.waiting()))
+	// there is room for the tasks waiting for the capacity
+	s.wakeup()
+	return pt
+}
+
+// enqueueTime returns the time the task is enqueued to mq, or now if it is unknown
+func enqueueTime(task *pb.TaskMessage) time.Time {
+	if enqueued, err := time.Parse(time.RFC3339, task.CreateTime); err == nil {
+		return enqueued
+	}
+	return time.Now()
+}
+
+// done marks a task of the tenant env finished
+func (s *taskScheduler) done(task *pb.TaskMessage) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	t, ok := s.tenants[task.TenantKey]
+	if !ok {
+		return
+	}
+	if t.running > 0 {
+		t.running--
+	}
+	s.cleanup(t)
+	s.wakeup()
+}
+
+// cleanup forgets the idle tenant env, must be called with the lock held
+func (s *taskScheduler) cleanup(t *tenantQueue) {
+	if t.waiting() == 0 && t.running == 0 {
+		delete(s.tenants, t.key)
+	}
+}
+
+// drain removes all pending tasks
+func (s *taskScheduler) drain() []*pb.TaskMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var tasks []*pb.TaskMessage
+	for _, t := range s.tenants {
+		for _, pt := range t.tasks {
+			tasks = append(tasks, pt.task)
+		}
+		tasks = append(tasks, t.held...)
+		t.tasks, t.held = nil, nil
+		MetricPendingTasks.WithLabelValues(t.key).Set(0)
+		s.cleanup(t)
+	}
+	s.pending, s.held = 0, 0
+	s.wakeup()
+	return tasks
+}
+
+// tasks returns the pending tasks
+func (s *taskScheduler) tasks() []*pb.TaskMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var tasks []*pb.TaskMessage
+	for _, t := range s.tenants {
+		for _, pt := range t.tasks {
+			tasks = append(tasks, pt.task)
+		}
+		tasks = append(tasks, t.held...)
+	}
+	return tasks
+}
+
+// remove takes the pending tasks matched by the filter out of the scheduler
+func (s *taskScheduler) remove(match func(*pb.TaskMessage) bool) []*pb.TaskMessage {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var removed []*pb.TaskMessage
+	for _, t := range s.tenants {
+		kept := t.tasks[:0]
+		for _, pt := range t.tasks {
+			if match(pt.task) {
+				removed = append(removed, pt.task)
+				continue
+			}
+			kept = append(kept, pt)
+		}
+		s.pending -= len(t.tasks) - len(kept)
+		t.tasks = kept
+		keptHeld := t.held[:0]
+		for _, task := range t.held {
+			if match(task) {
+				removed = append(removed, task)
+				continue
+			}
+			keptHeld = append(keptHeld, task)
+		}
+		s.held -= len(t.held) - len(keptHeld)
+		t.held = keptHeld
+		MetricPendingTasks.WithLabelValues(t.key).Set(float64(t.waiting()))
+		s.cleanup(t)
+	}
+	if len(removed) > 0 {
+		s.promote()
+		s.wakeup()
+	}
+	return removed
+}
@@ -0,0 +1,211 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package exector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wutong-paas/wutong/mq/api/grpc/pb"
+)
+
+func nextTenants(t *testing.T, s *taskScheduler, n int) []string {
+	var tenants []string
+	for i := 0; i < n; i++ {
+		s.lock.Lock()
+		pt := s.pick()
+		s.lock.Unlock()
+		if pt == nil {
+			t.Fatalf("expected a task at %d", i)
+		}
+		tenants = append(tenants, pt.task.TaskId)
+	}
+	return tenants
+}
+
+func TestTaskSchedulerFairShare(t *testing.T) {
+	s := newTaskScheduler(100, 0, map[string]int{"c": 2})
+	for i := 0; i < 10; i++ {
+		s.push(context.Background(), &pb.TaskMessage{TaskId: "a", TenantKey: "a"})
+	}
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "b", TenantKey: "b"})
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "b", TenantKey: "b"})
+	got := nextTenants(t, s, 4)
+	var b int
+	for _, tenant := range got {
+		if tenant == "b" {
+			b++
+		}
+	}
+	if b != 2 {
+		t.Fatalf("expected tenant b to get half of the first slots, got %v", got)
+	}
+
+	// the weight 2 tenant gets twice the share
+	s = newTaskScheduler(100, 0, map[string]int{"c": 2})
+	for i := 0; i < 6; i++ {
+		s.push(context.Background(), &pb.TaskMessage{TaskId: "a", TenantKey: "a"})
+		s.push(context.Background(), &pb.TaskMessage{TaskId: "c", TenantKey: "c"})
+	}
+	var c int
+	for _, tenant := range nextTenants(t, s, 6) {
+		if tenant == "c" {
+			c++
+		}
+	}
+	if c != 4 {
+		t.Fatalf("expected weighted tenant c to get 4 of 6 slots, got %d", c)
+	}
+}
+
+func TestTaskSchedulerPriorityAndLimit(t *testing.T) {
+	s := newTaskScheduler(100, 1, nil)
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "a1", TenantKey: "a"})
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "a2", TenantKey: "a", Priority: 10})
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "b1", TenantKey: "b"})
+
+	pt := s.next(context.Background())
+	if pt.task.TaskId != "a2" {
+		t.Fatalf("expected the high priority task first, got %s", pt.task.TaskId)
+	}
+	pt = s.next(context.Background())
+	if pt.task.TaskId != "b1" {
+		t.Fatalf("expected tenant a to be limited, got %s", pt.task.TaskId)
+	}
+	s.lock.Lock()
+	if s.pick() != nil {
+		t.Fatal("all tenants are at the limit, nothing should be picked")
+	}
+	s.lock.Unlock()
+	s.done(&pb.TaskMessage{TaskId: "a2", TenantKey: "a"})
+	if pt := s.next(context.Background()); pt.task.TaskId != "a1" {
+		t.Fatalf("expected a1 after a2 is done, got %s", pt.task.TaskId)
+	}
+}
+
+func TestTaskSchedulerCapacity(t *testing.T) {
+	s := newTaskScheduler(2, 0, nil)
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "a1", TenantKey: "a"})
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "c1", TenantKey: "c"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.push(ctx, &pb.TaskMessage{TaskId: "b1", TenantKey: "b"}); err == nil {
+		t.Fatal("expected the push to block until the context is done")
+	}
+
+	pushed := make(chan error)
+	go func() {
+		pushed <- s.push(context.Background(), &pb.TaskMessage{TaskId: "b1", TenantKey: "b"})
+	}()
+	if pt := s.next(context.Background()); pt.task.TaskId != "a1" {
+		t.Fatalf("expected a1, got %s", pt.task.TaskId)
+	}
+	select {
+	case err := <-pushed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the push to go on once a task is scheduled")
+	}
+}
+
+func TestTaskSchedulerTenantShare(t *testing.T) {
+	s := newTaskScheduler(4, 1, nil)
+	// tenant a is at its running limit, its pending tasks can not fill the buffer
+	for i := 0; i < 3; i++ {
+		if err := s.push(context.Background(), &pb.TaskMessage{TaskId: "a", TenantKey: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			s.next(context.Background())
+		}
+	}
+	if err := s.push(context.Background(), &pb.TaskMessage{TaskId: "a4", TenantKey: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if s.pending != 2 || s.held != 1 {
+		t.Fatalf("expected the task over the share of tenant a to be held, got %d pending %d held", s.pending, s.held)
+	}
+	if err := s.push(context.Background(), &pb.TaskMessage{TaskId: "b", TenantKey: "b"}); err != nil {
+		t.Fatalf("expected room for tenant b, got %v", err)
+	}
+	if pt := s.next(context.Background()); pt.task.TaskId != "b" {
+		t.Fatalf("expected tenant b to run while tenant a is limited, got %s", pt.task.TaskId)
+	}
+	if len(s.tasks()) != 3 {
+		t.Fatalf("expected the held task to be listed with the pending ones, got %d", len(s.tasks()))
+	}
+
+	// the held task keeps its place behind the pending tasks of tenant a
+	for _, want := range []string{"a", "a", "a4"} {
+		s.done(&pb.TaskMessage{TenantKey: "a"})
+		if pt := s.next(context.Background()); pt.task.TaskId != want {
+			t.Fatalf("expected %s, got %s", want, pt.task.TaskId)
+		}
+	}
+	if s.held != 0 {
+		t.Fatalf("expected no held task, got %d", s.held)
+	}
+}
+
+func TestTaskSchedulerWeightedShare(t *testing.T) {
+	s := newTaskScheduler(10, 0, map[string]int{"c": 3})
+	for i := 0; i < 10; i++ {
+		s.push(context.Background(), &pb.TaskMessage{TaskId: "c", TenantKey: "c"})
+	}
+	// c gets 3 of the 4 weights, the rest is left for a tenant env that has no task yet
+	if len(s.tenants["c"].tasks) != 7 {
+		t.Fatalf("expected 7 pending tasks of the weight 3 tenant, got %d", len(s.tenants["c"].tasks))
+	}
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "a", TenantKey: "a"})
+	if len(s.tenants["a"].tasks) != 1 {
+		t.Fatal("expected the task of tenant a to be pending at once")
+	}
+}
+
+func TestEnqueueTime(t *testing.T) {
+	enqueued := time.Now().Add(-time.Minute).Truncate(time.Second)
+	if got := enqueueTime(&pb.TaskMessage{CreateTime: enqueued.Format(time.RFC3339)}); !got.Equal(enqueued) {
+		t.Fatalf("expected %v, got %v", enqueued, got)
+	}
+	if got := enqueueTime(&pb.TaskMessage{}); time.Since(got) > time.Second {
+		t.Fatalf("expected now for the unknown create time, got %v", got)
+	}
+}
+
+func TestTaskSchedulerRemove(t *testing.T) {
+	s := newTaskScheduler(10, 0, nil)
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "a1", TenantKey: "a"})
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "a2", TenantKey: "a"})
+	s.push(context.Background(), &pb.TaskMessage{TaskId: "b1", TenantKey: "b"})
+	removed := s.remove(func(task *pb.TaskMessage) bool { return task.TaskId != "a2" })
+	if len(removed) != 2 {
+		t.Fatalf("expected 2 removed tasks, got %d", len(removed))
+	}
+	if _, ok := s.tenants["b"]; ok {
+		t.Fatal("expected the empty tenant b to be forgotten")
+	}
+	if pt := s.next(context.Background()); pt.task.TaskId != "a2" {
+		t.Fatalf("expected a2 to be left, got %s", pt.task.TaskId)
+	}
+	if s.pending != 0 {
+		t.Fatalf("expected no pending task, got %d", s.pending)
+	}
+}
@@ -0,0 +1,114 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package code
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BuildCmdEnv the build env of the service holding the build command
+const BuildCmdEnv = "BUILD_CMD"
+
+// GradleTaskEnv the build env of the service holding the gradle task of the module to build,
+// the gradle buildpack of the slug builder runs it, the buildpacks get it as BP_GRADLE_BUILD_ARGUMENTS
+const GradleTaskEnv = "GRADLE_TASK"
+
+// BuildCmdEnvs returns the buildpacks envs that make the buildpacks of the language run the build command.
+// The buildpacks do not run a shell command, so only the commands of the build tool of the language are
+// supported, they are passed to the buildpacks as the arguments of the build tool:
+//
+//	Node.js      npm run <script>, yarn [run] <script>, joined by &&   BP_NODE_RUN_SCRIPTS
+//	Go           go build [flags] [./targets]                          BP_GO_BUILD_FLAGS, BP_GO_TARGETS
+//	Java-maven   mvn|./mvnw <arguments>                                BP_MAVEN_BUILD_ARGUMENTS
+//	Gradle       gradle|./gradlew <arguments>                          BP_GRADLE_BUILD_ARGUMENTS
+//	.NetCore     dotnet publish [flags]                                BP_DOTNET_PUBLISH_FLAGS
+func BuildCmdEnvs(lang Lang, cmd string) (map[string]string, error) {
+	if strings.ContainsAny(cmd, "'\"`$|;<>\\") {
+		return nil, fmt.Errorf("the build command %q can not contain the shell syntax", cmd)
+	}
+	fields := strings.Fields(cmd)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("the build command is empty")
+	}
+	switch lang {
+	case Nodejs:
+		var scripts []string
+		for _, step := range strings.Split(cmd, "&&") {
+			script, ok := nodeRunScript(strings.Fields(step))
+			if !ok {
+				return nil, fmt.Errorf("the build command %q of %s must run the scripts of package.json by npm run or yarn run", cmd, lang)
+			}
+			scripts = append(scripts, script)
+		}
+		return map[string]string{"BP_NODE_RUN_SCRIPTS": strings.Join(scripts, ",")}, nil
+	case Golang:
+		if len(fields) < 2 || fields[0] != "go" || fields[1] != "build" {
+			return nil, fmt.Errorf("the build command %q of %s must be go build", cmd, lang)
+		}
+		var flags, targets []string
+		for _, field := range fields[2:] {
+			if strings.HasPrefix(field, "./") || field == "." {
+				targets = append(targets, field)
+				continue
+			}
+			flags = append(flags, field)
+		}
+		envs := make(map[string]string)
+		if len(flags) > 0 {
+			envs["BP_GO_BUILD_FLAGS"] = strings.Join(flags, " ")
+		}
+		if len(targets) > 0 {
+			envs["BP_GO_TARGETS"] = strings.Join(targets, ":")
+		}
+		return envs, nil
+	case JavaMaven:
+		if fields[0] != "mvn" && fields[0] != "./mvnw" && fields[0] != "mvnw" || len(fields) < 2 {
+			return nil, fmt.Errorf("the build command %q of %s must be mvn with the arguments", cmd, lang)
+		}
+		return map[string]string{"BP_MAVEN_BUILD_ARGUMENTS": strings.Join(fields[1:], " ")}, nil
+	case Gradle:
+		if fields[0] != "gradle" && fields[0] != "./gradlew" && fields[0] != "gradlew" || len(fields) < 2 {
+			return nil, fmt.Errorf("the build command %q of %s must be gradle with the arguments", cmd, lang)
+		}
+		return map[string]string{"BP_GRADLE_BUILD_ARGUMENTS": strings.Join(fields[1:], " ")}, nil
+	case NetCore:
+		if len(fields) < 2 || fields[0] != "dotnet" || fields[1] != "publish" {
+			return nil, fmt.Errorf("the build command %q of %s must be dotnet publish", cmd, lang)
+		}
+		if len(fields) == 2 {
+			return map[string]string{}, nil
+		}
+		return map[string]string{"BP_DOTNET_PUBLISH_FLAGS": strings.Join(fields[2:], " ")}, nil
+	}
+	return nil, fmt.Errorf("the build command is not supported by %s", lang)
+}
+
+// nodeRunScript returns the script of package.json run by npm or yarn
+func nodeRunScript(fields []string) (string, bool) {
+	switch {
+	case len(fields) == 3 && fields[0] == "npm" && (fields[1] == "run" || fields[1] == "run-script"):
+		return fields[2], true
+	case len(fields) == 3 && fields[0] == "yarn" && fields[1] == "run":
+		return fields[2], true
+	case len(fields) == 2 && fields[0] == "yarn" && !strings.HasPrefix(fields[1], "-"):
+		return fields[1], true
+	}
+	return "", false
+}
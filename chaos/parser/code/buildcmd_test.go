@@ -0,0 +1,55 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package code
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildCmdEnvs(t *testing.T) {
+	tests := []struct {
+		lang Lang
+		cmd  string
+		want map[string]string
+	}{
+		{Nodejs, "npm run build", map[string]string{"BP_NODE_RUN_SCRIPTS": "build"}},
+		{Nodejs, "yarn lint && yarn run build", map[string]string{"BP_NODE_RUN_SCRIPTS": "lint,build"}},
+		{Golang, "go build -ldflags=-s ./cmd/api ./cmd/worker", map[string]string{"BP_GO_BUILD_FLAGS": "-ldflags=-s", "BP_GO_TARGETS": "./cmd/api:./cmd/worker"}},
+		{JavaMaven, "./mvnw -DskipTests package", map[string]string{"BP_MAVEN_BUILD_ARGUMENTS": "-DskipTests package"}},
+		{Gradle, "gradle build -x test", map[string]string{"BP_GRADLE_BUILD_ARGUMENTS": "build -x test"}},
+		{NetCore, "dotnet publish -c Release", map[string]string{"BP_DOTNET_PUBLISH_FLAGS": "-c Release"}},
+		{Nodejs, "npm install && node build.js", nil},
+		{Golang, "make build", nil},
+		{Golang, "go build -ldflags \"-X main.v=1\" ./cmd/api", nil},
+		{Python, "python setup.py build", nil},
+	}
+	for _, tc := range tests {
+		got, err := BuildCmdEnvs(tc.lang, tc.cmd)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("%s %q: expected error, got %v", tc.lang, tc.cmd, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %q: expected %v, got %v %v", tc.lang, tc.cmd, tc.want, got, err)
+		}
+	}
+}
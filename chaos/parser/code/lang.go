@@ -32,9 +32,13 @@ func init() {
 	checkFuncList = append(checkFuncList, javaMaven)
 	checkFuncList = append(checkFuncList, php)
 	checkFuncList = append(checkFuncList, python)
+	checkFuncList = append(checkFuncList, deno)
+	checkFuncList = append(checkFuncList, bun)
 	checkFuncList = append(checkFuncList, nodeJSStatic)
 	checkFuncList = append(checkFuncList, nodejs)
 	checkFuncList = append(checkFuncList, ruby)
+	checkFuncList = append(checkFuncList, rust)
+	checkFuncList = append(checkFuncList, elixir)
 	checkFuncList = append(checkFuncList, static)
 	checkFuncList = append(checkFuncList, clojure)
 	checkFuncList = append(checkFuncList, golang)
@@ -118,6 +122,18 @@ var NetCore Lang = ".NetCore"
 //OSS Lang
 var OSS Lang = "OSS"
 
+//Rust Lang
+var Rust Lang = "Rust"
+
+//Elixir Lang
+var Elixir Lang = "Elixir"
+
+//Deno Lang
+var Deno Lang = "Deno"
+
+//Bun Lang
+var Bun Lang = "Bun"
+
 //GetLangType check code lang
 func GetLangType(homepath string) (Lang, error) {
 	if ok, _ := util.FileExists(homepath); !ok {
@@ -158,8 +174,29 @@ func python(homepath string) Lang {
 	if ok, _ := util.FileExists(path.Join(homepath, "Pipfile")); ok {
 		return Python
 	}
+	if pythonPackageTool(homepath) != "" {
+		return Python
+	}
 	return NO
 }
+
+//pythonPackageTool returns poetry or uv if the project is managed by them,
+//pyproject.toml alone may only hold the config of some python tools
+func pythonPackageTool(homepath string) string {
+	if ok, _ := util.FileExists(path.Join(homepath, "uv.lock")); ok {
+		return "uv"
+	}
+	if ok, _ := util.FileExists(path.Join(homepath, "poetry.lock")); ok {
+		return "poetry"
+	}
+	if util.SearchFileBody(path.Join(homepath, "pyproject.toml"), "[tool.poetry]") {
+		return "poetry"
+	}
+	if util.SearchFileBody(path.Join(homepath, "pyproject.toml"), "[tool.uv]") {
+		return "uv"
+	}
+	return ""
+}
 func ruby(homepath string) Lang {
 	if ok, _ := util.FileExists(path.Join(homepath, "Gemfile")); ok {
 		return Ruby
@@ -222,6 +259,23 @@ func nodejs(homepath string) Lang {
 	}
 	return NO
 }
+//deno project may also have package.json for npm compatibility
+func deno(homepath string) Lang {
+	for _, name := range []string{"deno.json", "deno.jsonc", "deno.lock"} {
+		if ok, _ := util.FileExists(path.Join(homepath, name)); ok {
+			return Deno
+		}
+	}
+	return NO
+}
+func bun(homepath string) Lang {
+	for _, name := range []string{"bun.lockb", "bun.lock", "bunfig.toml"} {
+		if ok, _ := util.FileExists(path.Join(homepath, name)); ok {
+			return Bun
+		}
+	}
+	return NO
+}
 func nodeJSStatic(homepath string) Lang {
 	if ok, _ := util.FileExists(path.Join(homepath, "package.json")); ok {
 		if ok, _ := util.FileExists(path.Join(homepath, "nodestatic.json")); ok {
@@ -244,6 +298,19 @@ func static(homepath string) Lang {
 	return NO
 }
 
+func rust(homepath string) Lang {
+	if ok, _ := util.FileExists(path.Join(homepath, "Cargo.toml")); ok {
+		return Rust
+	}
+	return NO
+}
+func elixir(homepath string) Lang {
+	if ok, _ := util.FileExists(path.Join(homepath, "mix.exs")); ok {
+		return Elixir
+	}
+	return NO
+}
+
 func clojure(homepath string) Lang {
 	if ok, _ := util.FileExists(path.Join(homepath, "project.clj")); ok {
 		return Clojure
@@ -281,6 +348,12 @@ func gradle(homepath string) Lang {
 	if ok, _ := util.FileExists(path.Join(homepath, "settings.gradle")); ok {
 		return Gradle
 	}
+	if ok, _ := util.FileExists(path.Join(homepath, "build.gradle.kts")); ok {
+		return Gradle
+	}
+	if ok, _ := util.FileExists(path.Join(homepath, "settings.gradle.kts")); ok {
+		return Gradle
+	}
 	return NO
 }
 func grails(homepath string) Lang {
@@ -0,0 +1,120 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package code
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, body := range files {
+		file := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGetLangTypeAndRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		lang    Lang
+		runtime map[string]string
+	}{
+		{
+			name:    "rust",
+			files:   map[string]string{"Cargo.toml": "[package]\nname = \"demo\"\nrust-version = \"1.70\"\n", "src/main.rs": ""},
+			lang:    Rust,
+			runtime: map[string]string{"RUNTIMES": "1.70"},
+		},
+		{
+			name:    "rust toolchain",
+			files:   map[string]string{"Cargo.toml": "", "rust-toolchain.toml": "[toolchain]\nchannel = \"1.75.0\"\n"},
+			lang:    Rust,
+			runtime: map[string]string{"RUNTIMES": "1.75.0"},
+		},
+		{
+			name:    "elixir",
+			files:   map[string]string{"mix.exs": "  def project do\n    [\n      app: :demo,\n      elixir: \"~> 1.15\",\n", ".tool-versions": "erlang 26.1\n"},
+			lang:    Elixir,
+			runtime: map[string]string{"RUNTIMES": "1.15", "RUNTIMES_ERLANG": "26.1"},
+		},
+		{
+			name:    "deno",
+			files:   map[string]string{"deno.json": "{}", "package.json": "{}", ".dvmrc": "1.40.2\n"},
+			lang:    Deno,
+			runtime: map[string]string{"RUNTIMES": "1.40.2"},
+		},
+		{
+			name:    "bun",
+			files:   map[string]string{"bun.lockb": "", "package.json": `{"packageManager": "bun@1.1.0"}`},
+			lang:    Bun,
+			runtime: map[string]string{"RUNTIMES": "1.1.0"},
+		},
+		{
+			name:    "poetry",
+			files:   map[string]string{"pyproject.toml": "[tool.poetry]\nname = \"demo\"\n\n[tool.poetry.dependencies]\npython = \"^3.11\"\n", "poetry.lock": ""},
+			lang:    Python,
+			runtime: map[string]string{"PACKAGE_TOOL": "poetry", "RUNTIMES": "python-3.11"},
+		},
+		{
+			name:    "uv",
+			files:   map[string]string{"pyproject.toml": "[project]\nrequires-python = \">=3.12\"\n", "uv.lock": ""},
+			lang:    Python,
+			runtime: map[string]string{"PACKAGE_TOOL": "uv", "RUNTIMES": "python-3.12"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := writeFiles(t, tc.files)
+			lang, err := GetLangType(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lang != tc.lang {
+				t.Fatalf("expected lang %s, got %s", tc.lang, lang)
+			}
+			runtime, err := CheckRuntime(dir, lang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.runtime {
+				if runtime[k] != v {
+					t.Fatalf("expected %s %s, got %s", k, v, runtime[k])
+				}
+			}
+		})
+	}
+}
+
+func TestPyprojectOnlyToolConfig(t *testing.T) {
+	// pyproject.toml only holding the config of a python tool is not a python project
+	dir := writeFiles(t, map[string]string{"pyproject.toml": "[tool.black]\n", "package.json": "{}"})
+	if lang, _ := GetLangType(dir); lang != Nodejs {
+		t.Fatalf("expected %s, got %s", Nodejs, lang)
+	}
+}
@@ -0,0 +1,194 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package multi
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/chaos/parser/code"
+	"github.com/wutong-paas/wutong/chaos/parser/types"
+	"github.com/wutong-paas/wutong/util"
+)
+
+// gradle is an implementation of ServiceInterface for gradle multi-project builds.
+type gradle struct {
+}
+
+// NewGradle creates a new ServiceInterface for gradle
+func NewGradle() ServiceInterface {
+	return &gradle{}
+}
+
+var (
+	// include 'a', ':b:c' or include("a", ":b")
+	gradleIncludeRegexp = regexp.MustCompile(`(?m)^\s*include\s*\(?([^\n)]*)\)?`)
+	gradleQuotedRegexp  = regexp.MustCompile(`["']([^"']+)["']`)
+	// project(':a').projectDir = file('modules/a') or new File(settingsDir, 'modules/a')
+	gradleProjectDirRegexp = regexp.MustCompile(`project\(\s*["']:?([^"']+)["']\s*\)\.projectDir\s*=\s*(?:file\(|new\s+File\(\s*(?:rootDir|settingsDir)\s*,\s*|File\(\s*(?:rootDir|settingsDir)\s*,\s*)\s*["']([^"']+)["']`)
+	gradlePluginRegexp     = regexp.MustCompile(`(?:id\s*\(?\s*["']([^"']+)["']|apply\s+plugin\s*:\s*["']([^"']+)["']|^\s*(war|application|java)\s*$|` + "`(java-library|war|application)`" + `)`)
+)
+
+// gradleProject represents a gradle subproject
+type gradleProject struct {
+	// Path the gradle path without the leading colon, e.g. services:api
+	Path string
+	// Dir the directory relative to the root project
+	Dir     string
+	plugins map[string]bool
+}
+
+// ListModules lists the runnable subprojects from settings.gradle or settings.gradle.kts
+func (g *gradle) ListModules(homepath string) ([]*types.Service, error) {
+	projects, err := listGradleProjects(homepath)
+	if err != nil {
+		return nil, err
+	}
+	var res []*types.Service
+	for _, project := range projects {
+		packaging, task, procfile := project.describe()
+		if packaging == "" {
+			logrus.Debugf("gradle project %s is not runnable, skip it", project.Path)
+			continue
+		}
+		mo := &types.Service{
+			ID:   util.NewUUID(),
+			Name: project.Dir,
+			Cname: func(name string) string {
+				names := strings.Split(name, ":")
+				return names[len(names)-1]
+			}(project.Path),
+			Packaging: packaging,
+			Envs:      make(map[string]*types.Env),
+		}
+		envs := []*types.Env{
+			{Name: "BUILD_" + code.GradleTaskEnv, Value: fmt.Sprintf(":%s:%s -x test", project.Path, task)},
+			// the buildpacks look for the artifact in the module
+			{Name: "BUILD_BP_GRADLE_BUILT_MODULE", Value: project.Dir},
+			{Name: "BUILD_PROCFILE", Value: procfile},
+		}
+		for _, env := range envs {
+			mo.Envs[env.Name] = env
+		}
+		res = append(res, mo)
+	}
+	return res, nil
+}
+
+func listGradleProjects(homepath string) ([]*gradleProject, error) {
+	var body []byte
+	var err error
+	for _, name := range []string{"settings.gradle", "settings.gradle.kts"} {
+		body, err = os.ReadFile(path.Join(homepath, name))
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		// a single project build does not need the settings
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	settings := stripGradleComments(string(body))
+	dirs := make(map[string]string)
+	for _, match := range gradleProjectDirRegexp.FindAllStringSubmatch(settings, -1) {
+		dirs[strings.TrimPrefix(match[1], ":")] = path.Clean(match[2])
+	}
+	var projects []*gradleProject
+	seen := make(map[string]bool)
+	for _, include := range gradleIncludeRegexp.FindAllStringSubmatch(settings, -1) {
+		for _, quoted := range gradleQuotedRegexp.FindAllStringSubmatch(include[1], -1) {
+			projectPath := strings.TrimPrefix(quoted[1], ":")
+			if projectPath == "" || seen[projectPath] {
+				continue
+			}
+			seen[projectPath] = true
+			dir, ok := dirs[projectPath]
+			if !ok {
+				dir = strings.ReplaceAll(projectPath, ":", "/")
+			}
+			project := &gradleProject{Path: projectPath, Dir: dir}
+			project.plugins = readGradlePlugins(path.Join(homepath, dir))
+			projects = append(projects, project)
+		}
+	}
+	return projects, nil
+}
+
+// stripGradleComments removes the line comments, so the commented includes are ignored
+func stripGradleComments(settings string) string {
+	lines := strings.Split(settings, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readGradlePlugins reads the plugins applied in build.gradle or build.gradle.kts
+func readGradlePlugins(dir string) map[string]bool {
+	plugins := make(map[string]bool)
+	for _, name := range []string{"build.gradle", "build.gradle.kts"} {
+		body, err := os.ReadFile(path.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		content := stripGradleComments(string(body))
+		for _, line := range strings.Split(content, "\n") {
+			for _, match := range gradlePluginRegexp.FindAllStringSubmatch(line, -1) {
+				for _, plugin := range match[1:] {
+					if plugin != "" {
+						plugins[plugin] = true
+					}
+				}
+			}
+		}
+		if strings.Contains(content, "org.springframework.boot") {
+			plugins["org.springframework.boot"] = true
+		}
+	}
+	return plugins
+}
+
+// describe returns the packaging, the gradle task to build and the Procfile of the
+// project. The packaging is empty if the project is a library.
+func (p *gradleProject) describe() (packaging, task, procfile string) {
+	libs := path.Join(p.Dir, "build", "libs")
+	switch {
+	case p.plugins["org.springframework.boot"] && p.plugins["war"]:
+		// executable war of spring boot
+		return "war", "bootWar", fmt.Sprintf("web: java $JAVA_OPTS -jar %s/*.war", libs)
+	case p.plugins["org.springframework.boot"]:
+		return "jar", "bootJar", fmt.Sprintf("web: java $JAVA_OPTS -jar %s/*.jar", libs)
+	case p.plugins["war"]:
+		return "war", "war", fmt.Sprintf("web: java $JAVA_OPTS -jar /opt/webapp-runner.jar --port $PORT %s/*.war", libs)
+	case p.plugins["application"]:
+		names := strings.Split(p.Path, ":")
+		name := names[len(names)-1]
+		return "jar", "installDist", fmt.Sprintf("web: %s/build/install/%s/bin/%s", p.Dir, name, name)
+	}
+	return "", "", ""
+}
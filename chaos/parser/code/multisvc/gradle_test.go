@@ -0,0 +1,117 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package multi
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func writeGradleFile(t *testing.T, dir, name, content string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGradle_ListModules(t *testing.T) {
+	home := t.TempDir()
+	writeGradleFile(t, home, "gradlew", "")
+	writeGradleFile(t, home, "settings.gradle", `rootProject.name = 'demo'
+include 'api', 'legacy'
+include ':services:worker'
+// include 'disabled'
+include 'common'
+project(':legacy').projectDir = file('apps/legacy-web')
+`)
+	writeGradleFile(t, path.Join(home, "api"), "build.gradle", `plugins {
+    id 'org.springframework.boot' version '3.1.0'
+    id 'java'
+}`)
+	writeGradleFile(t, path.Join(home, "apps/legacy-web"), "build.gradle", `apply plugin: 'war'`)
+	writeGradleFile(t, path.Join(home, "services/worker"), "build.gradle.kts", "plugins {\n    application\n}")
+	writeGradleFile(t, path.Join(home, "common"), "build.gradle", "plugins {\n    id 'java-library'\n}")
+
+	services, err := NewGradle().ListModules(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 3 {
+		t.Fatalf("expected 3 services, but returned %d", len(services))
+	}
+	tests := []struct {
+		name, cname, packaging, task, procfile string
+	}{
+		{"api", "api", "jar", ":api:bootJar -x test", "web: java $JAVA_OPTS -jar api/build/libs/*.jar"},
+		{"apps/legacy-web", "legacy", "war", ":legacy:war -x test", "web: java $JAVA_OPTS -jar /opt/webapp-runner.jar --port $PORT apps/legacy-web/build/libs/*.war"},
+		{"services/worker", "worker", "jar", ":services:worker:installDist -x test", "web: services/worker/build/install/worker/bin/worker"},
+	}
+	for i, tc := range tests {
+		svc := services[i]
+		if svc.Name != tc.name || svc.Cname != tc.cname || svc.Packaging != tc.packaging {
+			t.Errorf("expected %s(%s, %s), but returned %s(%s, %s)", tc.name, tc.cname, tc.packaging, svc.Name, svc.Cname, svc.Packaging)
+		}
+		if task := svc.Envs["BUILD_GRADLE_TASK"].Value; task != tc.task {
+			t.Errorf("expected task %q, but returned %q", tc.task, task)
+		}
+		if _, ok := svc.Envs["BUILD_BUILD_CMD"]; ok {
+			t.Errorf("expected the task is built by GRADLE_TASK only")
+		}
+		if module := svc.Envs["BUILD_BP_GRADLE_BUILT_MODULE"].Value; module != tc.name {
+			t.Errorf("expected built module %q, but returned %q", tc.name, module)
+		}
+		if procfile := svc.Envs["BUILD_PROCFILE"].Value; procfile != tc.procfile {
+			t.Errorf("expected procfile %q, but returned %q", tc.procfile, procfile)
+		}
+	}
+}
+
+func TestGradle_ListModulesKts(t *testing.T) {
+	home := t.TempDir()
+	writeGradleFile(t, home, "settings.gradle.kts", `include("web")`)
+	writeGradleFile(t, path.Join(home, "web"), "build.gradle.kts", `plugins {
+    id("org.springframework.boot") version "3.1.0"
+    war
+}`)
+	services, err := NewGradle().ListModules(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("expected 1 service, but returned %d", len(services))
+	}
+	if services[0].Packaging != "war" || services[0].Envs["BUILD_GRADLE_TASK"].Value != ":web:bootWar -x test" {
+		t.Errorf("expected executable war of spring boot, but returned %s %s", services[0].Packaging, services[0].Envs["BUILD_GRADLE_TASK"].Value)
+	}
+}
+
+func TestGradle_ListModulesSingleProject(t *testing.T) {
+	home := t.TempDir()
+	writeGradleFile(t, home, "build.gradle", "apply plugin: 'application'")
+	services, err := NewGradle().ListModules(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 0 {
+		t.Errorf("expected no service for the single project, but returned %d", len(services))
+	}
+}
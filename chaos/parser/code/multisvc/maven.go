@@ -81,7 +81,7 @@ type module struct {
 
 // ListModules lists all maven modules from pom.xml
 func (m *maven) ListModules(path string) ([]*types.Service, error) {
-	modules, err := listModules(path, strings.TrimRight(path, "/")+"/", "")
+	modules, err := listModules(path, strings.TrimRight(path, "/")+"/", "", false)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +119,9 @@ func (m *maven) ListModules(path string) ([]*types.Service, error) {
 	return res, nil
 }
 
-func listModules(prefix, topPref, finalName string) ([]*module, error) {
+// springBoot is true if a parent applies the spring-boot-maven-plugin,
+// the plugins of the parent are inherited by the modules
+func listModules(prefix, topPref, finalName string, springBoot bool) ([]*module, error) {
 	pomPath := path.Join(prefix, "pom.xml")
 	pom, err := parsePom(pomPath)
 	if err != nil {
@@ -129,6 +131,7 @@ func listModules(prefix, topPref, finalName string) ([]*module, error) {
 	if pom.Build != nil && pom.Build.FinalName != "" {
 		finalName = pom.Build.FinalName
 	}
+	springBoot = springBoot || pom.isSpringBoot()
 
 	var modules []*module // module names
 	// recursive end condition
@@ -148,7 +151,8 @@ func listModules(prefix, topPref, finalName string) ([]*module, error) {
 			MavenCustomOpts:  "-DskipTests",
 			MavenCustomGoals: fmt.Sprintf("clean dependency:list install -pl %s -am", name),
 			Procfile: func() string {
-				if pom.Packaging == "war" {
+				// the war repackaged by spring boot is executable
+				if pom.Packaging == "war" && !springBoot {
 					return fmt.Sprintf("web: java $JAVA_OPTS -jar /opt/webapp-runner.jar "+
 						"--port $PORT %s/target/%s", name, filename)
 				}
@@ -160,7 +164,7 @@ func listModules(prefix, topPref, finalName string) ([]*module, error) {
 
 	for _, name := range pom.Modules {
 		// submodule names
-		submodules, err := listModules(path.Join(prefix, name), topPref, finalName)
+		submodules, err := listModules(path.Join(prefix, name), topPref, finalName, springBoot)
 		if err != nil {
 			logrus.Warningf("Prefix: %s; error getting module names: %v",
 				path.Join(prefix, name), err)
@@ -226,6 +230,20 @@ func (p *pom) getExecuteFilename(finalName string) string {
 	return name + "." + suffix()
 }
 
+// isSpringBoot returns true if the spring-boot-maven-plugin repackages the module
+// into a fat jar or an executable war
+func (p *pom) isSpringBoot() bool {
+	if p.Build == nil || p.Build.Plugins == nil {
+		return false
+	}
+	for _, plugin := range p.Build.Plugins.Plugin {
+		if plugin.ArtifactID == "spring-boot-maven-plugin" && (plugin.GroupID == "" || plugin.GroupID == "org.springframework.boot") {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *pom) isValidModule() bool {
 	if p.Packaging != "jar" && p.Packaging != "war" && p.Packaging != "" {
 		return false
@@ -20,6 +20,7 @@ package multi
 
 import (
 	"os"
+	"path"
 	"testing"
 )
 
@@ -64,3 +65,36 @@ func TestMaven_ListModules(t *testing.T) {
 	}
 	t.Error("test")
 }
+
+func TestMaven_ListModulesSpringBootWar(t *testing.T) {
+	home := t.TempDir()
+	poms := map[string]string{
+		"pom.xml": `<project><artifactId>parent</artifactId><packaging>pom</packaging>
+<modules><module>boot-web</module><module>legacy-web</module></modules></project>`,
+		"boot-web/pom.xml": `<project><artifactId>boot-web</artifactId><packaging>war</packaging>
+<build><plugins><plugin><groupId>org.springframework.boot</groupId><artifactId>spring-boot-maven-plugin</artifactId></plugin></plugins></build></project>`,
+		"legacy-web/pom.xml": `<project><artifactId>legacy-web</artifactId><packaging>war</packaging></project>`,
+	}
+	for name, content := range poms {
+		if err := os.MkdirAll(path.Dir(path.Join(home, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path.Join(home, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err := NewMaven().ListModules(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	procfiles := make(map[string]string)
+	for _, svc := range res {
+		procfiles[svc.Name] = svc.Envs["BUILD_PROCFILE"].Value
+	}
+	if want := "web: java $JAVA_OPTS -jar boot-web/target/boot-web-*.war"; procfiles["boot-web"] != want {
+		t.Errorf("expected %q, but returned %q", want, procfiles["boot-web"])
+	}
+	if want := "web: java $JAVA_OPTS -jar /opt/webapp-runner.jar --port $PORT legacy-web/target/legacy-web-*.war"; procfiles["legacy-web"] != want {
+		t.Errorf("expected %q, but returned %q", want, procfiles["legacy-web"])
+	}
+}
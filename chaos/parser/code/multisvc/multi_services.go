@@ -33,6 +33,8 @@ func NewMultiServiceI(lang string) ServiceInterface {
 	switch lang {
 	case "Java-maven":
 		return NewMaven()
+	case "Gradle":
+		return NewGradle()
 	}
 	return nil
 }
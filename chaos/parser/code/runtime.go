@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 
 	simplejson "github.com/bitly/go-simplejson"
@@ -52,6 +53,14 @@ func CheckRuntime(buildPath string, lang Lang) (map[string]string, error) {
 		return runtime, nil
 	case Static:
 		return map[string]string{"RUNTIMES_SERVER": "nginx"}, nil
+	case Rust:
+		return readRustRuntimeInfo(buildPath)
+	case Elixir:
+		return readElixirRuntimeInfo(buildPath)
+	case Deno:
+		return readDenoRuntimeInfo(buildPath)
+	case Bun:
+		return readBunRuntimeInfo(buildPath)
 	default:
 		return nil, nil
 	}
@@ -122,16 +131,146 @@ func readPHPRuntimeInfo(buildPath string) (map[string]string, error) {
 	return phpRuntimeInfo, nil
 }
 
+// versionRegexp matches the version in a constraint like ">=3.11", "^1.2" or "~> 1.15"
+var versionRegexp = regexp.MustCompile(`[0-9]+(\.[0-9]+){0,2}`)
+
+// findVersion returns the first version of the value of the key in the file, the
+// key is matched at the start of a line, e.g. `rust-version = "1.70"` in Cargo.toml
+func findVersion(file, key string) string {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	re := regexp.MustCompile(`(?m)^\s*"?` + regexp.QuoteMeta(key) + `"?\s*[=:]\s*"([^"\n]*)"`)
+	match := re.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	return versionRegexp.FindString(string(match[1]))
+}
+
+// readVersionFile returns the version in a file like .python-version or .bun-version
+func readVersionFile(file string) string {
+	body, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return versionRegexp.FindString(string(body))
+}
+
+// readToolVersions returns the versions of the .tool-versions file of asdf
+func readToolVersions(buildPath string) map[string]string {
+	versions := make(map[string]string)
+	body, err := os.ReadFile(path.Join(buildPath, ".tool-versions"))
+	if err != nil {
+		return versions
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if version := versionRegexp.FindString(fields[1]); version != "" {
+			versions[fields[0]] = version
+		}
+	}
+	return versions
+}
+
 func readPythonRuntimeInfo(buildPath string) (map[string]string, error) {
 	var runtimeInfo = make(map[string]string, 1)
-	if ok, _ := util.FileExists(path.Join(buildPath, "runtime.txt")); !ok {
-		return runtimeInfo, nil
+	if tool := pythonPackageTool(buildPath); tool != "" {
+		runtimeInfo["PACKAGE_TOOL"] = tool
 	}
-	body, err := os.ReadFile(path.Join(buildPath, "runtime.txt"))
-	if err != nil {
+	if ok, _ := util.FileExists(path.Join(buildPath, "runtime.txt")); ok {
+		body, err := os.ReadFile(path.Join(buildPath, "runtime.txt"))
+		if err != nil {
+			return runtimeInfo, nil
+		}
+		runtimeInfo["RUNTIMES"] = string(body)
 		return runtimeInfo, nil
 	}
-	runtimeInfo["RUNTIMES"] = string(body)
+	version := readVersionFile(path.Join(buildPath, ".python-version"))
+	if version == "" {
+		version = findVersion(path.Join(buildPath, "pyproject.toml"), "requires-python")
+	}
+	if version == "" && runtimeInfo["PACKAGE_TOOL"] == "poetry" {
+		version = findVersion(path.Join(buildPath, "pyproject.toml"), "python")
+	}
+	if version != "" {
+		runtimeInfo["RUNTIMES"] = "python-" + version
+	}
+	return runtimeInfo, nil
+}
+
+func readRustRuntimeInfo(buildPath string) (map[string]string, error) {
+	var runtimeInfo = make(map[string]string, 1)
+	version := findVersion(path.Join(buildPath, "rust-toolchain.toml"), "channel")
+	if version == "" {
+		version = readVersionFile(path.Join(buildPath, "rust-toolchain"))
+	}
+	if version == "" {
+		version = findVersion(path.Join(buildPath, "Cargo.toml"), "rust-version")
+	}
+	if version != "" {
+		runtimeInfo["RUNTIMES"] = version
+	}
+	return runtimeInfo, nil
+}
+
+func readElixirRuntimeInfo(buildPath string) (map[string]string, error) {
+	var runtimeInfo = make(map[string]string, 1)
+	versions := readToolVersions(buildPath)
+	version := versions["elixir"]
+	if version == "" {
+		// mix.exs: elixir: "~> 1.15"
+		version = findVersion(path.Join(buildPath, "mix.exs"), "elixir")
+	}
+	if version != "" {
+		runtimeInfo["RUNTIMES"] = version
+	}
+	if erlang := versions["erlang"]; erlang != "" {
+		runtimeInfo["RUNTIMES_ERLANG"] = erlang
+	}
+	return runtimeInfo, nil
+}
+
+func readDenoRuntimeInfo(buildPath string) (map[string]string, error) {
+	var runtimeInfo = make(map[string]string, 1)
+	version := readVersionFile(path.Join(buildPath, ".dvmrc"))
+	if version == "" {
+		version = readToolVersions(buildPath)["deno"]
+	}
+	if version != "" {
+		runtimeInfo["RUNTIMES"] = version
+	}
+	return runtimeInfo, nil
+}
+
+func readBunRuntimeInfo(buildPath string) (map[string]string, error) {
+	var runtimeInfo = make(map[string]string, 1)
+	version := readVersionFile(path.Join(buildPath, ".bun-version"))
+	if version == "" {
+		version = readToolVersions(buildPath)["bun"]
+	}
+	if version == "" {
+		// package.json: "packageManager": "bun@1.1.0" or "engines": {"bun": ">=1.0"}
+		body, err := os.ReadFile(path.Join(buildPath, "package.json"))
+		if err == nil {
+			if json, err := simplejson.NewJson(body); err == nil {
+				if pm, _ := json.Get("packageManager").String(); strings.HasPrefix(pm, "bun@") {
+					version = versionRegexp.FindString(pm)
+				}
+				if version == "" {
+					engine, _ := json.Get("engines").Get("bun").String()
+					version = versionRegexp.FindString(engine)
+				}
+			}
+		}
+	}
+	if version != "" {
+		runtimeInfo["RUNTIMES"] = version
+	}
 	return runtimeInfo, nil
 }
 
@@ -47,6 +47,10 @@ func init() {
 	specification[NodeJSStatic] = nodeCheck
 	specification[Nodejs] = nodeCheck
 	specification[Golang] = golangCheck
+	specification[Python] = pythonCheck
+	specification[Rust] = rustCheck
+	specification[Elixir] = elixirCheck
+	specification[Bun] = bunCheck
 }
 
 // CheckCodeSpecification 检查语言规范
@@ -173,3 +177,58 @@ func nodeCheck(buildPath string) Specification {
 func golangCheck(buildPath string) Specification {
 	return common()
 }
+
+// poetry 项目必须提交 poetry.lock 文件
+func pythonCheck(buildPath string) Specification {
+	if pythonPackageTool(buildPath) != "poetry" {
+		return common()
+	}
+	if ok, _ := util.FileExists(path.Join(buildPath, "poetry.lock")); !ok {
+		return Specification{
+			Conform:   false,
+			Noconform: map[string]string{"识别为Python语言（Poetry），代码目录未发现poetry.lock文件": "必须生成并提交poetry.lock文件"},
+		}
+	}
+	return common()
+}
+
+// 必须定义可执行程序入口
+// 建议提交Cargo.lock文件
+func rustCheck(buildPath string) Specification {
+	main, _ := util.FileExists(path.Join(buildPath, "src", "main.rs"))
+	if !main && !util.SearchFileBody(path.Join(buildPath, "Cargo.toml"), "[[bin]]") {
+		return Specification{
+			Conform:   false,
+			Noconform: map[string]string{"识别为Rust语言，未发现可执行程序入口": "定义src/main.rs文件或在Cargo.toml中定义[[bin]]"},
+		}
+	}
+	if ok, _ := util.FileExists(path.Join(buildPath, "Cargo.lock")); !ok {
+		return Specification{
+			Conform: true,
+			Advice:  map[string]string{"代码目录未发现Cargo.lock文件": "建议生成并提交Cargo.lock文件，保证依赖版本一致"},
+		}
+	}
+	return common()
+}
+
+func elixirCheck(buildPath string) Specification {
+	if ok, _ := util.FileExists(path.Join(buildPath, "mix.lock")); !ok {
+		return Specification{
+			Conform: true,
+			Advice:  map[string]string{"代码目录未发现mix.lock文件": "建议生成并提交mix.lock文件，保证依赖版本一致"},
+		}
+	}
+	return common()
+}
+
+func bunCheck(buildPath string) Specification {
+	for _, name := range []string{"bun.lockb", "bun.lock"} {
+		if ok, _ := util.FileExists(path.Join(buildPath, name)); ok {
+			return common()
+		}
+	}
+	return Specification{
+		Conform:   false,
+		Noconform: map[string]string{"识别为Bun项目，代码目录未发现bun.lockb或bun.lock文件": "必须生成并提交bun.lockb或bun.lock文件"},
+	}
+}
@@ -43,6 +43,43 @@ type Service struct {
 	Name  string            `yaml:"name"`
 	Ports []Port            `yaml:"ports"`
 	Envs  map[string]string `yaml:"envs"`
+	// BuildPath the subdirectory of the service in a monorepo, relative to the wutongfile
+	BuildPath string `yaml:"buildpath"`
+	// Language detected in the BuildPath if not defined
+	Language string `yaml:"language"`
+	// BuildCmd the build command run by the buildpacks of the language, see BuildCmdEnvs
+	BuildCmd string `yaml:"buildcmd"`
+	Cmd      string `yaml:"cmd"`
+	// Depends the names of the sibling services
+	Depends []string `yaml:"depends"`
+	Probe   *Probe   `yaml:"probe"`
+}
+
+// Probe health probe of the service
+type Probe struct {
+	// Mode liveness or readiness, default readiness
+	Mode string `yaml:"mode"`
+	// Scheme tcp, http or cmd, default tcp
+	Scheme             string `yaml:"scheme"`
+	Port               int    `yaml:"port"`
+	Path               string `yaml:"path"`
+	Cmd                string `yaml:"cmd"`
+	InitialDelaySecond int    `yaml:"initial_delay_second"`
+	PeriodSecond       int    `yaml:"period_second"`
+	TimeoutSecond      int    `yaml:"timeout_second"`
+}
+
+// IsMonorepo returns true if the services are built from their own subdirectory
+func (w *WutongFileConfig) IsMonorepo() bool {
+	if w == nil {
+		return false
+	}
+	for _, svc := range w.Services {
+		if svc.BuildPath != "" {
+			return true
+		}
+	}
+	return false
 }
 
 // Port Port
@@ -188,6 +188,9 @@ type ServiceInfo struct {
 	Name      string `json:"name,omitempty"`  // module name
 	Cname     string `json:"cname,omitempty"` // service cname
 	Packaging string `json:"packaging,omitempty"`
+	// BuildPath the subdirectory of the service in a monorepo
+	BuildPath string       `json:"build_path,omitempty"`
+	Probe     *types.Probe `json:"probe,omitempty"`
 }
 
 // GetServiceInfo GetServiceInfo
@@ -277,6 +277,10 @@ func (d *SourceCodeParse) Parse() ParseErrorList {
 	}
 	//判断对象目录
 	var buildPath = buildInfo.GetCodeBuildAbsPath()
+	//monorepo, every service is built from its own subdirectory
+	if wtfileConfig.IsMonorepo() {
+		return d.parseMonorepo(buildPath, wtfileConfig)
+	}
 	//解析代码类型
 	var lang code.Lang
 	if wtfileConfig != nil && wtfileConfig.Language != "" {
@@ -544,6 +548,13 @@ func (d *SourceCodeParse) GetServiceInfo() []ServiceInfo {
 			info.Name = svc.Name
 			info.Cname = svc.Cname
 			info.Packaging = svc.Packaging
+			info.BuildPath = svc.BuildPath
+			info.DependServices = svc.Depends
+			info.Probe = svc.Probe
+			if svc.Language != "" {
+				info.Lang = code.Lang(svc.Language)
+				info.Memory = getRecommendedMemory(info.Lang)
+			}
 			for i := range svc.Envs {
 				info.Envs = append(info.Envs, *svc.Envs[i])
 			}
@@ -0,0 +1,187 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/wutong-paas/wutong/chaos/parser/code"
+	"github.com/wutong-paas/wutong/chaos/parser/types"
+	"github.com/wutong-paas/wutong/util"
+)
+
+// parseMonorepo emits one service for each service of the wutongfile, every
+// service is parsed in its own build path with its own language
+func (d *SourceCodeParse) parseMonorepo(buildPath string, wtfileConfig *code.WutongFileConfig) ParseErrorList {
+	names := make(map[string]struct{}, len(wtfileConfig.Services))
+	for _, svc := range wtfileConfig.Services {
+		if svc.Name == "" {
+			d.errappend(ErrorAndSolve(FatalError, "wutongfile 中的服务未定义名称", "请为 services 中的每个服务定义 name"))
+			return d.errors
+		}
+		if _, ok := names[svc.Name]; ok {
+			d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("wutongfile 中的服务 %s 重复定义", svc.Name), "请保证 services 中的服务名称唯一"))
+			return d.errors
+		}
+		names[svc.Name] = struct{}{}
+	}
+	var services []*types.Service
+	for _, svc := range wtfileConfig.Services {
+		service, ok := d.parseMonorepoService(buildPath, wtfileConfig, svc, names)
+		if !ok {
+			return d.errors
+		}
+		services = append(services, service)
+	}
+	d.isMulti = true
+	d.services = services
+	d.Lang = code.Lang(services[0].Language)
+	d.memory = getRecommendedMemory(d.Lang)
+	return d.errors
+}
+
+func (d *SourceCodeParse) parseMonorepoService(buildPath string, wtfileConfig *code.WutongFileConfig, svc *code.Service, names map[string]struct{}) (*types.Service, bool) {
+	buildPath = path.Join(buildPath, svc.BuildPath)
+	// the build path can not go out of the repository
+	if svc.BuildPath != "" && (path.IsAbs(svc.BuildPath) || strings.HasPrefix(path.Clean(svc.BuildPath), "..")) {
+		d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("服务 %s 的构建目录 %s 不合法", svc.Name, svc.BuildPath), "构建目录必须为仓库内的相对路径"))
+		return nil, false
+	}
+	if ok, _ := util.FileExists(buildPath); !ok {
+		d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("服务 %s 的构建目录 %s 不存在", svc.Name, svc.BuildPath), "请确认 wutongfile 中服务的 buildpath 配置"))
+		return nil, false
+	}
+	lang := code.Lang(svc.Language)
+	if lang == "" {
+		var err error
+		lang, err = code.GetLangType(buildPath)
+		if err != nil || lang == code.NO {
+			d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("服务 %s 的代码无法识别语言类型", svc.Name), "请在 wutongfile 中为服务定义 language，或参考文档查看平台语言支持规范"))
+			return nil, false
+		}
+	}
+	spec := code.CheckCodeSpecification(buildPath, lang, "")
+	for k, v := range spec.Advice {
+		d.errappend(ErrorAndSolve(NegligibleError, fmt.Sprintf("%s: %s", svc.Name, k), v))
+	}
+	for k, v := range spec.Noconform {
+		d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("%s: %s", svc.Name, k), v))
+	}
+	if !spec.Conform {
+		return nil, false
+	}
+	for _, depend := range svc.Depends {
+		if _, ok := names[depend]; !ok || depend == svc.Name {
+			d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("服务 %s 依赖的服务 %s 不存在", svc.Name, depend), "depends 只能引用 wutongfile 中的其他服务"))
+			return nil, false
+		}
+	}
+
+	service := &types.Service{
+		ID:        util.NewUUID(),
+		Name:      svc.Name,
+		Cname:     svc.Name,
+		BuildPath: path.Clean(svc.BuildPath),
+		Language:  lang.String(),
+		Depends:   svc.Depends,
+		Envs:      make(map[string]*types.Env),
+		Ports:     make(map[int]*types.Port),
+	}
+	runtimeInfo, err := code.CheckRuntime(buildPath, lang)
+	if err != nil && err == code.ErrRuntimeNotSupport {
+		d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("服务 %s 选择的运行时版本不支持", svc.Name), "请参考文档查看平台各语言支持的 Runtime 版本"))
+		return nil, false
+	}
+	for k, v := range runtimeInfo {
+		service.Envs["BUILD_"+k] = &types.Env{Name: "BUILD_" + k, Value: v}
+	}
+	// the build command is run by the buildpacks of the language
+	if svc.BuildCmd != "" {
+		if _, err := code.BuildCmdEnvs(lang, svc.BuildCmd); err != nil {
+			d.errappend(ErrorAndSolve(FatalError, fmt.Sprintf("服务 %s 的 buildcmd 不受支持: %s", svc.Name, err.Error()), "buildcmd 只能为语言构建工具的命令，如 npm run build、go build ./cmd/api、mvn package，或使用 Dockerfile 构建"))
+			return nil, false
+		}
+		service.Envs["BUILD_"+code.BuildCmdEnv] = &types.Env{Name: "BUILD_" + code.BuildCmdEnv, Value: svc.BuildCmd}
+	}
+	procfileLine := ""
+	if svc.Cmd != "" {
+		procfileLine = "web: " + svc.Cmd
+	} else {
+		_, procfileLine = code.CheckProcfile(buildPath, lang)
+	}
+	if procfileLine != "" {
+		service.Envs["BUILD_PROCFILE"] = &types.Env{Name: "BUILD_PROCFILE", Value: procfileLine}
+	}
+	for k, v := range svc.Envs {
+		service.Envs[k] = &types.Env{Name: k, Value: v}
+	}
+	// the envs and ports of the wutongfile are shared by all services
+	for k, v := range wtfileConfig.Envs {
+		if service.Envs[k] == nil {
+			service.Envs[k] = &types.Env{Name: k, Value: fmt.Sprintf("%v", v)}
+		}
+	}
+	ports := append(append([]code.Port{}, svc.Ports...), wtfileConfig.Ports...)
+	for _, port := range ports {
+		if port.Port == 0 || service.Ports[port.Port] != nil {
+			continue
+		}
+		if port.Protocol == "" {
+			port.Protocol = GetPortProtocol(port.Port)
+		}
+		service.Ports[port.Port] = &types.Port{ContainerPort: port.Port, Protocol: port.Protocol}
+	}
+	if svc.Probe != nil {
+		service.Probe = monorepoProbe(svc.Probe, ports)
+	}
+	return service, true
+}
+
+// monorepoProbe fills the defaults of the probe, the first port is probed if
+// the port is not defined
+func monorepoProbe(p *code.Probe, ports []code.Port) *types.Probe {
+	probe := &types.Probe{
+		Mode:               p.Mode,
+		Scheme:             p.Scheme,
+		Port:               p.Port,
+		Path:               p.Path,
+		Cmd:                p.Cmd,
+		InitialDelaySecond: p.InitialDelaySecond,
+		PeriodSecond:       p.PeriodSecond,
+		TimeoutSecond:      p.TimeoutSecond,
+	}
+	if probe.Mode == "" {
+		probe.Mode = "readiness"
+	}
+	if probe.Scheme == "" {
+		probe.Scheme = "tcp"
+		if probe.Path != "" {
+			probe.Scheme = "http"
+		}
+	}
+	if probe.Port == 0 && len(ports) > 0 {
+		probe.Port = ports[0].Port
+	}
+	if probe.Scheme == "http" && probe.Path == "" {
+		probe.Path = "/"
+	}
+	return probe
+}
@@ -0,0 +1,130 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/wutong-paas/wutong/chaos/parser/code"
+	"github.com/wutong-paas/wutong/chaos/parser/types"
+)
+
+const monorepoWutongfile = `
+envs:
+  TZ: Asia/Shanghai
+services:
+- name: api
+  buildpath: services/api
+  cmd: ./api --port 8080
+  depends: [worker]
+  ports:
+  - port: 8080
+    protocol: http
+  probe:
+    path: /healthz
+- name: worker
+  buildpath: services/worker
+  language: Go
+  envs:
+    TZ: UTC
+`
+
+func TestParseMonorepo(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"wutongfile":                  monorepoWutongfile,
+		"services/api/go.mod":         "module api\n",
+		"services/worker/placeholder": "",
+	}
+	for name, body := range files {
+		file := path.Join(dir, name)
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wtfile, err := code.ReadWutongFile(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !wtfile.IsMonorepo() {
+		t.Fatal("expected a monorepo wutongfile")
+	}
+	d := &SourceCodeParse{envs: make(map[string]*types.Env), ports: make(map[int]*types.Port)}
+	if errs := d.parseMonorepo(dir, wtfile); errs.IsFatalError() {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	infos := d.GetServiceInfo()
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(infos))
+	}
+	api, worker := infos[0], infos[1]
+	if api.BuildPath != "services/api" || api.Lang != code.Golang {
+		t.Fatalf("unexpected api service %+v", api)
+	}
+	if len(api.DependServices) != 1 || api.DependServices[0] != "worker" {
+		t.Fatalf("expected api depends on worker, got %v", api.DependServices)
+	}
+	if api.Probe == nil || api.Probe.Scheme != "http" || api.Probe.Port != 8080 {
+		t.Fatalf("unexpected api probe %+v", api.Probe)
+	}
+	envs := make(map[string]string)
+	for _, env := range api.Envs {
+		envs[env.Name] = env.Value
+	}
+	if envs["BUILD_PROCFILE"] != "web: ./api --port 8080" || envs["TZ"] != "Asia/Shanghai" {
+		t.Fatalf("unexpected api envs %v", envs)
+	}
+	for _, env := range worker.Envs {
+		if env.Name == "TZ" && env.Value != "UTC" {
+			t.Fatalf("expected the env of the service to win, got %s", env.Value)
+		}
+	}
+
+	// the build command is passed to the builder
+	d = &SourceCodeParse{}
+	wtfile.Services[0].BuildCmd = "go build -ldflags=-s ./cmd/api"
+	if errs := d.parseMonorepo(dir, wtfile); errs.IsFatalError() {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+	envs = make(map[string]string)
+	for _, env := range d.GetServiceInfo()[0].Envs {
+		envs[env.Name] = env.Value
+	}
+	if envs["BUILD_BUILD_CMD"] != "go build -ldflags=-s ./cmd/api" {
+		t.Fatalf("expected the build cmd in the build envs, got %v", envs)
+	}
+
+	d = &SourceCodeParse{}
+	wtfile.Services[0].BuildCmd = "make build && cp bin/api ."
+	if errs := d.parseMonorepo(dir, wtfile); !errs.IsFatalError() {
+		t.Fatal("expected a fatal error for the build cmd no buildpack runs")
+	}
+
+	d = &SourceCodeParse{}
+	wtfile.Services[0].BuildCmd = ""
+	wtfile.Services[1].Depends = []string{"db"}
+	if errs := d.parseMonorepo(dir, wtfile); !errs.IsFatalError() {
+		t.Fatal("expected a fatal error for an unknown dependency")
+	}
+}
@@ -26,6 +26,24 @@ type Service struct {
 	Packaging string          `json:"packaging"`
 	Envs      map[string]*Env `json:"envs,omitempty"`
 	Ports     map[int]*Port   `json:"ports,omitempty"`
+	// BuildPath the subdirectory of the service in a monorepo
+	BuildPath string `json:"build_path,omitempty"`
+	Language  string `json:"language,omitempty"`
+	// Depends the names of the sibling services it depends on
+	Depends []string `json:"depends,omitempty"`
+	Probe   *Probe   `json:"probe,omitempty"`
+}
+
+//Probe health probe of the service
+type Probe struct {
+	Mode               string `json:"mode"`
+	Scheme             string `json:"scheme"`
+	Port               int    `json:"port"`
+	Path               string `json:"path,omitempty"`
+	Cmd                string `json:"cmd,omitempty"`
+	InitialDelaySecond int    `json:"initial_delay_second,omitempty"`
+	PeriodSecond       int    `json:"period_second,omitempty"`
+	TimeoutSecond      int    `json:"timeout_second,omitempty"`
 }
 
 //Port -
@@ -50,6 +50,15 @@ func init() {
 
 	RUNNERIMAGENAME = fmt.Sprintf("%s:%s", path.Join(constants.WutongOnlineImageRepository, "runner"), "latest")
 	BUILDERIMAGENAME = fmt.Sprintf("%s:%s", path.Join(constants.WutongOnlineImageRepository, "builder"), "latest")
+	// the builder is pinned so that the builds are reproducible, upgrade it on purpose
+	BUILDPACKSBUILDERIMAGE = "paketobuildpacks/builder-jammy-base:0.4.301"
+	if os.Getenv("BUILDPACKS_BUILDER_IMAGE") != "" {
+		BUILDPACKSBUILDERIMAGE = os.Getenv("BUILDPACKS_BUILDER_IMAGE")
+	}
+	SBOMGENERATORIMAGE = "anchore/syft:v1.4.1"
+	if os.Getenv("SBOM_GENERATOR_IMAGE") != "" {
+		SBOMGENERATORIMAGE = os.Getenv("SBOM_GENERATOR_IMAGE")
+	}
 	PROBEMESHIMAGENAME = fmt.Sprintf("%s:%s", path.Join(constants.WutongOnlineImageRepository, "wt-init-probe"), CIVERSION)
 	TCPMESHIMAGENAME = fmt.Sprintf("%s:%s", path.Join(constants.WutongOnlineImageRepository, "wt-mesh-data-panel"), CIVERSION)
 	NODESHELLIMAGENAME = fmt.Sprintf("%s:%s", path.Join(constants.WutongOnlineImageRepository, "node-shell"), "stable")
@@ -91,6 +100,12 @@ var RUNNERIMAGENAME string
 // BUILDERIMAGENAME builder image name
 var BUILDERIMAGENAME string
 
+// BUILDPACKSBUILDERIMAGE cloud native buildpacks builder image name, overridden by the env BUILDPACKS_BUILDER_IMAGE
+var BUILDPACKSBUILDERIMAGE string
+
+// SBOMGENERATORIMAGE the syft image to generate the sbom of the build
+var SBOMGENERATORIMAGE string
+
 // PROBEMESHIMAGENAME probemesh image name
 var PROBEMESHIMAGENAME string
 
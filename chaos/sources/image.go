@@ -184,6 +184,10 @@ func ImageTag(containerdClient *containerd.Client, source, target string, logger
 // ImageNameHandle 解析imagename
 func ImageNameHandle(imageName string) *model.ImageName {
 	var i model.ImageName
+	// the image may be pinned to the digest after the tag
+	if index := strings.Index(imageName, "@"); index > 0 {
+		imageName = imageName[:index]
+	}
 	if strings.Contains(imageName, "/") {
 		mm := strings.Split(imageName, "/")
 		i.Host = mm[0]
@@ -51,27 +51,14 @@ func ImageExist(imageName, user, password string) (bool, error) {
 		logrus.Errorf("reference parse image name error: %s", err.Error())
 		return false, err
 	}
-	domain := reference.Domain(name)
-	if domain == "docker.io" {
-		domain = "registry-1.docker.io"
-	}
 	retry := 2
 	var rerr error
 	for retry > 0 {
 		retry--
-		reg, err := registry.New(domain, user, password)
+		reg, err := newRegistryClient(reference.Domain(name), user, password)
 		if err != nil {
-			logrus.Debugf("new registry client failure %s", err.Error())
-			reg, err = registry.NewInsecure(domain, user, password)
-			if err != nil {
-				logrus.Debugf("new insecure registry client failure %s", err.Error())
-				reg, err = registry.NewInsecure("http://"+domain, user, password)
-				if err != nil {
-					logrus.Errorf("new insecure registry http or https client all failure %s", err.Error())
-					rerr = err
-					continue
-				}
-			}
+			rerr = err
+			continue
 		}
 		tag := GetTagFromNamedRef(name)
 		if err := reg.CheckManifest(reference.Path(name), tag); err != nil {
@@ -82,3 +69,24 @@ func ImageExist(imageName, user, password string) (bool, error) {
 	}
 	return false, rerr
 }
+
+// newRegistryClient creates the registry client, the insecure registries are supported
+func newRegistryClient(domain, user, password string) (*registry.Registry, error) {
+	if domain == "docker.io" {
+		domain = "registry-1.docker.io"
+	}
+	reg, err := registry.New(domain, user, password)
+	if err != nil {
+		logrus.Debugf("new registry client failure %s", err.Error())
+		reg, err = registry.NewInsecure(domain, user, password)
+		if err != nil {
+			logrus.Debugf("new insecure registry client failure %s", err.Error())
+			reg, err = registry.NewInsecure("http://"+domain, user, password)
+			if err != nil {
+				logrus.Errorf("new insecure registry http or https client all failure %s", err.Error())
+				return nil, err
+			}
+		}
+	}
+	return reg, nil
+}
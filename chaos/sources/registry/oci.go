@@ -0,0 +1,117 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// the media types of the single and the multi platform images
+var manifestMediaTypes = []string{
+	ocispec.MediaTypeImageIndex,
+	ocispec.MediaTypeImageManifest,
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ImageDigest returns the digest of the manifest or the index of the image
+func (registry *Registry) ImageDigest(repository, reference string) (digest.Digest, error) {
+	body, dgst, err := registry.getManifest(repository, reference)
+	if err != nil {
+		return "", err
+	}
+	if dgst != "" {
+		return dgst, nil
+	}
+	return digest.FromBytes(body), nil
+}
+
+// OCIManifest returns the image manifest, the docker v2 manifest has the same layout
+func (registry *Registry) OCIManifest(repository, reference string) (*ocispec.Manifest, error) {
+	body, _, err := registry.getManifest(repository, reference)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %v", err)
+	}
+	return &manifest, nil
+}
+
+// Blob returns the content of the blob
+func (registry *Registry) Blob(repository string, dgst digest.Digest) ([]byte, error) {
+	url := registry.url("/v2/%s/blobs/%s", repository, dgst)
+	registry.Logf("registry.blob.get url=%s repository=%s digest=%s", url, repository, dgst)
+	resp, err := registry.Client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpect status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := dgst.Validate(); err == nil && dgst.Algorithm().FromBytes(body) != dgst {
+		return nil, fmt.Errorf("digest of blob %s mismatch", dgst)
+	}
+	return body, nil
+}
+
+func (registry *Registry) getManifest(repository, reference string) ([]byte, digest.Digest, error) {
+	url := registry.url("/v2/%s/manifests/%s", repository, reference)
+	registry.Logf("registry.manifest.get url=%s repository=%s reference=%s", url, repository, reference)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	resp, err := registry.Client.Do(req)
+	if err != nil {
+		var statusErr *HttpStatusError
+		if errors.As(err, &statusErr) && statusErr.Response.StatusCode == http.StatusNotFound {
+			return nil, "", errors.Wrap(ErrManifestNotFound, reference)
+		}
+		return nil, "", fmt.Errorf("do request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", errors.Wrap(ErrManifestNotFound, reference)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpect status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	dgst, _ := digest.Parse(resp.Header.Get("Docker-Content-Digest"))
+	return body, dgst, nil
+}
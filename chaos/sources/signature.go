@@ -0,0 +1,428 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sources
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/chaos/sources/registry"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+var (
+	// the oidc issuer extensions of the fulcio certificates
+	oidFulcioIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// ErrImageNotSigned the image has no cosign signature
+var ErrImageNotSigned = errors.New("the image has no cosign signature")
+
+// simpleSigning the payload signed by cosign
+type simpleSigning struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// rekorBundle the transparency log entry of the keyless signature attached by cosign
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+// rekorPayload the fields are in the canonical order, so the marshaled payload is the
+// content signed by rekor
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord the rekor entry of the signature
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// ImageSignatureVerifier verifies the cosign signatures of the images with the
+// public keys or the keyless identities of the policy. The keyless signatures must
+// be logged in rekor, the signing certificate is checked at the time it is logged.
+type ImageSignatureVerifier struct {
+	keys       []crypto.PublicKey
+	identities []dbmodel.KeylessIdentity
+	roots      *x509.CertPool
+	rekorKeys  []crypto.PublicKey
+}
+
+// NewImageSignatureVerifier creates the verifier of the policy
+func NewImageSignatureVerifier(policy *dbmodel.ImageSignaturePolicy) (*ImageSignatureVerifier, error) {
+	v := &ImageSignatureVerifier{}
+	keys, err := parsePublicKeys(policy.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	identities, err := policy.KeylessIdentities()
+	if err != nil {
+		return nil, fmt.Errorf("parse keyless identities: %v", err)
+	}
+	for _, identity := range identities {
+		if identity.Issuer == "" || identity.Subject == "" {
+			return nil, fmt.Errorf("the issuer and the subject are required by the keyless identity")
+		}
+	}
+	v.identities = identities
+	if len(v.identities) > 0 {
+		v.roots = x509.NewCertPool()
+		if !v.roots.AppendCertsFromPEM([]byte(policy.RootCerts)) {
+			return nil, fmt.Errorf("the root certificates are required by the keyless identities")
+		}
+		if v.rekorKeys, err = parsePublicKeys(policy.RekorPublicKeys); err != nil {
+			return nil, err
+		}
+		if len(v.rekorKeys) == 0 {
+			return nil, fmt.Errorf("the rekor public keys are required by the keyless identities")
+		}
+	}
+	if len(v.keys) == 0 && len(v.identities) == 0 {
+		return nil, fmt.Errorf("the policy has neither public keys nor keyless identities")
+	}
+	return v, nil
+}
+
+// parsePublicKeys parses the PEM encoded public keys
+func parsePublicKeys(keys string) ([]crypto.PublicKey, error) {
+	var res []crypto.PublicKey
+	rest := []byte(keys)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return res, nil
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key: %v", err)
+		}
+		res = append(res, key)
+	}
+}
+
+// ResolveImageDigest returns the image pinned to the digest of its manifest in the
+// registry, so the verified image is the one deployed even if the tag is moved.
+func ResolveImageDigest(image, user, password string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("parse image %s: %v", image, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return image, nil
+	}
+	reg, err := newRegistryClient(reference.Domain(named), user, password)
+	if err != nil {
+		return "", err
+	}
+	dgst, err := reg.ImageDigest(reference.Path(named), GetTagFromNamedRef(named))
+	if err != nil {
+		return "", fmt.Errorf("get digest of image %s: %v", image, err)
+	}
+	return image + "@" + dgst.String(), nil
+}
+
+// Verify verifies the signatures of the image pinned to the digest in the registry,
+// it returns nil if one of the signatures is trusted
+func (v *ImageSignatureVerifier) Verify(image, user, password string) error {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("parse image %s: %v", image, err)
+	}
+	digested, ok := named.(reference.Digested)
+	if !ok {
+		return fmt.Errorf("the image %s is not pinned to a digest", image)
+	}
+	dgst := digested.Digest()
+	reg, err := newRegistryClient(reference.Domain(named), user, password)
+	if err != nil {
+		return err
+	}
+	repository := reference.Path(named)
+	// cosign stores the signatures in the tag sha256-<hex>.sig
+	signatureTag := fmt.Sprintf("%s-%s.sig", dgst.Algorithm(), dgst.Encoded())
+	manifest, err := reg.OCIManifest(repository, signatureTag)
+	if err != nil {
+		if errors.Is(err, registry.ErrManifestNotFound) {
+			return ErrImageNotSigned
+		}
+		return fmt.Errorf("get signatures of image %s: %v", image, err)
+	}
+	verifyErr := ErrImageNotSigned
+	for _, layer := range manifest.Layers {
+		if layer.Annotations[cosignSignatureAnnotation] == "" {
+			continue
+		}
+		payload, err := reg.Blob(repository, layer.Digest)
+		if err != nil {
+			verifyErr = fmt.Errorf("get signature payload: %v", err)
+			continue
+		}
+		if verifyErr = v.verifySignature(payload, layer.Annotations, dgst); verifyErr == nil {
+			return nil
+		}
+		logrus.Debugf("signature %s of image %s is not trusted: %v", layer.Digest, image, verifyErr)
+	}
+	return verifyErr
+}
+
+// verifySignature verifies one signature of the image with the digest
+func (v *ImageSignatureVerifier) verifySignature(payload []byte, annotations map[string]string, dgst digest.Digest) error {
+	var ss simpleSigning
+	if err := json.Unmarshal(payload, &ss); err != nil {
+		return fmt.Errorf("unmarshal signature payload: %v", err)
+	}
+	if ss.Critical.Image.DockerManifestDigest != dgst.String() {
+		return fmt.Errorf("the signature is for %s, not %s", ss.Critical.Image.DockerManifestDigest, dgst)
+	}
+	signature, err := base64.StdEncoding.DecodeString(annotations[cosignSignatureAnnotation])
+	if err != nil {
+		return fmt.Errorf("decode signature: %v", err)
+	}
+	for _, key := range v.keys {
+		if verifyWithPublicKey(key, payload, signature) == nil {
+			return nil
+		}
+	}
+	if annotations[cosignCertificateAnnotation] == "" || len(v.identities) == 0 {
+		return fmt.Errorf("the signature is not signed by the trusted public keys")
+	}
+	block, _ := pem.Decode([]byte(annotations[cosignCertificateAnnotation]))
+	if block == nil {
+		return fmt.Errorf("decode signing certificate failure")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse signing certificate: %v", err)
+	}
+	integratedTime, err := v.verifyTlogEntry(annotations[cosignBundleAnnotation], payload, signature, cert)
+	if err != nil {
+		return err
+	}
+	if err := v.verifyCertificate(cert, annotations[cosignChainAnnotation], integratedTime); err != nil {
+		return err
+	}
+	return verifyWithPublicKey(cert.PublicKey, payload, signature)
+}
+
+// verifyTlogEntry verifies the signed entry timestamp of rekor, and the entry is the
+// signature of the payload by the certificate. It returns the time the entry is logged.
+func (v *ImageSignatureVerifier) verifyTlogEntry(bundleJSON string, payload, signature []byte, cert *x509.Certificate) (time.Time, error) {
+	if bundleJSON == "" {
+		return time.Time{}, fmt.Errorf("the keyless signature is not logged in rekor")
+	}
+	var bundle rekorBundle
+	if err := json.Unmarshal([]byte(bundleJSON), &bundle); err != nil {
+		return time.Time{}, fmt.Errorf("unmarshal rekor bundle: %v", err)
+	}
+	signed, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	verified := false
+	for _, key := range v.rekorKeys {
+		if verifyWithPublicKey(key, signed, bundle.SignedEntryTimestamp) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return time.Time{}, fmt.Errorf("the signed entry timestamp is not signed by the trusted rekor")
+	}
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode rekor entry: %v", err)
+	}
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("unmarshal rekor entry: %v", err)
+	}
+	if entry.Kind != "hashedrekord" {
+		return time.Time{}, fmt.Errorf("unsupported rekor entry kind %s", entry.Kind)
+	}
+	hash := sha256.Sum256(payload)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(hash[:]) {
+		return time.Time{}, fmt.Errorf("the rekor entry is not for the signature payload")
+	}
+	if logged, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.Content); err != nil || !bytes.Equal(logged, signature) {
+		return time.Time{}, fmt.Errorf("the rekor entry is not for the signature")
+	}
+	logged, err := base64.StdEncoding.DecodeString(entry.Spec.Signature.PublicKey.Content)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode the certificate of the rekor entry: %v", err)
+	}
+	if block, _ := pem.Decode(logged); block == nil || !bytes.Equal(block.Bytes, cert.Raw) {
+		return time.Time{}, fmt.Errorf("the rekor entry is not for the signing certificate")
+	}
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+// verifyCertificate verifies the fulcio certificate is valid when the signature is logged,
+// and it is issued to one of the identities
+func (v *ImageSignatureVerifier) verifyCertificate(cert *x509.Certificate, chainPEM string, at time.Time) error {
+	intermediates := x509.NewCertPool()
+	intermediates.AppendCertsFromPEM([]byte(chainPEM))
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return fmt.Errorf("verify signing certificate: %v", err)
+	}
+	issuer := certificateIssuer(cert)
+	var subjects []string
+	subjects = append(subjects, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	for _, identity := range v.identities {
+		if !globMatch(identity.Issuer, issuer) {
+			continue
+		}
+		for _, subject := range subjects {
+			if globMatch(identity.Subject, subject) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("the signing identity %s issued by %s is not trusted", strings.Join(subjects, ","), issuer)
+}
+
+// certificateIssuer returns the oidc issuer in the fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidFulcioIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidFulcioIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// globMatch matches the value with the pattern, an empty pattern matches nothing.
+// Unlike path.Match, * matches / as well, the subject uri of a workflow is a path
+// like https://github.com/org/repo/.github/workflows/release.yml@refs/tags/v1.0.
+func globMatch(pattern, value string) bool {
+	if pattern == "" || value == "" {
+		return false
+	}
+	// p and v are the positions in the pattern and the value, star and next are
+	// where the last * is and where the value is matched with it from
+	p, v, star, next := 0, 0, -1, 0
+	for v < len(value) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == value[v]):
+			p++
+			v++
+		case p < len(pattern) && pattern[p] == '*':
+			star, next = p, v
+			p++
+		case star >= 0:
+			// the last * takes one more character
+			next++
+			p, v = star+1, next
+		default:
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+func verifyWithPublicKey(key crypto.PublicKey, payload, signature []byte) error {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, hash[:], signature) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature); err == nil {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, payload, signature) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	return fmt.Errorf("invalid signature")
+}
@@ -0,0 +1,244 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2019 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package sources
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+func newTestSigner(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func signTestPayload(t *testing.T, key *ecdsa.PrivateKey, dgst digest.Digest) ([]byte, map[string]string) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"example.com/demo"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, dgst))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload, map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature)}
+}
+
+func TestImageSignatureVerifier_VerifySignature(t *testing.T) {
+	key, publicKey := newTestSigner(t)
+	_, otherPublicKey := newTestSigner(t)
+	dgst := digest.FromString("manifest")
+
+	verifier, err := NewImageSignatureVerifier(&dbmodel.ImageSignaturePolicy{PublicKeys: otherPublicKey + publicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, annotations := signTestPayload(t, key, dgst)
+	if err := verifier.verifySignature(payload, annotations, dgst); err != nil {
+		t.Errorf("expected trusted signature, got %v", err)
+	}
+	if err := verifier.verifySignature(payload, annotations, digest.FromString("other")); err == nil {
+		t.Error("expected error for the signature of another digest")
+	}
+
+	untrusted, err := NewImageSignatureVerifier(&dbmodel.ImageSignaturePolicy{PublicKeys: otherPublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := untrusted.verifySignature(payload, annotations, dgst); err == nil {
+		t.Error("expected error for the signature of an untrusted key")
+	}
+}
+
+// newTestKeylessSigner issues a short-lived fulcio-like certificate to the email
+func newTestKeylessSigner(t *testing.T, email string, notBefore time.Time) (*ecdsa.PrivateKey, *x509.Certificate, string) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio"},
+		NotBefore:             notBefore.Add(-time.Hour),
+		NotAfter:              notBefore.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       notBefore,
+		NotAfter:        notBefore.Add(10 * time.Minute),
+		EmailAddresses:  []string{email},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidFulcioIssuerV1, Value: []byte("https://accounts.example.com")}},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, root, &key.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return key, cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}))
+}
+
+// newTestRekorBundle logs the signature of the payload at the integrated time
+func newTestRekorBundle(t *testing.T, rekorKey *ecdsa.PrivateKey, payload []byte, signature string, cert *x509.Certificate, integratedTime time.Time) string {
+	var entry hashedRekord
+	entry.Kind = "hashedrekord"
+	hash := sha256.Sum256(payload)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(hash[:])
+	entry.Spec.Signature.Content = signature
+	entry.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	body, _ := json.Marshal(entry)
+	bundle := rekorBundle{Payload: rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integratedTime.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       42,
+	}}
+	signed, _ := json.Marshal(bundle.Payload)
+	signedHash := sha256.Sum256(signed)
+	set, err := ecdsa.SignASN1(rand.Reader, rekorKey, signedHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle.SignedEntryTimestamp = set
+	res, _ := json.Marshal(bundle)
+	return string(res)
+}
+
+func TestImageSignatureVerifier_VerifyKeyless(t *testing.T) {
+	issued := time.Now().Add(-time.Hour)
+	key, cert, rootPEM := newTestKeylessSigner(t, "dev@example.com", issued)
+	rekorKey, rekorPublicKey := newTestSigner(t)
+	otherRekorKey, _ := newTestSigner(t)
+	dgst := digest.FromString("manifest")
+	payload, annotations := signTestPayload(t, key, dgst)
+	annotations[cosignCertificateAnnotation] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+
+	policy := &dbmodel.ImageSignaturePolicy{
+		Identities:      `[{"issuer":"https://accounts.example.com","subject":"*@example.com"}]`,
+		RootCerts:       rootPEM,
+		RekorPublicKeys: rekorPublicKey,
+	}
+	verifier, err := NewImageSignatureVerifier(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := annotations[cosignSignatureAnnotation]
+	tests := []struct {
+		name   string
+		bundle string
+		valid  bool
+	}{
+		{name: "logged", bundle: newTestRekorBundle(t, rekorKey, payload, signature, cert, issued.Add(time.Minute)), valid: true},
+		{name: "not logged"},
+		{name: "untrusted rekor", bundle: newTestRekorBundle(t, otherRekorKey, payload, signature, cert, issued.Add(time.Minute))},
+		{name: "logged after the certificate expired", bundle: newTestRekorBundle(t, rekorKey, payload, signature, cert, time.Now())},
+		{name: "another signature", bundle: newTestRekorBundle(t, rekorKey, payload, base64.StdEncoding.EncodeToString([]byte("other")), cert, issued.Add(time.Minute))},
+	}
+	for _, tc := range tests {
+		annotations[cosignBundleAnnotation] = tc.bundle
+		if err := verifier.verifySignature(payload, annotations, dgst); (err == nil) != tc.valid {
+			t.Errorf("%s: want valid %v, got %v", tc.name, tc.valid, err)
+		}
+	}
+
+	policy.Identities = `[{"issuer":"https://accounts.example.com","subject":"*@example.org"}]`
+	untrusted, err := NewImageSignatureVerifier(policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations[cosignBundleAnnotation] = tests[0].bundle
+	if err := untrusted.verifySignature(payload, annotations, dgst); err == nil {
+		t.Error("expected error for the untrusted identity")
+	}
+}
+
+func TestNewImageSignatureVerifier(t *testing.T) {
+	if _, err := NewImageSignatureVerifier(&dbmodel.ImageSignaturePolicy{}); err == nil {
+		t.Error("expected error for the policy without keys and identities")
+	}
+	if _, err := NewImageSignatureVerifier(&dbmodel.ImageSignaturePolicy{Identities: `[{"issuer":"https://accounts.google.com","subject":"*@example.com"}]`}); err == nil {
+		t.Error("expected error for the keyless identities without root certificates")
+	}
+	_, _, rootPEM := newTestKeylessSigner(t, "dev@example.com", time.Now())
+	if _, err := NewImageSignatureVerifier(&dbmodel.ImageSignaturePolicy{Identities: `[{"issuer":"https://accounts.example.com","subject":"*@example.com"}]`, RootCerts: rootPEM}); err == nil {
+		t.Error("expected error for the keyless identities without rekor public keys")
+	}
+	if _, err := NewImageSignatureVerifier(&dbmodel.ImageSignaturePolicy{Identities: `[{"subject":"*@example.com"}]`}); err == nil {
+		t.Error("expected error for the keyless identity without issuer")
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"", "dev@example.com", false},
+		{"*", "", false},
+		{"*@example.com", "dev@example.com", true},
+		{"*@example.com", "dev@example.org", false},
+		{"https://github.com/wutong-paas/*", "https://github.com/wutong-paas/wutong", true},
+		{"https://github.com/org/*", "https://github.com/org/repo/.github/workflows/x.yml@refs/heads/main", true},
+		{"https://github.com/org/*/.github/workflows/*@refs/tags/*", "https://github.com/org/repo/.github/workflows/x.yml@refs/tags/v1.0", true},
+		{"https://github.com/org/*/.github/workflows/*@refs/tags/*", "https://github.com/org/repo/.github/workflows/x.yml@refs/heads/main", false},
+		{"https://github.com/org/*", "https://github.com/other/repo/.github/workflows/x.yml@refs/heads/main", false},
+		{"https://token.actions.githubusercontent.com", "https://token.actions.githubusercontent.com", true},
+		{"dev?@example.com", "dev1@example.com", true},
+		{"dev?@example.com", "dev@example.com", false},
+	}
+	for _, tc := range tests {
+		if got := globMatch(tc.pattern, tc.value); got != tc.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tc.pattern, tc.value, got, tc.want)
+		}
+	}
+}
@@ -19,6 +19,8 @@
 package option
 
 import (
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 	"github.com/wutong-paas/wutong/util/containerutil"
@@ -71,6 +73,7 @@ type Config struct {
 	VirtVNCAPI           []string
 	ContainerRuntime     string
 	RuntimeEndpoint      string
+	AlertRepeatInterval  time.Duration
 }
 
 // APIServer  apiserver server
@@ -136,6 +139,7 @@ func (a *APIServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringSliceVar(&a.VirtVNCAPI, "virt-vnc-api", []string{"wt-virt-vnc"}, "the virt-vnc api")
 	fs.StringVar(&a.ContainerRuntime, "container-runtime", containerutil.ContainerRuntimeDocker, "container runtime, support docker and containerd")
 	fs.StringVar(&a.RuntimeEndpoint, "runtime-endpoint", containerutil.DefaultDockerSock, "container runtime endpoint")
+	fs.DurationVar(&a.AlertRepeatInterval, "alert-repeat-interval", 4*time.Hour, "how long to wait before sending a firing alert to the notification channels again")
 }
 
 // SetLog 设置log
@@ -43,6 +43,9 @@ type Config struct {
 	PrometheusMetricPath string
 	KubeConfig           string
 	MaxTasks             int
+	TaskLeaseTimeout     int
+	TenantEnvMaxTasks    int
+	TenantEnvWeights     map[string]int
 	APIPort              int
 	MQAPI                string
 	DockerEndpoint       string
@@ -87,6 +90,9 @@ func (a *Builder) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&a.MysqlConnectionInfo, "mysql", "root:admin@tcp(127.0.0.1:3306)/region", "mysql db connection info")
 	fs.StringVar(&a.KubeConfig, "kube-config", "", "kubernetes api server config file")
 	fs.IntVar(&a.MaxTasks, "max-tasks", 50, "Maximum number of simultaneous build tasks")
+	fs.IntVar(&a.TenantEnvMaxTasks, "tenant-env-max-tasks", 0, "Maximum number of simultaneous build tasks of one tenant env, 0 means no limit")
+	fs.StringToIntVar(&a.TenantEnvWeights, "tenant-env-weights", nil, "Fair share weights of tenant envs, in the form of tenant_env_id=weight, the default weight is 1")
+	fs.IntVar(&a.TaskLeaseTimeout, "task-lease-timeout", 300, "Seconds a dequeued build task is leased, the lease is extended while the task is pending or running, and the task is redelivered if chaos stops extending it")
 	fs.IntVar(&a.APIPort, "api-port", 3228, "the port for api server")
 	// fs.StringVar(&a.MQAPI, "mq-api", "127.0.0.1:6300", "acp_mq api")
 	fs.StringVar(&a.RunMode, "run", "sync", "sync data when worker start")
@@ -20,6 +20,7 @@ package option
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"time"
 
@@ -77,6 +78,26 @@ type Config struct {
 	ShareMemory       uint64
 	SyncRateLimit     float32
 	EnableSSLStapling bool
+	// WorkerAPI the wt-worker api to wake up the components scaled to zero and to get the
+	// key authorizations of the acme challenges
+	WorkerAPI string
+	// WakeToken the token sent to the worker api to wake up the components, the same as the worker
+	WakeToken string
+	// AccessLogServer the udp address of the eventlog which receives the access logs of the components
+	AccessLogServer string
+	// Backend the data plane of the gateway, openresty or envoy
+	Backend string
+	// EnvoyXDSAddr the address of the xds server which serves the gateway configuration to envoy
+	EnvoyXDSAddr string
+	// EnvoyXDSCertFile, EnvoyXDSKeyFile and EnvoyXDSCAFile the mutual tls of the xds server, the envoy
+	// must present a client certificate signed by the ca
+	EnvoyXDSCertFile string
+	EnvoyXDSKeyFile  string
+	EnvoyXDSCAFile   string
+	// EnvoyNodeCluster the node cluster in the bootstrap of the envoy which consumes the xds server
+	EnvoyNodeCluster string
+	// EnvoyAdminAddr the admin address of the envoy, used to check if the envoy is ready
+	EnvoyAdminAddr string
 }
 
 // ListenPorts describe the ports required to run the gateway controller
@@ -127,6 +148,16 @@ func (g *GWServer) AddFlags(fs *pflag.FlagSet) {
 	fs.Float32Var(&g.SyncRateLimit, "sync-rate-limit", 0.3, "Define the sync frequency upper limit")
 	fs.StringArrayVar(&g.IgnoreInterface, "ignore-interface", []string{"docker0", "tunl0", "cni0", "kube-ipvs0", "flannel"}, "The network interface name that ignore by gateway")
 	fs.StringSliceVar(&g.EtcdEndpoint, "etcd-endpoints", []string{"http://wt-etcd:2379"}, "etcd cluster endpoints.")
+	fs.StringVar(&g.WorkerAPI, "worker-api", "http://wt-worker:6369", "the wt-worker api to wake up the components scaled to zero and to serve the acme http-01 challenges")
+	fs.StringVar(&g.WakeToken, "wake-token", "", "the token sent to the worker api to wake up the components scaled to zero, it must be the same as the wake-token of the worker")
+	fs.StringVar(&g.AccessLogServer, "access-log-server", "", "the udp address of the eventlog which receives the access logs of the components, such as wt-eventlog:6166, the port receiving the monitor messages, the access logs are not shipped if it is empty")
+	fs.StringVar(&g.Backend, "gateway-backend", "openresty", "the data plane of the gateway, openresty or envoy")
+	fs.StringVar(&g.EnvoyXDSAddr, "envoy-xds-addr", "127.0.0.1:18082", "the address of the xds server which serves the gateway configuration to envoy, only for the envoy backend")
+	fs.StringVar(&g.EnvoyXDSCertFile, "envoy-xds-cert", "", "the tls cert file of the xds server, required if the xds server does not listen on the loopback address")
+	fs.StringVar(&g.EnvoyXDSKeyFile, "envoy-xds-key", "", "the tls key file of the xds server, required if the xds server does not listen on the loopback address")
+	fs.StringVar(&g.EnvoyXDSCAFile, "envoy-xds-ca", "", "the ca file to verify the client certificates of the envoy, required if the xds server does not listen on the loopback address")
+	fs.StringVar(&g.EnvoyNodeCluster, "envoy-node-cluster", "wt-gateway", "the node cluster in the bootstrap of the envoy, only for the envoy backend")
+	fs.StringVar(&g.EnvoyAdminAddr, "envoy-admin-addr", "127.0.0.1:19000", "the admin address of the envoy to check if it is ready, only for the envoy backend")
 }
 
 // SetLog sets log
@@ -152,8 +183,33 @@ func (g *GWServer) CheckConfig() error {
 		}
 		g.HostIP = ip.String()
 	}
+	if g.Backend != "openresty" && g.Backend != "envoy" {
+		return fmt.Errorf("unsupported gateway backend %s", g.Backend)
+	}
+	if g.Backend == "envoy" {
+		if err := g.checkXDSConfig(); err != nil {
+			return err
+		}
+	}
 	if os.Getenv("ACCESS_LOG_FORMAT") != "" {
 		g.Config.AccessLogFormat = os.Getenv("ACCESS_LOG_FORMAT")
 	}
 	return nil
 }
+
+// checkXDSConfig requires the mutual tls if the xds server listens beyond the loopback address, the xds
+// serves the private keys of the certificates.
+func (g *GWServer) checkXDSConfig() error {
+	mtls := g.EnvoyXDSCertFile != "" || g.EnvoyXDSKeyFile != "" || g.EnvoyXDSCAFile != ""
+	if mtls && (g.EnvoyXDSCertFile == "" || g.EnvoyXDSKeyFile == "" || g.EnvoyXDSCAFile == "") {
+		return fmt.Errorf("--envoy-xds-cert, --envoy-xds-key and --envoy-xds-ca must be set together")
+	}
+	host, _, err := net.SplitHostPort(g.EnvoyXDSAddr)
+	if err != nil {
+		return fmt.Errorf("invalid envoy xds address %s: %v", g.EnvoyXDSAddr, err)
+	}
+	if ip := net.ParseIP(host); (ip == nil || !ip.IsLoopback()) && host != "localhost" && !mtls {
+		return fmt.Errorf("the xds server listens on %s, --envoy-xds-cert, --envoy-xds-key and --envoy-xds-ca are required", g.EnvoyXDSAddr)
+	}
+	return nil
+}
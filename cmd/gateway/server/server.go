@@ -114,6 +114,7 @@ func Run(s *option.GWServer) error {
 	mux := chi.NewMux()
 	registerHealthz(gwc, mux)
 	registerMetrics(reg, mux)
+	registerACMEChallenge(s.WorkerAPI, mux)
 	if s.Debug {
 		util.ProfilerSetup(mux)
 	}
@@ -160,6 +161,11 @@ func registerMetrics(reg *prometheus.Registry, mux *chi.Mux) {
 	)
 }
 
+func registerACMEChallenge(workerAPI string, mux *chi.Mux) {
+	// nginx proxies the http-01 challenges of the auto tls hosts here
+	mux.Handle(controller.ACMEChallengePath+"{token}", controller.NewACMEChallengeHandler(workerAPI))
+}
+
 func startHTTPServer(port int, mux *chi.Mux) {
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%v", port),
@@ -37,6 +37,10 @@ type Config struct {
 	RunMode              string //http grpc
 	HostIP               string
 	HostName             string
+	QueueBackend         string //etcd local
+	QueueDataDir         string
+	QueueCompactSize     int
+	MaxDeliveries        int
 }
 
 // MQServer lb worker server
@@ -65,6 +69,10 @@ func (a *MQServer) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&a.HostIP, "hostIP", "", "Current node Intranet IP")
 	fs.StringVar(&a.HostName, "hostName", "", "Current node host name")
 	fs.StringSliceVar(&a.EtcdEndPoints, "etcd-endpoints", []string{"http://wt-etcd:2379"}, "etcd v3 cluster endpoints.")
+	fs.StringVar(&a.QueueBackend, "queue-backend", "etcd", "the message queue storage backend, etcd or local")
+	fs.StringVar(&a.QueueDataDir, "queue-data-dir", "/wtdata/mq", "the data directory of the local message queue backend")
+	fs.IntVar(&a.MaxDeliveries, "max-deliveries", 5, "move a leased task to the dead letter topic after it is delivered this many times without ack")
+	fs.IntVar(&a.QueueCompactSize, "queue-compact-size", 1000, "compact the local queue log after this number of messages are dequeued")
 }
 
 // SetLog 设置log
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -44,11 +45,13 @@ type Config struct {
 	KubeAPIQPS              int
 	KubeAPIBurst            int
 	MaxTasks                int
+	TaskLeaseTimeout        int
 	MQAPI                   string
 	NodeName                string
 	Listen                  string
 	HostIP                  string
 	ServerPort              int
+	WakeToken               string
 	KubeClient              kubernetes.Interface
 	LeaderElectionNamespace string
 	LeaderElectionIdentity  string
@@ -56,6 +59,17 @@ type Config struct {
 	WTDataPVCName           string
 	Helm                    Helm
 	DefaultOTELServerHost   string // WT_OTEL_SERVER
+	PrometheusEndpoint      string
+	ACME                    ACME
+}
+
+// ACME the ACME server which issues the certificates of the auto tls http rules
+type ACME struct {
+	DirectoryURL string
+	Email        string
+	RenewBefore  time.Duration
+	// DNSWebhook the webhook to fulfil the dns-01 challenges
+	DNSWebhook string
 }
 
 // Helm helm configuration.
@@ -95,19 +109,26 @@ func (a *Worker) AddFlags(fs *pflag.FlagSet) {
 	fs.IntVar(&a.KubeAPIQPS, "kube-api-qps", 50, "kube client qps")
 	fs.IntVar(&a.KubeAPIBurst, "kube-api-burst", 10, "kube clint burst")
 	fs.IntVar(&a.MaxTasks, "max-tasks", 50, "the max tasks for per node")
+	fs.IntVar(&a.TaskLeaseTimeout, "task-lease-timeout", 300, "seconds a dequeued task is leased, it is redelivered if not handled in time")
 	// fs.StringVar(&a.MQAPI, "mq-api", "127.0.0.1:6300", "acp_mq api")
 	fs.StringVar(&a.RunMode, "run", "sync", "sync data when worker start")
 	fs.StringVar(&a.NodeName, "node-name", "", "the name of this worker,it must be global unique name")
 	fs.StringVar(&a.HostIP, "host-ip", "", "the ip of this worker,it must be global connected ip")
 	fs.IntVar(&a.ServerPort, "server-port", 6535, "the listen port that app runtime server")
+	fs.StringVar(&a.WakeToken, "wake-token", "", "the token the gateway sends to wake up the components scaled to zero, the wake requests are refused if it is empty")
 	fs.StringVar(&a.LeaderElectionNamespace, "leader-election-namespace", "wutong", "Namespace where this attacher runs.")
 	fs.StringVar(&a.LeaderElectionIdentity, "leader-election-identity", "", "Unique idenity of this attcher. Typically name of the pod where the attacher runs.")
 	fs.StringVar(&a.WTNamespace, "wt-system-namespace", "wt-system", "wt components kubernetes namespace")
 	fs.StringVar(&a.WTDataPVCName, "wtdata-pvc-name", "wt-cpt-wtdata", "The name of wtdata persistent volume claim")
 	fs.StringVar(&a.Helm.DataDir, "helm-data-dir", "helm-data-dir", "The data directory of Helm.")
 	fs.StringVar(&a.DefaultOTELServerHost, "otel-server-host", "obs-otel-biz-collector.wutong-obs", "The default OpenTelemetry server host.")
+	fs.StringVar(&a.PrometheusEndpoint, "prom-api", "wt-monitor:9999", "The service DNS name of Prometheus api, it is used to analyze the releases and detect the idle components.")
 	fs.StringSliceVar(&a.EtcdEndPoints, "etcd-endpoints", []string{"http://wt-etcd:2379"}, "etcd v3 cluster endpoints.")
 	fs.StringVar(&a.MQAPI, "mq-api", "wt-mq:6300", "acp_mq api")
+	fs.StringVar(&a.ACME.DirectoryURL, "acme-directory", "https://acme-v02.api.letsencrypt.org/directory", "The directory url of the ACME server which issues the certificates of the auto tls http rules, empty to disable it.")
+	fs.StringVar(&a.ACME.Email, "acme-email", "", "The contact email of the ACME account.")
+	fs.DurationVar(&a.ACME.RenewBefore, "acme-renew-before", 30*24*time.Hour, "Renew the certificates issued by the ACME server which expire in the duration.")
+	fs.StringVar(&a.ACME.DNSWebhook, "acme-dns-webhook", "", "The webhook to create the TXT records of the dns-01 challenges, it is required by the wildcard domains.")
 
 	if a.Helm.DataDir == "" {
 		a.Helm.DataDir = "/wtdata/helm"
@@ -53,6 +53,7 @@ type TenantEnvDao interface {
 	GetTenantEnvIDsByNames(tenantName string, tenantEnvNames []string) ([]string, error)
 	GetTenantEnvLimitsByNames(tenantName string, tenantEnvNames []string) (map[string]int, error)
 	GetTenantEnvByUUIDIsExist(uuid string) bool
+	GetTenantEnvByNamespace(namespace string) (*model.TenantEnvs, error)
 	DelByTenantEnvID(tenantEnvID string) error
 }
 
@@ -259,6 +260,7 @@ type TenantEnvServicePluginRelationDao interface {
 	CheckPluginBeforeInstall(serviceID, pluginModel string) (bool, error)
 	DeleteByComponentIDs(componentIDs []string) error
 	CreateOrUpdatePluginRelsInBatch(relations []*model.TenantEnvServicePluginRelation) error
+	ListByComponentIDs(componentIDs []string) ([]*model.TenantEnvServicePluginRelation, error)
 }
 
 // TenantEnvServiceRelationDao TenantEnvServiceRelationDao
@@ -472,6 +474,7 @@ type VersionInfoDao interface {
 	SearchVersionInfo() ([]*model.VersionInfo, error)
 	ListByServiceIDStatus(serviceID string, finalStatus *bool) ([]*model.VersionInfo, error)
 	ListVersionsByComponentIDs(componentIDs []string) ([]*model.VersionInfo, error)
+	CountUnfinishedByComponentIDs(componentIDs []string, since time.Time) (int, error)
 }
 
 // RegionAPIClassDao RegionAPIClassDao
@@ -514,6 +517,19 @@ type CertificateDao interface {
 	GetCertificateByID(certificateID string) (*model.Certificate, error)
 }
 
+// ACMEAccountDao -
+type ACMEAccountDao interface {
+	Dao
+	GetByDirectoryURL(directoryURL string) (*model.ACMEAccount, error)
+}
+
+// ACMEChallengeDao -
+type ACMEChallengeDao interface {
+	Dao
+	GetByToken(token string) (*model.ACMEChallenge, error)
+	DeleteByToken(token string) error
+}
+
 // RuleExtensionDao -
 type RuleExtensionDao interface {
 	Dao
@@ -535,6 +551,8 @@ type HTTPRuleDao interface {
 	ListByServiceID(serviceID string) ([]*model.HTTPRule, error)
 	ListByComponentPort(componentID string, port int) ([]*model.HTTPRule, error)
 	ListByCertID(certID string) ([]*model.HTTPRule, error)
+	ListByDomain(domain string) ([]*model.HTTPRule, error)
+	ListAutoTLS() ([]*model.HTTPRule, error)
 	DeleteByComponentPort(componentID string, port int) error
 	DeleteByComponentIDs(componentIDs []string) error
 	CreateOrUpdateHTTPRuleInBatch(httpRules []*model.HTTPRule) error
@@ -563,6 +581,7 @@ type TCPRuleDao interface {
 	DeleteByComponentPort(componentID string, port int) error
 	DeleteByComponentIDs(componentIDs []string) error
 	CreateOrUpdateTCPRuleInBatch(tcpRules []*model.TCPRule) error
+	CountByComponentIDs(componentIDs []string) (int, error)
 }
 
 // EndpointsDao is an interface for defining method
@@ -625,6 +644,26 @@ type TenantEnvServiceScalingRecordsDao interface {
 	CountByServiceID(serviceID string) (int, error)
 }
 
+// TenantEnvServiceScheduledScalingRuleDao -
+type TenantEnvServiceScheduledScalingRuleDao interface {
+	Dao
+	GetByRuleID(ruleID string) (*model.TenantEnvServiceScheduledScalingRule, error)
+	ListByServiceID(serviceID string) ([]*model.TenantEnvServiceScheduledScalingRule, error)
+	ListByTenantEnvID(tenantEnvID string) ([]*model.TenantEnvServiceScheduledScalingRule, error)
+	ListEnableOnes() ([]*model.TenantEnvServiceScheduledScalingRule, error)
+	DeleteByRuleID(ruleID string) error
+	DeleteByComponentIDs(componentIDs []string) error
+}
+
+// TenantEnvServiceScaleToZeroDao -
+type TenantEnvServiceScaleToZeroDao interface {
+	Dao
+	GetByServiceID(serviceID string) (*model.TenantEnvServiceScaleToZero, error)
+	ListIdleEnableOnes() ([]*model.TenantEnvServiceScaleToZero, error)
+	SwitchSleeping(setting *model.TenantEnvServiceScaleToZero) (bool, error)
+	DeleteByComponentIDs(componentIDs []string) error
+}
+
 // TenantEnvServiceMonitorDao -
 type TenantEnvServiceMonitorDao interface {
 	Dao
@@ -635,3 +674,67 @@ type TenantEnvServiceMonitorDao interface {
 	DeleteByComponentIDs(componentIDs []string) error
 	CreateOrUpdateMonitorInBatch(monitors []*model.TenantEnvServiceMonitor) error
 }
+
+// ImageSignaturePolicyDao -
+type ImageSignaturePolicyDao interface {
+	Dao
+	GetByTenantEnvID(tenantEnvID string) (*model.ImageSignaturePolicy, error)
+	GetEffectivePolicies(tenantEnvID string) (model.ImageSignaturePolicies, error)
+	DeleteByTenantEnvID(tenantEnvID string) error
+}
+
+// ServiceReleaseDao -
+type ServiceReleaseDao interface {
+	Dao
+	GetByReleaseID(releaseID string) (*model.ServiceRelease, error)
+	ListByServiceID(serviceID string) ([]*model.ServiceRelease, error)
+	GetRunningByServiceID(serviceID string) (*model.ServiceRelease, error)
+	ListByStatus(status ...string) ([]*model.ServiceRelease, error)
+	UpdateCommand(releaseID, command string) error
+}
+
+// NotificationChannelDao -
+type NotificationChannelDao interface {
+	Dao
+	GetByChannelID(channelID string) (*model.NotificationChannel, error)
+	ListByTenantEnvID(tenantEnvID string) ([]*model.NotificationChannel, error)
+	DeleteByChannelID(channelID string) error
+}
+
+// AlertSilenceDao -
+type AlertSilenceDao interface {
+	Dao
+	GetBySilenceID(silenceID string) (*model.AlertSilence, error)
+	ListByTenantEnvID(tenantEnvID string) ([]*model.AlertSilence, error)
+	DeleteBySilenceID(silenceID string) error
+}
+
+// AlertRuleDao -
+type AlertRuleDao interface {
+	Dao
+	GetByRuleID(ruleID string) (*model.AlertRule, error)
+	ListByScope(appID, serviceID string) ([]*model.AlertRule, error)
+	DeleteByRuleID(ruleID string) error
+	DeleteByServiceID(serviceID string) error
+	DeleteByAppID(appID string) error
+}
+
+// ServiceSLODao -
+type ServiceSLODao interface {
+	Dao
+	GetBySLOID(sloID string) (*model.ServiceSLO, error)
+	ListByServiceID(serviceID string) ([]*model.ServiceSLO, error)
+	DeleteBySLOID(sloID string) error
+	DeleteByServiceID(serviceID string) error
+	DeleteByHTTPRuleID(httpRuleID string) error
+}
+
+// LogAlertRuleDao -
+type LogAlertRuleDao interface {
+	Dao
+	GetByRuleID(ruleID string) (*model.LogAlertRule, error)
+	ListByServiceID(serviceID string) ([]*model.LogAlertRule, error)
+	ListEnabled() ([]*model.LogAlertRule, error)
+	DeleteByRuleID(ruleID string) error
+	DeleteByServiceID(serviceID string) error
+}
@@ -113,6 +113,8 @@ type Manager interface {
 	// gateway
 	CertificateDao() dao.CertificateDao
 	CertificateDaoTransactions(db *gorm.DB) dao.CertificateDao
+	ACMEAccountDao() dao.ACMEAccountDao
+	ACMEChallengeDao() dao.ACMEChallengeDao
 	RuleExtensionDao() dao.RuleExtensionDao
 	RuleExtensionDaoTransactions(db *gorm.DB) dao.RuleExtensionDao
 	HTTPRuleDao() dao.HTTPRuleDao
@@ -136,9 +138,26 @@ type Manager interface {
 	TenantEnvServceAutoscalerRuleMetricsDaoTransactions(db *gorm.DB) dao.TenantEnvServceAutoscalerRuleMetricsDao
 	TenantEnvServiceScalingRecordsDao() dao.TenantEnvServiceScalingRecordsDao
 	TenantEnvServiceScalingRecordsDaoTransactions(db *gorm.DB) dao.TenantEnvServiceScalingRecordsDao
+	TenantEnvServiceScheduledScalingRuleDao() dao.TenantEnvServiceScheduledScalingRuleDao
+	TenantEnvServiceScheduledScalingRuleDaoTransactions(db *gorm.DB) dao.TenantEnvServiceScheduledScalingRuleDao
+	TenantEnvServiceScaleToZeroDao() dao.TenantEnvServiceScaleToZeroDao
+	TenantEnvServiceScaleToZeroDaoTransactions(db *gorm.DB) dao.TenantEnvServiceScaleToZeroDao
 
 	TenantEnvServiceMonitorDao() dao.TenantEnvServiceMonitorDao
 	TenantEnvServiceMonitorDaoTransactions(db *gorm.DB) dao.TenantEnvServiceMonitorDao
+
+	ImageSignaturePolicyDao() dao.ImageSignaturePolicyDao
+
+	ServiceReleaseDao() dao.ServiceReleaseDao
+
+	NotificationChannelDao() dao.NotificationChannelDao
+	AlertSilenceDao() dao.AlertSilenceDao
+	AlertRuleDao() dao.AlertRuleDao
+	AlertRuleDaoTransactions(db *gorm.DB) dao.AlertRuleDao
+	ServiceSLODao() dao.ServiceSLODao
+	ServiceSLODaoTransactions(db *gorm.DB) dao.ServiceSLODao
+	LogAlertRuleDao() dao.LogAlertRuleDao
+	LogAlertRuleDaoTransactions(db *gorm.DB) dao.LogAlertRuleDao
 }
 
 var defaultManager Manager
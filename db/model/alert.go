@@ -0,0 +1,246 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// NotificationChannel the channel to deliver the alerts of a tenant env, such as a
+// webhook, the email, the DingTalk, the Feishu or the WeCom robot.
+type NotificationChannel struct {
+	Model
+	ChannelID   string `gorm:"column:channel_id;size:32;unique_index" json:"channel_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32;index" json:"tenant_env_id"`
+	Name        string `gorm:"column:name;size:64" json:"name"`
+	// Type webhook, email, dingtalk, feishu or wecom
+	Type string `gorm:"column:type;size:20" json:"type"`
+	// Config the json encoded config of the notifier, it contains the secrets
+	Config string `gorm:"column:config;type:text" json:"-"`
+	// Severities the severities to deliver, separated by comma. All severities are delivered if empty.
+	Severities   string `gorm:"column:severities;size:255" json:"severities"`
+	SendResolved bool   `gorm:"column:send_resolved" json:"send_resolved"`
+	Enabled      bool   `gorm:"column:enabled" json:"enabled"`
+}
+
+// TableName returns table name of NotificationChannel
+func (NotificationChannel) TableName() string {
+	return "tenant_env_notification_channel"
+}
+
+// AcceptSeverity returns true if the alerts of the severity are delivered to the channel
+func (c *NotificationChannel) AcceptSeverity(severity string) bool {
+	if strings.TrimSpace(c.Severities) == "" {
+		return true
+	}
+	for _, s := range strings.Split(c.Severities, ",") {
+		if strings.EqualFold(strings.TrimSpace(s), severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// AlertSilence mutes the alerts of a tenant env matching all matchers between
+// the start and the end time.
+type AlertSilence struct {
+	Model
+	SilenceID   string `gorm:"column:silence_id;size:32;unique_index" json:"silence_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32;index" json:"tenant_env_id"`
+	// Matchers the json encoded label matchers
+	Matchers  string    `gorm:"column:matchers;type:text" json:"matchers"`
+	StartsAt  time.Time `gorm:"column:starts_at" json:"starts_at"`
+	EndsAt    time.Time `gorm:"column:ends_at" json:"ends_at"`
+	Comment   string    `gorm:"column:comment;size:255" json:"comment"`
+	CreatedBy string    `gorm:"column:created_by;size:64" json:"created_by"`
+}
+
+// TableName returns table name of AlertSilence
+func (AlertSilence) TableName() string {
+	return "tenant_env_alert_silence"
+}
+
+// SilenceMatcher matches the label value, the regex is fully anchored like alertmanager
+type SilenceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"is_regex"`
+}
+
+// Validate returns an error if the name is empty or the regex is invalid
+func (m *SilenceMatcher) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("the name of the matcher is required")
+	}
+	if m.IsRegex {
+		if _, err := m.regexp(); err != nil {
+			return fmt.Errorf("invalid regex %q: %v", m.Value, err)
+		}
+	}
+	return nil
+}
+
+func (m *SilenceMatcher) regexp() (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + m.Value + ")$")
+}
+
+// Match returns true if the value of the label matches
+func (m *SilenceMatcher) Match(labels map[string]string) bool {
+	value := labels[m.Name]
+	if !m.IsRegex {
+		return value == m.Value
+	}
+	re, err := m.regexp()
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value)
+}
+
+// MatcherList returns the label matchers of the silence
+func (s *AlertSilence) MatcherList() ([]SilenceMatcher, error) {
+	var matchers []SilenceMatcher
+	if err := json.Unmarshal([]byte(s.Matchers), &matchers); err != nil {
+		return nil, err
+	}
+	return matchers, nil
+}
+
+// IsActive returns true if the silence takes effect at the time
+func (s *AlertSilence) IsActive(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Mutes returns true if the silence is active and all matchers match the labels
+func (s *AlertSilence) Mutes(labels map[string]string, now time.Time) bool {
+	if !s.IsActive(now) {
+		return false
+	}
+	matchers, err := s.MatcherList()
+	if err != nil || len(matchers) == 0 {
+		return false
+	}
+	for i := range matchers {
+		if !matchers[i].Match(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// AlertRule the PromQL alerting rule defined by the user for a component or an
+// application, the rules of the same scope are rendered into a PrometheusRule.
+type AlertRule struct {
+	Model
+	RuleID      string `gorm:"column:rule_id;size:32;unique_index" json:"rule_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32" json:"tenant_env_id"`
+	// AppID the application of the rule, empty for the rules of the components
+	AppID string `gorm:"column:app_id;size:32;index" json:"app_id"`
+	// ServiceID the component of the rule, empty for the rules of the applications
+	ServiceID string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	// Name the name of the alert
+	Name string `gorm:"column:name;size:64" json:"name"`
+	Expr string `gorm:"column:expr;type:text" json:"expr"`
+	// For the prometheus duration the expression should be true before firing
+	For         string `gorm:"column:for;size:20" json:"for"`
+	Severity    string `gorm:"column:severity;size:20" json:"severity"`
+	Summary     string `gorm:"column:summary;size:255" json:"summary"`
+	Description string `gorm:"column:description;size:1024" json:"description"`
+	// Labels the json encoded extra labels of the alert
+	Labels  string `gorm:"column:labels;type:text" json:"labels"`
+	Enabled bool   `gorm:"column:enabled" json:"enabled"`
+}
+
+// TableName returns table name of AlertRule
+func (AlertRule) TableName() string {
+	return "tenant_env_alert_rule"
+}
+
+// LabelMap returns the extra labels of the alert
+func (r *AlertRule) LabelMap() map[string]string {
+	labels := make(map[string]string)
+	if r.Labels != "" {
+		_ = json.Unmarshal([]byte(r.Labels), &labels)
+	}
+	return labels
+}
+
+// ServiceSLO the service level objective of the requests of a component through the gateway
+type ServiceSLO struct {
+	Model
+	SLOID       string `gorm:"column:slo_id;size:32;unique_index" json:"slo_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32" json:"tenant_env_id"`
+	ServiceID   string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	// HTTPRuleID the slo only covers the requests of the domain of the http rule if it is not empty
+	HTTPRuleID string `gorm:"column:http_rule_id;size:32" json:"http_rule_id"`
+	Name       string `gorm:"column:name;size:64" json:"name"`
+	// SLIType availability or latency
+	SLIType string `gorm:"column:sli_type;size:20" json:"sli_type"`
+	// Objective the target percentage of the good requests, eg: 99.9
+	Objective float64 `gorm:"column:objective" json:"objective"`
+	// Window the prometheus duration of the compliance window, eg: 30d
+	Window string `gorm:"column:slo_window;size:20" json:"window"`
+	// LatencyThreshold the seconds of the latency sli
+	LatencyThreshold float64 `gorm:"column:latency_threshold" json:"latency_threshold"`
+	Enabled          bool    `gorm:"column:enabled" json:"enabled"`
+}
+
+// TableName returns table name of ServiceSLO
+func (ServiceSLO) TableName() string {
+	return "tenant_env_service_slo"
+}
+
+// the match types of the log alert rules
+const (
+	// LogMatchTypeKeyword the log lines containing the pattern match
+	LogMatchTypeKeyword = "keyword"
+	// LogMatchTypeRegex the log lines matching the regular expression match
+	LogMatchTypeRegex = "regex"
+)
+
+// LogAlertRule the rule matching the container logs of a component, a notification event
+// is raised if the matches reach the threshold within the window
+type LogAlertRule struct {
+	Model
+	RuleID      string `gorm:"column:rule_id;size:32;unique_index" json:"rule_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32" json:"tenant_env_id"`
+	ServiceID   string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	Name        string `gorm:"column:name;size:64" json:"name"`
+	// MatchType keyword or regex
+	MatchType string `gorm:"column:match_type;size:20" json:"match_type"`
+	Pattern   string `gorm:"column:pattern;size:512" json:"pattern"`
+	// Threshold the matches within the window to raise a notification event
+	Threshold int `gorm:"column:threshold" json:"threshold"`
+	// WindowSeconds the length of the window
+	WindowSeconds int  `gorm:"column:window_seconds" json:"window_seconds"`
+	Enabled       bool `gorm:"column:enabled" json:"enabled"`
+}
+
+// TableName returns table name of LogAlertRule
+func (LogAlertRule) TableName() string {
+	return "tenant_env_service_log_alert_rule"
+}
+
+// Matcher returns the function matching the log lines
+func (r *LogAlertRule) Matcher() (func(line []byte) bool, error) {
+	if r.Pattern == "" {
+		return nil, fmt.Errorf("the pattern is empty")
+	}
+	switch r.MatchType {
+	case LogMatchTypeKeyword:
+		keyword := []byte(r.Pattern)
+		return func(line []byte) bool {
+			return bytes.Contains(line, keyword)
+		}, nil
+	case LogMatchTypeRegex:
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.Match, nil
+	}
+	return nil, fmt.Errorf("unknown match type %s", r.MatchType)
+}
@@ -67,6 +67,9 @@ var EventFinalStatusEmptyComplete EventFinalStatus = "emptycomplete"
 // EventFinalStatusTimeout -
 var EventFinalStatusTimeout EventFinalStatus = "timeout"
 
+// EventFinalStatusCanceled -
+var EventFinalStatusCanceled EventFinalStatus = "canceled"
+
 // EventStatus -
 type EventStatus string
 
@@ -125,6 +128,8 @@ type NotificationEvent struct {
 	HandleMessage string    `gorm:"column:handle_message;"`
 	ServiceName   string    `gorm:"column:service_name;size:40"`
 	TenantEnvName string    `gorm:"column:tenant_env_name;size:40"`
+	// NotifiedAt the last time the event was sent to the notification channels
+	NotifiedAt *time.Time `gorm:"column:notified_at;"`
 }
 
 // TableName table name
@@ -32,6 +32,37 @@ type Certificate struct {
 	PrivateKey      string `gorm:"column:private_key;size:65535"`
 }
 
+// ACMECertificateNamePrefix the name prefix of the certificates issued by the ACME controller
+const ACMECertificateNamePrefix = "acme-"
+
+// TableName returns table name of ACMEAccount
+func (ACMEAccount) TableName() string {
+	return "gateway_acme_account"
+}
+
+// ACMEAccount the account registered to the ACME server
+type ACMEAccount struct {
+	Model
+	DirectoryURL string `gorm:"column:directory_url;size:255;unique_index"`
+	Email        string `gorm:"column:email;size:255"`
+	URI          string `gorm:"column:uri;size:255"`
+	PrivateKey   string `gorm:"column:private_key;size:65535"`
+}
+
+// TableName returns table name of ACMEChallenge
+func (ACMEChallenge) TableName() string {
+	return "gateway_acme_challenge"
+}
+
+// ACMEChallenge the key authorization of the pending http-01 challenge, it is served to the
+// ACME server by the gateway through any worker.
+type ACMEChallenge struct {
+	Model
+	Token   string `gorm:"column:token;size:255;unique_index"`
+	Domain  string `gorm:"column:domain;size:255"`
+	KeyAuth string `gorm:"column:key_auth;size:512"`
+}
+
 // TableName returns table name of RuleExtension
 func (RuleExtension) TableName() string {
 	return "gateway_rule_extension"
@@ -46,6 +77,10 @@ var HTTPToHTTPS RuleExtensionKey = "httptohttps"
 // LBType load balancer type
 var LBType RuleExtensionKey = "lb-type"
 
+// BasicAuthUserConfigPrefix the prefix of the GwRuleConfig keys of the basic authentication users,
+// the value is the hashed password
+const BasicAuthUserConfigPrefix = "auth-basic-user-"
+
 // RuleExtension contains rule extensions for http rule or tcp rule
 type RuleExtension struct {
 	Model
@@ -84,6 +119,8 @@ type HTTPRule struct {
 	IP            string `gorm:"column:ip"`
 	CertificateID string `gorm:"column:certificate_id"`
 	PathRewrite   bool   `gorm:"column:path_rewrite"`
+	// AutoTLS the certificate of the domain is issued and renewed by the ACME controller
+	AutoTLS bool `gorm:"column:auto_tls"`
 }
 
 // TableName returns table name of TCPRule
@@ -0,0 +1,160 @@
+package model
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+const (
+	// ImageSignatureModeEnforce the unverified images can not be deployed
+	ImageSignatureModeEnforce = "enforce"
+	// ImageSignatureModeAudit the unverified images are only logged
+	ImageSignatureModeAudit = "audit"
+)
+
+const (
+	// ImageSignatureVerified the signature of the image is verified by the policy
+	ImageSignatureVerified = "verified"
+	// ImageSignatureUnverified the image has no signature trusted by the policy
+	ImageSignatureUnverified = "unverified"
+)
+
+// ImageSignaturePolicy the policy to verify the cosign signatures of the images.
+// The policy without tenant env id applies to the whole cluster, the policy of a
+// tenant env is applied on top of the cluster policy.
+type ImageSignaturePolicy struct {
+	Model
+	TenantEnvID string `gorm:"column:tenant_env_id;size:40;unique_index" json:"tenant_env_id"`
+	// Images the glob patterns of the images to verify, separated by comma. All images are verified if empty.
+	// `**` and a trailing `*` match the nested repositories.
+	Images string `gorm:"column:images;size:2047" json:"images"`
+	// Mode enforce or audit
+	Mode string `gorm:"column:mode;size:20" json:"mode"`
+	// PublicKeys the PEM encoded cosign public keys
+	PublicKeys string `gorm:"column:public_keys;type:text" json:"public_keys"`
+	// Identities the json encoded keyless identities
+	Identities string `gorm:"column:identities;type:text" json:"identities"`
+	// RootCerts the PEM encoded fulcio root certificates to verify the keyless signatures
+	RootCerts string `gorm:"column:root_certs;type:text" json:"root_certs"`
+	// RekorPublicKeys the PEM encoded rekor public keys to verify the keyless signatures are logged
+	RekorPublicKeys string `gorm:"column:rekor_public_keys;type:text" json:"rekor_public_keys"`
+}
+
+// TableName returns table name of ImageSignaturePolicy
+func (ImageSignaturePolicy) TableName() string {
+	return "image_signature_policy"
+}
+
+// KeylessIdentity the identity of the keyless signature, the subject is the
+// email or uri in the certificate. Both support the wildcards, * matches any
+// characters including /, ? matches one character.
+type KeylessIdentity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+// IsEnforced returns true if the unverified images can not be deployed
+func (p *ImageSignaturePolicy) IsEnforced() bool {
+	return p.Mode != ImageSignatureModeAudit
+}
+
+// Match returns true if the image is verified by the policy
+func (p *ImageSignaturePolicy) Match(image string) bool {
+	if image == "" {
+		return false
+	}
+	if strings.TrimSpace(p.Images) == "" {
+		return true
+	}
+	// the tag or the digest is not required by the pattern
+	name := image
+	if i := strings.Index(name, "@"); i > 0 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	for _, pattern := range strings.Split(p.Images, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if matchImage(pattern, image) || matchImage(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchImage matches the image with the pattern segment by segment. A `*` in a
+// segment does not match `/`, but a `**` segment matches any number of segments,
+// and a trailing `*` segment matches the nested repositories too, so that
+// `registry.example.com/team/*` covers `registry.example.com/team/app/api`.
+func matchImage(pattern, image string) bool {
+	patterns := strings.Split(pattern, "/")
+	if last := len(patterns) - 1; patterns[last] == "*" {
+		patterns[last] = "**"
+	}
+	return matchSegments(patterns, strings.Split(image, "/"))
+}
+
+func matchSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			// `**` matches at least one segment
+			for i := 1; i <= len(segments); i++ {
+				if matchSegments(patterns[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], segments[0]); !ok {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// KeylessIdentities returns the keyless identities of the policy
+func (p *ImageSignaturePolicy) KeylessIdentities() ([]KeylessIdentity, error) {
+	if strings.TrimSpace(p.Identities) == "" {
+		return nil, nil
+	}
+	var identities []KeylessIdentity
+	if err := json.Unmarshal([]byte(p.Identities), &identities); err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// ImageSignaturePolicies the cluster policy and the policy of the tenant env. The
+// cluster policy is the floor, the images it matches are verified with its own keys
+// and mode whatever the tenant env policy is.
+type ImageSignaturePolicies []*ImageSignaturePolicy
+
+// Match returns the policies verifying the image
+func (ps ImageSignaturePolicies) Match(image string) ImageSignaturePolicies {
+	var matched ImageSignaturePolicies
+	for _, p := range ps {
+		if p.Match(image) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// IsEnforced returns true if one of the policies is enforced
+func (ps ImageSignaturePolicies) IsEnforced() bool {
+	for _, p := range ps {
+		if p.IsEnforced() {
+			return true
+		}
+	}
+	return false
+}
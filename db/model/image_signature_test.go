@@ -0,0 +1,36 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2019 Wutong Co., Ltd.
+
+package model
+
+import "testing"
+
+func TestImageSignaturePolicyMatch(t *testing.T) {
+	tests := []struct {
+		images, image string
+		want          bool
+	}{
+		{"", "nginx:1.25", true},
+		{"docker.io/library/nginx", "docker.io/library/nginx:1.25", true},
+		{"registry.example.com/team/*", "registry.example.com/team/app:v1", true},
+		// the nested repositories are enforced too
+		{"registry.example.com/team/*", "registry.example.com/team/app/api:v1", true},
+		{"registry.example.com/team/*", "registry.example.com/team/app/api@sha256:abc", true},
+		{"registry.example.com/**/api", "registry.example.com/team/app/api:v1", true},
+		{"registry.example.com/**/api", "registry.example.com/team/app/web:v1", false},
+		{"registry.example.com/team/app-*", "registry.example.com/team/app-api:v1", true},
+		{"registry.example.com/team/app-*", "registry.example.com/team/app-api/sub:v1", false},
+		{"registry.example.com/team/*", "registry.example.com/other/app:v1", false},
+		{"registry.example.com/team/*", "registry.example.com/team:v1", false},
+	}
+	for _, tc := range tests {
+		p := &ImageSignaturePolicy{Images: tc.images}
+		if got := p.Match(tc.image); got != tc.want {
+			t.Errorf("policy %q match %q = %v, want %v", tc.images, tc.image, got, tc.want)
+		}
+	}
+	policies := ImageSignaturePolicies{{Images: "registry.example.com/team/*", Mode: ImageSignatureModeEnforce}}
+	if !policies.Match("registry.example.com/team/app/api:v1").IsEnforced() {
+		t.Error("the nested repository should be enforced")
+	}
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ReleaseStrategyCanary shifts the traffic to the new version step by step
+	ReleaseStrategyCanary = "canary"
+	// ReleaseStrategyBlueGreen switches all traffic to the new version at once
+	ReleaseStrategyBlueGreen = "bluegreen"
+)
+
+const (
+	// ReleaseStatusPending the release is waiting for the worker
+	ReleaseStatusPending = "pending"
+	// ReleaseStatusProgressing the traffic is shifting to the new version
+	ReleaseStatusProgressing = "progressing"
+	// ReleaseStatusPaused all steps are passed, waiting for the manual promotion
+	ReleaseStatusPaused = "paused"
+	// ReleaseStatusPromoting the new version is taking the place of the old version
+	ReleaseStatusPromoting = "promoting"
+	// ReleaseStatusPromoted the new version is the running version
+	ReleaseStatusPromoted = "promoted"
+	// ReleaseStatusRollingBack the traffic is going back to the old version
+	ReleaseStatusRollingBack = "rollingback"
+	// ReleaseStatusRolledBack the old version is kept
+	ReleaseStatusRolledBack = "rolledback"
+	// ReleaseStatusFailed the release is interrupted by an error
+	ReleaseStatusFailed = "failed"
+)
+
+const (
+	// ReleaseCommandPromote promotes the new version without the remaining steps
+	ReleaseCommandPromote = "promote"
+	// ReleaseCommandAbort rolls back to the old version
+	ReleaseCommandAbort = "abort"
+)
+
+// ServiceRelease the release of a new deploy version running side by side with the
+// old one, the gateway traffic is shifted to the new version by steps and each step
+// is gated by the prometheus analyses.
+type ServiceRelease struct {
+	Model
+	ReleaseID   string `gorm:"column:release_id;size:32;unique_index" json:"release_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32" json:"tenant_env_id"`
+	ServiceID   string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	EventID     string `gorm:"column:event_id;size:32" json:"event_id"`
+	// Strategy canary or bluegreen
+	Strategy      string `gorm:"column:strategy;size:20" json:"strategy"`
+	StableVersion string `gorm:"column:stable_version;size:40" json:"stable_version"`
+	CanaryVersion string `gorm:"column:canary_version;size:40" json:"canary_version"`
+	// CanaryReplicas the replicas of the new version before the promotion
+	CanaryReplicas int `gorm:"column:canary_replicas" json:"canary_replicas"`
+	// Steps the traffic percentages of the new version, separated by comma
+	Steps string `gorm:"column:steps;size:255" json:"steps"`
+	// StepInterval the seconds to wait before analyzing a step
+	StepInterval int `gorm:"column:step_interval" json:"step_interval"`
+	// NoDataTimeout the seconds a step is held at most while the analyses have no data, the
+	// release is rolled back after that. 0 holds the step until there is data, or the user
+	// promotes or aborts the release.
+	NoDataTimeout int `gorm:"column:no_data_timeout" json:"no_data_timeout"`
+	// Header the requests match the header are always routed to the new version, eg: x-canary=true
+	Header string `gorm:"column:header;size:255" json:"header"`
+	// Cookie the requests match the cookie are always routed to the new version
+	Cookie string `gorm:"column:cookie;size:255" json:"cookie"`
+	// Analyses the json encoded prometheus analyses
+	Analyses      string `gorm:"column:analyses;type:text" json:"analyses"`
+	ManualPromote bool   `gorm:"column:manual_promote" json:"manual_promote"`
+	// Command the command sent by the user to the running release, promote or abort
+	Command       string     `gorm:"column:command;size:20" json:"command"`
+	Status        string     `gorm:"column:status;size:20" json:"status"`
+	CurrentStep   int        `gorm:"column:current_step" json:"current_step"`
+	CurrentWeight int        `gorm:"column:current_weight" json:"current_weight"`
+	Message       string     `gorm:"column:message;size:2047" json:"message"`
+	FinishTime    *time.Time `gorm:"column:finish_time" json:"finish_time"`
+}
+
+// TableName returns table name of ServiceRelease
+func (ServiceRelease) TableName() string {
+	return "tenant_env_service_release"
+}
+
+// ReleaseAnalysis the prometheus query to analyze a step. The query supports the
+// variables {{namespace}}, {{service_id}}, {{stable_version}}, {{canary_version}}
+// and {{canary_workload}}, the step fails if any sample is out of the range.
+type ReleaseAnalysis struct {
+	Name  string   `json:"name"`
+	Query string   `json:"query"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// IsFinished returns true if the release is not running any more
+func (r *ServiceRelease) IsFinished() bool {
+	switch r.Status {
+	case ReleaseStatusPromoted, ReleaseStatusRolledBack, ReleaseStatusFailed:
+		return true
+	}
+	return false
+}
+
+// StepWeights returns the traffic percentages of the new version
+func (r *ServiceRelease) StepWeights() ([]int, error) {
+	if r.Strategy == ReleaseStrategyBlueGreen {
+		return []int{100}, nil
+	}
+	var weights []int
+	last := 0
+	for _, s := range strings.Split(r.Steps, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		w, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid step %s", s)
+		}
+		if w <= last || w > 100 {
+			return nil, fmt.Errorf("the steps must be increasing percentages between 1 and 100")
+		}
+		weights = append(weights, w)
+		last = w
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("the steps are required")
+	}
+	return weights, nil
+}
+
+// AnalysisList returns the prometheus analyses of the release
+func (r *ServiceRelease) AnalysisList() ([]ReleaseAnalysis, error) {
+	if strings.TrimSpace(r.Analyses) == "" {
+		return nil, nil
+	}
+	var analyses []ReleaseAnalysis
+	if err := json.Unmarshal([]byte(r.Analyses), &analyses); err != nil {
+		return nil, err
+	}
+	return analyses, nil
+}
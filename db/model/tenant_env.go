@@ -19,10 +19,12 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"github.com/wutong-paas/wutong/util/commonutil"
 )
 
@@ -68,6 +70,19 @@ type TenantEnvs struct {
 	LimitMemory int    `gorm:"column:limit_memory"`
 	Status      string `gorm:"column:status;default:'normal'"`
 	Namespace   string `gorm:"column:namespace;size:63;unique_index"`
+	// the quotas of the tenant env, 0 means unlimited
+	//LimitCPU the limit cpu of the running components, unit: millicores
+	LimitCPU int `gorm:"column:limit_cpu"`
+	//LimitEphemeralStorage the limit ephemeral storage of the pods, unit: MB
+	LimitEphemeralStorage int `gorm:"column:limit_ephemeral_storage"`
+	//LimitStorage the limit capacity of the persistent volumes, unit: GB
+	LimitStorage int `gorm:"column:limit_storage"`
+	//LimitPods the limit number of the pods
+	LimitPods int `gorm:"column:limit_pods"`
+	//LimitTCPPorts the limit number of the gateway tcp ports
+	LimitTCPPorts int `gorm:"column:limit_tcp_ports"`
+	//LimitBuilds the limit number of the concurrent builds
+	LimitBuilds int `gorm:"column:limit_builds"`
 }
 
 // TableName 返回租户表名称
@@ -636,6 +651,8 @@ type TenantEnvServiceAutoscalerRules struct {
 	XPAType     string `gorm:"column:xpa_type;size:3"`
 	MinReplicas int    `gorm:"colume:min_replicas"`
 	MaxReplicas int    `gorm:"colume:max_replicas"`
+	// Behavior the json encoded AutoscalerBehavior
+	Behavior string `gorm:"column:behavior;type:text"`
 }
 
 // TableName -
@@ -643,6 +660,61 @@ func (t *TenantEnvServiceAutoscalerRules) TableName() string {
 	return "tenant_env_services_autoscaler_rules"
 }
 
+// GetBehavior returns the scale up and scale down behavior of the rule
+func (t *TenantEnvServiceAutoscalerRules) GetBehavior() (*AutoscalerBehavior, error) {
+	if strings.TrimSpace(t.Behavior) == "" {
+		return nil, nil
+	}
+	var behavior AutoscalerBehavior
+	if err := json.Unmarshal([]byte(t.Behavior), &behavior); err != nil {
+		return nil, err
+	}
+	return &behavior, nil
+}
+
+// AutoscalerBehavior the scale up and scale down behavior of the autoscaler rule
+type AutoscalerBehavior struct {
+	ScaleUp   *AutoscalerScalingRules `json:"scale_up,omitempty"`
+	ScaleDown *AutoscalerScalingRules `json:"scale_down,omitempty"`
+}
+
+// AutoscalerScalingRules -
+type AutoscalerScalingRules struct {
+	StabilizationWindowSeconds *int32 `json:"stabilization_window_seconds,omitempty"`
+	// SelectPolicy Max, Min or Disabled
+	SelectPolicy string                    `json:"select_policy,omitempty"`
+	Policies     []AutoscalerScalingPolicy `json:"policies,omitempty"`
+}
+
+// AutoscalerScalingPolicy -
+type AutoscalerScalingPolicy struct {
+	// Type Pods or Percent
+	Type          string `json:"type"`
+	Value         int32  `json:"value"`
+	PeriodSeconds int32  `json:"period_seconds"`
+}
+
+// the metric types of the autoscaler rule
+const (
+	// XPAMetricTypeResource cpu or memory of the pods
+	XPAMetricTypeResource = "resource_metrics"
+	// XPAMetricTypePods the custom metric of the pods
+	XPAMetricTypePods = "pods_metrics"
+	// XPAMetricTypeObject the custom metric describing a kubernetes object
+	XPAMetricTypeObject = "object_metrics"
+	// XPAMetricTypeExternal the metric not related to any kubernetes object
+	XPAMetricTypeExternal = "external_metrics"
+	// XPAMetricTypePrometheus the metric queried by the promql from the platform prometheus
+	XPAMetricTypePrometheus = "prometheus_metrics"
+)
+
+// the metric target types of the autoscaler rule
+const (
+	XPATargetTypeUtilization  = "utilization"
+	XPATargetTypeAverageValue = "average_value"
+	XPATargetTypeValue        = "value"
+)
+
 // TenantEnvServiceAutoscalerRuleMetrics -
 type TenantEnvServiceAutoscalerRuleMetrics struct {
 	Model
@@ -651,6 +723,16 @@ type TenantEnvServiceAutoscalerRuleMetrics struct {
 	MetricsName       string `gorm:"column:metric_name;not null"`
 	MetricTargetType  string `gorm:"column:metric_target_type;not null"`
 	MetricTargetValue int    `gorm:"column:metric_target_value;not null"`
+	// MetricTargetQuantity the target in quantity format, eg: 500m, takes the place of MetricTargetValue if not empty
+	MetricTargetQuantity string `gorm:"column:metric_target_quantity;size:32"`
+	// MetricSelector the label selector of the custom or external metric, eg: verb=GET,code=200
+	MetricSelector string `gorm:"column:metric_selector;size:1024"`
+	// the kubernetes object described by the object metric
+	ObjectAPIVersion string `gorm:"column:object_api_version;size:64"`
+	ObjectKind       string `gorm:"column:object_kind;size:64"`
+	ObjectName       string `gorm:"column:object_name;size:253"`
+	// PromQL the query of the prometheus metric, supports the variables {{namespace}} and {{service_id}}
+	PromQL string `gorm:"column:promql;type:text"`
 }
 
 // TableName -
@@ -670,6 +752,8 @@ type TenantEnvServiceScalingRecords struct {
 	Description string    `gorm:"column:description;size:1023" json:"description"`
 	Operator    string    `gorm:"column:operator" json:"operator"`
 	LastTime    time.Time `gorm:"column:last_time" json:"last_time"`
+	// MetricName the metric triggered the scaling
+	MetricName string `gorm:"column:metric_name" json:"metric_name"`
 }
 
 // TableName -
@@ -677,6 +761,85 @@ func (t *TenantEnvServiceScalingRecords) TableName() string {
 	return "tenant_env_services_scaling_records"
 }
 
+const (
+	// ScalingRecordTypeManual the scaling is triggered by the user
+	ScalingRecordTypeManual = "manual"
+	// ScalingRecordTypeSchedule the scaling is triggered by a scheduled scaling rule
+	ScalingRecordTypeSchedule = "schedule"
+	// ScalingRecordTypeIdle the component is scaled to zero because there is no request
+	ScalingRecordTypeIdle = "idle"
+	// ScalingRecordTypeWake the component is woken up by a gateway request
+	ScalingRecordTypeWake = "wake"
+)
+
+const (
+	// ScheduledScalingActionScale scales the component to the replicas of the rule
+	ScheduledScalingActionScale = "scale"
+	// ScheduledScalingActionSleep scales the component to zero and keeps the current replicas
+	ScheduledScalingActionSleep = "sleep"
+	// ScheduledScalingActionWake restores the replicas kept by the sleep
+	ScheduledScalingActionWake = "wake"
+)
+
+// TenantEnvServiceScheduledScalingRule the cron style scaling rule, the rule applies to
+// all components of the tenant env if the service id is empty.
+type TenantEnvServiceScheduledScalingRule struct {
+	Model
+	RuleID      string `gorm:"column:rule_id;unique;size:32" json:"rule_id"`
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32;index" json:"tenant_env_id"`
+	ServiceID   string `gorm:"column:service_id;size:32;index" json:"service_id"`
+	Name        string `gorm:"column:name;size:64" json:"name"`
+	// Schedule the standard cron expression with 5 fields, eg: 0 20 * * 1-5
+	Schedule string `gorm:"column:schedule;size:64" json:"schedule"`
+	// TimeZone the IANA time zone of the schedule, defaults to the local time zone of the worker
+	TimeZone string `gorm:"column:time_zone;size:64" json:"time_zone"`
+	// Action scale, sleep or wake
+	Action           string     `gorm:"column:action;size:10" json:"action"`
+	Replicas         int        `gorm:"column:replicas" json:"replicas"`
+	Enable           bool       `gorm:"column:enable" json:"enable"`
+	LastScheduleTime *time.Time `gorm:"column:last_schedule_time" json:"last_schedule_time"`
+}
+
+// TableName -
+func (t *TenantEnvServiceScheduledScalingRule) TableName() string {
+	return "tenant_env_services_scheduled_scaling_rules"
+}
+
+// ParseSchedule parses the cron expression of the rule in the time zone of the rule
+func (t *TenantEnvServiceScheduledScalingRule) ParseSchedule() (cron.Schedule, error) {
+	spec := t.Schedule
+	if t.TimeZone != "" {
+		if _, err := time.LoadLocation(t.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone %s: %v", t.TimeZone, err)
+		}
+		spec = fmt.Sprintf("CRON_TZ=%s %s", t.TimeZone, t.Schedule)
+	}
+	return cron.ParseStandard(spec)
+}
+
+// TenantEnvServiceScaleToZero the idle scale-to-zero setting and the sleep state of the component
+type TenantEnvServiceScaleToZero struct {
+	Model
+	TenantEnvID string `gorm:"column:tenant_env_id;size:32" json:"tenant_env_id"`
+	ServiceID   string `gorm:"column:service_id;size:32;unique_index" json:"service_id"`
+	// IdleEnable scales the component to zero after IdleTimeout seconds without any gateway
+	// request, and wakes it up by the next request.
+	IdleEnable  bool `gorm:"column:idle_enable" json:"idle_enable"`
+	IdleTimeout int  `gorm:"column:idle_timeout" json:"idle_timeout"`
+	// HoldTimeout the seconds the gateway holds a request while the component is waking up
+	HoldTimeout int  `gorm:"column:hold_timeout" json:"hold_timeout"`
+	Sleeping    bool `gorm:"column:sleeping" json:"sleeping"`
+	// SleepReplicas the replicas before the sleep
+	SleepReplicas int        `gorm:"column:sleep_replicas" json:"sleep_replicas"`
+	SleepTime     *time.Time `gorm:"column:sleep_time" json:"sleep_time"`
+	WakeTime      *time.Time `gorm:"column:wake_time" json:"wake_time"`
+}
+
+// TableName -
+func (t *TenantEnvServiceScaleToZero) TableName() string {
+	return "tenant_env_services_scale_to_zero"
+}
+
 // ServiceID -
 type ServiceID struct {
 	ServiceID string `gorm:"column:service_id" json:"-"`
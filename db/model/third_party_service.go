@@ -53,6 +53,20 @@ var DiscorveryTypeEtcd DiscorveryType = "etcd"
 // DiscorveryTypeKubernetes kubernetes service
 var DiscorveryTypeKubernetes DiscorveryType = "kubernetes"
 
+// DiscorveryTypeConsul consul catalog
+var DiscorveryTypeConsul DiscorveryType = "consul"
+
+// DiscorveryTypeNacos nacos naming service
+var DiscorveryTypeNacos DiscorveryType = "nacos"
+
+// DiscorveryTypeEureka eureka registry
+var DiscorveryTypeEureka DiscorveryType = "eureka"
+
+// IsRegistry returns true if the endpoints are registered in a service registry
+func (d DiscorveryType) IsRegistry() bool {
+	return d == DiscorveryTypeConsul || d == DiscorveryTypeNacos || d == DiscorveryTypeEureka
+}
+
 func (d DiscorveryType) String() string {
 	return string(d)
 }
@@ -65,11 +79,14 @@ type ThirdPartySvcDiscoveryCfg struct {
 	ServiceID string `gorm:"column:service_id;size:32"`
 	Type      string `gorm:"column:type"`
 	Servers   string `gorm:"column:servers"`
-	Key       string `gorm:"key"`
-	Username  string `gorm:"username"`
-	Password  string `gorm:"password"`
-	//for kubernetes service
-	Namespace   string `gorm:"namespace"`
+	// Key the etcd key, the nacos group or the consul tag
+	Key      string `gorm:"key"`
+	Username string `gorm:"username"`
+	// Password the password, or the acl token for consul
+	Password string `gorm:"password"`
+	// Namespace the kubernetes namespace, the nacos namespace id or the consul datacenter
+	Namespace string `gorm:"namespace"`
+	// ServiceName the kubernetes service, or the name of the service in the registry
 	ServiceName string `gorm:"serviceName"`
 }
 
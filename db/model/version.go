@@ -52,6 +52,15 @@ type VersionInfo struct {
 	FinalStatus string    `gorm:"column:final_status;size:40" json:"final_status"`
 	FinishTime  time.Time `gorm:"column:finish_time;" json:"finish_time"`
 	PlanVersion string    `gorm:"column:plan_version;size:250" json:"plan_version"`
+	//SBOMPath the cyclonedx sbom of the delivered
+	SBOMPath string `gorm:"column:sbom_path;size:250" json:"sbom_path"`
+	//ProvenancePath the in-toto provenance statement of the build
+	ProvenancePath string `gorm:"column:provenance_path;size:250" json:"provenance_path"`
+	//SignatureStatus the image signature status verified by the image signature policy
+	//verified: the signature is trusted
+	//unverified: no signature is trusted
+	//empty: the image is not verified
+	SignatureStatus string `gorm:"column:signature_status;size:20" json:"signature_status"`
 }
 
 // TableName 表名
@@ -59,6 +68,29 @@ func (t *VersionInfo) TableName() string {
 	return "tenant_env_service_version"
 }
 
+// ArtifactFiles returns the files of the sbom and the provenance of the version
+func (t *VersionInfo) ArtifactFiles() []string {
+	var files []string
+	for _, file := range []string{t.SBOMPath, t.ProvenancePath} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// SignedImage returns the image whose signature is verified, it is the source
+// image for the image builds and the built image for the source code builds
+func (t *VersionInfo) SignedImage() string {
+	if (t.Kind == "build_from_image" || t.Kind == "build_from_market_image") && t.RepoURL != "" {
+		return t.RepoURL
+	}
+	if t.DeliveredType == "image" {
+		return t.DeliveredPath
+	}
+	return ""
+}
+
 // CreateShareImage create share image name
 func (t *VersionInfo) CreateShareImage(hubURL, namespace, appVersion string) (string, error) {
 	_, err := reference.ParseAnyReference(t.DeliveredPath)
@@ -0,0 +1,240 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+// NotificationChannelDaoImpl -
+type NotificationChannelDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create notification channel
+func (t *NotificationChannelDaoImpl) AddModel(mo model.Interface) error {
+	channel := mo.(*model.NotificationChannel)
+	return t.DB.Create(channel).Error
+}
+
+// UpdateModel update notification channel
+func (t *NotificationChannelDaoImpl) UpdateModel(mo model.Interface) error {
+	channel := mo.(*model.NotificationChannel)
+	return t.DB.Save(channel).Error
+}
+
+// GetByChannelID get notification channel by channel id
+func (t *NotificationChannelDaoImpl) GetByChannelID(channelID string) (*model.NotificationChannel, error) {
+	var channel model.NotificationChannel
+	if err := t.DB.Where("channel_id=?", channelID).Find(&channel).Error; err != nil {
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// ListByTenantEnvID list the notification channels of the tenant env
+func (t *NotificationChannelDaoImpl) ListByTenantEnvID(tenantEnvID string) ([]*model.NotificationChannel, error) {
+	var channels []*model.NotificationChannel
+	if err := t.DB.Where("tenant_env_id=?", tenantEnvID).Order("ID").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// DeleteByChannelID delete notification channel by channel id
+func (t *NotificationChannelDaoImpl) DeleteByChannelID(channelID string) error {
+	return t.DB.Where("channel_id=?", channelID).Delete(&model.NotificationChannel{}).Error
+}
+
+// AlertSilenceDaoImpl -
+type AlertSilenceDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create alert silence
+func (t *AlertSilenceDaoImpl) AddModel(mo model.Interface) error {
+	silence := mo.(*model.AlertSilence)
+	return t.DB.Create(silence).Error
+}
+
+// UpdateModel update alert silence
+func (t *AlertSilenceDaoImpl) UpdateModel(mo model.Interface) error {
+	silence := mo.(*model.AlertSilence)
+	return t.DB.Save(silence).Error
+}
+
+// GetBySilenceID get alert silence by silence id
+func (t *AlertSilenceDaoImpl) GetBySilenceID(silenceID string) (*model.AlertSilence, error) {
+	var silence model.AlertSilence
+	if err := t.DB.Where("silence_id=?", silenceID).Find(&silence).Error; err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// ListByTenantEnvID list the alert silences of the tenant env, the latest first
+func (t *AlertSilenceDaoImpl) ListByTenantEnvID(tenantEnvID string) ([]*model.AlertSilence, error) {
+	var silences []*model.AlertSilence
+	if err := t.DB.Where("tenant_env_id=?", tenantEnvID).Order("ID desc").Find(&silences).Error; err != nil {
+		return nil, err
+	}
+	return silences, nil
+}
+
+// DeleteBySilenceID delete alert silence by silence id
+func (t *AlertSilenceDaoImpl) DeleteBySilenceID(silenceID string) error {
+	return t.DB.Where("silence_id=?", silenceID).Delete(&model.AlertSilence{}).Error
+}
+
+// AlertRuleDaoImpl -
+type AlertRuleDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create alert rule
+func (t *AlertRuleDaoImpl) AddModel(mo model.Interface) error {
+	rule := mo.(*model.AlertRule)
+	return t.DB.Create(rule).Error
+}
+
+// UpdateModel update alert rule
+func (t *AlertRuleDaoImpl) UpdateModel(mo model.Interface) error {
+	rule := mo.(*model.AlertRule)
+	return t.DB.Save(rule).Error
+}
+
+// GetByRuleID get alert rule by rule id
+func (t *AlertRuleDaoImpl) GetByRuleID(ruleID string) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	if err := t.DB.Where("rule_id=?", ruleID).Find(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListByScope list the alert rules of the application or the component
+func (t *AlertRuleDaoImpl) ListByScope(appID, serviceID string) ([]*model.AlertRule, error) {
+	var rules []*model.AlertRule
+	if err := t.DB.Where("app_id=? and service_id=?", appID, serviceID).Order("ID").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeleteByRuleID delete alert rule by rule id
+func (t *AlertRuleDaoImpl) DeleteByRuleID(ruleID string) error {
+	return t.DB.Where("rule_id=?", ruleID).Delete(&model.AlertRule{}).Error
+}
+
+// DeleteByServiceID delete the alert rules of the component
+func (t *AlertRuleDaoImpl) DeleteByServiceID(serviceID string) error {
+	return t.DB.Where("service_id=?", serviceID).Delete(&model.AlertRule{}).Error
+}
+
+// DeleteByAppID delete the alert rules of the application
+func (t *AlertRuleDaoImpl) DeleteByAppID(appID string) error {
+	return t.DB.Where("app_id=? and service_id=''", appID).Delete(&model.AlertRule{}).Error
+}
+
+// ServiceSLODaoImpl -
+type ServiceSLODaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create service slo
+func (t *ServiceSLODaoImpl) AddModel(mo model.Interface) error {
+	slo := mo.(*model.ServiceSLO)
+	return t.DB.Create(slo).Error
+}
+
+// UpdateModel update service slo
+func (t *ServiceSLODaoImpl) UpdateModel(mo model.Interface) error {
+	slo := mo.(*model.ServiceSLO)
+	return t.DB.Save(slo).Error
+}
+
+// GetBySLOID get service slo by slo id
+func (t *ServiceSLODaoImpl) GetBySLOID(sloID string) (*model.ServiceSLO, error) {
+	var slo model.ServiceSLO
+	if err := t.DB.Where("slo_id=?", sloID).Find(&slo).Error; err != nil {
+		return nil, err
+	}
+	return &slo, nil
+}
+
+// ListByServiceID list the slos of the component
+func (t *ServiceSLODaoImpl) ListByServiceID(serviceID string) ([]*model.ServiceSLO, error) {
+	var slos []*model.ServiceSLO
+	if err := t.DB.Where("service_id=?", serviceID).Order("ID").Find(&slos).Error; err != nil {
+		return nil, err
+	}
+	return slos, nil
+}
+
+// DeleteBySLOID delete service slo by slo id
+func (t *ServiceSLODaoImpl) DeleteBySLOID(sloID string) error {
+	return t.DB.Where("slo_id=?", sloID).Delete(&model.ServiceSLO{}).Error
+}
+
+// DeleteByServiceID delete the slos of the component
+func (t *ServiceSLODaoImpl) DeleteByServiceID(serviceID string) error {
+	return t.DB.Where("service_id=?", serviceID).Delete(&model.ServiceSLO{}).Error
+}
+
+// DeleteByHTTPRuleID delete the slos of the http rule
+func (t *ServiceSLODaoImpl) DeleteByHTTPRuleID(httpRuleID string) error {
+	return t.DB.Where("http_rule_id=?", httpRuleID).Delete(&model.ServiceSLO{}).Error
+}
+
+// LogAlertRuleDaoImpl -
+type LogAlertRuleDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create log alert rule
+func (t *LogAlertRuleDaoImpl) AddModel(mo model.Interface) error {
+	rule := mo.(*model.LogAlertRule)
+	return t.DB.Create(rule).Error
+}
+
+// UpdateModel update log alert rule
+func (t *LogAlertRuleDaoImpl) UpdateModel(mo model.Interface) error {
+	rule := mo.(*model.LogAlertRule)
+	return t.DB.Save(rule).Error
+}
+
+// GetByRuleID get log alert rule by rule id
+func (t *LogAlertRuleDaoImpl) GetByRuleID(ruleID string) (*model.LogAlertRule, error) {
+	var rule model.LogAlertRule
+	if err := t.DB.Where("rule_id=?", ruleID).Find(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListByServiceID list the log alert rules of the component
+func (t *LogAlertRuleDaoImpl) ListByServiceID(serviceID string) ([]*model.LogAlertRule, error) {
+	var rules []*model.LogAlertRule
+	if err := t.DB.Where("service_id=?", serviceID).Order("ID").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListEnabled list the enabled log alert rules of all components
+func (t *LogAlertRuleDaoImpl) ListEnabled() ([]*model.LogAlertRule, error) {
+	var rules []*model.LogAlertRule
+	if err := t.DB.Where("enabled=?", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeleteByRuleID delete log alert rule by rule id
+func (t *LogAlertRuleDaoImpl) DeleteByRuleID(ruleID string) error {
+	return t.DB.Where("rule_id=?", ruleID).Delete(&model.LogAlertRule{}).Error
+}
+
+// DeleteByServiceID delete the log alert rules of the component
+func (t *LogAlertRuleDaoImpl) DeleteByServiceID(serviceID string) error {
+	return t.DB.Where("service_id=?", serviceID).Delete(&model.LogAlertRule{}).Error
+}
@@ -97,6 +97,83 @@ func (c *CertificateDaoImpl) GetCertificateByID(certificateID string) (*model.Ce
 	return &certificate, nil
 }
 
+// ACMEAccountDaoImpl -
+type ACMEAccountDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel adds ACME account
+func (a *ACMEAccountDaoImpl) AddModel(mo model.Interface) error {
+	account, ok := mo.(*model.ACMEAccount)
+	if !ok {
+		return fmt.Errorf("can't convert %s to %s", reflect.TypeOf(mo).String(), "*model.ACMEAccount")
+	}
+	var old model.ACMEAccount
+	if ok := a.DB.Where("directory_url = ?", account.DirectoryURL).Find(&old).RecordNotFound(); !ok {
+		return fmt.Errorf("acme account already exists based on directory(%s)", account.DirectoryURL)
+	}
+	return a.DB.Create(account).Error
+}
+
+// UpdateModel updates ACME account
+func (a *ACMEAccountDaoImpl) UpdateModel(mo model.Interface) error {
+	account, ok := mo.(*model.ACMEAccount)
+	if !ok {
+		return fmt.Errorf("failed to convert %s to *model.ACMEAccount", reflect.TypeOf(mo).String())
+	}
+	return a.DB.Save(account).Error
+}
+
+// GetByDirectoryURL gets the account registered to the ACME directory
+func (a *ACMEAccountDaoImpl) GetByDirectoryURL(directoryURL string) (*model.ACMEAccount, error) {
+	var account model.ACMEAccount
+	if err := a.DB.Where("directory_url = ?", directoryURL).Find(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ACMEChallengeDaoImpl -
+type ACMEChallengeDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel adds ACME challenge
+func (a *ACMEChallengeDaoImpl) AddModel(mo model.Interface) error {
+	challenge, ok := mo.(*model.ACMEChallenge)
+	if !ok {
+		return fmt.Errorf("can't convert %s to %s", reflect.TypeOf(mo).String(), "*model.ACMEChallenge")
+	}
+	var old model.ACMEChallenge
+	if ok := a.DB.Where("token = ?", challenge.Token).Find(&old).RecordNotFound(); !ok {
+		return fmt.Errorf("acme challenge already exists based on token(%s)", challenge.Token)
+	}
+	return a.DB.Create(challenge).Error
+}
+
+// UpdateModel updates ACME challenge
+func (a *ACMEChallengeDaoImpl) UpdateModel(mo model.Interface) error {
+	challenge, ok := mo.(*model.ACMEChallenge)
+	if !ok {
+		return fmt.Errorf("failed to convert %s to *model.ACMEChallenge", reflect.TypeOf(mo).String())
+	}
+	return a.DB.Save(challenge).Error
+}
+
+// GetByToken gets the challenge by the token
+func (a *ACMEChallengeDaoImpl) GetByToken(token string) (*model.ACMEChallenge, error) {
+	var challenge model.ACMEChallenge
+	if err := a.DB.Where("token = ?", token).Find(&challenge).Error; err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// DeleteByToken deletes the challenge by the token
+func (a *ACMEChallengeDaoImpl) DeleteByToken(token string) error {
+	return a.DB.Where("token = ?", token).Delete(&model.ACMEChallenge{}).Error
+}
+
 // RuleExtensionDaoImpl rule extension dao
 type RuleExtensionDaoImpl struct {
 	DB *gorm.DB
@@ -298,6 +375,24 @@ func (h *HTTPRuleDaoImpl) ListByCertID(certID string) ([]*model.HTTPRule, error)
 	return rules, nil
 }
 
+// ListByDomain lists the http rules of the domain
+func (h *HTTPRuleDaoImpl) ListByDomain(domain string) ([]*model.HTTPRule, error) {
+	var rules []*model.HTTPRule
+	if err := h.DB.Where("domain = ?", domain).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListAutoTLS lists the http rules whose certificates are issued by the ACME controller
+func (h *HTTPRuleDaoImpl) ListAutoTLS() ([]*model.HTTPRule, error) {
+	var rules []*model.HTTPRule
+	if err := h.DB.Where("auto_tls = ?", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
 // DeleteByComponentIDs delete http rule by component ids
 func (h *HTTPRuleDaoImpl) DeleteByComponentIDs(componentIDs []string) error {
 	return h.DB.Where("service_id in (?) ", componentIDs).Delete(&model.HTTPRule{}).Error
@@ -502,6 +597,18 @@ func (t *TCPRuleDaoTmpl) ListByServiceID(serviceID string) ([]*model.TCPRule, er
 	return rules, nil
 }
 
+// CountByComponentIDs counts the tcp rules of the components
+func (t *TCPRuleDaoTmpl) CountByComponentIDs(componentIDs []string) (int, error) {
+	if len(componentIDs) == 0 {
+		return 0, nil
+	}
+	var count int
+	if err := t.DB.Model(&model.TCPRule{}).Where("service_id in (?)", componentIDs).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // DeleteByComponentIDs delete tcp rule by component ids
 func (t *TCPRuleDaoTmpl) DeleteByComponentIDs(componentIDs []string) error {
 	return t.DB.Where("service_id in (?) ", componentIDs).Delete(&model.TCPRule{}).Error
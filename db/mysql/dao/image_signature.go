@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+// ImageSignaturePolicyDaoImpl -
+type ImageSignaturePolicyDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create image signature policy
+func (t *ImageSignaturePolicyDaoImpl) AddModel(mo model.Interface) error {
+	policy := mo.(*model.ImageSignaturePolicy)
+	return t.DB.Create(policy).Error
+}
+
+// UpdateModel update image signature policy
+func (t *ImageSignaturePolicyDaoImpl) UpdateModel(mo model.Interface) error {
+	policy := mo.(*model.ImageSignaturePolicy)
+	return t.DB.Save(policy).Error
+}
+
+// GetByTenantEnvID get the policy of the tenant env, the empty tenant env id gets the cluster policy
+func (t *ImageSignaturePolicyDaoImpl) GetByTenantEnvID(tenantEnvID string) (*model.ImageSignaturePolicy, error) {
+	var policy model.ImageSignaturePolicy
+	if err := t.DB.Where("tenant_env_id=?", tenantEnvID).Find(&policy).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetEffectivePolicies get the cluster policy and the policy of the tenant env, the tenant env
+// policy can not loosen the cluster policy so both of them are applied
+func (t *ImageSignaturePolicyDaoImpl) GetEffectivePolicies(tenantEnvID string) (model.ImageSignaturePolicies, error) {
+	var policies model.ImageSignaturePolicies
+	tenantEnvIDs := []string{""}
+	if tenantEnvID != "" {
+		tenantEnvIDs = append(tenantEnvIDs, tenantEnvID)
+	}
+	for _, id := range tenantEnvIDs {
+		policy, err := t.GetByTenantEnvID(id)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				continue
+			}
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// DeleteByTenantEnvID delete the policy of the tenant env
+func (t *ImageSignaturePolicyDaoImpl) DeleteByTenantEnvID(tenantEnvID string) error {
+	return t.DB.Where("tenant_env_id=?", tenantEnvID).Delete(&model.ImageSignaturePolicy{}).Error
+}
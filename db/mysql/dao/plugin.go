@@ -667,6 +667,15 @@ func (t *TenantEnvServicePluginRelationDaoImpl) DeleteByComponentIDs(componentID
 	return t.DB.Where("service_id in (?)", componentIDs).Delete(&model.TenantEnvServicePluginRelation{}).Error
 }
 
+// ListByComponentIDs returns the plugin relations of the components
+func (t *TenantEnvServicePluginRelationDaoImpl) ListByComponentIDs(componentIDs []string) ([]*model.TenantEnvServicePluginRelation, error) {
+	var relations []*model.TenantEnvServicePluginRelation
+	if err := t.DB.Where("service_id in (?)", componentIDs).Find(&relations).Error; err != nil {
+		return nil, err
+	}
+	return relations, nil
+}
+
 // CreateOrUpdatePluginRelsInBatch -
 func (t *TenantEnvServicePluginRelationDaoImpl) CreateOrUpdatePluginRelsInBatch(relations []*model.TenantEnvServicePluginRelation) error {
 	var objects []interface{}
@@ -0,0 +1,65 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+// ServiceReleaseDaoImpl -
+type ServiceReleaseDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create service release
+func (t *ServiceReleaseDaoImpl) AddModel(mo model.Interface) error {
+	release := mo.(*model.ServiceRelease)
+	return t.DB.Create(release).Error
+}
+
+// UpdateModel update service release, the command is only updated by UpdateCommand
+func (t *ServiceReleaseDaoImpl) UpdateModel(mo model.Interface) error {
+	release := mo.(*model.ServiceRelease)
+	return t.DB.Omit("command").Save(release).Error
+}
+
+// GetByReleaseID get service release by release id
+func (t *ServiceReleaseDaoImpl) GetByReleaseID(releaseID string) (*model.ServiceRelease, error) {
+	var release model.ServiceRelease
+	if err := t.DB.Where("release_id=?", releaseID).Find(&release).Error; err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// ListByServiceID list the releases of the service, the latest first
+func (t *ServiceReleaseDaoImpl) ListByServiceID(serviceID string) ([]*model.ServiceRelease, error) {
+	var releases []*model.ServiceRelease
+	if err := t.DB.Where("service_id=?", serviceID).Order("ID desc").Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// GetRunningByServiceID get the release of the service which is not finished
+func (t *ServiceReleaseDaoImpl) GetRunningByServiceID(serviceID string) (*model.ServiceRelease, error) {
+	var release model.ServiceRelease
+	finished := []string{model.ReleaseStatusPromoted, model.ReleaseStatusRolledBack, model.ReleaseStatusFailed}
+	if err := t.DB.Where("service_id=? and status not in (?)", serviceID, finished).Find(&release).Error; err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// ListByStatus list the releases in the status
+func (t *ServiceReleaseDaoImpl) ListByStatus(status ...string) ([]*model.ServiceRelease, error) {
+	var releases []*model.ServiceRelease
+	if err := t.DB.Where("status in (?)", status).Find(&releases).Error; err != nil {
+		return nil, err
+	}
+	return releases, nil
+}
+
+// UpdateCommand sets the command of the running release
+func (t *ServiceReleaseDaoImpl) UpdateCommand(releaseID, command string) error {
+	return t.DB.Model(&model.ServiceRelease{}).Where("release_id=?", releaseID).Update("command", command).Error
+}
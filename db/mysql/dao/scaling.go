@@ -0,0 +1,135 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/wutong-paas/wutong/db/errors"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+// TenantEnvServiceScheduledScalingRuleDaoImpl -
+type TenantEnvServiceScheduledScalingRuleDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create scheduled scaling rule
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) AddModel(mo model.Interface) error {
+	rule := mo.(*model.TenantEnvServiceScheduledScalingRule)
+	var old model.TenantEnvServiceScheduledScalingRule
+	if ok := t.DB.Where("rule_id=?", rule.RuleID).Find(&old).RecordNotFound(); !ok {
+		return errors.ErrRecordAlreadyExist
+	}
+	return t.DB.Create(rule).Error
+}
+
+// UpdateModel update scheduled scaling rule
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) UpdateModel(mo model.Interface) error {
+	rule := mo.(*model.TenantEnvServiceScheduledScalingRule)
+	return t.DB.Save(rule).Error
+}
+
+// GetByRuleID get scheduled scaling rule by rule id
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) GetByRuleID(ruleID string) (*model.TenantEnvServiceScheduledScalingRule, error) {
+	var rule model.TenantEnvServiceScheduledScalingRule
+	if err := t.DB.Where("rule_id=?", ruleID).Find(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListByServiceID list the scheduled scaling rules of the component
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) ListByServiceID(serviceID string) ([]*model.TenantEnvServiceScheduledScalingRule, error) {
+	var rules []*model.TenantEnvServiceScheduledScalingRule
+	if err := t.DB.Where("service_id=?", serviceID).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListByTenantEnvID list the scheduled scaling rules applied to the whole tenant env
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) ListByTenantEnvID(tenantEnvID string) ([]*model.TenantEnvServiceScheduledScalingRule, error) {
+	var rules []*model.TenantEnvServiceScheduledScalingRule
+	if err := t.DB.Where("tenant_env_id=? and service_id=?", tenantEnvID, "").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListEnableOnes list all enabled scheduled scaling rules
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) ListEnableOnes() ([]*model.TenantEnvServiceScheduledScalingRule, error) {
+	var rules []*model.TenantEnvServiceScheduledScalingRule
+	if err := t.DB.Where("enable=?", true).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// DeleteByRuleID delete scheduled scaling rule by rule id
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) DeleteByRuleID(ruleID string) error {
+	return t.DB.Where("rule_id=?", ruleID).Delete(&model.TenantEnvServiceScheduledScalingRule{}).Error
+}
+
+// DeleteByComponentIDs -
+func (t *TenantEnvServiceScheduledScalingRuleDaoImpl) DeleteByComponentIDs(componentIDs []string) error {
+	return t.DB.Where("service_id in (?)", componentIDs).Delete(&model.TenantEnvServiceScheduledScalingRule{}).Error
+}
+
+// TenantEnvServiceScaleToZeroDaoImpl -
+type TenantEnvServiceScaleToZeroDaoImpl struct {
+	DB *gorm.DB
+}
+
+// AddModel create scale-to-zero setting
+func (t *TenantEnvServiceScaleToZeroDaoImpl) AddModel(mo model.Interface) error {
+	setting := mo.(*model.TenantEnvServiceScaleToZero)
+	var old model.TenantEnvServiceScaleToZero
+	if ok := t.DB.Where("service_id=?", setting.ServiceID).Find(&old).RecordNotFound(); !ok {
+		return errors.ErrRecordAlreadyExist
+	}
+	return t.DB.Create(setting).Error
+}
+
+// UpdateModel update scale-to-zero setting
+func (t *TenantEnvServiceScaleToZeroDaoImpl) UpdateModel(mo model.Interface) error {
+	setting := mo.(*model.TenantEnvServiceScaleToZero)
+	return t.DB.Save(setting).Error
+}
+
+// GetByServiceID get the scale-to-zero setting of the component
+func (t *TenantEnvServiceScaleToZeroDaoImpl) GetByServiceID(serviceID string) (*model.TenantEnvServiceScaleToZero, error) {
+	var setting model.TenantEnvServiceScaleToZero
+	if err := t.DB.Where("service_id=?", serviceID).Find(&setting).Error; err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+// ListIdleEnableOnes list the components which sleep when idle
+func (t *TenantEnvServiceScaleToZeroDaoImpl) ListIdleEnableOnes() ([]*model.TenantEnvServiceScaleToZero, error) {
+	var settings []*model.TenantEnvServiceScaleToZero
+	if err := t.DB.Where("idle_enable=?", true).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SwitchSleeping saves the sleep state of the setting only if the state in db is the opposite,
+// returns false if the state is already switched by others
+func (t *TenantEnvServiceScaleToZeroDaoImpl) SwitchSleeping(setting *model.TenantEnvServiceScaleToZero) (bool, error) {
+	res := t.DB.Model(&model.TenantEnvServiceScaleToZero{}).
+		Where("ID=? and sleeping=?", setting.ID, !setting.Sleeping).
+		Updates(map[string]interface{}{
+			"sleeping":       setting.Sleeping,
+			"sleep_replicas": setting.SleepReplicas,
+			"sleep_time":     setting.SleepTime,
+			"wake_time":      setting.WakeTime,
+		})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// DeleteByComponentIDs -
+func (t *TenantEnvServiceScaleToZeroDaoImpl) DeleteByComponentIDs(componentIDs []string) error {
+	return t.DB.Where("service_id in (?)", componentIDs).Delete(&model.TenantEnvServiceScaleToZero{}).Error
+}
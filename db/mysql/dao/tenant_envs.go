@@ -80,6 +80,15 @@ func (t *TenantEnvDaoImpl) GetTenantEnvByUUIDIsExist(uuid string) bool {
 
 }
 
+// GetTenantEnvByNamespace get the tenant env by the kubernetes namespace
+func (t *TenantEnvDaoImpl) GetTenantEnvByNamespace(namespace string) (*model.TenantEnvs, error) {
+	var tenantEnv model.TenantEnvs
+	if err := t.DB.Where("namespace = ?", namespace).Find(&tenantEnv).Error; err != nil {
+		return nil, err
+	}
+	return &tenantEnv, nil
+}
+
 // GetTenantEnvIDByName 获取租户
 func (t *TenantEnvDaoImpl) GetTenantEnvIDByName(tenantName, tenantEnvName string) (*model.TenantEnvs, error) {
 	var tenantEnv model.TenantEnvs
@@ -2090,6 +2099,12 @@ func (t *TenantEnvServiceAutoscalerRuleMetricsDaoImpl) UpdateOrCreate(metric *mo
 	} else {
 		old.MetricTargetType = metric.MetricTargetType
 		old.MetricTargetValue = metric.MetricTargetValue
+		old.MetricTargetQuantity = metric.MetricTargetQuantity
+		old.MetricSelector = metric.MetricSelector
+		old.ObjectAPIVersion = metric.ObjectAPIVersion
+		old.ObjectKind = metric.ObjectKind
+		old.ObjectName = metric.ObjectName
+		old.PromQL = metric.PromQL
 		if err := t.DB.Save(&old).Error; err != nil {
 			return err
 		}
@@ -2170,6 +2185,9 @@ func (t *TenantEnvServiceScalingRecordsDaoImpl) UpdateOrCreate(new *model.Tenant
 
 	old.Count = new.Count
 	old.LastTime = new.LastTime
+	if new.MetricName != "" {
+		old.MetricName = new.MetricName
+	}
 	return t.DB.Save(&old).Error
 }
 
@@ -194,3 +194,16 @@ func (c *VersionInfoDaoImpl) ListVersionsByComponentIDs(componentIDs []string) (
 	}
 	return result, nil
 }
+
+// CountUnfinishedByComponentIDs counts the builds of the components created after since and not finished yet
+func (c *VersionInfoDaoImpl) CountUnfinishedByComponentIDs(componentIDs []string, since time.Time) (int, error) {
+	if len(componentIDs) == 0 {
+		return 0, nil
+	}
+	var count int
+	if err := c.DB.Model(&model.VersionInfo{}).Where("service_id in (?) and final_status=? and create_time>?", componentIDs, "", since).
+		Count(&count).Error; err != nil {
+		return 0, pkgerr.Wrap(err, "count unfinished versions")
+	}
+	return count, nil
+}
@@ -515,6 +515,20 @@ func (m *Manager) CertificateDaoTransactions(db *gorm.DB) dao.CertificateDao {
 	}
 }
 
+// ACMEAccountDao -
+func (m *Manager) ACMEAccountDao() dao.ACMEAccountDao {
+	return &mysqldao.ACMEAccountDaoImpl{
+		DB: m.db,
+	}
+}
+
+// ACMEChallengeDao -
+func (m *Manager) ACMEChallengeDao() dao.ACMEChallengeDao {
+	return &mysqldao.ACMEChallengeDaoImpl{
+		DB: m.db,
+	}
+}
+
 // RuleExtensionDao RuleExtensionDao
 func (m *Manager) RuleExtensionDao() dao.RuleExtensionDao {
 	return &mysqldao.RuleExtensionDaoImpl{
@@ -655,6 +669,34 @@ func (m *Manager) TenantEnvServiceScalingRecordsDaoTransactions(db *gorm.DB) dao
 	}
 }
 
+// TenantEnvServiceScheduledScalingRuleDao -
+func (m *Manager) TenantEnvServiceScheduledScalingRuleDao() dao.TenantEnvServiceScheduledScalingRuleDao {
+	return &mysqldao.TenantEnvServiceScheduledScalingRuleDaoImpl{
+		DB: m.db,
+	}
+}
+
+// TenantEnvServiceScheduledScalingRuleDaoTransactions -
+func (m *Manager) TenantEnvServiceScheduledScalingRuleDaoTransactions(db *gorm.DB) dao.TenantEnvServiceScheduledScalingRuleDao {
+	return &mysqldao.TenantEnvServiceScheduledScalingRuleDaoImpl{
+		DB: db,
+	}
+}
+
+// TenantEnvServiceScaleToZeroDao -
+func (m *Manager) TenantEnvServiceScaleToZeroDao() dao.TenantEnvServiceScaleToZeroDao {
+	return &mysqldao.TenantEnvServiceScaleToZeroDaoImpl{
+		DB: m.db,
+	}
+}
+
+// TenantEnvServiceScaleToZeroDaoTransactions -
+func (m *Manager) TenantEnvServiceScaleToZeroDaoTransactions(db *gorm.DB) dao.TenantEnvServiceScaleToZeroDao {
+	return &mysqldao.TenantEnvServiceScaleToZeroDaoImpl{
+		DB: db,
+	}
+}
+
 // TenantEnvServiceMonitorDao monitor dao
 func (m *Manager) TenantEnvServiceMonitorDao() dao.TenantEnvServiceMonitorDao {
 	return &mysqldao.TenantEnvServiceMonitorDaoImpl{
@@ -668,3 +710,73 @@ func (m *Manager) TenantEnvServiceMonitorDaoTransactions(db *gorm.DB) dao.Tenant
 		DB: db,
 	}
 }
+
+// ImageSignaturePolicyDao image signature policy dao
+func (m *Manager) ImageSignaturePolicyDao() dao.ImageSignaturePolicyDao {
+	return &mysqldao.ImageSignaturePolicyDaoImpl{
+		DB: m.db,
+	}
+}
+
+// ServiceReleaseDao service release dao
+func (m *Manager) ServiceReleaseDao() dao.ServiceReleaseDao {
+	return &mysqldao.ServiceReleaseDaoImpl{
+		DB: m.db,
+	}
+}
+
+// NotificationChannelDao notification channel dao
+func (m *Manager) NotificationChannelDao() dao.NotificationChannelDao {
+	return &mysqldao.NotificationChannelDaoImpl{
+		DB: m.db,
+	}
+}
+
+// AlertSilenceDao alert silence dao
+func (m *Manager) AlertSilenceDao() dao.AlertSilenceDao {
+	return &mysqldao.AlertSilenceDaoImpl{
+		DB: m.db,
+	}
+}
+
+// AlertRuleDao alert rule dao
+func (m *Manager) AlertRuleDao() dao.AlertRuleDao {
+	return &mysqldao.AlertRuleDaoImpl{
+		DB: m.db,
+	}
+}
+
+// AlertRuleDaoTransactions alert rule dao
+func (m *Manager) AlertRuleDaoTransactions(db *gorm.DB) dao.AlertRuleDao {
+	return &mysqldao.AlertRuleDaoImpl{
+		DB: db,
+	}
+}
+
+// ServiceSLODao service slo dao
+func (m *Manager) ServiceSLODao() dao.ServiceSLODao {
+	return &mysqldao.ServiceSLODaoImpl{
+		DB: m.db,
+	}
+}
+
+// ServiceSLODaoTransactions service slo dao
+func (m *Manager) ServiceSLODaoTransactions(db *gorm.DB) dao.ServiceSLODao {
+	return &mysqldao.ServiceSLODaoImpl{
+		DB: db,
+	}
+}
+
+// LogAlertRuleDao log alert rule dao
+func (m *Manager) LogAlertRuleDao() dao.LogAlertRuleDao {
+	return &mysqldao.LogAlertRuleDaoImpl{
+		DB: m.db,
+	}
+}
+
+// LogAlertRuleDaoTransactions log alert rule dao
+func (m *Manager) LogAlertRuleDaoTransactions(db *gorm.DB) dao.LogAlertRuleDao {
+	return &mysqldao.LogAlertRuleDaoImpl{
+		DB: db,
+	}
+}
@@ -141,6 +141,8 @@ func (m *Manager) RegisterTableModel() {
 	m.models = append(m.models, &model.ConfigGroupItem{})
 	// gateway
 	m.models = append(m.models, &model.Certificate{})
+	m.models = append(m.models, &model.ACMEAccount{})
+	m.models = append(m.models, &model.ACMEChallenge{})
 	m.models = append(m.models, &model.RuleExtension{})
 	m.models = append(m.models, &model.HTTPRule{})
 	m.models = append(m.models, &model.HTTPRuleRewrite{})
@@ -156,7 +158,16 @@ func (m *Manager) RegisterTableModel() {
 	m.models = append(m.models, &model.TenantEnvServiceAutoscalerRules{})
 	m.models = append(m.models, &model.TenantEnvServiceAutoscalerRuleMetrics{})
 	m.models = append(m.models, &model.TenantEnvServiceScalingRecords{})
+	m.models = append(m.models, &model.TenantEnvServiceScheduledScalingRule{})
+	m.models = append(m.models, &model.TenantEnvServiceScaleToZero{})
 	m.models = append(m.models, &model.TenantEnvServiceMonitor{})
+	m.models = append(m.models, &model.ImageSignaturePolicy{})
+	m.models = append(m.models, &model.ServiceRelease{})
+	m.models = append(m.models, &model.NotificationChannel{})
+	m.models = append(m.models, &model.AlertSilence{})
+	m.models = append(m.models, &model.AlertRule{})
+	m.models = append(m.models, &model.ServiceSLO{})
+	m.models = append(m.models, &model.LogAlertRule{})
 }
 
 // CheckTable check and create tables
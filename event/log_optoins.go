@@ -15,6 +15,11 @@ func GetTimeoutLoggerOption() map[string]string {
 	return map[string]string{"step": "callback", "status": "timeout"}
 }
 
+//GetCanceledLoggerOption get canceled logger
+func GetCanceledLoggerOption() map[string]string {
+	return map[string]string{"step": "callback", "status": "canceled"}
+}
+
 //GetLastLoggerOption get last logger
 func GetLastLoggerOption() map[string]string {
 	return map[string]string{"step": "last", "status": "success"}
@@ -166,7 +166,8 @@ func (s *Sub) listen(ins *dis.Instance) {
 				return err
 			}
 			if len(msgs) == 2 {
-				if string(msgs[0]) == string(db.EventMessage) || string(msgs[0]) == string(db.ServiceMonitorMessage) || string(msgs[0]) == string(db.ServiceNewMonitorMessage) {
+				if string(msgs[0]) == string(db.EventMessage) || string(msgs[0]) == string(db.ServiceMonitorMessage) || string(msgs[0]) == string(db.ServiceNewMonitorMessage) ||
+					string(msgs[0]) == string(db.ServiceAccessLogMessage) {
 					s.subMessageChan <- msgs
 				} else if string(msgs[0]) == string(db.MonitorMessage) {
 					//s.log.Debug("Receive a monitor message ", string(msgs[1]))
@@ -48,6 +48,8 @@ const (
 	ServiceNewMonitorMessage ClusterMessageType = "new_monitor_message"
 	//MonitorMessage 节点监控数据
 	MonitorMessage ClusterMessageType = "monitor"
+	//ServiceAccessLogMessage 网关访问日志
+	ServiceAccessLogMessage ClusterMessageType = "access_log"
 )
 
 type ClusterMessage struct {
@@ -41,6 +41,7 @@ type NMonitorMessageServer struct {
 	context            context.Context
 	storemanager       store.Manager
 	messageChan        chan []byte
+	accessLogChan      chan []byte
 	listenErr          chan error
 	serverLock         sync.Mutex
 	stopReceiveMessage bool
@@ -69,6 +70,10 @@ func NewNMonitorMessageServer(conf conf.NewMonitorMessageServerConf, log *logrus
 	if s.messageChan == nil {
 		return nil, errors.New("receive monitor message server can not get store message chan ")
 	}
+	s.accessLogChan = s.storemanager.AccessLogMessageChan()
+	if s.accessLogChan == nil {
+		return nil, errors.New("receive monitor message server can not get store access log chan ")
+	}
 	return s, nil
 }
 
@@ -97,6 +102,16 @@ func (s *NMonitorMessageServer) handleMessage() {
 		// fix issues https://github.com/golang/go/issues/35725
 		message := make([]byte, n)
 		copy(message, buf[0:n])
+		// the gateway sends the access logs to the same port by syslog
+		if store.IsAccessLog(message) {
+			select {
+			case s.accessLogChan <- message:
+			default:
+				// the access logs are dropped instead of blocking the monitor messages if the store is busy
+				s.log.Debug("access log chan is full, drop the message")
+			}
+			continue
+		}
 		s.messageChan <- message
 	}
 }
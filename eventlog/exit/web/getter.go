@@ -19,10 +19,13 @@
 package web
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/wutong-paas/wutong/eventlog/store"
 	httputil "github.com/wutong-paas/wutong/util/http"
 )
 
@@ -36,3 +39,66 @@ func (s *SocketServer) getDockerLogs(w http.ResponseWriter, r *http.Request) {
 	loglist := s.storemanager.GetDockerLogs(serviceID, rows)
 	httputil.ReturnSuccess(r, w, loglist)
 }
+
+// getAccessLogs get history gateway access logs, the new access logs are streamed if follow is true
+func (s *SocketServer) getAccessLogs(w http.ResponseWriter, r *http.Request) {
+	rows, _ := strconv.Atoi(r.URL.Query().Get("rows"))
+	serviceID := chi.URLParam(r, "serviceID")
+	if rows == 0 {
+		rows = 100
+	}
+	filter := store.AccessLogFilter{
+		RuleID: r.URL.Query().Get("rule_id"),
+		Status: r.URL.Query().Get("status"),
+	}
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	var loglist []string
+	for _, line := range s.storemanager.GetAccessLogs(serviceID, rows) {
+		if filter.Match(line) {
+			loglist = append(loglist, line)
+		}
+	}
+	if !follow {
+		httputil.ReturnSuccess(r, w, loglist)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httputil.ReturnError(r, w, 400, "streaming is not supported")
+		return
+	}
+	subID := uuid.New().String()
+	ch := s.storemanager.WebSocketMessageChan("access", serviceID, subID)
+	if ch == nil {
+		httputil.ReturnError(r, w, 500, "get access log chan failure")
+		return
+	}
+	defer s.storemanager.ReleaseWebSocketMessageChan("access", serviceID, subID)
+	// the access logs are streamed as json lines
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	for _, line := range loglist {
+		fmt.Fprintln(w, line)
+	}
+	flusher.Flush()
+	for {
+		select {
+		case message, ok := <-ch:
+			if !ok {
+				return
+			}
+			if message == nil || !filter.Match(message.Message) {
+				continue
+			}
+			if _, err := fmt.Fprintln(w, message.Message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-s.context.Done():
+			return
+		}
+	}
+}
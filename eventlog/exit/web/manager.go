@@ -472,6 +472,7 @@ func (s *SocketServer) listen() {
 	// new websocket pubsub
 	r.Get("/services/{serviceID}/pubsub", s.pubsub)
 	r.Get("/tenants/{tenant_name}/envs/{tenantEnvName}/services/{serviceID}/logs", s.getDockerLogs)
+	r.Get("/tenants/{tenant_name}/envs/{tenantEnvName}/services/{serviceID}/access-logs", s.getAccessLogs)
 	//monitor setting
 	// s.prometheus(r)
 	//pprof debug
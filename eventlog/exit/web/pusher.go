@@ -139,6 +139,9 @@ func (p *PubContext) createChan(channel, chantype, id string) {
 				if chantype == "event" {
 					return "event:log"
 				}
+				if chantype == "access" {
+					return "service:access-log"
+				}
 				return ""
 			}(),
 			p: p,
@@ -193,6 +196,9 @@ func (p *PubContext) handleSubscribe(wm WebsocketMessage) {
 		if channelInfo[0] == "e" {
 			p.createChan(channel, "event", channelInfo[1])
 		}
+		if channelInfo[0] == "a" {
+			p.createChan(channel, "access", channelInfo[1])
+		}
 	}
 }
 
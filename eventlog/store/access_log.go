@@ -0,0 +1,95 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/pquerna/ffjson/ffjson"
+)
+
+// AccessLog the access log of the gateway, it is formatted to json by the nginx log_format wutong_access
+type AccessLog struct {
+	Time          string `json:"time"`
+	ServiceID     string `json:"service_id"`
+	RuleID        string `json:"rule_id"`
+	Host          string `json:"host"`
+	Method        string `json:"method"`
+	URI           string `json:"uri"`
+	Status        string `json:"status"`
+	BodyBytesSent string `json:"body_bytes_sent"`
+	RequestTime   string `json:"request_time"`
+	UpstreamAddr  string `json:"upstream_addr"`
+	RemoteAddr    string `json:"remote_addr"`
+	UserAgent     string `json:"user_agent"`
+}
+
+// accessLogTag the syslog tag of the access logs, the monitor messages received by the
+// same udp server are json
+var accessLogTag = []byte(" wutong_access: ")
+
+// IsAccessLog returns whether the udp message is an access log sent by the syslog of the gateway
+func IsAccessLog(msg []byte) bool {
+	return len(msg) > 0 && msg[0] == '<' && bytes.Contains(msg, accessLogTag)
+}
+
+// parseAccessLog parses the access log sent by the nginx syslog, such as
+// `<190>Oct 17 10:00:00 wutong_access: {"service_id":"..."}`, it returns the json line of the log.
+func parseAccessLog(msg []byte) (*AccessLog, []byte, error) {
+	index := bytes.IndexByte(msg, '{')
+	if index < 0 {
+		return nil, nil, errors.New("there is no json in the access log")
+	}
+	line := bytes.TrimSpace(msg[index:])
+	var log AccessLog
+	if err := ffjson.Unmarshal(line, &log); err != nil {
+		return nil, nil, err
+	}
+	if log.ServiceID == "" {
+		return nil, nil, errors.New("the service_id is not present in the access log")
+	}
+	return &log, line, nil
+}
+
+// AccessLogFilter filters the access logs of a component
+type AccessLogFilter struct {
+	RuleID string
+	// Status the status code such as 502, or the status class such as 5xx
+	Status string
+}
+
+// Match returns whether the json line of the access log matches the filter
+func (f AccessLogFilter) Match(line string) bool {
+	if f.RuleID == "" && f.Status == "" {
+		return true
+	}
+	var log AccessLog
+	if err := ffjson.Unmarshal([]byte(line), &log); err != nil {
+		return false
+	}
+	if f.RuleID != "" && log.RuleID != f.RuleID {
+		return false
+	}
+	if f.Status == "" || f.Status == log.Status {
+		return true
+	}
+	return len(f.Status) == 3 && strings.HasSuffix(f.Status, "xx") && len(log.Status) == 3 && f.Status[0] == log.Status[0]
+}
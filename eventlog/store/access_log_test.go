@@ -0,0 +1,72 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import "testing"
+
+func TestParseAccessLog(t *testing.T) {
+	msg := []byte(`<190>Oct 17 10:00:00 wutong_access: {"service_id":"sid","rule_id":"rid","status":"502"}` + "\n")
+	log, line, err := parseAccessLog(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.ServiceID != "sid" || log.RuleID != "rid" || log.Status != "502" {
+		t.Errorf("parseAccessLog() = %+v", log)
+	}
+	if string(line) != `{"service_id":"sid","rule_id":"rid","status":"502"}` {
+		t.Errorf("parseAccessLog() line = %s", line)
+	}
+	for _, msg := range []string{`<190>Oct 17 10:00:00 wutong_access: GET /`, `{"rule_id":"rid"}`} {
+		if _, _, err := parseAccessLog([]byte(msg)); err == nil {
+			t.Errorf("parseAccessLog(%s) expect an error", msg)
+		}
+	}
+}
+
+func TestIsAccessLog(t *testing.T) {
+	if !IsAccessLog([]byte(`<190>Oct 17 10:00:00 wutong_access: {"service_id":"sid"}`)) {
+		t.Error("expected the syslog message to be an access log")
+	}
+	for _, msg := range []string{`[{"ServiceID":"sid","Key":"/","Count":1}]`, `<190>Oct 17 10:00:00 other: {}`, ``} {
+		if IsAccessLog([]byte(msg)) {
+			t.Errorf("expected %q not to be an access log", msg)
+		}
+	}
+}
+
+func TestAccessLogFilter(t *testing.T) {
+	line := `{"service_id":"sid","rule_id":"rid","status":"502"}`
+	tests := []struct {
+		filter AccessLogFilter
+		want   bool
+	}{
+		{filter: AccessLogFilter{}, want: true},
+		{filter: AccessLogFilter{RuleID: "rid"}, want: true},
+		{filter: AccessLogFilter{RuleID: "other"}, want: false},
+		{filter: AccessLogFilter{Status: "502"}, want: true},
+		{filter: AccessLogFilter{Status: "5xx"}, want: true},
+		{filter: AccessLogFilter{Status: "4xx"}, want: false},
+		{filter: AccessLogFilter{RuleID: "rid", Status: "200"}, want: false},
+	}
+	for _, tc := range tests {
+		if got := tc.filter.Match(line); got != tc.want {
+			t.Errorf("%+v.Match() = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
@@ -46,6 +46,8 @@ type dockerLogStore struct {
 	barrelSize   int
 	barrelEvent  chan []string
 	allLogCount  float64 //ues to pometheus monitor
+	// logRules counts the log lines matching the log alert rules, nil if the store does not store the container logs
+	logRules *logRuleCounter
 }
 
 func (d *dockerLogStore) Scrape(ch chan<- prometheus.Metric, namespace, exporter, from string) error {
@@ -61,7 +63,9 @@ func (d *dockerLogStore) Scrape(ch chan<- prometheus.Metric, namespace, exporter
 		[]string{"from"}, nil,
 	)
 	ch <- prometheus.MustNewConstMetric(logDesc, prometheus.GaugeValue, d.allLogCount, from)
-
+	if d.logRules != nil {
+		d.logRules.Scrape(ch, namespace, exporter, from)
+	}
 	return nil
 }
 
@@ -81,6 +85,9 @@ func (d *dockerLogStore) InsertMessage(message *db.EventLogMessage) {
 	}
 	d.LogSize++
 	d.allLogCount++
+	if d.logRules != nil {
+		d.logRules.match(message.EventID, containerLogLine(message.Content))
+	}
 	if ok := d.insertMessage(message); ok {
 		return
 	}
@@ -128,6 +135,9 @@ func (d *dockerLogStore) ReleaseSubChan(eventID, subID string) {
 func (d *dockerLogStore) Run() {
 	go d.Gc()
 	go d.handleBarrelEvent()
+	if d.logRules != nil {
+		go d.logRules.run(d.ctx)
+	}
 }
 
 func (d *dockerLogStore) GetMonitorData() *db.MonitorData {
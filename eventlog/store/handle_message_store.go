@@ -291,7 +291,9 @@ func (h *handleMessageStore) handleBarrelEvent() {
 						continue
 					}
 					event.Status = status
-					if strings.Contains(event.FinalStatus, model.EventFinalStatusEmpty.String()) {
+					if status == model.EventFinalStatusCanceled.String() {
+						event.FinalStatus = model.EventFinalStatusCanceled.String()
+					} else if strings.Contains(event.FinalStatus, model.EventFinalStatusEmpty.String()) {
 						event.FinalStatus = model.EventFinalStatusEmptyComplete.String()
 					} else {
 						event.FinalStatus = model.EventFinalStatusComplete.String()
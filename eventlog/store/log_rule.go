@@ -0,0 +1,255 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	cdb "github.com/wutong-paas/wutong/db"
+	"github.com/wutong-paas/wutong/db/model"
+	"golang.org/x/net/context"
+)
+
+// the notification events raised by the log alert rules
+const (
+	logRuleEventKind       = "service"
+	logRuleEventType       = "UnNormal"
+	logRuleEventMessageMax = 200
+	logRuleReloadInterval  = 30 * time.Second
+)
+
+// logRuleCounter counts the container log lines matching the log alert rules of the components,
+// and raises a notification event when the matches of a rule reach the threshold within the window.
+type logRuleCounter struct {
+	log       *logrus.Entry
+	dbmanager cdb.Manager
+	rwLock    sync.RWMutex
+	// rules the enabled rules by the service id
+	rules  map[string][]*logRule
+	events chan *logRuleEvent
+	now    func() time.Time
+}
+
+type logRule struct {
+	rule  *model.LogAlertRule
+	match func(line []byte) bool
+	lock  sync.Mutex
+	// total the matches since the rule is loaded
+	total float64
+	// the matches are counted in the fixed windows
+	windowStart time.Time
+	windowCount int
+}
+
+// logRuleEvent the matches of the rule reach the threshold within the window
+type logRuleEvent struct {
+	rule  *model.LogAlertRule
+	count int
+	line  string
+	time  time.Time
+}
+
+func newLogRuleCounter(log *logrus.Entry, dbmanager cdb.Manager) *logRuleCounter {
+	return &logRuleCounter{
+		log:       log,
+		dbmanager: dbmanager,
+		rules:     make(map[string][]*logRule),
+		events:    make(chan *logRuleEvent, 100),
+		now:       time.Now,
+	}
+}
+
+// run reloads the rules periodically and raises the notification events
+func (c *logRuleCounter) run(ctx context.Context) {
+	if err := c.reload(); err != nil {
+		c.log.Errorf("load the log alert rules: %v", err)
+	}
+	ticker := time.NewTicker(logRuleReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.reload(); err != nil {
+				c.log.Errorf("reload the log alert rules: %v", err)
+			}
+		case event := <-c.events:
+			if err := c.raise(event); err != nil {
+				c.log.Errorf("raise the notification event of the log alert rule %s: %v", event.rule.RuleID, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload loads the enabled rules, the counters of the unchanged rules are kept
+func (c *logRuleCounter) reload() error {
+	rules, err := c.dbmanager.LogAlertRuleDao().ListEnabled()
+	if err != nil {
+		return err
+	}
+	c.rwLock.RLock()
+	old := make(map[string]*logRule)
+	for _, serviceRules := range c.rules {
+		for _, r := range serviceRules {
+			old[r.rule.RuleID] = r
+		}
+	}
+	c.rwLock.RUnlock()
+
+	loaded := make(map[string][]*logRule)
+	for _, rule := range rules {
+		if r, ok := old[rule.RuleID]; ok && sameLogRule(r.rule, rule) {
+			loaded[rule.ServiceID] = append(loaded[rule.ServiceID], r)
+			continue
+		}
+		match, err := rule.Matcher()
+		if err != nil {
+			c.log.Warningf("skip the log alert rule %s: %v", rule.RuleID, err)
+			continue
+		}
+		loaded[rule.ServiceID] = append(loaded[rule.ServiceID], &logRule{rule: rule, match: match})
+	}
+	c.rwLock.Lock()
+	c.rules = loaded
+	c.rwLock.Unlock()
+	return nil
+}
+
+func sameLogRule(a, b *model.LogAlertRule) bool {
+	return a.Name == b.Name && a.MatchType == b.MatchType && a.Pattern == b.Pattern &&
+		a.Threshold == b.Threshold && a.WindowSeconds == b.WindowSeconds
+}
+
+// match counts the log line of the component
+func (c *logRuleCounter) match(serviceID string, line []byte) {
+	c.rwLock.RLock()
+	rules := c.rules[serviceID]
+	c.rwLock.RUnlock()
+	for _, r := range rules {
+		if !r.match(line) {
+			continue
+		}
+		event := r.inc(c.now(), line)
+		if event == nil {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			c.log.Warningf("too many notification events of the log alert rules, drop the event of the rule %s", r.rule.RuleID)
+		}
+	}
+}
+
+// inc counts a match, returns the event if the matches in the window reach the threshold,
+// the event is raised once in a window.
+func (r *logRule) inc(now time.Time, line []byte) *logRuleEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.total++
+	if now.Sub(r.windowStart) >= time.Duration(r.rule.WindowSeconds)*time.Second {
+		r.windowStart = now
+		r.windowCount = 0
+	}
+	r.windowCount++
+	if r.windowCount != r.rule.Threshold {
+		return nil
+	}
+	return &logRuleEvent{rule: r.rule, count: r.windowCount, line: string(line), time: now}
+}
+
+// raise creates or updates the notification event of the rule
+func (c *logRuleCounter) raise(e *logRuleEvent) error {
+	hash := "log-alert-rule-" + e.rule.RuleID
+	message := truncateRunes(fmt.Sprintf("%d log lines matched in %ds: %s", e.count, e.rule.WindowSeconds, bytes.TrimSpace([]byte(e.line))), logRuleEventMessageMax)
+	event, err := c.dbmanager.NotificationEventDao().GetNotificationEventByHash(hash)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == gorm.ErrRecordNotFound {
+		event = &model.NotificationEvent{
+			Kind:   logRuleEventKind,
+			KindID: e.rule.ServiceID,
+			Hash:   hash,
+		}
+		if service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(e.rule.ServiceID); err == nil {
+			event.ServiceName = service.ServiceAlias
+		}
+		if tenantEnv, err := c.dbmanager.TenantEnvDao().GetTenantEnvByUUID(e.rule.TenantEnvID); err == nil {
+			event.TenantEnvName = tenantEnv.Name
+		}
+	}
+	event.Type = logRuleEventType
+	event.Reason = truncateRunes(e.rule.Name, logRuleEventMessageMax)
+	event.Message = message
+	event.Count++
+	event.LastTime = e.time
+	// the rule fires again, it needs to be handled again
+	event.IsHandle = false
+	if event.ID == 0 {
+		return c.dbmanager.NotificationEventDao().AddModel(event)
+	}
+	return c.dbmanager.NotificationEventDao().UpdateModel(event)
+}
+
+// Scrape exports the matches of the rules
+func (c *logRuleCounter) Scrape(ch chan<- prometheus.Metric, namespace, exporter, from string) {
+	desc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, exporter, "container_log_rule_match_count"),
+		"the container log lines matching the log alert rule.",
+		[]string{"from", "service_id", "rule_id", "rule_name"}, nil,
+	)
+	c.rwLock.RLock()
+	defer c.rwLock.RUnlock()
+	for serviceID, rules := range c.rules {
+		for _, r := range rules {
+			r.lock.Lock()
+			total := r.total
+			r.lock.Unlock()
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, total, from, serviceID, r.rule.RuleID, r.rule.Name)
+		}
+	}
+}
+
+// containerLogLine returns the log line of the container log message, which is prefixed
+// with the container id, and the log time in the v2 log archivement
+func containerLogLine(content []byte) []byte {
+	if bytes.HasPrefix(content, []byte("v2:")) && len(content) > 23 {
+		content = content[23:]
+	}
+	if len(content) > 12 && content[12] == ':' {
+		return content[13:]
+	}
+	return content
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
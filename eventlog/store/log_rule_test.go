@@ -0,0 +1,102 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+func TestContainerLogLine(t *testing.T) {
+	tests := map[string]string{
+		"v2:1712710226503215565 0123456789ab:level=error oops": "level=error oops",
+		"0123456789ab:java.lang.OutOfMemoryError":              "java.lang.OutOfMemoryError",
+		"short": "short",
+	}
+	for content, want := range tests {
+		if got := string(containerLogLine([]byte(content))); got != want {
+			t.Errorf("want %q, got %q", want, got)
+		}
+	}
+}
+
+func newTestLogRuleCounter(t *testing.T, rule *model.LogAlertRule) *logRuleCounter {
+	match, err := rule.Matcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newLogRuleCounter(logrus.WithField("module", "test"), nil)
+	c.rules[rule.ServiceID] = []*logRule{{rule: rule, match: match}}
+	return c
+}
+
+func TestLogRuleCounterMatch(t *testing.T) {
+	rule := &model.LogAlertRule{RuleID: "rid", ServiceID: "sid", Name: "error", MatchType: model.LogMatchTypeRegex, Pattern: `level=(error|fatal)`, Threshold: 2, WindowSeconds: 60}
+	c := newTestLogRuleCounter(t, rule)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.match("sid", []byte("level=info ok"))
+	c.match("other", []byte("level=error oops"))
+	c.match("sid", []byte("level=error oops"))
+	if len(c.events) != 0 {
+		t.Fatalf("want no event under the threshold, got %d", len(c.events))
+	}
+	c.match("sid", []byte("level=fatal boom"))
+	c.match("sid", []byte("level=error again"))
+	if len(c.events) != 1 {
+		t.Fatalf("want one event in the window, got %d", len(c.events))
+	}
+	event := <-c.events
+	if event.count != 2 || event.line != "level=fatal boom" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	// the window is reset
+	now = now.Add(time.Minute)
+	c.match("sid", []byte("level=error"))
+	c.match("sid", []byte("level=error"))
+	if len(c.events) != 1 {
+		t.Fatalf("want one event in the next window, got %d", len(c.events))
+	}
+	if total := c.rules["sid"][0].total; total != 5 {
+		t.Errorf("want total 5, got %v", total)
+	}
+}
+
+func TestLogRuleCounterScrape(t *testing.T) {
+	rule := &model.LogAlertRule{RuleID: "rid", ServiceID: "sid", Name: "oom", MatchType: model.LogMatchTypeKeyword, Pattern: "OutOfMemoryError", Threshold: 10, WindowSeconds: 60}
+	c := newTestLogRuleCounter(t, rule)
+	c.match("sid", []byte("java.lang.OutOfMemoryError: Java heap space"))
+
+	ch := make(chan prometheus.Metric, 1)
+	c.Scrape(ch, "eventlog", "store", "local")
+	var metric dto.Metric
+	if err := (<-ch).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	if metric.GetCounter().GetValue() != 1 {
+		t.Errorf("want 1 match, got %v", metric.GetCounter().GetValue())
+	}
+}
@@ -51,6 +51,8 @@ type Manager interface {
 	PubMessageChan() chan [][]byte
 	DockerLogMessageChan() chan []byte
 	GetDockerLogs(serviceID string, length int) []string
+	AccessLogMessageChan() chan []byte
+	GetAccessLogs(serviceID string, length int) []string
 	MonitorMessageChan() chan [][]byte
 	WebSocketMessageChan(mode, eventID, subID string) chan *db.EventLogMessage
 	NewMonitorMessageChan() chan []byte
@@ -73,6 +75,10 @@ func NewManager(conf conf.EventStoreConf, log *logrus.Entry) (Manager, error) {
 	if err != nil {
 		return nil, err
 	}
+	accessFilePlugin, err := db.NewManager("file", filepath.Join(conf.DB.HomePath, accessLogDir))
+	if err != nil {
+		return nil, err
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	storeManager := &storeManager{
 		cancel:                cancel,
@@ -85,18 +91,24 @@ func NewManager(conf conf.EventStoreConf, log *logrus.Entry) (Manager, error) {
 		dockerLogChan:         make(chan []byte, 2048),  // docker日志
 		monitorMessageChan:    make(chan [][]byte, 100), // 监控消息
 		newmonitorMessageChan: make(chan []byte, 2048),
+		accessLogChan:         make(chan []byte, 2048), // 网关访问日志
 		chanCacheSize:         100,
 		eventfilePlugin:       eventfilePlugin,
 		filePlugin:            filePlugin,
+		accessFilePlugin:      accessFilePlugin,
 		errChan:               make(chan error),
 	}
 	storeManager.handleMessageStore = NewStore("handle", storeManager)
 	storeManager.readMessageStore = NewStore("read", storeManager)
 	storeManager.dockerLogStore = NewStore("docker_log", storeManager)
 	storeManager.newmonitorMessageStore = NewStore("newmonitor", storeManager)
+	storeManager.accessLogStore = NewStore("access_log", storeManager)
 	return storeManager, nil
 }
 
+// accessLogDir the directory of the gateway access logs in the log home path
+const accessLogDir = "access"
+
 type storeManager struct {
 	cancel                 func()
 	context                context.Context
@@ -104,16 +116,19 @@ type storeManager struct {
 	readMessageStore       MessageStore
 	dockerLogStore         MessageStore
 	newmonitorMessageStore MessageStore
+	accessLogStore         MessageStore
 	receiveChan            chan []byte
 	pubChan, subChan       chan [][]byte
 	dockerLogChan          chan []byte
 	monitorMessageChan     chan [][]byte
 	newmonitorMessageChan  chan []byte
+	accessLogChan          chan []byte
 	chanCacheSize          int
 	conf                   conf.EventStoreConf
 	log                    *logrus.Entry
 	eventfilePlugin        db.Manager
 	filePlugin             db.Manager
+	accessFilePlugin       db.Manager
 	errChan                chan error
 }
 
@@ -124,7 +139,8 @@ func (s *storeManager) HealthCheck() map[string]string {
 	dockerLogChan := len(s.dockerLogChan) == 2048
 	monitorMessageChan := len(s.monitorMessageChan) == 100
 	newmonitorMessageChan := len(s.newmonitorMessageChan) == 2048
-	if receiveChan || pubChan || subChan || dockerLogChan || monitorMessageChan || newmonitorMessageChan {
+	accessLogChan := len(s.accessLogChan) == 2048
+	if receiveChan || pubChan || subChan || dockerLogChan || monitorMessageChan || newmonitorMessageChan || accessLogChan {
 		return map[string]string{"status": "unusual", "info": "channel blockage"}
 	}
 	return map[string]string{"status": "health", "info": "eventlog service health"}
@@ -159,6 +175,7 @@ func (s *storeManager) Scrape(ch chan<- prometheus.Metric, namespace, exporter,
 	ch <- prometheus.MustNewConstMetric(chanDesc, prometheus.GaugeValue, float64(len(s.dockerLogChan)), from, "container_log")
 	ch <- prometheus.MustNewConstMetric(chanDesc, prometheus.GaugeValue, float64(len(s.monitorMessageChan)), from, "monitor_message")
 	ch <- prometheus.MustNewConstMetric(chanDesc, prometheus.GaugeValue, float64(len(s.receiveChan)), from, "event_message")
+	ch <- prometheus.MustNewConstMetric(chanDesc, prometheus.GaugeValue, float64(len(s.accessLogChan)), from, "access_log")
 	ch <- prometheus.MustNewConstMetric(healthDesc, prometheus.GaugeValue, healthStatus, "eventlog")
 	return nil
 }
@@ -221,6 +238,13 @@ func (s *storeManager) NewMonitorMessageChan() chan []byte {
 	return s.newmonitorMessageChan
 }
 
+func (s *storeManager) AccessLogMessageChan() chan []byte {
+	if s.accessLogChan == nil {
+		s.accessLogChan = make(chan []byte, 2048)
+	}
+	return s.accessLogChan
+}
+
 func (s *storeManager) WebSocketMessageChan(mode, eventID, subID string) chan *db.EventLogMessage {
 	if mode == "event" {
 		ch := s.readMessageStore.SubChan(eventID, subID)
@@ -234,6 +258,10 @@ func (s *storeManager) WebSocketMessageChan(mode, eventID, subID string) chan *d
 		ch := s.newmonitorMessageStore.SubChan(eventID, subID)
 		return ch
 	}
+	if mode == "access" {
+		ch := s.accessLogStore.SubChan(eventID, subID)
+		return ch
+	}
 	return nil
 }
 
@@ -243,6 +271,7 @@ func (s *storeManager) Run() error {
 	s.readMessageStore.Run()
 	s.dockerLogStore.Run()
 	s.newmonitorMessageStore.Run()
+	s.accessLogStore.Run()
 	for i := 0; i < s.conf.HandleMessageGoroutinues; i++ {
 		go s.handleReceiveMessage()
 	}
@@ -253,6 +282,7 @@ func (s *storeManager) Run() error {
 		go s.handleDockerLog()
 	}
 	go s.handleNewMonitorMessage()
+	go s.handleAccessLog()
 	go s.cleanLog()
 	return nil
 }
@@ -269,6 +299,10 @@ func (s *storeManager) cleanLog() {
 		if err != nil {
 			logrus.Error("list log dir error, ", err.Error())
 		} else {
+			// the access logs are in the sub directory of the home path
+			if accessFiles, err := coreutil.GetFileList(filepath.Join(pathname, accessLogDir), 2); err == nil {
+				files = append(files, accessFiles...)
+			}
 			for _, fi := range files {
 				if !strings.Contains(fi, "eventlog") {
 					if err := s.deleteFile(fi); err != nil {
@@ -419,6 +453,10 @@ func (s *storeManager) handleSubMessage() {
 					s.newmonitorMessageStore.InsertMessage(&db.EventLogMessage{MonitorData: msg[1]})
 					continue
 				}
+				if string(msg[0]) == string(db.ServiceAccessLogMessage) {
+					s.insertAccessLog(msg[1])
+					continue
+				}
 				//s.log.Debugf("receive sub message %s", string(msg))
 				message, err := s.parsingMessage(msg[1], s.conf.MessageType)
 				if err != nil {
@@ -502,6 +540,44 @@ loop:
 	s.errChan <- fmt.Errorf("handle docker log core exist")
 }
 
+// handleAccessLog 处理网关访问日志
+func (s *storeManager) handleAccessLog() {
+loop:
+	for {
+		select {
+		case <-s.context.Done():
+			return
+		case msg, ok := <-s.accessLogChan:
+			if !ok {
+				s.log.Error("handle access log core stop. access log chan closed")
+				break loop
+			}
+			if msg == nil {
+				continue
+			}
+			if s.conf.ClusterMode {
+				//消息直接集群共享
+				s.pubChan <- [][]byte{[]byte(db.ServiceAccessLogMessage), msg}
+			}
+			s.insertAccessLog(msg)
+		}
+	}
+	s.errChan <- fmt.Errorf("handle access log core exist")
+}
+
+func (s *storeManager) insertAccessLog(msg []byte) {
+	log, line, err := parseAccessLog(msg)
+	if err != nil {
+		s.log.Debugf("parsing the access log error. %s", err.Error())
+		return
+	}
+	s.accessLogStore.InsertMessage(&db.EventLogMessage{
+		Message: string(line),
+		Content: line,
+		EventID: log.ServiceID,
+	})
+}
+
 func (s *storeManager) ReleaseWebSocketMessageChan(mode string, eventID, subID string) {
 	if mode == "event" {
 		s.readMessageStore.ReleaseSubChan(eventID, subID)
@@ -512,6 +588,9 @@ func (s *storeManager) ReleaseWebSocketMessageChan(mode string, eventID, subID s
 	if mode == "newmonitor" {
 		s.newmonitorMessageStore.ReleaseSubChan(eventID, subID)
 	}
+	if mode == "access" {
+		s.accessLogStore.ReleaseSubChan(eventID, subID)
+	}
 }
 
 func (s *storeManager) Stop() {
@@ -519,6 +598,7 @@ func (s *storeManager) Stop() {
 	s.readMessageStore.stop()
 	s.dockerLogStore.stop()
 	s.newmonitorMessageStore.stop()
+	s.accessLogStore.stop()
 	s.cancel()
 	if s.filePlugin != nil {
 		s.filePlugin.Close()
@@ -536,3 +616,8 @@ func (s *storeManager) Error() chan error {
 func (s *storeManager) GetDockerLogs(serviceID string, length int) []string {
 	return s.dockerLogStore.GetHistoryMessage(serviceID, length)
 }
+
+// GetAccessLogs get history access log of the gateway
+func (s *storeManager) GetAccessLogs(serviceID string, length int) []string {
+	return s.accessLogStore.GetHistoryMessage(serviceID, length)
+}
@@ -22,6 +22,7 @@ import (
 	"sync"
 	"time"
 
+	cdb "github.com/wutong-paas/wutong/db"
 	"github.com/wutong-paas/wutong/eventlog/db"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -95,30 +96,15 @@ func NewStore(storeType string, manager *storeManager) MessageStore {
 		}
 		return read
 	case "docker_log":
-		docker := &dockerLogStore{
-			barrels:    make(map[string]*dockerLogEventBarrel, 100),
-			conf:       manager.conf,
-			log:        manager.log.WithField("module", "DockerLogStore"),
-			ctx:        ctx,
-			cancel:     cancel,
-			filePlugin: manager.filePlugin,
-			//TODO:
-			//此通道过小会阻塞接收消息的插入，造成死锁
-			//更改持久化事件为无阻塞插入
-			barrelEvent: make(chan []string, 100),
-		}
-		docker.pool = &sync.Pool{
-			New: func() interface{} {
-				reb := &dockerLogEventBarrel{
-					subSocketChan:   make(map[string]chan *db.EventLogMessage, 0),
-					cacheSize:       manager.conf.PeerDockerMaxCacheLogNumber,
-					barrelEvent:     docker.barrelEvent,
-					persistenceTime: time.Now(),
-				}
-				return reb
-			},
+		docker := newDockerLogStore(ctx, cancel, manager, manager.filePlugin, "DockerLogStore")
+		// the log alert rules are loaded from the db of the region
+		if dbmanager := cdb.GetManager(); dbmanager != nil {
+			docker.logRules = newLogRuleCounter(docker.log, dbmanager)
 		}
 		return docker
+	case "access_log":
+		// the access logs of the gateway are stored like the container logs in a separate directory
+		return newDockerLogStore(ctx, cancel, manager, manager.accessFilePlugin, "AccessLogStore")
 	case "newmonitor":
 		monitor := &newMonitorMessageStore{
 			barrels: make(map[string]*CacheMonitorMessageList, 100),
@@ -132,3 +118,30 @@ func NewStore(storeType string, manager *storeManager) MessageStore {
 
 	return nil
 }
+
+func newDockerLogStore(ctx context.Context, cancel func(), manager *storeManager, filePlugin db.Manager, module string) *dockerLogStore {
+	docker := &dockerLogStore{
+		barrels:    make(map[string]*dockerLogEventBarrel, 100),
+		conf:       manager.conf,
+		log:        manager.log.WithField("module", module),
+		ctx:        ctx,
+		cancel:     cancel,
+		filePlugin: filePlugin,
+		//TODO:
+		//此通道过小会阻塞接收消息的插入，造成死锁
+		//更改持久化事件为无阻塞插入
+		barrelEvent: make(chan []string, 100),
+	}
+	docker.pool = &sync.Pool{
+		New: func() interface{} {
+			reb := &dockerLogEventBarrel{
+				subSocketChan:   make(map[string]chan *db.EventLogMessage, 0),
+				cacheSize:       manager.conf.PeerDockerMaxCacheLogNumber,
+				barrelEvent:     docker.barrelEvent,
+				persistenceTime: time.Now(),
+			}
+			return reb
+		},
+	}
+	return docker
+}
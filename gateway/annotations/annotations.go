@@ -21,15 +21,22 @@ package annotations
 import (
 	"dario.cat/mergo"
 	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/gateway/annotations/auth"
+	"github.com/wutong-paas/wutong/gateway/annotations/autotls"
+	"github.com/wutong-paas/wutong/gateway/annotations/clientauth"
 	"github.com/wutong-paas/wutong/gateway/annotations/cookie"
 	"github.com/wutong-paas/wutong/gateway/annotations/header"
+	"github.com/wutong-paas/wutong/gateway/annotations/ipaccess"
 	"github.com/wutong-paas/wutong/gateway/annotations/l4"
 	"github.com/wutong-paas/wutong/gateway/annotations/lbtype"
 	"github.com/wutong-paas/wutong/gateway/annotations/parser"
 	"github.com/wutong-paas/wutong/gateway/annotations/proxy"
+	"github.com/wutong-paas/wutong/gateway/annotations/ratelimit"
 	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
 	"github.com/wutong-paas/wutong/gateway/annotations/rewrite"
 	"github.com/wutong-paas/wutong/gateway/annotations/upstreamhashby"
+	"github.com/wutong-paas/wutong/gateway/annotations/upstreamtls"
+	"github.com/wutong-paas/wutong/gateway/annotations/wake"
 	weight "github.com/wutong-paas/wutong/gateway/annotations/wight"
 	"github.com/wutong-paas/wutong/util/ingress-nginx/ingress/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -49,6 +56,13 @@ type Ingress struct {
 	UpstreamHashBy    string
 	LoadBalancingType string
 	Proxy             proxy.Config
+	Wake              wake.Config
+	RateLimit         ratelimit.Config
+	IPAccess          ipaccess.Config
+	BasicAuth         auth.Config
+	AutoTLS           autotls.Config
+	ClientAuth        clientauth.Config
+	UpstreamTLS       upstreamtls.Config
 }
 
 // Extractor defines the annotation parsers to be used in the extraction of annotations
@@ -68,6 +82,13 @@ func NewAnnotationExtractor(cfg resolver.Resolver) Extractor {
 			"UpstreamHashBy":    upstreamhashby.NewParser(cfg),
 			"LoadBalancingType": lbtype.NewParser(cfg),
 			"Proxy":             proxy.NewParser(cfg),
+			"Wake":              wake.NewParser(cfg),
+			"RateLimit":         ratelimit.NewParser(cfg),
+			"IPAccess":          ipaccess.NewParser(cfg),
+			"BasicAuth":         auth.NewParser(cfg),
+			"AutoTLS":           autotls.NewParser(cfg),
+			"ClientAuth":        clientauth.NewParser(cfg),
+			"UpstreamTLS":       upstreamtls.NewParser(cfg),
 		},
 	}
 }
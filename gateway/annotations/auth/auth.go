@@ -0,0 +1,67 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package auth
+
+import (
+	"strings"
+
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultRealm the default realm of the basic authentication
+const defaultRealm = "Authentication Required"
+
+// SecretKey the key of the htpasswd file in the secret
+const SecretKey = "auth"
+
+// Config the basic authentication of the location, the users are stored in the secret
+// with the same namespace as the ingress.
+type Config struct {
+	Secret string `json:"secret"`
+	Realm  string `json:"realm"`
+}
+
+type auth struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new basic authentication annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return auth{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+func (a auth) Parse(meta *metav1.ObjectMeta) (interface{}, error) {
+	secret, err := parser.GetStringAnnotation("auth-secret", meta)
+	if err != nil {
+		return nil, err
+	}
+	realm, _ := parser.GetStringAnnotation("auth-realm", meta)
+	// the realm is quoted in the nginx configuration and may contain variables
+	realm = strings.NewReplacer(`"`, "", `\`, "", "$", "", "\n", "", "\r", "").Replace(realm)
+	if realm == "" {
+		realm = defaultRealm
+	}
+	return &Config{
+		Secret: secret,
+		Realm:  realm,
+	}, nil
+}
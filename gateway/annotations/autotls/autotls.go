@@ -0,0 +1,49 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package autotls
+
+import (
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Config the certificate of the hosts is issued by the ACME controller of the worker, the
+// gateway serves the http-01 challenges of the hosts.
+type Config struct {
+	Enable bool
+}
+
+type autoTLS struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new auto tls annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return autoTLS{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+func (a autoTLS) Parse(meta *metav1.ObjectMeta) (interface{}, error) {
+	enable, err := parser.GetBoolAnnotation("auto-tls", meta)
+	if err != nil {
+		return nil, err
+	}
+	return &Config{Enable: enable}, nil
+}
@@ -0,0 +1,93 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package clientauth
+
+import (
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CASecretKey the key of the CA bundle in the secret
+const CASecretKey = "ca.crt"
+
+// defaultVerifyDepth the default depth of the client certificate chain
+const defaultVerifyDepth = 1
+
+// verifyModes the modes of the client certificate verification supported by nginx
+var verifyModes = map[string]bool{
+	"on":             true,
+	"optional":       true,
+	"optional_no_ca": true,
+}
+
+// Config the client certificate authentication of the host, the client certificates are verified
+// by the CA bundle in the secret with the same namespace as the ingress.
+type Config struct {
+	Secret string `json:"secret"`
+	// Verify on, optional or optional_no_ca
+	Verify      string `json:"verify"`
+	VerifyDepth int    `json:"verify_depth"`
+	// PassCertificate passes the client certificate to the upstream by the ssl-client-* headers
+	PassCertificate bool `json:"pass_certificate"`
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c2 *Config) bool {
+	if c == c2 {
+		return true
+	}
+	if c == nil || c2 == nil {
+		return false
+	}
+	return c.Secret == c2.Secret && c.Verify == c2.Verify &&
+		c.VerifyDepth == c2.VerifyDepth && c.PassCertificate == c2.PassCertificate
+}
+
+type clientAuth struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new client certificate authentication annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return clientAuth{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+func (a clientAuth) Parse(meta *metav1.ObjectMeta) (interface{}, error) {
+	secret, err := parser.GetStringAnnotation("client-auth-secret", meta)
+	if err != nil {
+		return nil, err
+	}
+	verify, _ := parser.GetStringAnnotation("client-auth-verify", meta)
+	if !verifyModes[verify] {
+		verify = "on"
+	}
+	depth, err := parser.GetIntAnnotation("client-auth-verify-depth", meta)
+	if err != nil || depth <= 0 {
+		depth = defaultVerifyDepth
+	}
+	passCertificate, _ := parser.GetBoolAnnotation("client-auth-pass-certificate", meta)
+	return &Config{
+		Secret:          secret,
+		Verify:          verify,
+		VerifyDepth:     depth,
+		PassCertificate: passCertificate,
+	}, nil
+}
@@ -0,0 +1,96 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ipaccess
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	"github.com/wutong-paas/wutong/util/ingress-nginx/ingress/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Config the client ips allowed or denied to access the location. The denied ips are checked
+// first, and all other ips are denied if the allowed ips are not empty.
+type Config struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c2 *Config) bool {
+	if c == c2 {
+		return true
+	}
+	if c == nil || c2 == nil {
+		return false
+	}
+	return strings.Join(c.Allow, ",") == strings.Join(c2.Allow, ",") &&
+		strings.Join(c.Deny, ",") == strings.Join(c2.Deny, ",")
+}
+
+type ipaccess struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new ip access annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return ipaccess{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+func (a ipaccess) Parse(meta *metav1.ObjectMeta) (interface{}, error) {
+	allow, _ := parser.GetStringAnnotation("allow-cidrs", meta)
+	deny, _ := parser.GetStringAnnotation("deny-cidrs", meta)
+	if allow == "" && deny == "" {
+		return nil, errors.ErrMissingAnnotations
+	}
+	cfg := &Config{
+		Allow: ParseCIDRs(allow),
+		Deny:  ParseCIDRs(deny),
+	}
+	if allow != "" && len(cfg.Allow) == 0 {
+		// no valid allowed ip, deny all instead of allow all
+		logrus.Warningf("no valid cidr in allow-cidrs of ingress %s/%s, deny all", meta.Namespace, meta.Name)
+		cfg.Deny = []string{"all"}
+	}
+	return cfg, nil
+}
+
+// ParseCIDRs parses the comma separated ips or cidrs, the invalid ones are ignored
+func ParseCIDRs(s string) []string {
+	var cidrs []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(item); err != nil && net.ParseIP(item) == nil {
+			logrus.Warningf("ignore invalid cidr %s", item)
+			continue
+		}
+		cidrs = append(cidrs, item)
+	}
+	sort.Strings(cidrs)
+	return cidrs
+}
@@ -0,0 +1,74 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ipaccess
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	"github.com/wutong-paas/wutong/util/ingress-nginx/ingress/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        *Config
+		wantErr     error
+	}{
+		{
+			name:        "no annotations",
+			annotations: map[string]string{"foo": "bar"},
+			wantErr:     errors.ErrMissingAnnotations,
+		},
+		{
+			name: "allow and deny",
+			annotations: map[string]string{
+				parser.GetAnnotationWithPrefix("allow-cidrs"): "192.168.0.0/16, 10.0.0.1",
+				parser.GetAnnotationWithPrefix("deny-cidrs"):  "192.168.1.0/24",
+			},
+			want: &Config{Allow: []string{"10.0.0.1", "192.168.0.0/16"}, Deny: []string{"192.168.1.0/24"}},
+		},
+		{
+			name:        "invalid cidrs are ignored",
+			annotations: map[string]string{parser.GetAnnotationWithPrefix("deny-cidrs"): "foo,10.0.0.0/8,300.1.1.1"},
+			want:        &Config{Deny: []string{"10.0.0.0/8"}},
+		},
+		{
+			name:        "deny all if no valid allowed cidr",
+			annotations: map[string]string{parser.GetAnnotationWithPrefix("allow-cidrs"): "foo"},
+			want:        &Config{Deny: []string{"all"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := &meta_v1.ObjectMeta{Name: "foo", Namespace: "default", Annotations: tc.annotations}
+			got, err := NewParser(resolver.Mock{}).Parse(meta)
+			if err != tc.wantErr {
+				t.Fatalf("Parse() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.want != nil && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package ratelimit
+
+import (
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	"github.com/wutong-paas/wutong/util/ingress-nginx/ingress/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultBurstMultiplier the burst is 5 times of the rate if not set
+const defaultBurstMultiplier = 5
+
+// Config the request rate and connection limits of a client ip
+type Config struct {
+	// RPS the requests per second
+	RPS int `json:"rps"`
+	// Burst the requests exceeding the rate which are served at once without delay, the others are rejected
+	Burst int `json:"burst"`
+	// Connections the concurrent connections
+	Connections int `json:"connections"`
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c2 *Config) bool {
+	if c == c2 {
+		return true
+	}
+	if c == nil || c2 == nil {
+		return false
+	}
+	return c.RPS == c2.RPS && c.Burst == c2.Burst && c.Connections == c2.Connections
+}
+
+type ratelimit struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new rate limit annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return ratelimit{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+func (a ratelimit) Parse(meta *metav1.ObjectMeta) (interface{}, error) {
+	rps, _ := parser.GetIntAnnotation("limit-rps", meta)
+	burst, _ := parser.GetIntAnnotation("limit-burst", meta)
+	connections, _ := parser.GetIntAnnotation("limit-connections", meta)
+	if rps <= 0 && connections <= 0 {
+		return nil, errors.ErrMissingAnnotations
+	}
+	cfg := &Config{}
+	if rps > 0 {
+		cfg.RPS = rps
+		cfg.Burst = burst
+		if cfg.Burst <= 0 {
+			cfg.Burst = rps * defaultBurstMultiplier
+		}
+	}
+	if connections > 0 {
+		cfg.Connections = connections
+	}
+	return cfg, nil
+}
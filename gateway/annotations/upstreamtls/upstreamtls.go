@@ -0,0 +1,89 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package upstreamtls
+
+import (
+	"strings"
+
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/gateway/annotations/resolver"
+	"github.com/wutong-paas/wutong/util/ingress-nginx/ingress/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// defaultVerifyDepth the default depth of the upstream certificate chain
+const defaultVerifyDepth = 1
+
+// Config the requests are proxied to the https upstreams. The upstream certificates are verified
+// by the CA bundle in the secret with the same namespace as the ingress if Verify is true.
+type Config struct {
+	Enable      bool   `json:"enable"`
+	Secret      string `json:"secret"`
+	Verify      bool   `json:"verify"`
+	VerifyDepth int    `json:"verify_depth"`
+	// ServerName the SNI and the name to verify the upstream certificate, default the request host
+	ServerName string `json:"server_name"`
+}
+
+// Equal tests for equality between two Config types
+func (c *Config) Equal(c2 *Config) bool {
+	if c == c2 {
+		return true
+	}
+	if c == nil || c2 == nil {
+		return false
+	}
+	return c.Enable == c2.Enable && c.Secret == c2.Secret && c.Verify == c2.Verify &&
+		c.VerifyDepth == c2.VerifyDepth && c.ServerName == c2.ServerName
+}
+
+type upstreamTLS struct {
+	r resolver.Resolver
+}
+
+// NewParser creates a new upstream tls annotation parser
+func NewParser(r resolver.Resolver) parser.IngressAnnotation {
+	return upstreamTLS{r}
+}
+
+// Parse parses the annotations contained in the ingress rule
+func (a upstreamTLS) Parse(meta *metav1.ObjectMeta) (interface{}, error) {
+	protocol, _ := parser.GetStringAnnotation("backend-protocol", meta)
+	if !strings.EqualFold(protocol, "HTTPS") {
+		return nil, errors.ErrMissingAnnotations
+	}
+	secret, _ := parser.GetStringAnnotation("proxy-ssl-secret", meta)
+	verify, err := parser.GetBoolAnnotation("proxy-ssl-verify", meta)
+	if err != nil {
+		// the upstream certificate is pinned to the CA bundle if there is one
+		verify = secret != ""
+	}
+	depth, err := parser.GetIntAnnotation("proxy-ssl-verify-depth", meta)
+	if err != nil || depth <= 0 {
+		depth = defaultVerifyDepth
+	}
+	serverName, _ := parser.GetStringAnnotation("proxy-ssl-server-name", meta)
+	return &Config{
+		Enable:      true,
+		Secret:      secret,
+		Verify:      verify,
+		VerifyDepth: depth,
+		ServerName:  serverName,
+	}, nil
+}
@@ -0,0 +1,82 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+// ACMEChallengePath the path prefix of the http-01 challenges, nginx proxies the requests of
+// the auto tls hosts to the gateway
+const ACMEChallengePath = "/.well-known/acme-challenge/"
+
+// maxKeyAuthSize limits the size of the key authorization relayed from the worker
+const maxKeyAuthSize = 1024
+
+var acmeTokenRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ACMEChallengeHandler serves the http-01 challenges with the key authorizations saved by the
+// ACME controller of the worker.
+type ACMEChallengeHandler struct {
+	workerAPI string
+	client    *http.Client
+}
+
+// NewACMEChallengeHandler creates the http-01 challenge handler
+func NewACMEChallengeHandler(workerAPI string) *ACMEChallengeHandler {
+	return &ACMEChallengeHandler{
+		workerAPI: strings.TrimSuffix(workerAPI, "/"),
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// ServeHTTP relays the key authorization of the token from the worker
+func (h *ACMEChallengeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	if h.workerAPI == "" || !acmeTokenRegexp.MatchString(token) {
+		http.NotFound(w, r)
+		return
+	}
+	resp, err := h.client.Get(fmt.Sprintf("%s/worker/acme/challenge/%s", h.workerAPI, token))
+	if err != nil {
+		logrus.Warningf("get key authorization of acme challenge %s: %v", token, err)
+		http.Error(w, "get key authorization failure", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		http.NotFound(w, r)
+		return
+	}
+	keyAuth, err := io.ReadAll(io.LimitReader(resp.Body, maxKeyAuthSize))
+	if err != nil {
+		http.Error(w, "read key authorization failure", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write(keyAuth)
+}
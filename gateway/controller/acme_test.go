@@ -0,0 +1,69 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+func TestACMEChallengeHandler(t *testing.T) {
+	worker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/worker/acme/challenge/token-1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("token-1.thumbprint"))
+	}))
+	defer worker.Close()
+
+	mux := chi.NewMux()
+	mux.Handle(ACMEChallengePath+"{token}", NewACMEChallengeHandler(worker.URL+"/"))
+	gateway := httptest.NewServer(mux)
+	defer gateway.Close()
+
+	tests := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{path: "token-1", wantCode: http.StatusOK, wantBody: "token-1.thumbprint"},
+		{path: "token-2", wantCode: http.StatusNotFound},
+		{path: "token%2E1", wantCode: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		resp, err := http.Get(gateway.URL + ACMEChallengePath + tc.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tc.wantCode {
+			t.Errorf("%s: want status %d, but got %d", tc.path, tc.wantCode, resp.StatusCode)
+			continue
+		}
+		if tc.wantBody != "" && string(body) != tc.wantBody {
+			t.Errorf("%s: want body %q, but got %q", tc.path, tc.wantBody, string(body))
+		}
+	}
+}
@@ -29,7 +29,6 @@ import (
 	"github.com/eapache/channels"
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/cmd/gateway/option"
-	"github.com/wutong-paas/wutong/gateway/controller/openresty"
 	"github.com/wutong-paas/wutong/gateway/metric"
 	"github.com/wutong-paas/wutong/gateway/store"
 	v1 "github.com/wutong-paas/wutong/gateway/v1"
@@ -159,11 +158,11 @@ func (gwc *GWController) syncGateway(key interface{}) error {
 		logrus.Debug("No need to update running configuration.")
 		return nil
 	}
-	logrus.Infof("update nginx server config file.")
+	logrus.Infof("update %s gateway config.", gwc.ocfg.Backend)
 	err := gwc.GWS.PersistConfig(currentConfig)
 	if err != nil {
-		// TODO: if nginx is not ready, then stop gateway
-		logrus.Errorf("Fail to persist Nginx config: %v\n", err)
+		// TODO: if the backend is not ready, then stop gateway
+		logrus.Errorf("Fail to persist %s config: %v\n", gwc.ocfg.Backend, err)
 		return nil
 	}
 
@@ -189,7 +188,7 @@ func NewGWController(ctx context.Context, clientset kubernetes.Interface, cfg *o
 		metricCollector: mc,
 	}
 
-	gwc.GWS = openresty.CreateOpenrestyService(cfg, &gwc.isShuttingDown)
+	gwc.GWS = newGWServicer(cfg, &gwc.isShuttingDown)
 
 	gwc.store = store.New(
 		clientset,
@@ -0,0 +1,142 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	datav3 "github.com/envoyproxy/go-control-plane/envoy/data/accesslog/v3"
+	alsv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	"github.com/sirupsen/logrus"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+)
+
+// accessLog the access log sent to the eventlog, the same as the nginx log_format wutong_access
+type accessLog struct {
+	Time          string `json:"time"`
+	ServiceID     string `json:"service_id"`
+	RuleID        string `json:"rule_id"`
+	Host          string `json:"host"`
+	Method        string `json:"method"`
+	URI           string `json:"uri"`
+	Status        string `json:"status"`
+	BodyBytesSent string `json:"body_bytes_sent"`
+	RequestTime   string `json:"request_time"`
+	UpstreamAddr  string `json:"upstream_addr"`
+	RemoteAddr    string `json:"remote_addr"`
+	UserAgent     string `json:"user_agent"`
+}
+
+// accessLogRouteName names the route by the component and the rule whose access logs are shipped
+func accessLogRouteName(loc *v1.Location) string {
+	if loc.ServiceID == "" {
+		return ""
+	}
+	return loc.ServiceID + "/" + loc.RuleID
+}
+
+// accessLogServer receives the access logs of envoy and sends them to the eventlog over udp,
+// the same as the syslog of the nginx.
+type accessLogServer struct {
+	addr string
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+func newAccessLogServer(addr string) *accessLogServer {
+	return &accessLogServer{addr: addr}
+}
+
+// StreamAccessLogs receives the http access logs, the logs of the routes without components are dropped
+func (a *accessLogServer) StreamAccessLogs(stream alsv3.AccessLogService_StreamAccessLogsServer) error {
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range msg.GetHttpLogs().GetLogEntry() {
+			serviceID, ruleID, found := strings.Cut(entry.GetCommonProperties().GetRouteName(), "/")
+			if !found || serviceID == "" {
+				continue
+			}
+			a.send(newAccessLog(entry, serviceID, ruleID))
+		}
+	}
+}
+
+func newAccessLog(entry *datav3.HTTPAccessLogEntry, serviceID, ruleID string) *accessLog {
+	common, request, response := entry.GetCommonProperties(), entry.GetRequest(), entry.GetResponse()
+	log := &accessLog{
+		Time:          time.Now().Format(time.RFC3339),
+		ServiceID:     serviceID,
+		RuleID:        ruleID,
+		Host:          request.GetAuthority(),
+		Method:        request.GetRequestMethod().String(),
+		URI:           request.GetPath(),
+		Status:        strconv.Itoa(int(response.GetResponseCode().GetValue())),
+		BodyBytesSent: strconv.FormatUint(response.GetResponseBodyBytes(), 10),
+		RequestTime:   fmt.Sprintf("%.3f", common.GetTimeToLastDownstreamTxByte().AsDuration().Seconds()),
+		RemoteAddr:    common.GetDownstreamRemoteAddress().GetSocketAddress().GetAddress(),
+		UserAgent:     request.GetUserAgent(),
+	}
+	if upstream := common.GetUpstreamRemoteAddress().GetSocketAddress(); upstream != nil {
+		log.UpstreamAddr = socketAddress(upstream)
+	}
+	return log
+}
+
+func socketAddress(addr *corev3.SocketAddress) string {
+	return net.JoinHostPort(addr.GetAddress(), strconv.Itoa(int(addr.GetPortValue())))
+}
+
+// send sends the access log in the syslog format, the connection is dialed again if it fails
+func (a *accessLogServer) send(log *accessLog) {
+	line, err := json.Marshal(log)
+	if err != nil {
+		return
+	}
+	msg := fmt.Sprintf("<190>%s wutong_access: %s", time.Now().Format(time.Stamp), line)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.conn == nil {
+		conn, err := net.Dial("udp", a.addr)
+		if err != nil {
+			logrus.Debugf("dial access log server %s: %v", a.addr, err)
+			return
+		}
+		a.conn = conn
+	}
+	if _, err := a.conn.Write([]byte(msg)); err != nil {
+		logrus.Debugf("send access log to %s: %v", a.addr, err)
+		a.conn.Close()
+		a.conn = nil
+	}
+}
@@ -0,0 +1,235 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// the context extensions of the ext_authz checks of the routes
+const (
+	authzLimitZone    = "limit_zone"
+	authzLimitRPS     = "limit_rps"
+	authzLimitBurst   = "limit_burst"
+	authzAllowCIDRs   = "allow_cidrs"
+	authzDenyCIDRs    = "deny_cidrs"
+	authzAuthRealm    = "auth_realm"
+	authzAuthUserFile = "auth_user_file"
+	authzWakePool     = "wake_pool"
+)
+
+// limiterIdleTimeout the rate limiters of the client ips idle for the timeout are removed
+const limiterIdleTimeout = 10 * time.Minute
+
+// authzServer checks the rate limits, the ip access lists, the basic authentication and the
+// wake-on-request of the routes for the ext_authz filter of envoy, the same as the openresty.
+type authzServer struct {
+	waker *waker
+
+	lock      sync.Mutex
+	limiters  map[string]*limiter
+	lastSweep time.Time
+	userFiles map[string]*userFile
+}
+
+// limiter the rate limiter of a client ip in a zone
+type limiter struct {
+	*rate.Limiter
+	lastSeen time.Time
+}
+
+// userFile the users of the htpasswd file, it is reloaded if the file is modified
+type userFile struct {
+	modTime time.Time
+	users   map[string]string
+}
+
+func newAuthzServer(waker *waker) *authzServer {
+	return &authzServer{
+		waker:     waker,
+		limiters:  make(map[string]*limiter),
+		userFiles: make(map[string]*userFile),
+	}
+}
+
+// Check checks the request in the order of the nginx phases, the rate limits, the ip access
+// lists and the basic authentication, then holds the request until the component is woken up.
+func (a *authzServer) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	exts := req.GetAttributes().GetContextExtensions()
+	clientIP := req.GetAttributes().GetSource().GetAddress().GetSocketAddress().GetAddress()
+	if zone := exts[authzLimitZone]; zone != "" {
+		rps, _ := strconv.Atoi(exts[authzLimitRPS])
+		burst, _ := strconv.Atoi(exts[authzLimitBurst])
+		if !a.allow(zone, clientIP, rps, burst) {
+			return deniedResponse(typev3.StatusCode_TooManyRequests, nil), nil
+		}
+	}
+	if !ipAllowed(clientIP, exts[authzAllowCIDRs], exts[authzDenyCIDRs]) {
+		return deniedResponse(typev3.StatusCode_Forbidden, nil), nil
+	}
+	if filename := exts[authzAuthUserFile]; filename != "" {
+		authorization := req.GetAttributes().GetRequest().GetHttp().GetHeaders()["authorization"]
+		if !a.authenticate(filename, authorization) {
+			return deniedResponse(typev3.StatusCode_Unauthorized, map[string]string{
+				"WWW-Authenticate": "Basic realm=\"" + exts[authzAuthRealm] + "\"",
+			}), nil
+		}
+	}
+	if pool := exts[authzWakePool]; pool != "" && !a.waker.wait(ctx, pool) {
+		return deniedResponse(typev3.StatusCode_ServiceUnavailable, nil), nil
+	}
+	return &authv3.CheckResponse{
+		Status:       &status.Status{Code: int32(codes.OK)},
+		HttpResponse: &authv3.CheckResponse_OkResponse{OkResponse: &authv3.OkHttpResponse{}},
+	}, nil
+}
+
+func deniedResponse(code typev3.StatusCode, headers map[string]string) *authv3.CheckResponse {
+	denied := &authv3.DeniedHttpResponse{Status: &typev3.HttpStatus{Code: code}}
+	for key, value := range headers {
+		denied.Headers = append(denied.Headers, &corev3.HeaderValueOption{
+			Header: &corev3.HeaderValue{Key: key, Value: value},
+		})
+	}
+	return &authv3.CheckResponse{
+		Status:       &status.Status{Code: int32(codes.PermissionDenied)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{DeniedResponse: denied},
+	}
+}
+
+// allow limits the requests of the client ip in the zone, the same as the limit_req with nodelay
+// which serves the burst at once.
+func (a *authzServer) allow(zone, clientIP string, rps, burst int) bool {
+	if rps <= 0 {
+		return true
+	}
+	now := time.Now()
+	key := zone + "/" + clientIP
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if now.Sub(a.lastSweep) > limiterIdleTimeout {
+		for k, l := range a.limiters {
+			if now.Sub(l.lastSeen) > limiterIdleTimeout {
+				delete(a.limiters, k)
+			}
+		}
+		a.lastSweep = now
+	}
+	l := a.limiters[key]
+	if l == nil || l.Limit() != rate.Limit(rps) || l.Burst() != burst+1 {
+		l = &limiter{Limiter: rate.NewLimiter(rate.Limit(rps), burst+1)}
+		a.limiters[key] = l
+	}
+	l.lastSeen = now
+	return l.AllowN(now, 1)
+}
+
+// ipAllowed checks the client ip by the comma separated ips and cidrs, the same as the nginx
+// which denies the ips of the deny list first and then all the ips out of the allow list.
+func ipAllowed(clientIP, allow, deny string) bool {
+	if allow == "" && deny == "" {
+		return true
+	}
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	if deny != "" && containsIP(strings.Split(deny, ","), ip) {
+		return false
+	}
+	return allow == "" || containsIP(strings.Split(allow, ","), ip)
+}
+
+func containsIP(cidrs []string, ip net.IP) bool {
+	for _, cidr := range cidrs {
+		if _, ipnet, err := net.ParseCIDR(cidr); err == nil {
+			if ipnet.Contains(ip) {
+				return true
+			}
+		} else if other := net.ParseIP(cidr); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate verifies the basic authorization by the users of the htpasswd file, the passwords
+// are hashed by bcrypt.
+func (a *authzServer) authenticate(filename, authorization string) bool {
+	scheme, credentials, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Basic") {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+	if err != nil {
+		return false
+	}
+	username, password, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return false
+	}
+	hash, exists := a.users(filename)[username]
+	return exists && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// users returns the users of the htpasswd file, there is no user if the file can not be read
+func (a *authzServer) users(filename string) map[string]string {
+	info, err := os.Stat(filename)
+	if err != nil {
+		logrus.Warningf("stat auth file %s: %v", filename, err)
+		return nil
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if file := a.userFiles[filename]; file != nil && file.modTime.Equal(info.ModTime()) {
+		return file.users
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		logrus.Warningf("open auth file %s: %v", filename, err)
+		return nil
+	}
+	defer f.Close()
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if username, hash, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":"); found {
+			users[username] = hash
+		}
+	}
+	a.userFiles[filename] = &userFile{modTime: info.ModTime(), users: users}
+	return users
+}
@@ -0,0 +1,137 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func checkRequest(clientIP, authorization string, exts map[string]string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{Attributes: &authv3.AttributeContext{
+		Source: &authv3.AttributeContext_Peer{Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+			SocketAddress: &corev3.SocketAddress{Address: clientIP},
+		}}},
+		Request: &authv3.AttributeContext_Request{Http: &authv3.AttributeContext_HttpRequest{
+			Headers: map[string]string{"authorization": authorization},
+		}},
+		ContextExtensions: exts,
+	}}
+}
+
+func TestAuthzCheck(t *testing.T) {
+	userFile := filepath.Join(t.TempDir(), "users")
+	hash, err := bcrypt.GenerateFromPassword([]byte("Hello world!"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userFile, []byte("foo:"+string(hash)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	basic := func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+	access := map[string]string{authzAllowCIDRs: "10.0.0.0/8,192.168.1.1", authzDenyCIDRs: "10.0.0.1"}
+	auth := map[string]string{authzAuthRealm: "wutong", authzAuthUserFile: userFile}
+	tests := []struct {
+		name          string
+		clientIP      string
+		authorization string
+		exts          map[string]string
+		want          typev3.StatusCode
+	}{
+		{name: "no checks", clientIP: "1.1.1.1", want: typev3.StatusCode_OK},
+		{name: "allowed cidr", clientIP: "10.0.0.2", exts: access, want: typev3.StatusCode_OK},
+		{name: "allowed ip", clientIP: "192.168.1.1", exts: access, want: typev3.StatusCode_OK},
+		{name: "denied before allowed", clientIP: "10.0.0.1", exts: access, want: typev3.StatusCode_Forbidden},
+		{name: "out of the allow list", clientIP: "1.1.1.1", exts: access, want: typev3.StatusCode_Forbidden},
+		{name: "authenticated", authorization: basic("foo:Hello world!"), exts: auth, want: typev3.StatusCode_OK},
+		{name: "wrong password", authorization: basic("foo:bar"), exts: auth, want: typev3.StatusCode_Unauthorized},
+		{name: "unknown user", authorization: basic("bar:Hello world!"), exts: auth, want: typev3.StatusCode_Unauthorized},
+		{name: "no authorization", exts: auth, want: typev3.StatusCode_Unauthorized},
+	}
+	a := newAuthzServer(newWaker("", ""))
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := a.Check(context.Background(), checkRequest(tc.clientIP, tc.authorization, tc.exts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := typev3.StatusCode_OK
+			if denied := resp.GetDeniedResponse(); denied != nil {
+				got = denied.GetStatus().GetCode()
+			}
+			if got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthzRateLimit(t *testing.T) {
+	a := newAuthzServer(newWaker("", ""))
+	exts := map[string]string{authzLimitZone: "www.example.com/", authzLimitRPS: "1", authzLimitBurst: "2"}
+	var allowed int
+	for i := 0; i < 5; i++ {
+		resp, _ := a.Check(context.Background(), checkRequest("10.0.0.1", "", exts))
+		if resp.GetDeniedResponse() == nil {
+			allowed++
+		}
+	}
+	// the request of the rate and the burst are served at once
+	if allowed != 3 {
+		t.Errorf("want 3 requests allowed, got %d", allowed)
+	}
+	if resp, _ := a.Check(context.Background(), checkRequest("10.0.0.2", "", exts)); resp.GetDeniedResponse() != nil {
+		t.Errorf("want the requests of another client ip allowed")
+	}
+}
+
+func TestWakerWait(t *testing.T) {
+	w := newWaker("", "")
+	sleeping := &v1.Pool{Meta: v1.Meta{Name: "web"}, WakeServiceID: "sid", WakeHoldTimeout: 1}
+	w.update([]*v1.Pool{sleeping})
+	if !w.wait(context.Background(), "other") {
+		t.Errorf("want the requests of the pool which is not sleeping passed")
+	}
+	if w.wait(context.Background(), "web") {
+		t.Errorf("want false if the pool is not woken up in the hold timeout")
+	}
+	if _, exists := w.requests["sid"]; !exists {
+		t.Errorf("want the wake request of the component recorded")
+	}
+
+	done := make(chan bool)
+	go func() { done <- w.wait(context.Background(), "web") }()
+	time.Sleep(100 * time.Millisecond)
+	w.update([]*v1.Pool{{Meta: v1.Meta{Name: "web"}, WakeServiceID: "sid", Nodes: []*v1.Node{{Host: "10.0.0.1", Port: 80}}}})
+	if !<-done {
+		t.Errorf("want the held request released after the pool is woken up")
+	}
+}
@@ -0,0 +1,770 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	accesslogv3 "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
+	configclusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	grpcaccesslogv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/grpc/v3"
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	tlsinspectorv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	matcherv3 "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/cmd/gateway/option"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+	envoyv3 "github.com/wutong-paas/wutong/node/core/envoy/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	// acmeChallengePath the http-01 challenges are proxied to the gateway, the same as controller.ACMEChallengePath
+	acmeChallengePath    = "/.well-known/acme-challenge/"
+	acmeChallengeCluster = "wutong_acme_challenge"
+	// sessionAffinityCookie the same cookie as the openresty
+	sessionAffinityCookie = "wutong-route"
+	// the default seconds of the connect timeout and the tcp idle timeout, the same as the nginx
+	defaultConnectTimeout = 60
+	defaultStreamTimeout  = 600
+	// xdsCluster the static cluster of the gateway in the bootstrap of envoy, which serves the xds,
+	// the ext_authz and the access log services
+	xdsCluster = "wutong_xds_cluster"
+	// defaultAuthzTimeout the timeout of the ext_authz checks without the wake-on-request
+	defaultAuthzTimeout = time.Second
+)
+
+// listenerOptions the options of the http listeners
+type listenerOptions struct {
+	// acme proxies the http-01 challenges to the gateway
+	acme bool
+	// accessLog sends the access logs to the access log service of the gateway
+	accessLog bool
+	// authzTimeout the timeout of the ext_authz checks, it is longer than the wake hold timeouts
+	authzTimeout time.Duration
+}
+
+// buildResources converts the virtual services and the pools to the listeners, the clusters, the endpoints
+// and the secrets, the private keys of the certificates are served by the sds rather than inline in the listeners.
+func buildResources(conf *v1.Config, hpools, tpools []*v1.Pool, ocfg *option.Config) (listeners, clusters, endpoints, secrets []types.Resource) {
+	// the pool name is unique to a location, see the backend name of the store
+	locations := make(map[string]*v1.Location)
+	pools := make(map[string]*v1.Pool)
+	opts := listenerOptions{
+		accessLog:    ocfg.AccessLogServer != "",
+		authzTimeout: defaultAuthzTimeout,
+	}
+	for _, vs := range conf.L7VS {
+		for _, loc := range vs.Locations {
+			for name := range loc.NameCondition {
+				locations[name] = loc
+			}
+		}
+		if vs.ACMEChallenge && ocfg.WorkerAPI != "" {
+			opts.acme = true
+		}
+	}
+	for _, pool := range append(append([]*v1.Pool{}, hpools...), tpools...) {
+		if _, exists := pools[pool.Name]; exists {
+			continue
+		}
+		pools[pool.Name] = pool
+		if pool.WakeServiceID != "" {
+			if timeout := time.Duration(wakeHoldTimeout(pool)+1) * time.Second; timeout > opts.authzTimeout {
+				opts.authzTimeout = timeout
+			}
+		}
+		cluster := buildCluster(pool, locations[pool.Name])
+		if cluster == nil {
+			continue
+		}
+		clusters = append(clusters, cluster)
+		endpoints = append(endpoints, buildLoadAssignment(pool))
+	}
+	if opts.acme {
+		cluster := envoyv3.CreateCluster(envoyv3.ClusterOptions{
+			Name:              acmeChallengeCluster,
+			ClusterType:       configclusterv3.Cluster_STATIC,
+			ConnectionTimeout: envoyv3.ConverTimeDuration(defaultConnectTimeout),
+			LoadAssignment: &endpointv3.ClusterLoadAssignment{
+				ClusterName: acmeChallengeCluster,
+				Endpoints: []*endpointv3.LocalityLbEndpoints{{
+					LbEndpoints: []*endpointv3.LbEndpoint{lbEndpoint("127.0.0.1", uint32(ocfg.ListenPorts.Health), 0)},
+				}},
+			},
+		})
+		if cluster != nil {
+			clusters = append(clusters, cluster)
+		}
+	}
+
+	httpListeners, tlsSecrets := buildHTTPListeners(conf.L7VS, pools, opts)
+	for _, listener := range httpListeners {
+		listeners = append(listeners, listener)
+	}
+	for _, secret := range tlsSecrets {
+		secrets = append(secrets, secret)
+	}
+	for _, vs := range conf.L4VS {
+		if listener := buildStreamListener(vs); listener != nil {
+			listeners = append(listeners, listener)
+		}
+	}
+	return listeners, clusters, endpoints, secrets
+}
+
+// buildCluster creates the eds cluster of the pool, the protocol and the tls to the upstreams
+// follow the location of the pool.
+func buildCluster(pool *v1.Pool, loc *v1.Location) *configclusterv3.Cluster {
+	options := envoyv3.ClusterOptions{
+		Name:              pool.Name,
+		ServiceName:       pool.Name,
+		ClusterType:       configclusterv3.Cluster_EDS,
+		ConnectionTimeout: envoyv3.ConverTimeDuration(defaultConnectTimeout),
+	}
+	var alpn []string
+	if loc != nil {
+		if loc.Proxy.ConnectTimeout > 0 {
+			options.ConnectionTimeout = envoyv3.ConverTimeDuration(int64(loc.Proxy.ConnectTimeout))
+		}
+		switch loc.BackendProtocol {
+		case "HTTP2":
+			options.Protocol, alpn = "http2", []string{"h2"}
+		case "GRPC":
+			options.Protocol, alpn = "grpc", []string{"h2"}
+		}
+		if loc.UpstreamTLS != nil {
+			socket, err := upstreamTLSSocket(loc.UpstreamTLS, alpn)
+			if err != nil {
+				// the requests fail rather than proxied without verifying the upstream certificate
+				logrus.Warningf("create the upstream tls of pool %s: %v", pool.Name, err)
+				return nil
+			}
+			options.TransportSocket = socket
+		}
+	}
+	cluster := envoyv3.CreateCluster(options)
+	if cluster == nil {
+		return nil
+	}
+	if pool.LoadBalancingType == v1.CookieSessionAffinity {
+		cluster.LbPolicy = configclusterv3.Cluster_RING_HASH
+	} else if pool.LeastConn {
+		cluster.LbPolicy = configclusterv3.Cluster_LEAST_REQUEST
+	}
+	if loc != nil && loc.UpstreamTLS != nil && loc.UpstreamTLS.ServerName == "" {
+		// the same as proxy_ssl_name $host
+		cluster.UpstreamHttpProtocolOptions = &corev3.UpstreamHttpProtocolOptions{
+			AutoSni:           true,
+			AutoSanValidation: loc.UpstreamTLS.Verify,
+		}
+	}
+	return cluster
+}
+
+func buildLoadAssignment(pool *v1.Pool) *endpointv3.ClusterLoadAssignment {
+	var lbEndpoints []*endpointv3.LbEndpoint
+	for _, node := range pool.Nodes {
+		// the eds endpoints must be ips
+		if net.ParseIP(node.Host) == nil {
+			logrus.Warningf("pool %s: endpoint %s is not an ip, ignore it", pool.Name, node.Host)
+			continue
+		}
+		lbEndpoints = append(lbEndpoints, lbEndpoint(node.Host, uint32(node.Port), node.Weight))
+	}
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: pool.Name,
+		Endpoints:   []*endpointv3.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}},
+	}
+}
+
+func lbEndpoint(host string, port uint32, weight int) *endpointv3.LbEndpoint {
+	endpoint := &endpointv3.LbEndpoint{
+		HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+			Endpoint: &endpointv3.Endpoint{
+				Address: envoyv3.CreateSocketAddress("tcp", host, port),
+			},
+		},
+	}
+	if weight > 0 {
+		endpoint.LoadBalancingWeight = envoyv3.ConversionUInt32(uint32(weight))
+	}
+	return endpoint
+}
+
+// buildHTTPListeners creates a listener of every port of the virtual services, the virtual services
+// with certificates are matched by sni, their certificates are returned as the sds secrets.
+func buildHTTPListeners(vss []*v1.VirtualService, pools map[string]*v1.Pool, opts listenerOptions) (listeners []*listenerv3.Listener, secrets []*tlsv3.Secret) {
+	ports := make(map[uint32][]*v1.VirtualService)
+	ssl := make(map[uint32]bool)
+	for _, vs := range vss {
+		if len(vs.Listening) == 0 {
+			continue
+		}
+		port, err := strconv.Atoi(vs.Listening[0])
+		if err != nil {
+			logrus.Warningf("invalid listening %s of virtual service %s", vs.Listening[0], vs.ServerName)
+			continue
+		}
+		ports[uint32(port)] = append(ports[uint32(port)], vs)
+		if vs.SSLCert != nil {
+			ssl[uint32(port)] = true
+		}
+	}
+	var sorted []uint32
+	for port := range ports {
+		sorted = append(sorted, port)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// the virtual services of the same certificate share the secret
+	names := make(map[string]struct{})
+	for _, port := range sorted {
+		name := fmt.Sprintf("http_%d", port)
+		listener := &listenerv3.Listener{
+			Name:    name,
+			Address: envoyv3.CreateSocketAddress("tcp", "0.0.0.0", port),
+		}
+		if !ssl[port] {
+			var vhosts []*routev3.VirtualHost
+			for _, vs := range ports[port] {
+				vhosts = append(vhosts, buildVirtualHost(vs, pools, opts.acme))
+			}
+			listener.FilterChains = []*listenerv3.FilterChain{
+				{Filters: []*listenerv3.Filter{httpConnectionManagerFilter(name, vhosts, false, opts)}},
+			}
+			listeners = append(listeners, listener)
+			continue
+		}
+		listener.Name = fmt.Sprintf("https_%d", port)
+		listener.ListenerFilters = []*listenerv3.ListenerFilter{{
+			Name:       wellknown.TLSInspector,
+			ConfigType: &listenerv3.ListenerFilter_TypedConfig{TypedConfig: envoyv3.Message2Any(&tlsinspectorv3.TlsInspector{})},
+		}}
+		for _, vs := range ports[port] {
+			if vs.SSLCert == nil {
+				continue
+			}
+			socket, secret, err := downstreamTLSSocket(vs)
+			if err != nil {
+				logrus.Warningf("create the tls of virtual service %s: %v", vs.ServerName, err)
+				continue
+			}
+			if _, exists := names[secret.Name]; !exists {
+				names[secret.Name] = struct{}{}
+				secrets = append(secrets, secret)
+			}
+			chain := &listenerv3.FilterChain{
+				Filters: []*listenerv3.Filter{
+					httpConnectionManagerFilter(listener.Name, []*routev3.VirtualHost{buildVirtualHost(vs, pools, opts.acme)}, passClientCertificate(vs), opts),
+				},
+				TransportSocket: socket,
+			}
+			if domain := serverName(vs); domain != "*" {
+				chain.FilterChainMatch = &listenerv3.FilterChainMatch{ServerNames: []string{domain}}
+			}
+			listener.FilterChains = append(listener.FilterChains, chain)
+		}
+		if len(listener.FilterChains) > 0 {
+			listeners = append(listeners, listener)
+		}
+	}
+	return listeners, secrets
+}
+
+// serverName returns the domain of the virtual service, the virtual services with certificates are prefixed with tls
+func serverName(vs *v1.VirtualService) string {
+	name := vs.ServerName
+	if vs.SSLCert != nil {
+		name = strings.TrimPrefix(name, "tls")
+	}
+	if name == "" || name == "_" {
+		return "*"
+	}
+	return name
+}
+
+func passClientCertificate(vs *v1.VirtualService) bool {
+	if vs.ClientAuth == nil {
+		return false
+	}
+	for _, loc := range vs.Locations {
+		if loc.PassClientCertificate {
+			return true
+		}
+	}
+	return false
+}
+
+func httpConnectionManagerFilter(name string, vhosts []*routev3.VirtualHost, passClientCert bool, opts listenerOptions) *listenerv3.Filter {
+	hcm := &hcmv3.HttpConnectionManager{
+		StatPrefix: name,
+		CodecType:  hcmv3.HttpConnectionManager_AUTO,
+		RouteSpecifier: &hcmv3.HttpConnectionManager_RouteConfig{
+			RouteConfig: &routev3.RouteConfiguration{
+				Name:         name,
+				VirtualHosts: vhosts,
+			},
+		},
+		HttpFilters: []*hcmv3.HttpFilter{
+			{
+				Name: wellknown.HTTPExternalAuthorization,
+				ConfigType: &hcmv3.HttpFilter_TypedConfig{TypedConfig: envoyv3.Message2Any(&extauthzv3.ExtAuthz{
+					Services:            &extauthzv3.ExtAuthz_GrpcService{GrpcService: gatewayGrpcService(opts.authzTimeout)},
+					TransportApiVersion: corev3.ApiVersion_V3,
+				})},
+				// the routes with the access control or the wake-on-request enable it
+				Disabled: true,
+			},
+			{
+				Name:       wellknown.Router,
+				ConfigType: &hcmv3.HttpFilter_TypedConfig{TypedConfig: envoyv3.Message2Any(&routerv3.Router{})},
+			},
+		},
+		// the domains are matched without the port, the same as the server_name of the nginx
+		StripPortMode:    &hcmv3.HttpConnectionManager_StripAnyHostPort{StripAnyHostPort: true},
+		UseRemoteAddress: wrapperspb.Bool(true),
+	}
+	if opts.accessLog {
+		hcm.AccessLog = []*accesslogv3.AccessLog{{
+			Name: wellknown.HTTPGRPCAccessLog,
+			ConfigType: &accesslogv3.AccessLog_TypedConfig{TypedConfig: envoyv3.Message2Any(&grpcaccesslogv3.HttpGrpcAccessLogConfig{
+				CommonConfig: &grpcaccesslogv3.CommonGrpcAccessLogConfig{
+					LogName:             name,
+					GrpcService:         gatewayGrpcService(0),
+					TransportApiVersion: corev3.ApiVersion_V3,
+				},
+			})},
+		}}
+	}
+	if passClientCert {
+		hcm.ForwardClientCertDetails = hcmv3.HttpConnectionManager_SANITIZE_SET
+		hcm.SetCurrentClientCertDetails = &hcmv3.HttpConnectionManager_SetCurrentClientCertDetails{
+			Subject: wrapperspb.Bool(true),
+			Cert:    true,
+		}
+	}
+	return &listenerv3.Filter{
+		Name:       wellknown.HTTPConnectionManager,
+		ConfigType: &listenerv3.Filter_TypedConfig{TypedConfig: envoyv3.Message2Any(hcm)},
+	}
+}
+
+// gatewayGrpcService returns the grpc service of the gateway, which is served with the xds
+func gatewayGrpcService(timeout time.Duration) *corev3.GrpcService {
+	service := &corev3.GrpcService{
+		TargetSpecifier: &corev3.GrpcService_EnvoyGrpc_{EnvoyGrpc: &corev3.GrpcService_EnvoyGrpc{ClusterName: xdsCluster}},
+	}
+	if timeout > 0 {
+		service.Timeout = durationpb.New(timeout)
+	}
+	return service
+}
+
+// buildVirtualHost creates the routes of the locations, the longer paths are matched first as
+// the prefix locations of the nginx, and the header or cookie conditions before the default one.
+func buildVirtualHost(vs *v1.VirtualService, pools map[string]*v1.Pool, acme bool) *routev3.VirtualHost {
+	var routes []*routev3.Route
+	if vs.ACMEChallenge && acme {
+		routes = append(routes, &routev3.Route{
+			Match: &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: acmeChallengePath}},
+			Action: &routev3.Route_Route{Route: &routev3.RouteAction{
+				ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: acmeChallengeCluster},
+			}},
+		})
+	}
+	locations := append([]*v1.Location{}, vs.Locations...)
+	sort.SliceStable(locations, func(i, j int) bool { return len(locations[i].Path) > len(locations[j].Path) })
+	for _, loc := range locations {
+		if loc.DisableProxyPass {
+			continue
+		}
+		for _, name := range sortedPoolNames(loc.NameCondition) {
+			route := buildRoute(loc, name, loc.NameCondition[name], pools[name])
+			if route.GetDirectResponse() == nil {
+				zone := vs.ServerName + loc.Path
+				if exts := authzContextExtensions(zone, loc, name, pools[name]); len(exts) > 0 {
+					route.TypedPerFilterConfig = map[string]*anypb.Any{
+						wellknown.HTTPExternalAuthorization: envoyv3.Message2Any(&extauthzv3.ExtAuthzPerRoute{
+							Override: &extauthzv3.ExtAuthzPerRoute_CheckSettings{
+								CheckSettings: &extauthzv3.CheckSettings{ContextExtensions: exts},
+							},
+						}),
+					}
+				}
+			}
+			routes = append(routes, clientAuthRoutes(loc, route)...)
+		}
+	}
+	return &routev3.VirtualHost{
+		Name:    vs.ServerName,
+		Domains: []string{serverName(vs)},
+		Routes:  routes,
+	}
+}
+
+// sortedPoolNames returns the pools with conditions before the default pool
+func sortedPoolNames(conditions map[string]*v1.Condition) []string {
+	var names []string
+	for name := range conditions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		di, dj := conditions[names[i]].Type == v1.DefaultType, conditions[names[j]].Type == v1.DefaultType
+		if di != dj {
+			return dj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func buildRoute(loc *v1.Location, poolName string, condition *v1.Condition, pool *v1.Pool) *routev3.Route {
+	path := loc.Path
+	if path == "" {
+		path = "/"
+	}
+	route := &routev3.Route{
+		// the access logs of the route are shipped to the component
+		Name: accessLogRouteName(loc),
+		Match: &routev3.RouteMatch{
+			PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: path},
+			Headers:       headerMatchers(condition),
+		},
+	}
+	if loc.ClientAuthVerify == v1.ClientAuthDeny {
+		// the CA bundle of the client certificates is invalid or conflicts with the other locations
+		route.Action = directResponse(403)
+		return route
+	}
+	if loc.RateLimit.Connections > 0 {
+		// envoy can not limit the connections of a client ip, the requests are denied rather than unlimited
+		logrus.Warningf("the connection limit of location %s is not supported by envoy, deny the requests", path)
+		route.Action = directResponse(403)
+		return route
+	}
+	if loc.UpstreamTLS != nil && loc.UpstreamTLS.Verify && loc.UpstreamTLS.CAFile == "" {
+		// the CA bundle of the upstream certificates is invalid
+		route.Action = directResponse(502)
+		return route
+	}
+	action := &routev3.RouteAction{
+		ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: poolName},
+		// the proxy_read_timeout of the nginx is the timeout between two reads
+		Timeout: durationpb.New(0),
+	}
+	if loc.Proxy.ReadTimeout > 0 {
+		action.IdleTimeout = envoyv3.ConverTimeDuration(int64(loc.Proxy.ReadTimeout))
+	}
+	if loc.PathRewrite {
+		action.PrefixRewrite = "/"
+	}
+	if pool != nil && pool.LoadBalancingType == v1.CookieSessionAffinity {
+		action.HashPolicy = []*routev3.RouteAction_HashPolicy{{
+			PolicySpecifier: &routev3.RouteAction_HashPolicy_Cookie_{
+				Cookie: &routev3.RouteAction_HashPolicy_Cookie{
+					Name: sessionAffinityCookie,
+					Path: "/",
+					// a session cookie is generated if it is not present
+					Ttl: durationpb.New(0),
+				},
+			},
+		}}
+	}
+	route.Action = &routev3.Route_Route{Route: action}
+	var keys []string
+	for key := range loc.Proxy.SetHeaders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := loc.Proxy.SetHeaders[key]
+		if strings.Contains(value, "$") {
+			// the nginx variables are not supported
+			continue
+		}
+		route.RequestHeadersToAdd = append(route.RequestHeadersToAdd, &corev3.HeaderValueOption{
+			Header:       &corev3.HeaderValue{Key: key, Value: value},
+			AppendAction: corev3.HeaderValueOption_OVERWRITE_IF_EXISTS_OR_ADD,
+		})
+	}
+	return route
+}
+
+// authzContextExtensions returns the context extensions of the ext_authz check of the route, the
+// rate limit, the ip access lists, the basic authentication and the wake-on-request of the pool.
+func authzContextExtensions(zone string, loc *v1.Location, poolName string, pool *v1.Pool) map[string]string {
+	exts := make(map[string]string)
+	if loc.RateLimit.RPS > 0 {
+		exts[authzLimitZone] = zone
+		exts[authzLimitRPS] = strconv.Itoa(loc.RateLimit.RPS)
+		exts[authzLimitBurst] = strconv.Itoa(loc.RateLimit.Burst)
+	}
+	if len(loc.IPAccess.Allow) > 0 {
+		exts[authzAllowCIDRs] = strings.Join(loc.IPAccess.Allow, ",")
+	}
+	if len(loc.IPAccess.Deny) > 0 {
+		exts[authzDenyCIDRs] = strings.Join(loc.IPAccess.Deny, ",")
+	}
+	if loc.BasicAuth != nil {
+		exts[authzAuthRealm] = loc.BasicAuth.Realm
+		exts[authzAuthUserFile] = loc.BasicAuth.UserFile
+	}
+	if pool != nil && pool.WakeServiceID != "" {
+		exts[authzWakePool] = poolName
+	}
+	return exts
+}
+
+// clientAuthRoutes verifies the client certificates of the route, the listener requests them optionally.
+// The route matches the validated certificates, or the absent ones if the verification is optional, the
+// other requests are denied.
+func clientAuthRoutes(loc *v1.Location, route *routev3.Route) []*routev3.Route {
+	if loc.ClientAuthVerify != "on" && loc.ClientAuthVerify != "optional" {
+		return []*routev3.Route{route}
+	}
+	denied := &routev3.Route{
+		Match: &routev3.RouteMatch{
+			PathSpecifier: route.Match.PathSpecifier,
+			Headers:       route.Match.Headers,
+		},
+		Action: directResponse(403),
+	}
+	routes := []*routev3.Route{route}
+	if loc.ClientAuthVerify == "optional" {
+		absent := proto.Clone(route).(*routev3.Route)
+		absent.Match.TlsContext = &routev3.RouteMatch_TlsContextMatchOptions{Presented: wrapperspb.Bool(false)}
+		routes = append(routes, absent)
+	}
+	route.Match.TlsContext = &routev3.RouteMatch_TlsContextMatchOptions{Validated: wrapperspb.Bool(true)}
+	return append(routes, denied)
+}
+
+func headerMatchers(condition *v1.Condition) (matchers []*routev3.HeaderMatcher) {
+	if condition == nil || condition.Type == v1.DefaultType {
+		return nil
+	}
+	var keys []string
+	for key := range condition.Value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := condition.Value[key]
+		matcher := &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Exact{Exact: value}}
+		name := key
+		if condition.Type == v1.CookieType {
+			name = "cookie"
+			matcher = &matcherv3.StringMatcher{MatchPattern: &matcherv3.StringMatcher_Contains{Contains: key + "=" + value}}
+		}
+		matchers = append(matchers, &routev3.HeaderMatcher{
+			Name:                 name,
+			HeaderMatchSpecifier: &routev3.HeaderMatcher_StringMatch{StringMatch: matcher},
+		})
+	}
+	return matchers
+}
+
+func directResponse(status uint32) *routev3.Route_DirectResponse {
+	return &routev3.Route_DirectResponse{DirectResponse: &routev3.DirectResponseAction{Status: status}}
+}
+
+// buildStreamListener creates the tcp or udp listener of the l4 virtual service
+func buildStreamListener(vs *v1.VirtualService) *listenerv3.Listener {
+	if len(vs.Listening) == 0 {
+		return nil
+	}
+	// host:port or host:port udp
+	fields := strings.Fields(vs.Listening[0])
+	host, portStr, err := net.SplitHostPort(fields[0])
+	if err != nil {
+		logrus.Warningf("invalid listening %s of pool %s", vs.Listening[0], vs.PoolName)
+		return nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		logrus.Warningf("invalid listening %s of pool %s", vs.Listening[0], vs.PoolName)
+		return nil
+	}
+	if len(fields) > 1 && fields[1] == "udp" {
+		return envoyv3.CreateUDPListener("udp_"+fields[0], vs.PoolName, host, vs.PoolName, uint32(port))
+	}
+	idleTimeout := int64(defaultStreamTimeout)
+	if timeout, err := time.ParseDuration(vs.ProxyStreamTimeout); err == nil && timeout > 0 {
+		idleTimeout = int64(timeout.Seconds())
+	}
+	return envoyv3.CreateTCPListener("tcp_"+fields[0], vs.PoolName, host, vs.PoolName, uint32(port), idleTimeout)
+}
+
+// downstreamTLSSocket creates the tls of the virtual service, the certificate is referenced by the name of
+// the returned secret, which is served by the sds.
+func downstreamTLSSocket(vs *v1.VirtualService) (*corev3.TransportSocket, *tlsv3.Secret, error) {
+	chain, key, err := readCertificate(vs.SSLCert.CertificatePem)
+	if err != nil {
+		return nil, nil, err
+	}
+	secret := &tlsv3.Secret{
+		Name: secretName(vs.SSLCert.CertificatePem),
+		Type: &tlsv3.Secret_TlsCertificate{TlsCertificate: &tlsv3.TlsCertificate{
+			CertificateChain: inlineBytes(chain),
+			PrivateKey:       inlineBytes(key),
+		}},
+	}
+	common := &tlsv3.CommonTlsContext{
+		TlsCertificateSdsSecretConfigs: []*tlsv3.SdsSecretConfig{{
+			Name: secret.Name,
+			SdsConfig: &corev3.ConfigSource{
+				ResourceApiVersion: corev3.ApiVersion_V3,
+				ConfigSourceSpecifier: &corev3.ConfigSource_ApiConfigSource{ApiConfigSource: &corev3.ApiConfigSource{
+					ApiType:             corev3.ApiConfigSource_GRPC,
+					TransportApiVersion: corev3.ApiVersion_V3,
+					GrpcServices:        []*corev3.GrpcService{gatewayGrpcService(0)},
+				}},
+			},
+		}},
+		TlsParams:     tlsParams(vs.SSlProtocols),
+		AlpnProtocols: []string{"h2", "http/1.1"},
+	}
+	tlsContext := &tlsv3.DownstreamTlsContext{CommonTlsContext: common}
+	if clientAuth := vs.ClientAuth; clientAuth != nil && clientAuth.CAFile != "" {
+		ca, err := os.ReadFile(clientAuth.CAFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		validation := &tlsv3.CertificateValidationContext{TrustedCa: inlineBytes(ca)}
+		if clientAuth.VerifyDepth > 0 {
+			validation.MaxVerifyDepth = envoyv3.ConversionUInt32(uint32(clientAuth.VerifyDepth))
+		}
+		// the routes verify the client certificates by themselves
+		if clientAuth.Verify == "optional_no_ca" {
+			validation.TrustChainVerification = tlsv3.CertificateValidationContext_ACCEPT_UNTRUSTED
+		}
+		common.ValidationContextType = &tlsv3.CommonTlsContext_ValidationContext{ValidationContext: validation}
+	}
+	return transportSocket(tlsContext), secret, nil
+}
+
+func upstreamTLSSocket(upstreamTLS *v1.UpstreamTLS, alpn []string) (*corev3.TransportSocket, error) {
+	common := &tlsv3.CommonTlsContext{AlpnProtocols: alpn}
+	if upstreamTLS.Verify && upstreamTLS.CAFile != "" {
+		ca, err := os.ReadFile(upstreamTLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		validation := &tlsv3.CertificateValidationContext{TrustedCa: inlineBytes(ca)}
+		if upstreamTLS.VerifyDepth > 0 {
+			validation.MaxVerifyDepth = envoyv3.ConversionUInt32(uint32(upstreamTLS.VerifyDepth))
+		}
+		common.ValidationContextType = &tlsv3.CommonTlsContext_ValidationContext{ValidationContext: validation}
+	}
+	return transportSocket(&tlsv3.UpstreamTlsContext{
+		Sni:              upstreamTLS.ServerName,
+		CommonTlsContext: common,
+	}), nil
+}
+
+func transportSocket(tlsContext protoreflect.ProtoMessage) *corev3.TransportSocket {
+	return &corev3.TransportSocket{
+		Name:       wellknown.TransportSocketTLS,
+		ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: envoyv3.Message2Any(tlsContext)},
+	}
+}
+
+// readCertificate splits the certificate chain and the private key of the pem file written by the store
+func readCertificate(filename string) (chain, key []byte, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			chain = append(chain, pem.EncodeToMemory(block)...)
+		} else if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			key = pem.EncodeToMemory(block)
+		}
+	}
+	if len(chain) == 0 || len(key) == 0 {
+		return nil, nil, fmt.Errorf("no certificate or private key found in %s", filename)
+	}
+	return chain, key, nil
+}
+
+// secretName names the secret by the pem file of the certificate, which is unique to the certificate
+func secretName(filename string) string {
+	return "cert_" + strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+}
+
+func inlineBytes(data []byte) *corev3.DataSource {
+	return &corev3.DataSource{Specifier: &corev3.DataSource_InlineBytes{InlineBytes: data}}
+}
+
+var tlsProtocols = map[string]tlsv3.TlsParameters_TlsProtocol{
+	"TLSv1":   tlsv3.TlsParameters_TLSv1_0,
+	"TLSv1.1": tlsv3.TlsParameters_TLSv1_1,
+	"TLSv1.2": tlsv3.TlsParameters_TLSv1_2,
+	"TLSv1.3": tlsv3.TlsParameters_TLSv1_3,
+}
+
+// tlsParams converts the ssl_protocols of the nginx, such as "TLSv1.2 TLSv1.3", to the protocol range
+func tlsParams(protocols string) *tlsv3.TlsParameters {
+	var params *tlsv3.TlsParameters
+	for _, name := range strings.Fields(protocols) {
+		protocol, ok := tlsProtocols[name]
+		if !ok {
+			continue
+		}
+		if params == nil {
+			params = &tlsv3.TlsParameters{TlsMinimumProtocolVersion: protocol, TlsMaximumProtocolVersion: protocol}
+			continue
+		}
+		if protocol < params.TlsMinimumProtocolVersion {
+			params.TlsMinimumProtocolVersion = protocol
+		}
+		if protocol > params.TlsMaximumProtocolVersion {
+			params.TlsMaximumProtocolVersion = protocol
+		}
+	}
+	return params
+}
@@ -0,0 +1,323 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	configclusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	extauthzv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/ext_authz/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/wutong-paas/wutong/cmd/gateway/option"
+	"github.com/wutong-paas/wutong/gateway/annotations/ipaccess"
+	"github.com/wutong-paas/wutong/gateway/annotations/ratelimit"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestBuildResources(t *testing.T) {
+	conf := &v1.Config{
+		L7VS: []*v1.VirtualService{{
+			Listening:  []string{"80"},
+			ServerName: "www.example.com",
+			Locations: []*v1.Location{
+				{
+					Path: "/",
+					NameCondition: map[string]*v1.Condition{
+						"web_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}},
+						"web_header":  {Type: v1.HeaderType, Value: map[string]string{"x-canary": "true"}},
+					},
+				},
+				{
+					Path:            "/api.Greeter",
+					BackendProtocol: "GRPC",
+					NameCondition: map[string]*v1.Condition{
+						"grpc_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}},
+					},
+				},
+			},
+		}},
+		L4VS: []*v1.VirtualService{{
+			Listening: []string{"0.0.0.0:5000"},
+			PoolName:  "tcp_5000",
+		}},
+	}
+	hpools := []*v1.Pool{
+		{Meta: v1.Meta{Name: "web_default"}, Nodes: []*v1.Node{{Host: "10.0.0.1", Port: 8080}, {Host: "foo.svc", Port: 8080}}},
+		{Meta: v1.Meta{Name: "web_header"}, LeastConn: true, Nodes: []*v1.Node{{Host: "10.0.0.2", Port: 8080}}},
+		{Meta: v1.Meta{Name: "grpc_default"}, Nodes: []*v1.Node{{Host: "10.0.0.3", Port: 9090}}},
+	}
+	tpools := []*v1.Pool{
+		{Meta: v1.Meta{Name: "tcp_5000"}, Nodes: []*v1.Node{{Host: "10.0.0.4", Port: 5000}}},
+	}
+	listeners, clusters, endpoints, _ := buildResources(conf, hpools, tpools, &option.Config{})
+	if len(listeners) != 2 || len(clusters) != 4 || len(endpoints) != 4 {
+		t.Fatalf("want 2 listeners, 4 clusters and 4 endpoints, got %d %d %d", len(listeners), len(clusters), len(endpoints))
+	}
+	snapshot, err := cache.NewSnapshot("1", map[string][]types.Resource{
+		resource.EndpointType: endpoints,
+		resource.ClusterType:  clusters,
+		resource.ListenerType: listeners,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := snapshot.Consistent(); err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range listeners {
+		if err := res.(*listenerv3.Listener).Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, res := range clusters {
+		cluster := res.(*configclusterv3.Cluster)
+		switch cluster.Name {
+		case "grpc_default":
+			if cluster.Http2ProtocolOptions == nil {
+				t.Errorf("the grpc cluster should use http2")
+			}
+		case "web_header":
+			if cluster.LbPolicy != configclusterv3.Cluster_LEAST_REQUEST {
+				t.Errorf("want least request, got %s", cluster.LbPolicy)
+			}
+		}
+	}
+
+	hcm := &hcmv3.HttpConnectionManager{}
+	if err := listeners[0].(*listenerv3.Listener).FilterChains[0].Filters[0].GetTypedConfig().UnmarshalTo(hcm); err != nil {
+		t.Fatal(err)
+	}
+	var clusterNames []string
+	for _, route := range hcm.GetRouteConfig().VirtualHosts[0].Routes {
+		clusterNames = append(clusterNames, route.GetRoute().GetCluster())
+	}
+	// the longer path first, and the header condition before the default one
+	want := []string{"grpc_default", "web_header", "web_default"}
+	if len(clusterNames) != len(want) {
+		t.Fatalf("want routes %v, got %v", want, clusterNames)
+	}
+	for i := range want {
+		if clusterNames[i] != want[i] {
+			t.Fatalf("want routes %v, got %v", want, clusterNames)
+		}
+	}
+}
+
+func TestBuildResourcesAccessControl(t *testing.T) {
+	conf := &v1.Config{
+		L7VS: []*v1.VirtualService{{
+			Listening:  []string{"80"},
+			ServerName: "www.example.com",
+			Locations: []*v1.Location{{
+				Path:      "/",
+				ServiceID: "sid",
+				RuleID:    "rid",
+				RateLimit: ratelimit.Config{RPS: 10, Burst: 5},
+				IPAccess:  ipaccess.Config{Allow: []string{"10.0.0.0/8"}},
+				BasicAuth: &v1.BasicAuth{Realm: "wutong", UserFile: "/tmp/users"},
+				NameCondition: map[string]*v1.Condition{
+					"web_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}},
+				},
+			}},
+		}},
+	}
+	hpools := []*v1.Pool{{Meta: v1.Meta{Name: "web_default"}, WakeServiceID: "sid", WakeHoldTimeout: 30}}
+	listeners, _, _, _ := buildResources(conf, hpools, nil, &option.Config{AccessLogServer: "127.0.0.1:6167"})
+	if len(listeners) != 1 {
+		t.Fatalf("want 1 listener, got %d", len(listeners))
+	}
+	listener := listeners[0].(*listenerv3.Listener)
+	if err := listener.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	hcm := &hcmv3.HttpConnectionManager{}
+	if err := listener.FilterChains[0].Filters[0].GetTypedConfig().UnmarshalTo(hcm); err != nil {
+		t.Fatal(err)
+	}
+	if len(hcm.AccessLog) != 1 {
+		t.Errorf("want the access logs sent to the gateway")
+	}
+	authz := &extauthzv3.ExtAuthz{}
+	if err := hcm.HttpFilters[0].GetTypedConfig().UnmarshalTo(authz); err != nil {
+		t.Fatal(err)
+	}
+	if timeout := authz.GetGrpcService().GetTimeout().AsDuration(); timeout != 31*time.Second {
+		t.Errorf("want the authz timeout longer than the wake hold timeout, got %s", timeout)
+	}
+	route := hcm.GetRouteConfig().VirtualHosts[0].Routes[0]
+	if route.Name != "sid/rid" {
+		t.Errorf("want the route named by the component and the rule, got %s", route.Name)
+	}
+	perRoute := &extauthzv3.ExtAuthzPerRoute{}
+	if err := route.TypedPerFilterConfig[wellknown.HTTPExternalAuthorization].UnmarshalTo(perRoute); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		authzLimitZone: "www.example.com/", authzLimitRPS: "10", authzLimitBurst: "5",
+		authzAllowCIDRs: "10.0.0.0/8", authzAuthRealm: "wutong", authzAuthUserFile: "/tmp/users",
+		authzWakePool: "web_default",
+	}
+	if got := perRoute.GetCheckSettings().GetContextExtensions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want context extensions %v, got %v", want, got)
+	}
+
+	conf.L7VS[0].Locations[0].RateLimit.Connections = 10
+	route = buildRoute(conf.L7VS[0].Locations[0], "web_default", &v1.Condition{Type: v1.DefaultType}, nil)
+	if status := route.GetDirectResponse().GetStatus(); status != 403 {
+		t.Errorf("want 403 with the unsupported connection limit, got %d", status)
+	}
+}
+
+func TestBuildResourcesSecrets(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "www.example.com"},
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "www.example.com.pem")
+	data := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})...)
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	location := &v1.Location{
+		Path: "/",
+		NameCondition: map[string]*v1.Condition{
+			"web_default": {Type: v1.DefaultType, Value: map[string]string{"1": "1"}},
+		},
+	}
+	conf := &v1.Config{
+		L7VS: []*v1.VirtualService{
+			{Listening: []string{"443"}, ServerName: "tlswww.example.com", SSLCert: &v1.SSLCert{CertificatePem: filename}, Locations: []*v1.Location{location}},
+			{Listening: []string{"443"}, ServerName: "tlsapi.example.com", SSLCert: &v1.SSLCert{CertificatePem: filename}, Locations: []*v1.Location{location}},
+		},
+	}
+	hpools := []*v1.Pool{{Meta: v1.Meta{Name: "web_default"}}}
+	listeners, _, _, secrets := buildResources(conf, hpools, nil, &option.Config{})
+	if len(listeners) != 1 || len(secrets) != 1 {
+		t.Fatalf("want 1 listener and 1 secret shared by the virtual services, got %d %d", len(listeners), len(secrets))
+	}
+	secret := secrets[0].(*tlsv3.Secret)
+	if secret.Name != "cert_www.example.com" || len(secret.GetTlsCertificate().GetPrivateKey().GetInlineBytes()) == 0 {
+		t.Fatalf("unexpected secret %s", secret.Name)
+	}
+	for _, chain := range listeners[0].(*listenerv3.Listener).FilterChains {
+		tlsContext := &tlsv3.DownstreamTlsContext{}
+		if err := chain.TransportSocket.GetTypedConfig().UnmarshalTo(tlsContext); err != nil {
+			t.Fatal(err)
+		}
+		common := tlsContext.CommonTlsContext
+		if len(common.TlsCertificates) != 0 {
+			t.Errorf("want no inline private key in the listener")
+		}
+		if configs := common.TlsCertificateSdsSecretConfigs; len(configs) != 1 || configs[0].Name != secret.Name {
+			t.Errorf("want the certificate referenced by the sds secret %s, got %v", secret.Name, configs)
+		}
+	}
+}
+
+func TestBuildRouteDenied(t *testing.T) {
+	loc := &v1.Location{Path: "/", UpstreamTLS: &v1.UpstreamTLS{Verify: true}}
+	route := buildRoute(loc, "foo", &v1.Condition{Type: v1.DefaultType}, nil)
+	if status := route.GetDirectResponse().GetStatus(); status != 502 {
+		t.Errorf("want 502 without the upstream CA bundle, got %d", status)
+	}
+	route = buildRoute(&v1.Location{Path: "/", ClientAuthVerify: v1.ClientAuthDeny}, "foo", &v1.Condition{Type: v1.DefaultType}, nil)
+	if status := route.GetDirectResponse().GetStatus(); status != 403 {
+		t.Errorf("want 403 without the client CA bundle, got %d", status)
+	}
+	route = buildRoute(&v1.Location{Path: "/"}, "foo", &v1.Condition{Type: v1.CookieType, Value: map[string]string{"canary": "1"}}, nil)
+	if matchers := route.Match.Headers; len(matchers) != 1 || matchers[0].Name != "cookie" ||
+		matchers[0].GetStringMatch().GetContains() != "canary=1" {
+		t.Errorf("unexpected cookie matchers %v", matchers)
+	}
+}
+
+func TestClientAuthRoutes(t *testing.T) {
+	tests := []struct {
+		verify string
+		// want the presented and validated matches of the routes, nil for the denied route
+		want []*routev3.RouteMatch_TlsContextMatchOptions
+	}{
+		{verify: "", want: []*routev3.RouteMatch_TlsContextMatchOptions{nil}},
+		{verify: "optional_no_ca", want: []*routev3.RouteMatch_TlsContextMatchOptions{nil}},
+		{verify: "on", want: []*routev3.RouteMatch_TlsContextMatchOptions{{Validated: wrapperspb.Bool(true)}, nil}},
+		{verify: "optional", want: []*routev3.RouteMatch_TlsContextMatchOptions{
+			{Validated: wrapperspb.Bool(true)}, {Presented: wrapperspb.Bool(false)}, nil}},
+	}
+	for _, tc := range tests {
+		loc := &v1.Location{Path: "/", ClientAuthVerify: tc.verify}
+		routes := clientAuthRoutes(loc, buildRoute(loc, "foo", &v1.Condition{Type: v1.DefaultType}, nil))
+		if len(routes) != len(tc.want) {
+			t.Fatalf("verify %q: want %d routes, got %d", tc.verify, len(tc.want), len(routes))
+		}
+		for i, route := range routes {
+			if !proto.Equal(route.Match.TlsContext, tc.want[i]) {
+				t.Errorf("verify %q: route %d, want tls context %v, got %v", tc.verify, i, tc.want[i], route.Match.TlsContext)
+			}
+			denied := route.GetDirectResponse().GetStatus() == 403
+			if last := i == len(routes)-1 && len(routes) > 1; denied != last {
+				t.Errorf("verify %q: route %d, want denied %v, got %v", tc.verify, i, last, denied)
+			}
+		}
+	}
+}
+
+func TestTLSParams(t *testing.T) {
+	params := tlsParams("TLSv1.2 TLSv1.3")
+	if params.TlsMinimumProtocolVersion != tlsv3.TlsParameters_TLSv1_2 || params.TlsMaximumProtocolVersion != tlsv3.TlsParameters_TLSv1_3 {
+		t.Errorf("unexpected tls params %v", params)
+	}
+	if params := tlsParams(""); params != nil {
+		t.Errorf("want nil, got %v", params)
+	}
+}
@@ -0,0 +1,277 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package envoy serves the gateway configuration to envoy over xds. The envoy runs beside
+// the gateway with a bootstrap whose node cluster is --envoy-node-cluster and whose static
+// cluster wutong_xds_cluster points to --envoy-xds-addr. The xds server listens on the loopback
+// address by default, it requires the mutual tls with --envoy-xds-cert, --envoy-xds-key and
+// --envoy-xds-ca otherwise, the private keys of the certificates are served by the sds.
+//
+// The virtual services, the locations, the header and cookie conditions, the session affinity,
+// the certificates, the client certificate authentication and the upstream tls are served the
+// same as the openresty. The rate limits, the ip access lists, the basic authentication and the
+// wake-on-request are checked by the ext_authz service of the gateway, and the access logs are
+// received by the access log service of the gateway and sent to the eventlog, both are served
+// with the xds. The connection limits are only supported by the openresty, the requests of the
+// locations with them are denied.
+package envoy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	configcorev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	alsv3 "github.com/envoyproxy/go-control-plane/envoy/service/accesslog/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/service/cluster/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/service/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/service/listener/v3"
+	secretv3 "github.com/envoyproxy/go-control-plane/envoy/service/secret/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/cmd/gateway/option"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const grpcMaxConcurrentStreams = 1000000
+
+// Service serves the gateway configuration to envoy
+type Service struct {
+	ocfg       *option.Config
+	cache      cache.SnapshotCache
+	server     server.Server
+	grpcServer *grpc.Server
+	ctx        context.Context
+	cancel     context.CancelFunc
+	waker      *waker
+
+	lock    sync.Mutex
+	version int64
+	conf    *v1.Config
+	hpools  []*v1.Pool
+	tpools  []*v1.Pool
+}
+
+// hasher returns the node cluster as the id, all envoy of the gateway share the same snapshot
+type hasher struct{}
+
+// ID function
+func (h hasher) ID(node *configcorev3.Node) string {
+	if node == nil {
+		return "unknown"
+	}
+	return node.Cluster
+}
+
+// CreateEnvoyService create envoy service
+func CreateEnvoyService(config *option.Config) *Service {
+	snapshots := cache.NewSnapshotCache(false, hasher{}, logrus.WithField("module", "gateway-xds-cache"))
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		ocfg:   config,
+		cache:  snapshots,
+		server: server.NewServer(ctx, snapshots, nil),
+		ctx:    ctx,
+		cancel: cancel,
+		waker:  newWaker(config.WorkerAPI, config.WakeToken),
+	}
+}
+
+// Start starts the xds server
+func (s *Service) Start(errCh chan error) error {
+	lis, err := net.Listen("tcp", s.ocfg.EnvoyXDSAddr)
+	if err != nil {
+		return fmt.Errorf("listen xds server %s: %v", s.ocfg.EnvoyXDSAddr, err)
+	}
+	opts := []grpc.ServerOption{grpc.MaxConcurrentStreams(grpcMaxConcurrentStreams)}
+	if s.ocfg.EnvoyXDSCertFile != "" {
+		creds, err := serverCredentials(s.ocfg.EnvoyXDSCertFile, s.ocfg.EnvoyXDSKeyFile, s.ocfg.EnvoyXDSCAFile)
+		if err != nil {
+			lis.Close()
+			return fmt.Errorf("create the mutual tls of xds server: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s.grpcServer = grpc.NewServer(opts...)
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(s.grpcServer, s.server)
+	endpointv3.RegisterEndpointDiscoveryServiceServer(s.grpcServer, s.server)
+	clusterv3.RegisterClusterDiscoveryServiceServer(s.grpcServer, s.server)
+	listenerv3.RegisterListenerDiscoveryServiceServer(s.grpcServer, s.server)
+	secretv3.RegisterSecretDiscoveryServiceServer(s.grpcServer, s.server)
+	authv3.RegisterAuthorizationServer(s.grpcServer, newAuthzServer(s.waker))
+	if s.ocfg.AccessLogServer != "" {
+		alsv3.RegisterAccessLogServiceServer(s.grpcServer, newAccessLogServer(s.ocfg.AccessLogServer))
+	}
+	go s.waker.relay(s.ctx)
+	logrus.Infof("gateway xds server listening %s", s.ocfg.EnvoyXDSAddr)
+	go func() {
+		if err := s.grpcServer.Serve(lis); err != nil {
+			errCh <- err
+		}
+	}()
+	return nil
+}
+
+// serverCredentials requires the client certificates signed by the ca
+func serverCredentials(certFile, keyFile, caFile string) (credentials.TransportCredentials, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in %s", caFile)
+	}
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+// Stop stops the xds server
+func (s *Service) Stop() error {
+	logrus.Info("Stopping gateway xds server")
+	s.cancel()
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+	return nil
+}
+
+// Check returns if the envoy is ready
+func (s *Service) Check() error {
+	url := fmt.Sprintf("http://%s/ready", s.ocfg.EnvoyAdminAddr)
+	client := &http.Client{
+		Timeout:   s.ocfg.HealthCheckTimeout * time.Second,
+		Transport: &http.Transport{DisableKeepAlives: true},
+	}
+	res, err := client.Get(url)
+	if err != nil {
+		logrus.Errorf("error checking %s: %v", url, err)
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("envoy is not ready")
+	}
+	return nil
+}
+
+// PersistConfig sets the snapshot of the virtual services
+func (s *Service) PersistConfig(conf *v1.Config) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.conf = conf
+	if err := s.setSnapshot(); err != nil {
+		return err
+	}
+	s.waker.update(s.hpools)
+	return nil
+}
+
+// UpdatePools sets the snapshot if the pools change
+func (s *Service) UpdatePools(hpools []*v1.Pool, tpools []*v1.Pool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if poolsEqual(s.hpools, hpools) && poolsEqual(s.tpools, tpools) {
+		return nil
+	}
+	s.hpools, s.tpools = hpools, tpools
+	if s.conf == nil {
+		// the listeners are not ready, the pools are served with the first config
+		return nil
+	}
+	if err := s.setSnapshot(); err != nil {
+		return err
+	}
+	// the held requests are released after the endpoints are served
+	s.waker.update(hpools)
+	return nil
+}
+
+func (s *Service) setSnapshot() error {
+	listeners, clusters, endpoints, secrets := buildResources(s.conf, s.hpools, s.tpools, s.ocfg)
+	s.version++
+	version := fmt.Sprintf("version_%d", s.version)
+	snapshot, err := cache.NewSnapshot(version, map[string][]types.Resource{
+		resource.EndpointType: endpoints,
+		resource.ClusterType:  clusters,
+		resource.ListenerType: listeners,
+		resource.SecretType:   secrets,
+	})
+	if err != nil {
+		return err
+	}
+	if err := snapshot.Consistent(); err != nil {
+		return fmt.Errorf("inconsistent gateway snapshot: %v", err)
+	}
+	if err := s.cache.SetSnapshot(s.ctx, s.ocfg.EnvoyNodeCluster, snapshot); err != nil {
+		return err
+	}
+	logrus.Debugf("set gateway xds snapshot %s", version)
+	return nil
+}
+
+// WaitPluginReady waits for envoy to be ready.
+func (s *Service) WaitPluginReady() {
+	for {
+		err := s.Check()
+		if err == nil {
+			logrus.Info("Envoy is ready")
+			break
+		}
+		logrus.Infof("Envoy is not ready yet: %v", err)
+		time.Sleep(1 * time.Second)
+	}
+}
+
+// poolsEqual compares the pools by name, the order of the pools listed by the store is random
+func poolsEqual(a []*v1.Pool, b []*v1.Pool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	pools := make(map[string]*v1.Pool, len(a))
+	for _, pool := range a {
+		pools[pool.Name] = pool
+	}
+	for _, pool := range b {
+		if !pools[pool.Name].Equals(pool) {
+			return false
+		}
+	}
+	return true
+}
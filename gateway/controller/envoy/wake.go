@@ -0,0 +1,153 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package envoy
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/gateway/util"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+)
+
+const (
+	// wakeRelayInterval the interval to relay the wake requests of the held requests to the worker
+	wakeRelayInterval = time.Second
+	// defaultWakeHoldTimeout the default seconds to hold the requests, the same as the openresty
+	defaultWakeHoldTimeout = 60
+)
+
+// waker holds the requests of the pools whose components are scaled to zero, until the
+// endpoints of the pools are served to envoy.
+type waker struct {
+	workerAPI string
+	wakeToken string
+
+	lock sync.Mutex
+	// pools the sleeping pools by name
+	pools map[string]*sleepingPool
+	// requests the service ids of the components to wake up
+	requests map[string]struct{}
+}
+
+type sleepingPool struct {
+	serviceID   string
+	holdTimeout time.Duration
+	// woken is closed if the pool has endpoints
+	woken chan struct{}
+}
+
+func newWaker(workerAPI, wakeToken string) *waker {
+	return &waker{
+		workerAPI: workerAPI,
+		wakeToken: wakeToken,
+		pools:     make(map[string]*sleepingPool),
+		requests:  make(map[string]struct{}),
+	}
+}
+
+// wakeHoldTimeout returns the seconds to hold the requests of the pool
+func wakeHoldTimeout(pool *v1.Pool) int {
+	if pool.WakeHoldTimeout > 0 {
+		return pool.WakeHoldTimeout
+	}
+	return defaultWakeHoldTimeout
+}
+
+// update sets the sleeping pools, the held requests of the pools with endpoints are released.
+// It is called after the endpoints are served to envoy.
+func (w *waker) update(pools []*v1.Pool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	sleeping := make(map[string]*sleepingPool)
+	for _, pool := range pools {
+		if pool.WakeServiceID == "" || len(pool.Nodes) > 0 {
+			continue
+		}
+		sp := w.pools[pool.Name]
+		if sp == nil {
+			sp = &sleepingPool{woken: make(chan struct{})}
+		}
+		sp.serviceID = pool.WakeServiceID
+		sp.holdTimeout = time.Duration(wakeHoldTimeout(pool)) * time.Second
+		sleeping[pool.Name] = sp
+	}
+	for name, sp := range w.pools {
+		if sleeping[name] == nil {
+			close(sp.woken)
+		}
+	}
+	w.pools = sleeping
+}
+
+// wait records the wake request of the pool and holds the request until the pool is woken up,
+// it returns false if the pool is not woken up in the hold timeout.
+func (w *waker) wait(ctx context.Context, pool string) bool {
+	w.lock.Lock()
+	sp := w.pools[pool]
+	if sp != nil {
+		w.requests[sp.serviceID] = struct{}{}
+	}
+	w.lock.Unlock()
+	if sp == nil {
+		return true
+	}
+	timer := time.NewTimer(sp.holdTimeout)
+	defer timer.Stop()
+	select {
+	case <-sp.woken:
+		return true
+	case <-timer.C:
+		logrus.Warningf("pool %s is not woken up in %s", pool, sp.holdTimeout)
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// relay sends the wake requests to the worker, the same as the openresty
+func (w *waker) relay(ctx context.Context) {
+	if w.workerAPI == "" {
+		return
+	}
+	ticker := time.NewTicker(wakeRelayInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		w.lock.Lock()
+		var serviceIDs []string
+		for serviceID := range w.requests {
+			serviceIDs = append(serviceIDs, serviceID)
+		}
+		w.requests = make(map[string]struct{})
+		w.lock.Unlock()
+		if len(serviceIDs) == 0 {
+			continue
+		}
+		if err := util.WakeComponents(w.workerAPI, w.wakeToken, serviceIDs); err != nil {
+			logrus.Warningf("wake up components %v: %v", serviceIDs, err)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/wutong-paas/wutong/gateway/annotations/ipaccess"
 	"github.com/wutong-paas/wutong/gateway/annotations/proxy"
+	"github.com/wutong-paas/wutong/gateway/annotations/ratelimit"
 	"github.com/wutong-paas/wutong/gateway/annotations/rewrite"
 	v1 "github.com/wutong-paas/wutong/gateway/v1"
 )
@@ -34,6 +36,10 @@ type Server struct {
 	Locations               []*Location
 	OptionValue             map[string]string
 	UpstreamName            string //used for tcp and udp server
+	ACMEChallengePass       string // the address of the gateway which serves the acme http-01 challenges
+
+	// ClientAuth verifies the client certificates by the CA bundle
+	ClientAuth *v1.ClientAuth
 
 	// Sets the number of datagrams expected from the proxied server in response
 	// to the client request if the UDP protocol is used.
@@ -127,6 +133,21 @@ type Location struct {
 	// to be used in connections against endpoints
 	// +optional
 	Proxy proxy.Config `json:"proxy,omitempty"`
+
+	// LimitZone the name prefix of the shared memory zones of the rate limit
+	LimitZone string
+	RateLimit ratelimit.Config
+	IPAccess  ipaccess.Config
+	BasicAuth *v1.BasicAuth
+	// UpstreamTLS proxies the requests to the https upstreams
+	UpstreamTLS *v1.UpstreamTLS
+	// ClientAuthVerify verifies the client certificate, on, optional, optional_no_ca or deny
+	ClientAuthVerify      string
+	PassClientCertificate bool
+	// AccessLogServer the syslog server which the access logs tagged with ServiceID and RuleID are sent to
+	AccessLogServer string
+	ServiceID       string
+	RuleID          string
 }
 
 // Validation validation nginx parameters
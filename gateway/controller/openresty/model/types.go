@@ -44,6 +44,15 @@ type Backend struct {
 	UpstreamHashBy string `json:"upstream-hash-by,omitempty"`
 	// LB algorithm configuration per ingress
 	LoadBalancing string `json:"load-balance,omitempty"`
+	// Wake wakes up the component scaled to zero
+	Wake *WakeConfig `json:"wake,omitempty"`
+}
+
+// WakeConfig the requests wake up the component scaled to zero, they are held until
+// the endpoints are ready or the hold timeout is reached.
+type WakeConfig struct {
+	ServiceID   string `json:"service-id"`
+	HoldTimeout int    `json:"hold-timeout"`
 }
 
 // SessionAffinityConfig describes different affinity configurations for new sessions.
@@ -77,6 +86,8 @@ type Endpoint struct {
 	Port string `json:"port"`
 	// Weight weight of the endpoint
 	Weight int `json:"weight"`
+	// Service the kubernetes service of the endpoint
+	Service string `json:"service,omitempty"`
 	// Target returns a reference to the object providing the endpoint
 	Target *apiv1.ObjectReference `json:"target,omitempty"`
 }
@@ -105,8 +116,15 @@ func CreateBackendByPool(pool *v1.Pool) *Backend {
 			Address: node.Host,
 			Port:    strconv.Itoa(int(node.Port)),
 			Weight:  node.Weight,
+			Service: node.Service,
 		})
 	}
 	backend.Endpoints = endpoints
+	if pool.WakeServiceID != "" {
+		backend.Wake = &WakeConfig{
+			ServiceID:   pool.WakeServiceID,
+			HoldTimeout: pool.WakeHoldTimeout,
+		}
+	}
 	return &backend
 }
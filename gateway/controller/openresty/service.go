@@ -20,6 +20,8 @@ package openresty
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -105,6 +107,7 @@ func (o *OrService) Start(errCh chan error) error {
 		return err
 	}
 	logrus.Infof("init openresty config success")
+	go o.relayWakeRequests()
 	go func() {
 		for {
 			logrus.Infof("start openresty progress")
@@ -154,6 +157,25 @@ func (o *OrService) PersistConfig(conf *v1.Config) error {
 	return nil
 }
 
+// accessLogServer resolves the address of the eventlog which receives the access logs, the nginx
+// can not reload if the host of the syslog server can not be resolved.
+func (o *OrService) accessLogServer() string {
+	if o.ocfg.AccessLogServer == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(o.ocfg.AccessLogServer)
+	if err != nil {
+		logrus.Warningf("invalid access log server %s: %v", o.ocfg.AccessLogServer, err)
+		return ""
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil || len(ips) == 0 {
+		logrus.Warningf("resolve access log server %s: %v", host, err)
+		return ""
+	}
+	return net.JoinHostPort(ips[0], port)
+}
+
 // persistUpstreams persists upstreams
 func (o *OrService) persistUpstreams(pools []*v1.Pool) error {
 	streams := make([]model.Backend, 0)
@@ -195,6 +217,7 @@ func (o *OrService) persistUpstreams(pools []*v1.Pool) error {
 }
 
 func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv []*model.Server) {
+	accessLogServer := o.accessLogServer()
 	for _, vs := range conf.L7VS {
 		server := &model.Server{
 			Listen:     strings.Join(vs.Listening, " "),
@@ -214,6 +237,10 @@ func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv
 			server.SSLCertificate = vs.SSLCert.CertificatePem
 			server.SSLCertificateKey = vs.SSLCert.CertificatePem
 			server.EnableSSLStapling = o.ocfg.EnableSSLStapling
+			server.ClientAuth = vs.ClientAuth
+		}
+		if vs.ACMEChallenge && o.ocfg.WorkerAPI != "" {
+			server.ACMEChallengePass = fmt.Sprintf("http://127.0.0.1:%d", o.ocfg.ListenPorts.Health)
 		}
 		for _, loc := range vs.Locations {
 			location := &model.Location{
@@ -227,6 +254,22 @@ func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv
 				Rewrite:          loc.Rewrite,
 				PathRewrite:      loc.PathRewrite,
 				DisableProxyPass: loc.DisableProxyPass,
+				RateLimit:        loc.RateLimit,
+				IPAccess:         loc.IPAccess,
+				BasicAuth:        loc.BasicAuth,
+				UpstreamTLS:      loc.UpstreamTLS,
+			}
+			if server.ClientAuth != nil {
+				location.ClientAuthVerify = loc.ClientAuthVerify
+				location.PassClientCertificate = loc.PassClientCertificate
+			}
+			if accessLogServer != "" && loc.ServiceID != "" {
+				location.AccessLogServer = accessLogServer
+				location.ServiceID = loc.ServiceID
+				location.RuleID = loc.RuleID
+			}
+			if loc.RateLimit.RPS > 0 || loc.RateLimit.Connections > 0 {
+				location.LimitZone = limitZoneName(vs.Namespace, vs.ServerName, loc.Path)
 			}
 			server.Locations = append(server.Locations, location)
 		}
@@ -272,6 +315,12 @@ func (o *OrService) getNgxServer(conf *v1.Config) (l7srv []*model.Server, l4srv
 	return l7srv, l4srv
 }
 
+// limitZoneName returns the unique zone name of the location, the zones are shared by all servers
+func limitZoneName(namespace, serverName, path string) string {
+	sum := sha1.Sum([]byte(namespace + "/" + serverName + path))
+	return "limit_" + hex.EncodeToString(sum[:8])
+}
+
 // UpdatePools updates http upstreams dynamically.
 func (o *OrService) UpdatePools(hpools []*v1.Pool, tpools []*v1.Pool) error {
 	var lock sync.Mutex
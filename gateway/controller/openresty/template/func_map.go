@@ -123,6 +123,8 @@ func buildLuaHeaderRouter(input interface{}) string {
 		}
 	}
 
+	// hold the request if the component is scaled to zero
+	out = append(out, "\t\t\tbalancer.wake()")
 	out = append(out, "\t\t}")
 
 	return strings.Join(out, "\n\r")
@@ -0,0 +1,75 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package openresty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/gateway/util"
+)
+
+// wakeRelayInterval the interval to relay the wake requests of the held requests to the worker
+const wakeRelayInterval = time.Second
+
+// relayWakeRequests pops the components to wake up from nginx and sends them to the worker,
+// nginx holds the requests of the components until they are ready.
+func (o *OrService) relayWakeRequests() {
+	if o.ocfg.WorkerAPI == "" {
+		return
+	}
+	ticker := time.NewTicker(wakeRelayInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if o.IsShuttingDown != nil && *o.IsShuttingDown {
+			return
+		}
+		serviceIDs, err := o.popWakeRequests()
+		if err != nil {
+			logrus.Debugf("pop wake requests: %v", err)
+			continue
+		}
+		if len(serviceIDs) == 0 {
+			continue
+		}
+		if err := util.WakeComponents(o.ocfg.WorkerAPI, o.ocfg.WakeToken, serviceIDs); err != nil {
+			logrus.Warningf("wake up components %v: %v", serviceIDs, err)
+		}
+	}
+}
+
+func (o *OrService) popWakeRequests() ([]string, error) {
+	url := fmt.Sprintf("http://127.0.0.1:%v/config/wake", o.ocfg.ListenPorts.Status)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var serviceIDs []string
+	if err := json.NewDecoder(resp.Body).Decode(&serviceIDs); err != nil {
+		return nil, err
+	}
+	return serviceIDs, nil
+}
@@ -18,14 +18,34 @@
 
 package controller
 
-import v1 "github.com/wutong-paas/wutong/gateway/v1"
+import (
+	"github.com/wutong-paas/wutong/cmd/gateway/option"
+	"github.com/wutong-paas/wutong/gateway/controller/envoy"
+	"github.com/wutong-paas/wutong/gateway/controller/openresty"
+	v1 "github.com/wutong-paas/wutong/gateway/v1"
+)
 
-//GWServicer -
+// GWServicer the data plane backend of the gateway. The controller syncs the same
+// v1.Config and v1.Pool model to every backend.
 type GWServicer interface {
+	// Start starts the data plane, the errors after starting are sent to errCh
 	Start(errCh chan error) error
 	Stop() error
+	// Check returns an error if the data plane is not healthy
 	Check() error
+	// PersistConfig applies the virtual services, it is called only if the configuration changes
 	PersistConfig(conf *v1.Config) error
+	// UpdatePools updates the endpoints of the http and the tcp/udp pools, it is called on every sync
 	UpdatePools(hpools []*v1.Pool, tpools []*v1.Pool) error
 	WaitPluginReady()
 }
+
+// newGWServicer creates the backend of cfg.Backend, the openresty is the default one
+func newGWServicer(cfg *option.Config, isShuttingDown *bool) GWServicer {
+	switch cfg.Backend {
+	case "envoy":
+		return envoy.CreateEnvoyService(cfg)
+	default:
+		return openresty.CreateOpenrestyService(cfg, isShuttingDown)
+	}
+}
@@ -50,6 +50,8 @@ type socketData struct {
 	Namespace      string  `json:"namespace"`
 	ServiceID      string  `json:"service_id"`
 	Path           string  `json:"path"`
+	// UpstreamService the kubernetes service of the upstream that served the request
+	UpstreamService string `json:"upstream_service"`
 }
 
 // SocketCollector stores prometheus metrics and ingress meta-data
@@ -76,6 +78,7 @@ var (
 		"namespace",
 		"service",
 		"service_id",
+		"upstream_service",
 	}
 )
 
@@ -151,7 +154,7 @@ func NewSocketCollector(gatewayHost string, metricsPerHost bool) (*SocketCollect
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 			},
-			[]string{"host", "namespace", "service", "status", "service_id"},
+			[]string{"host", "namespace", "service", "status", "service_id", "upstream_service"},
 		),
 
 		bytesSent: prometheus.NewHistogramVec(
@@ -172,7 +175,7 @@ func NewSocketCollector(gatewayHost string, metricsPerHost bool) (*SocketCollect
 				Namespace:   PrometheusNamespace,
 				ConstLabels: constLabels,
 			},
-			[]string{"namespace", "service", "service_id"},
+			[]string{"namespace", "service", "service_id", "upstream_service"},
 		),
 	}
 
@@ -212,27 +215,30 @@ func (sc *SocketCollector) handleMessage(msg []byte) {
 		}
 		// Note these must match the order in requestTags at the top
 		requestLabels := prometheus.Labels{
-			"status":     stats.Status,
-			"method":     stats.Method,
-			"path":       stats.Path,
-			"namespace":  stats.Namespace,
-			"service":    stats.ServiceID,
-			"service_id": stats.ServiceID,
+			"status":           stats.Status,
+			"method":           stats.Method,
+			"path":             stats.Path,
+			"namespace":        stats.Namespace,
+			"service":          stats.ServiceID,
+			"service_id":       stats.ServiceID,
+			"upstream_service": stats.UpstreamService,
 		}
 		if sc.metricsPerHost {
 			requestLabels["host"] = stats.Host
 		}
 		collectorLabels := prometheus.Labels{
-			"namespace":  stats.Namespace,
-			"service":    stats.ServiceID,
-			"service_id": stats.ServiceID,
-			"status":     stats.Status,
-			"host":       stats.Host,
+			"namespace":        stats.Namespace,
+			"service":          stats.ServiceID,
+			"service_id":       stats.ServiceID,
+			"upstream_service": stats.UpstreamService,
+			"status":           stats.Status,
+			"host":             stats.Host,
 		}
 		latencyLabels := prometheus.Labels{
-			"namespace":  stats.Namespace,
-			"service":    stats.ServiceID,
-			"service_id": stats.ServiceID,
+			"namespace":        stats.Namespace,
+			"service":          stats.ServiceID,
+			"service_id":       stats.ServiceID,
+			"upstream_service": stats.UpstreamService,
 		}
 		requestsMetric, err := sc.requests.GetMetricWith(collectorLabels)
 		if err != nil {
@@ -20,37 +20,88 @@ package store
 
 import (
 	"fmt"
+	"sync"
 
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
 	"github.com/wutong-paas/wutong/util/ingress-nginx/k8s"
 	networkingv1 "k8s.io/api/networking/v1"
 	betav1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type secretIngressMap struct {
+	// v the tls secrets of the ingresses
 	v map[string][]string
+	// refs the ingresses reference the secrets, including the tls and auth secrets
+	refs map[string]map[string]struct{}
+	mu   sync.RWMutex
 }
 
 func (m *secretIngressMap) update(ingress interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ingKey string
+	var meta *metav1.ObjectMeta
+	var secretNames []string
 	nwkIngress, ok := ingress.(*networkingv1.Ingress)
 	if ok {
-		ingKey := k8s.MetaNamespaceKey(nwkIngress)
+		ingKey = k8s.MetaNamespaceKey(nwkIngress)
+		meta = &nwkIngress.ObjectMeta
 		for _, tls := range nwkIngress.Spec.TLS {
 			secretKey := fmt.Sprintf("%s/%s", nwkIngress.Namespace, tls.SecretName)
 			m.v[ingKey] = append(m.v[ingKey], secretKey)
+			secretNames = append(secretNames, tls.SecretName)
 		}
 	} else {
 		betaIngress, ok := ingress.(*betav1.Ingress)
-		if ok {
-			ingKey := k8s.MetaNamespaceKey(betaIngress)
-			for _, tls := range betaIngress.Spec.TLS {
-				secretKey := fmt.Sprintf("%s/%s", betaIngress.Namespace, tls.SecretName)
-				m.v[ingKey] = append(m.v[ingKey], secretKey)
-			}
+		if !ok {
+			return
+		}
+		ingKey = k8s.MetaNamespaceKey(betaIngress)
+		meta = &betaIngress.ObjectMeta
+		for _, tls := range betaIngress.Spec.TLS {
+			secretKey := fmt.Sprintf("%s/%s", betaIngress.Namespace, tls.SecretName)
+			m.v[ingKey] = append(m.v[ingKey], secretKey)
+			secretNames = append(secretNames, tls.SecretName)
+		}
+	}
+	for _, annotation := range []string{"auth-secret", "client-auth-secret", "proxy-ssl-secret"} {
+		if secretName, _ := parser.GetStringAnnotation(annotation, meta); secretName != "" {
+			secretNames = append(secretNames, secretName)
 		}
 	}
 
+	if m.refs == nil {
+		m.refs = make(map[string]map[string]struct{})
+	}
+	for secretKey, ings := range m.refs {
+		delete(ings, ingKey)
+		if len(ings) == 0 {
+			delete(m.refs, secretKey)
+		}
+	}
+	for _, name := range secretNames {
+		secretKey := fmt.Sprintf("%s/%s", meta.Namespace, name)
+		if m.refs[secretKey] == nil {
+			m.refs[secretKey] = make(map[string]struct{})
+		}
+		m.refs[secretKey][ingKey] = struct{}{}
+	}
 }
 
 func (m *secretIngressMap) getSecretKeys(ingKey string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.v[ingKey]
 }
+
+// getIngressKeys returns the ingresses reference the secret
+func (m *secretIngressMap) getIngressKeys(secretKey string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var ingKeys []string
+	for ingKey := range m.refs[secretKey] {
+		ingKeys = append(ingKeys, ingKey)
+	}
+	return ingKeys
+}
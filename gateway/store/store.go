@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
@@ -36,6 +37,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/cmd/gateway/option"
 	"github.com/wutong-paas/wutong/gateway/annotations"
+	"github.com/wutong-paas/wutong/gateway/annotations/auth"
+	"github.com/wutong-paas/wutong/gateway/annotations/clientauth"
 	"github.com/wutong-paas/wutong/gateway/annotations/l4"
 	"github.com/wutong-paas/wutong/gateway/annotations/parser"
 	"github.com/wutong-paas/wutong/gateway/annotations/rewrite"
@@ -68,6 +71,10 @@ const (
 	DeleteEvent EventType = "DELETE"
 	// CertificatePath is the default path of certificate file
 	CertificatePath = "/run/nginx/conf/certificate"
+	// AuthPath is the default path of the htpasswd files of the basic authentication
+	AuthPath = "/run/nginx/conf/auth"
+	// CAPath is the default path of the CA bundles to verify the client and upstream certificates
+	CAPath = "/run/nginx/conf/ca"
 	// DefVirSrvName is the default virtual service name
 	DefVirSrvName = "_"
 )
@@ -104,6 +111,8 @@ type backend struct {
 	weight            int
 	hashBy            string
 	loadBalancingType string
+	wakeServiceID     string
+	wakeHoldTimeout   int
 }
 
 // Event holds the context of an event.
@@ -157,7 +166,8 @@ func New(client kubernetes.Interface,
 		informers: &Informer{},
 		listers:   &Lister{},
 		secretIngressMap: &secretIngressMap{
-			make(map[string][]string),
+			v:    make(map[string][]string),
+			refs: make(map[string]map[string]struct{}),
 		},
 		sslStore:        NewSSLCertTracker(),
 		conf:            conf,
@@ -247,7 +257,7 @@ func New(client kubernetes.Interface,
 			key := ik8s.MetaNamespaceKey(sec)
 
 			// find references in ingresses and update local ssl certs
-			if ings := store.secretIngressMap.getSecretKeys(key); len(ings) > 0 {
+			if ings := store.secretIngressMap.getIngressKeys(key); len(ings) > 0 {
 				logrus.Infof("secret %v was added and it is used in ingress annotations. Parsing...", key)
 				for _, ingKey := range ings {
 					ingress, err := store.GetIngress(ingKey)
@@ -274,7 +284,7 @@ func New(client kubernetes.Interface,
 				key := ik8s.MetaNamespaceKey(curSec)
 
 				// find references in ingresses and update local ssl certs
-				if ings := store.secretIngressMap.getSecretKeys(key); len(ings) > 0 {
+				if ings := store.secretIngressMap.getIngressKeys(key); len(ings) > 0 {
 					logrus.Infof("secret %v was updated and it is used in ingress annotations. Parsing...", key)
 					for _, ingKey := range ings {
 						ing, err := store.GetIngress(ingKey)
@@ -312,7 +322,7 @@ func New(client kubernetes.Interface,
 			key := ik8s.MetaNamespaceKey(sec)
 
 			// find references in ingresses
-			if ings := store.secretIngressMap.getSecretKeys(key); len(ings) > 0 {
+			if ings := store.secretIngressMap.getIngressKeys(key); len(ings) > 0 {
 				logrus.Infof("secret %v was deleted and it is used in ingress annotations. Parsing...", key)
 				updateCh.In() <- Event{
 					Type: DeleteEvent,
@@ -399,6 +409,20 @@ func (s *k8sStore) extractAnnotations(ingress interface{}) {
 	}
 }
 
+func newL7Pool(backend backend) *v1.Pool {
+	pool := &v1.Pool{
+		Nodes: []*v1.Node{},
+	}
+	pool.Name = backend.name
+	// TODO: The tenant env isolation
+	pool.Namespace = "default"
+	pool.UpstreamHashBy = backend.hashBy
+	pool.LoadBalancingType = v1.GetLoadBalancingType(backend.loadBalancingType)
+	pool.WakeServiceID = backend.wakeServiceID
+	pool.WakeHoldTimeout = backend.wakeHoldTimeout
+	return pool
+}
+
 // ListPool returns the list of Pools
 func (s *k8sStore) ListPool() ([]*v1.Pool, []*v1.Pool) {
 	var httpPools []*v1.Pool
@@ -414,14 +438,7 @@ func (s *k8sStore) ListPool() ([]*v1.Pool, []*v1.Pool) {
 			for _, backend := range backends {
 				pool := l7Pools[backend.name]
 				if pool == nil {
-					pool = &v1.Pool{
-						Nodes: []*v1.Node{},
-					}
-					pool.Name = backend.name
-					// TODO: The tenant env isolation
-					pool.Namespace = "default"
-					pool.UpstreamHashBy = backend.hashBy
-					pool.LoadBalancingType = v1.GetLoadBalancingType(backend.loadBalancingType)
+					pool = newL7Pool(backend)
 					l7Pools[backend.name] = pool
 				}
 				for _, ss := range ep.Subsets {
@@ -429,9 +446,10 @@ func (s *k8sStore) ListPool() ([]*v1.Pool, []*v1.Pool) {
 						for _, address := range ss.Addresses {
 							if _, ok := l7PoolMap[epn]; ok { // l7
 								pool.Nodes = append(pool.Nodes, &v1.Node{
-									Host:   address.IP,
-									Port:   port.Port,
-									Weight: backend.weight,
+									Host:    address.IP,
+									Port:    port.Port,
+									Weight:  backend.weight,
+									Service: epn,
 								})
 							}
 						}
@@ -465,6 +483,14 @@ func (s *k8sStore) ListPool() ([]*v1.Pool, []*v1.Pool) {
 					}
 				}
 			}
+		} else {
+			// the component is scaled to zero, keep the pools which wake it up so that the
+			// requests are held by the gateway instead of being refused
+			for _, backend := range l7PoolBackendMap[ep.ObjectMeta.Name] {
+				if backend.wakeServiceID != "" && l7Pools[backend.name] == nil {
+					l7Pools[backend.name] = newL7Pool(backend)
+				}
+			}
 		}
 	}
 	// change map to slice TODO: use map directly
@@ -485,6 +511,8 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 	l4vsMap := make(map[string]*v1.VirtualService)
 	// ServerName-LocationPath -> location
 	srvLocMap := make(map[string]*v1.Location)
+	// host -> namespace, the hosts whose certificates are issued by the ACME controller
+	acmeHosts := make(map[string]string)
 
 	for _, item := range s.listers.Ingress.List() {
 		if !s.ingressIsValid(item) {
@@ -525,6 +553,11 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 				anns = s.annotations.Extract(&ing.ObjectMeta)
 			}
 		}
+		if anns.AutoTLS.Enable {
+			for _, host := range ingressHosts(item) {
+				acmeHosts[host] = ingNamespace
+			}
+		}
 		if anns.L4.L4Enable && anns.L4.L4Port != 0 {
 			// region l4
 			host := strings.Replace(anns.L4.L4Host, " ", "", -1)
@@ -676,10 +709,19 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 							if pathRewrite {
 								location.PathRewrite = true
 							}
+							backendProtocol, _ := parser.GetStringAnnotation("backend-protocol", &ing.ObjectMeta)
+							location.BackendProtocol = strings.ToUpper(backendProtocol)
 							srvLocMap[locKey] = location
 							vs.Locations = append(vs.Locations, location)
 							// the first ingress proxy takes effect
 							location.Proxy = anns.Proxy
+							location.ServiceID = anns.Labels["service_id"]
+							location.RuleID = ing.Name
+							s.setAccessControl(location, anns, ing.Namespace)
+							s.setUpstreamTLS(location, anns, ing.Namespace)
+							if len(hostSSLMap) != 0 {
+								s.setClientAuth(vs, location, anns, ing.Namespace)
+							}
 						}
 						// If their ServiceName is the same, then the new one will overwrite the old one.
 						nameCondition := &v1.Condition{}
@@ -707,6 +749,10 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 						if anns.UpstreamHashBy != "" {
 							backend.hashBy = anns.UpstreamHashBy
 						}
+						if anns.Wake.Enable {
+							backend.wakeServiceID = anns.Labels["service_id"]
+							backend.wakeHoldTimeout = anns.Wake.HoldTimeout
+						}
 						l7PoolBackendMap[path.Backend.ServiceName] = append(l7PoolBackendMap[path.Backend.ServiceName], backend)
 					}
 				}
@@ -789,10 +835,19 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 							if pathRewrite {
 								location.PathRewrite = true
 							}
+							backendProtocol, _ := parser.GetStringAnnotation("backend-protocol", &ing.ObjectMeta)
+							location.BackendProtocol = strings.ToUpper(backendProtocol)
 							srvLocMap[locKey] = location
 							vs.Locations = append(vs.Locations, location)
 							// the first ingress proxy takes effect
 							location.Proxy = anns.Proxy
+							location.ServiceID = anns.Labels["service_id"]
+							location.RuleID = ing.Name
+							s.setAccessControl(location, anns, ing.Namespace)
+							s.setUpstreamTLS(location, anns, ing.Namespace)
+							if len(hostSSLMap) != 0 {
+								s.setClientAuth(vs, location, anns, ing.Namespace)
+							}
 						}
 						// If their ServiceName is the same, then the new one will overwrite the old one.
 						nameCondition := &v1.Condition{}
@@ -820,6 +875,10 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 						if anns.UpstreamHashBy != "" {
 							backend.hashBy = anns.UpstreamHashBy
 						}
+						if anns.Wake.Enable {
+							backend.wakeServiceID = anns.Labels["service_id"]
+							backend.wakeHoldTimeout = anns.Wake.HoldTimeout
+						}
 						l7PoolBackendMap[path.Backend.Service.Name] = append(l7PoolBackendMap[path.Backend.Service.Name], backend)
 					}
 				}
@@ -934,7 +993,51 @@ func (s *k8sStore) ListVirtualService() (l7vs []*v1.VirtualService, l4vs []*v1.V
 			// endregion
 		}
 	}
-	return l7vs, l4vs
+	return s.acmeChallengeServers(l7vs, l7vsMap, acmeHosts), l4vs
+}
+
+// acmeChallengeServers makes the http servers of the auto tls hosts serve the http-01 challenges.
+// The http server is created if the host only has the https one, so that the certificate can
+// be renewed.
+func (s *k8sStore) acmeChallengeServers(l7vs []*v1.VirtualService, l7vsMap map[string]*v1.VirtualService,
+	acmeHosts map[string]string) []*v1.VirtualService {
+	for host, namespace := range acmeHosts {
+		vs := l7vsMap[host]
+		if vs == nil {
+			vs = &v1.VirtualService{
+				Listening:  []string{strconv.Itoa(s.conf.ListenPorts.HTTP)},
+				ServerName: host,
+				Locations:  []*v1.Location{},
+			}
+			vs.Namespace = namespace
+			l7vsMap[host] = vs
+			l7vs = append(l7vs, vs)
+		}
+		vs.ACMEChallenge = true
+	}
+	return l7vs
+}
+
+// ingressHosts returns the hosts of the http rules of the ingress
+func ingressHosts(ingress interface{}) []string {
+	var hosts []string
+	add := func(host string) {
+		host = strings.Replace(host, " ", "", -1)
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	switch ing := ingress.(type) {
+	case *networkingv1.Ingress:
+		for _, rule := range ing.Spec.Rules {
+			add(rule.Host)
+		}
+	case *betav1.Ingress:
+		for _, rule := range ing.Spec.Rules {
+			add(rule.Host)
+		}
+	}
+	return hosts
 }
 
 // ingressIsValid checks if the specified ingress is valid
@@ -1135,6 +1238,120 @@ func (s *k8sStore) getCertificatePem(secrKey string) (*v1.SSLCert, error) {
 	}, nil
 }
 
+// setAccessControl sets the rate limit, the ip access and the basic authentication of the location
+func (s *k8sStore) setAccessControl(location *v1.Location, anns *annotations.Ingress, namespace string) {
+	location.RateLimit = anns.RateLimit
+	location.IPAccess = anns.IPAccess
+	if anns.BasicAuth.Secret != "" {
+		location.BasicAuth = &v1.BasicAuth{
+			Realm:    anns.BasicAuth.Realm,
+			UserFile: s.syncAuthFile(fmt.Sprintf("%s/%s", namespace, anns.BasicAuth.Secret)),
+		}
+	}
+}
+
+// setClientAuth sets the client certificate authentication of the location. The tls virtual service
+// requests the client certificates by the CA bundle of the first location, the locations with another
+// CA bundle or verify depth conflict with it and deny the requests.
+func (s *k8sStore) setClientAuth(vs *v1.VirtualService, location *v1.Location, anns *annotations.Ingress, namespace string) {
+	if anns.ClientAuth.Secret == "" {
+		return
+	}
+	secret := fmt.Sprintf("%s/%s", namespace, anns.ClientAuth.Secret)
+	if vs.ClientAuth == nil {
+		vs.ClientAuth = &v1.ClientAuth{
+			Secret:      secret,
+			CAFile:      s.syncCAFile(secret),
+			Verify:      "optional",
+			VerifyDepth: anns.ClientAuth.VerifyDepth,
+		}
+	}
+	if vs.ClientAuth.Secret != secret || vs.ClientAuth.VerifyDepth != anns.ClientAuth.VerifyDepth {
+		logrus.Warningf("the client certificate authentication of %s%s conflicts with the CA bundle %s of the host, deny the requests",
+			vs.ServerName, location.Path, vs.ClientAuth.Secret)
+		location.ClientAuthVerify = v1.ClientAuthDeny
+		return
+	}
+	if vs.ClientAuth.CAFile == "" {
+		location.ClientAuthVerify = v1.ClientAuthDeny
+		return
+	}
+	if anns.ClientAuth.Verify == "optional_no_ca" {
+		vs.ClientAuth.Verify = anns.ClientAuth.Verify
+	}
+	location.ClientAuthVerify = anns.ClientAuth.Verify
+	location.PassClientCertificate = anns.ClientAuth.PassCertificate
+}
+
+// setUpstreamTLS sets the tls connections to the upstreams of the location
+func (s *k8sStore) setUpstreamTLS(location *v1.Location, anns *annotations.Ingress, namespace string) {
+	if !anns.UpstreamTLS.Enable {
+		return
+	}
+	location.UpstreamTLS = &v1.UpstreamTLS{
+		Verify:      anns.UpstreamTLS.Verify,
+		VerifyDepth: anns.UpstreamTLS.VerifyDepth,
+		ServerName:  anns.UpstreamTLS.ServerName,
+	}
+	if anns.UpstreamTLS.Secret != "" {
+		location.UpstreamTLS.CAFile = s.syncCAFile(fmt.Sprintf("%s/%s", namespace, anns.UpstreamTLS.Secret))
+	}
+}
+
+// syncAuthFile writes the users in the secret to the htpasswd file. The file is empty if the
+// secret does not exist, so that all requests are rejected instead of allowed.
+func (s *k8sStore) syncAuthFile(secrKey string) string {
+	var users []byte
+	item, exists, err := s.listers.Secret.GetByKey(secrKey)
+	if err != nil {
+		logrus.Warningf("get auth secret %s: %v", secrKey, err)
+	} else if !exists {
+		logrus.Warningf("the auth secret named %s does not exist", secrKey)
+	} else {
+		users = item.(*corev1.Secret).Data[auth.SecretKey]
+	}
+	filename := fmt.Sprintf("%s/%s.passwd", AuthPath, strings.Replace(secrKey, "/", "-", 1))
+	writeSecretFile(filename, users)
+	return filename
+}
+
+// syncCAFile writes the CA bundle in the secret to the file. It returns an empty filename if the
+// secret does not exist or there is no valid certificate in it.
+func (s *k8sStore) syncCAFile(secrKey string) string {
+	item, exists, err := s.listers.Secret.GetByKey(secrKey)
+	if err != nil {
+		logrus.Warningf("get ca secret %s: %v", secrKey, err)
+		return ""
+	}
+	if !exists {
+		logrus.Warningf("the ca secret named %s does not exist", secrKey)
+		return ""
+	}
+	ca := item.(*corev1.Secret).Data[clientauth.CASecretKey]
+	if !x509.NewCertPool().AppendCertsFromPEM(ca) {
+		logrus.Warningf("there is no valid certificate in the ca secret named %s", secrKey)
+		return ""
+	}
+	filename := fmt.Sprintf("%s/%s.crt", CAPath, strings.Replace(secrKey, "/", "-", 1))
+	writeSecretFile(filename, ca)
+	return filename
+}
+
+// writeSecretFile writes the data to the file if it changes
+func writeSecretFile(filename string, data []byte) {
+	if old, err := os.ReadFile(filename); err == nil && bytes.Equal(old, data) {
+		return
+	}
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logrus.Errorf("cant not create directory %s: %v", dir, err)
+		return
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		logrus.Errorf("cant not write data to %s: %v", filename, err)
+	}
+}
+
 // GetDefaultBackend returns the default backend
 func (s *k8sStore) GetDefaultBackend() defaults.Backend {
 	return s.GetBackendConfiguration().Backend
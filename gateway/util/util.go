@@ -19,7 +19,11 @@
 package util
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"strings"
 )
 
@@ -33,3 +37,29 @@ func BackendName(name string, ns string) string {
 	name = strings.Replace(name, " ", "", -1)
 	return name
 }
+
+// WakeComponents sends the components scaled to zero to the worker to wake them up,
+// the worker only accepts the requests with its wake token.
+func WakeComponents(workerAPI, token string, serviceIDs []string) error {
+	buf, err := json.Marshal(map[string][]string{"service_ids": serviceIDs})
+	if err != nil {
+		return err
+	}
+	url := strings.TrimSuffix(workerAPI, "/") + "/worker/scaling/wake"
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
@@ -19,7 +19,9 @@
 package v1
 
 import (
+	"github.com/wutong-paas/wutong/gateway/annotations/ipaccess"
 	"github.com/wutong-paas/wutong/gateway/annotations/proxy"
+	"github.com/wutong-paas/wutong/gateway/annotations/ratelimit"
 	"github.com/wutong-paas/wutong/gateway/annotations/rewrite"
 )
 
@@ -35,6 +37,10 @@ var CookieType ConditionType = "cookie"
 // DefaultType -
 var DefaultType ConditionType = "default"
 
+// ClientAuthDeny denies the requests of the location, its client certificate authentication
+// conflicts with the other locations of the host
+const ClientAuthDeny = "deny"
+
 // Location -
 type Location struct {
 	Path          string
@@ -48,6 +54,45 @@ type Location struct {
 	Proxy            proxy.Config `json:"proxy,omitempty"`
 	DisableProxyPass bool
 	PathRewrite      bool `json:"pathRewrite"`
+	// RateLimit limits the request rate and connections of a client ip
+	// +optional
+	RateLimit ratelimit.Config `json:"rateLimit,omitempty"`
+	// IPAccess allows or denies the client ips
+	// +optional
+	IPAccess ipaccess.Config `json:"ipAccess,omitempty"`
+	// BasicAuth requires the basic authentication
+	// +optional
+	BasicAuth *BasicAuth `json:"basicAuth,omitempty"`
+	// UpstreamTLS proxies the requests to the https upstreams
+	// +optional
+	UpstreamTLS *UpstreamTLS `json:"upstreamTLS,omitempty"`
+	// ClientAuthVerify verifies the client certificate, on, optional, optional_no_ca or deny
+	// +optional
+	ClientAuthVerify string `json:"clientAuthVerify,omitempty"`
+	// PassClientCertificate passes the client certificate to the upstreams
+	PassClientCertificate bool `json:"passClientCertificate"`
+	// BackendProtocol the protocol to the upstreams, HTTP, HTTPS, HTTP2 or GRPC, default HTTP
+	BackendProtocol string `json:"backendProtocol,omitempty"`
+	// ServiceID and RuleID tag the access logs of the location
+	ServiceID string `json:"serviceID,omitempty"`
+	RuleID    string `json:"ruleID,omitempty"`
+}
+
+// BasicAuth the basic authentication of a location
+type BasicAuth struct {
+	Realm string `json:"realm"`
+	// UserFile the htpasswd file of the users
+	UserFile string `json:"userFile"`
+}
+
+// UpstreamTLS the tls connections to the upstreams of a location
+type UpstreamTLS struct {
+	Verify bool `json:"verify"`
+	// CAFile the CA bundle to verify the upstream certificates, the requests fail if it is empty and Verify is true
+	CAFile      string `json:"caFile"`
+	VerifyDepth int    `json:"verifyDepth"`
+	// ServerName the SNI and the name to verify the upstream certificate, default the request host
+	ServerName string `json:"serverName"`
 }
 
 // Condition is the condition that the traffic can reach the specified backend
@@ -88,6 +133,40 @@ func (l *Location) Equals(c *Location) bool {
 	if l.PathRewrite != c.PathRewrite {
 		return false
 	}
+
+	if !l.RateLimit.Equal(&c.RateLimit) {
+		return false
+	}
+
+	if !l.IPAccess.Equal(&c.IPAccess) {
+		return false
+	}
+
+	if (l.BasicAuth == nil) != (c.BasicAuth == nil) {
+		return false
+	}
+	if l.BasicAuth != nil && *l.BasicAuth != *c.BasicAuth {
+		return false
+	}
+
+	if (l.UpstreamTLS == nil) != (c.UpstreamTLS == nil) {
+		return false
+	}
+	if l.UpstreamTLS != nil && *l.UpstreamTLS != *c.UpstreamTLS {
+		return false
+	}
+	if l.ClientAuthVerify != c.ClientAuthVerify {
+		return false
+	}
+	if l.PassClientCertificate != c.PassClientCertificate {
+		return false
+	}
+	if l.BackendProtocol != c.BackendProtocol {
+		return false
+	}
+	if l.ServiceID != c.ServiceID || l.RuleID != c.RuleID {
+		return false
+	}
 	return true
 }
 
@@ -28,6 +28,7 @@ type Node struct {
 	PoolName    string `json:"pool_name"` //Belong to the pool
 	Ready       bool   `json:"ready"`     //Whether ready
 	Weight      int    `json:"weight"`
+	Service     string `json:"service"` //The kubernetes service of the node
 	MaxFails    int    `json:"max_fails"`
 	FailTimeout string `json:"fail_timeout"`
 }
@@ -64,6 +65,9 @@ func (n *Node) Equals(c *Node) bool { //
 	if n.Weight != c.Weight {
 		return false
 	}
+	if n.Service != c.Service {
+		return false
+	}
 	if n.MaxFails != c.MaxFails {
 		return false
 	}
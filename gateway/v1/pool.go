@@ -35,6 +35,10 @@ type Pool struct {
 	LeastConn         bool              `json:"least_conn"`
 	Monitors          []Monitor         `json:"monitors"`
 	Nodes             []*Node           `json:"nodes"`
+	// WakeServiceID the requests of the pool wake up the component if it is scaled to zero
+	WakeServiceID string `json:"wake_service_id"`
+	// WakeHoldTimeout the seconds to hold the requests while the component is waking up
+	WakeHoldTimeout int `json:"wake_hold_timeout"`
 }
 
 //Equals -
@@ -66,6 +70,9 @@ func (p *Pool) Equals(c *Pool) bool {
 	if p.LoadBalancingType != c.LoadBalancingType {
 		return false
 	}
+	if p.WakeServiceID != c.WakeServiceID || p.WakeHoldTimeout != c.WakeHoldTimeout {
+		return false
+	}
 
 	if len(p.Monitors) != len(c.Monitors) {
 		return false
@@ -60,6 +60,10 @@ type VirtualService struct {
 	Locations        []*Location            `json:"locations"`
 	ForceSSLRedirect bool                   `json:"force_ssl_redirect"`
 	ExtensionConfig  map[string]interface{} `json:"extension_config"`
+	// ACMEChallenge the http-01 challenges of the ACME server are served by the virtual service
+	ACMEChallenge bool `json:"acme_challenge"`
+	// ClientAuth requires the client certificates signed by the CA
+	ClientAuth *ClientAuth `json:"client_auth,omitempty"`
 
 	TCPKeepaliveEnabled            bool   `json:"tcp_keepalive_enabled"`
 	TCPKeepaliveIdle               string `json:"tcp_keepalive_idle"`
@@ -69,6 +73,18 @@ type VirtualService struct {
 	ProxyStreamNextUpstreamTimeout string `json:"proxy_stream_next_upstream_timeout"`
 }
 
+// ClientAuth the client certificate authentication of a virtual service. The virtual service requests
+// the client certificates optionally, the locations verify them by their ClientAuthVerify.
+type ClientAuth struct {
+	// Secret the namespace/name of the secret of the CA bundle
+	Secret string `json:"secret"`
+	// CAFile the CA bundle to verify the client certificates
+	CAFile string `json:"ca_file"`
+	// Verify optional, or optional_no_ca if any location accepts the untrusted certificates
+	Verify      string `json:"verify"`
+	VerifyDepth int    `json:"verify_depth"`
+}
+
 // Equals equals vs
 func (v *VirtualService) Equals(c *VirtualService) bool {
 	if v == c {
@@ -205,6 +221,15 @@ func (v *VirtualService) Equals(c *VirtualService) bool {
 	if v.ForceSSLRedirect != c.ForceSSLRedirect {
 		return false
 	}
+	if v.ACMEChallenge != c.ACMEChallenge {
+		return false
+	}
+	if (v.ClientAuth == nil) != (c.ClientAuth == nil) {
+		return false
+	}
+	if v.ClientAuth != nil && *v.ClientAuth != *c.ClientAuth {
+		return false
+	}
 	if len(v.ExtensionConfig) != len(c.ExtensionConfig) {
 		return false
 	}
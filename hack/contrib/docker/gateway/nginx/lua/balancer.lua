@@ -18,12 +18,23 @@ local IMPLEMENTATIONS = {
 local _M = {}
 -- save all backend balancer data
 local balancers = {}
+-- the backends which wake up the components scaled to zero
+local wake_backends = {}
+-- the kubernetes services of the endpoints by backend, the weighted backends mix the services
+local backend_services = {}
+-- the service ids of the components to wake up, they are popped by the controller
+local wake_requests = ngx.shared.wake_requests
 
 -- measured in seconds
 -- for an Nginx worker to pick up the new list of upstream peers
 -- it will take <the delay until controller POSTed the backend object to the Nginx endpoint> + BACKENDS_SYNC_INTERVAL
 local BACKENDS_SYNC_INTERVAL = 1
 
+-- measured in seconds
+-- the interval to check whether the held request can be passed to the woken up component
+local WAKE_CHECK_INTERVAL = 0.5
+local DEFAULT_WAKE_HOLD_TIMEOUT = 60
+
 -- get_implementation get backend balance type 
 -- if set sessionAffinityConfig.name is cookie, type is sticky
 -- if set upstream-hash-by, type is chash
@@ -87,10 +98,32 @@ local function sync_backends()
   end
 
   local balancers_to_keep = {}
+  local new_wake_backends = {}
+  local new_backend_services = {}
   for _, new_backend in ipairs(new_backends) do
-    sync_backend(new_backend)
-    balancers_to_keep[new_backend.name] = balancers[new_backend.name]
+    local has_endpoints = new_backend.endpoints and #new_backend.endpoints > 0
+    -- the balancer can not be created without endpoints
+    if has_endpoints then
+      sync_backend(new_backend)
+      balancers_to_keep[new_backend.name] = balancers[new_backend.name]
+      local services = {}
+      for _, endpoint in ipairs(new_backend.endpoints) do
+        if endpoint.service then
+          services[endpoint.address .. ":" .. endpoint.port] = endpoint.service
+        end
+      end
+      new_backend_services[new_backend.name] = services
+    end
+    if new_backend.wake then
+      new_wake_backends[new_backend.name] = {
+        service_id = new_backend.wake["service-id"],
+        hold_timeout = new_backend.wake["hold-timeout"],
+        sleeping = not has_endpoints,
+      }
+    end
   end
+  wake_backends = new_wake_backends
+  backend_services = new_backend_services
 
   for backend_name, _ in pairs(balancers) do
     if not balancers_to_keep[backend_name] then
@@ -115,6 +148,35 @@ function _M.rewrite()
   end
 end
 
+-- wake holds the request of the component scaled to zero until the endpoints are ready
+-- or the hold timeout is reached, the component is woken up by the worker.
+function _M.wake()
+  local backend_name = ngx.var.target
+  local wake = wake_backends[backend_name]
+  if not wake or not wake.sleeping then
+    return
+  end
+
+  local ok, err = wake_requests:set(wake.service_id, ngx.now())
+  if not ok then
+    ngx.log(ngx.ERR, string.format("error when recording the wake request of %s: %s", wake.service_id, tostring(err)))
+  end
+
+  local hold_timeout = wake.hold_timeout or DEFAULT_WAKE_HOLD_TIMEOUT
+  local deadline = ngx.now() + hold_timeout
+  while ngx.now() < deadline do
+    ngx.sleep(WAKE_CHECK_INTERVAL)
+    wake = wake_backends[backend_name]
+    if not wake or not wake.sleeping then
+      return
+    end
+  end
+
+  ngx.log(ngx.WARN, string.format("backend %s is not woken up in %s seconds", backend_name, hold_timeout))
+  ngx.status = ngx.HTTP_SERVICE_UNAVAILABLE
+  return ngx.exit(ngx.status)
+end
+
 function _M.balance()
   local balancer = get_balancer()
   if not balancer then
@@ -136,6 +198,17 @@ function _M.balance()
   ngx.log(ngx.INFO, string.format("successfully set current upstream peer %s: %s", peer, err))
 end
 
+-- upstream_service returns the kubernetes service of the last upstream peer of the request
+function _M.upstream_service()
+  local services = backend_services[ngx.var.target]
+  local upstream_addr = ngx.var.upstream_addr
+  if not services or not upstream_addr then
+    return nil
+  end
+  local peer = upstream_addr:match("([^,%s]+)%s*$")
+  return peer and services[peer]
+end
+
 function _M.log()
   local balancer = get_balancer()
   if not balancer then
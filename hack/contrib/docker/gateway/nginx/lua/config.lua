@@ -1,6 +1,7 @@
 local json = require("cjson")
 
 local configuration_data = ngx.shared.configuration_data
+local wake_requests = ngx.shared.wake_requests
 
 local _M = {
   nameservers = {}
@@ -30,6 +31,18 @@ local function fetch_request_body()
   return body
 end
 
+-- pop_wake_requests returns the service ids of the components to wake up and clears them
+local function pop_wake_requests()
+  local service_ids = wake_requests:get_keys(0)
+  for _, service_id in ipairs(service_ids) do
+    wake_requests:delete(service_id)
+  end
+  if #service_ids == 0 then
+    return "[]"
+  end
+  return json.encode(service_ids)
+end
+
 function _M.call()
   if ngx.var.request_method ~= "POST" and ngx.var.request_method ~= "GET" then
     ngx.log(ngx.ERR, "Only POST and GET requests are allowed!")
@@ -37,6 +50,12 @@ function _M.call()
     return
   end
 
+  if ngx.var.request_uri == "/config/wake" and ngx.var.request_method == "GET" then
+    ngx.status = ngx.HTTP_OK
+    ngx.print(pop_wake_requests())
+    return
+  end
+
   if ngx.var.request_uri ~= "/config/backends" then
     ngx.status = ngx.HTTP_NOT_FOUND
     ngx.print("Not found!")
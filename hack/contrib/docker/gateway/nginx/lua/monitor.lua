@@ -4,6 +4,7 @@ local assert = assert
 local new_tab = require "table.new"
 local clear_tab = require "table.clear"
 local clone_tab = require "table.clone"
+local balancer = require("balancer")
 
 -- if an Nginx worker processes more than (MAX_BATCH_SIZE/FLUSH_INTERVAL) RPS then it will start dropping metrics
 local MAX_BATCH_SIZE = 10000
@@ -25,6 +26,7 @@ local function metrics()
     host = ngx.var.host or "-",
     namespace = ngx.var.tenant_env_id or "-",
     service_id = ngx.var.service_id or "-",
+    upstream_service = balancer.upstream_service() or "-",
     path = ngx.var.location_path or "-",
     method = ngx.var.request_method or "-",
     status = ngx.var.status or "-",
@@ -52,6 +52,7 @@ type Manager struct {
 	conf      option.Config
 	server    Server
 	actionMQ  mq.ActionMQ
+	leases    *grpcserver.LeaseManager
 }
 type Server interface {
 	Server() error
@@ -125,7 +126,8 @@ func NewManager(c option.Config) (*Manager, error) {
 			return nil, err
 		}
 		s := grpc.NewServer()
-		grpcserver.RegisterServer(s, actionMQ)
+		manager.leases = grpcserver.NewLeaseManager(actionMQ, c.MaxDeliveries)
+		grpcserver.RegisterServer(s, actionMQ, manager.leases)
 		// Register reflection service on gRPC server.
 		reflection.Register(s)
 		manager.server = &grpcServer{
@@ -145,6 +147,11 @@ func (m *Manager) Start(errChan chan error) {
 	if err != nil {
 		errChan <- err
 	}
+	if m.leases != nil {
+		if err := m.leases.Start(); err != nil {
+			errChan <- err
+		}
+	}
 	go func() {
 		if err := m.server.Server(); err != nil {
 			logrus.Error("mq api listen error.", err.Error())
@@ -177,6 +184,9 @@ func (m *Manager) Stop() error {
 	logrus.Info("api server is stoping.")
 	m.cancel()
 	//m.server.Close()
+	if m.leases != nil {
+		m.leases.Stop()
+	}
 	return m.actionMQ.Stop()
 }
 
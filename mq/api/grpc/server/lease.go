@@ -0,0 +1,266 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/mq/api/grpc/pb"
+	"github.com/wutong-paas/wutong/mq/api/mq"
+	"github.com/wutong-paas/wutong/mq/client"
+	"github.com/wutong-paas/wutong/util"
+	context "golang.org/x/net/context"
+	proto "google.golang.org/protobuf/proto"
+)
+
+// LeaseManager tracks the messages dequeued with a visibility timeout until
+// they are acked. Expired or nacked messages are enqueued again, and moved to
+// the dead letter topic once they have been delivered maxDeliveries times.
+// The leases are persisted with the queue, so a restart of mq neither loses
+// nor redelivers the messages that are still being handled.
+type LeaseManager struct {
+	actionMQ      mq.ActionMQ
+	maxDeliveries int32
+	leases        map[string]*lease
+	lock          sync.Mutex
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+type lease struct {
+	topic    string
+	message  *pb.TaskMessage
+	deadline time.Time
+}
+
+// leaseRecord the persisted form of a lease
+type leaseRecord struct {
+	Topic    string    `json:"topic"`
+	Message  []byte    `json:"message"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// NewLeaseManager new lease manager
+func NewLeaseManager(actionMQ mq.ActionMQ, maxDeliveries int) *LeaseManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	if maxDeliveries <= 0 {
+		maxDeliveries = 5
+	}
+	return &LeaseManager{
+		actionMQ:      actionMQ,
+		maxDeliveries: int32(maxDeliveries),
+		leases:        make(map[string]*lease),
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+// Start loads the persisted leases and starts to redeliver the expired
+// messages
+func (l *LeaseManager) Start() error {
+	if err := l.load(); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.ctx.Done():
+				return
+			case now := <-ticker.C:
+				l.expire(now)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops to redeliver the expired messages, the leases are kept in the
+// queue and loaded again after restart.
+func (l *LeaseManager) Stop() {
+	l.cancel()
+}
+
+func (l *LeaseManager) load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	records, err := l.actionMQ.Leases(ctx)
+	if err != nil {
+		return fmt.Errorf("load leases failure %s", err.Error())
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for id, value := range records {
+		var record leaseRecord
+		var message pb.TaskMessage
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			logrus.Warningf("drop broken lease %s: %s", id, err.Error())
+			continue
+		}
+		if err := proto.Unmarshal(record.Message, &message); err != nil {
+			logrus.Warningf("drop broken lease %s: %s", id, err.Error())
+			continue
+		}
+		l.leases[id] = &lease{topic: record.Topic, message: &message, deadline: record.Deadline}
+	}
+	logrus.Infof("loaded %d leases", len(records))
+	return nil
+}
+
+// grant leases the message until timeout
+func (l *LeaseManager) grant(topic string, message *pb.TaskMessage, timeout time.Duration) error {
+	message.LeaseId = util.NewUUID()
+	message.DeliveryCount++
+	le := &lease{
+		topic:    topic,
+		message:  proto.Clone(message).(*pb.TaskMessage),
+		deadline: time.Now().Add(timeout),
+	}
+	if err := l.persist(message.LeaseId, le); err != nil {
+		// the message is already dequeued, send it back so it is not lost
+		message.DeliveryCount--
+		if err := l.enqueue(topic, message); err != nil {
+			logrus.Errorf("requeue task %s failure %s", message.TaskId, err.Error())
+		}
+		return fmt.Errorf("persist lease failure %s", err.Error())
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.leases[message.LeaseId] = le
+	return nil
+}
+
+// extend moves the deadline of the lease to timeout from now
+func (l *LeaseManager) extend(leaseID string, timeout time.Duration) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	le, ok := l.leases[leaseID]
+	if !ok {
+		return fmt.Errorf("lease %s is not found or expired", leaseID)
+	}
+	extended := *le
+	extended.deadline = time.Now().Add(timeout)
+	if err := l.persist(leaseID, &extended); err != nil {
+		return fmt.Errorf("persist lease failure %s", err.Error())
+	}
+	l.leases[leaseID] = &extended
+	return nil
+}
+
+func (l *LeaseManager) persist(leaseID string, le *lease) error {
+	message, err := proto.Marshal(le.message)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(leaseRecord{Topic: le.topic, Message: message, Deadline: le.deadline})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	return l.actionMQ.PutLease(ctx, leaseID, string(value))
+}
+
+func (l *LeaseManager) forget(leaseID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if err := l.actionMQ.DeleteLease(ctx, leaseID); err != nil {
+		logrus.Errorf("delete lease %s failure %s", leaseID, err.Error())
+	}
+}
+
+func (l *LeaseManager) take(leaseID string) (*lease, error) {
+	l.lock.Lock()
+	le, ok := l.leases[leaseID]
+	if !ok {
+		l.lock.Unlock()
+		return nil, fmt.Errorf("lease %s is not found or expired", leaseID)
+	}
+	delete(l.leases, leaseID)
+	l.lock.Unlock()
+	return le, nil
+}
+
+func (l *LeaseManager) ack(leaseID string) error {
+	le, err := l.take(leaseID)
+	if err != nil {
+		return err
+	}
+	l.forget(leaseID)
+	logrus.Debugf("task (%s) acked", le.message.TaskId)
+	return nil
+}
+
+func (l *LeaseManager) nack(leaseID, reason string) error {
+	le, err := l.take(leaseID)
+	if err != nil {
+		return err
+	}
+	if err := l.release(le, reason); err != nil {
+		return err
+	}
+	l.forget(leaseID)
+	return nil
+}
+
+func (l *LeaseManager) expire(now time.Time) {
+	expired := make(map[string]*lease)
+	l.lock.Lock()
+	for id, le := range l.leases {
+		if now.After(le.deadline) {
+			expired[id] = le
+			delete(l.leases, id)
+		}
+	}
+	l.lock.Unlock()
+	for id, le := range expired {
+		if err := l.release(le, "visibility timeout expired"); err != nil {
+			logrus.Errorf("release expired task %s failure %s", le.message.TaskId, err.Error())
+			continue
+		}
+		l.forget(id)
+	}
+}
+
+// release delivers the failed message again, or moves it to the dead letter
+// topic if it has failed too many times.
+func (l *LeaseManager) release(le *lease, reason string) error {
+	le.message.LastError = reason
+	if le.message.DeliveryCount >= l.maxDeliveries {
+		logrus.Warningf("task (%s) failed %d times, move to dead letter topic: %s", le.message.TaskId, le.message.DeliveryCount, reason)
+		return l.enqueue(client.DeadLetterTopic(le.topic), le.message)
+	}
+	logrus.Infof("task (%s) will be redelivered: %s", le.message.TaskId, reason)
+	return l.enqueue(le.topic, le.message)
+}
+
+func (l *LeaseManager) enqueue(topic string, message *pb.TaskMessage) error {
+	message.LeaseId = ""
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	return l.actionMQ.Enqueue(ctx, topic, string(data))
+}
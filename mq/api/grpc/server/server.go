@@ -20,10 +20,12 @@ package server
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/mq/api/grpc/pb"
 	"github.com/wutong-paas/wutong/mq/api/mq"
+	"github.com/wutong-paas/wutong/mq/client"
 	"github.com/wutong-paas/wutong/util"
 	context "golang.org/x/net/context"
 	grpc1 "google.golang.org/grpc"
@@ -32,6 +34,7 @@ import (
 
 type mqServer struct {
 	actionMQ mq.ActionMQ
+	leases   *LeaseManager
 	pb.UnimplementedTaskQueueServer
 }
 
@@ -42,6 +45,8 @@ func (s *mqServer) Enqueue(ctx context.Context, in *pb.EnqueueRequest) (*pb.Task
 	if in.Message.TaskId == "" {
 		in.Message.TaskId = util.NewUUID()
 	}
+	// a leased message may be sent back by the consumer
+	in.Message.LeaseId = ""
 	message, err := proto.Marshal(in.Message)
 	if err != nil {
 		return nil, err
@@ -79,13 +84,111 @@ func (s *mqServer) Dequeue(ctx context.Context, in *pb.DequeueRequest) (*pb.Task
 	if err != nil {
 		return nil, err
 	}
+	if in.VisibilityTimeout > 0 {
+		if err := s.leases.grant(in.Topic, &task, time.Duration(in.VisibilityTimeout)*time.Second); err != nil {
+			return nil, err
+		}
+	}
 	logrus.Debugf("task (%s) dnqueue by (%s).", task.GetTaskType(), in.ClientHost)
 	return &task, nil
 }
 
+func (s *mqServer) Ack(ctx context.Context, in *pb.AckRequest) (*pb.TaskReply, error) {
+	if err := s.leases.ack(in.LeaseId); err != nil {
+		return nil, err
+	}
+	return &pb.TaskReply{
+		Status: "success",
+	}, nil
+}
+
+func (s *mqServer) Nack(ctx context.Context, in *pb.NackRequest) (*pb.TaskReply, error) {
+	if err := s.leases.nack(in.LeaseId, in.Reason); err != nil {
+		return nil, err
+	}
+	return &pb.TaskReply{
+		Status: "success",
+	}, nil
+}
+
+func (s *mqServer) Extend(ctx context.Context, in *pb.ExtendRequest) (*pb.TaskReply, error) {
+	if in.VisibilityTimeout <= 0 {
+		return nil, fmt.Errorf("visibility timeout %d is invalid", in.VisibilityTimeout)
+	}
+	if err := s.leases.extend(in.LeaseId, time.Duration(in.VisibilityTimeout)*time.Second); err != nil {
+		return nil, err
+	}
+	return &pb.TaskReply{
+		Status: "success",
+	}, nil
+}
+
+func (s *mqServer) DeadLetters(ctx context.Context, in *pb.DeadLetterRequest) (*pb.DeadLetterReply, error) {
+	if in.Topic == "" || !s.actionMQ.TopicIsExist(in.Topic) {
+		return nil, fmt.Errorf("topic %s is not support", in.Topic)
+	}
+	topic := client.DeadLetterTopic(in.Topic)
+	messages, err := s.actionMQ.Range(ctx, topic, int(in.Limit))
+	if err != nil {
+		return nil, err
+	}
+	reply := &pb.DeadLetterReply{
+		Total: s.actionMQ.MessageQueueSize(topic),
+	}
+	for _, message := range messages {
+		var task pb.TaskMessage
+		if err := proto.Unmarshal([]byte(message), &task); err != nil {
+			logrus.Warningf("unmarshal dead letter of topic %s failure %s", in.Topic, err.Error())
+			continue
+		}
+		reply.Messages = append(reply.Messages, &task)
+	}
+	return reply, nil
+}
+
+func (s *mqServer) ReplayDeadLetters(ctx context.Context, in *pb.ReplayRequest) (*pb.TaskReply, error) {
+	if in.Topic == "" || !s.actionMQ.TopicIsExist(in.Topic) {
+		return nil, fmt.Errorf("topic %s is not support", in.Topic)
+	}
+	topic := client.DeadLetterTopic(in.Topic)
+	count := s.actionMQ.MessageQueueSize(topic)
+	if in.Count > 0 && int64(in.Count) < count {
+		count = int64(in.Count)
+	}
+	var replayed int64
+	for ; replayed < count; replayed++ {
+		ok, err := s.actionMQ.Requeue(ctx, topic, in.Topic, func(message string) (string, error) {
+			var task pb.TaskMessage
+			if err := proto.Unmarshal([]byte(message), &task); err != nil {
+				logrus.Warningf("drop broken dead letter of topic %s: %s", in.Topic, err.Error())
+				return "", mq.ErrDropMessage
+			}
+			task.DeliveryCount = 0
+			task.LastError = ""
+			data, err := proto.Marshal(&task)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("replay dead letters of topic %s failure %s", in.Topic, err.Error())
+		}
+		if !ok {
+			break
+		}
+	}
+	logrus.Infof("replayed %d dead letters of topic %s", replayed, in.Topic)
+	return &pb.TaskReply{
+		Status:  "success",
+		Message: fmt.Sprintf("replayed %d messages", replayed),
+	}, nil
+}
+
 // RegisterServer 注册服务
-func RegisterServer(server *grpc1.Server, actionMQ mq.ActionMQ) {
+func RegisterServer(server *grpc1.Server, actionMQ mq.ActionMQ, leases *LeaseManager) {
 	pb.RegisterTaskQueueServer(server, &mqServer{
 		actionMQ: actionMQ,
+		leases:   leases,
 	})
 }
@@ -0,0 +1,176 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wutong-paas/wutong/cmd/mq/option"
+	"github.com/wutong-paas/wutong/mq/api/grpc/pb"
+	"github.com/wutong-paas/wutong/mq/api/mq"
+	"github.com/wutong-paas/wutong/mq/client"
+	context "golang.org/x/net/context"
+)
+
+func newTestServer(t *testing.T) (*mqServer, func()) {
+	actionMQ := mq.NewActionMQ(context.TODO(), option.Config{
+		QueueBackend: "local",
+		QueueDataDir: t.TempDir(),
+	})
+	if err := actionMQ.Start(); err != nil {
+		t.Fatal(err)
+	}
+	leases := NewLeaseManager(actionMQ, 2)
+	return &mqServer{actionMQ: actionMQ, leases: leases}, func() {
+		leases.Stop()
+		actionMQ.Stop()
+	}
+}
+
+func TestLeaseRedeliveryAndDeadLetter(t *testing.T) {
+	s, stop := newTestServer(t)
+	defer stop()
+	ctx := context.Background()
+	if _, err := s.Enqueue(ctx, &pb.EnqueueRequest{Topic: client.BuilderTopic, Message: &pb.TaskMessage{TaskType: "build"}}); err != nil {
+		t.Fatal(err)
+	}
+	dequeue := func() *pb.TaskMessage {
+		task, err := s.Dequeue(ctx, &pb.DequeueRequest{Topic: client.BuilderTopic, VisibilityTimeout: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.LeaseId == "" {
+			t.Fatal("leased task has no lease id")
+		}
+		return task
+	}
+
+	// the first delivery expires and the task is redelivered
+	task := dequeue()
+	s.leases.expire(time.Now().Add(2 * time.Second))
+	if _, err := s.Ack(ctx, &pb.AckRequest{LeaseId: task.LeaseId}); err == nil {
+		t.Fatal("ack of an expired lease should fail")
+	}
+	task = dequeue()
+	if task.DeliveryCount != 2 {
+		t.Fatalf("expected delivery count 2, got %d", task.DeliveryCount)
+	}
+
+	// the second failure moves it to the dead letter topic
+	if _, err := s.Nack(ctx, &pb.NackRequest{LeaseId: task.LeaseId, Reason: "build failure"}); err != nil {
+		t.Fatal(err)
+	}
+	if size := s.actionMQ.MessageQueueSize(client.BuilderTopic); size != 0 {
+		t.Fatalf("expected empty topic, got %d", size)
+	}
+	reply, err := s.DeadLetters(ctx, &pb.DeadLetterRequest{Topic: client.BuilderTopic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Total != 1 || len(reply.Messages) != 1 || reply.Messages[0].LastError != "build failure" {
+		t.Fatalf("unexpected dead letters %v", reply)
+	}
+
+	// replay sends it back with a fresh delivery count
+	if _, err := s.ReplayDeadLetters(ctx, &pb.ReplayRequest{Topic: client.BuilderTopic}); err != nil {
+		t.Fatal(err)
+	}
+	task = dequeue()
+	if task.DeliveryCount != 1 || task.TaskId != reply.Messages[0].TaskId {
+		t.Fatalf("unexpected replayed task %v", task)
+	}
+	if _, err := s.Ack(ctx, &pb.AckRequest{LeaseId: task.LeaseId}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLeasePersistedOnRestart(t *testing.T) {
+	dir := t.TempDir()
+	start := func() (*mqServer, func()) {
+		actionMQ := mq.NewActionMQ(context.TODO(), option.Config{
+			QueueBackend: "local",
+			QueueDataDir: dir,
+		})
+		if err := actionMQ.Start(); err != nil {
+			t.Fatal(err)
+		}
+		leases := NewLeaseManager(actionMQ, 2)
+		if err := leases.Start(); err != nil {
+			t.Fatal(err)
+		}
+		return &mqServer{actionMQ: actionMQ, leases: leases}, func() {
+			leases.Stop()
+			actionMQ.Stop()
+		}
+	}
+	ctx := context.Background()
+	s, stop := start()
+	if _, err := s.Enqueue(ctx, &pb.EnqueueRequest{Topic: client.WorkerTopic, Message: &pb.TaskMessage{TaskType: "start"}}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.Dequeue(ctx, &pb.DequeueRequest{Topic: client.WorkerTopic, VisibilityTimeout: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	// the task is still leased after restart, neither lost nor redelivered
+	s, stop = start()
+	defer stop()
+	if size := s.actionMQ.MessageQueueSize(client.WorkerTopic); size != 0 {
+		t.Fatalf("expected the leased task not to be requeued, got %d", size)
+	}
+	if _, err := s.Ack(ctx, &pb.AckRequest{LeaseId: task.LeaseId}); err != nil {
+		t.Fatal(err)
+	}
+	leases, err := s.actionMQ.Leases(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leases) != 0 {
+		t.Fatalf("expected the acked lease to be deleted, got %v", leases)
+	}
+}
+
+func TestLeaseExtend(t *testing.T) {
+	s, stop := newTestServer(t)
+	defer stop()
+	ctx := context.Background()
+	if _, err := s.Enqueue(ctx, &pb.EnqueueRequest{Topic: client.BuilderTopic, Message: &pb.TaskMessage{TaskType: "build"}}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := s.Dequeue(ctx, &pb.DequeueRequest{Topic: client.BuilderTopic, VisibilityTimeout: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Extend(ctx, &pb.ExtendRequest{LeaseId: task.LeaseId, VisibilityTimeout: 60}); err != nil {
+		t.Fatal(err)
+	}
+	// the original deadline has passed, but the lease is extended
+	s.leases.expire(time.Now().Add(2 * time.Second))
+	if size := s.actionMQ.MessageQueueSize(client.BuilderTopic); size != 0 {
+		t.Fatalf("expected the extended task not to be redelivered, got %d", size)
+	}
+	if _, err := s.Ack(ctx, &pb.AckRequest{LeaseId: task.LeaseId}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Extend(ctx, &pb.ExtendRequest{LeaseId: task.LeaseId, VisibilityTimeout: 60}); err == nil {
+		t.Fatal("extend of an acked lease should fail")
+	}
+}
@@ -0,0 +1,625 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package mq
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/cmd/mq/option"
+	"golang.org/x/net/context"
+)
+
+const (
+	recordEnqueue     byte = 1
+	recordDequeue     byte = 2
+	recordLeasePut    byte = 3
+	recordLeaseDelete byte = 4
+
+	// record header: type(1) + payload length(4) + payload crc32(4)
+	recordHeaderSize = 9
+	maxRecordSize    = 64 << 20
+	logFileSuffix    = ".log"
+	// leaseFile the log of the leases, it is not loaded as a topic
+	leaseFile = "leases.lease"
+)
+
+// ErrQueueClosed the local queue is stopped
+var ErrQueueClosed = errors.New("message queue is closed")
+
+var errCorruptRecord = errors.New("corrupt queue record")
+
+// localQueue is an embedded ActionMQ backend that does not depend on etcd.
+// Every topic is an append-only log file in the data dir, each write is
+// fsynced before it returns, and the log is rewritten with only the pending
+// messages once enough of them have been dequeued. The leases are kept in
+// another log the same way.
+type localQueue struct {
+	config      option.Config
+	ctx         context.Context
+	cancel      context.CancelFunc
+	dir         string
+	compactSize int
+	queues      map[string]*localTopic
+	queuesLock  sync.Mutex
+	// requeueLock serializes Requeue, the only one holding two topic locks
+	requeueLock sync.Mutex
+	leases      *localLeases
+}
+
+type localLeases struct {
+	lock    sync.Mutex
+	path    string
+	file    *os.File
+	leases  map[string]string
+	deleted int
+}
+
+type localTopic struct {
+	lock     sync.Mutex
+	path     string
+	file     *os.File
+	messages []string
+	dequeued int
+	notify   chan struct{}
+}
+
+func newLocalQueue(ctx context.Context, c option.Config) *localQueue {
+	ctx, cancel := context.WithCancel(ctx)
+	compactSize := c.QueueCompactSize
+	if compactSize <= 0 {
+		compactSize = 1000
+	}
+	return &localQueue{
+		config:      c,
+		ctx:         ctx,
+		cancel:      cancel,
+		dir:         c.QueueDataDir,
+		compactSize: compactSize,
+		queues:      make(map[string]*localTopic),
+	}
+}
+
+func (l *localQueue) Start() error {
+	logrus.Debugf("local message queue starting, data dir %s", l.dir)
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return fmt.Errorf("create queue data dir %s failure %s", l.dir, err.Error())
+	}
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return err
+	}
+	// reload the topics left by the last run, they may still hold messages
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), logFileSuffix) {
+			continue
+		}
+		topic, err := url.PathUnescape(strings.TrimSuffix(entry.Name(), logFileSuffix))
+		if err != nil {
+			logrus.Warningf("skip unknown queue file %s", entry.Name())
+			continue
+		}
+		if _, err := l.getTopic(topic); err != nil {
+			return err
+		}
+	}
+	for _, t := range defaultTopics() {
+		if _, err := l.getTopic(t); err != nil {
+			return err
+		}
+	}
+	leases, err := openLocalLeases(filepath.Join(l.dir, leaseFile))
+	if err != nil {
+		return fmt.Errorf("open leases failure %s", err.Error())
+	}
+	l.leases = leases
+	logrus.Info("local message queue started success")
+	return nil
+}
+
+// getTopic returns the topic queue, opening its log file on first use.
+func (l *localQueue) getTopic(topic string) (*localTopic, error) {
+	l.queuesLock.Lock()
+	defer l.queuesLock.Unlock()
+	if t, ok := l.queues[topic]; ok {
+		return t, nil
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("topic can not be empty")
+	}
+	t, err := openLocalTopic(filepath.Join(l.dir, url.PathEscape(topic)+logFileSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("open topic %s failure %s", topic, err.Error())
+	}
+	l.queues[topic] = t
+	return t, nil
+}
+
+func (l *localQueue) TopicIsExist(topic string) bool {
+	l.queuesLock.Lock()
+	defer l.queuesLock.Unlock()
+	_, ok := l.queues[topic]
+	return ok
+}
+
+func (l *localQueue) GetAllTopics() []string {
+	l.queuesLock.Lock()
+	defer l.queuesLock.Unlock()
+	var topics []string
+	for k := range l.queues {
+		topics = append(topics, k)
+	}
+	return topics
+}
+
+func (l *localQueue) Stop() error {
+	l.cancel()
+	l.queuesLock.Lock()
+	defer l.queuesLock.Unlock()
+	for _, t := range l.queues {
+		t.close()
+	}
+	if l.leases != nil {
+		l.leases.close()
+	}
+	return nil
+}
+
+func (l *localQueue) Enqueue(ctx context.Context, topic, value string) error {
+	EnqueueNumber++
+	t, err := l.getTopic(topic)
+	if err != nil {
+		return err
+	}
+	return t.push(value)
+}
+
+// Dequeue returns messages in FIFO order. If the topic is empty, Dequeue
+// blocks until a message is available or the context is done.
+func (l *localQueue) Dequeue(ctx context.Context, topic string) (string, error) {
+	DequeueNumber++
+	t, err := l.getTopic(topic)
+	if err != nil {
+		return "", err
+	}
+	for {
+		value, ok, notify, err := t.pop(l.compactSize)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return value, nil
+		}
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-l.ctx.Done():
+			return "", ErrQueueClosed
+		}
+	}
+}
+
+func (l *localQueue) MessageQueueSize(topic string) int64 {
+	l.queuesLock.Lock()
+	t, ok := l.queues[topic]
+	l.queuesLock.Unlock()
+	if !ok {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return int64(len(t.messages))
+}
+
+func (l *localQueue) Range(ctx context.Context, topic string, limit int) ([]string, error) {
+	l.queuesLock.Lock()
+	t, ok := l.queues[topic]
+	l.queuesLock.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if limit <= 0 || limit > len(t.messages) {
+		limit = len(t.messages)
+	}
+	return append([]string(nil), t.messages[:limit]...), nil
+}
+
+// Requeue appends the message to to before the dequeue record is written to
+// from, a crash in between leaves the message in both rather than in none.
+func (l *localQueue) Requeue(ctx context.Context, from, to string, convert func(string) (string, error)) (bool, error) {
+	if from == to {
+		return false, fmt.Errorf("can not requeue topic %s to itself", from)
+	}
+	src, err := l.getTopic(from)
+	if err != nil {
+		return false, err
+	}
+	dst, err := l.getTopic(to)
+	if err != nil {
+		return false, err
+	}
+	l.requeueLock.Lock()
+	defer l.requeueLock.Unlock()
+	src.lock.Lock()
+	defer src.lock.Unlock()
+	if src.file == nil {
+		return false, ErrQueueClosed
+	}
+	if len(src.messages) == 0 {
+		return false, nil
+	}
+	value, err := convert(src.messages[0])
+	if err != nil && err != ErrDropMessage {
+		return false, err
+	}
+	if err == nil {
+		if err := dst.push(value); err != nil {
+			return false, err
+		}
+	}
+	if _, err := src.shift(l.compactSize); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *localQueue) PutLease(ctx context.Context, id, value string) error {
+	if l.leases == nil {
+		return ErrQueueClosed
+	}
+	return l.leases.put(id, value)
+}
+
+func (l *localQueue) DeleteLease(ctx context.Context, id string) error {
+	if l.leases == nil {
+		return ErrQueueClosed
+	}
+	return l.leases.delete(id, l.compactSize)
+}
+
+func (l *localQueue) Leases(ctx context.Context) (map[string]string, error) {
+	if l.leases == nil {
+		return nil, ErrQueueClosed
+	}
+	l.leases.lock.Lock()
+	defer l.leases.lock.Unlock()
+	leases := make(map[string]string, len(l.leases.leases))
+	for id, value := range l.leases.leases {
+		leases[id] = value
+	}
+	return leases, nil
+}
+
+func openLocalLeases(path string) (*localLeases, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l := &localLeases{path: path, file: file, leases: make(map[string]string)}
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		typ, payload, err := readRecord(reader)
+		if err != nil {
+			if err == io.ErrUnexpectedEOF || err == errCorruptRecord {
+				logrus.Warningf("lease log %s is broken at offset %d, the rest will be dropped", path, offset)
+			} else if err != io.EOF {
+				file.Close()
+				return nil, err
+			}
+			break
+		}
+		switch typ {
+		case recordLeasePut:
+			if id, value, ok := strings.Cut(string(payload), "\n"); ok {
+				l.leases[id] = value
+			}
+		case recordLeaseDelete:
+			delete(l.leases, string(payload))
+			l.deleted++
+		}
+		offset += int64(recordHeaderSize + len(payload))
+	}
+	// drop the torn tail left by a crash in the middle of a write
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *localLeases) put(id, value string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return ErrQueueClosed
+	}
+	if err := syncRecord(l.file, recordLeasePut, []byte(id+"\n"+value)); err != nil {
+		return err
+	}
+	l.leases[id] = value
+	return nil
+}
+
+func (l *localLeases) delete(id string, compactSize int) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file == nil {
+		return ErrQueueClosed
+	}
+	if _, ok := l.leases[id]; !ok {
+		return nil
+	}
+	if err := syncRecord(l.file, recordLeaseDelete, []byte(id)); err != nil {
+		return err
+	}
+	delete(l.leases, id)
+	l.deleted++
+	if l.deleted >= compactSize && l.deleted >= len(l.leases) {
+		if err := l.compact(); err != nil {
+			logrus.Errorf("compact lease log %s failure %s", l.path, err.Error())
+		}
+	}
+	return nil
+}
+
+// compact rewrites the log with only the live leases
+func (l *localLeases) compact() error {
+	var records [][]byte
+	for id, value := range l.leases {
+		records = append(records, []byte(id+"\n"+value))
+	}
+	file, err := rewriteLog(l.path, recordLeasePut, records)
+	if err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file = file
+	l.deleted = 0
+	return nil
+}
+
+func (l *localLeases) close() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+func openLocalTopic(path string) (*localTopic, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t := &localTopic{path: path, file: file, notify: make(chan struct{})}
+	offset, err := t.replay()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// drop the torn tail left by a crash in the middle of a write
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// replay rebuilds the pending messages from the log and returns the offset
+// of the last complete record.
+func (t *localTopic) replay() (int64, error) {
+	reader := bufio.NewReader(t.file)
+	var offset int64
+	for {
+		typ, payload, err := readRecord(reader)
+		if err != nil {
+			if err == io.EOF {
+				return offset, nil
+			}
+			if err == io.ErrUnexpectedEOF || err == errCorruptRecord {
+				logrus.Warningf("queue log %s is broken at offset %d, the rest will be dropped", t.path, offset)
+				return offset, nil
+			}
+			return offset, err
+		}
+		switch typ {
+		case recordEnqueue:
+			t.messages = append(t.messages, string(payload))
+		case recordDequeue:
+			if len(t.messages) > 0 {
+				t.messages = t.messages[1:]
+			}
+			t.dequeued++
+		}
+		offset += int64(recordHeaderSize + len(payload))
+	}
+}
+
+func (t *localTopic) push(value string) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file == nil {
+		return ErrQueueClosed
+	}
+	if err := t.append(recordEnqueue, []byte(value)); err != nil {
+		return err
+	}
+	t.messages = append(t.messages, value)
+	close(t.notify)
+	t.notify = make(chan struct{})
+	return nil
+}
+
+// pop removes the first message. If there is none, it returns a channel that
+// is closed on the next push.
+func (t *localTopic) pop(compactSize int) (string, bool, <-chan struct{}, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file == nil {
+		return "", false, nil, ErrQueueClosed
+	}
+	if len(t.messages) == 0 {
+		return "", false, t.notify, nil
+	}
+	value, err := t.shift(compactSize)
+	if err != nil {
+		return "", false, nil, err
+	}
+	return value, true, nil, nil
+}
+
+// shift removes the first message, the caller holds the lock and makes sure
+// there is one.
+func (t *localTopic) shift(compactSize int) (string, error) {
+	if err := t.append(recordDequeue, nil); err != nil {
+		return "", err
+	}
+	value := t.messages[0]
+	t.messages = t.messages[1:]
+	t.dequeued++
+	if t.dequeued >= compactSize && t.dequeued >= len(t.messages) {
+		if err := t.compact(); err != nil {
+			logrus.Errorf("compact queue log %s failure %s", t.path, err.Error())
+		}
+	}
+	return value, nil
+}
+
+func (t *localTopic) append(typ byte, payload []byte) error {
+	return syncRecord(t.file, typ, payload)
+}
+
+// syncRecord writes the record and fsyncs the file
+func syncRecord(file *os.File, typ byte, payload []byte) error {
+	if err := writeRecord(file, typ, payload); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// compact rewrites the log with only the pending messages and atomically
+// replaces the old one.
+func (t *localTopic) compact() error {
+	var records [][]byte
+	for _, m := range t.messages {
+		records = append(records, []byte(m))
+	}
+	file, err := rewriteLog(t.path, recordEnqueue, records)
+	if err != nil {
+		return err
+	}
+	t.file.Close()
+	t.file = file
+	t.messages = append([]string(nil), t.messages...)
+	t.dequeued = 0
+	return nil
+}
+
+// rewriteLog writes the records to a new log and atomically replaces the old
+// one, it returns the new log opened for appending.
+func rewriteLog(path string, typ byte, records [][]byte) (*os.File, error) {
+	tmp := path + ".compact"
+	file, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		if err := writeRecord(writer, typ, record); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return file, nil
+}
+
+func (t *localTopic) close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+func writeRecord(w io.Writer, typ byte, payload []byte) error {
+	buf := make([]byte, recordHeaderSize+len(payload))
+	buf[0] = typ
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[5:9], crc32.ChecksumIEEE(payload))
+	copy(buf[recordHeaderSize:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readRecord(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, recordHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	typ := header[0]
+	if typ < recordEnqueue || typ > recordLeaseDelete {
+		return 0, nil, errCorruptRecord
+	}
+	size := binary.BigEndian.Uint32(header[1:5])
+	if size > maxRecordSize {
+		return 0, nil, errCorruptRecord
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			return 0, nil, io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[5:9]) {
+		return 0, nil, errCorruptRecord
+	}
+	return typ, payload, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package mq
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wutong-paas/wutong/cmd/mq/option"
+)
+
+func TestLocalQueueRecover(t *testing.T) {
+	conf := option.Config{
+		QueueBackend:     "local",
+		QueueDataDir:     t.TempDir(),
+		QueueCompactSize: 3,
+	}
+	ctx := context.Background()
+	mq := NewActionMQ(ctx, conf)
+	if err := mq.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		if err := mq.Enqueue(ctx, "builder", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the third dequeue triggers a compaction
+	for _, v := range []string{"a", "b", "c"} {
+		if got, _ := mq.Dequeue(ctx, "builder"); got != v {
+			t.Fatalf("expected %s, got %s", v, got)
+		}
+	}
+	mq.Stop()
+
+	// simulate a crash in the middle of writing a record
+	f, err := os.OpenFile(filepath.Join(conf.QueueDataDir, "builder.log"), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{recordEnqueue, 0, 0, 0, 10, 1, 2})
+	f.Close()
+
+	mq = NewActionMQ(ctx, conf)
+	if err := mq.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer mq.Stop()
+	if size := mq.MessageQueueSize("builder"); size != 2 {
+		t.Fatalf("expected 2 pending messages, got %d", size)
+	}
+	if err := mq.Enqueue(ctx, "builder", "f"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"d", "e", "f"} {
+		if got, _ := mq.Dequeue(ctx, "builder"); got != v {
+			t.Fatalf("expected %s, got %s", v, got)
+		}
+	}
+}
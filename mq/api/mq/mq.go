@@ -19,6 +19,8 @@
 package mq
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -44,16 +46,35 @@ type ActionMQ interface {
 	Start() error
 	Stop() error
 	MessageQueueSize(topic string) int64
+	// Range returns up to limit messages of the topic in FIFO order without
+	// removing them, limit <= 0 returns all of them.
+	Range(ctx context.Context, topic string, limit int) ([]string, error)
+	// Requeue moves the first message of from to the end of to, convert may
+	// rewrite it on the way. The message is only removed from from once it is
+	// in to, so it is never lost. It returns false if from is empty.
+	Requeue(ctx context.Context, from, to string, convert func(string) (string, error)) (bool, error)
+	// PutLease, DeleteLease and Leases persist the leases of the dequeued
+	// messages with the queue, so they survive the restart of mq.
+	PutLease(ctx context.Context, id, value string) error
+	DeleteLease(ctx context.Context, id string) error
+	Leases(ctx context.Context) (map[string]string, error)
 }
 
+// ErrDropMessage is returned by the convert func of Requeue to drop the message
+// instead of moving it.
+var ErrDropMessage = errors.New("drop message")
+
 // EnqueueNumber enqueue number
 var EnqueueNumber float64 = 0
 
 // DequeueNumber dequeue number
 var DequeueNumber float64 = 0
 
-// NewActionMQ new etcd mq
+// NewActionMQ new mq by the configured queue backend
 func NewActionMQ(ctx context.Context, c option.Config) ActionMQ {
+	if c.QueueBackend == "local" {
+		return newLocalQueue(ctx, c)
+	}
 	etcdQueue := etcdQueue{
 		config: c,
 		ctx:    ctx,
@@ -85,20 +106,22 @@ func (e *etcdQueue) Start() error {
 		return err
 	}
 	e.client = cli
-	topics := os.Getenv("topics")
-	if topics != "" {
-		ts := strings.Split(topics, ",")
-		for _, t := range ts {
-			e.registerTopic(t)
-		}
+	for _, t := range defaultTopics() {
+		e.registerTopic(t)
 	}
-	e.registerTopic(client.BuilderTopic)
-	e.registerTopic(client.WindowsBuilderTopic)
-	e.registerTopic(client.WorkerTopic)
 	logrus.Info("etcd message queue client started success")
 	return nil
 }
 
+// defaultTopics returns the topics registered on start, including the ones from env
+func defaultTopics() []string {
+	var topics []string
+	if ts := os.Getenv("topics"); ts != "" {
+		topics = append(topics, strings.Split(ts, ",")...)
+	}
+	return append(topics, client.BuilderTopic, client.WindowsBuilderTopic, client.WorkerTopic, client.BuilderCancelTopic)
+}
+
 // registerTopic 注册消息队列主题
 func (e *etcdQueue) registerTopic(topic string) {
 	e.queuesLock.Lock()
@@ -153,3 +176,78 @@ func (e *etcdQueue) MessageQueueSize(topic string) int64 {
 	}
 	return 0
 }
+
+func (e *etcdQueue) Range(ctx context.Context, topic string, limit int) ([]string, error) {
+	opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByCreateRevision, clientv3.SortAscend)}
+	if limit > 0 {
+		opts = append(opts, clientv3.WithLimit(int64(limit)))
+	}
+	res, err := e.client.Get(ctx, e.queueKey(topic)+"/", opts...)
+	if err != nil {
+		return nil, err
+	}
+	var messages []string
+	for _, kv := range res.Kvs {
+		messages = append(messages, string(kv.Value))
+	}
+	return messages, nil
+}
+
+func (e *etcdQueue) Requeue(ctx context.Context, from, to string, convert func(string) (string, error)) (bool, error) {
+	for {
+		res, err := e.client.Get(ctx, e.queueKey(from)+"/", clientv3.WithFirstRev()...)
+		if err != nil {
+			return false, err
+		}
+		if len(res.Kvs) == 0 {
+			return false, nil
+		}
+		kv := res.Kvs[0]
+		// the message is deleted only if nobody else has taken it meanwhile
+		cmps := []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(string(kv.Key)), "=", kv.ModRevision)}
+		ops := []clientv3.Op{clientv3.OpDelete(string(kv.Key))}
+		value, err := convert(string(kv.Value))
+		if err != nil && err != ErrDropMessage {
+			return false, err
+		}
+		if err == nil {
+			newKey := fmt.Sprintf("%s/%v", e.queueKey(to), time.Now().UnixNano())
+			cmps = append(cmps, clientv3.Compare(clientv3.Version(newKey), "=", 0))
+			ops = append(ops, clientv3.OpPut(newKey, value))
+		}
+		txn, err := e.client.Txn(ctx).If(cmps...).Then(ops...).Commit()
+		if err != nil {
+			return false, err
+		}
+		if txn.Succeeded {
+			return true, nil
+		}
+	}
+}
+
+// leaseKey the leases are kept apart from the topics, a topic can not start with a dot
+func (e *etcdQueue) leaseKey(id string) string {
+	return e.config.EtcdPrefix + "/.leases/" + id
+}
+
+func (e *etcdQueue) PutLease(ctx context.Context, id, value string) error {
+	_, err := e.client.Put(ctx, e.leaseKey(id), value)
+	return err
+}
+
+func (e *etcdQueue) DeleteLease(ctx context.Context, id string) error {
+	_, err := e.client.Delete(ctx, e.leaseKey(id))
+	return err
+}
+
+func (e *etcdQueue) Leases(ctx context.Context) (map[string]string, error) {
+	res, err := e.client.Get(ctx, e.leaseKey(""), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	leases := make(map[string]string, len(res.Kvs))
+	for _, kv := range res.Kvs {
+		leases[strings.TrimPrefix(string(kv.Key), e.leaseKey(""))] = string(kv.Value)
+	}
+	return leases, nil
+}
@@ -20,9 +20,15 @@ package mq
 
 import (
 	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/wutong-paas/wutong/cmd/mq/option"
+	"github.com/wutong-paas/wutong/mq/client"
+	"github.com/wutong-paas/wutong/util"
 )
 
 func TestEnqueue(t *testing.T) {
@@ -41,3 +47,151 @@ func TestEnqueue(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestLocalQueue(t *testing.T) {
+	testActionMQ(t, func() ActionMQ {
+		return NewActionMQ(context.TODO(), option.Config{
+			QueueBackend:     "local",
+			QueueDataDir:     t.TempDir(),
+			QueueCompactSize: 2,
+		})
+	})
+}
+
+func TestEtcdQueue(t *testing.T) {
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:2379", time.Second)
+	if err != nil {
+		t.Skipf("etcd is not available: %s", err.Error())
+	}
+	conn.Close()
+	testActionMQ(t, func() ActionMQ {
+		return NewActionMQ(context.TODO(), option.Config{
+			EtcdEndPoints: []string{"http://127.0.0.1:2379"},
+			EtcdPrefix:    "/mq",
+			EtcdTimeout:   5,
+		})
+	})
+}
+
+// testActionMQ runs the behavioural tests every ActionMQ backend must pass.
+func testActionMQ(t *testing.T, newMQ func() ActionMQ) {
+	mq := newMQ()
+	if err := mq.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer mq.Stop()
+
+	topic := "test-" + util.NewUUID()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("Topics", func(t *testing.T) {
+		for _, topic := range []string{client.BuilderTopic, client.WindowsBuilderTopic, client.WorkerTopic} {
+			if !mq.TopicIsExist(topic) {
+				t.Errorf("topic %s is not registered", topic)
+			}
+			var found bool
+			for _, tp := range mq.GetAllTopics() {
+				found = found || tp == topic
+			}
+			if !found {
+				t.Errorf("topic %s is not listed", topic)
+			}
+		}
+		if mq.TopicIsExist("not-exist-" + util.NewUUID()) {
+			t.Error("unregistered topic should not exist")
+		}
+	})
+
+	t.Run("EnqueueDequeue", func(t *testing.T) {
+		values := []string{"hello", "word", "wutong"}
+		for _, v := range values {
+			if err := mq.Enqueue(ctx, topic, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if size := mq.MessageQueueSize(topic); size != int64(len(values)) {
+			t.Fatalf("expected queue size %d, got %d", len(values), size)
+		}
+		messages, err := mq.Range(ctx, topic, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 2 || messages[0] != values[0] || messages[1] != values[1] {
+			t.Fatalf("expected the first two messages, got %v", messages)
+		}
+		for _, v := range values {
+			got, err := mq.Dequeue(ctx, topic)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != v {
+				t.Fatalf("expected %s, got %s", v, got)
+			}
+		}
+		if size := mq.MessageQueueSize(topic); size != 0 {
+			t.Fatalf("expected empty queue, got %d", size)
+		}
+	})
+
+	t.Run("DequeueBlocking", func(t *testing.T) {
+		result := make(chan string)
+		go func() {
+			value, _ := mq.Dequeue(ctx, topic)
+			result <- value
+		}()
+		time.Sleep(100 * time.Millisecond)
+		if err := mq.Enqueue(ctx, topic, "late"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case value := <-result:
+			if value != "late" {
+				t.Fatalf("expected late, got %s", value)
+			}
+		case <-ctx.Done():
+			t.Fatal("dequeue is not woken up by enqueue")
+		}
+	})
+	t.Run("Requeue", func(t *testing.T) {
+		to := topic + "-to"
+		for _, v := range []string{"broken", "hello", "word"} {
+			if err := mq.Enqueue(ctx, topic, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		convert := func(message string) (string, error) {
+			if message == "broken" {
+				return "", ErrDropMessage
+			}
+			return strings.ToUpper(message), nil
+		}
+		// a failed convert leaves the message where it is
+		failure := errors.New("convert failure")
+		if _, err := mq.Requeue(ctx, topic, to, func(string) (string, error) { return "", failure }); err != failure {
+			t.Fatalf("expected the convert failure, got %v", err)
+		}
+		if size := mq.MessageQueueSize(topic); size != 3 {
+			t.Fatalf("expected 3 messages left, got %d", size)
+		}
+		for {
+			ok, err := mq.Requeue(ctx, topic, to, convert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				break
+			}
+		}
+		if size := mq.MessageQueueSize(topic); size != 0 {
+			t.Fatalf("expected empty queue, got %d", size)
+		}
+		messages, err := mq.Range(ctx, to, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 2 || messages[0] != "HELLO" || messages[1] != "WORD" {
+			t.Fatalf("expected the requeued messages, got %v", messages)
+		}
+	})
+}
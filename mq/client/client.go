@@ -39,11 +39,24 @@ var WindowsBuilderTopic = "windows_builder"
 // WorkerTopic worker topic
 var WorkerTopic = "worker"
 
+// BuilderCancelTopic the topic of the tasks to cancel the builds, it is consumed
+// apart from the builds, so the builds waiting for the capacity can be canceled
+var BuilderCancelTopic = "builder_cancel"
+
+// DeadLetterTopic returns the topic that keeps the messages of topic which
+// failed too many times
+func DeadLetterTopic(topic string) string {
+	return "dead_letter_" + topic
+}
+
 // MQClient mq  client
 type MQClient interface {
 	pb.TaskQueueClient
 	Close()
 	SendBuilderTopic(t TaskStruct) error
+	AckTask(task *pb.TaskMessage) error
+	NackTask(task *pb.TaskMessage, reason string) error
+	ExtendTask(task *pb.TaskMessage, visibilityTimeout int) error
 }
 
 type mqClient struct {
@@ -74,12 +87,21 @@ func (m *mqClient) Close() {
 	m.cancel()
 }
 
+// TaskPriorityNormal the default task priority
+const TaskPriorityNormal int32 = 0
+
+// TaskPriorityHigh priority for the tasks a user is waiting for
+const TaskPriorityHigh int32 = 10
+
 // TaskStruct task struct
 type TaskStruct struct {
 	Topic    string
 	TaskType string
 	Operator string
 	TaskBody interface{}
+	Priority int32
+	// TenantKey defaults to the tenant_env_id of the task body
+	TenantKey string
 }
 
 // buildTask build task
@@ -90,12 +112,23 @@ func buildTask(t TaskStruct) (*pb.EnqueueRequest, error) {
 		logrus.Errorf("tran task json error")
 		return &er, err
 	}
+	tenantKey := t.TenantKey
+	if tenantKey == "" {
+		var body struct {
+			TenantEnvID string `json:"tenant_env_id"`
+		}
+		if err := json.Unmarshal(taskJSON, &body); err == nil {
+			tenantKey = body.TenantEnvID
+		}
+	}
 	er.Topic = t.Topic
 	er.Message = &pb.TaskMessage{
 		TaskType:   t.TaskType,
 		CreateTime: time.Now().Format(time.RFC3339),
 		TaskBody:   taskJSON,
 		User:       t.Operator,
+		Priority:   t.Priority,
+		TenantKey:  tenantKey,
 	}
 	return &er, nil
 }
@@ -113,3 +146,44 @@ func (m *mqClient) SendBuilderTopic(t TaskStruct) error {
 	}
 	return nil
 }
+
+// AckTask acks the leased task, it is a no-op for the task without lease
+func (m *mqClient) AckTask(task *pb.TaskMessage) error {
+	if task.LeaseId == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, time.Second*5)
+	defer cancel()
+	if _, err := m.TaskQueueClient.Ack(ctx, &pb.AckRequest{LeaseId: task.LeaseId}); err != nil {
+		return fmt.Errorf("send ack request error %s", err.Error())
+	}
+	return nil
+}
+
+// NackTask gives the leased task back to mq, it will be redelivered or moved
+// to the dead letter topic
+func (m *mqClient) NackTask(task *pb.TaskMessage, reason string) error {
+	if task.LeaseId == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, time.Second*5)
+	defer cancel()
+	if _, err := m.TaskQueueClient.Nack(ctx, &pb.NackRequest{LeaseId: task.LeaseId, Reason: reason}); err != nil {
+		return fmt.Errorf("send nack request error %s", err.Error())
+	}
+	return nil
+}
+
+// ExtendTask keeps the leased task invisible for visibilityTimeout seconds
+// more, it is a no-op for the task without lease
+func (m *mqClient) ExtendTask(task *pb.TaskMessage, visibilityTimeout int) error {
+	if task.LeaseId == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, time.Second*5)
+	defer cancel()
+	if _, err := m.TaskQueueClient.Extend(ctx, &pb.ExtendRequest{LeaseId: task.LeaseId, VisibilityTimeout: int32(visibilityTimeout)}); err != nil {
+		return fmt.Errorf("send extend request error %s", err.Error())
+	}
+	return nil
+}
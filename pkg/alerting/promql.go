@@ -0,0 +1,90 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alerting validates the user-defined PromQL alerting rules and scopes
+// them to the namespace of the tenant env.
+package alerting
+
+import (
+	"fmt"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// NamespaceLabel the label of the kubernetes namespace of the series
+const NamespaceLabel = "namespace"
+
+// ValidateExpr returns an error if the expression is not a valid PromQL expression
+// of the instant vector type, which is required by the alerting rules.
+func ValidateExpr(expr string) error {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return err
+	}
+	if e.Type() != parser.ValueTypeVector {
+		return fmt.Errorf("the expression should return an instant vector, got %s", e.Type())
+	}
+	return nil
+}
+
+// ValidateDuration returns an error if the duration is not a valid prometheus duration, the empty duration is valid
+func ValidateDuration(duration string) error {
+	if duration == "" {
+		return nil
+	}
+	_, err := model.ParseDuration(duration)
+	return err
+}
+
+// ValidateLabelName returns an error if the name is not a valid prometheus label name
+func ValidateLabelName(name string) error {
+	if !model.LabelName(name).IsValidLegacy() {
+		return fmt.Errorf("invalid label name %q", name)
+	}
+	return nil
+}
+
+// EnforceNamespace rewrites the expression so that every selector only selects the
+// series of the namespace. The namespace matchers set by the user are replaced.
+func EnforceNamespace(expr, namespace string) (string, error) {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return "", err
+	}
+	matcher, err := labels.NewMatcher(labels.MatchEqual, NamespaceLabel, namespace)
+	if err != nil {
+		return "", err
+	}
+	parser.Inspect(e, func(node parser.Node, _ []parser.Node) error {
+		vs, ok := node.(*parser.VectorSelector)
+		if !ok {
+			return nil
+		}
+		matchers := make([]*labels.Matcher, 0, len(vs.LabelMatchers)+1)
+		for _, m := range vs.LabelMatchers {
+			if m.Name != NamespaceLabel {
+				matchers = append(matchers, m)
+			}
+		}
+		vs.LabelMatchers = append(matchers, matcher)
+		return nil
+	})
+	return e.String(), nil
+}
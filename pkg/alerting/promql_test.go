@@ -0,0 +1,81 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package alerting
+
+import "testing"
+
+func TestValidateExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		valid bool
+	}{
+		{expr: `rate(http_requests_total{status=~"5.."}[5m]) > 1`, valid: true},
+		{expr: `up == 0`, valid: true},
+		{expr: `rate(http_requests_total[5m]`, valid: false},
+		{expr: `http_requests_total[5m]`, valid: false},
+		{expr: `1 > 0`, valid: false},
+	}
+	for _, tc := range tests {
+		if err := ValidateExpr(tc.expr); (err == nil) != tc.valid {
+			t.Errorf("%s: want valid %v, got %v", tc.expr, tc.valid, err)
+		}
+	}
+}
+
+func TestEnforceNamespace(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{
+			expr: `up == 0`,
+			want: `up{namespace="foo"} == 0`,
+		},
+		{
+			expr: `sum by (pod) (rate(container_cpu_usage_seconds_total{namespace="bar",pod=~"gr.*"}[5m])) / on (pod) kube_pod_info{namespace=~".+"}`,
+			want: `sum by (pod) (rate(container_cpu_usage_seconds_total{namespace="foo",pod=~"gr.*"}[5m])) / on (pod) kube_pod_info{namespace="foo"}`,
+		},
+		{
+			expr: `max_over_time(up[1h:5m]) < 1`,
+			want: `max_over_time(up{namespace="foo"}[1h:5m]) < 1`,
+		},
+	}
+	for _, tc := range tests {
+		got, err := EnforceNamespace(tc.expr, "foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("want %s, got %s", tc.want, got)
+		}
+	}
+}
+
+func TestValidateDuration(t *testing.T) {
+	for _, d := range []string{"", "30s", "5m", "1h30m"} {
+		if err := ValidateDuration(d); err != nil {
+			t.Errorf("%s: %v", d, err)
+		}
+	}
+	for _, d := range []string{"5", "1x", "-1m"} {
+		if err := ValidateDuration(d); err == nil {
+			t.Errorf("%s: want error", d)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	monitorv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// the sli types
+const (
+	// SLITypeAvailability the requests not responding 5xx are good
+	SLITypeAvailability = "availability"
+	// SLITypeLatency the requests faster than the latency threshold are good
+	SLITypeLatency = "latency"
+)
+
+// SLOBurnRateAlert the name of the alerts of burning the error budget
+const SLOBurnRateAlert = "SLOErrorBudgetBurn"
+
+// the request metrics collected by the gateway
+const (
+	gatewayRequests        = "gateway_requests"
+	gatewayRequestDuration = "gateway_request_duration_seconds"
+)
+
+// LatencyBuckets the buckets of the gateway request duration histogram, the
+// latency threshold of a SLO should be one of them.
+var LatencyBuckets = prometheus.DefBuckets
+
+// the compliance window of a SLO should be in the range
+const (
+	minSLOWindow = 7 * 24 * time.Hour
+	maxSLOWindow = 90 * 24 * time.Hour
+)
+
+// burnRateAlert alerts if both the long and the short window burn the error budget too fast,
+// the short window resets the alert soon after the burning stops.
+type burnRateAlert struct {
+	long, short string
+	// budget the fraction of the error budget consumed in the long window
+	budget   float64
+	severity string
+}
+
+// burnRateAlerts the multi-window, multi-burn-rate alerts recommended by the SRE workbook,
+// which pages at 2% of a 30d budget in 1h, 5% in 6h and tickets at 10% in 1d and 3d.
+var burnRateAlerts = []burnRateAlert{
+	{long: "1h", short: "5m", budget: 0.02, severity: "critical"},
+	{long: "6h", short: "30m", budget: 0.05, severity: "critical"},
+	{long: "1d", short: "2h", budget: 0.1, severity: "warning"},
+	{long: "3d", short: "6h", budget: 0.1, severity: "warning"},
+}
+
+// SLO the service level objective of the requests through the gateway
+type SLO struct {
+	ID   string
+	Name string
+	// Type availability or latency
+	Type string
+	// Objective the target ratio of the good requests, eg: 0.999
+	Objective float64
+	// Window the compliance window, eg: 30d
+	Window string
+	// LatencyThreshold the seconds of the latency sli
+	LatencyThreshold float64
+	// Selector the labels of the gateway metrics to select, eg: namespace, service_id and host
+	Selector map[string]string
+}
+
+// Validate validates the type, the objective, the window and the latency threshold
+func (s *SLO) Validate() error {
+	switch s.Type {
+	case SLITypeAvailability:
+	case SLITypeLatency:
+		if !isLatencyBucket(s.LatencyThreshold) {
+			return fmt.Errorf("the latency threshold should be one of %v seconds", LatencyBuckets)
+		}
+	default:
+		return fmt.Errorf("unknown sli type %s", s.Type)
+	}
+	if s.Objective <= 0 || s.Objective >= 1 {
+		return fmt.Errorf("the objective should be between 0 and 100 percent")
+	}
+	window, err := model.ParseDuration(s.Window)
+	if err != nil {
+		return fmt.Errorf("invalid window: %v", err)
+	}
+	if time.Duration(window) < minSLOWindow || time.Duration(window) > maxSLOWindow {
+		return fmt.Errorf("the window should be between %s and %s", model.Duration(minSLOWindow), model.Duration(maxSLOWindow))
+	}
+	return nil
+}
+
+// ValidateRetention validates the window is not longer than the retention of the samples, the error
+// ratio of the window is measured over the recorded samples, it is short of the window otherwise.
+func (s *SLO) ValidateRetention(retention time.Duration) error {
+	window, err := model.ParseDuration(s.Window)
+	if err != nil {
+		return fmt.Errorf("invalid window: %v", err)
+	}
+	if time.Duration(window) > retention {
+		return fmt.Errorf("the window should not be longer than the retention %s of the monitor", model.Duration(retention))
+	}
+	return nil
+}
+
+func isLatencyBucket(threshold float64) bool {
+	for _, bucket := range LatencyBuckets {
+		if bucket == threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorBudget returns the ratio of the bad requests allowed by the objective
+func (s *SLO) ErrorBudget() float64 {
+	return 1 - s.Objective
+}
+
+// BurnRate returns how fast the error ratio burns the error budget, the budget
+// is used up at the end of the window if the burn rate is 1.
+func (s *SLO) BurnRate(errorRatio float64) float64 {
+	return errorRatio / s.ErrorBudget()
+}
+
+// BudgetRemaining returns the ratio of the error budget left if the error ratio
+// is measured over the window, it is negative if the budget is overspent.
+func (s *SLO) BudgetRemaining(errorRatio float64) float64 {
+	return 1 - s.BurnRate(errorRatio)
+}
+
+// ErrorRatioExpr returns the PromQL of the ratio of the bad requests in the window
+func (s *SLO) ErrorRatioExpr(window string) string {
+	if s.Type == SLITypeLatency {
+		le := strconv.FormatFloat(s.LatencyThreshold, 'f', -1, 64)
+		return fmt.Sprintf("1 - (sum(rate(%s_bucket%s[%s])) / sum(rate(%s_count%s[%s])))",
+			gatewayRequestDuration, s.selector(`le="`+le+`"`), window, gatewayRequestDuration, s.selector(), window)
+	}
+	return fmt.Sprintf("sum(rate(%s%s[%s])) / sum(rate(%s%s[%s]))",
+		gatewayRequests, s.selector(`status=~"5.."`), window, gatewayRequests, s.selector(), window)
+}
+
+// sloShortWindow the window of the error ratio that the ratio over the compliance window is built from
+const sloShortWindow = "5m"
+
+// WindowErrorRatioExpr returns the PromQL of the ratio of the bad requests in the compliance window,
+// which averages the recorded short window ratios instead of the rate of the raw requests over the
+// whole window, so that the query is cheap and the window is not limited by the lookback of the rate.
+func (s *SLO) WindowErrorRatioExpr() string {
+	return fmt.Sprintf("avg_over_time(%s{slo_id=%q}[%s])", SLORecordName(sloShortWindow), s.ID, s.Window)
+}
+
+func (s *SLO) selector(extra ...string) string {
+	matchers := make([]string, 0, len(s.Selector)+len(extra))
+	for name, value := range s.Selector {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", name, value))
+	}
+	sort.Strings(matchers)
+	matchers = append(matchers, extra...)
+	return "{" + strings.Join(matchers, ",") + "}"
+}
+
+// SLORecordName returns the name of the recording rule of the error ratio in the window
+func SLORecordName(window string) string {
+	return "slo:sli_error:ratio_rate" + window
+}
+
+// Rules returns the recording rules of the error ratios and the multi-window burn rate
+// alerts of the SLO, all the rules are labeled with slo_id and the given labels.
+func (s *SLO) Rules(labels map[string]string) ([]monitorv1.Rule, error) {
+	window, err := model.ParseDuration(s.Window)
+	if err != nil {
+		return nil, err
+	}
+	ruleLabels := func(extra map[string]string) map[string]string {
+		result := map[string]string{"slo_id": s.ID}
+		for key, value := range labels {
+			result[key] = value
+		}
+		for key, value := range extra {
+			result[key] = value
+		}
+		return result
+	}
+
+	var rules []monitorv1.Rule
+	recorded := make(map[string]bool)
+	record := func(w, expr string) {
+		if recorded[w] {
+			return
+		}
+		recorded[w] = true
+		rules = append(rules, monitorv1.Rule{
+			Record: SLORecordName(w),
+			Expr:   intstr.FromString(expr),
+			Labels: ruleLabels(nil),
+		})
+	}
+	record(sloShortWindow, s.ErrorRatioExpr(sloShortWindow))
+	for _, alert := range burnRateAlerts {
+		record(alert.short, s.ErrorRatioExpr(alert.short))
+		record(alert.long, s.ErrorRatioExpr(alert.long))
+	}
+	record(s.Window, s.WindowErrorRatioExpr())
+
+	for _, alert := range burnRateAlerts {
+		long, _ := model.ParseDuration(alert.long)
+		// the budget fraction is consumed in the long window at the factor times the error budget
+		factor := alert.budget * float64(window) / float64(long)
+		threshold := strconv.FormatFloat(factor*s.ErrorBudget(), 'g', 6, 64)
+		expr := fmt.Sprintf(`%s{slo_id=%q} > %s and %s{slo_id=%q} > %s`,
+			SLORecordName(alert.long), s.ID, threshold, SLORecordName(alert.short), s.ID, threshold)
+		rules = append(rules, monitorv1.Rule{
+			Alert: SLOBurnRateAlert,
+			Expr:  intstr.FromString(expr),
+			Labels: ruleLabels(map[string]string{
+				"slo":         s.Name,
+				"severity":    alert.severity,
+				"long_window": alert.long,
+			}),
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("SLO %s is burning the error budget", s.Name),
+				"description": fmt.Sprintf("the error ratio of the last %s is {{ $value | humanizePercentage }}, more than %s%% of the %s error budget is spent in %s at this rate",
+					alert.long, strconv.FormatFloat(alert.budget*100, 'f', -1, 64), s.Window, alert.long),
+			},
+		})
+	}
+	return rules, nil
+}
@@ -0,0 +1,135 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package alerting
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSLOValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		slo   SLO
+		valid bool
+	}{
+		{name: "availability", slo: SLO{Type: SLITypeAvailability, Objective: 0.999, Window: "30d"}, valid: true},
+		{name: "latency", slo: SLO{Type: SLITypeLatency, Objective: 0.99, Window: "4w", LatencyThreshold: 0.5}, valid: true},
+		{name: "unknown type", slo: SLO{Type: "foo", Objective: 0.99, Window: "30d"}},
+		{name: "objective", slo: SLO{Type: SLITypeAvailability, Objective: 1, Window: "30d"}},
+		{name: "short window", slo: SLO{Type: SLITypeAvailability, Objective: 0.99, Window: "1d"}},
+		{name: "invalid window", slo: SLO{Type: SLITypeAvailability, Objective: 0.99, Window: "30 days"}},
+		{name: "not a bucket", slo: SLO{Type: SLITypeLatency, Objective: 0.99, Window: "30d", LatencyThreshold: 0.3}},
+	}
+	for _, tc := range tests {
+		if err := tc.slo.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%s: want valid %v, got %v", tc.name, tc.valid, err)
+		}
+	}
+}
+
+func TestSLOErrorRatioExpr(t *testing.T) {
+	slo := SLO{Type: SLITypeAvailability, Selector: map[string]string{"service_id": "sid", "namespace": "ns", "host": "a.example.com"}}
+	want := `sum(rate(gateway_requests{host="a.example.com",namespace="ns",service_id="sid",status=~"5.."}[1h])) / sum(rate(gateway_requests{host="a.example.com",namespace="ns",service_id="sid"}[1h]))`
+	if got := slo.ErrorRatioExpr("1h"); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	slo = SLO{Type: SLITypeLatency, LatencyThreshold: 0.25, Selector: map[string]string{"service_id": "sid"}}
+	want = `1 - (sum(rate(gateway_request_duration_seconds_bucket{service_id="sid",le="0.25"}[5m])) / sum(rate(gateway_request_duration_seconds_count{service_id="sid"}[5m])))`
+	if got := slo.ErrorRatioExpr("5m"); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	for _, w := range []string{"5m", "30d"} {
+		if err := ValidateExpr(slo.ErrorRatioExpr(w)); err != nil {
+			t.Errorf("%s: %v", w, err)
+		}
+	}
+}
+
+func TestSLORules(t *testing.T) {
+	slo := SLO{ID: "slo1", Name: "api", Type: SLITypeAvailability, Objective: 0.999, Window: "30d", Selector: map[string]string{"service_id": "sid"}}
+	rules, err := slo.Rules(map[string]string{"service_id": "sid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records, alerts int
+	for _, rule := range rules {
+		if rule.Labels["slo_id"] != "slo1" || rule.Labels["service_id"] != "sid" {
+			t.Errorf("unexpected labels %v", rule.Labels)
+		}
+		if err := ValidateExpr(rule.Expr.String()); err != nil {
+			t.Errorf("%s: %v", rule.Expr.String(), err)
+		}
+		if rule.Record != "" {
+			records++
+			continue
+		}
+		alerts++
+	}
+	// 5m, 30m, 1h, 2h, 6h, 1d, 3d and 30d
+	if records != 8 || alerts != 4 {
+		t.Errorf("want 8 records and 4 alerts, got %d and %d", records, alerts)
+	}
+	// 2% of the budget of 30 days in 1 hour is the burn rate 14.4
+	want := `slo:sli_error:ratio_rate1h{slo_id="slo1"} > 0.0144 and slo:sli_error:ratio_rate5m{slo_id="slo1"} > 0.0144`
+	if got := rules[records].Expr.String(); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	if rules[records].Labels["severity"] != "critical" {
+		t.Errorf("unexpected labels %v", rules[records].Labels)
+	}
+}
+
+func TestSLOWindowErrorRatio(t *testing.T) {
+	slo := SLO{ID: "slo1", Type: SLITypeAvailability, Objective: 0.999, Window: "30d"}
+	want := `avg_over_time(slo:sli_error:ratio_rate5m{slo_id="slo1"}[30d])`
+	if got := slo.WindowErrorRatioExpr(); got != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+	rules, err := slo.Rules(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, rule := range rules {
+		if rule.Record == SLORecordName("30d") {
+			found = rule.Expr.String() == want
+		}
+	}
+	if !found {
+		t.Errorf("the window ratio should be recorded by %s", want)
+	}
+	if err := slo.ValidateRetention(7 * 24 * time.Hour); err == nil {
+		t.Errorf("the window longer than the retention should be rejected")
+	}
+	if err := slo.ValidateRetention(30 * 24 * time.Hour); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSLOBudget(t *testing.T) {
+	slo := SLO{Objective: 0.99}
+	if got := slo.BurnRate(0.02); math.Abs(got-2) > 1e-9 {
+		t.Errorf("want burn rate 2, got %v", got)
+	}
+	if got := slo.BudgetRemaining(0.0025); math.Abs(got-0.75) > 1e-9 {
+		t.Errorf("want remaining 0.75, got %v", got)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	validation "github.com/wutong-paas/wutong/util/endpoint"
@@ -89,7 +90,7 @@ func (in ThirdComponentSpec) NeedProbe() bool {
 	if in.Probe == nil {
 		return false
 	}
-	return in.IsStaticEndpoints()
+	return in.IsProbeEndpoints()
 }
 
 // IsStaticEndpoints -
@@ -97,14 +98,29 @@ func (in ThirdComponentSpec) IsStaticEndpoints() bool {
 	return len(in.EndpointSource.StaticEndpoints) > 0
 }
 
+// IsDNSEndpoints -
+func (in ThirdComponentSpec) IsDNSEndpoints() bool {
+	return in.EndpointSource.DNS != nil
+}
+
+// IsRegistryEndpoints -
+func (in ThirdComponentSpec) IsRegistryEndpoints() bool {
+	return in.EndpointSource.Registry != nil
+}
+
+// IsProbeEndpoints returns true if the health of the endpoints is checked by the probe,
+// the online endpoints of the registry are probed as well.
+func (in ThirdComponentSpec) IsProbeEndpoints() bool {
+	return in.IsStaticEndpoints() || in.IsDNSEndpoints() || in.IsRegistryEndpoints()
+}
+
 // ThirdComponentEndpointSource -
 type ThirdComponentEndpointSource struct {
 	StaticEndpoints   []*ThirdComponentEndpoint `json:"endpoints,omitempty"`
 	KubernetesService *KubernetesServiceSource  `json:"kubernetesService,omitempty"`
+	Registry          *RegistrySource           `json:"registry,omitempty"`
+	DNS               *DNSSource                `json:"dns,omitempty"`
 	//other source
-	// NacosSource
-	// EurekaSource
-	// ConsulSource
 	// CustomAPISource
 }
 
@@ -152,6 +168,75 @@ type KubernetesServiceSource struct {
 	Name      string `json:"name"`
 }
 
+// RegistrySource the service registered in consul, nacos or eureka
+type RegistrySource struct {
+	// Type of the registry, including: consul, nacos, eureka
+	Type string `json:"type"`
+	// The addresses of the registry, separated by comma
+	Servers string `json:"servers"`
+	// The name of the service registered in the registry
+	ServiceName string `json:"serviceName"`
+	// The nacos namespace id or the consul datacenter
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// The nacos group or the consul tag
+	// +optional
+	Group string `json:"group,omitempty"`
+	// +optional
+	Username string `json:"username,omitempty"`
+	// The secret key of the password, or the acl token of consul
+	// +optional
+	PasswordSecretRef *v1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+}
+
+// DNSRecordType the type of the dns record
+type DNSRecordType string
+
+const (
+	// DNSRecordTypeA the ipv4 addresses of the hostname
+	DNSRecordTypeA DNSRecordType = "A"
+	// DNSRecordTypeAAAA the ipv6 addresses of the hostname
+	DNSRecordTypeAAAA DNSRecordType = "AAAA"
+	// DNSRecordTypeSRV the targets and the ports of the service
+	DNSRecordTypeSRV DNSRecordType = "SRV"
+)
+
+// DNSSource the endpoints resolved from the dns records
+type DNSSource struct {
+	// The hostname to resolve, such as db.example.com, or _http._tcp.example.com for SRV records
+	Hostname string `json:"hostname"`
+	// The record type, including: A, AAAA, SRV. Defaults to A.
+	// The ports of the A and AAAA records are the component ports.
+	// +optional
+	Type DNSRecordType `json:"type,omitempty"`
+	// The address of the dns server, such as 10.0.0.10:53.
+	// If not specified, the resolver of the system is used.
+	// +optional
+	Resolver string `json:"resolver,omitempty"`
+	// How often (in seconds) to resolve the hostname. Defaults to 30 seconds. Minimum value is 5.
+	// +optional
+	RefreshIntervalSeconds int32 `json:"refreshIntervalSeconds,omitempty"`
+}
+
+// GetType -
+func (in *DNSSource) GetType() DNSRecordType {
+	if in.Type == "" {
+		return DNSRecordTypeA
+	}
+	return DNSRecordType(strings.ToUpper(string(in.Type)))
+}
+
+// GetRefreshInterval -
+func (in *DNSSource) GetRefreshInterval() time.Duration {
+	if in.RefreshIntervalSeconds <= 0 {
+		return 30 * time.Second
+	}
+	if in.RefreshIntervalSeconds < 5 {
+		return 5 * time.Second
+	}
+	return time.Duration(in.RefreshIntervalSeconds) * time.Second
+}
+
 // Probe describes a health check to be performed against a container to determine whether it is
 // alive or ready to receive traffic.
 type Probe struct {
@@ -0,0 +1,194 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+// Package notifier sends the alerts to the notification channels, such as a generic
+// webhook, the email, the DingTalk, the Feishu and the WeCom robots.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// TypeWebhook posts the message as json to the url
+	TypeWebhook = "webhook"
+	// TypeEmail sends the message by smtp
+	TypeEmail = "email"
+	// TypeDingTalk sends the message to the DingTalk robot
+	TypeDingTalk = "dingtalk"
+	// TypeFeishu sends the message to the Feishu robot
+	TypeFeishu = "feishu"
+	// TypeWeCom sends the message to the WeCom robot
+	TypeWeCom = "wecom"
+)
+
+const (
+	// StatusFiring the alert is firing
+	StatusFiring = "firing"
+	// StatusResolved the alert is resolved
+	StatusResolved = "resolved"
+)
+
+// Config the config of the notification channel, the fields used depend on the type
+type Config struct {
+	// URL the webhook url, or the robot url of the DingTalk, the Feishu and the WeCom
+	URL string `json:"url,omitempty"`
+	// Secret signs the webhook body, or the sign secret of the DingTalk and the Feishu robot
+	Secret string `json:"secret,omitempty"`
+	// Headers the extra headers of the webhook requests
+	Headers map[string]string `json:"headers,omitempty"`
+	// the smtp server of the email
+	SMTPHost string   `json:"smtp_host,omitempty"`
+	SMTPPort int      `json:"smtp_port,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+}
+
+// Message the alert sent to the notification channels
+type Message struct {
+	Status        string            `json:"status"`
+	AlertName     string            `json:"alert_name"`
+	Severity      string            `json:"severity,omitempty"`
+	Summary       string            `json:"summary,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	TenantEnvName string            `json:"tenant_env_name,omitempty"`
+	ServiceAlias  string            `json:"service_alias,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	StartsAt      time.Time         `json:"starts_at"`
+	EndsAt        time.Time         `json:"ends_at,omitempty"`
+	GeneratorURL  string            `json:"generator_url,omitempty"`
+}
+
+// Title returns the title of the message
+func (m *Message) Title() string {
+	title := fmt.Sprintf("[%s] %s", strings.ToUpper(m.Status), m.AlertName)
+	if m.ServiceAlias != "" {
+		title += " " + m.ServiceAlias
+	}
+	return title
+}
+
+// Text returns the plain text of the message
+func (m *Message) Text() string {
+	var buf strings.Builder
+	buf.WriteString(m.Title() + "\n")
+	line := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&buf, "%s: %s\n", name, value)
+		}
+	}
+	line("Severity", m.Severity)
+	line("TenantEnv", m.TenantEnvName)
+	line("Component", m.ServiceAlias)
+	line("Summary", m.Summary)
+	line("Description", m.Description)
+	line("StartsAt", m.StartsAt.Format(time.RFC3339))
+	if m.Status == StatusResolved && !m.EndsAt.IsZero() {
+		line("EndsAt", m.EndsAt.Format(time.RFC3339))
+	}
+	keys := make([]string, 0, len(m.Labels))
+	for key := range m.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var labels []string
+	for _, key := range keys {
+		labels = append(labels, key+"="+m.Labels[key])
+	}
+	line("Labels", strings.Join(labels, ", "))
+	return buf.String()
+}
+
+// Markdown returns the markdown text of the message
+func (m *Message) Markdown() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "### %s\n", m.Title())
+	for _, line := range strings.Split(strings.TrimSpace(m.Text()), "\n")[1:] {
+		fmt.Fprintf(&buf, "- %s\n", line)
+	}
+	return buf.String()
+}
+
+// Notifier sends the messages to a notification channel
+type Notifier interface {
+	Notify(ctx context.Context, msg *Message) error
+}
+
+// New creates the notifier of the type, the config is validated
+func New(typ string, cfg *Config) (Notifier, error) {
+	switch typ {
+	case TypeWebhook:
+		return newWebhook(cfg)
+	case TypeEmail:
+		return newEmail(cfg)
+	case TypeDingTalk:
+		return newDingTalk(cfg)
+	case TypeFeishu:
+		return newFeishu(cfg)
+	case TypeWeCom:
+		return newWeCom(cfg)
+	}
+	return nil, fmt.Errorf("unsupported notifier type %q", typ)
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// postJSON posts the body to the url, the response is decoded into result if it is not nil
+func postJSON(ctx context.Context, url string, headers map[string]string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %d: %s", res.StatusCode, string(data))
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("decode response %s: %v", string(data), err)
+	}
+	return nil
+}
+
+func requireURL(typ string, cfg *Config) error {
+	if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+		return fmt.Errorf("the url of the %s notifier should be http or https", typ)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMessage() *Message {
+	return &Message{
+		Status:       StatusFiring,
+		AlertName:    "HighErrorRate",
+		Severity:     "critical",
+		Summary:      "the error rate is 12%",
+		ServiceAlias: "gr123456",
+		Labels:       map[string]string{"service_id": "foo"},
+		StartsAt:     time.Unix(0, 0),
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer server.Close()
+
+	n, err := New(TypeWebhook, &Config{URL: server.URL, Secret: "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Notify(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil || msg.AlertName != "HighErrorRate" {
+		t.Fatalf("unexpected body %s: %v", string(body), err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("want signature %s, got %s", want, signature)
+	}
+}
+
+func TestRobots(t *testing.T) {
+	var query map[string][]string
+	var payload map[string]interface{}
+	reply := `{"errcode":0,"errmsg":"ok"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		payload = nil
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = io.WriteString(w, reply)
+	}))
+	defer server.Close()
+
+	now := func() time.Time { return time.Unix(1700000000, 0) }
+	ding := &dingTalk{cfg: &Config{URL: server.URL + "?access_token=foo", Secret: "SEC"}, now: now}
+	if err := ding.Notify(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if query["timestamp"][0] != "1700000000000" || query["sign"][0] == "" || query["access_token"][0] != "foo" {
+		t.Errorf("unexpected dingtalk query %v", query)
+	}
+	if payload["msgtype"] != "markdown" {
+		t.Errorf("unexpected dingtalk payload %v", payload)
+	}
+
+	reply = `{"code":0,"msg":"success"}`
+	fs := &feishu{cfg: &Config{URL: server.URL, Secret: "SEC"}, now: now}
+	if err := fs.Notify(context.Background(), testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if payload["timestamp"] != "1700000000" || payload["sign"] != hmacBase64("1700000000\nSEC", "") {
+		t.Errorf("unexpected feishu payload %v", payload)
+	}
+
+	reply = `{"errcode":93000,"errmsg":"invalid webhook url"}`
+	wc, _ := New(TypeWeCom, &Config{URL: server.URL})
+	if err := wc.Notify(context.Background(), testMessage()); err == nil || !strings.Contains(err.Error(), "93000") {
+		t.Errorf("want the robot error, got %v", err)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		typ string
+		cfg *Config
+	}{
+		{typ: "slack", cfg: &Config{URL: "https://example.com"}},
+		{typ: TypeWebhook, cfg: &Config{URL: "example.com"}},
+		{typ: TypeEmail, cfg: &Config{SMTPHost: "smtp.example.com", SMTPPort: 25, From: "ops@example.com"}},
+		{typ: TypeEmail, cfg: &Config{SMTPHost: "smtp.example.com", SMTPPort: 25, From: "ops", To: []string{"dev@example.com"}}},
+	}
+	for _, tc := range tests {
+		if _, err := New(tc.typ, tc.cfg); err == nil {
+			t.Errorf("want error for %s %+v", tc.typ, tc.cfg)
+		}
+	}
+	if _, err := New(TypeEmail, &Config{SMTPHost: "smtp.example.com", SMTPPort: 465, From: "Ops <ops@example.com>", To: []string{"dev@example.com"}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if s := truncateUTF8("告警abc", 4); s != "告" {
+		t.Errorf("want 告, got %q", s)
+	}
+}
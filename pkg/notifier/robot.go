@@ -0,0 +1,170 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// the robots of the DingTalk and the WeCom reply errcode, the Feishu robot replies code
+type robotResult struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+func (r *robotResult) err() error {
+	if r.ErrCode != 0 {
+		return fmt.Errorf("robot error %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	if r.Code != 0 {
+		return fmt.Errorf("robot error %d: %s", r.Code, r.Msg)
+	}
+	return nil
+}
+
+func postRobot(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	var result robotResult
+	if err := postJSON(ctx, url, nil, body, &result); err != nil {
+		return err
+	}
+	return result.err()
+}
+
+func hmacBase64(key, message string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type dingTalk struct {
+	cfg *Config
+	now func() time.Time
+}
+
+func newDingTalk(cfg *Config) (Notifier, error) {
+	if err := requireURL(TypeDingTalk, cfg); err != nil {
+		return nil, err
+	}
+	return &dingTalk{cfg: cfg, now: time.Now}, nil
+}
+
+// Notify sends the markdown message, the url is signed if the secret is set
+func (d *dingTalk) Notify(ctx context.Context, msg *Message) error {
+	target := d.cfg.URL
+	if d.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(d.now().UnixNano()/int64(time.Millisecond), 10)
+		sign := hmacBase64(d.cfg.Secret, timestamp+"\n"+d.cfg.Secret)
+		u, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+		query := u.Query()
+		query.Set("timestamp", timestamp)
+		query.Set("sign", sign)
+		u.RawQuery = query.Encode()
+		target = u.String()
+	}
+	return postRobot(ctx, target, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": msg.Title(),
+			"text":  msg.Markdown(),
+		},
+	})
+}
+
+type feishu struct {
+	cfg *Config
+	now func() time.Time
+}
+
+func newFeishu(cfg *Config) (Notifier, error) {
+	if err := requireURL(TypeFeishu, cfg); err != nil {
+		return nil, err
+	}
+	return &feishu{cfg: cfg, now: time.Now}, nil
+}
+
+// Notify sends the text message, the body is signed if the secret is set
+func (f *feishu) Notify(ctx context.Context, msg *Message) error {
+	payload := map[string]interface{}{
+		"msg_type": "text",
+		"content": map[string]string{
+			"text": msg.Text(),
+		},
+	}
+	if f.cfg.Secret != "" {
+		timestamp := strconv.FormatInt(f.now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		// the feishu robot uses the string to sign as the key of an empty message
+		payload["sign"] = hmacBase64(timestamp+"\n"+f.cfg.Secret, "")
+	}
+	return postRobot(ctx, f.cfg.URL, payload)
+}
+
+type weCom struct {
+	cfg *Config
+}
+
+func newWeCom(cfg *Config) (Notifier, error) {
+	if err := requireURL(TypeWeCom, cfg); err != nil {
+		return nil, err
+	}
+	return &weCom{cfg: cfg}, nil
+}
+
+// weComMaxContent the max bytes of the markdown content of the WeCom robot
+const weComMaxContent = 4096
+
+// Notify sends the markdown message
+func (w *weCom) Notify(ctx context.Context, msg *Message) error {
+	content := truncateUTF8(msg.Markdown(), weComMaxContent)
+	return postRobot(ctx, w.cfg.URL, map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": content,
+		},
+	})
+}
+
+// truncateUTF8 truncates the string to at most n bytes without breaking a rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,59 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// SignatureHeader the header of the hmac-sha256 signature of the webhook body
+const SignatureHeader = "X-Wutong-Signature"
+
+type webhook struct {
+	cfg *Config
+}
+
+func newWebhook(cfg *Config) (Notifier, error) {
+	if err := requireURL(TypeWebhook, cfg); err != nil {
+		return nil, err
+	}
+	return &webhook{cfg: cfg}, nil
+}
+
+// Notify posts the message as json, the body is signed by the secret if it is set
+func (w *webhook) Notify(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	headers := make(map[string]string, len(w.cfg.Headers)+1)
+	for key, value := range w.cfg.Headers {
+		headers[key] = value
+	}
+	if w.cfg.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.cfg.Secret))
+		mac.Write(body)
+		headers[SignatureHeader] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return postJSON(ctx, w.cfg.URL, headers, body, nil)
+}
@@ -30,4 +30,5 @@ type Interface interface {
 	GetComponentMetadata(tenantEnvID, componentID string) []Metadata
 	GetMetricLabelSet(expr string, start, end time.Time) []map[string]string
 	GetEndpoint() string
+	GetRetention() (time.Duration, error)
 }
@@ -231,6 +231,27 @@ func (p prometheus) GetEndpoint() string {
 	return p.endpoint
 }
 
+// GetRetention returns how long the samples are retained by the flags of Prometheus
+func (p prometheus) GetRetention() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	flags, err := p.client.Flags(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, name := range []string{"storage.tsdb.retention.time", "storage.tsdb.retention"} {
+		// 0s means the retention is not set by the flag
+		if value, ok := flags[name]; ok && value != "" && value != "0s" {
+			retention, err := model.ParseDuration(value)
+			if err != nil {
+				return 0, fmt.Errorf("parse the flag %s: %v", name, err)
+			}
+			return time.Duration(retention), nil
+		}
+	}
+	return 0, fmt.Errorf("the retention is not found in the flags")
+}
+
 func parseQueryRangeResp(value model.Value) MetricData {
 	res := MetricData{MetricType: MetricTypeMatrix}
 
@@ -198,14 +198,30 @@ func ReturnNoFomart(r *http.Request, w http.ResponseWriter, code int, reb interf
 // ReturnResNotEnough http return node resource not enough, http code = 412
 func ReturnResNotEnough(r *http.Request, w http.ResponseWriter, eventID, msg string) {
 	logrus.Debugf("resource not enough, msg: %s", msg)
-	if err := db.GetManager().ServiceEventDao().UpdateReason(eventID, msg); err != nil {
+	updateEventReason(eventID, msg)
+
+	r = r.WithContext(context.WithValue(r.Context(), render.StatusCtxKey, 412))
+	render.DefaultResponder(w, r, ResponseBody{Msg: msg})
+}
+
+// ReturnResNotEnoughError returns the error of the resource check, the bcode errors such as
+// the exceeded quotas are returned as bcode errors, others as resource not enough.
+func ReturnResNotEnoughError(r *http.Request, w http.ResponseWriter, eventID string, err error) {
+	var coder bcode.Coder
+	if !errors.As(err, &coder) {
+		ReturnResNotEnough(r, w, eventID, err.Error())
+		return
+	}
+	updateEventReason(eventID, err.Error())
+	ReturnBcodeError(r, w, err)
+}
+
+func updateEventReason(eventID, reason string) {
+	if err := db.GetManager().ServiceEventDao().UpdateReason(eventID, reason); err != nil {
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			logrus.Warningf("update event reason: %v", err)
 		}
 	}
-
-	r = r.WithContext(context.WithValue(r.Context(), render.StatusCtxKey, 412))
-	render.DefaultResponder(w, r, ResponseBody{Msg: msg})
 }
 
 // ReturnBcodeError bcode error
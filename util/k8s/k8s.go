@@ -6,7 +6,10 @@ import (
 	"os"
 	"sync"
 
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	utilversion "k8s.io/apimachinery/pkg/util/version"
 
 	"github.com/sirupsen/logrus"
@@ -137,6 +140,54 @@ func IsHighVersion() bool {
 	return GetKubeVersion().AtLeast(utilversion.MustParseSemantic("v1.19.0"))
 }
 
+var (
+	autoscalingV2Once sync.Once
+	autoscalingV2     bool
+)
+
+// IsAutoscalingV2 returns if the autoscaling/v2 api is served, it is served since k8s 1.23,
+// the HPAs are managed by autoscaling/v2beta2 otherwise.
+func IsAutoscalingV2() bool {
+	autoscalingV2Once.Do(func() {
+		_, err := GetClientSet().Discovery().ServerResourcesForGroupVersion(autoscalingv2.SchemeGroupVersion.String())
+		if err == nil || errors.IsNotFound(err) {
+			autoscalingV2 = err == nil
+			return
+		}
+		logrus.Warningf("discover %s failed, decide by the kubernetes version: %v", autoscalingv2.SchemeGroupVersion, err)
+		autoscalingV2 = GetKubeVersion().AtLeast(utilversion.MustParseSemantic("v1.23.0"))
+	})
+	return autoscalingV2
+}
+
+// HPAToV2beta2 converts the autoscaling/v2 HPA to autoscaling/v2beta2, the two versions share the same schema
+func HPAToV2beta2(hpa *autoscalingv2.HorizontalPodAutoscaler) (*autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+	data, err := json.Marshal(hpa)
+	if err != nil {
+		return nil, err
+	}
+	var beta autoscalingv2beta2.HorizontalPodAutoscaler
+	if err := json.Unmarshal(data, &beta); err != nil {
+		return nil, err
+	}
+	beta.APIVersion = autoscalingv2beta2.SchemeGroupVersion.String()
+	return &beta, nil
+}
+
+// HPAFromV2beta2 converts the autoscaling/v2beta2 HPA to autoscaling/v2
+func HPAFromV2beta2(beta *autoscalingv2beta2.HorizontalPodAutoscaler) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	data, err := json.Marshal(beta)
+	if err != nil {
+		return nil, err
+	}
+	var hpa autoscalingv2.HorizontalPodAutoscaler
+	if err := json.Unmarshal(data, &hpa); err != nil {
+		return nil, err
+	}
+	hpa.APIVersion = autoscalingv2.SchemeGroupVersion.String()
+	return &hpa, nil
+}
+
 // GetKubeVersion returns the version of k8s
 func GetKubeVersion() *utilversion.Version {
 	var serverVersion, err = GetClientSet().Discovery().ServerVersion()
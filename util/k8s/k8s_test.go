@@ -0,0 +1,45 @@
+package k8s
+
+import (
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHPAV2beta2Conversion(t *testing.T) {
+	window := int32(300)
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar", Labels: map[string]string{"service_id": "sid"}},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "foo", APIVersion: "apps/v1"},
+			MaxReplicas:    5,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ExternalMetricSourceType,
+				External: &autoscalingv2.ExternalMetricSource{
+					Metric: autoscalingv2.MetricIdentifier{Name: "queue_length"},
+					Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: resource.NewQuantity(10, resource.DecimalSI)},
+				},
+			}},
+			Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+				ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &window},
+			},
+		},
+	}
+	beta, err := HPAToV2beta2(hpa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if beta.APIVersion != "autoscaling/v2beta2" || *beta.Spec.Behavior.ScaleDown.StabilizationWindowSeconds != window {
+		t.Fatalf("unexpected v2beta2 hpa %#v", beta)
+	}
+	got, err := HPAFromV2beta2(beta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(got.Spec, hpa.Spec) || got.Labels["service_id"] != "sid" {
+		t.Fatalf("want %#v, got %#v", hpa.Spec, got.Spec)
+	}
+}
@@ -23,6 +23,7 @@ import "github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
 //ThirdComponentProperties third component properties
 type ThirdComponentProperties struct {
 	Kubernetes *ThirdComponentKubernetes          `json:"kubernetes,omitempty"`
+	Registry   *v1alpha1.RegistrySource           `json:"registry,omitempty"`
 	Endpoints  []*v1alpha1.ThirdComponentEndpoint `json:"endpoints,omitempty"`
 	Port       []*ThirdComponentPort              `json:"port"`
 	Probe      *v1alpha1.Probe                    `json:"probe,omitempty"`
@@ -30,10 +30,13 @@ import (
 	"github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
 	wutongversioned "github.com/wutong-paas/wutong/pkg/generated/clientset/versioned"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const registryPasswordKey = "password"
+
 // ErrNotSupport -
 var ErrNotSupport = fmt.Errorf("not support component definition")
 
@@ -120,6 +123,24 @@ func (c *Builder) GetComponentProperties(as *v1.AppService, dbm db.Manager, cd *
 					Namespace: tpsd.Namespace,
 				}
 			}
+			if dbmodel.DiscorveryType(tpsd.Type).IsRegistry() {
+				properties.Registry = &v1alpha1.RegistrySource{
+					Type:        tpsd.Type,
+					Servers:     tpsd.Servers,
+					ServiceName: tpsd.ServiceName,
+					Namespace:   tpsd.Namespace,
+					Group:       tpsd.Key,
+					Username:    tpsd.Username,
+				}
+				if tpsd.Password != "" {
+					secret := registryPasswordSecret(as, tpsd.Password)
+					as.SetSecret(secret)
+					properties.Registry.PasswordSecretRef = &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+						Key:                  registryPasswordKey,
+					}
+				}
+			}
 		}
 
 		// static endpoints
@@ -174,6 +195,21 @@ func (c *Builder) listStaticEndpoints(componentID string) ([]*v1alpha1.ThirdComp
 	return res, nil
 }
 
+// registryPasswordSecret keeps the password of the registry out of the ThirdComponent spec
+func registryPasswordSecret(as *v1.AppService, password string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-registry", as.ServiceID),
+			Namespace: as.GetNamespace(),
+			Labels:    as.GetCommonLabels(),
+		},
+		Data: map[string][]byte{
+			registryPasswordKey: []byte(password),
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+}
+
 // BuildWorkloadResource -
 func (c *Builder) BuildWorkloadResource(as *v1.AppService, dbm db.Manager) error {
 	cd := c.GetComponentDefinition(as.GetComponentDefinitionName())
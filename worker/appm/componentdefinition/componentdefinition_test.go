@@ -22,7 +22,11 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestTemplateContext(t *testing.T) {
@@ -40,3 +44,39 @@ func TestTemplateContext(t *testing.T) {
 	show, _ := json.Marshal(manifests)
 	t.Log(string(show))
 }
+
+func TestTemplateContextRegistry(t *testing.T) {
+	as := &v1.AppService{AppServiceBase: v1.AppServiceBase{ServiceID: "1234567890", ServiceAlias: "niasdjaj", TenantEnvID: "098765432345678"}}
+	as.SetTenantEnv(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "t-namespace"}})
+	ctx := NewTemplateContext(as, cueTemplate, &ThirdComponentProperties{
+		Registry: &v1alpha1.RegistrySource{
+			Type:        "nacos",
+			Servers:     "127.0.0.1:8848",
+			ServiceName: "user-service",
+			Group:       "DEFAULT_GROUP",
+			PasswordSecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "1234567890-registry"},
+				Key:                  "password",
+			},
+		},
+		Port: []*ThirdComponentPort{},
+	})
+	manifests, err := ctx.GenerateComponentManifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifests) != 1 {
+		t.Fatalf("expected 1 manifest, got %d", len(manifests))
+	}
+	var component v1alpha1.ThirdComponent
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(manifests[0].Object, &component); err != nil {
+		t.Fatal(err)
+	}
+	registry := component.Spec.EndpointSource.Registry
+	if registry == nil || registry.Type != "nacos" || registry.ServiceName != "user-service" || registry.Group != "DEFAULT_GROUP" {
+		t.Errorf("unexpected registry source %+v", registry)
+	}
+	if ref := registry.PasswordSecretRef; ref == nil || ref.Name != "1234567890-registry" || ref.Key != "password" {
+		t.Errorf("unexpected registry password secret %+v", ref)
+	}
+}
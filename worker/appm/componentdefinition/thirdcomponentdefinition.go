@@ -40,6 +40,9 @@ output: {
 					name: parameter["kubernetes"]["name"]
 				}
 			}
+			if parameter["registry"] != _|_ {
+				registry: parameter["registry"]
+			}
 			if parameter["endpoints"] != _|_ {
 				endpoints: parameter["endpoints"]
 			}
@@ -58,6 +61,18 @@ parameter: {
 		namespace?: string
 		name: string
 	}
+	registry?: {
+		type:         "consul" | "nacos" | "eureka"
+		servers:      string
+		serviceName:  string
+		namespace?:   string
+		group?:       string
+		username?:    string
+		passwordSecretRef?: {
+			name: string
+			key:  string
+		}
+	}
 	endpoints?: [...{
 		address:       string
 		name?:         string
@@ -102,7 +117,7 @@ var thirdComponentDefine = v1alpha1.ComponentDefinition{
 		Name: thirdComponentDefineName,
 		Annotations: map[string]string{
 			"definition.oam.dev/description": "Wutong built-in component type that defines third-party service components.",
-			"version":                        "0.3",
+			"version":                        "0.5",
 		},
 	},
 	Spec: v1alpha1.ComponentDefinitionSpec{
@@ -23,6 +23,8 @@ import (
 	"fmt"
 	"sync"
 
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
 	"github.com/wutong-paas/wutong/util"
 	"github.com/wutong-paas/wutong/util/apply"
 	"github.com/wutong-paas/wutong/worker/appm/store"
@@ -243,3 +245,23 @@ func (s *sequencelist) Contains(id string) bool {
 func (s *sequencelist) Add(ids []*v1.AppService) {
 	*s = append(*s, ids)
 }
+
+// StartReleaseController starts the release of the canary version side by side with the stable version
+func (m *Manager) StartReleaseController(release *dbmodel.ServiceRelease, prometheusCli prometheus.Interface, stable, canary v1.AppService) error {
+	controllerID := util.NewUUID()
+	controller := &releaseController{
+		controllerID: controllerID,
+		release:      release,
+		stable:       stable,
+		canary:       canary,
+		prometheus:   prometheusCli,
+		manager:      m,
+		stopChan:     make(chan struct{}),
+		ctx:          context.Background(),
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.controllers[controllerID] = controller
+	go controller.Begin()
+	return nil
+}
@@ -22,11 +22,14 @@ import (
 	"context"
 	"sync"
 
+	"github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/wutong-paas/wutong/worker/appm/f"
+	"github.com/wutong-paas/wutong/worker/appm/store"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
 )
 
@@ -61,16 +64,64 @@ func (a *refreshXPAController) applyOne(clientset kubernetes.Interface, app *v1.
 
 	for _, hpa := range app.GetDelHPAs() {
 		logrus.Debugf("hpa name: %s; start deleting hpa.", hpa.GetName())
-		err := clientset.AutoscalingV1().HorizontalPodAutoscalers(hpa.GetNamespace()).Delete(context.Background(), hpa.GetName(), metav1.DeleteOptions{})
+		err := f.DeleteHPA(context.Background(), hpa.GetNamespace(), hpa.GetName(), clientset)
 		if err != nil {
 			// don't return error, hope it is ok next time
 			logrus.Warningf("error deleting secret(%#v): %v", hpa, err)
 		}
 	}
 
+	a.applyPrometheusRules(app)
+
 	return nil
 }
 
+// applyPrometheusRules ensures the recording rules of the prometheus metrics, the rules
+// of the deleted HPAs or the HPAs without prometheus metrics will be deleted.
+func (a *refreshXPAController) applyPrometheusRules(app *v1.AppService) {
+	client := prometheusRuleClient(a.manager, app)
+	if client == nil {
+		return
+	}
+	var ruleNames = make(map[string]struct{})
+	for _, rule := range app.GetPrometheusRules() {
+		f.EnsurePrometheusRule(rule, client)
+		ruleNames[rule.Name] = struct{}{}
+	}
+	var names []string
+	for _, hpa := range app.GetHPAs() {
+		names = append(names, hpa.GetName())
+	}
+	for _, hpa := range app.GetDelHPAs() {
+		names = append(names, hpa.GetName())
+	}
+	for _, name := range names {
+		if _, ok := ruleNames[name]; ok {
+			continue
+		}
+		err := client.MonitoringV1().PrometheusRules(app.GetNamespace()).Delete(context.Background(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			logrus.Warningf("error deleting prometheus rule %s: %v", name, err)
+		}
+	}
+}
+
+// prometheusRuleClient returns nil if the PrometheusRule CRD is not installed.
+func prometheusRuleClient(manager *Manager, app *v1.AppService) *versioned.Clientset {
+	if crd, _ := manager.store.GetCrd(store.PrometheusRule); crd == nil {
+		if len(app.GetPrometheusRules()) > 0 {
+			logrus.Warningf("service %s: the prometheus metrics of the HPA need the CRD %s", app.ServiceID, store.PrometheusRule)
+		}
+		return nil
+	}
+	client, err := manager.store.GetServiceMonitorClient()
+	if err != nil {
+		logrus.Errorf("create prometheus rule client failure %s", err.Error())
+		return nil
+	}
+	return client
+}
+
 func (a *refreshXPAController) Stop() error {
 	close(a.stopChan)
 	return nil
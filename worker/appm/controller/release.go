@@ -0,0 +1,726 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/event"
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+	"github.com/wutong-paas/wutong/worker/appm/conversion"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// releasePollInterval the interval to check the canary workload and the release command
+var releasePollInterval = 5 * time.Second
+
+// defaultReleaseStepInterval the interval of the steps if not set
+var defaultReleaseStepInterval = time.Minute
+
+// errReleaseStopped the worker is stopping, the release is left as it is
+var errReleaseStopped = fmt.Errorf("release controller stopped")
+
+// releaseController runs the new deploy version side by side with the running one.
+// The canary workload and services are named with the suffix -canary, they are not
+// labeled with creater_id so that the store does not take them as the resources of the
+// running version. The gateway traffic is shifted by the weights of the ingresses
+// sharing the same host and path, and each step is gated by the prometheus analyses.
+type releaseController struct {
+	stopChan     chan struct{}
+	controllerID string
+	release      *dbmodel.ServiceRelease
+	// stable the running version, canary the new version
+	stable     v1.AppService
+	canary     v1.AppService
+	prometheus prometheus.Interface
+	manager    *Manager
+	ctx        context.Context
+}
+
+func (s *releaseController) Begin() {
+	defer s.manager.callback(s.controllerID, nil)
+	logger := s.canary.Logger
+	err := s.run()
+	if err == errReleaseStopped {
+		return
+	}
+	if err != nil {
+		logrus.Errorf("release %s of service %s failure: %s", s.release.ReleaseID, s.release.ServiceID, err.Error())
+		logger.Error(fmt.Sprintf("发布失败：%s", err.Error()), event.GetCallbackLoggerOption())
+		return
+	}
+	switch s.release.Status {
+	case dbmodel.ReleaseStatusPromoted:
+		logger.Info(fmt.Sprintf("新版本 %s 发布成功！", s.release.CanaryVersion), event.GetLastLoggerOption())
+	case dbmodel.ReleaseStatusFailed:
+		logger.Error(fmt.Sprintf("发布失败：%s", s.release.Message), event.GetCallbackLoggerOption())
+	default:
+		logger.Info(fmt.Sprintf("发布已回滚，保留版本 %s：%s", s.release.StableVersion, s.release.Message), event.GetLastLoggerOption())
+	}
+}
+
+func (s *releaseController) Stop() error {
+	close(s.stopChan)
+	return nil
+}
+
+func (s *releaseController) run() error {
+	logger := s.canary.Logger
+	weights, err := s.release.StepWeights()
+	if err != nil {
+		return s.finish(dbmodel.ReleaseStatusFailed, err.Error())
+	}
+	analyses, err := s.release.AnalysisList()
+	if err != nil {
+		return s.finish(dbmodel.ReleaseStatusFailed, fmt.Sprintf("invalid analyses: %v", err))
+	}
+	if s.canary.GetDeployment() == nil {
+		return s.finish(dbmodel.ReleaseStatusFailed, "只有无状态组件支持灰度或蓝绿发布")
+	}
+	ingresses, betaIngresses := s.stable.GetIngress(true)
+	if len(betaIngresses) > 0 {
+		return s.finish(dbmodel.ReleaseStatusFailed, "集群版本过低，不支持通过网关切换流量")
+	}
+	if len(ingresses) == 0 {
+		return s.finish(dbmodel.ReleaseStatusFailed, "组件没有 HTTP 访问策略，无法切换流量")
+	}
+
+	// the release interrupted by the restart of the worker goes on from the persisted status
+	switch s.release.Status {
+	case dbmodel.ReleaseStatusPromoting:
+		return s.promote()
+	case dbmodel.ReleaseStatusRollingBack:
+		return s.rollback(dbmodel.ReleaseStatusRolledBack, s.release.Message)
+	}
+	resumePaused := s.release.Status == dbmodel.ReleaseStatusPaused
+	if s.release.Status == dbmodel.ReleaseStatusPending {
+		logger.Info(fmt.Sprintf("开始发布新版本 %s，当前版本 %s", s.release.CanaryVersion, s.release.StableVersion), event.GetLoggerOption("starting"))
+	} else {
+		logger.Info(fmt.Sprintf("继续发布新版本 %s，当前版本 %s", s.release.CanaryVersion, s.release.StableVersion), event.GetLoggerOption("starting"))
+	}
+
+	s.release.Status = dbmodel.ReleaseStatusProgressing
+	s.updateRelease()
+	if err := s.deployCanary(); err != nil {
+		if err == errReleaseStopped {
+			return err
+		}
+		return s.rollback(dbmodel.ReleaseStatusFailed, fmt.Sprintf("新版本部署失败：%s", err.Error()))
+	}
+
+	for i := s.release.CurrentStep; i < len(weights) && !resumePaused; i++ {
+		if err := s.shiftTraffic(weights[i]); err != nil {
+			return s.rollback(dbmodel.ReleaseStatusFailed, fmt.Sprintf("切换流量失败：%s", err.Error()))
+		}
+		s.release.CurrentStep = i
+		s.release.CurrentWeight = weights[i]
+		s.updateRelease()
+		logger.Info(fmt.Sprintf("第 %d/%d 步：%d%% 的流量已切换到新版本", i+1, len(weights), weights[i]), event.GetLoggerOption("running"))
+
+		interval := time.Duration(s.release.StepInterval) * time.Second
+		if interval <= 0 {
+			interval = defaultReleaseStepInterval
+		}
+		// the step is held while the analyses have no data, there may be no traffic to the new
+		// version yet. It is rolled back only if the release has a no data timeout.
+		stepStart := time.Now()
+		for {
+			command, err := s.waitCommand(interval)
+			if err != nil {
+				return err
+			}
+			switch command {
+			case dbmodel.ReleaseCommandAbort:
+				return s.rollback(dbmodel.ReleaseStatusRolledBack, "用户终止发布")
+			case dbmodel.ReleaseCommandPromote:
+				return s.promote()
+			}
+			result, msg := s.analyze(analyses)
+			if result == analysisFailed {
+				return s.rollback(dbmodel.ReleaseStatusRolledBack, msg)
+			}
+			if result == analysisPassed {
+				break
+			}
+			if timeout := time.Duration(s.release.NoDataTimeout) * time.Second; timeout > 0 && time.Since(stepStart) >= timeout {
+				return s.rollback(dbmodel.ReleaseStatusRolledBack, fmt.Sprintf("%s，%s 内无法得出分析结果", msg, timeout))
+			}
+			logger.Info(fmt.Sprintf("%s，第 %d/%d 步保持当前流量等待数据", msg, i+1, len(weights)), event.GetLoggerOption("running"))
+		}
+	}
+
+	if s.release.ManualPromote {
+		s.release.Status = dbmodel.ReleaseStatusPaused
+		s.updateRelease()
+		logger.Info("所有发布步骤已通过，等待确认发布", event.GetLoggerOption("running"))
+		command, err := s.waitCommand(0)
+		if err != nil {
+			return err
+		}
+		if command == dbmodel.ReleaseCommandAbort {
+			return s.rollback(dbmodel.ReleaseStatusRolledBack, "用户终止发布")
+		}
+	}
+	return s.promote()
+}
+
+// deployCanary creates the canary workload and services, and waits for the workload ready
+func (s *releaseController) deployCanary() error {
+	namespace := s.canary.GetNamespace()
+	deployment := newCanaryDeployment(s.canary.GetDeployment(), s.canary.ServiceAlias, s.release)
+	old, err := s.manager.client.AppsV1().Deployments(namespace).Get(s.ctx, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		if _, err := s.manager.client.AppsV1().Deployments(namespace).Create(s.ctx, deployment, metav1.CreateOptions{}); err != nil {
+			return err
+		}
+	} else {
+		deployment.ResourceVersion = old.ResourceVersion
+		if _, err := s.manager.client.AppsV1().Deployments(namespace).Update(s.ctx, deployment, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	ingresses, _ := s.stable.GetIngress(true)
+	backends := make(map[string]bool)
+	for _, ing := range ingresses {
+		for _, name := range ingressBackendServices(ing) {
+			backends[name] = true
+		}
+	}
+	for _, svc := range s.canary.GetServices(true) {
+		if !backends[svc.Name] {
+			continue
+		}
+		if err := s.ensureService(newCanaryService(svc, s.canary.ServiceAlias, s.release)); err != nil {
+			return err
+		}
+	}
+	return s.waitCanaryReady(deployment.Name)
+}
+
+func (s *releaseController) ensureService(svc *corev1.Service) error {
+	old, err := s.manager.client.CoreV1().Services(svc.Namespace).Get(s.ctx, svc.Name, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = s.manager.client.CoreV1().Services(svc.Namespace).Create(s.ctx, svc, metav1.CreateOptions{})
+		return err
+	}
+	svc.ResourceVersion = old.ResourceVersion
+	svc.Spec.ClusterIP = old.Spec.ClusterIP
+	_, err = s.manager.client.CoreV1().Services(svc.Namespace).Update(s.ctx, svc, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *releaseController) waitCanaryReady(name string) error {
+	logger := s.canary.Logger
+	var initTime int32
+	if podt := s.canary.GetPodTemplate(); podt != nil {
+		for _, c := range podt.Spec.Containers {
+			if c.ReadinessProbe != nil {
+				initTime = c.ReadinessProbe.InitialDelaySeconds
+				break
+			}
+		}
+	}
+	replicas := s.release.CanaryReplicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	timeout := time.Second * time.Duration(40+initTime) * time.Duration(2*replicas)
+	logger.Info(fmt.Sprintf("等待新版本就绪，超时时间：%ds", int(timeout.Seconds())), event.GetLoggerOption("running"))
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(releasePollInterval)
+	defer ticker.Stop()
+	for {
+		deployment, err := s.manager.client.AppsV1().Deployments(s.canary.GetNamespace()).Get(s.ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if deploymentReady(deployment) {
+			logger.Info("新版本已就绪", event.GetLoggerOption("running"))
+			return nil
+		}
+		select {
+		case <-s.stopChan:
+			return errReleaseStopped
+		case <-timer.C:
+			return fmt.Errorf("等待新版本就绪超时")
+		case <-ticker.C:
+		}
+	}
+}
+
+// shiftTraffic routes the percentage of the traffic to the canary version, the
+// percentage 0 routes all traffic back to the stable version.
+func (s *releaseController) shiftTraffic(percentage int) error {
+	ingresses, _ := s.stable.GetIngress(true)
+	stableWeight, canaryWeight := releaseWeights(percentage, int(s.stable.Replicas), s.release.CanaryReplicas)
+	for _, ing := range ingresses {
+		stable := ing.DeepCopy()
+		switch percentage {
+		case 0:
+		case 100:
+			renameIngressBackends(stable, canaryName)
+		default:
+			setIngressWeight(stable, stableWeight)
+		}
+		if err := s.ensureIngress(stable, true); err != nil {
+			return err
+		}
+
+		weighted := newCanaryIngress(ing, s.canary.ServiceAlias, s.release, canaryName(ing.Name))
+		if percentage > 0 && percentage < 100 {
+			setIngressWeight(weighted, canaryWeight)
+			if err := s.ensureIngress(weighted, false); err != nil {
+				return err
+			}
+		} else if err := s.deleteIngress(weighted.Namespace, weighted.Name); err != nil {
+			return err
+		}
+
+		matched := newCanaryIngress(ing, s.canary.ServiceAlias, s.release, canaryName(ing.Name)+"-match")
+		if percentage > 0 && setIngressMatch(matched, s.release.Header, s.release.Cookie) {
+			if err := s.ensureIngress(matched, false); err != nil {
+				return err
+			}
+		} else if err := s.deleteIngress(matched.Namespace, matched.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureIngress creates or updates the ingress, the stable ingress is only updated
+func (s *releaseController) ensureIngress(ing *networkingv1.Ingress, stable bool) error {
+	client := s.manager.client.NetworkingV1().Ingresses(ing.Namespace)
+	old, err := client.Get(s.ctx, ing.Name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) && stable {
+			logrus.Warningf("ingress %s/%s of the release %s not found", ing.Namespace, ing.Name, s.release.ReleaseID)
+			return nil
+		}
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = client.Create(s.ctx, ing, metav1.CreateOptions{})
+		return err
+	}
+	ing.ResourceVersion = old.ResourceVersion
+	ing.UID = old.UID
+	_, err = client.Update(s.ctx, ing, metav1.UpdateOptions{})
+	return err
+}
+
+func (s *releaseController) deleteIngress(namespace, name string) error {
+	err := s.manager.client.NetworkingV1().Ingresses(namespace).Delete(s.ctx, name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// waitCommand waits for the command of the user until timeout, 0 means no timeout
+func (s *releaseController) waitCommand(timeout time.Duration) (string, error) {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+	ticker := time.NewTicker(releasePollInterval)
+	defer ticker.Stop()
+	for {
+		release, err := db.GetManager().ServiceReleaseDao().GetByReleaseID(s.release.ReleaseID)
+		if err != nil {
+			logrus.Warningf("get release %s: %v", s.release.ReleaseID, err)
+		} else if release.Command != "" {
+			return release.Command, nil
+		}
+		select {
+		case <-s.stopChan:
+			return "", errReleaseStopped
+		case <-timer:
+			return "", nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// analysisResult the result of the analyses of a step
+type analysisResult int
+
+const (
+	analysisPassed analysisResult = iota
+	analysisFailed
+	// analysisInconclusive the metric has no data, the step can not be judged yet
+	analysisInconclusive
+)
+
+// analyze runs the analyses of the step, it is inconclusive if any analysis has no data
+// and none fails
+func (s *releaseController) analyze(analyses []dbmodel.ReleaseAnalysis) (analysisResult, string) {
+	if len(analyses) == 0 {
+		return analysisPassed, ""
+	}
+	if s.prometheus == nil {
+		return analysisFailed, "Prometheus 不可用，无法分析发布指标"
+	}
+	replacer := strings.NewReplacer(
+		"{{namespace}}", s.canary.GetNamespace(),
+		"{{service_id}}", s.release.ServiceID,
+		"{{stable_version}}", s.release.StableVersion,
+		"{{canary_version}}", s.release.CanaryVersion,
+		"{{canary_workload}}", canaryName(s.canary.GetDeployment().Name),
+	)
+	result, inconclusive := analysisPassed, ""
+	for _, analysis := range analyses {
+		metric := s.prometheus.GetMetric(replacer.Replace(analysis.Query), time.Now())
+		res, msg := analyzeMetric(analysis, metric)
+		switch res {
+		case analysisFailed:
+			return res, msg
+		case analysisInconclusive:
+			result, inconclusive = res, msg
+		default:
+			s.canary.Logger.Info(msg, event.GetLoggerOption("running"))
+		}
+	}
+	return result, inconclusive
+}
+
+// promote takes all traffic to the canary version, then upgrades the stable workload
+// to the new version and removes the canary resources.
+func (s *releaseController) promote() error {
+	logger := s.canary.Logger
+	s.release.Status = dbmodel.ReleaseStatusPromoting
+	s.updateRelease()
+	logger.Info(fmt.Sprintf("开始将新版本 %s 提升为正式版本", s.release.CanaryVersion), event.GetLoggerOption("running"))
+	if err := s.shiftTraffic(100); err != nil {
+		return s.rollback(dbmodel.ReleaseStatusFailed, fmt.Sprintf("切换流量失败：%s", err.Error()))
+	}
+	service, err := db.GetManager().TenantEnvServiceDao().GetServiceByID(s.release.ServiceID)
+	if err != nil {
+		return s.rollback(dbmodel.ReleaseStatusFailed, fmt.Sprintf("获取组件信息失败：%s", err.Error()))
+	}
+	service.DeployVersion = s.release.CanaryVersion
+	if err := db.GetManager().TenantEnvServiceDao().UpdateModel(service); err != nil {
+		return s.rollback(dbmodel.ReleaseStatusFailed, fmt.Sprintf("更新组件版本失败：%s", err.Error()))
+	}
+	newApp, err := conversion.InitAppService(db.GetManager(), s.release.ServiceID, nil)
+	if err != nil {
+		return s.rollbackVersion(service, fmt.Sprintf("应用组件初始创建失败：%s", err.Error()))
+	}
+	newApp.Logger = logger
+	oldApp := s.manager.store.GetAppService(s.release.ServiceID)
+	if oldApp == nil || oldApp.IsClosed() {
+		return s.rollbackVersion(service, "当前版本已关闭")
+	}
+	if err := oldApp.SetUpgradePatch(newApp); err != nil {
+		if err.Error() != "no upgrade" {
+			return s.rollbackVersion(service, fmt.Sprintf("获取应用组件更新信息失败：%s", err.Error()))
+		}
+	} else {
+		upgrade := &upgradeController{
+			controllerID: s.controllerID,
+			manager:      s.manager,
+			stopChan:     s.stopChan,
+			ctx:          s.ctx,
+		}
+		if err := upgrade.upgradeOne(*newApp); err != nil {
+			if err == ErrWaitCancel {
+				return errReleaseStopped
+			}
+			return s.rollbackVersion(service, fmt.Sprintf("正式版本更新失败：%s", err.Error()))
+		}
+	}
+	// the ingresses of the new version take the place of the old ones
+	s.stable = *newApp
+	if err := s.cleanCanary(); err != nil {
+		logrus.Warningf("clean canary resources of release %s: %v", s.release.ReleaseID, err)
+	}
+	return s.finish(dbmodel.ReleaseStatusPromoted, "")
+}
+
+// rollbackVersion restores the deploy version of the component if the promotion failed
+func (s *releaseController) rollbackVersion(service *dbmodel.TenantEnvServices, message string) error {
+	service.DeployVersion = s.release.StableVersion
+	if err := db.GetManager().TenantEnvServiceDao().UpdateModel(service); err != nil {
+		logrus.Errorf("restore deploy version of service %s: %v", service.ServiceID, err)
+	}
+	return s.rollback(dbmodel.ReleaseStatusFailed, message)
+}
+
+// rollback routes all traffic back to the stable version and removes the canary resources
+func (s *releaseController) rollback(status, message string) error {
+	s.release.Status = dbmodel.ReleaseStatusRollingBack
+	s.release.Message = message
+	s.updateRelease()
+	s.canary.Logger.Error(fmt.Sprintf("%s，开始回滚到版本 %s", message, s.release.StableVersion), event.GetLoggerOption("failure"))
+	if err := s.cleanCanary(); err != nil {
+		return s.finish(dbmodel.ReleaseStatusFailed, fmt.Sprintf("%s；回滚失败：%s", message, err.Error()))
+	}
+	return s.finish(status, message)
+}
+
+func (s *releaseController) cleanCanary() error {
+	if err := s.shiftTraffic(0); err != nil {
+		return err
+	}
+	namespace := s.canary.GetNamespace()
+	for _, svc := range s.canary.GetServices(true) {
+		err := s.manager.client.CoreV1().Services(namespace).Delete(s.ctx, canaryName(svc.Name), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	if deployment := s.canary.GetDeployment(); deployment != nil {
+		err := s.manager.client.AppsV1().Deployments(namespace).Delete(s.ctx, canaryName(deployment.Name), metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *releaseController) finish(status, message string) error {
+	now := time.Now()
+	s.release.Status = status
+	s.release.Message = message
+	s.release.FinishTime = &now
+	s.updateRelease()
+	return nil
+}
+
+func (s *releaseController) updateRelease() {
+	if err := db.GetManager().ServiceReleaseDao().UpdateModel(s.release); err != nil {
+		logrus.Errorf("update release %s: %v", s.release.ReleaseID, err)
+	}
+}
+
+func canaryName(name string) string {
+	return name + "-canary"
+}
+
+// canaryLabels returns the labels of the canary resources, the name label is changed so
+// that the stable services do not select the canary pods
+func canaryLabels(labels map[string]string, alias string, release *dbmodel.ServiceRelease) map[string]string {
+	res := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		res[k] = v
+	}
+	delete(res, "creater_id")
+	if _, ok := res["name"]; ok {
+		res["name"] = canaryName(alias)
+	}
+	if _, ok := res["version"]; ok {
+		res["version"] = release.CanaryVersion
+	}
+	res["release_id"] = release.ReleaseID
+	return res
+}
+
+func newCanaryDeployment(deployment *appsv1.Deployment, alias string, release *dbmodel.ServiceRelease) *appsv1.Deployment {
+	canary := deployment.DeepCopy()
+	canary.ObjectMeta = metav1.ObjectMeta{
+		Name:        canaryName(deployment.Name),
+		Namespace:   deployment.Namespace,
+		Labels:      canaryLabels(deployment.Labels, alias, release),
+		Annotations: deployment.Annotations,
+	}
+	canary.Status = appsv1.DeploymentStatus{}
+	replicas := int32(release.CanaryReplicas)
+	canary.Spec.Replicas = &replicas
+	if canary.Spec.Selector != nil {
+		canary.Spec.Selector.MatchLabels = canaryLabels(canary.Spec.Selector.MatchLabels, alias, release)
+		delete(canary.Spec.Selector.MatchLabels, "version")
+	}
+	canary.Spec.Template.Labels = canaryLabels(canary.Spec.Template.Labels, alias, release)
+	return canary
+}
+
+func newCanaryService(svc *corev1.Service, alias string, release *dbmodel.ServiceRelease) *corev1.Service {
+	canary := svc.DeepCopy()
+	canary.ObjectMeta = metav1.ObjectMeta{
+		Name:        canaryName(svc.Name),
+		Namespace:   svc.Namespace,
+		Labels:      canaryLabels(svc.Labels, alias, release),
+		Annotations: svc.Annotations,
+	}
+	canary.Status = corev1.ServiceStatus{}
+	if canary.Spec.ClusterIP != corev1.ClusterIPNone {
+		canary.Spec.ClusterIP = ""
+		canary.Spec.ClusterIPs = nil
+	}
+	canary.Spec.Selector = canaryLabels(canary.Spec.Selector, alias, release)
+	delete(canary.Spec.Selector, "release_id")
+	return canary
+}
+
+func newCanaryIngress(ing *networkingv1.Ingress, alias string, release *dbmodel.ServiceRelease, name string) *networkingv1.Ingress {
+	canary := ing.DeepCopy()
+	canary.ObjectMeta = metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   ing.Namespace,
+		Labels:      canaryLabels(ing.Labels, alias, release),
+		Annotations: make(map[string]string, len(ing.Annotations)),
+	}
+	for k, v := range ing.Annotations {
+		canary.Annotations[k] = v
+	}
+	canary.Status = networkingv1.IngressStatus{}
+	renameIngressBackends(canary, canaryName)
+	return canary
+}
+
+// ingressBackendServices returns the names of the backend services of the ingress
+func ingressBackendServices(ing *networkingv1.Ingress) []string {
+	var names []string
+	renameIngressBackends(ing.DeepCopy(), func(name string) string {
+		names = append(names, name)
+		return name
+	})
+	return names
+}
+
+func renameIngressBackends(ing *networkingv1.Ingress, rename func(string) string) {
+	if b := ing.Spec.DefaultBackend; b != nil && b.Service != nil {
+		b.Service.Name = rename(b.Service.Name)
+	}
+	for i := range ing.Spec.Rules {
+		if ing.Spec.Rules[i].HTTP == nil {
+			continue
+		}
+		for j := range ing.Spec.Rules[i].HTTP.Paths {
+			if b := ing.Spec.Rules[i].HTTP.Paths[j].Backend.Service; b != nil {
+				b.Name = rename(b.Name)
+			}
+		}
+	}
+}
+
+func setIngressWeight(ing *networkingv1.Ingress, weight int) {
+	if ing.Annotations == nil {
+		ing.Annotations = make(map[string]string)
+	}
+	ing.Annotations[parser.GetAnnotationWithPrefix("weight")] = fmt.Sprintf("%d", weight)
+}
+
+// setIngressMatch routes the requests match the header or the cookie to the canary
+// version, it returns false if neither is set.
+func setIngressMatch(ing *networkingv1.Ingress, header, cookie string) bool {
+	if header == "" && cookie == "" {
+		return false
+	}
+	delete(ing.Annotations, parser.GetAnnotationWithPrefix("weight"))
+	delete(ing.Annotations, parser.GetAnnotationWithPrefix("header"))
+	delete(ing.Annotations, parser.GetAnnotationWithPrefix("cookie"))
+	if header != "" {
+		ing.Annotations[parser.GetAnnotationWithPrefix("header")] = header
+	} else {
+		ing.Annotations[parser.GetAnnotationWithPrefix("cookie")] = cookie
+	}
+	return true
+}
+
+// releaseWeights returns the weights of the stable and canary endpoints. The gateway
+// weights every endpoint of the pool, so the weights are divided by the replicas.
+func releaseWeights(percentage, stableReplicas, canaryReplicas int) (int, int) {
+	if stableReplicas < 1 {
+		stableReplicas = 1
+	}
+	if canaryReplicas < 1 {
+		canaryReplicas = 1
+	}
+	stable := (100 - percentage) * canaryReplicas
+	canary := percentage * stableReplicas
+	if stable == 0 || canary == 0 {
+		return stable, canary
+	}
+	g := gcd(stable, canary)
+	return stable / g, canary / g
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func deploymentReady(deployment *appsv1.Deployment) bool {
+	if deployment.Generation > deployment.Status.ObservedGeneration {
+		return false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.UpdatedReplicas >= replicas && deployment.Status.ReadyReplicas >= replicas
+}
+
+// analyzeMetric checks the samples of the metric are in the range of the analysis,
+// the metric without samples is inconclusive, there may be no requests yet.
+func analyzeMetric(analysis dbmodel.ReleaseAnalysis, metric prometheus.Metric) (analysisResult, string) {
+	if metric.Error != "" {
+		return analysisFailed, fmt.Sprintf("分析指标 %s 失败：%s", analysis.Name, metric.Error)
+	}
+	var values []float64
+	for _, v := range metric.MetricValues {
+		if v.Sample != nil {
+			values = append(values, v.Sample.Value())
+		} else if len(v.Series) > 0 {
+			values = append(values, v.Series[len(v.Series)-1].Value())
+		}
+	}
+	var checked int
+	for _, value := range values {
+		if value != value { // NaN
+			continue
+		}
+		checked++
+		if analysis.Max != nil && value > *analysis.Max {
+			return analysisFailed, fmt.Sprintf("指标 %s 的值 %g 大于 %g", analysis.Name, value, *analysis.Max)
+		}
+		if analysis.Min != nil && value < *analysis.Min {
+			return analysisFailed, fmt.Sprintf("指标 %s 的值 %g 小于 %g", analysis.Name, value, *analysis.Min)
+		}
+	}
+	if checked == 0 {
+		return analysisInconclusive, fmt.Sprintf("指标 %s 无数据", analysis.Name)
+	}
+	return analysisPassed, fmt.Sprintf("指标 %s 分析通过", analysis.Name)
+}
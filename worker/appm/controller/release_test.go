@@ -0,0 +1,218 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package controller
+
+import (
+	"math"
+	"testing"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/gateway/annotations/parser"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReleaseWeights(t *testing.T) {
+	tests := []struct {
+		percentage, stableReplicas, canaryReplicas int
+		stable, canary                             int
+	}{
+		{percentage: 10, stableReplicas: 1, canaryReplicas: 1, stable: 9, canary: 1},
+		{percentage: 50, stableReplicas: 3, canaryReplicas: 1, stable: 1, canary: 3},
+		{percentage: 20, stableReplicas: 4, canaryReplicas: 2, stable: 2, canary: 1},
+		{percentage: 100, stableReplicas: 2, canaryReplicas: 1, stable: 0, canary: 200},
+		{percentage: 30, stableReplicas: 0, canaryReplicas: 0, stable: 7, canary: 3},
+	}
+	for _, tc := range tests {
+		stable, canary := releaseWeights(tc.percentage, tc.stableReplicas, tc.canaryReplicas)
+		if stable != tc.stable || canary != tc.canary {
+			t.Errorf("releaseWeights(%d, %d, %d) = %d, %d; want %d, %d", tc.percentage, tc.stableReplicas,
+				tc.canaryReplicas, stable, canary, tc.stable, tc.canary)
+		}
+	}
+}
+
+func TestAnalyzeMetric(t *testing.T) {
+	max := 0.05
+	min := 10.0
+	sample := func(v float64) prometheus.MetricValue {
+		return prometheus.MetricValue{Sample: &prometheus.Point{1, v}}
+	}
+	tests := []struct {
+		name     string
+		analysis dbmodel.ReleaseAnalysis
+		metric   prometheus.Metric
+		want     analysisResult
+	}{
+		{
+			name:     "under max",
+			analysis: dbmodel.ReleaseAnalysis{Name: "5xx", Max: &max},
+			metric:   prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{sample(0.01)}}},
+			want:     analysisPassed,
+		},
+		{
+			name:     "over max",
+			analysis: dbmodel.ReleaseAnalysis{Name: "5xx", Max: &max},
+			metric:   prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{sample(0.01), sample(0.2)}}},
+			want:     analysisFailed,
+		},
+		{
+			name:     "under min",
+			analysis: dbmodel.ReleaseAnalysis{Name: "qps", Min: &min},
+			metric: prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{
+				{Series: []prometheus.Point{{1, 20}, {2, 5}}},
+			}}},
+			want: analysisFailed,
+		},
+		{
+			name:     "no data",
+			analysis: dbmodel.ReleaseAnalysis{Name: "5xx", Max: &max},
+			metric:   prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{sample(math.NaN())}}},
+			want:     analysisInconclusive,
+		},
+		{
+			name:     "no series",
+			analysis: dbmodel.ReleaseAnalysis{Name: "5xx", Max: &max},
+			metric:   prometheus.Metric{},
+			want:     analysisInconclusive,
+		},
+		{
+			name:     "query error",
+			analysis: dbmodel.ReleaseAnalysis{Name: "5xx", Max: &max},
+			metric:   prometheus.Metric{Error: "bad query"},
+			want:     analysisFailed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, msg := analyzeMetric(tc.analysis, tc.metric)
+			if result != tc.want {
+				t.Errorf("analyzeMetric() = %v, %s; want %v", result, msg, tc.want)
+			}
+		})
+	}
+}
+
+func TestCanaryResources(t *testing.T) {
+	release := &dbmodel.ServiceRelease{ReleaseID: "r1", CanaryVersion: "v2", CanaryReplicas: 2}
+	labels := map[string]string{"name": "app", "service_id": "s1", "creater_id": "c1", "version": "v1"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-deploy", Namespace: "ns", Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "app", "service_id": "s1"}},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: labels}},
+		},
+	}
+	canary := newCanaryDeployment(deployment, "app", release)
+	if canary.Name != "app-deploy-canary" || *canary.Spec.Replicas != 2 {
+		t.Fatalf("unexpected canary deployment %s with replicas %d", canary.Name, *canary.Spec.Replicas)
+	}
+	if _, ok := canary.Labels["creater_id"]; ok {
+		t.Errorf("the canary deployment should not be labeled with creater_id")
+	}
+	if canary.Spec.Template.Labels["name"] != "app-canary" || canary.Spec.Template.Labels["version"] != "v2" {
+		t.Errorf("unexpected canary pod labels %v", canary.Spec.Template.Labels)
+	}
+	for k, v := range canary.Spec.Selector.MatchLabels {
+		if canary.Spec.Template.Labels[k] != v {
+			t.Errorf("the canary selector %s=%s does not match the pod labels", k, v)
+		}
+	}
+	if deployment.Labels["name"] != "app" {
+		t.Errorf("the stable deployment is changed")
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-80", Namespace: "ns", Labels: labels},
+		Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.1", Selector: map[string]string{"name": "app"}},
+	}
+	canarySvc := newCanaryService(svc, "app", release)
+	if canarySvc.Name != "app-80-canary" || canarySvc.Spec.ClusterIP != "" || canarySvc.Spec.Selector["name"] != "app-canary" {
+		t.Errorf("unexpected canary service %s %s %v", canarySvc.Name, canarySvc.Spec.ClusterIP, canarySvc.Spec.Selector)
+	}
+
+	pathType := networkingv1.PathTypePrefix
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "rule",
+			Namespace:   "ns",
+			Labels:      labels,
+			Annotations: map[string]string{parser.GetAnnotationWithPrefix("weight"): "5"},
+		},
+		Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+			Host: "app.example.com",
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+				Paths: []networkingv1.HTTPIngressPath{{
+					Path:     "/",
+					PathType: &pathType,
+					Backend:  networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "app-80"}},
+				}},
+			}},
+		}}},
+	}
+	if names := ingressBackendServices(ing); len(names) != 1 || names[0] != "app-80" {
+		t.Errorf("unexpected backend services %v", names)
+	}
+	weighted := newCanaryIngress(ing, "app", release, canaryName(ing.Name))
+	setIngressWeight(weighted, 3)
+	if weighted.Name != "rule-canary" || weighted.Annotations[parser.GetAnnotationWithPrefix("weight")] != "3" {
+		t.Errorf("unexpected canary ingress %s %v", weighted.Name, weighted.Annotations)
+	}
+	if weighted.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name != "app-80-canary" {
+		t.Errorf("the canary ingress should route to the canary service")
+	}
+	if ing.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name != "app-80" || ing.Annotations[parser.GetAnnotationWithPrefix("weight")] != "5" {
+		t.Errorf("the stable ingress is changed")
+	}
+
+	matched := newCanaryIngress(ing, "app", release, "rule-canary-match")
+	if setIngressMatch(matched, "", "") {
+		t.Errorf("the ingress without header or cookie should not be matched")
+	}
+	if !setIngressMatch(matched, "x-canary=true", "") {
+		t.Fatalf("the ingress with header should be matched")
+	}
+	if _, ok := matched.Annotations[parser.GetAnnotationWithPrefix("weight")]; ok || matched.Annotations[parser.GetAnnotationWithPrefix("header")] != "x-canary=true" {
+		t.Errorf("unexpected match annotations %v", matched.Annotations)
+	}
+}
+
+func TestDeploymentReady(t *testing.T) {
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 2, ReadyReplicas: 2},
+	}
+	if deploymentReady(deployment) {
+		t.Errorf("the deployment is not observed")
+	}
+	deployment.Status.ObservedGeneration = 2
+	deployment.Status.ReadyReplicas = 1
+	if deploymentReady(deployment) {
+		t.Errorf("the deployment is not ready")
+	}
+	deployment.Status.ReadyReplicas = 2
+	if !deploymentReady(deployment) {
+		t.Errorf("the deployment is ready")
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/event"
 	"github.com/wutong-paas/wutong/util"
+	"github.com/wutong-paas/wutong/worker/appm/f"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -184,13 +185,18 @@ func (s *startController) startOne(app v1.AppService) error {
 	if hpas := app.GetHPAs(); len(hpas) != 0 {
 		for _, hpa := range hpas {
 			if len(hpa.ResourceVersion) == 0 {
-				_, err := s.manager.client.AutoscalingV1().HorizontalPodAutoscalers(hpa.GetNamespace()).Create(s.ctx, hpa, metav1.CreateOptions{})
+				err := f.CreateHPA(s.ctx, hpa, s.manager.client)
 				if err != nil && !errors.IsAlreadyExists(err) {
 					logrus.Debugf("hpa: %#v", hpa)
 					return fmt.Errorf("create hpa: %v", err)
 				}
 			}
 		}
+		if client := prometheusRuleClient(s.manager, &app); client != nil {
+			for _, rule := range app.GetPrometheusRules() {
+				f.EnsurePrometheusRule(rule, client)
+			}
+		}
 	}
 
 	//step 7: create CR resource
@@ -27,6 +27,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/event"
 	"github.com/wutong-paas/wutong/util"
+	"github.com/wutong-paas/wutong/worker/appm/f"
 	"github.com/wutong-paas/wutong/worker/appm/store"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -179,11 +180,21 @@ func (s *stopController) stopOne(app v1.AppService) error {
 	//step 7: deleta all hpa
 	if hpas := app.GetHPAs(); len(hpas) != 0 {
 		for _, hpa := range hpas {
-			err := s.manager.client.AutoscalingV1().HorizontalPodAutoscalers(hpa.GetNamespace()).Delete(s.ctx, hpa.GetName(), metav1.DeleteOptions{})
+			err := f.DeleteHPA(s.ctx, hpa.GetNamespace(), hpa.GetName(), s.manager.client)
 			if err != nil && !errors.IsNotFound(err) {
 				return fmt.Errorf("删除 HPA 资源错误：%v", err)
 			}
 		}
+		if crd, _ := s.manager.store.GetCrd(store.PrometheusRule); crd != nil {
+			if client, err := s.manager.store.GetServiceMonitorClient(); err == nil {
+				for _, hpa := range hpas {
+					err := client.MonitoringV1().PrometheusRules(hpa.GetNamespace()).Delete(s.ctx, hpa.GetName(), metav1.DeleteOptions{})
+					if err != nil && !errors.IsNotFound(err) {
+						logrus.Errorf("delete prometheus rule failure: %s", err.Error())
+					}
+				}
+			}
+		}
 	}
 
 	//step 8: delete CR resource
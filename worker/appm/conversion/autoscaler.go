@@ -20,20 +20,29 @@ package conversion
 
 import (
 	"fmt"
+	"strings"
 
+	monitorv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/sirupsen/logrus"
 
-	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/wutong-paas/wutong/db"
 	"github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/alerting"
 	"github.com/wutong-paas/wutong/util"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
 )
 
+// XPAPrometheusMetricName the name of the recorded prometheus metrics. The metrics is
+// served to the HPA as an external metric by the prometheus adapter, and the series of
+// a rule metric is selected by the labels rule_id and metric_name.
+const XPAPrometheusMetricName = "wutong_xpa_metric"
+
 var str2ResourceName = map[string]corev1.ResourceName{
 	"cpu":    corev1.ResourceCPU,
 	"memory": corev1.ResourceMemory,
@@ -41,24 +50,26 @@ var str2ResourceName = map[string]corev1.ResourceName{
 
 // TenantEnvServiceAutoscaler -
 func TenantEnvServiceAutoscaler(as *v1.AppService, dbmanager db.Manager) error {
-	hpas, err := newHPAs(as, dbmanager)
+	hpas, rules, err := newHPAs(as, dbmanager)
 	if err != nil {
 		return fmt.Errorf("create HPAs: %v", err)
 	}
 	logrus.Debugf("the numbers of HPAs: %d", len(hpas))
 
 	as.SetHPAs(hpas)
+	as.SetPrometheusRules(rules)
 
 	return nil
 }
 
-func newHPAs(as *v1.AppService, dbmanager db.Manager) ([]*autoscalingv1.HorizontalPodAutoscaler, error) {
+func newHPAs(as *v1.AppService, dbmanager db.Manager) ([]*autoscalingv2.HorizontalPodAutoscaler, []*monitorv1.PrometheusRule, error) {
 	xpaRules, err := dbmanager.TenantEnvServceAutoscalerRulesDao().ListEnableOnesByServiceID(as.ServiceID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var hpas []*autoscalingv1.HorizontalPodAutoscaler
+	var hpas []*autoscalingv2.HorizontalPodAutoscaler
+	var promRules []*monitorv1.PrometheusRule
 	for _, rule := range xpaRules {
 		var kind, name string
 		if as.GetStatefulSet() != nil {
@@ -67,51 +78,235 @@ func newHPAs(as *v1.AppService, dbmanager db.Manager) ([]*autoscalingv1.Horizont
 			kind, name = "Deployment", as.GetDeployment().GetName()
 		}
 
+		metrics, err := dbmanager.TenantEnvServceAutoscalerRuleMetricsDao().ListByRuleID(rule.RuleID)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		labels := as.GetCommonLabels(map[string]string{
 			"rule_id": rule.RuleID,
 			"version": as.DeployVersion,
 		})
 
-		hpa := newHPA(as.GetNamespace(), kind, name, labels, rule)
-
+		hpa, err := newHPA(as.GetNamespace(), kind, name, labels, rule, metrics)
+		if err != nil {
+			logrus.Warningf("rule id: %s; skip the invalid rule: %v", rule.RuleID, err)
+			continue
+		}
+		promRule, err := newPrometheusRule(as.GetNamespace(), as.ServiceID, labels, rule, metrics)
+		if err != nil {
+			logrus.Warningf("rule id: %s; skip the invalid rule: %v", rule.RuleID, err)
+			continue
+		}
 		hpas = append(hpas, hpa)
+		if promRule != nil {
+			promRules = append(promRules, promRule)
+		}
 	}
 
-	return hpas, nil
+	return hpas, promRules, nil
 }
 
-func createResourceMetrics(metric *model.TenantEnvServiceAutoscalerRuleMetrics) autoscalingv1.MetricSpec {
-	ms := autoscalingv1.MetricSpec{
-		Type: autoscalingv1.ResourceMetricSourceType,
-		Resource: &autoscalingv1.ResourceMetricSource{
+func createResourceMetrics(metric *model.TenantEnvServiceAutoscalerRuleMetrics) autoscalingv2.MetricSpec {
+	ms := autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
 			Name: str2ResourceName[metric.MetricsName],
 		},
 	}
 
-	if metric.MetricTargetType == "utilization" {
+	if metric.MetricTargetType == model.XPATargetTypeUtilization {
 		value := int32(metric.MetricTargetValue)
-		ms.Resource.TargetAverageUtilization = &value
-		// ms.Resource.Target = autoscalingv1.MetricTarget{
-		// 	Type:               autoscalingv1.UtilizationMetricType,
-		// 	AverageUtilization: &value,
-		// }
-	}
-	if metric.MetricTargetType == "average_value" {
-		ms.Resource.TargetAverageValue = resource.NewMilliQuantity(int64(metric.MetricTargetValue), resource.DecimalSI)
-		// ms.Resource.Target.Type = autoscalingv1.AverageValueMetricType
-		// if metric.MetricsName == "cpu" {
-		// 	ms.Resource.Target.AverageValue = resource.NewMilliQuantity(int64(metric.MetricTargetValue), resource.DecimalSI)
-		// }
-		// if metric.MetricsName == "memory" {
-		// 	ms.Resource.Target.AverageValue = resource.NewQuantity(int64(metric.MetricTargetValue*1024*1024), resource.BinarySI)
-		// }
+		ms.Resource.Target = autoscalingv2.MetricTarget{
+			Type:               autoscalingv2.UtilizationMetricType,
+			AverageUtilization: &value,
+		}
+	}
+	if metric.MetricTargetType == model.XPATargetTypeAverageValue {
+		ms.Resource.Target.Type = autoscalingv2.AverageValueMetricType
+		if metric.MetricsName == "cpu" {
+			ms.Resource.Target.AverageValue = resource.NewMilliQuantity(int64(metric.MetricTargetValue), resource.DecimalSI)
+		}
+		if metric.MetricsName == "memory" {
+			ms.Resource.Target.AverageValue = resource.NewQuantity(int64(metric.MetricTargetValue*1024*1024), resource.BinarySI)
+		}
 	}
 
 	return ms
 }
 
-func newHPA(namespace, kind, name string, labels map[string]string, rule *model.TenantEnvServiceAutoscalerRules) *autoscalingv1.HorizontalPodAutoscaler {
-	hpa := &autoscalingv1.HorizontalPodAutoscaler{
+// createMetricSpec converts the rule metric to the metric of autoscaling/v2
+func createMetricSpec(ruleID string, metric *model.TenantEnvServiceAutoscalerRuleMetrics) (autoscalingv2.MetricSpec, error) {
+	if metric.MetricsType == model.XPAMetricTypeResource {
+		if _, ok := str2ResourceName[metric.MetricsName]; !ok {
+			return autoscalingv2.MetricSpec{}, fmt.Errorf("unsupported resource metric %s", metric.MetricsName)
+		}
+		if metric.MetricTargetType != model.XPATargetTypeUtilization && metric.MetricTargetType != model.XPATargetTypeAverageValue {
+			return autoscalingv2.MetricSpec{}, fmt.Errorf("unsupported target type %s of resource metric", metric.MetricTargetType)
+		}
+		return createResourceMetrics(metric), nil
+	}
+
+	target, err := createMetricTarget(metric)
+	if err != nil {
+		return autoscalingv2.MetricSpec{}, err
+	}
+	selector, err := parseMetricSelector(metric.MetricSelector)
+	if err != nil {
+		return autoscalingv2.MetricSpec{}, err
+	}
+	identifier := autoscalingv2.MetricIdentifier{Name: metric.MetricsName, Selector: selector}
+
+	switch metric.MetricsType {
+	case model.XPAMetricTypePods:
+		if target.Type != autoscalingv2.AverageValueMetricType {
+			return autoscalingv2.MetricSpec{}, fmt.Errorf("the target of pods metric must be average_value")
+		}
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{Metric: identifier, Target: target},
+		}, nil
+	case model.XPAMetricTypeObject:
+		if metric.ObjectKind == "" || metric.ObjectName == "" {
+			return autoscalingv2.MetricSpec{}, fmt.Errorf("the described object of object metric is required")
+		}
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ObjectMetricSourceType,
+			Object: &autoscalingv2.ObjectMetricSource{
+				DescribedObject: autoscalingv2.CrossVersionObjectReference{
+					APIVersion: metric.ObjectAPIVersion,
+					Kind:       metric.ObjectKind,
+					Name:       metric.ObjectName,
+				},
+				Metric: identifier,
+				Target: target,
+			},
+		}, nil
+	case model.XPAMetricTypeExternal:
+		return autoscalingv2.MetricSpec{
+			Type:     autoscalingv2.ExternalMetricSourceType,
+			External: &autoscalingv2.ExternalMetricSource{Metric: identifier, Target: target},
+		}, nil
+	case model.XPAMetricTypePrometheus:
+		if strings.TrimSpace(metric.PromQL) == "" {
+			return autoscalingv2.MetricSpec{}, fmt.Errorf("the promql of prometheus metric is required")
+		}
+		return autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ExternalMetricSourceType,
+			External: &autoscalingv2.ExternalMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{
+					Name: XPAPrometheusMetricName,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{
+							"rule_id":     ruleID,
+							"metric_name": metric.MetricsName,
+						},
+					},
+				},
+				Target: target,
+			},
+		}, nil
+	}
+	return autoscalingv2.MetricSpec{}, fmt.Errorf("unsupported metric type %s", metric.MetricsType)
+}
+
+func createMetricTarget(metric *model.TenantEnvServiceAutoscalerRuleMetrics) (autoscalingv2.MetricTarget, error) {
+	quantity := resource.NewQuantity(int64(metric.MetricTargetValue), resource.DecimalSI)
+	if metric.MetricTargetQuantity != "" {
+		q, err := resource.ParseQuantity(metric.MetricTargetQuantity)
+		if err != nil {
+			return autoscalingv2.MetricTarget{}, fmt.Errorf("invalid target quantity %s: %v", metric.MetricTargetQuantity, err)
+		}
+		quantity = &q
+	}
+	switch metric.MetricTargetType {
+	case model.XPATargetTypeAverageValue:
+		return autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: quantity}, nil
+	case model.XPATargetTypeValue:
+		return autoscalingv2.MetricTarget{Type: autoscalingv2.ValueMetricType, Value: quantity}, nil
+	}
+	return autoscalingv2.MetricTarget{}, fmt.Errorf("unsupported target type %s of %s", metric.MetricTargetType, metric.MetricsType)
+}
+
+// parseMetricSelector parses the selector like verb=GET,code=200
+func parseMetricSelector(selector string) (*metav1.LabelSelector, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, nil
+	}
+	s, err := metav1.ParseToLabelSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metric selector %s: %v", selector, err)
+	}
+	return s, nil
+}
+
+func createBehavior(rule *model.TenantEnvServiceAutoscalerRules) (*autoscalingv2.HorizontalPodAutoscalerBehavior, error) {
+	behavior, err := rule.GetBehavior()
+	if err != nil {
+		return nil, fmt.Errorf("invalid behavior: %v", err)
+	}
+	if behavior == nil || (behavior.ScaleUp == nil && behavior.ScaleDown == nil) {
+		return nil, nil
+	}
+	scaleUp, err := createScalingRules(behavior.ScaleUp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scale up behavior: %v", err)
+	}
+	scaleDown, err := createScalingRules(behavior.ScaleDown)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scale down behavior: %v", err)
+	}
+	return &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   scaleUp,
+		ScaleDown: scaleDown,
+	}, nil
+}
+
+func createScalingRules(rules *model.AutoscalerScalingRules) (*autoscalingv2.HPAScalingRules, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	res := &autoscalingv2.HPAScalingRules{
+		StabilizationWindowSeconds: rules.StabilizationWindowSeconds,
+	}
+	if w := rules.StabilizationWindowSeconds; w != nil && (*w < 0 || *w > 3600) {
+		return nil, fmt.Errorf("the stabilization window must be between 0 and 3600 seconds")
+	}
+	if rules.SelectPolicy != "" {
+		policy := autoscalingv2.ScalingPolicySelect(rules.SelectPolicy)
+		switch policy {
+		case autoscalingv2.MaxChangePolicySelect, autoscalingv2.MinChangePolicySelect, autoscalingv2.DisabledPolicySelect:
+		default:
+			return nil, fmt.Errorf("unsupported select policy %s", rules.SelectPolicy)
+		}
+		res.SelectPolicy = &policy
+	}
+	for _, p := range rules.Policies {
+		policyType := autoscalingv2.HPAScalingPolicyType(p.Type)
+		if policyType != autoscalingv2.PodsScalingPolicy && policyType != autoscalingv2.PercentScalingPolicy {
+			return nil, fmt.Errorf("unsupported policy type %s", p.Type)
+		}
+		if p.Value <= 0 {
+			return nil, fmt.Errorf("the value of policy must be greater than 0")
+		}
+		if p.PeriodSeconds <= 0 || p.PeriodSeconds > 1800 {
+			return nil, fmt.Errorf("the period of policy must be between 1 and 1800 seconds")
+		}
+		res.Policies = append(res.Policies, autoscalingv2.HPAScalingPolicy{
+			Type:          policyType,
+			Value:         p.Value,
+			PeriodSeconds: p.PeriodSeconds,
+		})
+	}
+	return res, nil
+}
+
+func newHPA(namespace, kind, name string, labels map[string]string, rule *model.TenantEnvServiceAutoscalerRules,
+	metrics []*model.TenantEnvServiceAutoscalerRuleMetrics) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if rule.MinReplicas < 1 || rule.MaxReplicas < rule.MinReplicas {
+		return nil, fmt.Errorf("the max replicas must be greater than or equal to the min replicas, and the min replicas must be greater than 0")
+	}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      rule.RuleID,
 			Namespace: namespace,
@@ -119,34 +314,81 @@ func newHPA(namespace, kind, name string, labels map[string]string, rule *model.
 		},
 	}
 
-	spec := autoscalingv1.HorizontalPodAutoscalerSpec{
+	spec := autoscalingv2.HorizontalPodAutoscalerSpec{
 		MinReplicas: util.Int32(int32(rule.MinReplicas)),
 		MaxReplicas: int32(rule.MaxReplicas),
-		ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
 			Kind:       kind,
 			Name:       name,
 			APIVersion: "apps/v1",
 		},
 	}
 
-	// for _, metric := range metrics {
-	// 	if metric.MetricsType != "resource_metrics" {
-	// 		logrus.Warningf("rule id:  %s; unsupported metric type: %s", rule.RuleID, metric.MetricsType)
-	// 		continue
-	// 	}
-	// 	if metric.MetricTargetValue <= 0 {
-	// 		// TODO: If the target value of cpu and memory is 0, it will not take effect.
-	// 		// TODO: The target value of the custom indicator can be 0.
-	// 		continue
-	// 	}
-
-	// 	ms := createResourceMetrics(metric)
-	// 	spec.Metrics = append(spec.Metrics, ms)
-	// }
-	// if len(spec.Metrics) == 0 {
-	// 	return nil
-	// }
+	names := make(map[string]struct{}, len(metrics))
+	for _, metric := range metrics {
+		key := metric.MetricsType + "/" + metric.MetricsName
+		if _, ok := names[key]; ok {
+			return nil, fmt.Errorf("duplicate metric %s", key)
+		}
+		names[key] = struct{}{}
+		ms, err := createMetricSpec(rule.RuleID, metric)
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: %v", metric.MetricsName, err)
+		}
+		spec.Metrics = append(spec.Metrics, ms)
+	}
+
+	behavior, err := createBehavior(rule)
+	if err != nil {
+		return nil, err
+	}
+	spec.Behavior = behavior
 	hpa.Spec = spec
 
-	return hpa
+	return hpa, nil
+}
+
+// newPrometheusRule creates the recording rules of the prometheus metrics, returns nil
+// if the rule has no prometheus metric. The queries only select the series of the namespace.
+func newPrometheusRule(namespace, serviceID string, labels map[string]string, rule *model.TenantEnvServiceAutoscalerRules,
+	metrics []*model.TenantEnvServiceAutoscalerRuleMetrics) (*monitorv1.PrometheusRule, error) {
+	replacer := strings.NewReplacer("{{namespace}}", namespace, "{{service_id}}", serviceID)
+	var rules []monitorv1.Rule
+	for _, metric := range metrics {
+		if metric.MetricsType != model.XPAMetricTypePrometheus {
+			continue
+		}
+		expr, err := alerting.EnforceNamespace(replacer.Replace(metric.PromQL), namespace)
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: invalid promql: %v", metric.MetricsName, err)
+		}
+		rules = append(rules, monitorv1.Rule{
+			Record: XPAPrometheusMetricName,
+			Expr:   intstr.FromString(expr),
+			Labels: map[string]string{
+				"namespace":   namespace,
+				"service_id":  serviceID,
+				"rule_id":     rule.RuleID,
+				"metric_name": metric.MetricsName,
+			},
+		})
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return &monitorv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rule.RuleID,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: monitorv1.PrometheusRuleSpec{
+			Groups: []monitorv1.RuleGroup{
+				{
+					Name:  "xpa-" + rule.RuleID,
+					Rules: rules,
+				},
+			},
+		},
+	}, nil
 }
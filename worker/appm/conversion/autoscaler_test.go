@@ -6,6 +6,7 @@ import (
 
 	"github.com/wutong-paas/wutong/db/model"
 	k8sutil "github.com/wutong-paas/wutong/util/k8s"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,16 +34,133 @@ func TestNewHPA(t *testing.T) {
 	kind := "Deployment"
 	name := "45197f4936cf45efa2ac4831ce42025a-deployment-6d84f798b4-tmvfc"
 
-	hpa := newHPA(namespace, kind, name, nil, rule)
+	hpa, err := newHPA(namespace, kind, name, nil, rule, nil)
+	if err != nil {
+		t.Fatalf("new hpa: %v", err)
+	}
 
 	clientset, err := k8sutil.NewClientset("/opt/wutong/etc/kubernetes/kubecfg/admin.kubeconfig")
 	if err != nil {
 		t.Fatalf("error creating k8s clientset: %s", err.Error())
 	}
 
-	// _, err = clientset.AutoscalingV2beta2().HorizontalPodAutoscalers(hpa.GetNamespace()).Create(context.Background(), hpa, metav1.CreateOptions{})
-	_, err = clientset.AutoscalingV1().HorizontalPodAutoscalers(hpa.GetNamespace()).Create(context.Background(), hpa, metav1.CreateOptions{})
+	_, err = clientset.AutoscalingV2().HorizontalPodAutoscalers(hpa.GetNamespace()).Create(context.Background(), hpa, metav1.CreateOptions{})
 	if err != nil {
 		t.Fatalf("create hpa: %v", err)
 	}
 }
+
+func TestCreateMetricSpecV2(t *testing.T) {
+	tests := []struct {
+		name    string
+		metric  *model.TenantEnvServiceAutoscalerRuleMetrics
+		want    autoscalingv2.MetricSourceType
+		wantErr bool
+	}{
+		{
+			name: "cpu utilization",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypeResource, MetricsName: "cpu",
+				MetricTargetType: model.XPATargetTypeUtilization, MetricTargetValue: 60},
+			want: autoscalingv2.ResourceMetricSourceType,
+		},
+		{
+			name: "pods metric",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypePods, MetricsName: "http_requests",
+				MetricTargetType: model.XPATargetTypeAverageValue, MetricTargetQuantity: "500m", MetricSelector: "verb=GET"},
+			want: autoscalingv2.PodsMetricSourceType,
+		},
+		{
+			name: "pods metric with value target",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypePods, MetricsName: "http_requests",
+				MetricTargetType: model.XPATargetTypeValue, MetricTargetValue: 10},
+			wantErr: true,
+		},
+		{
+			name: "object metric",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypeObject, MetricsName: "requests-per-second",
+				MetricTargetType: model.XPATargetTypeValue, MetricTargetValue: 100, ObjectAPIVersion: "networking.k8s.io/v1", ObjectKind: "Ingress", ObjectName: "main"},
+			want: autoscalingv2.ObjectMetricSourceType,
+		},
+		{
+			name: "external metric",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypeExternal, MetricsName: "queue_messages_ready",
+				MetricTargetType: model.XPATargetTypeAverageValue, MetricTargetValue: 30, MetricSelector: "queue=worker_tasks"},
+			want: autoscalingv2.ExternalMetricSourceType,
+		},
+		{
+			name: "prometheus metric",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypePrometheus, MetricsName: "qps",
+				MetricTargetType: model.XPATargetTypeValue, MetricTargetValue: 100, PromQL: `sum(rate(gateway_requests{service_id="{{service_id}}"}[1m]))`},
+			want: autoscalingv2.ExternalMetricSourceType,
+		},
+		{
+			name: "invalid selector",
+			metric: &model.TenantEnvServiceAutoscalerRuleMetrics{MetricsType: model.XPAMetricTypeExternal, MetricsName: "queue_messages_ready",
+				MetricTargetType: model.XPATargetTypeValue, MetricTargetValue: 30, MetricSelector: "queue in (a"},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ms, err := createMetricSpec("rule", tc.metric)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("createMetricSpec() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && ms.Type != tc.want {
+				t.Errorf("createMetricSpec() type = %s, want %s", ms.Type, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewHPAWithPrometheusMetrics(t *testing.T) {
+	rule := &model.TenantEnvServiceAutoscalerRules{
+		RuleID:      "rule",
+		MinReplicas: 1,
+		MaxReplicas: 5,
+		Behavior:    `{"scale_down":{"stabilization_window_seconds":600,"policies":[{"type":"Pods","value":1,"period_seconds":60}]}}`,
+	}
+	metrics := []*model.TenantEnvServiceAutoscalerRuleMetrics{
+		{MetricsType: model.XPAMetricTypeResource, MetricsName: "memory", MetricTargetType: model.XPATargetTypeAverageValue, MetricTargetValue: 512},
+		{MetricsType: model.XPAMetricTypePrometheus, MetricsName: "qps", MetricTargetType: model.XPATargetTypeValue, MetricTargetValue: 100,
+			PromQL: `sum(rate(gateway_requests{namespace="other",service_id="{{service_id}}"}[1m]))`},
+	}
+	hpa, err := newHPA("ns", "Deployment", "deploy", nil, rule, metrics)
+	if err != nil {
+		t.Fatalf("new hpa: %v", err)
+	}
+	if len(hpa.Spec.Metrics) != 2 {
+		t.Fatalf("want 2 metrics, got %d", len(hpa.Spec.Metrics))
+	}
+	if v := hpa.Spec.Metrics[0].Resource.Target.AverageValue.String(); v != "512Mi" {
+		t.Errorf("want memory target 512Mi, got %s", v)
+	}
+	external := hpa.Spec.Metrics[1].External
+	if external.Metric.Name != XPAPrometheusMetricName || external.Metric.Selector.MatchLabels["metric_name"] != "qps" {
+		t.Errorf("unexpected external metric %+v", external.Metric)
+	}
+	if hpa.Spec.Behavior == nil || hpa.Spec.Behavior.ScaleUp != nil || *hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds != 600 {
+		t.Errorf("unexpected behavior %+v", hpa.Spec.Behavior)
+	}
+
+	promRule, err := newPrometheusRule("ns", "sid", nil, rule, metrics)
+	if err != nil {
+		t.Fatalf("new prometheus rule: %v", err)
+	}
+	if promRule == nil || len(promRule.Spec.Groups[0].Rules) != 1 {
+		t.Fatalf("want 1 recording rule, got %+v", promRule)
+	}
+	recording := promRule.Spec.Groups[0].Rules[0]
+	if recording.Expr.String() != `sum(rate(gateway_requests{namespace="ns",service_id="sid"}[1m]))` || recording.Labels["rule_id"] != "rule" {
+		t.Errorf("unexpected recording rule %+v", recording)
+	}
+	metrics[1].PromQL = `sum(rate(gateway_requests{service_id="{{service_id}}"}[1m])`
+	if _, err := newPrometheusRule("ns", "sid", nil, rule, metrics); err == nil {
+		t.Errorf("want error of the invalid promql")
+	}
+
+	rule.Behavior = `{"scale_up":{"policies":[{"type":"Replicas","value":1,"period_seconds":60}]}}`
+	if _, err := newHPA("ns", "Deployment", "deploy", nil, rule, metrics); err == nil {
+		t.Errorf("want error of the invalid policy type")
+	}
+}
@@ -64,6 +64,16 @@ func RegistConversion(name string, fun Conversion) {
 
 // InitAppService init a app service
 func InitAppService(dbmanager db.Manager, serviceID string, configs map[string]string, enableConversionList ...string) (*v1.AppService, error) {
+	return initAppService(dbmanager, serviceID, "", configs, enableConversionList...)
+}
+
+// InitAppServiceByVersion init the app service of the deploy version instead of the
+// deploy version of the component, it is used by the release running side by side.
+func InitAppServiceByVersion(dbmanager db.Manager, serviceID, deployVersion string, configs map[string]string) (*v1.AppService, error) {
+	return initAppService(dbmanager, serviceID, deployVersion, configs)
+}
+
+func initAppService(dbmanager db.Manager, serviceID, deployVersion string, configs map[string]string, enableConversionList ...string) (*v1.AppService, error) {
 	if configs == nil {
 		configs = make(map[string]string)
 	}
@@ -71,6 +81,7 @@ func InitAppService(dbmanager db.Manager, serviceID string, configs map[string]s
 	appService := &v1.AppService{
 		AppServiceBase: v1.AppServiceBase{
 			ServiceID:      serviceID,
+			DeployVersion:  deployVersion,
 			ExtensionSet:   configs,
 			GovernanceMode: model.GovernanceModeBuildInServiceMesh,
 		},
@@ -21,12 +21,15 @@ package conversion
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/db"
 	"github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/gateway/annotations/auth"
 	"github.com/wutong-paas/wutong/gateway/annotations/parser"
 	"github.com/wutong-paas/wutong/util/k8s"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
@@ -205,7 +208,7 @@ func (a AppServiceBuild) ApplyRules(serviceID string, containerPort, pluginConta
 	logrus.Debugf("find %d count http rule", len(httpRules))
 	if len(httpRules) > 0 {
 		for _, httpRule := range httpRules {
-			ingress, sec, err := a.applyHTTPRule(httpRule, containerPort, pluginContainerPort, service)
+			ingress, secs, err := a.applyHTTPRule(httpRule, containerPort, pluginContainerPort, service)
 			if err != nil {
 				logrus.Errorf("Unexpected error occurred while applying http rule: %v", err)
 				// skip the failed rule
@@ -213,9 +216,7 @@ func (a AppServiceBuild) ApplyRules(serviceID string, containerPort, pluginConta
 			}
 			//logrus.Debugf("create ingress %s", ingress.Name)
 			ingresses = append(ingresses, ingress)
-			if sec != nil {
-				secrets = append(secrets, sec)
-			}
+			secrets = append(secrets, secs...)
 
 		}
 	}
@@ -242,7 +243,7 @@ func (a AppServiceBuild) ApplyRules(serviceID string, containerPort, pluginConta
 
 // applyHTTPRule applies stream rule into ingress
 func (a *AppServiceBuild) applyHTTPRule(rule *model.HTTPRule, containerPort, pluginContainerPort int,
-	service *corev1.Service) (ingress interface{}, sec *corev1.Secret, err error) {
+	service *corev1.Service) (ingress interface{}, secrets []*corev1.Secret, err error) {
 	//deal with empty path and domain
 	path := strings.Replace(rule.Path, " ", "", -1)
 	if path == "" {
@@ -259,10 +260,21 @@ func (a *AppServiceBuild) applyHTTPRule(rule *model.HTTPRule, containerPort, plu
 	serviceName := service.Name
 
 	// certificate
-	sec, err = a.createSecret(rule, name, namespace, labels)
+	sec, err := a.createSecret(rule, name, namespace, labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sec != nil {
+		secrets = append(secrets, sec)
+	}
+	// basic authentication users
+	authSec, err := a.createBasicAuthSecret(rule, namespace, labels)
 	if err != nil {
 		return nil, nil, err
 	}
+	if authSec != nil {
+		secrets = append(secrets, authSec)
+	}
 
 	// parse annotations
 	annotations, err := a.parseAnnotations(rule)
@@ -281,7 +293,7 @@ func (a *AppServiceBuild) applyHTTPRule(rule *model.HTTPRule, containerPort, plu
 			}
 		}
 		ntwIngress.SetAnnotations(annotations)
-		return ntwIngress, sec, nil
+		return ntwIngress, secrets, nil
 	}
 
 	beatIngress := createBetaIngress(domain, path, name, namespace, serviceName, labels, pluginContainerPort)
@@ -294,7 +306,7 @@ func (a *AppServiceBuild) applyHTTPRule(rule *model.HTTPRule, containerPort, plu
 		}
 	}
 	beatIngress.SetAnnotations(annotations)
-	return beatIngress, sec, nil
+	return beatIngress, secrets, nil
 
 }
 
@@ -591,18 +603,69 @@ func (a *AppServiceBuild) parseAnnotations(rule *model.HTTPRule) (map[string]str
 		}
 	}
 
+	// the certificate is issued by the ACME controller, the gateway serves the http-01 challenges
+	if rule.AutoTLS {
+		annos[parser.GetAnnotationWithPrefix("auto-tls")] = "true"
+	}
+
+	// wake the component scaled to zero by the request
+	scaleToZero, err := a.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(rule.ServiceID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == nil && scaleToZero.IdleEnable {
+		annos[parser.GetAnnotationWithPrefix("wake-on-request")] = "true"
+		if scaleToZero.HoldTimeout > 0 {
+			annos[parser.GetAnnotationWithPrefix("wake-hold-timeout")] = strconv.Itoa(scaleToZero.HoldTimeout)
+		}
+	}
+
 	configs, err := db.GetManager().GwRuleConfigDao().ListByRuleID(rule.UUID)
 	if err != nil {
 		return nil, err
 	}
 	if len(configs) > 0 {
 		for _, cfg := range configs {
+			if strings.HasPrefix(cfg.Key, model.BasicAuthUserConfigPrefix) {
+				// the users are saved in the secret
+				annos[parser.GetAnnotationWithPrefix("auth-secret")] = basicAuthSecretName(rule.UUID)
+				continue
+			}
 			annos[parser.GetAnnotationWithPrefix(cfg.Key)] = cfg.Value
 		}
 	}
 	return annos, nil
 }
 
+func basicAuthSecretName(ruleID string) string {
+	return ruleID + "-basic-auth"
+}
+
+// createBasicAuthSecret creates the secret contains the htpasswd file of the basic authentication users
+func (a *AppServiceBuild) createBasicAuthSecret(rule *model.HTTPRule, namespace string, labels map[string]string) (*corev1.Secret, error) {
+	configs, err := a.dbmanager.GwRuleConfigDao().ListByRuleID(rule.UUID)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, cfg := range configs {
+		if strings.HasPrefix(cfg.Key, model.BasicAuthUserConfigPrefix) {
+			users = append(users, strings.TrimPrefix(cfg.Key, model.BasicAuthUserConfigPrefix)+":"+cfg.Value)
+		}
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	sort.Strings(users)
+	return &corev1.Secret{
+		ObjectMeta: createIngressMeta(basicAuthSecretName(rule.UUID), namespace, labels),
+		Data: map[string][]byte{
+			auth.SecretKey: []byte(strings.Join(users, "\n") + "\n"),
+		},
+		Type: corev1.SecretTypeOpaque,
+	}, nil
+}
+
 func (a *AppServiceBuild) createSecret(rule *model.HTTPRule, name, namespace string, labels map[string]string) (*corev1.Secret, error) {
 	if rule.CertificateID == "" {
 		return nil, nil
@@ -28,8 +28,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/wutong-paas/wutong/gateway/annotations/parser"
 	"github.com/wutong-paas/wutong/util/apply"
+	k8sutil "github.com/wutong-paas/wutong/util/k8s"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
-	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	betav1 "k8s.io/api/networking/v1beta1"
@@ -79,6 +80,7 @@ func ApplyOne(ctx context.Context, apply apply.Applicator, clientset kubernetes.
 							}
 						}
 					}
+					ensureAuthSecret(app, ing.Annotations, clientset)
 					ensureIngress(ing, clientset)
 				}
 			}
@@ -91,6 +93,7 @@ func ApplyOne(ctx context.Context, apply apply.Applicator, clientset kubernetes.
 							}
 						}
 					}
+					ensureAuthSecret(app, ing.Annotations, clientset)
 					ensureBetaIngress(ing, clientset)
 				}
 			}
@@ -251,6 +254,19 @@ func ensureBetaIngress(ing *betav1.Ingress, clientSet kubernetes.Interface) {
 	}
 }
 
+// ensureAuthSecret ensures the basic authentication secret referenced by the ingress annotations
+func ensureAuthSecret(app *v1.AppService, annotations map[string]string, clientSet kubernetes.Interface) {
+	name := annotations[parser.GetAnnotationWithPrefix("auth-secret")]
+	if name == "" {
+		return
+	}
+	for _, secret := range app.GetSecrets(true) {
+		if secret.Name == name {
+			ensureSecret(secret, clientSet)
+		}
+	}
+}
+
 func ensureSecret(secret *corev1.Secret, clientSet kubernetes.Interface) {
 	_, err := clientSet.CoreV1().Secrets(secret.Namespace).Update(context.Background(), secret, metav1.UpdateOptions{})
 
@@ -325,11 +341,15 @@ func EnsureService(new *corev1.Service, clientSet kubernetes.Interface) error {
 }
 
 // EnsureHPA -
-func EnsureHPA(new *autoscalingv1.HorizontalPodAutoscaler, clientSet kubernetes.Interface) {
-	_, err := clientSet.AutoscalingV1().HorizontalPodAutoscalers(new.Namespace).Get(context.Background(), new.Name, metav1.GetOptions{})
+func EnsureHPA(new *autoscalingv2.HorizontalPodAutoscaler, clientSet kubernetes.Interface) {
+	if !k8sutil.IsAutoscalingV2() {
+		ensureBetaHPA(new, clientSet)
+		return
+	}
+	old, err := clientSet.AutoscalingV2().HorizontalPodAutoscalers(new.Namespace).Get(context.Background(), new.Name, metav1.GetOptions{})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			_, err = clientSet.AutoscalingV1().HorizontalPodAutoscalers(new.Namespace).Create(context.Background(), new, metav1.CreateOptions{})
+			_, err = clientSet.AutoscalingV2().HorizontalPodAutoscalers(new.Namespace).Create(context.Background(), new, metav1.CreateOptions{})
 			if err != nil {
 				logrus.Warningf("error creating hpa %s: %v", new.Namespace+"/"+new.Name, err)
 			}
@@ -338,13 +358,83 @@ func EnsureHPA(new *autoscalingv1.HorizontalPodAutoscaler, clientSet kubernetes.
 		logrus.Errorf("error getting hpa(%s): %v", fmt.Sprintf("%s/%s", new.Namespace, new.Name), err)
 		return
 	}
-	_, err = clientSet.AutoscalingV1().HorizontalPodAutoscalers(new.Namespace).Update(context.Background(), new, metav1.UpdateOptions{})
+	new.ResourceVersion = old.ResourceVersion
+	_, err = clientSet.AutoscalingV2().HorizontalPodAutoscalers(new.Namespace).Update(context.Background(), new, metav1.UpdateOptions{})
 	if err != nil {
 		logrus.Warningf("error updating hpa %s: %v", new.Namespace+"/"+new.Name, err)
 		return
 	}
 }
 
+// ensureBetaHPA ensures the hpa by autoscaling/v2beta2 on the clusters without autoscaling/v2
+func ensureBetaHPA(new *autoscalingv2.HorizontalPodAutoscaler, clientSet kubernetes.Interface) {
+	beta, err := k8sutil.HPAToV2beta2(new)
+	if err != nil {
+		logrus.Errorf("error converting hpa(%s): %v", fmt.Sprintf("%s/%s", new.Namespace, new.Name), err)
+		return
+	}
+	old, err := clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(beta.Namespace).Get(context.Background(), beta.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			_, err = clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(beta.Namespace).Create(context.Background(), beta, metav1.CreateOptions{})
+			if err != nil {
+				logrus.Warningf("error creating hpa %s: %v", beta.Namespace+"/"+beta.Name, err)
+			}
+			return
+		}
+		logrus.Errorf("error getting hpa(%s): %v", fmt.Sprintf("%s/%s", beta.Namespace, beta.Name), err)
+		return
+	}
+	beta.ResourceVersion = old.ResourceVersion
+	_, err = clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(beta.Namespace).Update(context.Background(), beta, metav1.UpdateOptions{})
+	if err != nil {
+		logrus.Warningf("error updating hpa %s: %v", beta.Namespace+"/"+beta.Name, err)
+	}
+}
+
+// CreateHPA creates the hpa by autoscaling/v2, or autoscaling/v2beta2 on the clusters without it
+func CreateHPA(ctx context.Context, hpa *autoscalingv2.HorizontalPodAutoscaler, clientSet kubernetes.Interface) error {
+	if k8sutil.IsAutoscalingV2() {
+		_, err := clientSet.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Create(ctx, hpa, metav1.CreateOptions{})
+		return err
+	}
+	beta, err := k8sutil.HPAToV2beta2(hpa)
+	if err != nil {
+		return err
+	}
+	_, err = clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(beta.Namespace).Create(ctx, beta, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteHPA deletes the hpa by autoscaling/v2, or autoscaling/v2beta2 on the clusters without it
+func DeleteHPA(ctx context.Context, namespace, name string, clientSet kubernetes.Interface) error {
+	if k8sutil.IsAutoscalingV2() {
+		return clientSet.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	}
+	return clientSet.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// EnsurePrometheusRule -
+func EnsurePrometheusRule(new *monitorv1.PrometheusRule, clientset *versioned.Clientset) {
+	old, err := clientset.MonitoringV1().PrometheusRules(new.Namespace).Get(context.Background(), new.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			_, err = clientset.MonitoringV1().PrometheusRules(new.Namespace).Create(context.Background(), new, metav1.CreateOptions{})
+			if err != nil {
+				logrus.Warningf("error creating prometheus rule %s: %v", new.Namespace+"/"+new.Name, err)
+			}
+			return
+		}
+		logrus.Errorf("error getting prometheus rule(%s): %v", fmt.Sprintf("%s/%s", new.Namespace, new.Name), err)
+		return
+	}
+	new.ResourceVersion = old.ResourceVersion
+	_, err = clientset.MonitoringV1().PrometheusRules(new.Namespace).Update(context.Background(), new, metav1.UpdateOptions{})
+	if err != nil {
+		logrus.Warningf("error updating prometheus rule %s: %v", new.Namespace+"/"+new.Name, err)
+	}
+}
+
 // UpgradeIngress is used to update *networkingv1.Ingress.
 func UpgradeIngress(clientset kubernetes.Interface,
 	as *v1.AppService,
@@ -12,6 +12,9 @@ import (
 //ServiceMonitor service monitor custom resource
 const ServiceMonitor = "servicemonitors.monitoring.coreos.com"
 
+//PrometheusRule prometheus rule custom resource
+const PrometheusRule = "prometheusrules.monitoring.coreos.com"
+
 func (a *appRuntimeStore) GetCrds() (ret []*apiextensions.CustomResourceDefinition, err error) {
 	return a.listers.CRD.List(nil)
 }
@@ -22,7 +22,8 @@ import (
 	"github.com/wutong-paas/wutong/pkg/generated/listers/wutong/v1alpha1"
 	crdlisters "k8s.io/apiextensions-apiserver/pkg/client/listers/apiextensions/v1"
 	appsv1 "k8s.io/client-go/listers/apps/v1"
-	autoscalingv1 "k8s.io/client-go/listers/autoscaling/v1"
+	autoscalingv2 "k8s.io/client-go/listers/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/client-go/listers/autoscaling/v2beta2"
 	corev1 "k8s.io/client-go/listers/core/v1"
 	networkingv1 "k8s.io/client-go/listers/networking/v1"
 	betav1 "k8s.io/client-go/listers/networking/v1beta1"
@@ -44,9 +45,11 @@ type Lister struct {
 	Nodes                   corev1.NodeLister
 	StorageClass            storagev1.StorageClassLister
 	Claims                  corev1.PersistentVolumeClaimLister
-	HorizontalPodAutoscaler autoscalingv1.HorizontalPodAutoscalerLister
-	CRD                     crdlisters.CustomResourceDefinitionLister
-	HelmApp                 v1alpha1.HelmAppLister
-	ComponentDefinition     v1alpha1.ComponentDefinitionLister
-	ThirdComponent          v1alpha1.ThirdComponentLister
+	HorizontalPodAutoscaler autoscalingv2.HorizontalPodAutoscalerLister
+	// BetaHorizontalPodAutoscaler lists the HPAs on the clusters without autoscaling/v2
+	BetaHorizontalPodAutoscaler autoscalingv2beta2.HorizontalPodAutoscalerLister
+	CRD                         crdlisters.CustomResourceDefinitionLister
+	HelmApp                     v1alpha1.HelmAppLister
+	ComponentDefinition         v1alpha1.ComponentDefinitionLister
+	ThirdComponent              v1alpha1.ThirdComponentLister
 }
@@ -22,6 +22,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 	"sync"
 	"time"
 
@@ -49,7 +51,8 @@ import (
 	"github.com/wutong-paas/wutong/worker/server/pb"
 	workerutil "github.com/wutong-paas/wutong/worker/util"
 	appsv1 "k8s.io/api/apps/v1"
-	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -237,8 +240,14 @@ func NewStore(
 
 	store.informers.Events = infFactory.Core().V1().Events().Informer()
 
-	store.informers.HorizontalPodAutoscaler = infFactory.Autoscaling().V1().HorizontalPodAutoscalers().Informer()
-	store.listers.HorizontalPodAutoscaler = infFactory.Autoscaling().V1().HorizontalPodAutoscalers().Lister()
+	// autoscaling/v2 is not served before k8s 1.23
+	if k8sutil.IsAutoscalingV2() {
+		store.informers.HorizontalPodAutoscaler = infFactory.Autoscaling().V2().HorizontalPodAutoscalers().Informer()
+		store.listers.HorizontalPodAutoscaler = infFactory.Autoscaling().V2().HorizontalPodAutoscalers().Lister()
+	} else {
+		store.informers.HorizontalPodAutoscaler = infFactory.Autoscaling().V2beta2().HorizontalPodAutoscalers().Informer()
+		store.listers.BetaHorizontalPodAutoscaler = infFactory.Autoscaling().V2beta2().HorizontalPodAutoscalers().Lister()
+	}
 
 	// wutong custom resource
 	wutongInformer := externalversions.NewSharedInformerFactoryWithOptions(wutongClient, 10*time.Second,
@@ -517,7 +526,14 @@ func (a *appRuntimeStore) OnAdd(obj interface{}, isInInitialList bool) {
 			}
 		}
 	}
-	if hpa, ok := obj.(*autoscalingv1.HorizontalPodAutoscaler); ok {
+	if beta, ok := obj.(*autoscalingv2beta2.HorizontalPodAutoscaler); ok {
+		if hpa, err := k8sutil.HPAFromV2beta2(beta); err == nil {
+			obj = hpa
+		} else {
+			logrus.Warningf("convert hpa %s/%s: %v", beta.Namespace, beta.Name, err)
+		}
+	}
+	if hpa, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler); ok {
 		serviceID := hpa.Labels["service_id"]
 		version := hpa.Labels["version"]
 		createrID := hpa.Labels["creater_id"]
@@ -747,7 +763,14 @@ func (a *appRuntimeStore) OnDeletes(objs ...interface{}) {
 				}
 			}
 		}
-		if hpa, ok := obj.(*autoscalingv1.HorizontalPodAutoscaler); ok {
+		if beta, ok := obj.(*autoscalingv2beta2.HorizontalPodAutoscaler); ok {
+			if hpa, err := k8sutil.HPAFromV2beta2(beta); err == nil {
+				obj = hpa
+			} else {
+				logrus.Warningf("convert hpa %s/%s: %v", beta.Namespace, beta.Name, err)
+			}
+		}
+		if hpa, ok := obj.(*autoscalingv2.HorizontalPodAutoscaler); ok {
 			serviceID := hpa.Labels["service_id"]
 			version := hpa.Labels["version"]
 			createrID := hpa.Labels["creater_id"]
@@ -1315,6 +1338,7 @@ func (a *appRuntimeStore) evtEventHandler() cache.ResourceEventHandlerFuncs {
 				Description: evt.Message,
 				Operator:    "system",
 				LastTime:    evt.LastTimestamp.Time,
+				MetricName:  scalingMetricName(evt),
 			}
 			logrus.Debugf("received add record: %#v", record)
 
@@ -1347,6 +1371,7 @@ func (a *appRuntimeStore) evtEventHandler() cache.ResourceEventHandlerFuncs {
 				Reason:      cevt.Reason,
 				LastTime:    cevt.LastTimestamp.Time,
 				Description: cevt.Message,
+				MetricName:  scalingMetricName(cevt),
 			}
 			logrus.Debugf("received update record: %#v", record)
 
@@ -1453,7 +1478,13 @@ func (a *appRuntimeStore) scalingRecordServiceAndRuleID(evt *corev1.Event) (stri
 		serviceID = statefulset.GetLabels()["service_id"]
 		ruleID = statefulset.GetLabels()["rule_id"]
 	case "HorizontalPodAutoscaler":
-		hpa, err := a.listers.HorizontalPodAutoscaler.HorizontalPodAutoscalers(evt.InvolvedObject.Namespace).Get(evt.InvolvedObject.Name)
+		var hpa metav1.Object
+		var err error
+		if a.listers.HorizontalPodAutoscaler != nil {
+			hpa, err = a.listers.HorizontalPodAutoscaler.HorizontalPodAutoscalers(evt.InvolvedObject.Namespace).Get(evt.InvolvedObject.Name)
+		} else {
+			hpa, err = a.listers.BetaHorizontalPodAutoscaler.HorizontalPodAutoscalers(evt.InvolvedObject.Namespace).Get(evt.InvolvedObject.Name)
+		}
 		if err != nil {
 			logrus.Warningf("retrieve statefulset: %v", err)
 			return "", ""
@@ -1467,6 +1498,47 @@ func (a *appRuntimeStore) scalingRecordServiceAndRuleID(evt *corev1.Event) (stri
 	return serviceID, ruleID
 }
 
+var (
+	// the message of the rescale event, eg: New size: 3; reason: cpu resource utilization (percentage of request) above target
+	rescaleReasonRegexp = regexp.MustCompile(`reason: (.+) (above|below) target`)
+	xpaMetricNameRegexp = regexp.MustCompile(`metric_name: ?([^,}\s]+)`)
+)
+
+// scalingMetricName returns the name of the metric triggered the scaling of the HPA
+func scalingMetricName(evt *corev1.Event) string {
+	if evt.InvolvedObject.Kind != "HorizontalPodAutoscaler" {
+		return ""
+	}
+	match := rescaleReasonRegexp.FindStringSubmatch(evt.Message)
+	if match == nil || match[1] == "All metrics" {
+		return ""
+	}
+	reason := match[1]
+	switch {
+	case strings.HasPrefix(reason, "external metric "+conversion.XPAPrometheusMetricName):
+		if name := xpaMetricNameRegexp.FindStringSubmatch(reason); name != nil {
+			return name[1]
+		}
+		return conversion.XPAPrometheusMetricName
+	case strings.HasPrefix(reason, "external metric "):
+		name := strings.TrimPrefix(reason, "external metric ")
+		if i := strings.Index(name, "("); i > 0 {
+			name = name[:i]
+		}
+		return name
+	case strings.HasPrefix(reason, "pods metric "):
+		return strings.TrimPrefix(reason, "pods metric ")
+	case strings.Contains(reason, " resource"):
+		// cpu resource utilization (percentage of request), memory resource
+		return reason[:strings.Index(reason, " ")]
+	}
+	// object metric: Service metric requests-per-second
+	if i := strings.Index(reason, " metric "); i > 0 {
+		return reason[i+len(" metric "):]
+	}
+	return reason
+}
+
 func (a *appRuntimeStore) RegistPodUpdateListener(name string, ch chan<- *corev1.Pod) {
 	a.podUpdateListenerLock.Lock()
 	defer a.podUpdateListenerLock.Unlock()
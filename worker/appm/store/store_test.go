@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
 	"github.com/wutong-paas/wutong/worker/server/pb"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestGetAppStatus(t *testing.T) {
@@ -108,3 +109,23 @@ func TestGetAppStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestScalingMetricName(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{message: "New size: 3; reason: cpu resource utilization (percentage of request) above target", want: "cpu"},
+		{message: "New size: 2; reason: memory resource above target", want: "memory"},
+		{message: "New size: 4; reason: pods metric http_requests above target", want: "http_requests"},
+		{message: "New size: 4; reason: Service metric requests-per-second above target", want: "requests-per-second"},
+		{message: "New size: 5; reason: external metric queue_messages_ready(&LabelSelector{MatchLabels:map[string]string{queue: worker_tasks,},MatchExpressions:[]LabelSelectorRequirement{},}) above target", want: "queue_messages_ready"},
+		{message: "New size: 5; reason: external metric wutong_xpa_metric(&LabelSelector{MatchLabels:map[string]string{metric_name: qps,rule_id: abc,},MatchExpressions:[]LabelSelectorRequirement{},}) above target", want: "qps"},
+		{message: "New size: 1; reason: All metrics below target", want: ""},
+		{message: "failed to get cpu utilization", want: ""},
+	}
+	for _, tc := range tests {
+		evt := &corev1.Event{InvolvedObject: corev1.ObjectReference{Kind: "HorizontalPodAutoscaler"}, Message: tc.message}
+		assert.Equal(t, tc.want, scalingMetricName(evt), tc.message)
+	}
+}
@@ -0,0 +1,178 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/wutong-paas/wutong/db/model"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+)
+
+// consulWaitTime the max time of a consul blocking query
+var consulWaitTime = 5 * time.Minute
+
+type consul struct {
+	client *http.Client
+
+	sid        string
+	servers    []string
+	service    string
+	datacenter string
+	tag        string
+	token      string
+
+	updateCh *channels.RingChannel
+	stopCh   chan struct{}
+}
+
+// consulServiceEntry the entry of the consul health service api
+type consulServiceEntry struct {
+	Node struct {
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string `json:"ID"`
+		Address string `json:"Address"`
+		Port    int    `json:"Port"`
+	} `json:"Service"`
+	Checks []struct {
+		Status string `json:"Status"`
+	} `json:"Checks"`
+}
+
+// NewConsul creates a new Discorvery which watches the service in the consul catalog.
+// The key of the config is the tag of the service, the namespace is the datacenter,
+// and the password is the acl token.
+func NewConsul(cfg *model.ThirdPartySvcDiscoveryCfg,
+	updateCh *channels.RingChannel,
+	stopCh chan struct{}) Discoverier {
+	return &consul{
+		sid:        cfg.ServiceID,
+		servers:    registryServers(cfg.Servers),
+		service:    cfg.ServiceName,
+		datacenter: cfg.Namespace,
+		tag:        cfg.Key,
+		token:      cfg.Password,
+		updateCh:   updateCh,
+		stopCh:     stopCh,
+	}
+}
+
+// Connect creates the http client of consul.
+func (c *consul) Connect() error {
+	if len(c.servers) == 0 || c.service == "" {
+		return fmt.Errorf("the servers and the service name of consul are required")
+	}
+	c.client = &http.Client{Timeout: consulWaitTime + 30*time.Second}
+	return nil
+}
+
+// Close does nothing, the requests of consul are stateless.
+func (c *consul) Close() error {
+	return nil
+}
+
+// Fetch fetches the instances of the service from consul.
+func (c *consul) Fetch() ([]*v1.WtEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	endpoints, _, err := c.fetch(ctx, 0)
+	return endpoints, err
+}
+
+// Watch watches the instances of the service with the consul blocking query.
+func (c *consul) Watch() {
+	// the index of the blocking query, it is only used by the watch loop
+	var index uint64
+	watchRegistry("consul service "+c.service, func(ctx context.Context) ([]*v1.WtEndpoint, error) {
+		endpoints, next, err := c.fetch(ctx, index)
+		if err != nil {
+			return nil, err
+		}
+		if next < index {
+			// the index goes backwards, reset the blocking query
+			next = 0
+		}
+		index = next
+		return endpoints, nil
+	}, time.Second, c.updateCh, c.stopCh)
+}
+
+// fetch fetches the instances of the service, the query blocks until the index of consul
+// is changed if the index is greater than 0. It returns the index of the instances.
+func (c *consul) fetch(ctx context.Context, index uint64) ([]*v1.WtEndpoint, uint64, error) {
+	if c.client == nil {
+		return nil, 0, fmt.Errorf("can't fetching data from consul without connecting")
+	}
+	query := url.Values{}
+	if c.datacenter != "" {
+		query.Set("dc", c.datacenter)
+	}
+	if c.tag != "" {
+		query.Set("tag", c.tag)
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", consulWaitTime.String())
+	}
+	var entries []consulServiceEntry
+	header, err := getJSON(ctx, c.client, c.servers, func(server string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, server+"/v1/health/service/"+url.PathEscape(c.service)+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if c.token != "" {
+			req.Header.Set("X-Consul-Token", c.token)
+		}
+		return req, nil
+	}, &entries)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error fetching endpoints from consul: %v", err)
+	}
+	index, _ = strconv.ParseUint(header.Get("X-Consul-Index"), 10, 64)
+
+	var res []*v1.WtEndpoint
+	for _, entry := range entries {
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		online := true
+		for _, check := range entry.Checks {
+			if check.Status == "critical" {
+				online = false
+			}
+		}
+		res = append(res, &v1.WtEndpoint{
+			UUID:     entry.Service.ID,
+			Sid:      c.sid,
+			IP:       address,
+			Port:     entry.Service.Port,
+			IsOnline: online,
+		})
+	}
+	return res, index, nil
+}
@@ -0,0 +1,164 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/wutong-paas/wutong/db/model"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+)
+
+type fakeConsul struct {
+	lock    sync.Mutex
+	index   int
+	entries []consulServiceEntry
+	changed chan struct{}
+	blocked chan struct{}
+}
+
+func newConsulEntry(id, address string, port int, status string) consulServiceEntry {
+	var entry consulServiceEntry
+	entry.Service.ID = id
+	entry.Service.Address = address
+	entry.Service.Port = port
+	entry.Checks = append(entry.Checks, struct {
+		Status string `json:"Status"`
+	}{Status: status})
+	return entry
+}
+
+func (f *fakeConsul) set(entries ...consulServiceEntry) {
+	// wait for the blocking query of the watcher
+	<-f.blocked
+	f.lock.Lock()
+	f.index++
+	f.entries = entries
+	f.lock.Unlock()
+	f.changed <- struct{}{}
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/health/service/user-service" || r.Header.Get("X-Consul-Token") != "token" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if r.URL.Query().Get("dc") != "dc1" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("index") != "" {
+		// blocking query
+		f.blocked <- struct{}{}
+		select {
+		case <-f.changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.Itoa(f.index))
+	json.NewEncoder(w).Encode(f.entries)
+}
+
+func TestConsul_Fetch(t *testing.T) {
+	fake := &fakeConsul{index: 1, changed: make(chan struct{}, 1)}
+	fake.entries = []consulServiceEntry{
+		newConsulEntry("user-1", "10.0.0.1", 8080, "passing"),
+		newConsulEntry("user-2", "10.0.0.2", 8080, "critical"),
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	c := NewConsul(&model.ThirdPartySvcDiscoveryCfg{
+		ServiceID:   "sid",
+		Servers:     "http://127.0.0.1:1," + server.URL,
+		ServiceName: "user-service",
+		Namespace:   "dc1",
+		Password:    "token",
+	}, channels.NewRingChannel(1024), make(chan struct{}))
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	eps, err := c.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eps) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(eps))
+	}
+	if eps[0].UUID != "user-1" || eps[0].IP != "10.0.0.1" || eps[0].Port != 8080 || !eps[0].IsOnline || eps[0].Sid != "sid" {
+		t.Errorf("unexpected endpoint %+v", eps[0])
+	}
+	if eps[1].IsOnline {
+		t.Errorf("expected endpoint %s offline", eps[1].UUID)
+	}
+}
+
+func TestConsul_Watch(t *testing.T) {
+	fake := &fakeConsul{index: 1, changed: make(chan struct{}, 1), blocked: make(chan struct{}, 1)}
+	fake.entries = []consulServiceEntry{newConsulEntry("user-1", "10.0.0.1", 8080, "passing")}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	updateCh := channels.NewRingChannel(1024)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	c := NewConsul(&model.ThirdPartySvcDiscoveryCfg{
+		Servers:     server.URL,
+		ServiceName: "user-service",
+		Namespace:   "dc1",
+		Password:    "token",
+	}, updateCh, stopCh)
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	go c.Watch()
+
+	fake.set(newConsulEntry("user-1", "10.0.0.1", 8080, "passing"), newConsulEntry("user-2", "10.0.0.2", 8080, "passing"))
+	expectEvent(t, updateCh, CreateEvent, "user-2")
+	// the fetch of the discover runs along with the watch, it does not block on the index of the watch
+	if eps, err := c.Fetch(); err != nil || len(eps) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d, %v", len(eps), err)
+	}
+	fake.set(newConsulEntry("user-2", "10.0.0.2", 8080, "critical"))
+	expectEvent(t, updateCh, UnhealthyEvent, "user-2")
+	expectEvent(t, updateCh, DeleteEvent, "user-1")
+}
+
+func expectEvent(t *testing.T, updateCh *channels.RingChannel, typ EventType, uuid string) {
+	t.Helper()
+	select {
+	case obj := <-updateCh.Out():
+		event := obj.(Event)
+		ep := event.Obj.(*v1.WtEndpoint)
+		if event.Type != typ || ep.UUID != uuid {
+			t.Fatalf("expected %s event of %s, got %s event of %s", typ, uuid, event.Type, ep.UUID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for %s event of %s", typ, uuid)
+	}
+}
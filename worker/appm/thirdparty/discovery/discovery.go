@@ -65,6 +65,12 @@ func NewDiscoverier(cfg *model.ThirdPartySvcDiscoveryCfg,
 	switch strings.ToLower(cfg.Type) {
 	case strings.ToLower(string(model.DiscorveryTypeEtcd)):
 		return NewEtcd(cfg, updateCh, stopCh), nil
+	case strings.ToLower(string(model.DiscorveryTypeConsul)):
+		return NewConsul(cfg, updateCh, stopCh), nil
+	case strings.ToLower(string(model.DiscorveryTypeNacos)):
+		return NewNacos(cfg, updateCh, stopCh), nil
+	case strings.ToLower(string(model.DiscorveryTypeEureka)):
+		return NewEureka(cfg, updateCh, stopCh), nil
 	default:
 		return nil, fmt.Errorf("Unsupported discovery type: %s", cfg.Type)
 	}
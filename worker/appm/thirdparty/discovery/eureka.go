@@ -0,0 +1,150 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/wutong-paas/wutong/db/model"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+)
+
+type eureka struct {
+	client *http.Client
+
+	sid      string
+	servers  []string
+	app      string
+	username string
+	password string
+
+	updateCh *channels.RingChannel
+	stopCh   chan struct{}
+}
+
+// eurekaPort the port of the eureka instance
+type eurekaPort struct {
+	Port    int    `json:"$"`
+	Enabled string `json:"@enabled"`
+}
+
+// eurekaInstance the instance of the eureka apps api
+type eurekaInstance struct {
+	InstanceID string     `json:"instanceId"`
+	HostName   string     `json:"hostName"`
+	IPAddr     string     `json:"ipAddr"`
+	Status     string     `json:"status"`
+	Port       eurekaPort `json:"port"`
+	SecurePort eurekaPort `json:"securePort"`
+}
+
+// NewEureka creates a new Discorvery which watches the application in eureka.
+// The servers are the service urls of eureka, such as http://127.0.0.1:8761/eureka.
+func NewEureka(cfg *model.ThirdPartySvcDiscoveryCfg,
+	updateCh *channels.RingChannel,
+	stopCh chan struct{}) Discoverier {
+	return &eureka{
+		sid:      cfg.ServiceID,
+		servers:  registryServers(cfg.Servers),
+		app:      strings.ToUpper(cfg.ServiceName),
+		username: cfg.Username,
+		password: cfg.Password,
+		updateCh: updateCh,
+		stopCh:   stopCh,
+	}
+}
+
+// Connect creates the http client of eureka.
+func (e *eureka) Connect() error {
+	if len(e.servers) == 0 || e.app == "" {
+		return fmt.Errorf("the servers and the application name of eureka are required")
+	}
+	e.client = &http.Client{Timeout: 10 * time.Second}
+	return nil
+}
+
+// Close does nothing, the requests of eureka are stateless.
+func (e *eureka) Close() error {
+	return nil
+}
+
+// Fetch fetches the instances of the application from eureka.
+func (e *eureka) Fetch() ([]*v1.WtEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return e.fetch(ctx)
+}
+
+// Watch polls the instances of the application from eureka.
+func (e *eureka) Watch() {
+	watchRegistry("eureka application "+e.app, e.fetch, registryPollInterval, e.updateCh, e.stopCh)
+}
+
+func (e *eureka) fetch(ctx context.Context) ([]*v1.WtEndpoint, error) {
+	if e.client == nil {
+		return nil, fmt.Errorf("can't fetching data from eureka without connecting")
+	}
+	var res struct {
+		Application struct {
+			Instance []eurekaInstance `json:"instance"`
+		} `json:"application"`
+	}
+	_, err := getJSON(ctx, e.client, e.servers, func(server string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, server+"/apps/"+url.PathEscape(e.app), nil)
+		if err != nil {
+			return nil, err
+		}
+		if e.username != "" {
+			req.SetBasicAuth(e.username, e.password)
+		}
+		return req, nil
+	}, &res)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching endpoints from eureka: %v", err)
+	}
+	var eps []*v1.WtEndpoint
+	for _, ins := range res.Application.Instance {
+		port := ins.Port.Port
+		if ins.Port.Enabled == "false" && ins.SecurePort.Enabled == "true" {
+			port = ins.SecurePort.Port
+		}
+		address := ins.IPAddr
+		if address == "" {
+			address = ins.HostName
+		}
+		uuid := ins.InstanceID
+		if uuid == "" {
+			uuid = fmt.Sprintf("%s:%d", address, port)
+		}
+		eps = append(eps, &v1.WtEndpoint{
+			UUID:     uuid,
+			Sid:      e.sid,
+			IP:       address,
+			Port:     port,
+			IsOnline: ins.Status == "UP",
+		})
+	}
+	return eps, nil
+}
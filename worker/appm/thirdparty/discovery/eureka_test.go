@@ -0,0 +1,156 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+type fakeEureka struct {
+	lock      sync.Mutex
+	instances []eurekaInstance
+}
+
+func newEurekaInstance(id, ip string, port int, status string) eurekaInstance {
+	return eurekaInstance{
+		InstanceID: id,
+		HostName:   "user-service",
+		IPAddr:     ip,
+		Status:     status,
+		Port:       eurekaPort{Port: port, Enabled: "true"},
+		SecurePort: eurekaPort{Port: 443, Enabled: "false"},
+	}
+}
+
+func (f *fakeEureka) set(instances ...eurekaInstance) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.instances = instances
+}
+
+func (f *fakeEureka) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if user, password, ok := r.BasicAuth(); !ok || user != "eureka" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Path != "/eureka/apps/USER-SERVICE" || r.Header.Get("Accept") != "application/json" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"application": map[string]interface{}{
+			"name":     "USER-SERVICE",
+			"instance": f.instances,
+		},
+	})
+}
+
+func newTestEureka(url string, updateCh *channels.RingChannel, stopCh chan struct{}) Discoverier {
+	return NewEureka(&model.ThirdPartySvcDiscoveryCfg{
+		ServiceID:   "sid",
+		Servers:     url + "/eureka/",
+		ServiceName: "user-service",
+		Username:    "eureka",
+		Password:    "secret",
+	}, updateCh, stopCh)
+}
+
+func TestEureka_Fetch(t *testing.T) {
+	fake := &fakeEureka{}
+	fake.set(newEurekaInstance("user-1", "10.0.0.1", 8080, "UP"), newEurekaInstance("user-2", "10.0.0.2", 8080, "DOWN"))
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	e := newTestEureka(server.URL, channels.NewRingChannel(1024), make(chan struct{}))
+	if err := e.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	eps, err := e.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eps) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(eps))
+	}
+	if eps[0].UUID != "user-1" || eps[0].IP != "10.0.0.1" || eps[0].Port != 8080 || !eps[0].IsOnline || eps[0].Sid != "sid" {
+		t.Errorf("unexpected endpoint %+v", eps[0])
+	}
+	if eps[1].IsOnline {
+		t.Errorf("expected endpoint %s offline", eps[1].UUID)
+	}
+}
+
+func TestEureka_FetchUnregistered(t *testing.T) {
+	server := httptest.NewServer(&fakeEureka{})
+	defer server.Close()
+
+	e := NewEureka(&model.ThirdPartySvcDiscoveryCfg{
+		Servers:     server.URL + "/eureka",
+		ServiceName: "order-service",
+		Username:    "eureka",
+		Password:    "secret",
+	}, channels.NewRingChannel(1024), make(chan struct{}))
+	if err := e.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	eps, err := e.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eps) != 0 {
+		t.Errorf("expected no endpoints, got %d", len(eps))
+	}
+}
+
+func TestEureka_Watch(t *testing.T) {
+	defer func(interval time.Duration) { registryPollInterval = interval }(registryPollInterval)
+	registryPollInterval = 10 * time.Millisecond
+
+	fake := &fakeEureka{}
+	fake.set(newEurekaInstance("user-1", "10.0.0.1", 8080, "UP"))
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	updateCh := channels.NewRingChannel(1024)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	e := newTestEureka(server.URL, updateCh, stopCh)
+	if err := e.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	go e.Watch()
+	time.Sleep(100 * time.Millisecond)
+
+	fake.set(newEurekaInstance("user-1", "10.0.0.1", 8080, "UP"), newEurekaInstance("user-2", "10.0.0.2", 8080, "STARTING"))
+	expectEvent(t, updateCh, CreateEvent, "user-2")
+	fake.set(newEurekaInstance("user-1", "10.0.0.1", 8080, "OUT_OF_SERVICE"), newEurekaInstance("user-2", "10.0.0.2", 8080, "STARTING"))
+	expectEvent(t, updateCh, UnhealthyEvent, "user-1")
+	fake.set(newEurekaInstance("user-2", "10.0.0.2", 8080, "UP"))
+	expectEvent(t, updateCh, HealthEvent, "user-2")
+	expectEvent(t, updateCh, DeleteEvent, "user-1")
+}
@@ -0,0 +1,187 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/wutong-paas/wutong/db/model"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+)
+
+type nacos struct {
+	client      *http.Client
+	accessToken string
+
+	sid       string
+	servers   []string
+	service   string
+	namespace string
+	group     string
+	username  string
+	password  string
+
+	updateCh *channels.RingChannel
+	stopCh   chan struct{}
+}
+
+// nacosInstance the instance of the nacos naming api
+type nacosInstance struct {
+	InstanceID string `json:"instanceId"`
+	IP         string `json:"ip"`
+	Port       int    `json:"port"`
+	Healthy    bool   `json:"healthy"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// NewNacos creates a new Discorvery which watches the service in the nacos naming service.
+// The key of the config is the group of the service, the namespace is the namespace id.
+func NewNacos(cfg *model.ThirdPartySvcDiscoveryCfg,
+	updateCh *channels.RingChannel,
+	stopCh chan struct{}) Discoverier {
+	var servers []string
+	for _, server := range registryServers(cfg.Servers) {
+		// the context path of nacos is /nacos by default
+		if u, err := url.Parse(server); err == nil && u.Path == "" {
+			server += "/nacos"
+		}
+		servers = append(servers, server)
+	}
+	return &nacos{
+		sid:       cfg.ServiceID,
+		servers:   servers,
+		service:   cfg.ServiceName,
+		namespace: cfg.Namespace,
+		group:     cfg.Key,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		updateCh:  updateCh,
+		stopCh:    stopCh,
+	}
+}
+
+// Connect creates the http client of nacos, and logs in if the username is given.
+func (n *nacos) Connect() error {
+	if len(n.servers) == 0 || n.service == "" {
+		return fmt.Errorf("the servers and the service name of nacos are required")
+	}
+	n.client = &http.Client{Timeout: 10 * time.Second}
+	if n.username == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return n.login(ctx)
+}
+
+func (n *nacos) login(ctx context.Context) error {
+	var res struct {
+		AccessToken string `json:"accessToken"`
+	}
+	form := url.Values{"username": {n.username}, "password": {n.password}}
+	_, err := getJSON(ctx, n.client, n.servers, func(server string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, server+"/v1/auth/login", strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	}, &res)
+	if err != nil {
+		return fmt.Errorf("error logging in nacos: %v", err)
+	}
+	n.accessToken = res.AccessToken
+	return nil
+}
+
+// Close does nothing, the requests of nacos are stateless.
+func (n *nacos) Close() error {
+	return nil
+}
+
+// Fetch fetches the instances of the service from nacos.
+func (n *nacos) Fetch() ([]*v1.WtEndpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return n.fetch(ctx)
+}
+
+// Watch polls the instances of the service from nacos.
+func (n *nacos) Watch() {
+	watchRegistry("nacos service "+n.service, n.fetch, registryPollInterval, n.updateCh, n.stopCh)
+}
+
+func (n *nacos) fetch(ctx context.Context) ([]*v1.WtEndpoint, error) {
+	if n.client == nil {
+		return nil, fmt.Errorf("can't fetching data from nacos without connecting")
+	}
+	hosts, err := n.listInstances(ctx)
+	var statusErr *registryStatusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusForbidden && n.username != "" {
+		// the access token expired
+		if err = n.login(ctx); err == nil {
+			hosts, err = n.listInstances(ctx)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching endpoints from nacos: %v", err)
+	}
+	var eps []*v1.WtEndpoint
+	for _, host := range hosts {
+		uuid := host.InstanceID
+		if uuid == "" {
+			uuid = fmt.Sprintf("%s#%d", host.IP, host.Port)
+		}
+		eps = append(eps, &v1.WtEndpoint{
+			UUID:     uuid,
+			Sid:      n.sid,
+			IP:       host.IP,
+			Port:     host.Port,
+			IsOnline: host.Healthy && host.Enabled,
+		})
+	}
+	return eps, nil
+}
+
+func (n *nacos) listInstances(ctx context.Context) ([]nacosInstance, error) {
+	var res struct {
+		Hosts []nacosInstance `json:"hosts"`
+	}
+	_, err := getJSON(ctx, n.client, n.servers, func(server string) (*http.Request, error) {
+		query := url.Values{"serviceName": {n.service}, "healthyOnly": {"false"}}
+		if n.namespace != "" {
+			query.Set("namespaceId", n.namespace)
+		}
+		if n.group != "" {
+			query.Set("groupName", n.group)
+		}
+		if n.accessToken != "" {
+			query.Set("accessToken", n.accessToken)
+		}
+		return http.NewRequest(http.MethodGet, server+"/v1/ns/instance/list?"+query.Encode(), nil)
+	}, &res)
+	return res.Hosts, err
+}
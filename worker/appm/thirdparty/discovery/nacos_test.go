@@ -0,0 +1,155 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+package discovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/wutong-paas/wutong/db/model"
+)
+
+type fakeNacos struct {
+	lock   sync.Mutex
+	token  string
+	logins int
+	hosts  []nacosInstance
+}
+
+func (f *fakeNacos) set(hosts ...nacosInstance) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.hosts = hosts
+}
+
+func (f *fakeNacos) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	switch r.URL.Path {
+	case "/nacos/v1/auth/login":
+		if r.FormValue("username") != "nacos" || r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		f.logins++
+		json.NewEncoder(w).Encode(map[string]interface{}{"accessToken": f.token, "tokenTtl": 18000})
+	case "/nacos/v1/ns/instance/list":
+		query := r.URL.Query()
+		if query.Get("accessToken") != f.token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if query.Get("serviceName") != "user-service" || query.Get("groupName") != "DEFAULT_GROUP" || query.Get("namespaceId") != "dev" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"hosts": []nacosInstance{}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "DEFAULT_GROUP@@user-service", "hosts": f.hosts})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestNacos(url string, updateCh *channels.RingChannel, stopCh chan struct{}) Discoverier {
+	return NewNacos(&model.ThirdPartySvcDiscoveryCfg{
+		ServiceID:   "sid",
+		Servers:     url,
+		ServiceName: "user-service",
+		Namespace:   "dev",
+		Key:         "DEFAULT_GROUP",
+		Username:    "nacos",
+		Password:    "secret",
+	}, updateCh, stopCh)
+}
+
+func TestNacos_Fetch(t *testing.T) {
+	fake := &fakeNacos{token: "token-1", hosts: []nacosInstance{
+		{InstanceID: "10.0.0.1#8080#DEFAULT#DEFAULT_GROUP@@user-service", IP: "10.0.0.1", Port: 8080, Healthy: true, Enabled: true},
+		{IP: "10.0.0.2", Port: 8080, Healthy: true, Enabled: false},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	n := newTestNacos(server.URL, channels.NewRingChannel(1024), make(chan struct{}))
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	eps, err := n.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eps) != 2 {
+		t.Fatalf("expected 2 endpoints, got %d", len(eps))
+	}
+	if eps[0].IP != "10.0.0.1" || eps[0].Port != 8080 || !eps[0].IsOnline || eps[0].Sid != "sid" {
+		t.Errorf("unexpected endpoint %+v", eps[0])
+	}
+	if eps[1].UUID != "10.0.0.2#8080" || eps[1].IsOnline {
+		t.Errorf("unexpected endpoint %+v", eps[1])
+	}
+
+	// the access token expired
+	fake.lock.Lock()
+	fake.token = "token-2"
+	fake.lock.Unlock()
+	if _, err := n.Fetch(); err != nil {
+		t.Fatalf("expected fetching with a new access token, got %v", err)
+	}
+	fake.lock.Lock()
+	defer fake.lock.Unlock()
+	if fake.logins != 2 {
+		t.Errorf("expected 2 logins, got %d", fake.logins)
+	}
+}
+
+func TestNacos_Watch(t *testing.T) {
+	defer func(interval time.Duration) { registryPollInterval = interval }(registryPollInterval)
+	registryPollInterval = 10 * time.Millisecond
+
+	instance := nacosInstance{InstanceID: "user-1", IP: "10.0.0.1", Port: 8080, Healthy: true, Enabled: true}
+	fake := &fakeNacos{token: "token", hosts: []nacosInstance{instance}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	updateCh := channels.NewRingChannel(1024)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	n := newTestNacos(server.URL, updateCh, stopCh)
+	if err := n.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	go n.Watch()
+	time.Sleep(100 * time.Millisecond)
+
+	moved := instance
+	moved.Port = 8081
+	fake.set(moved)
+	expectEvent(t, updateCh, UpdateEvent, "user-1")
+	moved.Healthy = false
+	fake.set(moved)
+	expectEvent(t, updateCh, UnhealthyEvent, "user-1")
+	moved.Healthy = true
+	fake.set(moved)
+	expectEvent(t, updateCh, HealthEvent, "user-1")
+	fake.set()
+	expectEvent(t, updateCh, DeleteEvent, "user-1")
+}
@@ -0,0 +1,165 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eapache/channels"
+	"github.com/sirupsen/logrus"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+)
+
+// registryPollInterval the interval to fetch the endpoints from the registries without blocking query
+var registryPollInterval = 10 * time.Second
+
+// registryServers returns the addresses of the registry with the scheme
+func registryServers(servers string) []string {
+	var res []string
+	for _, server := range strings.Split(servers, ",") {
+		server = strings.TrimRight(strings.TrimSpace(server), "/")
+		if server == "" {
+			continue
+		}
+		if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+			server = "http://" + server
+		}
+		res = append(res, server)
+	}
+	return res
+}
+
+// registryStatusError the unexpected status code of the registry
+type registryStatusError struct {
+	path string
+	code int
+	body string
+}
+
+func (e *registryStatusError) Error() string {
+	return fmt.Sprintf("%s: unexpect status code %d: %s", e.path, e.code, e.body)
+}
+
+// getJSON requests the servers in turn until one of them succeeds, and decodes the json body into v.
+// It returns the response header of the succeeded request.
+func getJSON(ctx context.Context, client *http.Client, servers []string, newRequest func(server string) (*http.Request, error), v interface{}) (http.Header, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no registry server")
+	}
+	var lastErr error
+	for _, server := range servers {
+		req, err := newRequest(server)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			// the service is not registered yet
+			return resp.Header, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = &registryStatusError{path: req.URL.Path, code: resp.StatusCode, body: strings.TrimSpace(string(body))}
+			continue
+		}
+		if err := json.Unmarshal(body, v); err != nil {
+			return nil, fmt.Errorf("decode response of %s: %v", req.URL.Path, err)
+		}
+		return resp.Header, nil
+	}
+	return nil, lastErr
+}
+
+// sendEndpointEvents sends the events of the differences between the records and
+// the endpoints, and returns the endpoints as the new records.
+func sendEndpointEvents(records map[string]*v1.WtEndpoint, endpoints []*v1.WtEndpoint, updateCh *channels.RingChannel) map[string]*v1.WtEndpoint {
+	newRecords := make(map[string]*v1.WtEndpoint, len(endpoints))
+	for _, ep := range endpoints {
+		newRecords[ep.UUID] = ep
+		old, ok := records[ep.UUID]
+		switch {
+		case !ok:
+			updateCh.In() <- Event{Type: CreateEvent, Obj: ep}
+		case old.IP != ep.IP || old.Port != ep.Port:
+			updateCh.In() <- Event{Type: UpdateEvent, Obj: ep}
+		case old.IsOnline && !ep.IsOnline:
+			updateCh.In() <- Event{Type: UnhealthyEvent, Obj: ep}
+		case !old.IsOnline && ep.IsOnline:
+			updateCh.In() <- Event{Type: HealthEvent, Obj: ep}
+		}
+	}
+	for uuid, ep := range records {
+		if _, ok := newRecords[uuid]; !ok {
+			updateCh.In() <- Event{Type: DeleteEvent, Obj: ep}
+		}
+	}
+	return newRecords
+}
+
+// watchRegistry fetches the endpoints until the stop channel is closed, the changes
+// are sent to the update channel. The fetch may block until the endpoints change.
+func watchRegistry(name string, fetch func(ctx context.Context) ([]*v1.WtEndpoint, error), interval time.Duration, updateCh *channels.RingChannel, stopCh chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stopCh
+		cancel()
+	}()
+	logrus.Infof("Start watching third-party endpoints of %s", name)
+	var records map[string]*v1.WtEndpoint
+	for {
+		endpoints, err := fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.Warningf("error fetching endpoints of %s: %v", name, err)
+		} else if records == nil {
+			// the endpoints fetched before watching are not sent
+			records = make(map[string]*v1.WtEndpoint, len(endpoints))
+			for _, ep := range endpoints {
+				records[ep.UUID] = ep
+			}
+		} else {
+			records = sendEndpointEvents(records, endpoints, updateCh)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
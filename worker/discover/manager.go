@@ -90,6 +90,7 @@ func (t *TaskManager) Start() error {
 		return err
 	}
 	t.client = client
+	go t.handleManager.ResumeReleases()
 	go t.Do()
 	logrus.Info("start discover success.")
 	return nil
@@ -104,7 +105,11 @@ func (t *TaskManager) Do() {
 		case <-t.ctx.Done():
 			return
 		default:
-			data, err := t.client.Dequeue(t.ctx, &pb.DequeueRequest{Topic: client.WorkerTopic, ClientHost: hostname + "-worker"})
+			data, err := t.client.Dequeue(t.ctx, &pb.DequeueRequest{
+				Topic:             client.WorkerTopic,
+				ClientHost:        hostname + "-worker",
+				VisibilityTimeout: int32(t.config.TaskLeaseTimeout),
+			})
 			if err != nil {
 				if grpc1.ErrorDesc(err) == context.DeadlineExceeded.Error() {
 					continue
@@ -126,11 +131,15 @@ func (t *TaskManager) Do() {
 			transData, err := model.TransTask(data)
 			if err != nil {
 				logrus.Error("trans mq msg data error ", err.Error())
+				if err := t.client.NackTask(data, err.Error()); err != nil {
+					logrus.Warningf("nack task %s failure %s", data.TaskId, err.Error())
+				}
 				continue
 			}
 			rc := t.handleManager.AnalystToExec(transData)
 			if rc != nil && rc != handle.ErrCallback {
 				logrus.Warningf("execute task: %v", rc)
+				t.ackTask(data)
 				TaskError++
 			} else if rc != nil && rc == handle.ErrCallback {
 				logrus.Errorf("err callback; analyst to exet: %v", rc)
@@ -142,18 +151,28 @@ func (t *TaskManager) Do() {
 				cancel()
 				logrus.Debugf("retry send task to mq ,reply is %v", reply)
 				if err != nil {
+					// keep the lease, the task is redelivered after it expires
 					logrus.Errorf("enqueue task %v to mq topic %v Error", data, client.WorkerTopic)
 					continue
 				}
+				t.ackTask(data)
 				//if handle is waiting, sleep 3 second
 				time.Sleep(time.Second * 3)
 			} else {
+				t.ackTask(data)
 				TaskNum++
 			}
 		}
 	}
 }
 
+// ackTask releases the lease of the handled task
+func (t *TaskManager) ackTask(data *pb.TaskMessage) {
+	if err := t.client.AckTask(data); err != nil {
+		logrus.Warningf("ack task %s failure %s", data.TaskId, err.Error())
+	}
+}
+
 // Stop 停止
 func (t *TaskManager) Stop() error {
 	logrus.Info("discover manager is stoping.")
@@ -179,6 +179,13 @@ func NewTaskBody(taskType string, body []byte) TaskBody {
 			return nil
 		}
 		return &b
+	case "release_service":
+		b := ReleaseServiceTaskBody{}
+		err := ffjson.Unmarshal(body, &b)
+		if err != nil {
+			return nil
+		}
+		return &b
 	default:
 		return DefaultTaskBody{}
 	}
@@ -246,6 +253,10 @@ type HorizontalScalingTaskBody struct {
 	Replicas    int32  `json:"replicas"`
 	EventID     string `json:"event_id"`
 	Username    string `json:"username"`
+	// RecordType the type of the scaling record, defaults to manual
+	RecordType string `json:"record_type,omitempty"`
+	// RuleID the scheduled scaling rule triggered the scaling
+	RuleID string `json:"rule_id,omitempty"`
 }
 
 // VerticalScalingTaskBody 垂直伸缩操作任务主体
@@ -399,5 +410,13 @@ type ExportHelmChartOrK8sYamlTaskBody struct {
 	End        bool   `json:"end"`
 }
 
+// ReleaseServiceTaskBody runs the canary or blue-green release of a component
+type ReleaseServiceTaskBody struct {
+	TenantEnvID string `json:"tenant_env_id"`
+	ServiceID   string `json:"service_id"`
+	ReleaseID   string `json:"release_id"`
+	EventID     string `json:"event_id"`
+}
+
 // DefaultTaskBody 默认操作任务主体
 type DefaultTaskBody map[string]interface{}
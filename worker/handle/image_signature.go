@@ -0,0 +1,67 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, component Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handle
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+)
+
+// checkImageSignature refuses to deploy the image which is not verified by the
+// enforced image signature policy of the tenant env. The signature is verified
+// by chaos when the image is built or imported.
+func (m *Manager) checkImageSignature(app *v1.AppService) error {
+	if app.DeployVersion == "" {
+		return nil
+	}
+	policies, err := m.dbmanager.ImageSignaturePolicyDao().GetEffectivePolicies(app.TenantEnvID)
+	if err != nil {
+		return fmt.Errorf("获取镜像签名策略失败：%v", err)
+	}
+	if !policies.IsEnforced() {
+		return nil
+	}
+	// the image of the unknown version is not verified, it can not be deployed
+	version, err := m.dbmanager.VersionInfoDao().GetVersionByDeployVersion(app.DeployVersion, app.ServiceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return fmt.Errorf("应用组件构建版本 %s 不存在，无法验证镜像签名", app.DeployVersion)
+		}
+		return fmt.Errorf("获取应用组件构建版本失败：%v", err)
+	}
+	return checkVersionSignature(policies, version)
+}
+
+func checkVersionSignature(policies dbmodel.ImageSignaturePolicies, version *dbmodel.VersionInfo) error {
+	image := version.SignedImage()
+	if !policies.Match(image).IsEnforced() {
+		return nil
+	}
+	switch version.SignatureStatus {
+	case dbmodel.ImageSignatureVerified:
+		return nil
+	case dbmodel.ImageSignatureUnverified:
+		return fmt.Errorf("镜像 %s 未通过签名验证，拒绝部署", image)
+	default:
+		return fmt.Errorf("镜像 %s 未经过签名验证，请重新构建后部署", image)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/wutong-paas/wutong/db"
 	dbmodel "github.com/wutong-paas/wutong/db/model"
 	"github.com/wutong-paas/wutong/event"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
 	"github.com/wutong-paas/wutong/util"
 	"github.com/wutong-paas/wutong/worker/appm/controller"
 	"github.com/wutong-paas/wutong/worker/appm/conversion"
@@ -50,6 +51,7 @@ type Manager struct {
 	dbmanager         db.Manager
 	controllerManager *controller.Manager
 	garbageCollector  *gc.GarbageCollector
+	prometheusCli     prometheus.Interface
 }
 
 // NewManager now handle
@@ -59,6 +61,13 @@ func NewManager(ctx context.Context,
 	controllerManager *controller.Manager,
 	garbageCollector *gc.GarbageCollector) *Manager {
 
+	prometheusCli, err := prometheus.NewPrometheus(&prometheus.Options{
+		Endpoint: config.PrometheusEndpoint,
+	})
+	if err != nil {
+		logrus.Warningf("create prometheus client failure, the releases can not be analyzed: %v", err)
+		prometheusCli = nil
+	}
 	return &Manager{
 		ctx:               ctx,
 		cfg:               config,
@@ -66,6 +75,7 @@ func NewManager(ctx context.Context,
 		store:             store,
 		controllerManager: controllerManager,
 		garbageCollector:  garbageCollector,
+		prometheusCli:     prometheusCli,
 	}
 }
 
@@ -131,6 +141,9 @@ func (m *Manager) AnalystToExec(task *model.Task) error {
 	case "export_k8s_yaml":
 		logrus.Info("start a 'export_k8s_yaml' task worker")
 		return m.ExecExportK8sYamlTask(task)
+	case "release_service":
+		logrus.Info("start a 'release_service' task worker")
+		return m.ExecReleaseServiceTask(task)
 	default:
 		logrus.Warning("task can not execute because no type is identified")
 		return nil
@@ -158,6 +171,12 @@ func (m *Manager) startExec(task *model.Task) error {
 		event.CloseLogger(body.EventID)
 		return fmt.Errorf("application init create failure")
 	}
+	if err := m.checkImageSignature(newAppService); err != nil {
+		logrus.Warningf("component %s start is refused: %s", body.ServiceID, err.Error())
+		logger.Error(err.Error(), event.GetCallbackLoggerOption())
+		event.CloseLogger(body.EventID)
+		return err
+	}
 	newAppService.Logger = logger
 	//regist new app service
 	m.store.RegistAppService(newAppService)
@@ -262,10 +281,15 @@ func (m *Manager) horizontalScalingExec(task *model.Task) (err error) {
 			desc = fmt.Sprintf(desc, oldReplicas, newReplicas, err)
 			reason = "FailedRescale"
 		}
+		recordType := body.RecordType
+		if recordType == "" {
+			recordType = dbmodel.ScalingRecordTypeManual
+		}
 		scalingRecord := &dbmodel.TenantEnvServiceScalingRecords{
 			ServiceID:   body.ServiceID,
+			RuleID:      body.RuleID,
 			EventName:   util.NewUUID(),
-			RecordType:  "manual",
+			RecordType:  recordType,
 			Reason:      reason,
 			Count:       1,
 			Description: desc,
@@ -351,6 +375,12 @@ func (m *Manager) rollingUpgradeExec(task *model.Task) error {
 		event.CloseLogger(body.EventID)
 		return fmt.Errorf("component init create failure")
 	}
+	if err := m.checkImageSignature(newAppService); err != nil {
+		logrus.Warningf("component %s rolling upgrade is refused: %s", body.ServiceID, err.Error())
+		logger.Error(err.Error(), event.GetCallbackLoggerOption())
+		event.CloseLogger(body.EventID)
+		return err
+	}
 	newAppService.Logger = logger
 	oldAppService := m.store.GetAppService(body.ServiceID)
 	// if service not deploy,start it
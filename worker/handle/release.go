@@ -0,0 +1,113 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, component Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package handle
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/event"
+	"github.com/wutong-paas/wutong/worker/appm/conversion"
+	"github.com/wutong-paas/wutong/worker/discover/model"
+)
+
+// ExecReleaseServiceTask runs the canary or blue-green release of the component
+func (m *Manager) ExecReleaseServiceTask(task *model.Task) error {
+	body, ok := task.Body.(*model.ReleaseServiceTaskBody)
+	if !ok {
+		logrus.Errorf("exec task 'release_service'; wrong type: %v", reflect.TypeOf(task.Body))
+		return fmt.Errorf("exec task 'release_service': wrong input")
+	}
+	logger := event.GetLogger(body.EventID)
+	release, err := m.dbmanager.ServiceReleaseDao().GetByReleaseID(body.ReleaseID)
+	if err != nil {
+		logrus.Errorf("get release %s failure: %v", body.ReleaseID, err)
+		logger.Error("获取发布信息失败", event.GetCallbackLoggerOption())
+		return fmt.Errorf("get release %s: %v", body.ReleaseID, err)
+	}
+	if release.Status != dbmodel.ReleaseStatusPending {
+		logrus.Warningf("release %s is %s, ignore it", release.ReleaseID, release.Status)
+		return nil
+	}
+	if err := m.startRelease(release, logger); err != nil {
+		return fmt.Errorf("release %s: %s", release.ReleaseID, err.Error())
+	}
+	logrus.Infof("service(%s) %s working is running.", body.ServiceID, "release")
+	return nil
+}
+
+// ResumeReleases runs again the releases interrupted by the restart of the worker,
+// the pending releases are left to their tasks.
+func (m *Manager) ResumeReleases() {
+	releases, err := m.dbmanager.ServiceReleaseDao().ListByStatus(dbmodel.ReleaseStatusProgressing, dbmodel.ReleaseStatusPaused,
+		dbmodel.ReleaseStatusPromoting, dbmodel.ReleaseStatusRollingBack)
+	if err != nil {
+		logrus.Errorf("list the unfinished releases: %v", err)
+		return
+	}
+	for _, release := range releases {
+		logrus.Infof("resume the %s release %s of service %s", release.Status, release.ReleaseID, release.ServiceID)
+		if err := m.startRelease(release, event.GetLogger(release.EventID)); err != nil {
+			logrus.Errorf("resume release %s: %s", release.ReleaseID, err.Error())
+		}
+	}
+}
+
+// startRelease runs the release controller, the release is failed if it can not be run
+func (m *Manager) startRelease(release *dbmodel.ServiceRelease, logger event.Logger) error {
+	failed := func(message string) error {
+		now := time.Now()
+		release.Status = dbmodel.ReleaseStatusFailed
+		release.Message = message
+		release.FinishTime = &now
+		if err := m.dbmanager.ServiceReleaseDao().UpdateModel(release); err != nil {
+			logrus.Errorf("update release %s: %v", release.ReleaseID, err)
+		}
+		logger.Error(message, event.GetCallbackLoggerOption())
+		return fmt.Errorf("%s", message)
+	}
+
+	stableApp := m.store.GetAppService(release.ServiceID)
+	if stableApp == nil || stableApp.IsClosed() {
+		return failed("应用组件未运行，无法发布")
+	}
+	stable, err := conversion.InitAppServiceByVersion(m.dbmanager, release.ServiceID, release.StableVersion, nil)
+	if err != nil {
+		logrus.Errorf("component init create failure:%s", err.Error())
+		return failed("应用组件初始创建失败")
+	}
+	canary, err := conversion.InitAppServiceByVersion(m.dbmanager, release.ServiceID, release.CanaryVersion, nil)
+	if err != nil {
+		logrus.Errorf("component init create failure:%s", err.Error())
+		return failed("新版本初始创建失败")
+	}
+	if err := m.checkImageSignature(canary); err != nil {
+		logrus.Warningf("component %s release is refused: %s", release.ServiceID, err.Error())
+		return failed(err.Error())
+	}
+	stable.Logger = logger
+	canary.Logger = logger
+	if err := m.controllerManager.StartReleaseController(release, m.prometheusCli, *stable, *canary); err != nil {
+		return failed(fmt.Sprintf("运行发布控制器失败：%s", err.Error()))
+	}
+	return nil
+}
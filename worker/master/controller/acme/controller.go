@@ -0,0 +1,435 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/mq/client"
+	"github.com/wutong-paas/wutong/util"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// checkInterval the interval to check the certificates of the auto tls rules
+	checkInterval = time.Minute
+	// obtainTimeout the timeout to issue a certificate
+	obtainTimeout = 5 * time.Minute
+	// minRetryInterval and maxRetryInterval limit the interval to retry the failed domains,
+	// the ACME servers limit the failed validations.
+	minRetryInterval = 10 * time.Minute
+	maxRetryInterval = 12 * time.Hour
+)
+
+// OptTypeIssueCertificate the event type of the failed certificate issuing
+const OptTypeIssueCertificate = "issue-certificate"
+
+// Options the options of the ACME controller
+type Options struct {
+	// DirectoryURL the directory of the ACME server, the controller is disabled if it is empty
+	DirectoryURL string
+	Email        string
+	// RenewBefore renews the certificates which expire in the duration
+	RenewBefore time.Duration
+	// DNSProvider fulfils the dns-01 challenges, it is required by the wildcard domains
+	DNSProvider DNSProvider
+}
+
+// Controller issues and renews the certificates of the http rules marked as auto tls. The
+// certificates are saved as the Certificate of the rules, the secrets are created by the
+// apply_rule task of the worker, the same as the uploaded certificates.
+type Controller struct {
+	opts      Options
+	dbmanager db.Manager
+	mqclient  client.MQClient
+	issuer    *Issuer
+	// domain -> retry
+	retries map[string]*retry
+}
+
+type retry struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// NewController creates the ACME controller
+func NewController(opts Options, mqAPI string) *Controller {
+	mqclient, err := client.NewMqClient(mqAPI)
+	if err != nil {
+		logrus.Warningf("create mq client failure, the issued certificates can not be applied: %v", err)
+		mqclient = nil
+	}
+	return &Controller{
+		opts:      opts,
+		dbmanager: db.GetManager(),
+		mqclient:  mqclient,
+		retries:   make(map[string]*retry),
+	}
+}
+
+// Start issues and renews the certificates until the context is done, it should only run on the leader.
+func (c *Controller) Start(ctx context.Context) {
+	if c.opts.DirectoryURL == "" {
+		logrus.Info("acme directory is not configured, the auto tls rules are ignored")
+		return
+	}
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.reconcile(ctx, now)
+		}
+	}
+}
+
+// KeyAuthorization returns the key authorization of the pending http-01 challenge
+func (c *Controller) KeyAuthorization(token string) (string, error) {
+	return KeyAuthorization(c.dbmanager, token)
+}
+
+func (c *Controller) reconcile(ctx context.Context, now time.Time) {
+	rules, err := c.dbmanager.HTTPRuleDao().ListAutoTLS()
+	if err != nil {
+		logrus.Errorf("list auto tls http rules: %v", err)
+		return
+	}
+	domainRules := make(map[string][]*dbmodel.HTTPRule)
+	for _, rule := range rules {
+		domain := strings.ToLower(strings.TrimSpace(rule.Domain))
+		if domain == "" {
+			continue
+		}
+		domainRules[domain] = append(domainRules[domain], rule)
+	}
+	var domains []string
+	for domain := range domainRules {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		if ctx.Err() != nil {
+			return
+		}
+		c.ensureCertificate(ctx, domain, domainRules[domain], now)
+	}
+	for domain := range c.retries {
+		if _, ok := domainRules[domain]; !ok {
+			delete(c.retries, domain)
+		}
+	}
+}
+
+// ensureCertificate issues the certificate of the domain if there is no valid one, and links
+// the rules of the domain to it.
+func (c *Controller) ensureCertificate(ctx context.Context, domain string, rules []*dbmodel.HTTPRule, now time.Time) {
+	cert := c.issuedCertificate(domain, rules)
+	if cert != nil && !needsRenewal(cert.Certificate, domain, now, c.opts.RenewBefore) {
+		delete(c.retries, domain)
+		c.linkRules(cert.UUID, rules, false)
+		return
+	}
+	if r := c.retries[domain]; r != nil && now.Before(r.next) {
+		return
+	}
+
+	certPEM, keyPEM, err := c.obtain(ctx, domain)
+	if err != nil {
+		c.backoff(domain, now)
+		logrus.Warningf("issue certificate of %s: %v", domain, err)
+		c.failureEvents(domain, rules, err)
+		return
+	}
+	delete(c.retries, domain)
+
+	certID := util.NewUUID()
+	if cert != nil {
+		certID = cert.UUID
+	}
+	if err := c.dbmanager.CertificateDao().AddOrUpdate(&dbmodel.Certificate{
+		UUID:            certID,
+		CertificateName: certificateName(domain),
+		Certificate:     string(certPEM),
+		PrivateKey:      string(keyPEM),
+	}); err != nil {
+		logrus.Errorf("save certificate of %s: %v", domain, err)
+		return
+	}
+	logrus.Infof("certificate of %s is issued", domain)
+	// the rules linked to the renewed certificate are applied again to update the secrets
+	c.linkRules(certID, rules, true)
+}
+
+func (c *Controller) obtain(ctx context.Context, domain string) ([]byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, obtainTimeout)
+	defer cancel()
+	if c.issuer == nil {
+		acmeClient, err := c.registerAccount(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("register acme account: %v", err)
+		}
+		c.issuer = NewIssuer(acmeClient, &dbChallengeStore{dbmanager: c.dbmanager}, c.opts.DNSProvider)
+	}
+	return c.issuer.Obtain(ctx, domain)
+}
+
+// issuedCertificate returns the certificate issued by the controller for the domain, which is
+// linked to the rules
+func (c *Controller) issuedCertificate(domain string, rules []*dbmodel.HTTPRule) *dbmodel.Certificate {
+	for _, rule := range rules {
+		if rule.CertificateID == "" {
+			continue
+		}
+		cert, err := c.dbmanager.CertificateDao().GetCertificateByID(rule.CertificateID)
+		if err != nil {
+			logrus.Warningf("get certificate %s: %v", rule.CertificateID, err)
+			continue
+		}
+		if cert != nil && cert.CertificateName == certificateName(domain) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// linkRules links the rules to the certificate and applies the changed ones
+func (c *Controller) linkRules(certID string, rules []*dbmodel.HTTPRule, force bool) {
+	for _, rule := range rules {
+		if rule.CertificateID == certID && !force {
+			continue
+		}
+		rule.CertificateID = certID
+		if err := c.dbmanager.HTTPRuleDao().UpdateModel(rule); err != nil {
+			logrus.Errorf("link http rule %s to certificate %s: %v", rule.UUID, certID, err)
+			continue
+		}
+		if err := c.applyRule(rule); err != nil {
+			logrus.Errorf("apply http rule %s: %v", rule.UUID, err)
+		}
+	}
+}
+
+func (c *Controller) applyRule(rule *dbmodel.HTTPRule) error {
+	if c.mqclient == nil {
+		return fmt.Errorf("mq client is not available")
+	}
+	service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(rule.ServiceID)
+	if err != nil {
+		return err
+	}
+	return c.mqclient.SendBuilderTopic(client.TaskStruct{
+		Topic:    client.WorkerTopic,
+		TaskType: "apply_rule",
+		TaskBody: map[string]interface{}{
+			"service_id":     rule.ServiceID,
+			"deploy_version": service.DeployVersion,
+			"event_id":       util.NewUUID(),
+			"action":         "update-rule-config",
+			"limit":          map[string]string{"domain": rule.Domain},
+		},
+	})
+}
+
+func (c *Controller) backoff(domain string, now time.Time) {
+	r := c.retries[domain]
+	if r == nil {
+		r = &retry{interval: minRetryInterval}
+		c.retries[domain] = r
+	} else {
+		r.interval *= 2
+		if r.interval > maxRetryInterval {
+			r.interval = maxRetryInterval
+		}
+	}
+	r.next = now.Add(r.interval)
+}
+
+// failureEvents records the failure to the components of the domain
+func (c *Controller) failureEvents(domain string, rules []*dbmodel.HTTPRule, cause error) {
+	message := fmt.Sprintf("issue certificate of %s: %v", domain, cause)
+	if len(message) > 255 {
+		message = message[:255]
+	}
+	now := time.Now().Format(time.RFC3339)
+	services := make(map[string]struct{})
+	for _, rule := range rules {
+		if _, ok := services[rule.ServiceID]; ok {
+			continue
+		}
+		services[rule.ServiceID] = struct{}{}
+		service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(rule.ServiceID)
+		if err != nil {
+			logrus.Warningf("get component %s of http rule %s: %v", rule.ServiceID, rule.UUID, err)
+			continue
+		}
+		event := &dbmodel.ServiceEvent{
+			EventID:     util.NewUUID(),
+			TenantEnvID: service.TenantEnvID,
+			ServiceID:   service.ServiceID,
+			Target:      dbmodel.TargetTypeService,
+			TargetID:    service.ServiceID,
+			UserName:    dbmodel.UsernameSystem,
+			StartTime:   now,
+			EndTime:     now,
+			OptType:     OptTypeIssueCertificate,
+			Status:      dbmodel.EventStatusFailure.String(),
+			FinalStatus: dbmodel.EventFinalStatusComplete.String(),
+			Message:     message,
+		}
+		if err := c.dbmanager.ServiceEventDao().AddModel(event); err != nil {
+			logrus.Warningf("create certificate failure event of component %s: %v", service.ServiceID, err)
+		}
+	}
+}
+
+// registerAccount returns the ACME client of the account, the account is registered and saved
+// to db at the first time.
+func (c *Controller) registerAccount(ctx context.Context) (*acme.Client, error) {
+	account, err := c.dbmanager.ACMEAccountDao().GetByDirectoryURL(c.opts.DirectoryURL)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound {
+		account = &dbmodel.ACMEAccount{DirectoryURL: c.opts.DirectoryURL}
+	}
+	var key crypto.Signer
+	if account.PrivateKey != "" {
+		if key, err = parseAccountKey(account.PrivateKey); err != nil {
+			return nil, err
+		}
+	} else {
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			return nil, err
+		}
+	}
+	acmeClient := &acme.Client{
+		Key:          key,
+		DirectoryURL: c.opts.DirectoryURL,
+		UserAgent:    "wutong-worker",
+	}
+	if account.URI != "" {
+		acmeClient.KID = acme.KeyID(account.URI)
+		return acmeClient, nil
+	}
+
+	var contact []string
+	if c.opts.Email != "" {
+		contact = []string{"mailto:" + c.opts.Email}
+	}
+	if err := register(ctx, acmeClient, contact); err != nil {
+		return nil, err
+	}
+	keyPEM, err := encodeAccountKey(key.(*ecdsa.PrivateKey))
+	if err != nil {
+		return nil, err
+	}
+	account.Email = c.opts.Email
+	account.URI = string(acmeClient.KID)
+	account.PrivateKey = keyPEM
+	if account.ID == 0 {
+		err = c.dbmanager.ACMEAccountDao().AddModel(account)
+	} else {
+		err = c.dbmanager.ACMEAccountDao().UpdateModel(account)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("save acme account: %v", err)
+	}
+	return acmeClient, nil
+}
+
+// register registers the account of the client key, the existing account of the key is looked up
+// if it is registered already, eg: the account was not saved to db last time.
+func register(ctx context.Context, acmeClient *acme.Client, contact []string) error {
+	_, err := acmeClient.Register(ctx, &acme.Account{Contact: contact}, acme.AcceptTOS)
+	if err == acme.ErrAccountAlreadyExists {
+		existing, err := acmeClient.GetReg(ctx, "")
+		if err != nil {
+			return fmt.Errorf("get the existing acme account: %v", err)
+		}
+		acmeClient.KID = acme.KeyID(existing.URI)
+	} else if err != nil {
+		return err
+	}
+	if acmeClient.KID == "" {
+		return fmt.Errorf("the url of the acme account is unknown")
+	}
+	return nil
+}
+
+func parseAccountKey(keyPEM string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(keyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("invalid acme account key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+func encodeAccountKey(key *ecdsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})), nil
+}
+
+// needsRenewal returns true if the certificate is invalid, does not cover the domain or
+// expires in the renew duration.
+func needsRenewal(certPEM, domain string, now time.Time, renewBefore time.Duration) bool {
+	block, _ := pem.Decode([]byte(certPEM))
+	if block == nil {
+		return true
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	covered := false
+	for _, name := range leaf.DNSNames {
+		if strings.EqualFold(name, domain) {
+			covered = true
+			break
+		}
+	}
+	return !covered || now.Add(renewBefore).After(leaf.NotAfter)
+}
+
+// certificateName returns the name of the certificate issued for the domain
+func certificateName(domain string) string {
+	name := dbmodel.ACMECertificateNamePrefix + domain
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
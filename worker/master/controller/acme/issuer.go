@@ -0,0 +1,198 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+)
+
+// DNSProvider creates and removes the TXT records of the dns-01 challenges, Present should
+// not return until the record is visible to the ACME server.
+type DNSProvider interface {
+	Present(fqdn, value string) error
+	CleanUp(fqdn, value string) error
+}
+
+// HTTPChallengeStore saves the key authorizations of the http-01 challenges, they are served
+// by the gateway under /.well-known/acme-challenge/.
+type HTTPChallengeStore interface {
+	Present(domain, token, keyAuth string) error
+	CleanUp(token string) error
+}
+
+// Issuer obtains the certificates from the ACME server. The http-01 challenge is preferred,
+// the dns-01 challenge is used for the wildcard domains or if there is no http-01 store.
+type Issuer struct {
+	client      *acme.Client
+	httpStore   HTTPChallengeStore
+	dnsProvider DNSProvider
+}
+
+// NewIssuer creates the issuer with the registered ACME client, either the http store or the
+// dns provider can be nil.
+func NewIssuer(client *acme.Client, httpStore HTTPChallengeStore, dnsProvider DNSProvider) *Issuer {
+	return &Issuer{
+		client:      client,
+		httpStore:   httpStore,
+		dnsProvider: dnsProvider,
+	}
+}
+
+// Obtain issues the certificate of the domain, returns the PEM encoded certificate chain and
+// private key.
+func (i *Issuer) Obtain(ctx context.Context, domain string) (certPEM, keyPEM []byte, err error) {
+	order, err := i.client.AuthorizeOrder(ctx, acme.DomainIDs(domain))
+	if err != nil {
+		return nil, nil, fmt.Errorf("authorize order: %v", err)
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err := i.authorize(ctx, authzURL); err != nil {
+			return nil, nil, err
+		}
+	}
+	order, err = i.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wait order: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate private key: %v", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: []string{domain},
+	}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create certificate request: %v", err)
+	}
+	ders, _, err := i.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("finalize order: %v", err)
+	}
+
+	for _, der := range ders {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal private key: %v", err)
+	}
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func (i *Issuer) authorize(ctx context.Context, authzURL string) error {
+	authz, err := i.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("get authorization: %v", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	chal, err := i.pickChallenge(authz)
+	if err != nil {
+		return err
+	}
+	cleanUp, err := i.present(authz.Identifier.Value, chal)
+	if err != nil {
+		return fmt.Errorf("present %s challenge: %v", chal.Type, err)
+	}
+	defer cleanUp()
+
+	if _, err := i.client.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("accept %s challenge: %v", chal.Type, err)
+	}
+	if _, err := i.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("wait authorization of %s: %v", authz.Identifier.Value, err)
+	}
+	return nil
+}
+
+func (i *Issuer) pickChallenge(authz *acme.Authorization) (*acme.Challenge, error) {
+	var types []string
+	if i.httpStore != nil && !authz.Wildcard {
+		types = append(types, "http-01")
+	}
+	if i.dnsProvider != nil {
+		types = append(types, "dns-01")
+	}
+	for _, typ := range types {
+		for _, chal := range authz.Challenges {
+			if chal.Type == typ {
+				return chal, nil
+			}
+		}
+	}
+	if authz.Wildcard && i.dnsProvider == nil {
+		return nil, fmt.Errorf("the wildcard domain %s requires the dns-01 challenge, but there is no dns provider", authz.Identifier.Value)
+	}
+	return nil, fmt.Errorf("no supported challenge for %s", authz.Identifier.Value)
+}
+
+// present fulfils the challenge, returns the function to clean it up
+func (i *Issuer) present(domain string, chal *acme.Challenge) (func(), error) {
+	switch chal.Type {
+	case "http-01":
+		keyAuth, err := i.client.HTTP01ChallengeResponse(chal.Token)
+		if err != nil {
+			return nil, err
+		}
+		if err := i.httpStore.Present(domain, chal.Token, keyAuth); err != nil {
+			return nil, err
+		}
+		return func() {
+			if err := i.httpStore.CleanUp(chal.Token); err != nil {
+				logrus.Warningf("clean up %s challenge of %s: %v", chal.Type, domain, err)
+			}
+		}, nil
+	case "dns-01":
+		value, err := i.client.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return nil, err
+		}
+		fqdn := dnsChallengeFQDN(domain)
+		if err := i.dnsProvider.Present(fqdn, value); err != nil {
+			return nil, err
+		}
+		return func() {
+			if err := i.dnsProvider.CleanUp(fqdn, value); err != nil {
+				logrus.Warningf("clean up %s challenge of %s: %v", chal.Type, domain, err)
+			}
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported challenge type %s", chal.Type)
+	}
+}
+
+// dnsChallengeFQDN returns the name of the TXT record of the dns-01 challenge
+func dnsChallengeFQDN(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(domain, "*.") + "."
+}
@@ -0,0 +1,434 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// stubACME is a minimal RFC 8555 server like Pebble, the signatures of the requests are not
+// verified and the challenges are validated by the given function synchronously.
+type stubACME struct {
+	srv      *httptest.Server
+	caKey    *ecdsa.PrivateKey
+	caCert   *x509.Certificate
+	validate func(typ, domain, token string) bool
+	// existing the account of the key is registered already
+	existing bool
+
+	mu     sync.Mutex
+	nonce  int
+	orders map[string]*stubOrder
+}
+
+type stubOrder struct {
+	id         string
+	domain     string
+	wildcard   bool
+	token      string
+	authzValid bool
+	status     string
+	certDER    []byte
+}
+
+func newStubACME(t *testing.T, validate func(typ, domain, token string) bool) *stubACME {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "stub acme ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(der)
+	s := &stubACME{
+		caKey:    caKey,
+		caCert:   caCert,
+		validate: validate,
+		orders:   make(map[string]*stubOrder),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *stubACME) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonce++
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", s.nonce))
+
+	url := s.srv.URL
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch parts[0] {
+	case "directory":
+		s.reply(w, http.StatusOK, map[string]string{
+			"newNonce":   url + "/nonce",
+			"newAccount": url + "/account",
+			"newOrder":   url + "/new-order",
+		})
+		return
+	case "nonce":
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	payload, err := jwsPayload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch parts[0] {
+	case "account":
+		w.Header().Set("Location", url+"/account/1")
+		if s.existing {
+			s.reply(w, http.StatusOK, map[string]string{"status": "valid"})
+			return
+		}
+		s.reply(w, http.StatusCreated, map[string]string{"status": "valid"})
+	case "new-order":
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+		json.Unmarshal(payload, &req)
+		domain := req.Identifiers[0].Value
+		o := &stubOrder{
+			id:       fmt.Sprint(len(s.orders) + 1),
+			domain:   strings.TrimPrefix(domain, "*."),
+			wildcard: strings.HasPrefix(domain, "*."),
+			token:    fmt.Sprintf("token-%d", len(s.orders)+1),
+			status:   acme.StatusPending,
+		}
+		s.orders[o.id] = o
+		w.Header().Set("Location", url+"/order/"+o.id)
+		s.reply(w, http.StatusCreated, s.order(o))
+	case "order":
+		s.reply(w, http.StatusOK, s.order(s.orders[parts[1]]))
+	case "authz":
+		s.reply(w, http.StatusOK, s.authz(s.orders[parts[1]]))
+	case "chal":
+		o := s.orders[parts[1]]
+		if s.validate(parts[2], o.domain, o.token) {
+			o.authzValid = true
+			o.status = acme.StatusReady
+		} else {
+			o.status = acme.StatusInvalid
+		}
+		s.reply(w, http.StatusOK, s.challenge(o, parts[2]))
+	case "finalize":
+		o := s.orders[parts[1]]
+		var req struct{ CSR string }
+		json.Unmarshal(payload, &req)
+		csrDER, _ := base64.RawURLEncoding.DecodeString(req.CSR)
+		csr, err := x509.ParseCertificateRequest(csrDER)
+		if err != nil || o.status != acme.StatusReady {
+			http.Error(w, "order is not ready", http.StatusForbidden)
+			return
+		}
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(s.nonce) + 100),
+			Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+			DNSNames:     csr.DNSNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		}
+		o.certDER, _ = x509.CreateCertificate(rand.Reader, tmpl, s.caCert, csr.PublicKey, s.caKey)
+		o.status = acme.StatusValid
+		w.Header().Set("Location", url+"/order/"+o.id)
+		s.reply(w, http.StatusOK, s.order(o))
+	case "cert":
+		o := s.orders[parts[1]]
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: o.certDER})
+		pem.Encode(w, &pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *stubACME) reply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *stubACME) order(o *stubOrder) map[string]interface{} {
+	domain := o.domain
+	if o.wildcard {
+		domain = "*." + domain
+	}
+	res := map[string]interface{}{
+		"status":         o.status,
+		"identifiers":    []map[string]string{{"type": "dns", "value": domain}},
+		"authorizations": []string{s.srv.URL + "/authz/" + o.id},
+		"finalize":       s.srv.URL + "/finalize/" + o.id,
+	}
+	if o.status == acme.StatusValid {
+		res["certificate"] = s.srv.URL + "/cert/" + o.id
+	}
+	return res
+}
+
+func (s *stubACME) authz(o *stubOrder) map[string]interface{} {
+	status := acme.StatusPending
+	if o.authzValid {
+		status = acme.StatusValid
+	} else if o.status == acme.StatusInvalid {
+		status = acme.StatusInvalid
+	}
+	types := []string{"http-01", "dns-01"}
+	if o.wildcard {
+		types = []string{"dns-01"}
+	}
+	var challenges []map[string]interface{}
+	for _, typ := range types {
+		challenges = append(challenges, s.challenge(o, typ))
+	}
+	return map[string]interface{}{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": o.domain},
+		"wildcard":   o.wildcard,
+		"challenges": challenges,
+	}
+}
+
+func (s *stubACME) challenge(o *stubOrder, typ string) map[string]interface{} {
+	status := acme.StatusPending
+	if o.authzValid {
+		status = acme.StatusValid
+	} else if o.status == acme.StatusInvalid {
+		status = acme.StatusInvalid
+	}
+	return map[string]interface{}{
+		"type":   typ,
+		"url":    fmt.Sprintf("%s/chal/%s/%s", s.srv.URL, o.id, typ),
+		"token":  o.token,
+		"status": status,
+	}
+}
+
+func jwsPayload(r *http.Request) ([]byte, error) {
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(jws.Payload)
+}
+
+type memChallengeStore struct {
+	mu       sync.Mutex
+	keyAuths map[string]string
+}
+
+func (m *memChallengeStore) Present(domain, token, keyAuth string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyAuths[token] = keyAuth
+	return nil
+}
+
+func (m *memChallengeStore) CleanUp(token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keyAuths, token)
+	return nil
+}
+
+func (m *memChallengeStore) get(token string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.keyAuths[token]
+}
+
+type memDNSProvider struct {
+	mu      sync.Mutex
+	records map[string]string
+}
+
+func (m *memDNSProvider) Present(fqdn, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[fqdn] = value
+	return nil
+}
+
+func (m *memDNSProvider) CleanUp(fqdn, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, fqdn)
+	return nil
+}
+
+func (m *memDNSProvider) get(fqdn string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.records[fqdn]
+}
+
+func newTestIssuer(t *testing.T, withDNS bool) (*Issuer, *memChallengeStore, *memDNSProvider) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbprint, err := acme.JWKThumbprint(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &memChallengeStore{keyAuths: make(map[string]string)}
+	dns := &memDNSProvider{records: make(map[string]string)}
+	stub := newStubACME(t, func(typ, domain, token string) bool {
+		keyAuth := token + "." + thumbprint
+		switch typ {
+		case "http-01":
+			return store.get(token) == keyAuth
+		case "dns-01":
+			sum := sha256.Sum256([]byte(keyAuth))
+			return dns.get("_acme-challenge."+domain+".") == base64.RawURLEncoding.EncodeToString(sum[:])
+		}
+		return false
+	})
+	client := &acme.Client{Key: key, DirectoryURL: stub.srv.URL + "/directory"}
+	if _, err := client.Register(context.Background(), &acme.Account{}, acme.AcceptTOS); err != nil {
+		t.Fatal(err)
+	}
+	var provider DNSProvider
+	if withDNS {
+		provider = dns
+	}
+	return NewIssuer(client, store, provider), store, dns
+}
+
+func TestIssuerObtain(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  string
+		withDNS bool
+		wantErr bool
+	}{
+		{name: "http-01", domain: "www.example.com"},
+		{name: "dns-01 for wildcard domain", domain: "*.example.com", withDNS: true},
+		{name: "wildcard domain without dns provider", domain: "*.example.com", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			issuer, store, dns := newTestIssuer(t, tc.withDNS)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			certPEM, keyPEM, err := issuer.Obtain(ctx, tc.domain)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("want error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("obtain certificate: %v", err)
+			}
+			if needsRenewal(string(certPEM), tc.domain, time.Now(), 30*24*time.Hour) {
+				t.Errorf("the issued certificate does not cover %s or expires", tc.domain)
+			}
+			block, _ := pem.Decode(keyPEM)
+			if block == nil || block.Type != "EC PRIVATE KEY" {
+				t.Errorf("invalid private key %q", string(keyPEM))
+			}
+			if len(store.keyAuths) != 0 || len(dns.records) != 0 {
+				t.Errorf("the challenges are not cleaned up")
+			}
+		})
+	}
+}
+
+func TestRegisterExistingAccount(t *testing.T) {
+	for _, existing := range []bool{false, true} {
+		stub := newStubACME(t, nil)
+		stub.existing = existing
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &acme.Client{Key: key, DirectoryURL: stub.srv.URL + "/directory"}
+		if err := register(context.Background(), client, nil); err != nil {
+			t.Fatalf("existing %v: %v", existing, err)
+		}
+		if want := acme.KeyID(stub.srv.URL + "/account/1"); client.KID != want {
+			t.Errorf("existing %v: want the account url %s, got %q", existing, want, client.KID)
+		}
+	}
+}
+
+func TestNeedsRenewal(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"www.example.com"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(60 * 24 * time.Hour),
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+	tests := []struct {
+		name        string
+		cert        string
+		domain      string
+		renewBefore time.Duration
+		want        bool
+	}{
+		{name: "valid", cert: certPEM, domain: "WWW.example.com", renewBefore: 30 * 24 * time.Hour, want: false},
+		{name: "expires soon", cert: certPEM, domain: "www.example.com", renewBefore: 90 * 24 * time.Hour, want: true},
+		{name: "another domain", cert: certPEM, domain: "api.example.com", renewBefore: 30 * 24 * time.Hour, want: true},
+		{name: "invalid pem", cert: "invalid", domain: "www.example.com", want: true},
+	}
+	for _, tc := range tests {
+		if got := needsRenewal(tc.cert, tc.domain, now, tc.renewBefore); got != tc.want {
+			t.Errorf("%s: want %v, but got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+)
+
+// dbChallengeStore saves the key authorizations of the http-01 challenges to db, so that any
+// worker can serve them to the gateway.
+type dbChallengeStore struct {
+	dbmanager db.Manager
+}
+
+func (d *dbChallengeStore) Present(domain, token, keyAuth string) error {
+	if err := d.dbmanager.ACMEChallengeDao().DeleteByToken(token); err != nil {
+		return err
+	}
+	return d.dbmanager.ACMEChallengeDao().AddModel(&dbmodel.ACMEChallenge{
+		Token:   token,
+		Domain:  domain,
+		KeyAuth: keyAuth,
+	})
+}
+
+func (d *dbChallengeStore) CleanUp(token string) error {
+	return d.dbmanager.ACMEChallengeDao().DeleteByToken(token)
+}
+
+// KeyAuthorization returns the key authorization of the pending http-01 challenge, an empty
+// string is returned if the challenge does not exist.
+func KeyAuthorization(dbmanager db.Manager, token string) (string, error) {
+	challenge, err := dbmanager.ACMEChallengeDao().GetByToken(token)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	return challenge.KeyAuth, nil
+}
+
+// WebhookDNSProvider delegates the TXT records of the dns-01 challenges to a webhook, it
+// receives the POST requests with the json body:
+//
+//	{"action": "present|cleanup", "fqdn": "_acme-challenge.example.com.", "value": "..."}
+//
+// and should respond 2xx once the record is created or removed.
+type WebhookDNSProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookDNSProvider creates the webhook dns provider
+func NewWebhookDNSProvider(url string) *WebhookDNSProvider {
+	return &WebhookDNSProvider{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// Present creates the TXT record
+func (w *WebhookDNSProvider) Present(fqdn, value string) error {
+	return w.call("present", fqdn, value)
+}
+
+// CleanUp removes the TXT record
+func (w *WebhookDNSProvider) CleanUp(fqdn, value string) error {
+	return w.call("cleanup", fqdn, value)
+}
+
+func (w *WebhookDNSProvider) call(action, fqdn, value string) error {
+	body, err := json.Marshal(map[string]string{
+		"action": action,
+		"fqdn":   fqdn,
+		"value":  value,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("dns webhook responds %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
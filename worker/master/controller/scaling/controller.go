@@ -0,0 +1,343 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package scaling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/db"
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/mq/client"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+	"github.com/wutong-paas/wutong/util"
+	"github.com/wutong-paas/wutong/worker/appm/store"
+	"github.com/wutong-paas/wutong/worker/discover/model"
+)
+
+// checkInterval the interval to check the scheduled rules and the idle components
+const checkInterval = 30 * time.Second
+
+// scalingOperator the operator of the scaling records created by the controller
+const scalingOperator = "system"
+
+// Controller executes the scheduled scaling rules and scales the idle components to zero.
+// The replicas are saved to db and scaled by the horizontal_scaling task of the worker, the
+// same as the manual scaling.
+type Controller struct {
+	store         store.Storer
+	dbmanager     db.Manager
+	mqclient      client.MQClient
+	prometheusCli prometheus.Interface
+}
+
+// NewController creates the scaling controller
+func NewController(store store.Storer, mqAPI, prometheusEndpoint string) *Controller {
+	mqclient, err := client.NewMqClient(mqAPI)
+	if err != nil {
+		logrus.Warningf("create mq client failure, the scheduled scaling can not be executed: %v", err)
+		mqclient = nil
+	}
+	prometheusCli, err := prometheus.NewPrometheus(&prometheus.Options{
+		Endpoint: prometheusEndpoint,
+	})
+	if err != nil {
+		logrus.Warningf("create prometheus client failure, the idle components can not be detected: %v", err)
+		prometheusCli = nil
+	}
+	return &Controller{
+		store:         store,
+		dbmanager:     db.GetManager(),
+		mqclient:      mqclient,
+		prometheusCli: prometheusCli,
+	}
+}
+
+// Start executes the scheduled rules and detects the idle components until the context is done,
+// it should only run on the leader.
+func (c *Controller) Start(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.runScheduledRules(now)
+			c.sleepIdleComponents(now)
+		}
+	}
+}
+
+// WakeOnRequest wakes up the sleeping component requested by the gateway
+func (c *Controller) WakeOnRequest(serviceID string) error {
+	setting, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(serviceID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	if !setting.IdleEnable {
+		return nil
+	}
+	return c.wake(setting, dbmodel.ScalingRecordTypeWake, "")
+}
+
+func (c *Controller) runScheduledRules(now time.Time) {
+	rules, err := c.dbmanager.TenantEnvServiceScheduledScalingRuleDao().ListEnableOnes()
+	if err != nil {
+		logrus.Errorf("list scheduled scaling rules: %v", err)
+		return
+	}
+	dues, errs := dueRules(rules, now)
+	for ruleID, err := range errs {
+		logrus.Warningf("scheduled scaling rule %s: %v", ruleID, err)
+	}
+	for _, due := range dues {
+		rule := due.rule
+		// save the schedule time first, the rule is not executed twice if the worker fails
+		rule.LastScheduleTime = &now
+		if err := c.dbmanager.TenantEnvServiceScheduledScalingRuleDao().UpdateModel(rule); err != nil {
+			logrus.Errorf("update the schedule time of scheduled scaling rule %s: %v", rule.RuleID, err)
+			continue
+		}
+		for _, service := range c.ruleComponents(rule) {
+			if err := c.applyRule(rule, service); err != nil {
+				logrus.Errorf("apply scheduled scaling rule %s to component %s: %v", rule.RuleID, service.ServiceID, err)
+			}
+		}
+	}
+}
+
+// ruleComponents returns the running components the rule applies to
+func (c *Controller) ruleComponents(rule *dbmodel.TenantEnvServiceScheduledScalingRule) []*dbmodel.TenantEnvServices {
+	var services []*dbmodel.TenantEnvServices
+	if rule.ServiceID != "" {
+		service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(rule.ServiceID)
+		if err != nil {
+			logrus.Warningf("get component %s of scheduled scaling rule %s: %v", rule.ServiceID, rule.RuleID, err)
+			return nil
+		}
+		services = append(services, service)
+	} else {
+		var err error
+		services, err = c.dbmanager.TenantEnvServiceDao().GetServicesByTenantEnvID(rule.TenantEnvID)
+		if err != nil {
+			logrus.Warningf("list components of scheduled scaling rule %s: %v", rule.RuleID, err)
+			return nil
+		}
+	}
+	var running []*dbmodel.TenantEnvServices
+	for _, service := range services {
+		if service.Kind == dbmodel.ServiceKindThirdParty.String() {
+			continue
+		}
+		if app := c.store.GetAppService(service.ServiceID); app == nil || app.IsClosed() {
+			continue
+		}
+		running = append(running, service)
+	}
+	return running
+}
+
+func (c *Controller) applyRule(rule *dbmodel.TenantEnvServiceScheduledScalingRule, service *dbmodel.TenantEnvServices) error {
+	switch rule.Action {
+	case dbmodel.ScheduledScalingActionSleep:
+		return c.sleep(service, dbmodel.ScalingRecordTypeSchedule, rule.RuleID)
+	case dbmodel.ScheduledScalingActionWake:
+		setting, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(service.ServiceID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+		return c.wake(setting, dbmodel.ScalingRecordTypeSchedule, rule.RuleID)
+	case dbmodel.ScheduledScalingActionScale:
+		setting, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(service.ServiceID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		if err == nil && setting.Sleeping && rule.Replicas > 0 {
+			// the component is woken up by the rule
+			now := time.Now()
+			setting.Sleeping = false
+			setting.WakeTime = &now
+			if _, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().SwitchSleeping(setting); err != nil {
+				return err
+			}
+		}
+		return c.scale(service, rule.Replicas, dbmodel.ScalingRecordTypeSchedule, rule.RuleID)
+	default:
+		return fmt.Errorf("unknown action %s", rule.Action)
+	}
+}
+
+func (c *Controller) sleepIdleComponents(now time.Time) {
+	if c.prometheusCli == nil {
+		return
+	}
+	settings, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().ListIdleEnableOnes()
+	if err != nil {
+		logrus.Errorf("list scale-to-zero settings: %v", err)
+		return
+	}
+	for _, setting := range settings {
+		if setting.IdleTimeout <= 0 {
+			continue
+		}
+		if setting.Sleeping {
+			c.checkManualWake(setting, now)
+			continue
+		}
+		idleTimeout := time.Duration(setting.IdleTimeout) * time.Second
+		// give the component woken up recently a whole idle timeout to receive the requests
+		if setting.WakeTime != nil && now.Sub(*setting.WakeTime) < idleTimeout {
+			continue
+		}
+		if app := c.store.GetAppService(setting.ServiceID); app == nil || app.IsClosed() {
+			continue
+		}
+		query := fmt.Sprintf(`sum(increase(gateway_requests{service_id="%s"}[%ds]))`, setting.ServiceID, setting.IdleTimeout)
+		count, ok := requestCount(c.prometheusCli.GetMetric(query, now))
+		if !ok || count > 0 {
+			continue
+		}
+		service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(setting.ServiceID)
+		if err != nil {
+			logrus.Warningf("get idle component %s: %v", setting.ServiceID, err)
+			continue
+		}
+		logrus.Infof("component %s has no request in %d seconds, scale it to zero", setting.ServiceID, setting.IdleTimeout)
+		if err := c.sleep(service, dbmodel.ScalingRecordTypeIdle, ""); err != nil {
+			logrus.Errorf("scale idle component %s to zero: %v", setting.ServiceID, err)
+		}
+	}
+}
+
+// checkManualWake clears the sleep state of the component scaled up manually, so that it can be
+// detected as idle again.
+func (c *Controller) checkManualWake(setting *dbmodel.TenantEnvServiceScaleToZero, now time.Time) {
+	service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(setting.ServiceID)
+	if err != nil || service.Replicas == 0 {
+		return
+	}
+	setting.Sleeping = false
+	setting.WakeTime = &now
+	if _, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().SwitchSleeping(setting); err != nil {
+		logrus.Warningf("clear the sleep state of component %s: %v", setting.ServiceID, err)
+	}
+}
+
+// sleep scales the component to zero and keeps the current replicas to wake up
+func (c *Controller) sleep(service *dbmodel.TenantEnvServices, recordType, ruleID string) error {
+	if service.Replicas == 0 {
+		return nil
+	}
+	setting, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().GetByServiceID(service.ServiceID)
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return err
+		}
+		setting = &dbmodel.TenantEnvServiceScaleToZero{
+			TenantEnvID: service.TenantEnvID,
+			ServiceID:   service.ServiceID,
+		}
+		if err := c.dbmanager.TenantEnvServiceScaleToZeroDao().AddModel(setting); err != nil {
+			return err
+		}
+	}
+	if setting.Sleeping {
+		return nil
+	}
+	now := time.Now()
+	setting.Sleeping = true
+	setting.SleepReplicas = service.Replicas
+	setting.SleepTime = &now
+	switched, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().SwitchSleeping(setting)
+	if err != nil || !switched {
+		return err
+	}
+	return c.scale(service, 0, recordType, ruleID)
+}
+
+// wake restores the replicas of the sleeping component
+func (c *Controller) wake(setting *dbmodel.TenantEnvServiceScaleToZero, recordType, ruleID string) error {
+	if !setting.Sleeping {
+		return nil
+	}
+	service, err := c.dbmanager.TenantEnvServiceDao().GetServiceByID(setting.ServiceID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	setting.Sleeping = false
+	setting.WakeTime = &now
+	switched, err := c.dbmanager.TenantEnvServiceScaleToZeroDao().SwitchSleeping(setting)
+	if err != nil || !switched {
+		return err
+	}
+	replicas := setting.SleepReplicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	return c.scale(service, replicas, recordType, ruleID)
+}
+
+// scale saves the replicas and sends the horizontal_scaling task to the worker
+func (c *Controller) scale(service *dbmodel.TenantEnvServices, replicas int, recordType, ruleID string) error {
+	if service.Replicas == replicas {
+		return nil
+	}
+	if c.mqclient == nil {
+		return fmt.Errorf("mq client is not ready")
+	}
+	oldReplicas := service.Replicas
+	service.Replicas = replicas
+	if err := c.dbmanager.TenantEnvServiceDao().UpdateModel(service); err != nil {
+		return err
+	}
+	err := c.mqclient.SendBuilderTopic(client.TaskStruct{
+		TaskType: "horizontal_scaling",
+		TaskBody: model.HorizontalScalingTaskBody{
+			TenantEnvID: service.TenantEnvID,
+			ServiceID:   service.ServiceID,
+			Replicas:    int32(replicas),
+			EventID:     util.NewUUID(),
+			Username:    scalingOperator,
+			RecordType:  recordType,
+			RuleID:      ruleID,
+		},
+		Topic: client.WorkerTopic,
+	})
+	if err != nil {
+		// roll back the replicas
+		service.Replicas = oldReplicas
+		if err := c.dbmanager.TenantEnvServiceDao().UpdateModel(service); err != nil {
+			logrus.Errorf("roll back the replicas of component %s: %v", service.ServiceID, err)
+		}
+		return err
+	}
+	logrus.Infof("component %s is scaling from %d to %d by %s", service.ServiceID, oldReplicas, replicas, recordType)
+	return nil
+}
@@ -0,0 +1,98 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package scaling
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+)
+
+// maxMissedSchedules limits the activations walked through to find the latest missed one
+const maxMissedSchedules = 100000
+
+// dueTime returns the latest activation of the rule which is not executed yet. The missed
+// activations, eg: the worker is down, are skipped except the latest one, so that the
+// components end up with the state of the latest activation.
+func dueTime(rule *dbmodel.TenantEnvServiceScheduledScalingRule, now time.Time) (time.Time, bool, error) {
+	schedule, err := rule.ParseSchedule()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	last := rule.CreatedAt
+	if rule.LastScheduleTime != nil {
+		last = *rule.LastScheduleTime
+	}
+	var due time.Time
+	for i, next := 0, schedule.Next(last); !next.IsZero() && !next.After(now) && i < maxMissedSchedules; i++ {
+		due = next
+		next = schedule.Next(next)
+	}
+	return due, !due.IsZero(), nil
+}
+
+type dueRule struct {
+	rule *dbmodel.TenantEnvServiceScheduledScalingRule
+	due  time.Time
+}
+
+// dueRules returns the rules to execute ordered by the activation time
+func dueRules(rules []*dbmodel.TenantEnvServiceScheduledScalingRule, now time.Time) ([]dueRule, map[string]error) {
+	var dues []dueRule
+	errs := make(map[string]error)
+	for _, rule := range rules {
+		due, ok, err := dueTime(rule, now)
+		if err != nil {
+			errs[rule.RuleID] = err
+			continue
+		}
+		if ok {
+			dues = append(dues, dueRule{rule: rule, due: due})
+		}
+	}
+	sort.SliceStable(dues, func(i, j int) bool {
+		return dues[i].due.Before(dues[j].due)
+	})
+	return dues, errs
+}
+
+// requestCount sums the samples of the gateway requests query, false is returned if the
+// query fails so that the component is not put to sleep by mistake.
+func requestCount(metric prometheus.Metric) (float64, bool) {
+	if metric.Error != "" {
+		return 0, false
+	}
+	var count float64
+	for _, v := range metric.MetricValues {
+		var value float64
+		if v.Sample != nil {
+			value = v.Sample.Value()
+		} else if len(v.Series) > 0 {
+			value = v.Series[len(v.Series)-1].Value()
+		}
+		if math.IsNaN(value) {
+			continue
+		}
+		count += value
+	}
+	return count, true
+}
@@ -0,0 +1,167 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2014-2017 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package scaling
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	dbmodel "github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/prometheus"
+)
+
+func TestDueTime(t *testing.T) {
+	created := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2023, 5, 3, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rule    *dbmodel.TenantEnvServiceScheduledScalingRule
+		now     time.Time
+		want    time.Time
+		wantOk  bool
+		wantErr bool
+	}{
+		{
+			name:   "not due",
+			rule:   &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 20 * * *", TimeZone: "UTC"},
+			now:    time.Date(2023, 5, 1, 19, 59, 0, 0, time.UTC),
+			wantOk: false,
+		},
+		{
+			name:   "due",
+			rule:   &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 20 * * *", TimeZone: "UTC"},
+			now:    time.Date(2023, 5, 1, 20, 0, 30, 0, time.UTC),
+			want:   time.Date(2023, 5, 1, 20, 0, 0, 0, time.UTC),
+			wantOk: true,
+		},
+		{
+			name:   "latest missed activation",
+			rule:   &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 20 * * *", TimeZone: "UTC"},
+			now:    time.Date(2023, 5, 5, 8, 0, 0, 0, time.UTC),
+			want:   time.Date(2023, 5, 4, 20, 0, 0, 0, time.UTC),
+			wantOk: true,
+		},
+		{
+			name:   "executed",
+			rule:   &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 20 * * *", TimeZone: "UTC", LastScheduleTime: &last},
+			now:    time.Date(2023, 5, 4, 8, 0, 0, 0, time.UTC),
+			wantOk: false,
+		},
+		{
+			name:   "time zone",
+			rule:   &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 8 * * *", TimeZone: "Asia/Shanghai"},
+			now:    time.Date(2023, 5, 2, 0, 30, 0, 0, time.UTC),
+			want:   time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC),
+			wantOk: true,
+		},
+		{
+			name:    "invalid schedule",
+			rule:    &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 25 * * *"},
+			now:     created,
+			wantErr: true,
+		},
+		{
+			name:    "invalid time zone",
+			rule:    &dbmodel.TenantEnvServiceScheduledScalingRule{Schedule: "0 20 * * *", TimeZone: "Mars/Base"},
+			now:     created,
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.rule.CreatedAt = created
+			due, ok, err := dueTime(tc.rule, tc.now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("dueTime() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if ok != tc.wantOk || !due.Equal(tc.want) {
+				t.Errorf("dueTime() = %v, %v; want %v, %v", due, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
+
+func TestDueRules(t *testing.T) {
+	created := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	rules := []*dbmodel.TenantEnvServiceScheduledScalingRule{
+		{RuleID: "wake", Schedule: "0 8 * * *", TimeZone: "UTC"},
+		{RuleID: "sleep", Schedule: "0 6 * * *", TimeZone: "UTC"},
+		{RuleID: "later", Schedule: "0 12 * * *", TimeZone: "UTC"},
+		{RuleID: "invalid", Schedule: "invalid"},
+	}
+	for _, rule := range rules {
+		rule.CreatedAt = created
+	}
+	dues, errs := dueRules(rules, time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC))
+	if len(errs) != 1 || errs["invalid"] == nil {
+		t.Errorf("dueRules() errs = %v; want the error of rule invalid", errs)
+	}
+	var got []string
+	for _, due := range dues {
+		got = append(got, due.rule.RuleID)
+	}
+	if len(got) != 2 || got[0] != "sleep" || got[1] != "wake" {
+		t.Errorf("dueRules() = %v; want [sleep wake]", got)
+	}
+}
+
+func TestRequestCount(t *testing.T) {
+	sample := func(v float64) prometheus.MetricValue {
+		return prometheus.MetricValue{Sample: &prometheus.Point{1, v}}
+	}
+	tests := []struct {
+		name   string
+		metric prometheus.Metric
+		want   float64
+		wantOk bool
+	}{
+		{
+			name:   "no data",
+			metric: prometheus.Metric{},
+			wantOk: true,
+		},
+		{
+			name:   "samples",
+			metric: prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{sample(2), sample(math.NaN()), sample(3)}}},
+			want:   5,
+			wantOk: true,
+		},
+		{
+			name: "series",
+			metric: prometheus.Metric{MetricData: prometheus.MetricData{MetricValues: []prometheus.MetricValue{
+				{Series: []prometheus.Point{{1, 4}, {2, 1}}},
+			}}},
+			want:   1,
+			wantOk: true,
+		},
+		{
+			name:   "query error",
+			metric: prometheus.Metric{Error: "bad query"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			count, ok := requestCount(tc.metric)
+			if ok != tc.wantOk || count != tc.want {
+				t.Errorf("requestCount() = %v, %v; want %v, %v", count, ok, tc.want, tc.wantOk)
+			}
+		})
+	}
+}
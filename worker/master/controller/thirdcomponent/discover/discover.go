@@ -57,6 +57,17 @@ func NewDiscover(component *v1alpha1.ThirdComponent,
 			client:    clientset,
 		}, nil
 	}
+	if component.Spec.EndpointSource.DNS != nil {
+		return newDNSEndpoint(component)
+	}
+	if component.Spec.EndpointSource.Registry != nil {
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			logrus.Errorf("create kube client error: %s", err.Error())
+			return nil, err
+		}
+		return newRegistryDiscover(component, clientset)
+	}
 	if len(component.Spec.EndpointSource.StaticEndpoints) > 0 {
 		return &staticEndpoint{
 			component: component,
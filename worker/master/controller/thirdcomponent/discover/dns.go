@@ -0,0 +1,206 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2021-2021 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
+	"github.com/wutong-paas/wutong/worker/master/controller/thirdcomponent/prober"
+	"github.com/wutong-paas/wutong/worker/master/controller/thirdcomponent/prober/results"
+)
+
+// dnsEndpoint discovers the endpoints by resolving the dns records on a schedule
+type dnsEndpoint struct {
+	component *v1alpha1.ThirdComponent
+	resolver  *net.Resolver
+	// endpoints the endpoints sent last time, the status of the component is not refreshed
+	endpoints []*v1alpha1.ThirdComponentEndpointStatus
+
+	pmlock        sync.Mutex
+	proberManager prober.Manager
+}
+
+func newDNSEndpoint(component *v1alpha1.ThirdComponent) (Discover, error) {
+	source := component.Spec.EndpointSource.DNS
+	if source.Hostname == "" {
+		return nil, fmt.Errorf("the hostname of dns source is required")
+	}
+	switch source.GetType() {
+	case v1alpha1.DNSRecordTypeA, v1alpha1.DNSRecordTypeAAAA, v1alpha1.DNSRecordTypeSRV:
+	default:
+		return nil, fmt.Errorf("not support dns record type %s", source.Type)
+	}
+	return &dnsEndpoint{
+		component: component,
+		resolver:  newResolver(source.Resolver),
+		endpoints: component.Status.Endpoints,
+	}, nil
+}
+
+// newResolver creates a resolver which sends the queries to the given dns server
+func newResolver(server string) *net.Resolver {
+	if server == "" {
+		return net.DefaultResolver
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+func (d *dnsEndpoint) GetComponent() *v1alpha1.ThirdComponent {
+	return d.component
+}
+
+func (d *dnsEndpoint) Discover(ctx context.Context, update chan *v1alpha1.ThirdComponent) ([]*v1alpha1.ThirdComponentEndpointStatus, error) {
+	ticker := time.NewTicker(d.component.Spec.EndpointSource.DNS.GetRefreshInterval())
+	defer ticker.Stop()
+	var probeUpdates <-chan results.Update
+	if proberManager := d.getProberManager(); proberManager != nil {
+		probeUpdates = proberManager.Updates()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-ticker.C:
+			d.discoverOne(ctx, update)
+		case <-probeUpdates:
+			d.discoverOne(ctx, update)
+		}
+	}
+}
+
+func (d *dnsEndpoint) DiscoverOne(ctx context.Context) ([]*v1alpha1.ThirdComponentEndpointStatus, error) {
+	addresses, err := d.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []*v1alpha1.ThirdComponentEndpointStatus
+	for _, address := range addresses {
+		endpoints = append(endpoints, &v1alpha1.ThirdComponentEndpointStatus{
+			Address: address,
+			Name:    d.component.Spec.EndpointSource.DNS.Hostname,
+			// Make ready as the default status
+			Status: v1alpha1.EndpointReady,
+		})
+	}
+
+	// Update status with probe result
+	proberManager := d.getProberManager()
+	if proberManager != nil && d.component.Spec.NeedProbe() {
+		for _, ep := range endpoints {
+			result, found := proberManager.GetResult(d.component.GetEndpointID(ep))
+			if !found {
+				// NotReady means the endpoint should not be online.
+				ep.Status = v1alpha1.EndpointNotReady
+			} else if result != results.Success {
+				ep.Status = v1alpha1.EndpointUnhealthy
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// resolve returns the sorted addresses of the dns records
+func (d *dnsEndpoint) resolve(ctx context.Context) ([]v1alpha1.EndpointAddress, error) {
+	source := d.component.Spec.EndpointSource.DNS
+	var addresses []v1alpha1.EndpointAddress
+	switch source.GetType() {
+	case v1alpha1.DNSRecordTypeSRV:
+		_, srvs, err := d.resolver.LookupSRV(ctx, "", "", source.Hostname)
+		if err != nil {
+			return nil, fmt.Errorf("lookup srv records of %s failure %s", source.Hostname, err.Error())
+		}
+		for _, srv := range srvs {
+			ips, err := d.resolver.LookupIPAddr(ctx, srv.Target)
+			if err != nil {
+				logrus.Warningf("lookup addresses of srv target %s failure %s", srv.Target, err.Error())
+				continue
+			}
+			for _, ip := range ips {
+				if address := v1alpha1.NewEndpointAddress(ip.IP.String(), int(srv.Port)); address != nil {
+					addresses = append(addresses, *address)
+				}
+			}
+		}
+	default:
+		network := "ip4"
+		if source.GetType() == v1alpha1.DNSRecordTypeAAAA {
+			network = "ip6"
+		}
+		ips, err := d.resolver.LookupIP(ctx, network, source.Hostname)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s records of %s failure %s", source.GetType(), source.Hostname, err.Error())
+		}
+		for _, ip := range ips {
+			for _, port := range d.component.Spec.Ports {
+				if address := v1alpha1.NewEndpointAddress(ip.String(), port.Port); address != nil {
+					addresses = append(addresses, *address)
+				}
+			}
+		}
+	}
+	// the order of the records is random, sort them to compare with the status
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i] < addresses[j] })
+	return addresses, nil
+}
+
+func (d *dnsEndpoint) SetProberManager(proberManager prober.Manager) {
+	d.pmlock.Lock()
+	defer d.pmlock.Unlock()
+	d.proberManager = proberManager
+}
+
+func (d *dnsEndpoint) getProberManager() prober.Manager {
+	d.pmlock.Lock()
+	defer d.pmlock.Unlock()
+	return d.proberManager
+}
+
+func (d *dnsEndpoint) discoverOne(ctx context.Context, update chan *v1alpha1.ThirdComponent) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	endpoints, err := d.DiscoverOne(ctx)
+	if err != nil {
+		// keep the endpoints resolved last time
+		logrus.Errorf("discover dns endpoints %s failure %s", d.component.Spec.EndpointSource.DNS.Hostname, err.Error())
+		return
+	}
+	if !reflect.DeepEqual(endpoints, d.endpoints) {
+		newComponent := d.component.DeepCopy()
+		newComponent.Status.Endpoints = endpoints
+		update <- newComponent
+		d.endpoints = endpoints
+	}
+}
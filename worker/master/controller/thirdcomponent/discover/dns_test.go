@@ -0,0 +1,208 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2021-2021 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startFakeDNSServer serves the records on udp until the test finishes
+func startFakeDNSServer(t *testing.T, a map[string][]string, aaaa map[string][]string, srv map[string][]dnsmessage.SRVResource) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(buf[:n]); err != nil || len(req.Questions) == 0 {
+				continue
+			}
+			q := req.Questions[0]
+			res := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true},
+				Questions: req.Questions,
+			}
+			header := dnsmessage.ResourceHeader{Name: q.Name, Type: q.Type, Class: dnsmessage.ClassINET, TTL: 30}
+			name := q.Name.String()
+			switch q.Type {
+			case dnsmessage.TypeA:
+				for _, ip := range a[name] {
+					var body dnsmessage.AResource
+					copy(body.A[:], net.ParseIP(ip).To4())
+					res.Answers = append(res.Answers, dnsmessage.Resource{Header: header, Body: &body})
+				}
+			case dnsmessage.TypeAAAA:
+				for _, ip := range aaaa[name] {
+					var body dnsmessage.AAAAResource
+					copy(body.AAAA[:], net.ParseIP(ip).To16())
+					res.Answers = append(res.Answers, dnsmessage.Resource{Header: header, Body: &body})
+				}
+			case dnsmessage.TypeSRV:
+				for i := range srv[name] {
+					res.Answers = append(res.Answers, dnsmessage.Resource{Header: header, Body: &srv[name][i]})
+				}
+			}
+			if len(res.Answers) == 0 && a[name] == nil && aaaa[name] == nil && srv[name] == nil {
+				res.RCode = dnsmessage.RCodeNameError
+			}
+			packed, err := res.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(packed, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func newDNSComponent(source *v1alpha1.DNSSource, ports ...int) *v1alpha1.ThirdComponent {
+	component := &v1alpha1.ThirdComponent{}
+	component.Namespace = "ns"
+	component.Name = "db"
+	component.Spec.EndpointSource.DNS = source
+	for _, port := range ports {
+		component.Spec.Ports = append(component.Spec.Ports, &v1alpha1.ComponentPort{Port: port})
+	}
+	return component
+}
+
+func discoverAddresses(t *testing.T, component *v1alpha1.ThirdComponent) []string {
+	discover, err := newDNSEndpoint(component)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints, err := discover.DiscoverOne(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addresses []string
+	for _, ep := range endpoints {
+		if ep.Status != v1alpha1.EndpointReady {
+			t.Errorf("expected endpoint %s ready, got %s", ep.Address, ep.Status)
+		}
+		addresses = append(addresses, string(ep.Address))
+	}
+	return addresses
+}
+
+func TestDNSEndpoint_DiscoverOne(t *testing.T) {
+	resolver := startFakeDNSServer(t,
+		map[string][]string{
+			"db.example.com.":    {"10.0.0.2", "10.0.0.1"},
+			"node1.example.com.": {"10.0.1.1"},
+			"node2.example.com.": {"10.0.1.2"},
+		},
+		map[string][]string{
+			"db.example.com.": {"fd00::1"},
+		},
+		map[string][]dnsmessage.SRVResource{
+			"_pg._tcp.example.com.": {
+				{Priority: 10, Weight: 5, Port: 5432, Target: dnsmessage.MustNewName("node1.example.com.")},
+				{Priority: 10, Weight: 5, Port: 5433, Target: dnsmessage.MustNewName("node2.example.com.")},
+			},
+		})
+
+	tests := []struct {
+		name   string
+		source *v1alpha1.DNSSource
+		ports  []int
+		want   []string
+	}{
+		{
+			name:   "a records with component ports",
+			source: &v1alpha1.DNSSource{Hostname: "db.example.com", Resolver: resolver},
+			ports:  []int{5432, 8080},
+			want:   []string{"10.0.0.1:5432", "10.0.0.1:8080", "10.0.0.2:5432", "10.0.0.2:8080"},
+		},
+		{
+			name:   "aaaa records",
+			source: &v1alpha1.DNSSource{Hostname: "db.example.com", Type: "aaaa", Resolver: resolver},
+			ports:  []int{5432},
+			want:   []string{"fd00::1:5432"},
+		},
+		{
+			name:   "srv records",
+			source: &v1alpha1.DNSSource{Hostname: "_pg._tcp.example.com", Type: v1alpha1.DNSRecordTypeSRV, Resolver: resolver},
+			want:   []string{"10.0.1.1:5432", "10.0.1.2:5433"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := discoverAddresses(t, newDNSComponent(tc.source, tc.ports...))
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected addresses %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("expected addresses %v, got %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDNSEndpoint_DiscoverOneNotFound(t *testing.T) {
+	resolver := startFakeDNSServer(t, nil, nil, nil)
+	discover, err := newDNSEndpoint(newDNSComponent(&v1alpha1.DNSSource{Hostname: "missing.example.com", Resolver: resolver}, 80))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := discover.DiscoverOne(context.Background()); err == nil {
+		t.Error("expected error for the hostname without records")
+	}
+}
+
+func TestNewDNSEndpoint(t *testing.T) {
+	if _, err := newDNSEndpoint(newDNSComponent(&v1alpha1.DNSSource{Hostname: "db.example.com", Type: "MX"})); err == nil {
+		t.Error("expected error for the unsupported record type")
+	}
+	if _, err := newDNSEndpoint(newDNSComponent(&v1alpha1.DNSSource{})); err == nil {
+		t.Error("expected error for the empty hostname")
+	}
+}
+
+func TestDNSEndpoint_DiscoverOnlyChanged(t *testing.T) {
+	resolver := startFakeDNSServer(t, map[string][]string{"db.example.com.": {"10.0.0.1"}}, nil, nil)
+	discover, err := newDNSEndpoint(newDNSComponent(&v1alpha1.DNSSource{Hostname: "db.example.com", Resolver: resolver}, 5432))
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := make(chan *v1alpha1.ThirdComponent, 2)
+	for i := 0; i < 2; i++ {
+		discover.(*dnsEndpoint).discoverOne(context.Background(), update)
+	}
+	if len(update) != 1 {
+		t.Fatalf("expected the unchanged endpoints to be sent once, got %d updates", len(update))
+	}
+	if component := <-update; len(component.Status.Endpoints) != 1 || component.Status.Endpoints[0].Address != "10.0.0.1:5432" {
+		t.Errorf("unexpected endpoints %v", component.Status.Endpoints)
+	}
+}
@@ -0,0 +1,268 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2021-2021 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/eapache/channels"
+	"github.com/sirupsen/logrus"
+	"github.com/wutong-paas/wutong/db/model"
+	"github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
+	"github.com/wutong-paas/wutong/worker/appm/thirdparty/discovery"
+	"github.com/wutong-paas/wutong/worker/master/controller/thirdcomponent/prober"
+	"github.com/wutong-paas/wutong/worker/master/controller/thirdcomponent/prober/results"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// registryDiscover discovers the endpoints registered in consul, nacos or eureka
+type registryDiscover struct {
+	component     *v1alpha1.ThirdComponent
+	client        kubernetes.Interface
+	pmlock        sync.Mutex
+	proberManager prober.Manager
+}
+
+func newRegistryDiscover(component *v1alpha1.ThirdComponent, client kubernetes.Interface) (Discover, error) {
+	if !model.DiscorveryType(component.Spec.EndpointSource.Registry.Type).IsRegistry() {
+		return nil, fmt.Errorf("not support registry type %s", component.Spec.EndpointSource.Registry.Type)
+	}
+	return &registryDiscover{component: component, client: client}, nil
+}
+
+func (r *registryDiscover) GetComponent() *v1alpha1.ThirdComponent {
+	return r.component
+}
+
+// registryClient the connected discoverier of a component, which is watched and fetched
+// until the registry spec or the password secret changes
+type registryClient struct {
+	// key the registry spec and the resource version of the password secret
+	key         string
+	discoverier discovery.Discoverier
+	updateCh    *channels.RingChannel
+	// stopCh is closed when the client is replaced or removed
+	stopCh chan struct{}
+}
+
+func (c *registryClient) close() {
+	close(c.stopCh)
+	if err := c.discoverier.Close(); err != nil {
+		logrus.Warningf("close registry discoverier failure %s", err.Error())
+	}
+}
+
+// registryClients the clients of the components, the discovers of a component are created
+// on every reconcile, they share the client instead of connecting the registry every time
+var registryClients = struct {
+	sync.Mutex
+	clients map[string]*registryClient
+}{clients: make(map[string]*registryClient)}
+
+// RemoveRegistryClient closes the registry client of the component
+func RemoveRegistryClient(namespace, name string) {
+	registryClients.Lock()
+	defer registryClients.Unlock()
+	if c, ok := registryClients.clients[namespace+"/"+name]; ok {
+		c.close()
+		delete(registryClients.clients, namespace+"/"+name)
+	}
+}
+
+// currentClient returns the client of the component, nil if it is removed
+func (r *registryDiscover) currentClient() *registryClient {
+	registryClients.Lock()
+	defer registryClients.Unlock()
+	return registryClients.clients[r.component.Namespace+"/"+r.component.Name]
+}
+
+// password reads the password of the registry and the resource version of the referenced secret
+func (r *registryDiscover) password(ctx context.Context) (string, string, error) {
+	ref := r.component.Spec.EndpointSource.Registry.PasswordSecretRef
+	if ref == nil {
+		return "", "", nil
+	}
+	secret, err := r.client.CoreV1().Secrets(r.component.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) && ref.Optional != nil && *ref.Optional {
+			return "", "", nil
+		}
+		return "", "", fmt.Errorf("get registry password secret %s failure %s", ref.Name, err.Error())
+	}
+	password, ok := secret.Data[ref.Key]
+	if !ok && (ref.Optional == nil || !*ref.Optional) {
+		return "", "", fmt.Errorf("key %s not found in registry password secret %s", ref.Key, ref.Name)
+	}
+	return string(password), secret.ResourceVersion, nil
+}
+
+// getClient returns the client of the component, it is created again only if the registry
+// spec or the password secret is changed
+func (r *registryDiscover) getClient(ctx context.Context) (*registryClient, error) {
+	registry := r.component.Spec.EndpointSource.Registry
+	password, version, err := r.password(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := json.Marshal(registry)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s/%s/%s", spec, r.component.Labels["service_id"], version)
+	name := r.component.Namespace + "/" + r.component.Name
+
+	registryClients.Lock()
+	defer registryClients.Unlock()
+	if c, ok := registryClients.clients[name]; ok {
+		if c.key == key {
+			return c, nil
+		}
+		c.close()
+		delete(registryClients.clients, name)
+	}
+	c := &registryClient{key: key, updateCh: channels.NewRingChannel(1024), stopCh: make(chan struct{})}
+	c.discoverier, err = discovery.NewDiscoverier(&model.ThirdPartySvcDiscoveryCfg{
+		ServiceID:   r.component.Labels["service_id"],
+		Type:        registry.Type,
+		Servers:     registry.Servers,
+		ServiceName: registry.ServiceName,
+		Namespace:   registry.Namespace,
+		Key:         registry.Group,
+		Username:    registry.Username,
+		Password:    password,
+	}, c.updateCh, c.stopCh)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.discoverier.Connect(); err != nil {
+		return nil, fmt.Errorf("connect %s failure %s", registry.Type, err.Error())
+	}
+	go c.discoverier.Watch()
+	registryClients.clients[name] = c
+	return c, nil
+}
+
+func (r *registryDiscover) Discover(ctx context.Context, update chan *v1alpha1.ThirdComponent) ([]*v1alpha1.ThirdComponentEndpointStatus, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var probeUpdates <-chan results.Update
+	if proberManager := r.getProberManager(); proberManager != nil {
+		probeUpdates = proberManager.Updates()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-c.stopCh:
+			// the client is replaced by the reconcile of the new spec, or removed with the component
+			if c = r.currentClient(); c == nil {
+				return nil, nil
+			}
+			r.discoverOne(ctx, c, update)
+		case <-c.updateCh.Out():
+			// the events are merged, the endpoints are fetched again
+			r.discoverOne(ctx, c, update)
+		case <-probeUpdates:
+			r.discoverOne(ctx, c, update)
+		}
+	}
+}
+
+func (r *registryDiscover) discoverOne(ctx context.Context, c *registryClient, update chan *v1alpha1.ThirdComponent) {
+	endpoints, err := r.fetch(c)
+	if err != nil {
+		logrus.Errorf("discover %s endpoints %s change failure %s", r.component.Spec.EndpointSource.Registry.Type, r.component.Spec.EndpointSource.Registry.ServiceName, err.Error())
+		return
+	}
+	new := r.component.DeepCopy()
+	new.Status.Endpoints = endpoints
+	select {
+	case update <- new:
+	case <-ctx.Done():
+	}
+}
+
+func (r *registryDiscover) DiscoverOne(ctx context.Context) ([]*v1alpha1.ThirdComponentEndpointStatus, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.fetch(c)
+}
+
+// fetch returns the endpoints in the registry with the probe results
+func (r *registryDiscover) fetch(c *registryClient) ([]*v1alpha1.ThirdComponentEndpointStatus, error) {
+	eps, err := c.discoverier.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	var es = []*v1alpha1.ThirdComponentEndpointStatus{}
+	for _, ep := range eps {
+		address := v1alpha1.NewEndpointAddress(ep.IP, ep.Port)
+		if address == nil {
+			continue
+		}
+		status := v1alpha1.EndpointReady
+		if !ep.IsOnline {
+			status = v1alpha1.EndpointNotReady
+		}
+		es = append(es, &v1alpha1.ThirdComponentEndpointStatus{
+			Address: *address,
+			Name:    ep.UUID,
+			Status:  status,
+		})
+	}
+
+	// Update status of the online endpoints with probe result
+	proberManager := r.getProberManager()
+	if proberManager != nil && r.component.Spec.NeedProbe() {
+		for _, ep := range es {
+			if ep.Status != v1alpha1.EndpointReady {
+				continue
+			}
+			result, found := proberManager.GetResult(r.component.GetEndpointID(ep))
+			if !found {
+				// NotReady means the endpoint should not be online.
+				ep.Status = v1alpha1.EndpointNotReady
+			} else if result != results.Success {
+				ep.Status = v1alpha1.EndpointUnhealthy
+			}
+		}
+	}
+	return es, nil
+}
+
+func (r *registryDiscover) SetProberManager(proberManager prober.Manager) {
+	r.pmlock.Lock()
+	defer r.pmlock.Unlock()
+	r.proberManager = proberManager
+}
+
+func (r *registryDiscover) getProberManager() prober.Manager {
+	r.pmlock.Lock()
+	defer r.pmlock.Unlock()
+	return r.proberManager
+}
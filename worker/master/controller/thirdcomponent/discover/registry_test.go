@@ -0,0 +1,217 @@
+// WUTONG, Application Management Platform
+// Copyright (C) 2021-2021 Wutong Co., Ltd.
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wutong-paas/wutong/pkg/apis/wutong/v1alpha1"
+	v1 "github.com/wutong-paas/wutong/worker/appm/types/v1"
+	"github.com/wutong-paas/wutong/worker/master/controller/thirdcomponent/prober/results"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRegistryDiscoverPassword(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "consul", Namespace: "ns"},
+		Data:       map[string][]byte{"password": []byte("token")},
+	})
+	newComponent := func(ref *corev1.SecretKeySelector) *v1alpha1.ThirdComponent {
+		return &v1alpha1.ThirdComponent{
+			ObjectMeta: metav1.ObjectMeta{Name: "component", Namespace: "ns"},
+			Spec: v1alpha1.ThirdComponentSpec{
+				EndpointSource: v1alpha1.ThirdComponentEndpointSource{
+					Registry: &v1alpha1.RegistrySource{Type: "consul", PasswordSecretRef: ref},
+				},
+			},
+		}
+	}
+	optional := true
+	tests := []struct {
+		name     string
+		ref      *corev1.SecretKeySelector
+		password string
+		wantErr  bool
+	}{
+		{name: "no reference"},
+		{
+			name:     "referenced key",
+			ref:      &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "consul"}, Key: "password"},
+			password: "token",
+		},
+		{
+			name:    "missing key",
+			ref:     &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "consul"}, Key: "token"},
+			wantErr: true,
+		},
+		{
+			name:    "missing secret",
+			ref:     &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "nacos"}, Key: "password"},
+			wantErr: true,
+		},
+		{
+			name: "optional missing secret",
+			ref:  &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "nacos"}, Key: "password", Optional: &optional},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := newRegistryDiscover(newComponent(tc.ref), client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			password, _, err := d.(*registryDiscover).password(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("want error %v, got %v", tc.wantErr, err)
+			}
+			if password != tc.password {
+				t.Errorf("want password %q, got %q", tc.password, password)
+			}
+		})
+	}
+}
+
+func TestRegistryDiscoverClientCache(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "consul", Namespace: "ns", ResourceVersion: "1"},
+		Data:       map[string][]byte{"password": []byte("token")},
+	})
+	component := &v1alpha1.ThirdComponent{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "ns"},
+		Spec: v1alpha1.ThirdComponentSpec{
+			EndpointSource: v1alpha1.ThirdComponentEndpointSource{
+				Registry: &v1alpha1.RegistrySource{
+					Type:              "consul",
+					Servers:           "127.0.0.1:1",
+					ServiceName:       "api",
+					PasswordSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "consul"}, Key: "password"},
+				},
+			},
+		},
+	}
+	defer RemoveRegistryClient("ns", "cache")
+	getClient := func(component *v1alpha1.ThirdComponent) *registryClient {
+		d, err := newRegistryDiscover(component, client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := d.(*registryDiscover).getClient(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	first := getClient(component)
+	if c := getClient(component.DeepCopy()); c != first {
+		t.Fatal("the client should be reused by the discover of the same spec")
+	}
+
+	secret, _ := client.CoreV1().Secrets("ns").Get(context.Background(), "consul", metav1.GetOptions{})
+	secret.ResourceVersion = "2"
+	secret.Data["password"] = []byte("new-token")
+	if _, err := client.CoreV1().Secrets("ns").Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	second := getClient(component)
+	if second == first {
+		t.Fatal("the client should be created again after the secret is changed")
+	}
+	select {
+	case <-first.stopCh:
+	default:
+		t.Error("the replaced client should be closed")
+	}
+
+	changed := component.DeepCopy()
+	changed.Spec.EndpointSource.Registry.ServiceName = "web"
+	if c := getClient(changed); c == second {
+		t.Fatal("the client should be created again after the spec is changed")
+	}
+}
+
+type fakeDiscoverier struct {
+	endpoints []*v1.WtEndpoint
+}
+
+func (f *fakeDiscoverier) Connect() error                   { return nil }
+func (f *fakeDiscoverier) Close() error                     { return nil }
+func (f *fakeDiscoverier) Fetch() ([]*v1.WtEndpoint, error) { return f.endpoints, nil }
+func (f *fakeDiscoverier) Watch()                           {}
+
+type fakeProberManager struct {
+	results map[string]results.Result
+}
+
+func (f *fakeProberManager) AddThirdComponent(*v1alpha1.ThirdComponent) {}
+func (f *fakeProberManager) Stop()                                      {}
+func (f *fakeProberManager) Updates() <-chan results.Update             { return nil }
+func (f *fakeProberManager) GetResult(endpointID string) (results.Result, bool) {
+	result, ok := f.results[endpointID]
+	return result, ok
+}
+
+func TestRegistryDiscoverProbe(t *testing.T) {
+	component := &v1alpha1.ThirdComponent{
+		ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: "ns"},
+		Spec: v1alpha1.ThirdComponentSpec{
+			Probe: &v1alpha1.Probe{},
+			EndpointSource: v1alpha1.ThirdComponentEndpointSource{
+				Registry: &v1alpha1.RegistrySource{Type: "consul", Servers: "127.0.0.1:8500", ServiceName: "api"},
+			},
+		},
+	}
+	if !component.Spec.NeedProbe() {
+		t.Fatal("the endpoints of the registry should be probed")
+	}
+	d, err := newRegistryDiscover(component, fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetProberManager(&fakeProberManager{results: map[string]results.Result{
+		"ns/probe/10.0.0.1:8080": results.Success,
+		"ns/probe/10.0.0.2:8080": results.Failure,
+	}})
+	c := &registryClient{discoverier: &fakeDiscoverier{endpoints: []*v1.WtEndpoint{
+		{IP: "10.0.0.1", Port: 8080, IsOnline: true},
+		{IP: "10.0.0.2", Port: 8080, IsOnline: true},
+		{IP: "10.0.0.3", Port: 8080, IsOnline: true},
+		{IP: "10.0.0.4", Port: 8080},
+	}}}
+	endpoints, err := d.(*registryDiscover).fetch(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]v1alpha1.EndpointStatus{
+		"10.0.0.1:8080": v1alpha1.EndpointReady,
+		"10.0.0.2:8080": v1alpha1.EndpointUnhealthy,
+		"10.0.0.3:8080": v1alpha1.EndpointNotReady,
+		"10.0.0.4:8080": v1alpha1.EndpointNotReady,
+	}
+	if len(endpoints) != len(want) {
+		t.Fatalf("want %d endpoints, got %d", len(want), len(endpoints))
+	}
+	for _, ep := range endpoints {
+		if ep.Status != want[string(ep.Address)] {
+			t.Errorf("%s: want status %s, got %s", ep.Address, want[string(ep.Address)], ep.Status)
+		}
+	}
+}
@@ -108,7 +108,7 @@ func (d *DiscoverPool) newWorker(dis dis.Discover) *Worker {
 	}
 
 	component := dis.GetComponent()
-	if component.Spec.IsStaticEndpoints() {
+	if component.Spec.IsProbeEndpoints() {
 		proberManager := prober.NewManager(d.recorder)
 		dis.SetProberManager(proberManager)
 		worker.proberManager = proberManager
@@ -135,7 +135,7 @@ func (d *DiscoverPool) AddDiscover(dis dis.Discover) {
 		return
 	}
 	worker := d.newWorker(dis)
-	if component.Spec.IsStaticEndpoints() {
+	if component.Spec.IsProbeEndpoints() {
 		worker.proberManager.AddThirdComponent(dis.GetComponent())
 	}
 	go worker.Start()
@@ -152,6 +152,7 @@ func (d *DiscoverPool) RemoveDiscover(component *v1alpha1.ThirdComponent) {
 		olddis.Stop()
 		delete(d.discoverWorker, key)
 	}
+	dis.RemoveRegistryClient(component.Namespace, component.Name)
 }
 
 // RemoveDiscoverByName -
@@ -164,4 +165,5 @@ func (d *DiscoverPool) RemoveDiscoverByName(req types.NamespacedName) {
 		olddis.Stop()
 		delete(d.discoverWorker, key)
 	}
+	dis.RemoveRegistryClient(req.Namespace, req.Name)
 }
@@ -37,7 +37,7 @@ func (w *Worker) Start() {
 // UpdateDiscover -
 func (w *Worker) UpdateDiscover(discover dis.Discover) {
 	component := discover.GetComponent()
-	if component.Spec.IsStaticEndpoints() {
+	if component.Spec.IsProbeEndpoints() {
 		w.proberManager.AddThirdComponent(discover.GetComponent())
 		discover.SetProberManager(w.proberManager)
 	}
@@ -34,7 +34,9 @@ import (
 	"github.com/wutong-paas/wutong/util/leader"
 	"github.com/wutong-paas/wutong/worker/appm/store"
 	mcontroller "github.com/wutong-paas/wutong/worker/master/controller"
+	"github.com/wutong-paas/wutong/worker/master/controller/acme"
 	"github.com/wutong-paas/wutong/worker/master/controller/helmapp"
+	"github.com/wutong-paas/wutong/worker/master/controller/scaling"
 	"github.com/wutong-paas/wutong/worker/master/controller/thirdcomponent"
 	"github.com/wutong-paas/wutong/worker/master/podevent"
 	"github.com/wutong-paas/wutong/worker/master/volumes/provider"
@@ -66,6 +68,8 @@ type Controller struct {
 	namespaceCPULimit   *prometheus.GaugeVec
 	pc                  *controller.ProvisionController
 	helmAppController   *helmapp.Controller
+	scalingController   *scaling.Controller
+	acmeController      *acme.Controller
 	controllers         []mcontroller.Controller
 	isLeader            bool
 
@@ -108,6 +112,15 @@ func NewMasterController(conf option.Config, store store.Storer, kubeClient kube
 	}, serverVersion.GitVersion)
 	stopCh := make(chan struct{})
 
+	acmeOpts := acme.Options{
+		DirectoryURL: conf.ACME.DirectoryURL,
+		Email:        conf.ACME.Email,
+		RenewBefore:  conf.ACME.RenewBefore,
+	}
+	if conf.ACME.DNSWebhook != "" {
+		acmeOpts.DNSProvider = acme.NewWebhookDNSProvider(conf.ACME.DNSWebhook)
+	}
+
 	helmAppController := helmapp.NewController(ctx, stopCh, kubeClient, wutongClient,
 		store.Informer().HelmApp, store.Lister().HelmApp, conf.Helm.RepoFile, conf.Helm.RepoCache, conf.Helm.RepoCache)
 
@@ -116,6 +129,8 @@ func NewMasterController(conf option.Config, store store.Storer, kubeClient kube
 		restConfig:        restConfig,
 		pc:                pc,
 		helmAppController: helmAppController,
+		scalingController: scaling.NewController(store, conf.MQAPI, conf.PrometheusEndpoint),
+		acmeController:    acme.NewController(acmeOpts, conf.MQAPI),
 		store:             store,
 		stopCh:            stopCh,
 		cancel:            cancel,
@@ -171,6 +186,17 @@ func (m *Controller) IsLeader() bool {
 	return m.isLeader
 }
 
+// WakeOnRequest wakes up the component scaled to zero, it is requested by the gateway
+func (m *Controller) WakeOnRequest(serviceID string) error {
+	return m.scalingController.WakeOnRequest(serviceID)
+}
+
+// ACMEKeyAuthorization returns the key authorization of the pending acme http-01 challenge,
+// it is requested by the gateway
+func (m *Controller) ACMEKeyAuthorization(token string) (string, error) {
+	return m.acmeController.KeyAuthorization(token)
+}
+
 // Start start
 func (m *Controller) Start() error {
 	logrus.Debug("master controller starting")
@@ -198,6 +224,11 @@ func (m *Controller) Start() error {
 		go m.helmAppController.Start()
 		defer m.helmAppController.Stop()
 
+		// scheduled scaling and scale-to-zero controller
+		go m.scalingController.Start(ctx)
+		// certificates of the auto tls http rules
+		go m.acmeController.Start(ctx)
+
 		ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 		// start controller
 		mgr, err := ctrl.NewManager(m.restConfig, ctrl.Options{
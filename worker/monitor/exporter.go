@@ -20,7 +20,10 @@ package monitor
 
 import (
 	"context"
+	"crypto/subtle"
+	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -88,6 +91,11 @@ func (t *ExporterManager) Start() error {
 		}
 		httputil.ReturnSuccess(r, w, healthStatus)
 	})
+	if t.config.WakeToken == "" {
+		logrus.Warning("the wake token is not configured, the components scaled to zero can not be woken up by the gateway")
+	}
+	http.HandleFunc("/worker/scaling/wake", t.wake)
+	http.HandleFunc("/worker/acme/challenge/", t.acmeChallenge)
 	logrus.Infoln("Listening on", t.config.Listen)
 	go func() {
 		logrus.Fatal(http.ListenAndServe(t.config.Listen, nil))
@@ -96,6 +104,69 @@ func (t *ExporterManager) Start() error {
 	return nil
 }
 
+// wake wakes up the components scaled to zero, it is requested by the gateway which holds
+// the requests of the components. The listen address is reachable in the cluster, so only
+// the requests with the wake token shared with the gateway are accepted.
+func (t *ExporterManager) wake(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputil.ReturnError(r, w, 405, "method not allowed")
+		return
+	}
+	if !validWakeToken(t.config.WakeToken, r) {
+		httputil.ReturnError(r, w, 401, "invalid wake token")
+		return
+	}
+	var req struct {
+		ServiceIDs []string `json:"service_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httputil.ReturnError(r, w, 400, err.Error())
+		return
+	}
+	for _, serviceID := range req.ServiceIDs {
+		if err := t.masterController.WakeOnRequest(serviceID); err != nil {
+			logrus.Errorf("wake up component %s: %v", serviceID, err)
+		}
+	}
+	httputil.ReturnSuccess(r, w, nil)
+}
+
+// validWakeToken returns true if the request carries the wake token, no request is
+// valid if the token is not configured
+func validWakeToken(token string, r *http.Request) bool {
+	if token == "" {
+		return false
+	}
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// acmeChallenge returns the key authorization of the acme http-01 challenge, it is requested
+// by the gateway which serves the challenges of the auto tls hosts.
+func (t *ExporterManager) acmeChallenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		httputil.ReturnError(r, w, 405, "method not allowed")
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, "/worker/acme/challenge/")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	keyAuth, err := t.masterController.ACMEKeyAuthorization(token)
+	if err != nil {
+		logrus.Errorf("get key authorization of acme challenge %s: %v", token, err)
+		httputil.ReturnError(r, w, 500, err.Error())
+		return
+	}
+	if keyAuth == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(keyAuth))
+}
+
 // Stop 停止
 func (t *ExporterManager) Stop() {
 	t.cancel()
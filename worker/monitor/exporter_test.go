@@ -0,0 +1,47 @@
+// Copyright (C) 2014-2018 Wutong Co., Ltd.
+// WUTONG, Application Management Platform
+
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version. For any non-GPL usage of Wutong,
+// one or multiple Commercial Licenses authorized by Wutong Co., Ltd.
+// must be obtained first.
+
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package monitor
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidWakeToken(t *testing.T) {
+	tests := []struct {
+		token, header string
+		want          bool
+	}{
+		{"secret", "Bearer secret", true},
+		{"secret", "Bearer other", false},
+		{"secret", "", false},
+		// the wake requests are refused if no token is configured
+		{"", "", false},
+		{"", "Bearer ", false},
+	}
+	for _, tc := range tests {
+		r := httptest.NewRequest("POST", "/worker/scaling/wake", nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		if got := validWakeToken(tc.token, r); got != tc.want {
+			t.Errorf("validWakeToken(%q, %q) = %v, want %v", tc.token, tc.header, got, tc.want)
+		}
+	}
+}
@@ -425,6 +425,29 @@ spec:
             endpointSource:
               description: endpoint source config
               properties:
+                dns:
+                  description: DNSSource the endpoints resolved from the dns records
+                  properties:
+                    hostname:
+                      description: The hostname to resolve, such as db.example.com, or
+                        _http._tcp.example.com for SRV records
+                      type: string
+                    refreshIntervalSeconds:
+                      description: How often (in seconds) to resolve the hostname. Defaults
+                        to 30 seconds. Minimum value is 5.
+                      format: int32
+                      type: integer
+                    resolver:
+                      description: The address of the dns server, such as 10.0.0.10:53.
+                        If not specified, the resolver of the system is used.
+                      type: string
+                    type:
+                      description: 'The record type, including: A, AAAA, SRV. Defaults to
+                        A. The ports of the A and AAAA records are the component ports.'
+                      type: string
+                  required:
+                  - hostname
+                  type: object
                 endpoints:
                   items:
                     properties:
@@ -454,6 +477,52 @@ spec:
                   required:
                   - name
                   type: object
+                registry:
+                  description: RegistrySource the service registered in consul,
+                    nacos or eureka
+                  properties:
+                    group:
+                      description: The nacos group or the consul tag
+                      type: string
+                    namespace:
+                      description: The nacos namespace id or the consul datacenter
+                      type: string
+                    passwordSecretRef:
+                      description: The secret key of the password, or the acl
+                        token of consul
+                      properties:
+                        key:
+                          description: The key of the secret to select from.  Must
+                            be a valid secret key.
+                          type: string
+                        name:
+                          description: 'Name of the referent. More info: https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names'
+                          type: string
+                        optional:
+                          description: Specify whether the Secret or its key must
+                            be defined
+                          type: boolean
+                      required:
+                      - key
+                      type: object
+                    serviceName:
+                      description: The name of the service registered in the registry
+                      type: string
+                    servers:
+                      description: The addresses of the registry, separated by
+                        comma
+                      type: string
+                    type:
+                      description: 'Type of the registry, including: consul, nacos,
+                        eureka'
+                      type: string
+                    username:
+                      type: string
+                  required:
+                  - servers
+                  - serviceName
+                  - type
+                  type: object
               type: object
             ports:
               description: component regist ports